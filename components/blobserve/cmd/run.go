@@ -77,7 +77,7 @@ var runCmd = &cobra.Command{
 			return docker.NewResolver(resolverOpts)
 		}
 
-		srv, err := blobserve.NewServer(cfg.BlobServe, resolverProvider)
+		srv, err := blobserve.NewServer(cfg.BlobServe, resolverProvider, prometheus.WrapRegistererWithPrefix("gitpod_blobserve_", reg))
 		if err != nil {
 			log.WithError(err).Fatal("cannot create blob server")
 		}
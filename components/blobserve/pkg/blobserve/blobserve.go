@@ -23,6 +23,7 @@ import (
 	"github.com/containerd/containerd/remotes"
 	"github.com/docker/distribution/reference"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/xerrors"
 
 	"github.com/gitpod-io/gitpod/common-go/log"
@@ -66,6 +67,9 @@ type Config struct {
 	// ref config or not.
 	AllowAnyRepo bool      `json:"allowAnyRepo"`
 	BlobSpace    BlobSpace `json:"blobSpace"`
+	// PreWarm lists refs which are prepared in the background when blobserve starts.
+	// Unlike a repo's PrePull list, failing to prepare them does not prevent startup.
+	PreWarm []string `json:"preWarm,omitempty"`
 }
 
 type StringReplacement struct {
@@ -116,8 +120,12 @@ var ReferenceRegexp = expression(reference.NameRegexp,
 	optional(literal("@"), reference.DigestRegexp))
 
 // NewServer creates a new blob server
-func NewServer(cfg Config, resolver ResolverProvider) (*Server, error) {
-	refstore, err := newRefStore(cfg, resolver)
+func NewServer(cfg Config, resolver ResolverProvider, reg prometheus.Registerer) (*Server, error) {
+	metrics, err := newMetrics(reg)
+	if err != nil {
+		return nil, err
+	}
+	refstore, err := newRefStore(cfg, resolver, metrics)
 	if err != nil {
 		return nil, err
 	}
@@ -137,9 +145,23 @@ func NewServer(cfg Config, resolver ResolverProvider) (*Server, error) {
 			}
 		}
 	}
+	if len(cfg.PreWarm) > 0 {
+		go s.preWarm(cfg.PreWarm)
+	}
 	return s, nil
 }
 
+// preWarm prepares the given refs one after the other
+func (reg *Server) preWarm(refs []string) {
+	for _, ref := range refs {
+		log.WithField("ref", ref).Info("pre-warming blob server")
+		err := reg.Prepare(context.Background(), ref)
+		if err != nil {
+			log.WithError(err).WithField("ref", ref).Warn("cannot pre-warm blob server")
+		}
+	}
+}
+
 // Serve serves the registry on the given port
 func (reg *Server) Serve() error {
 	r := mux.NewRouter()
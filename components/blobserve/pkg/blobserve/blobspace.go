@@ -17,6 +17,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/containerd/containerd/errdefs"
@@ -30,15 +31,18 @@ type FileModifier func(in io.Reader, out io.Writer) error
 
 type blobspace interface {
 	Get(name string) (fs http.FileSystem, state blobstate)
+	State(name string) blobstate
 	AddFromTarGzip(ctx context.Context, name string, in io.Reader, modifications []blobModifier) (err error)
 }
 
 type diskBlobspace struct {
 	Location string
 	MaxSize  int64
+
+	metrics *metrics
 }
 
-func newBlobSpace(loc string, maxSize int64, housekeepingInterval time.Duration) (bs *diskBlobspace, err error) {
+func newBlobSpace(loc string, maxSize int64, housekeepingInterval time.Duration, metrics *metrics) (bs *diskBlobspace, err error) {
 	if tproot := os.Getenv("TELEPRESENCE_ROOT"); tproot != "" {
 		loc = filepath.Join(tproot, loc)
 	}
@@ -51,7 +55,9 @@ func newBlobSpace(loc string, maxSize int64, housekeepingInterval time.Duration)
 	bs = &diskBlobspace{
 		Location: loc,
 		MaxSize:  maxSize,
+		metrics:  metrics,
 	}
+	bs.validate()
 	if maxSize > 0 {
 		go bs.collectGarbage(housekeepingInterval)
 	}
@@ -131,6 +137,7 @@ func (b *diskBlobspace) collectGarbage(interval time.Duration) {
 				}
 				totalSize -= blob.Size
 				spaceFreed += blob.Size
+				b.metrics.evicted(blob.Size)
 			}
 		}
 		log.WithField("spaceFreed", spaceFreed).Info("blobspace GC complete")
@@ -171,6 +178,68 @@ func getGCBlob(wd string, f os.DirEntry) (blob gcBlob) {
 	return
 }
 
+// validate removes all blobs left behind by a previous run which are not ready for use,
+// e.g. because their extraction was interrupted. Ready blobs are kept for reuse.
+func (b *diskBlobspace) validate() {
+	files, err := os.ReadDir(b.Location)
+	if err != nil {
+		log.WithError(err).WithField("location", b.Location).Error("blobspace cannot list files in working area")
+		return
+	}
+
+	var kept, removed int
+	for _, f := range files {
+		fn := filepath.Join(b.Location, f.Name())
+		if !f.IsDir() {
+			// remove markers of blobs which no longer exist
+			ext := filepath.Ext(f.Name())
+			if ext != ".ready" && ext != ".size" && ext != ".used" {
+				continue
+			}
+			if _, err := os.Stat(strings.TrimSuffix(fn, ext)); os.IsNotExist(err) {
+				os.Remove(fn)
+			}
+			continue
+		}
+
+		if b.State(f.Name()) == blobReady {
+			kept++
+			continue
+		}
+
+		log.WithField("location", fn).Info("removing blob which is not ready")
+		os.Remove(fmt.Sprintf("%s.ready", fn))
+		os.Remove(fmt.Sprintf("%s.size", fn))
+		os.Remove(fmt.Sprintf("%s.used", fn))
+		err = os.RemoveAll(fn)
+		if err != nil {
+			log.WithError(err).WithField("location", fn).Error("cannot remove blob")
+			continue
+		}
+		removed++
+	}
+	log.WithField("kept", kept).WithField("removed", removed).Info("blobspace validated")
+}
+
+// State returns the state of a blob without marking it as used
+func (b *diskBlobspace) State(name string) blobstate {
+	fn := filepath.Join(b.Location, name)
+	if _, err := os.Stat(fn); os.IsNotExist(err) {
+		return blobUnknown
+	}
+	if _, err := os.Stat(fmt.Sprintf("%s.ready", fn)); os.IsNotExist(err) {
+		return blobUnready
+	}
+	rawSize, err := os.ReadFile(fmt.Sprintf("%s.size", fn))
+	if err != nil {
+		return blobUnready
+	}
+	if _, err := strconv.ParseInt(string(rawSize), 10, 64); err != nil {
+		return blobUnready
+	}
+	return blobReady
+}
+
 func (b *diskBlobspace) Get(name string) (fs http.FileSystem, state blobstate) {
 	fn := filepath.Join(b.Location, name)
 	if _, err := os.Stat(fn); os.IsNotExist(err) {
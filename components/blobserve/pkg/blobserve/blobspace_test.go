@@ -71,6 +71,48 @@ func Test_diskBlobspace_modifyFile(t *testing.T) {
 	}
 }
 
+func Test_diskBlobspace_validate(t *testing.T) {
+	tmp := t.TempDir()
+	for _, f := range []struct {
+		Name    string
+		Content string
+	}{
+		{Name: "ready/index.html", Content: "hello"},
+		{Name: "ready.ready"},
+		{Name: "ready.size", Content: "5"},
+		{Name: "unready/index.html", Content: "hel"},
+		{Name: "nosize/index.html", Content: "hello"},
+		{Name: "nosize.ready"},
+		{Name: "gone.used"},
+		{Name: "refs.json", Content: "{}"},
+	} {
+		fn := filepath.Join(tmp, f.Name)
+		os.MkdirAll(filepath.Dir(fn), 0755)
+		err := os.WriteFile(fn, []byte(f.Content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := &diskBlobspace{Location: tmp}
+	b.validate()
+
+	files, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	if diff := cmp.Diff([]string{"ready", "ready.ready", "ready.size", "refs.json"}, names); diff != "" {
+		t.Errorf("validate() mismatch (-want +got):\n%s", diff)
+	}
+	if state := b.State("ready"); state != blobReady {
+		t.Errorf("expected ready blob to be kept, got state %v", state)
+	}
+}
+
 func Test_modifySearchAndReplace(t *testing.T) {
 	type args struct {
 		Search  string
@@ -0,0 +1,70 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package blobserve
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// metrics combine the custom metrics exported by blobserve
+type metrics struct {
+	BlobRequestCounter    *prometheus.CounterVec
+	GCEvictionCounter     prometheus.Counter
+	GCEvictedBytesCounter prometheus.Counter
+}
+
+func newMetrics(reg prometheus.Registerer) (*metrics, error) {
+	blobRequestCounter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blob_req_total",
+		Help: "number of blob requests by whether the blob was readily available (hit) or had to be downloaded first (miss)",
+	}, []string{"result"})
+	err := reg.Register(blobRequestCounter)
+	if err != nil {
+		return nil, err
+	}
+
+	gcEvictionCounter := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gc_evictions_total",
+		Help: "number of blobs removed by the blobspace garbage collector",
+	})
+	err = reg.Register(gcEvictionCounter)
+	if err != nil {
+		return nil, err
+	}
+
+	gcEvictedBytesCounter := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "gc_evicted_bytes_total",
+		Help: "number of bytes freed by the blobspace garbage collector",
+	})
+	err = reg.Register(gcEvictedBytesCounter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metrics{
+		BlobRequestCounter:    blobRequestCounter,
+		GCEvictionCounter:     gcEvictionCounter,
+		GCEvictedBytesCounter: gcEvictedBytesCounter,
+	}, nil
+}
+
+func (m *metrics) blobRequest(hit bool) {
+	if m == nil {
+		return
+	}
+	if hit {
+		m.BlobRequestCounter.WithLabelValues("hit").Inc()
+	} else {
+		m.BlobRequestCounter.WithLabelValues("miss").Inc()
+	}
+}
+
+func (m *metrics) evicted(size int64) {
+	if m == nil {
+		return
+	}
+	m.GCEvictionCounter.Inc()
+	m.GCEvictedBytesCounter.Add(float64(size))
+}
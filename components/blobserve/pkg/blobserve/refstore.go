@@ -6,7 +6,10 @@ package blobserve
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -24,6 +27,10 @@ const (
 	// parallelBlobDownloads is the number of service worker a refstore uses to
 	// serve download requests.
 	parallelBlobDownloads = 10
+
+	// refIndexFilename is the name of the file in the blobspace location which
+	// persists the ref to blob mapping across restarts.
+	refIndexFilename = "refs.json"
 )
 
 // blobModifier modifies files in a blob. The path is relative to the blob's
@@ -48,13 +55,21 @@ type refstore struct {
 	requests  chan downloadRequest
 	blobspace blobspace
 	config    map[string]blobConfig
+	metrics   *metrics
+
+	// index maps digest-pinned refs to the digest of their blob once that blob is ready.
+	// Refs by tag are not indexed, as the tag may point to a different image after a restart.
+	// If indexPath is set, the index is persisted there.
+	index     map[string]string
+	indexPath string
+	indexMu   sync.Mutex
 
 	close chan struct{}
 	once  *sync.Once
 }
 
-func newRefStore(cfg Config, resolver ResolverProvider) (*refstore, error) {
-	bs, err := newBlobSpace(cfg.BlobSpace.Location, cfg.BlobSpace.MaxSize, 10*time.Minute)
+func newRefStore(cfg Config, resolver ResolverProvider, metrics *metrics) (*refstore, error) {
+	bs, err := newBlobSpace(cfg.BlobSpace.Location, cfg.BlobSpace.MaxSize, 10*time.Minute, metrics)
 	if err != nil {
 		return nil, err
 	}
@@ -76,17 +91,104 @@ func newRefStore(cfg Config, resolver ResolverProvider) (*refstore, error) {
 		Resolver:  resolver,
 		blobspace: bs,
 		config:    config,
+		metrics:   metrics,
 		refcache:  make(map[string]*refstate),
+		index:     make(map[string]string),
+		indexPath: filepath.Join(bs.Location, refIndexFilename),
 		requests:  make(chan downloadRequest),
 		once:      &sync.Once{},
 		close:     make(chan struct{}),
 	}
+	res.restoreIndex()
 	for i := 0; i < parallelBlobDownloads; i++ {
 		go res.serveRequests()
 	}
 	return res, nil
 }
 
+// restoreIndex loads the persisted ref index and adds all digest-pinned refs whose blob is still
+// ready to the refcache. Refs by tag and refs of blobs which are gone or incomplete are dropped.
+func (store *refstore) restoreIndex() {
+	fc, err := os.ReadFile(store.indexPath)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.WithError(err).WithField("fn", store.indexPath).Warn("cannot read blobserve ref index")
+		return
+	}
+
+	var index map[string]string
+	err = json.Unmarshal(fc, &index)
+	if err != nil {
+		log.WithError(err).WithField("fn", store.indexPath).Warn("cannot parse blobserve ref index - starting with an empty one")
+		index = nil
+	}
+
+	store.mu.Lock()
+	for ref, digest := range index {
+		if !isDigestRef(ref) {
+			log.WithField("ref", ref).Info("dropping ref from index - it is not pinned to a digest")
+			continue
+		}
+		if store.blobspace.State(digest) != blobReady {
+			log.WithField("ref", ref).WithField("digest", digest).Info("dropping ref from index - blob is no longer available")
+			continue
+		}
+
+		rs := &refstate{Digest: digest, ch: make(chan error)}
+		close(rs.ch)
+		store.refcache[ref] = rs
+		store.index[ref] = digest
+	}
+	dropped := len(index) - len(store.index)
+	store.mu.Unlock()
+	log.WithField("refs", len(store.index)).Info("restored blobserve ref index")
+
+	if dropped > 0 {
+		store.persistIndex()
+	}
+}
+
+// isDigestRef returns true if ref refers to an image by digest
+func isDigestRef(ref string) bool {
+	r, err := reference.Parse(ref)
+	if err != nil {
+		return false
+	}
+	_, ok := r.(reference.Digested)
+	return ok
+}
+
+// persistIndex atomically replaces the ref index on disk
+func (store *refstore) persistIndex() {
+	if store.indexPath == "" {
+		return
+	}
+
+	store.indexMu.Lock()
+	defer store.indexMu.Unlock()
+
+	store.mu.RLock()
+	fc, err := json.Marshal(store.index)
+	store.mu.RUnlock()
+	if err != nil {
+		log.WithError(err).Warn("cannot marshal blobserve ref index")
+		return
+	}
+
+	tmp := store.indexPath + ".tmp"
+	err = os.WriteFile(tmp, fc, 0644)
+	if err != nil {
+		log.WithError(err).WithField("fn", tmp).Warn("cannot write blobserve ref index")
+		return
+	}
+	err = os.Rename(tmp, store.indexPath)
+	if err != nil {
+		log.WithError(err).WithField("fn", store.indexPath).Warn("cannot write blobserve ref index")
+	}
+}
+
 type refstate struct {
 	Digest string
 
@@ -138,6 +240,7 @@ func (store *refstore) BlobFor(ctx context.Context, ref string, readOnly bool) (
 		}
 	}
 	if !exists && readOnly {
+		store.metrics.blobRequest(false)
 		return nil, "", errdefs.ErrNotFound
 	}
 
@@ -147,6 +250,7 @@ func (store *refstore) BlobFor(ctx context.Context, ref string, readOnly bool) (
 		// hence blobState can validly be blobUnknown.
 		fs, blobState = store.blobspace.Get(rs.Digest)
 	}
+	store.metrics.blobRequest(blobState == blobReady)
 	if blobState == blobUnknown {
 		// if refcache thinks the blob should exist, but it doesn't, we force a redownload.
 		err = store.downloadBlobFor(ctx, ref, exists)
@@ -233,11 +337,26 @@ func (store *refstore) handleRequest(ctx context.Context, ref string, force bool
 	store.mu.Unlock()
 
 	defer func() {
+		var indexChanged bool
+		store.mu.Lock()
 		if err != nil {
-			store.mu.Lock()
 			delete(store.refcache, ref)
-			store.mu.Unlock()
+			if _, indexed := store.index[ref]; indexed {
+				delete(store.index, ref)
+				indexChanged = true
+			}
+		} else if isDigestRef(ref) && store.index[ref] != rs.Digest {
+			if store.index == nil {
+				store.index = make(map[string]string)
+			}
+			store.index[ref] = rs.Digest
+			indexChanged = true
 		}
+		store.mu.Unlock()
+		if indexChanged {
+			store.persistIndex()
+		}
+
 		rs.MarkDone(err)
 	}()
 
@@ -12,9 +12,12 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
+	"github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/remotes"
 	"github.com/google/go-cmp/cmp"
 	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
@@ -434,6 +437,67 @@ func TestBlobFor(t *testing.T) {
 
 }
 
+func TestRefIndex(t *testing.T) {
+	const (
+		ref       = "gitpod.io/test@sha256:1c5bf5a4d1c1dfdc8c7e9a11a7c5b0ff5ed5d9bb6a1e1e1bf8d4e0f6a7c0b2d1"
+		otherRef  = "gitpod.io/test@sha256:2c5bf5a4d1c1dfdc8c7e9a11a7c5b0ff5ed5d9bb6a1e1e1bf8d4e0f6a7c0b2d1"
+		tagRef    = "gitpod.io/test:latest"
+		hashLayer = "4970405cb2a3a461cc00fd755712beded51919d7e69270d7d10d0dcf5e209714"
+	)
+	indexPath := filepath.Join(t.TempDir(), refIndexFilename)
+	err := os.WriteFile(indexPath, []byte(`{"`+ref+`":"`+hashLayer+`","`+otherRef+`":"gone","`+tagRef+`":"`+hashLayer+`"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &refstore{
+		// an empty fetcher ensures that everything must come from the index
+		Resolver:  func() remotes.Resolver { return &fakeFetcher{} },
+		blobspace: &inMemoryBlobspace{Content: map[string]blobstate{hashLayer: blobReady}},
+		refcache:  make(map[string]*refstate),
+		index:     make(map[string]string),
+		indexPath: indexPath,
+		close:     make(chan struct{}),
+		once:      &sync.Once{},
+		requests:  make(chan downloadRequest),
+	}
+	s.restoreIndex()
+	for i := 0; i < parallelBlobDownloads; i++ {
+		go s.serveRequests()
+	}
+	defer s.Close()
+
+	_, hash, err := s.BlobFor(context.Background(), ref, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(hashLayer, hash); diff != "" {
+		t.Errorf("unexpected blob hash (-want +got):\n%s", diff)
+	}
+
+	_, _, err = s.BlobFor(context.Background(), otherRef, true)
+	if err != errdefs.ErrNotFound {
+		t.Errorf("expected ref of missing blob to be dropped, got %v", err)
+	}
+	_, _, err = s.BlobFor(context.Background(), tagRef, true)
+	if err != errdefs.ErrNotFound {
+		t.Errorf("expected ref by tag to be dropped, got %v", err)
+	}
+
+	fc, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index map[string]string
+	err = json.Unmarshal(fc, &index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[string]string{ref: hashLayer}, index); diff != "" {
+		t.Errorf("unexpected persisted index (-want +got):\n%s", diff)
+	}
+}
+
 type inMemoryBlobspace struct {
 	Content map[string]blobstate
 	Adder   func(ctx context.Context, name string, in io.Reader) (err error)
@@ -443,6 +507,10 @@ func (s *inMemoryBlobspace) Get(name string) (fs http.FileSystem, state blobstat
 	return &FakeFileSystem{}, s.Content[name]
 }
 
+func (s *inMemoryBlobspace) State(name string) blobstate {
+	return s.Content[name]
+}
+
 func (s *inMemoryBlobspace) AddFromTarGzip(ctx context.Context, name string, in io.Reader, modifications []blobModifier) (err error) {
 	return s.Adder(ctx, name, in)
 }
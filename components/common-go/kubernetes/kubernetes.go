@@ -62,6 +62,14 @@ const (
 
 	// WorkspaceExposedPorts contains the exposed ports in the workspace
 	WorkspaceExposedPorts = "gitpod/exposedPorts"
+
+	// WorkspacePortProtectionAnnotation contains the JSON serialized protection of public workspace ports.
+	// It's set from the "portProtection" annotation of the workspace start request, which ws-manager prefixes with "gitpod.io/annotation.".
+	WorkspacePortProtectionAnnotation = "gitpod.io/annotation.portProtection"
+
+	// WorkspaceCustomDomainsAnnotation contains the JSON serialized custom domains mapped to workspace ports.
+	// It's set from the "customDomains" annotation of the workspace start request.
+	WorkspaceCustomDomainsAnnotation = "gitpod.io/annotation.customDomains"
 )
 
 // WorkspaceSupervisorEndpoint produces the supervisor endpoint of a workspace.
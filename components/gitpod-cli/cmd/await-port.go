@@ -6,13 +6,16 @@ package cmd
 
 import (
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"regexp"
 	"strconv"
 	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
 )
 
 var awaitPortCmd = &cobra.Command{
@@ -22,25 +25,27 @@ var awaitPortCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		port, err := strconv.ParseUint(args[0], 10, 16)
 		if err != nil {
-			log.Fatalf("port cannot be parsed as int: %s", err)
+			fail(fmt.Sprintf("port cannot be parsed as int: %s", err))
 		}
 
 		// Expected format: local port (in hex), remote address (irrelevant here), connection state ("0A" is "TCP_LISTEN")
 		pattern, err := regexp.Compile(fmt.Sprintf(":[0]*%X \\w+:\\w+ 0A ", port))
 		if err != nil {
-			log.Fatal("cannot compile regexp pattern")
+			fail("cannot compile regexp pattern")
 		}
 
-		fmt.Printf("Awaiting port %d... ", port)
+		if rootOpts.Output == output.Text {
+			fmt.Printf("Awaiting port %d... ", port)
+		}
 		for {
 			tcp, err := os.ReadFile("/proc/net/tcp")
 			if err != nil {
-				log.Fatalf("cannot read /proc/net/tcp: %s", err)
+				exitWithError(xerrors.Errorf("cannot read /proc/net/tcp: %w", err))
 			}
 
 			tcp6, err := os.ReadFile("/proc/net/tcp6")
 			if err != nil {
-				log.Fatalf("cannot read /proc/net/tcp6: %s", err)
+				exitWithError(xerrors.Errorf("cannot read /proc/net/tcp6: %w", err))
 			}
 
 			if pattern.MatchString(string(tcp)) || pattern.MatchString(string(tcp6)) {
@@ -50,10 +55,19 @@ var awaitPortCmd = &cobra.Command{
 			time.Sleep(2 * time.Second)
 		}
 
-		fmt.Println("ok")
+		res := awaitPortOutput{Port: int(port)}
+		printOutput(&res, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, "ok")
+			return err
+		})
 	},
 }
 
+// awaitPortOutput is how gp await-port prints the port it awaited in the JSON and YAML formats
+type awaitPortOutput struct {
+	Port int `json:"port"`
+}
+
 func init() {
 	rootCmd.AddCommand(awaitPortCmd)
 }
@@ -0,0 +1,101 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
+	serverapi "github.com/gitpod-io/gitpod/gitpod-protocol"
+)
+
+var envExportCmdOpts struct {
+	Format string
+}
+
+var envExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports persistent environment variables as dotenv file or JSON",
+	Long: `Prints the persistent environment variables of this workspace as dotenv file or as JSON.
+If --scope is given, only the variables of exactly that scope are exported.
+
+Values are masked unless --reveal is given, e.g.
+	gp env export --reveal > .env
+
+If --output json or yaml is given and --format is not, the variables are printed in that format.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := formatEnvVars(nil, envExportCmdOpts.Format); err != nil {
+			exitWithError(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+		result, err := connectToServer(ctx, envScope, false)
+		if err != nil {
+			exitWithError(err)
+		}
+		vars, err := getScopedEnvVars(ctx, result)
+		if err != nil {
+			exitWithError(err)
+		}
+		if !revealEnvs {
+			for i, v := range vars {
+				vars[i] = displayedVar(v)
+			}
+			fmt.Fprintln(os.Stderr, "values are masked, use --reveal to export them")
+		}
+
+		if rootOpts.Output != output.Text && !cmd.Flags().Changed("format") {
+			printOutput(newEnvVarOutputs(vars), nil)
+			return
+		}
+		out, err := formatEnvVars(vars, envExportCmdOpts.Format)
+		if err != nil {
+			exitWithError(err)
+		}
+		fmt.Print(out)
+	},
+}
+
+// formatEnvVars formats environment variables as dotenv file or JSON.
+func formatEnvVars(vars []*serverapi.UserEnvVarValue, format string) (string, error) {
+	switch format {
+	case "dotenv":
+		var res strings.Builder
+		for _, v := range vars {
+			fmt.Fprintf(&res, "%s=%s\n", v.Name, quoteDotenvValue(v.Value))
+		}
+		return res.String(), nil
+	case "json":
+		out, err := json.MarshalIndent(newEnvVarOutputs(vars), "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(out) + "\n", nil
+	default:
+		return "", xerrors.Errorf("unknown format %q (supported formats are dotenv and json)", format)
+	}
+}
+
+// quoteDotenvValue double quotes a value such that parseDotenv reads it back unchanged.
+func quoteDotenvValue(value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`)
+	return `"` + r.Replace(value) + `"`
+}
+
+func init() {
+	envCmd.AddCommand(envExportCmd)
+
+	envExportCmd.Flags().StringVar(&envExportCmdOpts.Format, "format", "dotenv", "output format: dotenv or json")
+}
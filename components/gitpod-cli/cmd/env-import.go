@@ -0,0 +1,211 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
+	serverapi "github.com/gitpod-io/gitpod/gitpod-protocol"
+)
+
+var envImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Imports persistent environment variables from a dotenv file",
+	Long: `Imports persistent environment variables from a dotenv file, or from stdin if the file is -.
+Each line of the file has the form NAME=value and may be prefixed with export. Values can be
+quoted using single quotes (taken literally) or double quotes (supporting \n, \t, \" and \\ escapes
+and spanning multiple lines). Empty lines and lines starting with # are ignored. Masked values as printed
+by gp env export without --reveal are rejected.
+
+The changes to the variables in the scope are printed. Use --dry-run to only print them.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var (
+			content []byte
+			err     error
+		)
+		if args[0] == "-" {
+			content, err = io.ReadAll(os.Stdin)
+		} else {
+			content, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+		result, err := connectToServer(ctx, envScope, true)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		vars, err := parseDotenv(content, result.repositoryPattern)
+		if err != nil {
+			fail(fmt.Sprintf("cannot parse %s: %v", args[0], err))
+		}
+		current, err := getScopedEnvVars(ctx, result)
+		if err != nil {
+			exitWithError(err)
+		}
+		changes := diffEnvVars(current, vars, nil, result.repositoryPattern)
+		printEnvVarChanges(changes)
+		if dryRunEnvs {
+			return
+		}
+
+		var (
+			exitCode int
+			mu       sync.Mutex
+			wg       sync.WaitGroup
+		)
+		wg.Add(len(changes))
+		for _, c := range changes {
+			go func(c envVarChange) {
+				err := result.client.SetEnvVar(ctx, &serverapi.UserEnvVarValue{Name: c.Name, Value: c.NewValue, RepositoryPattern: result.repositoryPattern})
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "cannot set %s: %v\n", c.Name, err)
+					mu.Lock()
+					exitCode = output.ExitCodeError
+					mu.Unlock()
+				}
+				wg.Done()
+			}(c)
+		}
+		wg.Wait()
+		os.Exit(exitCode)
+	},
+}
+
+// parseDotenv parses the content of a dotenv file. If a variable is defined more than once, the last definition wins.
+func parseDotenv(content []byte, pattern string) ([]*serverapi.UserEnvVarValue, error) {
+	var (
+		res   []*serverapi.UserEnvVarValue
+		index = make(map[string]int)
+		lines = strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	)
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		segs := strings.SplitN(line, "=", 2)
+		if len(segs) != 2 {
+			return nil, xerrors.Errorf("line %d: %s has no equal character (correct format is NAME=value)", lineNo, line)
+		}
+		name := strings.TrimSpace(segs[0])
+		if !envVarNamePattern.MatchString(name) {
+			return nil, xerrors.Errorf("line %d: invalid variable name %q", lineNo, name)
+		}
+
+		value := strings.TrimLeft(segs[1], " \t")
+		switch {
+		case strings.HasPrefix(value, `"`):
+			var (
+				unquoted strings.Builder
+				closed   bool
+			)
+			value = value[1:]
+			for !closed {
+				var err error
+				closed, err = unquoteDotenvLine(&unquoted, value)
+				if err != nil {
+					return nil, xerrors.Errorf("line %d: %w", lineNo, err)
+				}
+				if closed {
+					break
+				}
+				i++
+				if i >= len(lines) {
+					return nil, xerrors.Errorf("line %d: unterminated double quoted value of %s", lineNo, name)
+				}
+				unquoted.WriteByte('\n')
+				value = lines[i]
+			}
+			value = unquoted.String()
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end == -1 {
+				return nil, xerrors.Errorf("line %d: unterminated single quoted value of %s", lineNo, name)
+			}
+			value = value[1 : end+1]
+		default:
+			if idx := strings.Index(value, " #"); idx != -1 {
+				value = value[:idx]
+			}
+			value = strings.TrimSpace(value)
+		}
+		if value == "" {
+			return nil, xerrors.Errorf("line %d: %s must have a value", lineNo, name)
+		}
+		if value == maskedEnvValue {
+			return nil, xerrors.Errorf("line %d: the value of %s is masked (use gp env export --reveal to export the actual values)", lineNo, name)
+		}
+
+		v := &serverapi.UserEnvVarValue{Name: name, Value: value, RepositoryPattern: pattern}
+		if idx, exists := index[name]; exists {
+			res[idx] = v
+			continue
+		}
+		index[name] = len(res)
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// unquoteDotenvLine writes the unescaped content of a double quoted value up to the closing quote to b.
+// It reports whether the closing quote was found.
+func unquoteDotenvLine(b *strings.Builder, line string) (closed bool, err error) {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch c {
+		case '"':
+			rest := strings.TrimSpace(line[i+1:])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return false, xerrors.Errorf("unexpected %q after closing quote", rest)
+			}
+			return true, nil
+		case '\\':
+			if i+1 == len(line) {
+				b.WriteByte(c)
+				continue
+			}
+			i++
+			switch line[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(line[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(line[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return false, nil
+}
+
+func init() {
+	envCmd.AddCommand(envImportCmd)
+}
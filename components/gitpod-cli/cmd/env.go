@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -18,12 +19,19 @@ import (
 	"golang.org/x/xerrors"
 	"google.golang.org/grpc"
 
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
 	serverapi "github.com/gitpod-io/gitpod/gitpod-protocol"
 	supervisor "github.com/gitpod-io/gitpod/supervisor/api"
 )
 
 var exportEnvs = false
 var unsetEnvs = false
+var envScope = ""
+var revealEnvs = false
+var dryRunEnvs = false
+
+// maskedEnvValue replaces the values of environment variables in output unless --reveal is given
+const maskedEnvValue = "********"
 
 // envCmd represents the env command
 var envCmd = &cobra.Command{
@@ -45,18 +53,26 @@ To update the current terminal session with the latest set of persistent environ
 To delete a persistent environment variable use:
 	gp env -u foo
 
-Note that you can delete/unset variables if their repository pattern matches the repository of this workspace exactly. I.e. you cannot
-delete environment variables with a repository pattern of */foo, foo/* or */*.
+Variables are set and deleted for the repository of this workspace. Use --scope to only print the variables which apply to
+this workspace through a wider repository pattern, e.g. those of all repositories of the owner or of all repositories:
+	gp env --scope owner/*
+	gp env --scope '*'
+
+Note that you can delete/unset variables only if their repository pattern matches the repository of this workspace exactly. I.e. you cannot
+delete environment variables with a repository pattern of */foo, foo/* or */* - use the Gitpod dashboard to manage those.
+
+Values are masked in the output unless --reveal is given, except when producing a script using -e.
+Use --dry-run to show what would change without changing anything.
 `,
 	Args: cobra.ArbitraryArgs,
-	Run: func(cmd *cobra.Command, args []string) {
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		log.SetOutput(io.Discard)
 		f, err := os.OpenFile(os.TempDir()+"/gp-env.log", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 		if err == nil {
-			defer f.Close()
 			log.SetOutput(f)
 		}
-
+	},
+	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) > 0 {
 			if unsetEnvs {
 				deleteEnvs(args)
@@ -72,10 +88,15 @@ delete environment variables with a repository pattern of */foo, foo/* or */*.
 
 type connectToServerResult struct {
 	repositoryPattern string
-	client            *serverapi.APIoverJSONRPC
+	// scoped is true if the repository pattern was given with --scope
+	scoped bool
+	client serverapi.APIInterface
 }
 
-func connectToServer(ctx context.Context) (*connectToServerResult, error) {
+// connectToServer connects to the server with a token that can modify the environment variables of this workspace's repository.
+// If scope is empty, the repository of this workspace is used. Otherwise the scope must be one the token can access, i.e. when
+// modify is true only the repository of this workspace.
+func connectToServer(ctx context.Context, scope string, modify bool) (*connectToServerResult, error) {
 	supervisorAddr := os.Getenv("SUPERVISOR_ADDR")
 	if supervisorAddr == "" {
 		supervisorAddr = "localhost:22999"
@@ -97,15 +118,28 @@ func connectToServer(ctx context.Context) (*connectToServerResult, error) {
 	if wsinfo.Repository.Name == "" {
 		return nil, xerrors.New("repository info is missing name")
 	}
-	repositoryPattern := wsinfo.Repository.Owner + "/" + wsinfo.Repository.Name
+	workspacePattern := wsinfo.Repository.Owner + "/" + wsinfo.Repository.Name
+	repositoryPattern := workspacePattern
+	if scope != "" {
+		repositoryPattern, err = parseScope(scope)
+		if err != nil {
+			return nil, err
+		}
+		err = checkScope(repositoryPattern, workspacePattern, modify)
+		if err != nil {
+			return nil, err
+		}
+	}
 	clientToken, err := supervisor.NewTokenServiceClient(supervisorConn).GetToken(ctx, &supervisor.GetTokenRequest{
 		Host: wsinfo.GitpodApi.Host,
 		Kind: "gitpod",
 		Scope: []string{
 			"function:getEnvVars",
+			"function:getAllEnvVars",
 			"function:setEnvVar",
 			"function:deleteEnvVar",
-			"resource:envVar::" + repositoryPattern + "::create/get/update/delete",
+			// the workspace token only grants access to the variables of its repository
+			"resource:envVar::" + workspacePattern + "::create/get/update/delete",
 		},
 	})
 	if err != nil {
@@ -119,76 +153,128 @@ func connectToServer(ctx context.Context) (*connectToServerResult, error) {
 	if err != nil {
 		return nil, xerrors.Errorf("failed connecting to server: %w", err)
 	}
-	return &connectToServerResult{repositoryPattern, client}, nil
+	return &connectToServerResult{repositoryPattern: repositoryPattern, scoped: scope != "", client: client}, nil
 }
 
 func getEnvs() {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer cancel()
-	result, err := connectToServer(ctx)
+	result, err := connectToServer(ctx, envScope, false)
 	if err != nil {
-		fail(err.Error())
+		exitWithError(err)
 	}
 
-	vars, err := result.client.GetEnvVars(ctx)
+	vars, err := getScopedEnvVars(ctx, result)
 	if err != nil {
-		fail("failed to fetch env vars from server: " + err.Error())
+		exitWithError(err)
+	}
+
+	printVars(vars)
+}
+
+// getScopedEnvVars fetches the environment variables of this workspace. If --scope is given, only
+// variables with exactly that repository pattern are returned.
+func getScopedEnvVars(ctx context.Context, conn *connectToServerResult) ([]*serverapi.UserEnvVarValue, error) {
+	if !conn.scoped {
+		vars, err := conn.client.GetEnvVars(ctx)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to fetch env vars from server: %w", err)
+		}
+		return vars, nil
 	}
 
+	// getEnvVars returns one variable per name only, which need not be the one of the scope
+	vars, err := conn.client.GetAllEnvVars(ctx)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to fetch env vars from server: %w", err)
+	}
+	var res []*serverapi.UserEnvVarValue
 	for _, v := range vars {
-		printVar(v, exportEnvs)
+		if strings.EqualFold(v.RepositoryPattern, conn.repositoryPattern) {
+			res = append(res, v)
+		}
 	}
+	return res, nil
 }
 
 func setEnvs(args []string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer cancel()
-	result, err := connectToServer(ctx)
+	result, err := connectToServer(ctx, envScope, true)
 	if err != nil {
-		fail(err.Error())
+		exitWithError(err)
 	}
 
 	vars, err := parseArgs(args, result.repositoryPattern)
 	if err != nil {
-		fail(err.Error())
+		exitWithError(err)
+	}
+	if dryRunEnvs {
+		current, err := getScopedEnvVars(ctx, result)
+		if err != nil {
+			exitWithError(err)
+		}
+		printEnvVarChanges(diffEnvVars(current, vars, nil, result.repositoryPattern))
+		return
 	}
 
-	var exitCode int
-	var wg sync.WaitGroup
+	var (
+		errs = make([]error, len(vars))
+		wg   sync.WaitGroup
+	)
 	wg.Add(len(vars))
-	for _, v := range vars {
-		go func(v *serverapi.UserEnvVarValue) {
-			err = result.client.SetEnvVar(ctx, v)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "cannot set %s: %v\n", v.Name, err)
-				exitCode = -1
-			} else {
-				printVar(v, exportEnvs)
-			}
+	for i, v := range vars {
+		go func(i int, v *serverapi.UserEnvVarValue) {
+			errs[i] = result.client.SetEnvVar(ctx, v)
 			wg.Done()
-		}(v)
+		}(i, v)
 	}
 	wg.Wait()
-	os.Exit(exitCode)
+
+	var set []*serverapi.UserEnvVarValue
+	for i, v := range vars {
+		if errs[i] != nil {
+			fmt.Fprintf(os.Stderr, "cannot set %s: %v\n", v.Name, errs[i])
+			continue
+		}
+		set = append(set, v)
+	}
+	printVars(set)
+	if len(set) != len(vars) {
+		os.Exit(output.ExitCodeError)
+	}
 }
 
 func deleteEnvs(args []string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
 	defer cancel()
-	result, err := connectToServer(ctx)
+	result, err := connectToServer(ctx, envScope, true)
 	if err != nil {
-		fail(err.Error())
+		exitWithError(err)
+	}
+	if dryRunEnvs {
+		current, err := getScopedEnvVars(ctx, result)
+		if err != nil {
+			exitWithError(err)
+		}
+		printEnvVarChanges(diffEnvVars(current, nil, args, result.repositoryPattern))
+		return
 	}
 
-	var exitCode int
-	var wg sync.WaitGroup
+	var (
+		exitCode int
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+	)
 	wg.Add(len(args))
 	for _, name := range args {
 		go func(name string) {
-			err = result.client.DeleteEnvVar(ctx, &serverapi.UserEnvVarValue{Name: name, RepositoryPattern: result.repositoryPattern})
+			err := result.client.DeleteEnvVar(ctx, &serverapi.UserEnvVarValue{Name: name, RepositoryPattern: result.repositoryPattern})
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "cannot unset %s: %v\n", name, err)
-				exitCode = -1
+				mu.Lock()
+				exitCode = output.ExitCodeError
+				mu.Unlock()
 			}
 			wg.Done()
 		}(name)
@@ -197,18 +283,203 @@ func deleteEnvs(args []string) {
 	os.Exit(exitCode)
 }
 
-func fail(msg string) {
-	fmt.Fprintln(os.Stderr, msg)
-	os.Exit(-1)
+// envVarOutput is how gp env prints a variable in the JSON and YAML formats
+type envVarOutput struct {
+	Name              string `json:"name"`
+	Value             string `json:"value"`
+	RepositoryPattern string `json:"repositoryPattern"`
+}
+
+func newEnvVarOutputs(vars []*serverapi.UserEnvVarValue) []envVarOutput {
+	res := make([]envVarOutput, 0, len(vars))
+	for _, v := range vars {
+		res = append(res, envVarOutput{Name: v.Name, Value: v.Value, RepositoryPattern: v.RepositoryPattern})
+	}
+	return res
+}
+
+// printVars prints variables in the format selected using --output, masking their values unless they are revealed.
+func printVars(vars []*serverapi.UserEnvVarValue) {
+	displayed := make([]*serverapi.UserEnvVarValue, 0, len(vars))
+	for _, v := range vars {
+		displayed = append(displayed, displayedVar(v))
+	}
+	printOutput(newEnvVarOutputs(displayed), func(w io.Writer) error {
+		for _, v := range displayed {
+			printVar(w, v, exportEnvs)
+		}
+		return nil
+	})
 }
 
-func printVar(v *serverapi.UserEnvVarValue, export bool) {
+func printVar(w io.Writer, v *serverapi.UserEnvVarValue, export bool) {
 	val := strings.Replace(v.Value, "\"", "\\\"", -1)
 	if export {
-		fmt.Printf("export %s=\"%s\"\n", v.Name, val)
+		fmt.Fprintf(w, "export %s=\"%s\"\n", v.Name, val)
 	} else {
-		fmt.Printf("%s=%s\n", v.Name, val)
+		fmt.Fprintf(w, "%s=%s\n", v.Name, val)
+	}
+}
+
+// displayedVar returns v with its value masked, unless values are revealed or we produce a script.
+func displayedVar(v *serverapi.UserEnvVarValue) *serverapi.UserEnvVarValue {
+	if revealEnvs || exportEnvs {
+		return v
+	}
+	return &serverapi.UserEnvVarValue{Name: v.Name, RepositoryPattern: v.RepositoryPattern, Value: maskedEnvValue}
+}
+
+var (
+	scopeSegmentPattern = regexp.MustCompile(`^[a-zA-Z0-9_\-.*]+$`)
+	envVarNamePattern   = regexp.MustCompile(`^[a-zA-Z_]+[a-zA-Z0-9_]*$`)
+)
+
+// parseScope turns a scope given as owner/repo, owner/*, */repo or * into a repository pattern.
+func parseScope(scope string) (string, error) {
+	if scope == "*" {
+		return "*/*", nil
+	}
+	segments := strings.Split(scope, "/")
+	if len(segments) != 2 {
+		return "", xerrors.Errorf("invalid scope %q (correct format is owner/repo, owner/*, */repo or *)", scope)
+	}
+	for _, s := range segments {
+		if !scopeSegmentPattern.MatchString(s) {
+			return "", xerrors.Errorf("invalid scope %q: only ASCII characters, numbers, -, _, . or * are allowed", scope)
+		}
+	}
+	return strings.ToLower(scope), nil
+}
+
+// checkScope checks that the workspace token, which grants access to the variables of the workspace's repository pattern,
+// allows to read or modify variables with the given repository pattern. Variables of wider patterns which apply to the
+// workspace can be read, but only variables of the workspace's own repository can be modified.
+func checkScope(pattern, workspacePattern string, modify bool) error {
+	pattern, workspacePattern = strings.ToLower(pattern), strings.ToLower(workspacePattern)
+	if pattern == workspacePattern {
+		return nil
+	}
+	if modify {
+		return xerrors.Errorf("cannot modify variables of scope %s: only variables of this workspace's repository %s can be modified, use the Gitpod dashboard for other scopes", pattern, workspacePattern)
+	}
+	segments := strings.SplitN(workspacePattern, "/", 2)
+	owner, repo := segments[0], segments[1]
+	switch pattern {
+	case "*/*", owner + "/*", "*/" + repo:
+		return nil
+	default:
+		return xerrors.Errorf("cannot read variables of scope %s: only scopes which apply to this workspace's repository %s can be read", pattern, workspacePattern)
+	}
+}
+
+type envVarChangeKind int
+
+const (
+	envVarAdded envVarChangeKind = iota
+	envVarUpdated
+	envVarDeleted
+)
+
+// envVarChange is a change to an environment variable in a scope
+type envVarChange struct {
+	Kind     envVarChangeKind
+	Name     string
+	OldValue string
+	NewValue string
+}
+
+// diffEnvVars computes the changes needed to set the variables in set and to delete the variables named in unset.
+// Only variables of current with the given repository pattern are considered. Variables that are set to their
+// current value and unknown variables that are to be deleted yield no change.
+func diffEnvVars(current, set []*serverapi.UserEnvVarValue, unset []string, repositoryPattern string) []envVarChange {
+	values := make(map[string]string, len(current))
+	for _, v := range current {
+		if strings.EqualFold(v.RepositoryPattern, repositoryPattern) {
+			values[v.Name] = v.Value
+		}
+	}
+
+	var res []envVarChange
+	for _, v := range set {
+		old, exists := values[v.Name]
+		switch {
+		case !exists:
+			res = append(res, envVarChange{Kind: envVarAdded, Name: v.Name, NewValue: v.Value})
+		case old != v.Value:
+			res = append(res, envVarChange{Kind: envVarUpdated, Name: v.Name, OldValue: old, NewValue: v.Value})
+		}
+		values[v.Name] = v.Value
+	}
+	for _, name := range unset {
+		old, exists := values[name]
+		if !exists {
+			continue
+		}
+		res = append(res, envVarChange{Kind: envVarDeleted, Name: name, OldValue: old})
+		delete(values, name)
+	}
+	return res
+}
+
+func formatEnvVarChange(c envVarChange, reveal bool) string {
+	oldValue, newValue := c.OldValue, c.NewValue
+	if !reveal {
+		oldValue, newValue = maskedEnvValue, maskedEnvValue
+	}
+	switch c.Kind {
+	case envVarAdded:
+		return fmt.Sprintf("+ %s=%s", c.Name, newValue)
+	case envVarUpdated:
+		return fmt.Sprintf("~ %s=%s -> %s", c.Name, oldValue, newValue)
+	default:
+		return fmt.Sprintf("- %s=%s", c.Name, oldValue)
+	}
+}
+
+// envVarChangeOutput is how gp env prints a change to a variable in the JSON and YAML formats
+type envVarChangeOutput struct {
+	Change   string `json:"change"`
+	Name     string `json:"name"`
+	OldValue string `json:"oldValue,omitempty"`
+	NewValue string `json:"newValue,omitempty"`
+}
+
+func newEnvVarChangeOutputs(changes []envVarChange, reveal bool) []envVarChangeOutput {
+	res := make([]envVarChangeOutput, 0, len(changes))
+	for _, c := range changes {
+		out := envVarChangeOutput{Name: c.Name, OldValue: c.OldValue, NewValue: c.NewValue}
+		switch c.Kind {
+		case envVarAdded:
+			out.Change = "added"
+		case envVarUpdated:
+			out.Change = "updated"
+		default:
+			out.Change = "deleted"
+		}
+		if !reveal {
+			if out.OldValue != "" {
+				out.OldValue = maskedEnvValue
+			}
+			if out.NewValue != "" {
+				out.NewValue = maskedEnvValue
+			}
+		}
+		res = append(res, out)
 	}
+	return res
+}
+
+func printEnvVarChanges(changes []envVarChange) {
+	printOutput(newEnvVarChangeOutputs(changes, revealEnvs), func(w io.Writer) error {
+		if len(changes) == 0 {
+			fmt.Fprintln(w, "no changes")
+			return nil
+		}
+		for _, c := range changes {
+			fmt.Fprintln(w, formatEnvVarChange(c, revealEnvs))
+		}
+		return nil
+	})
 }
 
 func parseArgs(args []string, pattern string) ([]*serverapi.UserEnvVarValue, error) {
@@ -253,4 +524,7 @@ func init() {
 
 	envCmd.Flags().BoolVarP(&exportEnvs, "export", "e", false, "produce a script that can be eval'ed in Bash")
 	envCmd.Flags().BoolVarP(&unsetEnvs, "unset", "u", false, "deletes/unsets persisted environment variables")
+	envCmd.PersistentFlags().StringVar(&envScope, "scope", "", "repository pattern of the variables: owner/repo, owner/*, */repo or * (defaults to the repository of this workspace, other patterns can only be read)")
+	envCmd.PersistentFlags().BoolVar(&revealEnvs, "reveal", false, "print the values of variables instead of masking them")
+	envCmd.PersistentFlags().BoolVar(&dryRunEnvs, "dry-run", false, "print the changes to the variables without applying them")
 }
@@ -5,8 +5,11 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"testing"
 
+	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
 
 	serverapi "github.com/gitpod-io/gitpod/gitpod-protocol"
@@ -82,3 +85,241 @@ func TestParseArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestParseScope(t *testing.T) {
+	tests := []struct {
+		Scope       string
+		Expectation string
+		Error       bool
+	}{
+		{Scope: "gitpod-io/gitpod", Expectation: "gitpod-io/gitpod"},
+		{Scope: "Gitpod-IO/*", Expectation: "gitpod-io/*"},
+		{Scope: "*/gitpod", Expectation: "*/gitpod"},
+		{Scope: "*", Expectation: "*/*"},
+		{Scope: "gitpod-io", Error: true},
+		{Scope: "gitpod-io/gitpod/foo", Error: true},
+		{Scope: "gitpod io/gitpod", Error: true},
+		{Scope: "gitpod-io/", Error: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Scope, func(t *testing.T) {
+			act, err := parseScope(test.Scope)
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if act != test.Expectation {
+				t.Errorf("unexpected pattern: %q, expected %q", act, test.Expectation)
+			}
+		})
+	}
+}
+
+func TestCheckScope(t *testing.T) {
+	tests := []struct {
+		Pattern string
+		Modify  bool
+		Error   bool
+	}{
+		{Pattern: "gitpod-io/gitpod"},
+		{Pattern: "gitpod-io/gitpod", Modify: true},
+		{Pattern: "gitpod-io/*"},
+		{Pattern: "*/gitpod"},
+		{Pattern: "*/*"},
+		{Pattern: "gitpod-io/*", Modify: true, Error: true},
+		{Pattern: "*/*", Modify: true, Error: true},
+		{Pattern: "gitpod-io/website", Error: true},
+		{Pattern: "foo/*", Error: true},
+		{Pattern: "*/website", Error: true},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s modify=%v", test.Pattern, test.Modify), func(t *testing.T) {
+			err := checkScope(test.Pattern, "Gitpod-IO/gitpod", test.Modify)
+			if (err != nil) != test.Error {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDiffEnvVars(t *testing.T) {
+	current := []*serverapi.UserEnvVarValue{
+		{Name: "FOO", Value: "foo", RepositoryPattern: "gitpod-io/gitpod"},
+		{Name: "BAR", Value: "bar", RepositoryPattern: "gitpod-io/gitpod"},
+		{Name: "BAZ", Value: "baz", RepositoryPattern: "gitpod-io/*"},
+	}
+	set := []*serverapi.UserEnvVarValue{
+		{Name: "FOO", Value: "foo"},
+		{Name: "BAR", Value: "new bar"},
+		{Name: "BAZ", Value: "baz"},
+	}
+	act := diffEnvVars(current, set, []string{"FOO", "UNKNOWN"}, "gitpod-io/gitpod")
+	expectation := []envVarChange{
+		{Kind: envVarUpdated, Name: "BAR", OldValue: "bar", NewValue: "new bar"},
+		{Kind: envVarAdded, Name: "BAZ", NewValue: "baz"},
+		{Kind: envVarDeleted, Name: "FOO", OldValue: "foo"},
+	}
+	if diff := cmp.Diff(expectation, act); diff != "" {
+		t.Errorf("unexpected changes (-want +got):\n%s", diff)
+	}
+
+	var lines []string
+	for _, c := range act {
+		lines = append(lines, formatEnvVarChange(c, false))
+	}
+	if diff := cmp.Diff([]string{"~ BAR=******** -> ********", "+ BAZ=********", "- FOO=********"}, lines); diff != "" {
+		t.Errorf("unexpected masked changes (-want +got):\n%s", diff)
+	}
+	if act := formatEnvVarChange(act[0], true); act != "~ BAR=bar -> new bar" {
+		t.Errorf("unexpected revealed change: %q", act)
+	}
+}
+
+func TestGetScopedEnvVars(t *testing.T) {
+	// the same name at the scope of the workspace repository and at the one of its owner
+	all := []*serverapi.UserEnvVarValue{
+		{Name: "FOO", Value: "repo", RepositoryPattern: "gitpod-io/gitpod"},
+		{Name: "FOO", Value: "owner", RepositoryPattern: "gitpod-io/*"},
+		{Name: "BAR", Value: "repo", RepositoryPattern: "gitpod-io/gitpod"},
+	}
+	tests := []struct {
+		Desc        string
+		Conn        connectToServerResult
+		Expectation []*serverapi.UserEnvVarValue
+	}{
+		{
+			Desc:        "workspace repository",
+			Conn:        connectToServerResult{repositoryPattern: "gitpod-io/gitpod"},
+			Expectation: []*serverapi.UserEnvVarValue{all[0], all[2]},
+		},
+		{
+			Desc:        "scope of the repository",
+			Conn:        connectToServerResult{repositoryPattern: "gitpod-io/gitpod", scoped: true},
+			Expectation: []*serverapi.UserEnvVarValue{all[0], all[2]},
+		},
+		{
+			Desc:        "scope of the owner",
+			Conn:        connectToServerResult{repositoryPattern: "gitpod-io/*", scoped: true},
+			Expectation: []*serverapi.UserEnvVarValue{all[1]},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			client := serverapi.NewMockAPIInterface(ctrl)
+			if test.Conn.scoped {
+				client.EXPECT().GetAllEnvVars(gomock.Any()).Return(all, nil)
+			} else {
+				// the server picks the variable of the most specific pattern per name
+				client.EXPECT().GetEnvVars(gomock.Any()).Return([]*serverapi.UserEnvVarValue{all[0], all[2]}, nil)
+			}
+			test.Conn.client = client
+
+			act, err := getScopedEnvVars(context.Background(), &test.Conn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Expectation, act); diff != "" {
+				t.Errorf("unexpected env vars (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Content     string
+		Expectation []*serverapi.UserEnvVarValue
+		Error       bool
+	}{
+		{
+			Desc: "values",
+			Content: `# comment
+FOO=foo
+export BAR = bar baz # comment
+
+SINGLE='single $quoted # value'
+DOUBLE="double \"quoted\"\tvalue" # comment
+MULTI="first
+second"
+FOO=last
+`,
+			Expectation: []*serverapi.UserEnvVarValue{
+				{Name: "FOO", Value: "last", RepositoryPattern: "foo/bar"},
+				{Name: "BAR", Value: "bar baz", RepositoryPattern: "foo/bar"},
+				{Name: "SINGLE", Value: "single $quoted # value", RepositoryPattern: "foo/bar"},
+				{Name: "DOUBLE", Value: "double \"quoted\"\tvalue", RepositoryPattern: "foo/bar"},
+				{Name: "MULTI", Value: "first\nsecond", RepositoryPattern: "foo/bar"},
+			},
+		},
+		{Desc: "windows line endings", Content: "FOO=foo\r\nBAR=bar\r\n", Expectation: []*serverapi.UserEnvVarValue{
+			{Name: "FOO", Value: "foo", RepositoryPattern: "foo/bar"},
+			{Name: "BAR", Value: "bar", RepositoryPattern: "foo/bar"},
+		}},
+		{Desc: "no equal character", Content: "FOO", Error: true},
+		{Desc: "invalid name", Content: "FOO-BAR=foo", Error: true},
+		{Desc: "empty value", Content: "FOO=", Error: true},
+		{Desc: "unterminated double quote", Content: "FOO=\"foo\nBAR=bar", Error: true},
+		{Desc: "unterminated single quote", Content: "FOO='foo", Error: true},
+		{Desc: "content after quote", Content: `FOO="foo" bar`, Error: true},
+		{Desc: "masked value", Content: "FOO=\"********\"", Error: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			act, err := parseDotenv([]byte(test.Content), "foo/bar")
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.Expectation, act); diff != "" {
+				t.Errorf("unexpected variables (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatEnvVars(t *testing.T) {
+	vars := []*serverapi.UserEnvVarValue{
+		{Name: "FOO", Value: "foo", RepositoryPattern: "foo/bar"},
+		{Name: "BAR", Value: "a \"quoted\"\n$value\\", RepositoryPattern: "foo/*"},
+	}
+
+	dotenv, err := formatEnvVars(vars, "dotenv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("FOO=\"foo\"\nBAR=\"a \\\"quoted\\\"\\n\\$value\\\\\"\n", dotenv); diff != "" {
+		t.Errorf("unexpected dotenv (-want +got):\n%s", diff)
+	}
+	parsed, err := parseDotenv([]byte(dotenv), "foo/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range parsed {
+		if v.Value != vars[i].Value {
+			t.Errorf("value of %s does not round-trip: %q, expected %q", v.Name, v.Value, vars[i].Value)
+		}
+	}
+
+	js, err := formatEnvVars(vars[:1], "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectation := `[
+  {
+    "name": "FOO",
+    "value": "foo",
+    "repositoryPattern": "foo/bar"
+  }
+]
+`
+	if diff := cmp.Diff(expectation, js); diff != "" {
+		t.Errorf("unexpected JSON (-want +got):\n%s", diff)
+	}
+
+	_, err = formatEnvVars(vars, "yaml")
+	if err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -6,16 +6,15 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"os"
 	"strconv"
 
 	"github.com/google/tcpproxy"
 	"github.com/gorilla/handlers"
 	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
 )
 
 var rewriteHostHeader bool
@@ -28,9 +27,7 @@ var portFwdCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		srcp, err := strconv.ParseUint(args[0], 10, 16)
 		if err != nil {
-			log.Fatalf("local-port cannot be parsed as int: %s", err)
-			os.Exit(-1)
-			return
+			fail(fmt.Sprintf("local-port cannot be parsed as int: %s", err))
 		}
 
 		trgp := srcp + 1
@@ -38,9 +35,7 @@ var portFwdCmd = &cobra.Command{
 			var err error
 			trgp, err = strconv.ParseUint(args[1], 10, 16)
 			if err != nil {
-				log.Fatalf("target-port cannot be parsed as int: %s", err)
-				os.Exit(-1)
-				return
+				fail(fmt.Sprintf("target-port cannot be parsed as int: %s", err))
 			}
 		}
 
@@ -59,7 +54,7 @@ var portFwdCmd = &cobra.Command{
 			fmt.Printf("Proxying HTTP traffic: 0.0.0.0:%d -> 127.0.0.1:%d (with host rewriting)\n", trgp, srcp)
 			err = http.ListenAndServe(fmt.Sprintf(":%d", trgp), nil)
 			if err != nil {
-				log.Fatalf("reverse proxy: %s", err)
+				exitWithError(xerrors.Errorf("reverse proxy: %w", err))
 			}
 			return
 		}
@@ -67,7 +62,10 @@ var portFwdCmd = &cobra.Command{
 		var p tcpproxy.Proxy
 		p.AddRoute(fmt.Sprintf(":%d", trgp), tcpproxy.To(fmt.Sprintf("127.0.0.1:%d", srcp)))
 		fmt.Printf("Forwarding traffic: 0.0.0.0:%d -> 127.0.0.1:%d\n", trgp, srcp)
-		log.Fatal(p.Run())
+		err = p.Run()
+		if err != nil {
+			exitWithError(err)
+		}
 	},
 }
 
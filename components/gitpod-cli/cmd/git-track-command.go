@@ -41,7 +41,7 @@ var gitTrackCommand = &cobra.Command{
 		defer cancel()
 		wsInfo, err := gitpod.GetWSInfo(ctx)
 		if err != nil {
-			fail(err.Error())
+			exitWithError(err)
 		}
 
 		client, err := gitpod.ConnectToServer(ctx, wsInfo, []string{"function:trackEvent"})
@@ -7,7 +7,6 @@ package cmd
 import (
 	"encoding/base64"
 	"io"
-	"log"
 	"os"
 	"syscall"
 
@@ -36,26 +35,26 @@ var gitpodRunCmd = &cobra.Command{
 		}
 		if err != nil {
 			_ = os.Remove(tmpfile.Name())
-			log.Fatal(err)
+			exitWithError(err)
 		}
 
 		decoder := base64.NewDecoder(base64.RawStdEncoding, &delimitingReader{os.Stdin, false})
 		_, err = io.Copy(tmpfile, decoder)
 		if err != nil {
 			_ = os.Remove(tmpfile.Name())
-			log.Fatal(err)
+			exitWithError(err)
 		}
 		tmpfile.Close()
 
 		err = os.Chmod(tmpfile.Name(), 0700)
 		if err != nil {
 			_ = os.Remove(tmpfile.Name())
-			log.Fatal(err)
+			exitWithError(err)
 		}
 		err = syscall.Exec(tmpfile.Name(), []string{"gpr", "serve"}, []string{})
 		if err != nil {
 			_ = os.Remove(tmpfile.Name())
-			log.Fatal(err)
+			exitWithError(err)
 		}
 	},
 }
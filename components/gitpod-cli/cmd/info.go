@@ -0,0 +1,151 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+
+	gitpod "github.com/gitpod-io/gitpod/gitpod-cli/pkg/gitpod"
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
+)
+
+var infoCmdOpts struct {
+	JSON bool
+}
+
+// workspaceInfo is what gp info prints. Fields which could not be determined are left empty.
+type workspaceInfo struct {
+	WorkspaceID       string `json:"workspaceId"`
+	InstanceID        string `json:"instanceId"`
+	Class             string `json:"class,omitempty"`
+	URL               string `json:"url"`
+	Timeout           string `json:"timeout,omitempty"`
+	RemainingLifetime string `json:"remainingLifetime,omitempty"`
+	Owner             string `json:"owner,omitempty"`
+}
+
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Prints information about this workspace",
+	Long: `Prints the ID, instance ID, class, URL, inactivity timeout, remaining lifetime and owner of this workspace.
+The remaining lifetime is the time until the workspace is stopped regardless of activity.`,
+	Args: cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		if infoCmdOpts.JSON {
+			rootOpts.Output = output.JSON
+		}
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+		wsInfo, err := gitpod.GetWSInfo(ctx)
+		if err != nil {
+			exitWithError(err)
+		}
+		info := workspaceInfo{
+			WorkspaceID: wsInfo.WorkspaceId,
+			InstanceID:  wsInfo.InstanceId,
+			Class:       os.Getenv("GITPOD_WORKSPACE_CLASS"),
+			URL:         wsInfo.WorkspaceUrl,
+		}
+
+		ownerID := os.Getenv("GITPOD_OWNER_ID")
+		client, err := gitpod.ConnectToServer(ctx, wsInfo, []string{
+			"function:getWorkspace",
+			"function:getWorkspaceOwner",
+			"function:getWorkspaceTimeout",
+			"resource:workspace::" + wsInfo.WorkspaceId + "::get",
+			"resource:workspaceInstance::" + wsInfo.InstanceId + "::get",
+			"resource:user::" + ownerID + "::get",
+		})
+		if err != nil {
+			exitWithError(err)
+		}
+		defer client.Close()
+
+		if timeout, err := client.GetWorkspaceTimeout(ctx, wsInfo.WorkspaceId); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot get the workspace timeout: %v\n", err)
+		} else {
+			info.Timeout = formatTimeoutDuration(timeout.Duration)
+		}
+
+		if ws, err := client.GetWorkspace(ctx, wsInfo.WorkspaceId); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot get the workspace: %v\n", err)
+		} else if ws.LatestInstance != nil {
+			remaining, err := remainingLifetime(ws.LatestInstance.StartedTime, os.Getenv("GITPOD_WORKSPACE_MAX_LIFETIME"), time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "cannot compute the remaining lifetime: %v\n", err)
+			} else {
+				info.RemainingLifetime = remaining.String()
+			}
+		}
+
+		if owner, err := client.GetWorkspaceOwner(ctx, wsInfo.WorkspaceId); err != nil {
+			fmt.Fprintf(os.Stderr, "cannot get the workspace owner: %v\n", err)
+		} else if owner != nil {
+			info.Owner = owner.Name
+		}
+
+		printOutput(&info, func(w io.Writer) error {
+			return printWorkspaceInfo(w, &info)
+		})
+	},
+}
+
+// remainingLifetime computes the time left until a workspace instance started at startedTime (RFC3339)
+// reaches its maximum lifetime. The result is rounded to seconds and never negative.
+func remainingLifetime(startedTime string, maxLifetime string, now time.Time) (time.Duration, error) {
+	if startedTime == "" {
+		return 0, xerrors.Errorf("the workspace has not started yet")
+	}
+	if maxLifetime == "" {
+		return 0, xerrors.Errorf("the maximum lifetime is unknown")
+	}
+	started, err := time.Parse(time.RFC3339, startedTime)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid start time %q: %w", startedTime, err)
+	}
+	lifetime, err := time.ParseDuration(maxLifetime)
+	if err != nil {
+		return 0, xerrors.Errorf("invalid maximum lifetime %q: %w", maxLifetime, err)
+	}
+	remaining := started.Add(lifetime).Sub(now).Round(time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, nil
+}
+
+func printWorkspaceInfo(out io.Writer, info *workspaceInfo) error {
+	orUnknown := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Workspace ID:\t%s\n", info.WorkspaceID)
+	fmt.Fprintf(w, "Instance ID:\t%s\n", info.InstanceID)
+	fmt.Fprintf(w, "Class:\t%s\n", orUnknown(info.Class))
+	fmt.Fprintf(w, "URL:\t%s\n", info.URL)
+	fmt.Fprintf(w, "Timeout:\t%s\n", orUnknown(info.Timeout))
+	fmt.Fprintf(w, "Remaining lifetime:\t%s\n", orUnknown(info.RemainingLifetime))
+	fmt.Fprintf(w, "Owner:\t%s\n", orUnknown(info.Owner))
+	return w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+
+	infoCmd.Flags().BoolVar(&infoCmdOpts.JSON, "json", false, "print the information as JSON (same as --output json)")
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRemainingLifetime(t *testing.T) {
+	now := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		Desc        string
+		StartedTime string
+		MaxLifetime string
+		Expectation time.Duration
+		Error       bool
+	}{
+		{"running", "2022-03-01T09:00:00.000Z", "36h0m0s", 35 * time.Hour, false},
+		{"rounded to seconds", "2022-03-01T09:59:59.400Z", "1m0s", 59 * time.Second, false},
+		{"exceeded", "2022-02-27T09:00:00Z", "36h0m0s", 0, false},
+		{"not started", "", "36h0m0s", 0, true},
+		{"unknown max lifetime", "2022-03-01T09:00:00Z", "", 0, true},
+		{"invalid start time", "yesterday", "36h0m0s", 0, true},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			act, err := remainingLifetime(test.StartedTime, test.MaxLifetime, now)
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if act != test.Expectation {
+				t.Errorf("unexpected remaining lifetime: %s, expected %s", act, test.Expectation)
+			}
+		})
+	}
+}
+
+func TestPrintWorkspaceInfo(t *testing.T) {
+	info := &workspaceInfo{
+		WorkspaceID:       "gitpodio-gitpod-abc123",
+		InstanceID:        "a4f3c1b2",
+		URL:               "https://gitpodio-gitpod-abc123.ws-eu.gitpod.io",
+		Timeout:           "30m",
+		RemainingLifetime: "35h0m0s",
+		Owner:             "jane",
+	}
+	expectation := `Workspace ID:        gitpodio-gitpod-abc123
+Instance ID:         a4f3c1b2
+Class:               unknown
+URL:                 https://gitpodio-gitpod-abc123.ws-eu.gitpod.io
+Timeout:             30m
+Remaining lifetime:  35h0m0s
+Owner:               jane
+`
+
+	var out bytes.Buffer
+	err := printWorkspaceInfo(&out, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expectation, out.String()); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+}
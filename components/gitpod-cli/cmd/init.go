@@ -7,7 +7,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -29,41 +29,43 @@ var initCmd = &cobra.Command{
 	Short: "Create a Gitpod configuration for this project.",
 	Long: `
 Create a Gitpod configuration for this project.
+
+The project is inspected for Go, Node.js, Java, Python, Rust and Docker Compose
+setups, and tasks, ports and a workspace image are suggested for them. With
+--interactive each suggestion can be accepted, edited or skipped.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
-		cfg := gitpodlib.GitpodFile{}
+		stacks := detectStacks(".", os.Stderr)
+
+		var (
+			cfg gitpodlib.GitpodFile
+			d   []byte
+			err error
+		)
 		if interactive {
-			if err := askForDockerImage(&cfg); err != nil {
-				log.Fatal(err)
+			stacks, err = askForStacks(&cfg, stacks)
+			if err != nil {
+				exitWithError(err)
+			}
+			if err := askForDockerImage(&cfg, gitpodlib.SuggestImage(stacks)); err != nil {
+				exitWithError(err)
 			}
-			if err := askForPorts(&cfg); err != nil {
-				log.Fatal(err)
+			if err := askForPorts(&cfg, stackPorts(stacks)); err != nil {
+				exitWithError(err)
 			}
 			if err := askForTask(&cfg); err != nil {
-				log.Fatal(err)
+				exitWithError(err)
+			}
+			d, err = yaml.Marshal(cfg)
+			if err != nil {
+				exitWithError(err)
 			}
-		} else {
-			cfg.AddPort(3000)
-			cfg.AddTask("echo 'start script'", "echo 'init script'")
-		}
-
-		d, err := yaml.Marshal(cfg)
-		if err != nil {
-			log.Fatal(err)
-		}
-		if !interactive {
-			d = []byte(`# List the start up tasks. Learn more https://www.gitpod.io/docs/config-start-tasks/
-tasks:
-  - init: echo 'init script' # runs during prebuild
-    command: echo 'start script'
-
-# List the ports to expose. Learn more https://www.gitpod.io/docs/config-ports/
-ports:
-  - port: 3000
-    onOpen: open-preview
-`)
-		} else {
 			fmt.Printf("\n\n---\n%s", d)
+		} else {
+			cfg, d, err = generateGitpodConfig(stacks)
+			if err != nil {
+				exitWithError(err)
+			}
 		}
 
 		if _, err := os.Stat(".gitpod.yml"); err == nil {
@@ -79,7 +81,7 @@ ports:
 		}
 
 		if err := os.WriteFile(".gitpod.yml", d, 0644); err != nil {
-			log.Fatal(err)
+			exitWithError(err)
 		}
 
 		// open .gitpod.yml and Dockerfile
@@ -98,7 +100,7 @@ USER gitpod
 #
 # More information: https://www.gitpod.io/docs/config-docker/
 `), 0644); err != nil {
-					log.Fatal(err)
+					exitWithError(err)
 				}
 			}
 
@@ -108,6 +110,61 @@ USER gitpod
 	},
 }
 
+// defaultGitpodConfig is the .gitpod.yml we suggest if we cannot tell anything about the project
+const defaultGitpodConfig = `# List the start up tasks. Learn more https://www.gitpod.io/docs/config-start-tasks/
+tasks:
+  - init: echo 'init script' # runs during prebuild
+    command: echo 'start script'
+
+# List the ports to expose. Learn more https://www.gitpod.io/docs/config-ports/
+ports:
+  - port: 3000
+    onOpen: open-preview
+`
+
+// detectStacks returns the stacks of the project in dir. Failing to inspect the project, e.g. because
+// of a broken package.json, is no reason to not create a configuration, hence we only warn about it.
+func detectStacks(dir string, warn io.Writer) []*gitpodlib.Stack {
+	stacks, err := gitpodlib.DetectStacks(dir)
+	if err != nil {
+		fmt.Fprintf(warn, "cannot detect the project setup, using the default configuration: %v\n", err)
+		return nil
+	}
+	return stacks
+}
+
+// generateGitpodConfig produces the .gitpod.yml for the stacks, or the default one if there are none
+func generateGitpodConfig(stacks []*gitpodlib.Stack) (cfg gitpodlib.GitpodFile, content []byte, err error) {
+	if len(stacks) == 0 {
+		return cfg, []byte(defaultGitpodConfig), nil
+	}
+
+	for _, s := range stacks {
+		for _, t := range s.Tasks {
+			cfg.AddStackTask(t)
+		}
+	}
+	for _, p := range stackPorts(stacks) {
+		cfg.AddPort(p)
+	}
+	if img := gitpodlib.SuggestImage(stacks); img != "" {
+		cfg.SetImageName(img)
+	}
+
+	content, err = yaml.Marshal(cfg)
+	if err != nil {
+		return cfg, nil, err
+	}
+	names := make([]string, 0, len(stacks))
+	for _, s := range stacks {
+		names = append(names, s.Name)
+	}
+	content = append([]byte(fmt.Sprintf(`# Generated for %s. Learn more about tasks and ports at
+# https://www.gitpod.io/docs/config-start-tasks/ and https://www.gitpod.io/docs/config-ports/
+`, strings.Join(names, ", "))), content...)
+	return cfg, content, nil
+}
+
 func isRequired(input string) error {
 	if input == "" {
 		return errors.New("Cannot be empty")
@@ -128,10 +185,14 @@ func ask(lbl string, def string, validator promptui.ValidateFunc) (string, error
 	return prompt.Run()
 }
 
-func askForDockerImage(cfg *gitpodlib.GitpodFile) error {
+func askForDockerImage(cfg *gitpodlib.GitpodFile, suggestion string) error {
+	items := []string{"default", "custom image", "docker file"}
+	if suggestion != "" {
+		items = append([]string{suggestion}, items...)
+	}
 	prompt := promptui.Select{
 		Label: "Workspace Docker image",
-		Items: []string{"default", "custom image", "docker file"},
+		Items: items,
 		Templates: &promptui.SelectTemplates{
 			Selected: "Workspace Image: {{ . }}",
 		},
@@ -140,6 +201,13 @@ func askForDockerImage(cfg *gitpodlib.GitpodFile) error {
 	if err != nil {
 		return err
 	}
+	if suggestion != "" {
+		if chce == 0 {
+			cfg.SetImageName(suggestion)
+			return nil
+		}
+		chce--
+	}
 
 	if chce == 0 {
 		return nil
@@ -185,8 +253,12 @@ func parsePorts(input string) ([]int32, error) {
 	return rst, nil
 }
 
-func askForPorts(cfg *gitpodlib.GitpodFile) error {
-	input, err := ask("Expose Ports (comma separated)", "", func(input string) error {
+func askForPorts(cfg *gitpodlib.GitpodFile, suggestion []int32) error {
+	def := make([]string, 0, len(suggestion))
+	for _, p := range suggestion {
+		def = append(def, strconv.Itoa(int(p)))
+	}
+	input, err := ask("Expose Ports (comma separated)", strings.Join(def, ","), func(input string) error {
 		if _, err := parsePorts(input); err != nil {
 			return err
 		}
@@ -207,8 +279,76 @@ func askForPorts(cfg *gitpodlib.GitpodFile) error {
 	return nil
 }
 
+// stackPorts returns the ports of all stacks without duplicates
+func stackPorts(stacks []*gitpodlib.Stack) []int32 {
+	var (
+		res  []int32
+		seen = make(map[int32]bool)
+	)
+	for _, s := range stacks {
+		for _, p := range s.Ports {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// askForStacks lets the user accept, edit or skip the tasks suggested for each detected stack
+// and returns the stacks which were not skipped
+func askForStacks(cfg *gitpodlib.GitpodFile, stacks []*gitpodlib.Stack) ([]*gitpodlib.Stack, error) {
+	var res []*gitpodlib.Stack
+	for _, s := range stacks {
+		fmt.Printf("\nDetected %s\n", s.Name)
+		for _, t := range s.Tasks {
+			if t.Init != "" {
+				fmt.Printf("  init:    %s\n", t.Init)
+			}
+			if t.Command != "" {
+				fmt.Printf("  command: %s\n", t.Command)
+			}
+		}
+		prompt := promptui.Select{
+			Label: fmt.Sprintf("Use the suggested %s setup", s.Name),
+			Items: []string{"accept", "edit", "skip"},
+			Templates: &promptui.SelectTemplates{
+				Selected: fmt.Sprintf("%s: {{ . }}", s.Name),
+			},
+		}
+		chce, _, err := prompt.Run()
+		if err != nil {
+			return nil, err
+		}
+		if chce == 2 {
+			continue
+		}
+
+		for _, t := range s.Tasks {
+			if chce == 1 {
+				t.Init, err = ask(fmt.Sprintf("%s init (runs during prebuild, enter to skip)", t.Name), t.Init, nil)
+				if err != nil {
+					return nil, err
+				}
+				t.Command, err = ask(fmt.Sprintf("%s command (enter to skip)", t.Name), t.Command, nil)
+				if err != nil {
+					return nil, err
+				}
+				if t.Init == "" && t.Command == "" {
+					continue
+				}
+			}
+			cfg.AddStackTask(t)
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
 func askForTask(cfg *gitpodlib.GitpodFile) error {
-	input, err := ask("Startup task (enter to skip)", "", nil)
+	input, err := ask("Additional startup task (enter to skip)", "", nil)
 	if err != nil {
 		return err
 	}
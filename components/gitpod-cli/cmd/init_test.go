@@ -0,0 +1,40 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInitFallsBackToDefaultConfig(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"scripts": {"dev": `), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var warn bytes.Buffer
+	stacks := detectStacks(dir, &warn)
+	if len(stacks) != 0 {
+		t.Errorf("expected no stacks, got %d", len(stacks))
+	}
+	if !strings.Contains(warn.String(), "cannot parse package.json") {
+		t.Errorf("expected a warning about package.json, got %q", warn.String())
+	}
+
+	_, content, err := generateGitpodConfig(stacks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(defaultGitpodConfig, string(content)); diff != "" {
+		t.Errorf("unexpected configuration (-want +got):\n%s", diff)
+	}
+}
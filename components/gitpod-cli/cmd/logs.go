@@ -0,0 +1,350 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/supervisor"
+	"github.com/gitpod-io/gitpod/supervisor/api"
+)
+
+var logsCmdOpts struct {
+	Follow     bool
+	Since      string
+	Timestamps bool
+	StripANSI  bool
+	Prebuild   bool
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [task]",
+	Short: "Prints the output of a workspace task",
+	Long: `Prints the output of a workspace task. The task can be given by its name, its ID or the alias of its
+terminal (see gp tasks list) and can be omitted if there is only one task.
+
+Supervisor keeps the last 256KiB of output of every task terminal. If this workspace was started from a
+prebuild, the task terminal starts with the log of the task's init phase. Once that is no longer part of
+the terminal output, use --prebuild to print the prebuild log first. Its lines are stamped with the time
+the prebuild log was last written.
+
+--since accepts a duration (e.g. 10m) or an RFC3339 timestamp (e.g. 2022-03-01T10:00:00Z).`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		since, err := parseSince(logsCmdOpts.Since, time.Now())
+		if err != nil {
+			exitWithError(err)
+		}
+
+		conn, err := supervisor.Dial()
+		if err != nil {
+			exitWithError(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		tasks, err := supervisor.GetTasksList(ctx, api.NewStatusServiceClient(conn))
+		cancel()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		var selection string
+		if len(args) > 0 {
+			selection = args[0]
+		}
+		task, err := findTask(tasks, selection)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		out := bufio.NewWriter(os.Stdout)
+		w := &logWriter{
+			out:        out,
+			timestamps: logsCmdOpts.Timestamps,
+			stripANSI:  logsCmdOpts.StripANSI,
+		}
+		defer func() {
+			w.Flush()
+			out.Flush()
+		}()
+
+		if logsCmdOpts.Prebuild {
+			err = printPrebuildLog(w, task.Id, since)
+			if err != nil {
+				exitWithError(err)
+			}
+			out.Flush()
+		}
+
+		if task.State == api.TaskState_closed {
+			return
+		}
+		req := &api.ListenTerminalRequest{
+			Alias:       task.Terminal,
+			HistoryOnly: !logsCmdOpts.Follow,
+		}
+		if !since.IsZero() {
+			req.Since = timestamppb.New(since)
+		}
+		listen, err := api.NewTerminalServiceClient(conn).Listen(context.Background(), req)
+		if err != nil {
+			exitWithError(err)
+		}
+		for {
+			resp, err := listen.Recv()
+			if err == io.EOF {
+				return
+			}
+			if status.Code(err) == codes.NotFound {
+				// the task terminal has been closed in the meantime
+				return
+			}
+			if err != nil {
+				w.Flush()
+				out.Flush()
+				exitWithError(err)
+			}
+
+			data, ok := resp.Output.(*api.ListenTerminalResponse_Data)
+			if !ok {
+				continue
+			}
+			t := time.Now()
+			if resp.Time != nil {
+				t = resp.Time.AsTime()
+			}
+			w.Write(data.Data, t)
+			if logsCmdOpts.Follow {
+				out.Flush()
+			}
+		}
+	},
+}
+
+// findTask finds a task by its name, ID or terminal alias. If selection is empty and there is exactly one task, that task is returned.
+func findTask(tasks []*api.TaskStatus, selection string) (*api.TaskStatus, error) {
+	if selection == "" {
+		switch len(tasks) {
+		case 0:
+			return nil, fmt.Errorf("there are no tasks in this workspace")
+		case 1:
+			return tasks[0], nil
+		default:
+			return nil, fmt.Errorf("there are %d tasks in this workspace, please select one of %s", len(tasks), strings.Join(taskNames(tasks), ", "))
+		}
+	}
+	for _, t := range tasks {
+		if t.Id == selection || t.Terminal == selection {
+			return t, nil
+		}
+	}
+	var res *api.TaskStatus
+	for _, t := range tasks {
+		if t.Presentation == nil || t.Presentation.Name != selection {
+			continue
+		}
+		if res != nil {
+			return nil, fmt.Errorf("there is more than one task named %q, please use the task ID instead", selection)
+		}
+		res = t
+	}
+	if res == nil {
+		return nil, fmt.Errorf("unknown task %q, please select one of %s", selection, strings.Join(taskNames(tasks), ", "))
+	}
+	return res, nil
+}
+
+func taskNames(tasks []*api.TaskStatus) []string {
+	res := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		name := t.Id
+		if t.Presentation != nil && t.Presentation.Name != "" {
+			name = t.Presentation.Name
+		}
+		res = append(res, name)
+	}
+	return res
+}
+
+// parseSince parses a duration relative to now or an RFC3339 timestamp. An empty string yields the zero time.
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(since); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("--since must not be negative: %s", since)
+		}
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--since must be a duration or an RFC3339 timestamp: %s", since)
+	}
+	return t, nil
+}
+
+// prebuildLogFiles are the locations of the prebuild log of a task in recent and older workspaces,
+// see content-service/pkg/logs.
+func prebuildLogFiles(taskID string) []string {
+	return []string{
+		"/workspace/.prebuild-log-" + taskID,
+		"/workspace/.gitpod/prebuild-log-" + taskID,
+	}
+}
+
+// printPrebuildLog prints the prebuild log of a task if there is one that was written at or after since.
+func printPrebuildLog(w *logWriter, taskID string, since time.Time) error {
+	for _, fn := range prebuildLogFiles(taskID) {
+		stat, err := os.Stat(fn)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if stat.ModTime().Before(since) {
+			continue
+		}
+		content, err := os.ReadFile(fn)
+		if err != nil {
+			return err
+		}
+		w.Write(content, stat.ModTime())
+	}
+	return nil
+}
+
+type ansiState int
+
+const (
+	ansiNone ansiState = iota
+	// ansiEscape follows an ESC
+	ansiEscape
+	// ansiCSI is within a control sequence, i.e. ESC [ ... up to a final byte
+	ansiCSI
+	// ansiOSC is within an operating system command, i.e. ESC ] ... up to BEL or ESC \
+	ansiOSC
+	// ansiOSCEscape follows an ESC within an operating system command
+	ansiOSCEscape
+)
+
+// logWriter writes terminal output line by line. Line endings are normalised to \n,
+// lines are optionally prefixed with the time they were written and ANSI escape sequences
+// are optionally removed. Sequences and line endings may be split across writes.
+type logWriter struct {
+	out        io.Writer
+	timestamps bool
+	stripANSI  bool
+
+	midLine   bool
+	pendingCR bool
+	ansi      ansiState
+	buf       []byte
+}
+
+// Write writes terminal output that was written at time t.
+func (w *logWriter) Write(data []byte, t time.Time) {
+	w.buf = w.buf[:0]
+	for _, c := range data {
+		if w.stripANSI && w.skipANSI(c) {
+			continue
+		}
+		if w.pendingCR {
+			w.pendingCR = false
+			if c == '\n' {
+				w.emit('\n', t)
+				continue
+			}
+			w.emit('\r', t)
+		}
+		if c == '\r' {
+			w.pendingCR = true
+			continue
+		}
+		w.emit(c, t)
+	}
+	_, _ = w.out.Write(w.buf)
+}
+
+// Flush writes a carriage return that was held back to check for a following newline.
+func (w *logWriter) Flush() {
+	if !w.pendingCR {
+		return
+	}
+	w.pendingCR = false
+	_, _ = w.out.Write([]byte{'\r'})
+}
+
+func (w *logWriter) emit(c byte, t time.Time) {
+	if !w.midLine && w.timestamps {
+		w.buf = append(w.buf, t.Format(time.RFC3339Nano)...)
+		w.buf = append(w.buf, ' ')
+	}
+	w.buf = append(w.buf, c)
+	w.midLine = c != '\n'
+}
+
+// skipANSI advances the escape sequence state and reports whether c is part of an escape sequence.
+func (w *logWriter) skipANSI(c byte) bool {
+	switch w.ansi {
+	case ansiEscape:
+		switch c {
+		case '[':
+			w.ansi = ansiCSI
+		case ']':
+			w.ansi = ansiOSC
+		default:
+			// two-character sequence, e.g. ESC 7
+			w.ansi = ansiNone
+		}
+		return true
+	case ansiCSI:
+		if c >= 0x40 && c <= 0x7e {
+			w.ansi = ansiNone
+		}
+		return true
+	case ansiOSC:
+		switch c {
+		case '\a':
+			w.ansi = ansiNone
+		case 0x1b:
+			w.ansi = ansiOSCEscape
+		}
+		return true
+	case ansiOSCEscape:
+		if c == '\\' {
+			w.ansi = ansiNone
+		} else {
+			w.ansi = ansiOSC
+		}
+		return true
+	}
+	if c == 0x1b {
+		w.ansi = ansiEscape
+		return true
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	logsCmd.Flags().BoolVarP(&logsCmdOpts.Follow, "follow", "f", false, "keep printing the output of the task until it ends")
+	logsCmd.Flags().StringVar(&logsCmdOpts.Since, "since", "", "only print output written since a duration ago (e.g. 10m) or a timestamp (RFC3339)")
+	logsCmd.Flags().BoolVarP(&logsCmdOpts.Timestamps, "timestamps", "t", false, "prefix each line with the time it was written")
+	logsCmd.Flags().BoolVar(&logsCmdOpts.StripANSI, "strip-ansi", false, "remove ANSI escape sequences like colors from the output")
+	logsCmd.Flags().BoolVar(&logsCmdOpts.Prebuild, "prebuild", false, "print the prebuild log of the task's init phase first if there is one")
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/gitpod-io/gitpod/supervisor/api"
+)
+
+func TestLogWriter(t *testing.T) {
+	t0 := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	t1 := t0.Add(1500 * time.Millisecond)
+	type write struct {
+		Data string
+		Time time.Time
+	}
+	tests := []struct {
+		Desc        string
+		Timestamps  bool
+		StripANSI   bool
+		Writes      []write
+		Expectation string
+	}{
+		{
+			Desc:        "line endings",
+			Writes:      []write{{"foo\r\nbar\r", t0}, {"\nprogress 1\rprogress 2\r", t0}},
+			Expectation: "foo\nbar\nprogress 1\rprogress 2\r",
+		},
+		{
+			Desc:        "timestamps",
+			Timestamps:  true,
+			Writes:      []write{{"foo\r\nba", t0}, {"r\r\nbaz", t1}},
+			Expectation: "2022-03-01T10:00:00Z foo\n2022-03-01T10:00:00Z bar\n2022-03-01T10:00:01.5Z baz",
+		},
+		{
+			Desc:        "strip ANSI",
+			StripANSI:   true,
+			Writes:      []write{{"\x1b[1;32mgreen\x1b[0m \x1b]0;title\a\x1b]2;other title\x1b\\plain\x1b7", t0}},
+			Expectation: "green plain",
+		},
+		{
+			Desc:        "split escape sequence",
+			StripANSI:   true,
+			Timestamps:  true,
+			Writes:      []write{{"\x1b[", t0}, {"31mred\x1b", t0}, {"[0m\r\n", t1}},
+			Expectation: "2022-03-01T10:00:00Z red\n",
+		},
+		{
+			Desc:        "keep ANSI",
+			Writes:      []write{{"\x1b[1mbold\x1b[0m", t0}},
+			Expectation: "\x1b[1mbold\x1b[0m",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			var out bytes.Buffer
+			w := &logWriter{out: &out, timestamps: test.Timestamps, stripANSI: test.StripANSI}
+			for _, wr := range test.Writes {
+				w.Write([]byte(wr.Data), wr.Time)
+			}
+			w.Flush()
+			if diff := cmp.Diff(test.Expectation, out.String()); diff != "" {
+				t.Errorf("unexpected output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		Since       string
+		Expectation time.Time
+		Error       bool
+	}{
+		{Since: ""},
+		{Since: "10m", Expectation: now.Add(-10 * time.Minute)},
+		{Since: "2022-03-01T09:00:00Z", Expectation: now.Add(-time.Hour)},
+		{Since: "-5m", Error: true},
+		{Since: "yesterday", Error: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Since, func(t *testing.T) {
+			act, err := parseSince(test.Since, now)
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !act.Equal(test.Expectation) {
+				t.Errorf("unexpected time: %v, expected %v", act, test.Expectation)
+			}
+		})
+	}
+}
+
+func TestFindTask(t *testing.T) {
+	tasks := []*api.TaskStatus{
+		{Id: "0", Terminal: "alias-0", Presentation: &api.TaskPresentation{Name: "backend"}},
+		{Id: "1", Terminal: "alias-1", Presentation: &api.TaskPresentation{Name: "frontend"}},
+		{Id: "2", Terminal: "alias-2", Presentation: &api.TaskPresentation{Name: "frontend"}},
+	}
+	tests := []struct {
+		Desc        string
+		Tasks       []*api.TaskStatus
+		Selection   string
+		Expectation string
+		Error       bool
+	}{
+		{Desc: "by name", Tasks: tasks, Selection: "backend", Expectation: "0"},
+		{Desc: "by ID", Tasks: tasks, Selection: "2", Expectation: "2"},
+		{Desc: "by terminal alias", Tasks: tasks, Selection: "alias-1", Expectation: "1"},
+		{Desc: "ambiguous name", Tasks: tasks, Selection: "frontend", Error: true},
+		{Desc: "unknown task", Tasks: tasks, Selection: "database", Error: true},
+		{Desc: "no selection", Tasks: tasks, Error: true},
+		{Desc: "no selection with single task", Tasks: tasks[:1], Expectation: "0"},
+		{Desc: "no tasks", Error: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			act, err := findTask(test.Tasks, test.Selection)
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if act.Id != test.Expectation {
+				t.Errorf("unexpected task: %s, expected %s", act.Id, test.Expectation)
+			}
+		})
+	}
+}
@@ -5,7 +5,7 @@
 package cmd
 
 import (
-	"log"
+	"fmt"
 	"os"
 	"os/exec"
 	"sync"
@@ -34,20 +34,18 @@ var openCmd = &cobra.Command{
 
 		pcmd := os.Getenv("GP_OPEN_EDITOR")
 		if pcmd == "" {
-			log.Fatal("GP_OPEN_EDITOR is not set")
-			return
+			fail("GP_OPEN_EDITOR is not set")
 		}
 		pargs, err := shlex.Split(pcmd)
 		if err != nil {
-			log.Fatalf("cannot parse GP_OPEN_EDITOR: %v", err)
-			return
+			fail(fmt.Sprintf("cannot parse GP_OPEN_EDITOR: %v", err))
 		}
 		if len(pargs) > 1 {
 			pcmd = pargs[0]
 		}
 		pcmd, err = exec.LookPath(pcmd)
 		if err != nil {
-			log.Fatal(err)
+			exitWithError(err)
 		}
 
 		if wait {
@@ -56,7 +54,7 @@ var openCmd = &cobra.Command{
 
 		err = unix.Exec(pcmd, append(pargs, args...), os.Environ())
 		if err != nil {
-			log.Fatal(err)
+			exitWithError(err)
 		}
 	},
 }
@@ -93,8 +91,7 @@ func tryOpenInTheia(args []string, wait bool) error {
 			for {
 				resp, err := service.IsFileOpen(theialib.IsFileOpenRequest{Path: fn})
 				if err != nil {
-					log.Fatal(err)
-					return
+					exitWithError(err)
 				}
 				if !resp.IsOpen {
 					return
@@ -0,0 +1,97 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/gitpod-cli/cmd/tasks"
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
+	serverapi "github.com/gitpod-io/gitpod/gitpod-protocol"
+	"github.com/gitpod-io/gitpod/supervisor/api"
+)
+
+var updateGolden = flag.Bool("update", false, "update .golden files")
+
+// TestOutputSchema pins the JSON printed by each command, since scripts depend on it.
+func TestOutputSchema(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Value interface{}
+	}{
+		{"await-port", &awaitPortOutput{Port: 3000}},
+		{"env", newEnvVarOutputs([]*serverapi.UserEnvVarValue{
+			{Name: "FOO", Value: "bar", RepositoryPattern: "gitpod-io/gitpod"},
+			{Name: "TOKEN", Value: maskedEnvValue, RepositoryPattern: "*/*"},
+		})},
+		{"env-changes", newEnvVarChangeOutputs([]envVarChange{
+			{Kind: envVarAdded, Name: "FOO", NewValue: "bar"},
+			{Kind: envVarUpdated, Name: "BAR", OldValue: "a", NewValue: "b"},
+			{Kind: envVarDeleted, Name: "BAZ", OldValue: "c"},
+		}, false)},
+		{"info", &workspaceInfo{
+			WorkspaceID:       "gitpodio-gitpod-abc123",
+			InstanceID:        "a4f3c1b2",
+			Class:             "g1-standard",
+			URL:               "https://gitpodio-gitpod-abc123.ws-eu.gitpod.io",
+			Timeout:           "30m",
+			RemainingLifetime: "35h0m0s",
+			Owner:             "jane",
+		}},
+		{"snapshot", &snapshotOutput{SnapshotID: "0fe3cd3a", URL: "https://gitpod.io/#snapshot/0fe3cd3a"}},
+		{"tasks-list", tasks.NewTaskOutputs([]*api.TaskStatus{
+			{Id: "0", Terminal: "9c8a4d0b", State: api.TaskState_running, Presentation: &api.TaskPresentation{Name: "watch"}},
+			{Id: "1", Terminal: "2f7b19e6", State: api.TaskState_closed},
+		})},
+		{"tcp", &tcpOutput{Port: 5432, Client: "psql", ConnectionString: "postgresql://5432-gitpodio-gitpod-abc123.ws-eu.gitpod.io:9443/?sslmode=require&sslnegotiation=direct"}},
+		{"timeout", &timeoutOutput{Timeout: "180m", CanChange: true, ResetTimeoutOnWorkspaces: []string{"gitpodio-website-def456"}}},
+		{"url", &urlOutput{Port: 8080, URL: "https://8080-gitpodio-gitpod-abc123.ws-eu.gitpod.io"}},
+		{"validate", &validateOutput{File: "/workspace/gitpod/.gitpod.yml", Problems: []configProblem{{Line: 3, Column: 5, Message: "unknown key \"comand\""}}}},
+		{"version", &versionOutput{Version: "v0.1.0"}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var act bytes.Buffer
+			err := output.Write(&act, output.JSON, test.Value, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, test.Name, act.Bytes())
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		var act bytes.Buffer
+		err := output.WriteError(&act, output.JSON, xerrors.New("not running in a Gitpod workspace"), output.ExitCodeNotInWorkspace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertGolden(t, "error", act.Bytes())
+	})
+}
+
+func assertGolden(t *testing.T, name string, act []byte) {
+	fn := filepath.Join("testdata", "output", name+".golden")
+	if *updateGolden {
+		err := os.WriteFile(fn, act, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp, err := os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(exp), string(act)); diff != "" {
+		t.Errorf("unexpected output for %s (-want +got):\n%s", name, diff)
+	}
+}
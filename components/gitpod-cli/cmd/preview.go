@@ -5,7 +5,7 @@
 package cmd
 
 import (
-	"log"
+	"fmt"
 	"os"
 	"os/exec"
 	"regexp"
@@ -44,25 +44,23 @@ var previewCmd = &cobra.Command{
 func openPreview(gpBrowserEnvVar string, url string) {
 	pcmd := os.Getenv(gpBrowserEnvVar)
 	if pcmd == "" {
-		log.Fatalf("%s is not set", gpBrowserEnvVar)
-		return
+		fail(fmt.Sprintf("%s is not set", gpBrowserEnvVar))
 	}
 	pargs, err := shlex.Split(pcmd)
 	if err != nil {
-		log.Fatalf("cannot parse %s: %v", gpBrowserEnvVar, err)
-		return
+		fail(fmt.Sprintf("cannot parse %s: %v", gpBrowserEnvVar, err))
 	}
 	if len(pargs) > 1 {
 		pcmd = pargs[0]
 	}
 	pcmd, err = exec.LookPath(pcmd)
 	if err != nil {
-		log.Fatal(err)
+		exitWithError(err)
 	}
 
 	err = unix.Exec(pcmd, append(pargs, url), os.Environ())
 	if err != nil {
-		log.Fatal(err)
+		exitWithError(err)
 	}
 }
 
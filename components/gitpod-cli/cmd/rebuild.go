@@ -0,0 +1,218 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"golang.org/x/xerrors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
+	protocol "github.com/gitpod-io/gitpod/gitpod-protocol"
+)
+
+var rebuildCmdOpts struct {
+	Tag       string
+	NoCache   bool
+	BuildOnly bool
+	AllEnv    bool
+	Env       []string
+}
+
+var rebuildCmd = &cobra.Command{
+	Use:   "rebuild [-- command...]",
+	Short: "Builds the workspace image and opens a shell in a container running it",
+	Long: `Builds the workspace image configured in .gitpod.yml using the Docker daemon of
+this workspace, then starts a debug container from the built image. The container
+has the repository mounted at the same location as in this workspace and gets the
+GITPOD_* environment variables of this workspace, so that you can check changes to
+your Dockerfile without pushing them first.
+
+Other environment variables, e.g. the ones configured using "gp env" or in the
+project settings, are not passed on by default. Use --all-env to pass on all
+environment variables of this shell except for the ones which describe this
+container (e.g. PATH or HOME), or --env to pass on individual variables.
+
+By default the debug container runs an interactive shell. Pass a command after --
+to run that instead, e.g.
+    gp rebuild -- go version`,
+	Run: func(cmd *cobra.Command, args []string) {
+		repoRoot := os.Getenv("GITPOD_REPO_ROOT")
+		if repoRoot == "" {
+			exitWithError(xerrors.Errorf("GITPOD_REPO_ROOT is not set: %w", output.ErrNotInWorkspace))
+		}
+
+		content, err := os.ReadFile(filepath.Join(repoRoot, ".gitpod.yml"))
+		if err != nil && !os.IsNotExist(err) {
+			exitWithError(err)
+		}
+		img, err := workspaceImage(content, repoRoot)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		image := img.Name
+		if img.File != "" {
+			image = rebuildCmdOpts.Tag
+			fmt.Printf("building %s from %s\n", image, img.File)
+			err = runDocker(dockerBuildArgs(img, image, rebuildCmdOpts.NoCache))
+		} else {
+			fmt.Printf(".gitpod.yml does not configure a Dockerfile - pulling %s\n", image)
+			err = runDocker([]string{"pull", image})
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+		if rebuildCmdOpts.BuildOnly {
+			return
+		}
+
+		env := append(workspaceEnvNames(os.Environ(), rebuildCmdOpts.AllEnv), rebuildCmdOpts.Env...)
+		tty := term.IsTerminal(int(os.Stdin.Fd()))
+		err = runDocker(dockerRunArgs(image, repoRoot, env, tty, args))
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+// workspaceImageSpec describes either a Dockerfile to build or an image to use
+type workspaceImageSpec struct {
+	Name    string
+	File    string
+	Context string
+}
+
+// workspaceImage determines the workspace image configured in a .gitpod.yml. Paths are resolved relative to repoRoot.
+func workspaceImage(gitpodYml []byte, repoRoot string) (*workspaceImageSpec, error) {
+	var cfg struct {
+		Image yaml.Node `yaml:"image"`
+	}
+	err := yaml.Unmarshal(gitpodYml, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse .gitpod.yml: %w", err)
+	}
+
+	if cfg.Image.Kind == 0 || cfg.Image.Tag == "!!null" {
+		// no image configured, i.e. the default image
+		return &workspaceImageSpec{Name: "gitpod/workspace-full"}, nil
+	}
+	switch cfg.Image.Kind {
+	case yaml.ScalarNode:
+		return &workspaceImageSpec{Name: cfg.Image.Value}, nil
+	case yaml.MappingNode:
+		var img protocol.Image_object
+		err = cfg.Image.Decode(&img)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse image of .gitpod.yml: %w", err)
+		}
+		if img.File == "" {
+			return nil, errors.New(".gitpod.yml: image.file is required")
+		}
+		res := &workspaceImageSpec{
+			File:    filepath.Join(repoRoot, img.File),
+			Context: repoRoot,
+		}
+		if img.Context != "" {
+			res.Context = filepath.Join(repoRoot, img.Context)
+		}
+		return res, nil
+	default:
+		return nil, errors.New(".gitpod.yml: image must be an image name or an object with file and context")
+	}
+}
+
+func dockerBuildArgs(img *workspaceImageSpec, tag string, noCache bool) []string {
+	args := []string{"build", "-f", img.File, "-t", tag}
+	if noCache {
+		args = append(args, "--no-cache")
+	}
+	return append(args, img.Context)
+}
+
+func dockerRunArgs(image, repoRoot string, env []string, tty bool, command []string) []string {
+	args := []string{"run", "--rm", "-i"}
+	if tty {
+		args = append(args, "-t")
+	}
+	args = append(args, "-v", repoRoot+":"+repoRoot, "-w", repoRoot)
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+	args = append(args, image)
+	if len(command) == 0 {
+		command = []string{"bash"}
+	}
+	return append(args, command...)
+}
+
+// containerEnvNames are the environment variables which describe this container rather than the workspace.
+// The debug container gets its own values from the image or docker run.
+var containerEnvNames = map[string]struct{}{
+	"_":        {},
+	"HOME":     {},
+	"HOSTNAME": {},
+	"OLDPWD":   {},
+	"PATH":     {},
+	"PWD":      {},
+	"SHELL":    {},
+	"SHLVL":    {},
+	"TERM":     {},
+	"USER":     {},
+}
+
+// workspaceEnvNames returns the names of the environment variables passed on to the debug container, i.e. the
+// GITPOD_ ones, or all but the containerEnvNames if all is set. docker run takes the values from our own environment,
+// so that they don't show up in the process list.
+func workspaceEnvNames(environ []string, all bool) []string {
+	var res []string
+	for _, e := range environ {
+		name := strings.SplitN(e, "=", 2)[0]
+		if name == "" {
+			continue
+		}
+		if all {
+			if _, ok := containerEnvNames[name]; ok || strings.HasPrefix(name, "BASH_FUNC_") {
+				continue
+			}
+		} else if !strings.HasPrefix(name, "GITPOD_") {
+			continue
+		}
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func runDocker(args []string) error {
+	cmd := exec.Command("docker", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func init() {
+	rootCmd.AddCommand(rebuildCmd)
+
+	rebuildCmd.Flags().StringVar(&rebuildCmdOpts.Tag, "tag", "gitpod-rebuild:latest", "tag of the built image")
+	rebuildCmd.Flags().BoolVar(&rebuildCmdOpts.NoCache, "no-cache", false, "do not use the Docker build cache")
+	rebuildCmd.Flags().BoolVar(&rebuildCmdOpts.BuildOnly, "build-only", false, "build the image but do not start a debug container")
+	rebuildCmd.Flags().BoolVar(&rebuildCmdOpts.AllEnv, "all-env", false, "pass on all environment variables except the ones describing this container, not just GITPOD_*")
+	rebuildCmd.Flags().StringSliceVarP(&rebuildCmdOpts.Env, "env", "e", nil, "additional environment variables (NAME=value) for the debug container")
+}
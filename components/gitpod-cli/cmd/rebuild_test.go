@@ -0,0 +1,99 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWorkspaceImage(t *testing.T) {
+	const repoRoot = "/workspace/gitpod"
+	tests := []struct {
+		Desc        string
+		Content     string
+		Expectation *workspaceImageSpec
+		Error       bool
+	}{
+		{"no .gitpod.yml", "", &workspaceImageSpec{Name: "gitpod/workspace-full"}, false},
+		{"no image", "tasks:\n  - command: echo hello\n", &workspaceImageSpec{Name: "gitpod/workspace-full"}, false},
+		{"image name", "image: gitpod/workspace-go\n", &workspaceImageSpec{Name: "gitpod/workspace-go"}, false},
+		{"dockerfile", "image:\n  file: .gitpod.Dockerfile\n", &workspaceImageSpec{File: "/workspace/gitpod/.gitpod.Dockerfile", Context: "/workspace/gitpod"}, false},
+		{"dockerfile with context", "image:\n  file: dev/image/Dockerfile\n  context: dev\n", &workspaceImageSpec{File: "/workspace/gitpod/dev/image/Dockerfile", Context: "/workspace/gitpod/dev"}, false},
+		{"missing file", "image:\n  context: dev\n", nil, true},
+		{"invalid image", "image:\n  - foo\n", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			act, err := workspaceImage([]byte(test.Content), repoRoot)
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.Expectation, act); diff != "" {
+				t.Errorf("unexpected image (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDockerRunArgs(t *testing.T) {
+	env := workspaceEnvNames([]string{"PATH=/usr/bin", "GITPOD_WORKSPACE_ID=amaranth-smelt-9ba20cc1", "GITPOD_REPO_ROOT=/workspace/gitpod", "HOME=/home/gitpod"}, false)
+	tests := []struct {
+		Desc        string
+		TTY         bool
+		Command     []string
+		Expectation []string
+	}{
+		{
+			Desc:        "interactive shell",
+			TTY:         true,
+			Expectation: []string{"run", "--rm", "-i", "-t", "-v", "/workspace/gitpod:/workspace/gitpod", "-w", "/workspace/gitpod", "-e", "GITPOD_REPO_ROOT", "-e", "GITPOD_WORKSPACE_ID", "gitpod-rebuild:latest", "bash"},
+		},
+		{
+			Desc:        "command",
+			Command:     []string{"go", "version"},
+			Expectation: []string{"run", "--rm", "-i", "-v", "/workspace/gitpod:/workspace/gitpod", "-w", "/workspace/gitpod", "-e", "GITPOD_REPO_ROOT", "-e", "GITPOD_WORKSPACE_ID", "gitpod-rebuild:latest", "go", "version"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			act := dockerRunArgs("gitpod-rebuild:latest", "/workspace/gitpod", env, test.TTY, test.Command)
+			if diff := cmp.Diff(test.Expectation, act); diff != "" {
+				t.Errorf("unexpected arguments (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWorkspaceEnvNames(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin",
+		"HOME=/home/gitpod",
+		"GITPOD_WORKSPACE_ID=amaranth-smelt-9ba20cc1",
+		"DATABASE_URL=postgres://localhost",
+		"JAVA_TOOL_OPTIONS= -Xmx1024m",
+		"BASH_FUNC_foo%%=() {  echo foo\n}",
+		"GITPOD_REPO_ROOT=/workspace/gitpod",
+	}
+	tests := []struct {
+		Desc        string
+		All         bool
+		Expectation []string
+	}{
+		{Desc: "gitpod only", Expectation: []string{"GITPOD_REPO_ROOT", "GITPOD_WORKSPACE_ID"}},
+		{Desc: "all", All: true, Expectation: []string{"DATABASE_URL", "GITPOD_REPO_ROOT", "GITPOD_WORKSPACE_ID", "JAVA_TOOL_OPTIONS"}},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			act := workspaceEnvNames(environ, test.All)
+			if diff := cmp.Diff(test.Expectation, act); diff != "" {
+				t.Errorf("unexpected environment variables (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
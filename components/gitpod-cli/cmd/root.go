@@ -6,16 +6,37 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
 )
 
+var rootOpts struct {
+	Output output.Format
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "gp",
 	Short: "Command line interface for Gitpod",
+	Long: `Command line interface for Gitpod.
+
+Commands which print data support --output json and --output yaml for use in scripts.
+Errors are then printed to stderr in the same format. Commands which stream output
+or are interactive always print text.
+
+Exit codes:
+  0  success
+  1  any failure without an exit code of its own
+  2  invalid command line
+  3  not running in a Gitpod workspace
+  4  supervisor is unreachable
+  5  permission denied`,
 }
 
 // Execute runs the root command
@@ -32,6 +53,28 @@ func Execute() {
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(output.ExitCodeUsage)
+	}
+}
+
+// printOutput prints v in the format selected using --output. For the text format, text is called instead.
+func printOutput(v interface{}, text func(w io.Writer) error) {
+	err := output.Write(os.Stdout, rootOpts.Output, v, text)
+	if err != nil {
+		exitWithError(err)
 	}
 }
+
+// exitWithError prints err in the format selected using --output and exits with the exit code for err.
+func exitWithError(err error) {
+	output.Fail(rootOpts.Output, err)
+}
+
+// fail is exitWithError for a plain message.
+func fail(msg string) {
+	exitWithError(xerrors.New(msg))
+}
+
+func init() {
+	rootCmd.PersistentFlags().VarP(&rootOpts.Output, "output", "o", "output format of commands which print data: text, json or yaml")
+}
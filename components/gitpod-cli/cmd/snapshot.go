@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
@@ -39,7 +40,7 @@ var snapshotCmd = &cobra.Command{
 		}()
 		wsInfo, err := gitpod.GetWSInfo(ctx)
 		if err != nil {
-			fail(err.Error())
+			exitWithError(err)
 		}
 		client, err := gitpod.ConnectToServer(ctx, wsInfo, []string{
 			"function:takeSnapshot",
@@ -47,32 +48,44 @@ var snapshotCmd = &cobra.Command{
 			"resource:workspace::" + wsInfo.WorkspaceId + "::get/update",
 		})
 		if err != nil {
-			fail(err.Error())
+			exitWithError(err)
 		}
 		snapshotId, err := client.TakeSnapshot(ctx, &protocol.TakeSnapshotOptions{
 			WorkspaceID: wsInfo.WorkspaceId,
 			DontWait:    true,
 		})
 		if err != nil {
-			fail(err.Error())
+			exitWithError(err)
 		}
 		for ctx.Err() == nil {
 			err := client.WaitForSnapshot(ctx, snapshotId)
 			if err != nil {
 				var responseErr *jsonrpc2.Error
 				if errors.As(err, &responseErr) && (responseErr.Code == ErrorCodeSnapshotNotFound || responseErr.Code == ErrorCodeSnapshotError) {
-					panic(err)
+					exitWithError(err)
 				}
 				time.Sleep(time.Second * 3)
 			} else {
 				break
 			}
 		}
-		url := fmt.Sprintf("%s/#snapshot/%s", wsInfo.GitpodHost, snapshotId)
-		fmt.Println(url)
+		res := snapshotOutput{
+			SnapshotID: snapshotId,
+			URL:        fmt.Sprintf("%s/#snapshot/%s", wsInfo.GitpodHost, snapshotId),
+		}
+		printOutput(&res, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, res.URL)
+			return err
+		})
 	},
 }
 
+// snapshotOutput is how gp snapshot prints a snapshot in the JSON and YAML formats
+type snapshotOutput struct {
+	SnapshotID string `json:"snapshotId"`
+	URL        string `json:"url"`
+}
+
 func init() {
 	rootCmd.AddCommand(snapshotCmd)
 }
@@ -22,18 +22,18 @@ var stopWorkspaceCmd = &cobra.Command{
 		defer cancel()
 		wsInfo, err := gitpod.GetWSInfo(ctx)
 		if err != nil {
-			fail(err.Error())
+			exitWithError(err)
 		}
 		client, err := gitpod.ConnectToServer(ctx, wsInfo, []string{
 			"function:stopWorkspace",
 			"resource:workspace::" + wsInfo.WorkspaceId + "::get/update",
 		})
 		if err != nil {
-			fail(err.Error())
+			exitWithError(err)
 		}
 		err = client.StopWorkspace(ctx, wsInfo.WorkspaceId)
 		if err != nil {
-			fail(err.Error())
+			exitWithError(err)
 		}
 	},
 }
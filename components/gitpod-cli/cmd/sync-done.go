@@ -8,7 +8,6 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
-	"log"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -32,7 +31,7 @@ var syncDoneCmd = &cobra.Command{
 
 		err := os.WriteFile(lockFile, []byte("done"), 0600)
 		if err != nil {
-			log.Fatalf("cannot write lock file: %v", err)
+			fail(fmt.Sprintf("cannot write lock file: %v", err))
 		}
 	},
 }
@@ -10,6 +10,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
 	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/supervisor"
 	"github.com/gitpod-io/gitpod/supervisor/api"
 	"github.com/manifoldco/promptui"
@@ -21,7 +22,10 @@ import (
 func AttachTasksCmd(cmd *cobra.Command, args []string) {
 	var terminalAlias string
 
-	conn := supervisor.Dial()
+	conn, err := supervisor.Dial()
+	if err != nil {
+		output.Fail(output.FromCommand(cmd), err)
+	}
 
 	if len(args) > 0 {
 		terminalAlias = args[0]
@@ -32,7 +36,10 @@ func AttachTasksCmd(cmd *cobra.Command, args []string) {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		tasks := supervisor.GetTasksListByState(ctx, statusClient, stateToFilter)
+		tasks, err := supervisor.GetTasksListByState(ctx, statusClient, stateToFilter)
+		if err != nil {
+			output.Fail(output.FromCommand(cmd), err)
+		}
 
 		if len(tasks) == 0 {
 			fmt.Println("There are no running tasks")
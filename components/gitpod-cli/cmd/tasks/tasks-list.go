@@ -7,9 +7,11 @@ package tasks
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
 	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/supervisor"
 	"github.com/gitpod-io/gitpod/supervisor/api"
 	"github.com/spf13/cobra"
@@ -17,34 +19,69 @@ import (
 	"github.com/olekukonko/tablewriter"
 )
 
+// TaskOutput is how gp tasks list prints a task in the JSON and YAML formats
+type TaskOutput struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Terminal string `json:"terminal"`
+	State    string `json:"state"`
+}
+
+// NewTaskOutputs converts tasks to the way gp tasks list prints them in the JSON and YAML formats.
+func NewTaskOutputs(tasks []*api.TaskStatus) []TaskOutput {
+	res := make([]TaskOutput, 0, len(tasks))
+	for _, task := range tasks {
+		res = append(res, TaskOutput{
+			ID:       task.Id,
+			Name:     task.GetPresentation().GetName(),
+			Terminal: task.Terminal,
+			State:    task.State.String(),
+		})
+	}
+	return res
+}
+
 func ListTasksCmd(cmd *cobra.Command, args []string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	conn := supervisor.Dial()
+	format := output.FromCommand(cmd)
+	conn, err := supervisor.Dial()
+	if err != nil {
+		output.Fail(format, err)
+	}
 	client := api.NewStatusServiceClient(conn)
 
-	tasks := supervisor.GetTasksList(ctx, client)
-
-	if len(tasks) == 0 {
-		fmt.Println("No tasks detected")
-		return
+	tasks, err := supervisor.GetTasksList(ctx, client)
+	if err != nil {
+		output.Fail(format, err)
 	}
 
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Terminal ID", "Name", "State"})
-	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
-	table.SetCenterSeparator("|")
+	err = output.Write(os.Stdout, format, NewTaskOutputs(tasks), func(w io.Writer) error {
+		if len(tasks) == 0 {
+			_, err := fmt.Fprintln(w, "No tasks detected")
+			return err
+		}
 
-	mapStatusToColor := map[api.TaskState]int{
-		0: tablewriter.FgHiGreenColor,
-		1: tablewriter.FgHiGreenColor,
-		2: tablewriter.FgHiBlackColor,
-	}
+		table := tablewriter.NewWriter(w)
+		table.SetHeader([]string{"Terminal ID", "Name", "State"})
+		table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+		table.SetCenterSeparator("|")
 
-	for _, task := range tasks {
-		table.Rich([]string{task.Terminal, task.Presentation.Name, task.State.String()}, []tablewriter.Colors{{}, {}, {mapStatusToColor[task.State]}})
-	}
+		mapStatusToColor := map[api.TaskState]int{
+			0: tablewriter.FgHiGreenColor,
+			1: tablewriter.FgHiGreenColor,
+			2: tablewriter.FgHiBlackColor,
+		}
+
+		for _, task := range tasks {
+			table.Rich([]string{task.Terminal, task.GetPresentation().GetName(), task.State.String()}, []tablewriter.Colors{{}, {}, {mapStatusToColor[task.State]}})
+		}
 
-	table.Render()
+		table.Render()
+		return nil
+	})
+	if err != nil {
+		output.Fail(format, err)
+	}
 }
@@ -0,0 +1,129 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
+)
+
+// defaultTCPProxyPort is the port on which ws-proxy accepts raw TCP connections
+const defaultTCPProxyPort = 9443
+
+var tcpCmdOpts struct {
+	Client    string
+	ProxyPort int
+}
+
+// tcpClients produce the connection string for a client given the port host and the proxy address
+var tcpClients = map[string]func(host, addr string, port int) string{
+	"address": func(host, addr string, port int) string {
+		return addr
+	},
+	"openssl": func(host, addr string, port int) string {
+		return fmt.Sprintf("openssl s_client -quiet -connect %s -servername %s", addr, host)
+	},
+	"socat": func(host, addr string, port int) string {
+		return fmt.Sprintf("socat TCP-LISTEN:%d,reuseaddr,fork OPENSSL:%s,snihost=%s", port, addr, host)
+	},
+	"psql": func(host, addr string, port int) string {
+		return fmt.Sprintf("postgresql://%s/?sslmode=require&sslnegotiation=direct", addr)
+	},
+	"redis": func(host, addr string, port int) string {
+		return fmt.Sprintf("rediss://%s", addr)
+	},
+	"mongodb": func(host, addr string, port int) string {
+		return fmt.Sprintf("mongodb://%s/?tls=true", addr)
+	},
+}
+
+var tcpCmd = &cobra.Command{
+	Use:   "tcp <port>",
+	Short: "Prints a connection string for raw TCP access to a port",
+	Long: `Prints a connection string which clients outside of this workspace can use to
+connect to a TCP service (e.g. a database) running on a port of this workspace.
+The connection is encrypted using TLS up to Gitpod and passed on to the port as
+plain TCP. The port must be public and must not be protected by a password or
+OIDC, since raw TCP offers no way to authenticate.
+
+For clients which do not speak TLS themselves, use --client socat to get a
+command which makes the port available on localhost. For example:
+    gp tcp 5432 --client psql
+will print a PostgreSQL connection URL for the database listening on port 5432.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		port, err := strconv.ParseUint(args[0], 10, 16)
+		if err != nil {
+			fail(fmt.Sprintf("port \"%s\" is not a valid number", args[0]))
+		}
+
+		wsurl := os.Getenv("GITPOD_WORKSPACE_URL")
+		if wsurl == "" {
+			exitWithError(output.ErrNotInWorkspace)
+		}
+		conn, err := tcpConnectionString(wsurl, int(port), tcpCmdOpts.ProxyPort, tcpCmdOpts.Client)
+		if err != nil {
+			exitWithError(err)
+		}
+		res := tcpOutput{Port: int(port), Client: tcpCmdOpts.Client, ConnectionString: conn}
+		printOutput(&res, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, res.ConnectionString)
+			return err
+		})
+	},
+}
+
+// tcpOutput is how gp tcp prints a connection string in the JSON and YAML formats
+type tcpOutput struct {
+	Port             int    `json:"port"`
+	Client           string `json:"client"`
+	ConnectionString string `json:"connectionString"`
+}
+
+// tcpConnectionString produces the connection string of a client for a workspace port
+func tcpConnectionString(workspaceURL string, port, proxyPort int, client string) (string, error) {
+	format, ok := tcpClients[client]
+	if !ok {
+		return "", fmt.Errorf("unknown client %q, must be one of %s", client, strings.Join(tcpClientNames(), ", "))
+	}
+
+	wsurl, err := url.Parse(workspaceURL)
+	if err != nil || wsurl.Hostname() == "" {
+		return "", fmt.Errorf("cannot determine the workspace host from GITPOD_WORKSPACE_URL %q", workspaceURL)
+	}
+	host := fmt.Sprintf("%d-%s", port, wsurl.Hostname())
+	addr := net.JoinHostPort(host, strconv.Itoa(proxyPort))
+	return format(host, addr, port), nil
+}
+
+func tcpClientNames() []string {
+	res := make([]string, 0, len(tcpClients))
+	for name := range tcpClients {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func init() {
+	proxyPort := defaultTCPProxyPort
+	if p, err := strconv.Atoi(os.Getenv("GITPOD_TCP_PROXY_PORT")); err == nil {
+		proxyPort = p
+	}
+
+	tcpCmd.Flags().StringVarP(&tcpCmdOpts.Client, "client", "c", "address", fmt.Sprintf("the client to produce the connection string for (%s)", strings.Join(tcpClientNames(), ", ")))
+	tcpCmd.Flags().IntVar(&tcpCmdOpts.ProxyPort, "proxy-port", proxyPort, "the port on which Gitpod accepts TCP connections (defaults to $GITPOD_TCP_PROXY_PORT)")
+	rootCmd.AddCommand(tcpCmd)
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import "testing"
+
+func TestTCPConnectionString(t *testing.T) {
+	const wsURL = "https://amaranth-smelt-9ba20cc1.ws-eu45.gitpod.io"
+	tests := []struct {
+		Desc         string
+		WorkspaceURL string
+		Client       string
+		Expectation  string
+		Error        bool
+	}{
+		{"address", wsURL, "address", "5432-amaranth-smelt-9ba20cc1.ws-eu45.gitpod.io:9443", false},
+		{"openssl", wsURL, "openssl", "openssl s_client -quiet -connect 5432-amaranth-smelt-9ba20cc1.ws-eu45.gitpod.io:9443 -servername 5432-amaranth-smelt-9ba20cc1.ws-eu45.gitpod.io", false},
+		{"socat", wsURL, "socat", "socat TCP-LISTEN:5432,reuseaddr,fork OPENSSL:5432-amaranth-smelt-9ba20cc1.ws-eu45.gitpod.io:9443,snihost=5432-amaranth-smelt-9ba20cc1.ws-eu45.gitpod.io", false},
+		{"psql", wsURL, "psql", "postgresql://5432-amaranth-smelt-9ba20cc1.ws-eu45.gitpod.io:9443/?sslmode=require&sslnegotiation=direct", false},
+		{"unknown client", wsURL, "telnet", "", true},
+		{"no workspace URL", "", "address", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			act, err := tcpConnectionString(test.WorkspaceURL, 5432, 9443, test.Client)
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if act != test.Expectation {
+				t.Errorf("unexpected result: %s, expected %s", act, test.Expectation)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+
+	gitpod "github.com/gitpod-io/gitpod/gitpod-cli/pkg/gitpod"
+	serverapi "github.com/gitpod-io/gitpod/gitpod-protocol"
+	"github.com/gitpod-io/gitpod/supervisor/api"
+)
+
+var timeoutCmd = &cobra.Command{
+	Use:   "timeout",
+	Short: "Shows or changes the inactivity timeout of this workspace",
+	Long: `Shows or changes the inactivity timeout of this workspace. A workspace is stopped when it was
+inactive for longer than its timeout. Changing the timeout requires a plan which allows it. Setting
+the timeout of this workspace resets the timeout of your other running workspaces to your default.`,
+}
+
+var timeoutShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Shows the inactivity timeout of this workspace",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+		client, wsInfo, err := connectForTimeout(ctx)
+		if err != nil {
+			exitWithError(err)
+		}
+		defer client.Close()
+
+		res, err := client.GetWorkspaceTimeout(ctx, wsInfo.WorkspaceId)
+		if err != nil {
+			exitWithError(err)
+		}
+		out := timeoutOutput{Timeout: formatTimeoutDuration(res.Duration), CanChange: res.CanChange}
+		printOutput(&out, func(w io.Writer) error {
+			fmt.Fprintln(w, out.Timeout)
+			if !out.CanChange {
+				fmt.Fprintln(w, "Your plan does not allow changing the timeout.")
+			}
+			return nil
+		})
+	},
+}
+
+var timeoutSetCmd = &cobra.Command{
+	Use:   "set <duration>",
+	Short: "Sets the inactivity timeout of this workspace",
+	Long: `Sets the inactivity timeout of this workspace. Supported durations are 30m, 60m and 180m
+(or short, long and extended).`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		duration, err := parseTimeoutDuration(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+		client, wsInfo, err := connectForTimeout(ctx)
+		if err != nil {
+			exitWithError(err)
+		}
+		defer client.Close()
+
+		out, err := setWorkspaceTimeout(ctx, client, wsInfo.WorkspaceId, duration)
+		if err != nil {
+			exitWithError(err)
+		}
+		printTimeoutChange(out)
+	},
+}
+
+var timeoutExtendCmd = &cobra.Command{
+	Use:   "extend",
+	Short: "Extends the inactivity timeout of this workspace to the maximum",
+	Long: `Extends the inactivity timeout of this workspace to 180m. This does nothing if the timeout
+already is 180m, so it can safely be called repeatedly, e.g. by a long-running job.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+		client, wsInfo, err := connectForTimeout(ctx)
+		if err != nil {
+			exitWithError(err)
+		}
+		defer client.Close()
+
+		current, err := client.GetWorkspaceTimeout(ctx, wsInfo.WorkspaceId)
+		if err != nil {
+			exitWithError(err)
+		}
+		if formatTimeoutDuration(current.Duration) == serverapi.WorkspaceTimeoutDuration180m {
+			out := timeoutOutput{Timeout: serverapi.WorkspaceTimeoutDuration180m, CanChange: current.CanChange}
+			printOutput(&out, func(w io.Writer) error {
+				_, err := fmt.Fprintln(w, "Workspace timeout already is "+out.Timeout)
+				return err
+			})
+			return
+		}
+		if !current.CanChange {
+			fail("Your plan does not allow changing the timeout.")
+		}
+		out, err := setWorkspaceTimeout(ctx, client, wsInfo.WorkspaceId, serverapi.WorkspaceTimeoutDurationExtended)
+		if err != nil {
+			exitWithError(err)
+		}
+		printTimeoutChange(out)
+	},
+}
+
+func connectForTimeout(ctx context.Context) (*serverapi.APIoverJSONRPC, *api.WorkspaceInfoResponse, error) {
+	wsInfo, err := gitpod.GetWSInfo(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := gitpod.ConnectToServer(ctx, wsInfo, []string{
+		"function:getWorkspaceTimeout",
+		"function:setWorkspaceTimeout",
+		"resource:workspace::" + wsInfo.WorkspaceId + "::get/update",
+		"resource:workspaceInstance::" + wsInfo.InstanceId + "::get/update",
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, wsInfo, nil
+}
+
+// timeoutOutput is how gp timeout prints the timeout in the JSON and YAML formats
+type timeoutOutput struct {
+	Timeout                  string   `json:"timeout"`
+	CanChange                bool     `json:"canChange"`
+	ResetTimeoutOnWorkspaces []string `json:"resetTimeoutOnWorkspaces,omitempty"`
+}
+
+func setWorkspaceTimeout(ctx context.Context, client serverapi.APIInterface, workspaceID string, duration serverapi.WorkspaceTimeoutDuration) (*timeoutOutput, error) {
+	res, err := client.SetWorkspaceTimeout(ctx, workspaceID, &duration)
+	if err != nil {
+		return nil, err
+	}
+	return &timeoutOutput{
+		Timeout:                  formatTimeoutDuration(string(duration)),
+		CanChange:                true,
+		ResetTimeoutOnWorkspaces: res.ResetTimeoutOnWorkspaces,
+	}, nil
+}
+
+func printTimeoutChange(out *timeoutOutput) {
+	printOutput(out, func(w io.Writer) error {
+		fmt.Fprintln(w, "Workspace timeout set to "+out.Timeout)
+		if len(out.ResetTimeoutOnWorkspaces) > 0 {
+			fmt.Fprintln(w, "The timeout of these workspaces was reset to your default: "+strings.Join(out.ResetTimeoutOnWorkspaces, ", "))
+		}
+		return nil
+	})
+}
+
+// parseTimeoutDuration parses a timeout given as duration (e.g. 30m or 1h) or by its name
+// and returns the value the server accepts for it.
+func parseTimeoutDuration(s string) (serverapi.WorkspaceTimeoutDuration, error) {
+	switch strings.ToLower(s) {
+	case serverapi.WorkspaceTimeoutDurationShort:
+		return serverapi.WorkspaceTimeoutDurationShort, nil
+	case serverapi.WorkspaceTimeoutDurationLong:
+		return serverapi.WorkspaceTimeoutDurationLong, nil
+	case serverapi.WorkspaceTimeoutDurationExtended:
+		return serverapi.WorkspaceTimeoutDurationExtended, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	switch {
+	case err != nil:
+		// handled below
+	case d == 30*time.Minute:
+		return serverapi.WorkspaceTimeoutDurationShort, nil
+	case d == 60*time.Minute:
+		return serverapi.WorkspaceTimeoutDurationLong, nil
+	case d == 180*time.Minute:
+		return serverapi.WorkspaceTimeoutDurationExtended, nil
+	}
+	return "", xerrors.Errorf("unsupported timeout %q (supported timeouts are 30m, 60m and 180m)", s)
+}
+
+// formatTimeoutDuration returns the duration of a timeout as reported by the server, e.g. 30m for short.
+func formatTimeoutDuration(d string) string {
+	switch d {
+	case serverapi.WorkspaceTimeoutDurationShort:
+		return serverapi.WorkspaceTimeoutDuration30m
+	case serverapi.WorkspaceTimeoutDurationLong:
+		return serverapi.WorkspaceTimeoutDuration60m
+	case serverapi.WorkspaceTimeoutDurationExtended, serverapi.WorkspaceTimeoutDuration180m:
+		return serverapi.WorkspaceTimeoutDuration180m
+	default:
+		return d
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(timeoutCmd)
+	timeoutCmd.AddCommand(timeoutShowCmd)
+	timeoutCmd.AddCommand(timeoutSetCmd)
+	timeoutCmd.AddCommand(timeoutExtendCmd)
+}
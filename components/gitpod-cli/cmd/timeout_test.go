@@ -0,0 +1,62 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	serverapi "github.com/gitpod-io/gitpod/gitpod-protocol"
+)
+
+func TestParseTimeoutDuration(t *testing.T) {
+	tests := []struct {
+		Input       string
+		Expectation serverapi.WorkspaceTimeoutDuration
+		Error       bool
+	}{
+		{"30m", serverapi.WorkspaceTimeoutDurationShort, false},
+		{"60m", serverapi.WorkspaceTimeoutDurationLong, false},
+		{"1h", serverapi.WorkspaceTimeoutDurationLong, false},
+		{"180m", serverapi.WorkspaceTimeoutDurationExtended, false},
+		{"3h", serverapi.WorkspaceTimeoutDurationExtended, false},
+		{"short", serverapi.WorkspaceTimeoutDurationShort, false},
+		{"Extended", serverapi.WorkspaceTimeoutDurationExtended, false},
+		{"45m", "", true},
+		{"forever", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.Input, func(t *testing.T) {
+			act, err := parseTimeoutDuration(test.Input)
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.Expectation, act); diff != "" {
+				t.Errorf("unexpected duration (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatTimeoutDuration(t *testing.T) {
+	tests := []struct {
+		Input       string
+		Expectation string
+	}{
+		{"short", "30m"},
+		{"long", "60m"},
+		{"extended", "180m"},
+		{"180m", "180m"},
+		{"unknown", "unknown"},
+	}
+	for _, test := range tests {
+		t.Run(test.Input, func(t *testing.T) {
+			if act := formatTimeoutDuration(test.Input); act != test.Expectation {
+				t.Errorf("unexpected duration: %s, expected %s", act, test.Expectation)
+			}
+		})
+	}
+}
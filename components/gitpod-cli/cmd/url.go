@@ -6,11 +6,14 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
 )
 
 // urlCmd represents the url command
@@ -24,21 +27,33 @@ particular port. For example:
 will print the URL of a service/server exposed on port 8080.`,
 	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) == 0 {
-			fmt.Println(os.Getenv("GITPOD_WORKSPACE_URL"))
-			return
+		if os.Getenv("GITPOD_WORKSPACE_URL") == "" {
+			exitWithError(output.ErrNotInWorkspace)
 		}
 
-		port, err := strconv.ParseUint(args[0], 10, 16)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "port \"%s\" is not a valid number\n", args[0])
-			return
+		var port uint64
+		if len(args) > 0 {
+			var err error
+			port, err = strconv.ParseUint(args[0], 10, 16)
+			if err != nil {
+				fail(fmt.Sprintf("port \"%s\" is not a valid number", args[0]))
+			}
 		}
 
-		fmt.Println(GetWorkspaceURL(int(port)))
+		res := urlOutput{Port: int(port), URL: GetWorkspaceURL(int(port))}
+		printOutput(&res, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, res.URL)
+			return err
+		})
 	},
 }
 
+// urlOutput is how gp url prints a URL in the JSON and YAML formats
+type urlOutput struct {
+	Port int    `json:"port,omitempty"`
+	URL  string `json:"url"`
+}
+
 func init() {
 	rootCmd.AddCommand(urlCmd)
 }
@@ -0,0 +1,520 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/supervisor"
+	protocol "github.com/gitpod-io/gitpod/gitpod-protocol"
+	"github.com/gitpod-io/gitpod/supervisor/api"
+)
+
+var validateCmdOpts struct {
+	File  string
+	Run   bool
+	Tasks []string
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validates the .gitpod.yml and re-runs the tasks you changed",
+	Long: `Validates the .gitpod.yml of this workspace against the Gitpod configuration
+schema and reports problems such as unknown keys or invalid values with their
+line numbers.
+
+With --run the before, init and command phases of the tasks which differ from
+the ones this workspace was started with are run again in new terminals, so that
+you can try out changes to your tasks without starting a new workspace. Use
+--task to pick the tasks to run by name or index instead.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fn := validateCmdOpts.File
+		if fn == "" {
+			fn = filepath.Join(os.Getenv("GITPOD_REPO_ROOT"), ".gitpod.yml")
+		}
+		content, err := os.ReadFile(fn)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		problems, err := validateGitpodConfig(content)
+		if err != nil {
+			exitWithError(xerrors.Errorf("%s: %w", fn, err))
+		}
+		res := validateOutput{File: fn, Valid: len(problems) == 0, Problems: problems}
+		if res.Problems == nil {
+			res.Problems = []configProblem{}
+		}
+		printOutput(&res, func(w io.Writer) error {
+			for _, p := range problems {
+				fmt.Fprintf(w, "%s:%d:%d: %s\n", fn, p.Line, p.Column, p.Message)
+			}
+			if res.Valid {
+				fmt.Fprintf(w, "%s is valid\n", fn)
+			}
+			return nil
+		})
+		if !res.Valid {
+			os.Exit(output.ExitCodeError)
+		}
+
+		if !validateCmdOpts.Run && len(validateCmdOpts.Tasks) == 0 {
+			return
+		}
+
+		var cfg struct {
+			Tasks []gitpodTask `yaml:"tasks"`
+		}
+		err = yaml.Unmarshal(content, &cfg)
+		if err != nil {
+			exitWithError(err)
+		}
+		var started []gitpodTask
+		if env := os.Getenv("GITPOD_TASKS"); env != "" {
+			err = json.Unmarshal([]byte(env), &started)
+			if err != nil {
+				exitWithError(xerrors.Errorf("cannot parse GITPOD_TASKS: %w", err))
+			}
+		}
+		selection, err := selectTasks(cfg.Tasks, started, validateCmdOpts.Tasks)
+		if err != nil {
+			exitWithError(err)
+		}
+		if len(selection) == 0 {
+			fmt.Println("no task has changed - use --task to run tasks anyway")
+			return
+		}
+
+		runTasks(filepath.Dir(fn), cfg.Tasks, selection)
+	},
+}
+
+// validateOutput is how gp validate prints its result in the JSON and YAML formats
+type validateOutput struct {
+	File     string          `json:"file"`
+	Valid    bool            `json:"valid"`
+	Problems []configProblem `json:"problems"`
+}
+
+// configProblem is an issue found while validating a .gitpod.yml
+type configProblem struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// gitpodConfigEnums lists the allowed values of string fields by their path in the configuration
+var gitpodConfigEnums = mustLoadGitpodConfigEnums(protocol.GitpodSchema)
+
+// jsonSchema is the part of a JSON schema we need to find the enums in it
+type jsonSchema struct {
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	OneOf                []*jsonSchema          `json:"oneOf"`
+	AnyOf                []*jsonSchema          `json:"anyOf"`
+	Enum                 []interface{}          `json:"enum"`
+}
+
+func mustLoadGitpodConfigEnums(schema []byte) map[string][]string {
+	res, err := loadGitpodConfigEnums(schema)
+	if err != nil {
+		panic(fmt.Sprintf("cannot load the enums of the .gitpod.yml schema: %v", err))
+	}
+	return res
+}
+
+// loadGitpodConfigEnums collects the allowed values of string fields from a JSON schema. The paths
+// are the ones the config validator uses, i.e. without list indices and with * for the keys of maps.
+func loadGitpodConfigEnums(schema []byte) (map[string][]string, error) {
+	var root jsonSchema
+	err := json.Unmarshal(schema, &root)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string][]string)
+	var collect func(s *jsonSchema, path string) error
+	collect = func(s *jsonSchema, path string) error {
+		if s == nil {
+			return nil
+		}
+		for _, e := range s.Enum {
+			if v, ok := e.(string); ok {
+				res[path] = append(res[path], v)
+			}
+		}
+		for name, p := range s.Properties {
+			err := collect(p, joinConfigPath(path, name))
+			if err != nil {
+				return err
+			}
+		}
+		// additionalProperties is either a schema or a boolean
+		if len(s.AdditionalProperties) > 0 && s.AdditionalProperties[0] == '{' {
+			var p jsonSchema
+			err := json.Unmarshal(s.AdditionalProperties, &p)
+			if err != nil {
+				return err
+			}
+			err = collect(&p, path+".*")
+			if err != nil {
+				return err
+			}
+		}
+		for _, p := range append(append([]*jsonSchema{s.Items}, s.OneOf...), s.AnyOf...) {
+			err := collect(p, path)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = collect(&root, "")
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// gitpodConfigObjects names the types of fields which accept a string or an object in the configuration
+var gitpodConfigObjects = map[string]reflect.Type{
+	"image":            reflect.TypeOf(protocol.Image_object{}),
+	"github.prebuilds": reflect.TypeOf(protocol.Prebuilds_object{}),
+}
+
+var portRangeRegexp = regexp.MustCompile(`^\d+[:-]\d+$`)
+
+// validateGitpodConfig checks a .gitpod.yml against the GitpodConfig schema.
+// An error is returned only if the content is no valid YAML at all.
+func validateGitpodConfig(content []byte) ([]configProblem, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(content, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	v := &configValidator{}
+	v.validate(doc.Content[0], reflect.TypeOf(protocol.GitpodConfig{}), "", "")
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		if v.problems[i].Line != v.problems[j].Line {
+			return v.problems[i].Line < v.problems[j].Line
+		}
+		return v.problems[i].Column < v.problems[j].Column
+	})
+	return v.problems, nil
+}
+
+type configValidator struct {
+	problems []configProblem
+}
+
+func (v *configValidator) report(n *yaml.Node, path string, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if path != "" {
+		msg = path + ": " + msg
+	}
+	v.problems = append(v.problems, configProblem{Line: n.Line, Column: n.Column, Message: msg})
+}
+
+// validate checks node n against type t. path is the location of n as shown to the user (tasks[0].init),
+// schemaPath is the location without indices (tasks.init) which we use to look up enums.
+func (v *configValidator) validate(n *yaml.Node, t reflect.Type, path, schemaPath string) {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			v.report(n, path, "expected a map")
+			return
+		}
+		if t.NumField() == 0 {
+			// free-form maps like the env of tasks
+			return
+		}
+		v.validateStruct(n, t, path, schemaPath)
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			v.report(n, path, "expected a map")
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			v.validate(n.Content[i+1], t.Elem(), joinConfigPath(path, key), schemaPath+".*")
+		}
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			v.report(n, path, "expected a list")
+			return
+		}
+		for i, c := range n.Content {
+			v.validate(c, t.Elem(), fmt.Sprintf("%s[%d]", path, i), schemaPath)
+		}
+	case reflect.String:
+		if n.Kind != yaml.ScalarNode {
+			v.report(n, path, "expected a string")
+			return
+		}
+		allowed, ok := gitpodConfigEnums[schemaPath]
+		if !ok {
+			return
+		}
+		for _, a := range allowed {
+			if n.Value == a {
+				return
+			}
+		}
+		v.report(n, path, "%q is not one of %s", n.Value, strings.Join(allowed, ", "))
+	case reflect.Bool:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!bool" {
+			v.report(n, path, "expected true or false")
+		}
+	case reflect.Interface:
+		v.validateAny(n, path, schemaPath)
+	}
+}
+
+func (v *configValidator) validateStruct(n *yaml.Node, t reflect.Type, path, schemaPath string) {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = f
+		if !strings.Contains(opts, "omitempty") && !hasConfigKey(n, name) {
+			v.report(n, path, "%q is required", name)
+		}
+	}
+
+	seen := make(map[string]bool, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key := n.Content[i]
+		if seen[key.Value] {
+			v.report(key, path, "duplicate key %q", key.Value)
+			continue
+		}
+		seen[key.Value] = true
+
+		f, ok := fields[key.Value]
+		if !ok {
+			if path == "" {
+				v.report(key, "", "unknown key %q", key.Value)
+			} else {
+				v.report(key, "", "unknown key %q in %s", key.Value, path)
+			}
+			continue
+		}
+		v.validate(n.Content[i+1], f.Type, joinConfigPath(path, key.Value), joinConfigPath(schemaPath, key.Value))
+	}
+}
+
+// validateAny checks fields the schema allows several types for
+func (v *configValidator) validateAny(n *yaml.Node, path, schemaPath string) {
+	switch schemaPath {
+	case "ports.port":
+		if n.Kind != yaml.ScalarNode {
+			v.report(n, path, "expected a port number or range")
+			return
+		}
+		if portRangeRegexp.MatchString(n.Value) {
+			return
+		}
+		port, err := strconv.ParseUint(n.Value, 10, 16)
+		if err != nil || port == 0 {
+			v.report(n, path, "%q is not a valid port number or range", n.Value)
+		}
+		return
+	}
+
+	t, ok := gitpodConfigObjects[schemaPath]
+	if !ok {
+		return
+	}
+	if n.Kind == yaml.MappingNode {
+		v.validate(n, t, path, schemaPath)
+	}
+}
+
+func hasConfigKey(n *yaml.Node, key string) bool {
+	for i := 0; i < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return true
+		}
+	}
+	return false
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// gitpodTask is a task as far as running it is concerned. It matches the tasks
+// of .gitpod.yml as well as the ones in GITPOD_TASKS.
+type gitpodTask struct {
+	Name    string                 `yaml:"name" json:"name,omitempty"`
+	Before  string                 `yaml:"before" json:"before,omitempty"`
+	Init    string                 `yaml:"init" json:"init,omitempty"`
+	Command string                 `yaml:"command" json:"command,omitempty"`
+	Env     map[string]interface{} `yaml:"env" json:"env,omitempty"`
+}
+
+// environment produces the environment variables of a task the way supervisor does
+func (t gitpodTask) environment() map[string]string {
+	res := make(map[string]string, len(t.Env))
+	for k, v := range t.Env {
+		if s, ok := v.(string); ok {
+			res[k] = s
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		res[k] = string(b)
+	}
+	return res
+}
+
+// command produces the command line which runs all phases of a task
+func (t gitpodTask) command() string {
+	var commands []string
+	for _, c := range []string{t.Before, t.Init, t.Command} {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		commands = append(commands, fmt.Sprintf("{\n%s\n}", c))
+	}
+	return strings.Join(commands, " && ")
+}
+
+func (t gitpodTask) equal(o gitpodTask) bool {
+	return t.Before == o.Before && t.Init == o.Init && t.Command == o.Command && reflect.DeepEqual(t.environment(), o.environment())
+}
+
+// selectTasks returns the indices of the tasks which are named in selection by name or index, or of all tasks
+// which differ from the ones the workspace was started with if there's no selection.
+func selectTasks(tasks, started []gitpodTask, selection []string) ([]int, error) {
+	if len(selection) == 0 {
+		var res []int
+		for i, t := range tasks {
+			if i < len(started) && t.equal(started[i]) {
+				continue
+			}
+			res = append(res, i)
+		}
+		return res, nil
+	}
+
+	var res []int
+	for _, s := range selection {
+		idx := -1
+		for i, t := range tasks {
+			if t.Name != "" && t.Name == s {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			i, err := strconv.Atoi(s)
+			if err != nil || i < 0 || i >= len(tasks) {
+				return nil, fmt.Errorf("there is no task %q", s)
+			}
+			idx = i
+		}
+		res = append(res, idx)
+	}
+	return res, nil
+}
+
+// runTasks opens a supervisor terminal per selected task and runs the task in it
+func runTasks(workdir string, tasks []gitpodTask, selection []int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := supervisor.Dial()
+	if err != nil {
+		exitWithError(err)
+	}
+	defer conn.Close()
+	client := api.NewTerminalServiceClient(conn)
+
+	var aliases []string
+	for _, i := range selection {
+		t := tasks[i]
+		command := t.command()
+		if command == "" {
+			continue
+		}
+		resp, err := client.Open(ctx, &api.OpenTerminalRequest{
+			Workdir: workdir,
+			Env:     t.environment(),
+		})
+		if err != nil {
+			exitWithError(xerrors.Errorf("cannot open terminal: %w", err))
+		}
+		alias := resp.Terminal.Alias
+		_, err = client.Write(ctx, &api.WriteTerminalRequest{Alias: alias, Stdin: []byte(command + "\n")})
+		if err != nil {
+			exitWithError(xerrors.Errorf("cannot run task in terminal %s: %w", alias, err))
+		}
+
+		name := t.Name
+		if name == "" {
+			name = fmt.Sprintf("tasks[%d]", i)
+		}
+		fmt.Printf("running %s in terminal %s\n", name, alias)
+		aliases = append(aliases, alias)
+	}
+
+	if len(aliases) != 1 {
+		for _, alias := range aliases {
+			fmt.Printf("attach using: gp tasks attach %s\n", alias)
+		}
+		return
+	}
+	cancel()
+	supervisor.AttachToTerminal(context.Background(), client, aliases[0], supervisor.AttachToTerminalOpts{Interactive: true})
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVarP(&validateCmdOpts.File, "file", "f", "", "the .gitpod.yml to validate (defaults to the one in $GITPOD_REPO_ROOT)")
+	validateCmd.Flags().BoolVar(&validateCmdOpts.Run, "run", false, "run the tasks which differ from the ones this workspace started with")
+	validateCmd.Flags().StringSliceVarP(&validateCmdOpts.Tasks, "task", "t", nil, "run this task (name or index) - may be given multiple times")
+}
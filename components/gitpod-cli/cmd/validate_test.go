@@ -0,0 +1,179 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateGitpodConfig(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Content     string
+		Expectation []configProblem
+		Error       bool
+	}{
+		{
+			Desc: "valid",
+			Content: `image:
+  file: .gitpod.Dockerfile
+ports:
+  - port: 3000
+    onOpen: open-preview
+    description: the frontend
+  - port: 5900-5999
+tasks:
+  - name: dev
+    init: npm install
+    command: npm run dev
+    env:
+      NODE_ENV: development
+      DEBUG: true
+vscode:
+  extensions:
+    - golang.go
+`,
+		},
+		{
+			Desc:    "empty",
+			Content: "",
+		},
+		{
+			Desc: "unknown keys",
+			Content: `tasks:
+  - init: npm install
+    comand: npm run dev
+foo: bar
+`,
+			Expectation: []configProblem{
+				{Line: 3, Column: 5, Message: `unknown key "comand" in tasks[0]`},
+				{Line: 4, Column: 1, Message: `unknown key "foo"`},
+			},
+		},
+		{
+			Desc: "invalid values",
+			Content: `ports:
+  - port: 99999
+    onOpen: open-tab
+  - onOpen: ignore
+tasks:
+  - openIn: left
+    command:
+      - npm run dev
+`,
+			Expectation: []configProblem{
+				{Line: 2, Column: 11, Message: `ports[0].port: "99999" is not a valid port number or range`},
+				{Line: 3, Column: 13, Message: `ports[0].onOpen: "open-tab" is not one of open-browser, open-preview, notify, ignore`},
+				{Line: 4, Column: 5, Message: `ports[1]: "port" is required`},
+				{Line: 8, Column: 7, Message: `tasks[0].command: expected a string`},
+			},
+		},
+		{
+			Desc: "image object",
+			Content: `image:
+  context: .
+`,
+			Expectation: []configProblem{
+				{Line: 2, Column: 3, Message: `image: "file" is required`},
+			},
+		},
+		{
+			Desc:    "no YAML",
+			Content: "tasks: [",
+			Error:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			act, err := validateGitpodConfig([]byte(test.Content))
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.Expectation, act); diff != "" {
+				t.Errorf("unexpected problems (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLoadGitpodConfigEnums(t *testing.T) {
+	act, err := loadGitpodConfigEnums([]byte(`{
+	"properties": {
+		"ports": {"type": "array", "items": {"properties": {"visibility": {"enum": ["private", "public"]}}}},
+		"labels": {"additionalProperties": {"enum": ["a", "b"]}},
+		"addCheck": {"enum": [true, false, "prevent-merge-on-error"]},
+		"image": {"oneOf": [{"type": "string"}, {"properties": {"kind": {"enum": ["file"]}}}]}
+	},
+	"additionalProperties": false
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string][]string{
+		"ports.visibility": {"private", "public"},
+		"labels.*":         {"a", "b"},
+		"addCheck":         {"prevent-merge-on-error"},
+		"image.kind":       {"file"},
+	}
+	if diff := cmp.Diff(exp, act); diff != "" {
+		t.Errorf("unexpected enums (-want +got):\n%s", diff)
+	}
+
+	// the enums of the embedded schema
+	if diff := cmp.Diff([]string{"split-left", "split-right", "tab-before", "tab-after"}, gitpodConfigEnums["tasks.openMode"]); diff != "" {
+		t.Errorf("unexpected tasks.openMode enum (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"stable", "latest", "both"}, gitpodConfigEnums["jetbrains.intellij.prebuilds.version"]); diff != "" {
+		t.Errorf("unexpected jetbrains.intellij.prebuilds.version enum (-want +got):\n%s", diff)
+	}
+}
+
+func TestSelectTasks(t *testing.T) {
+	tasks := []gitpodTask{
+		{Name: "backend", Init: "go build ./...", Command: "go run .", Env: map[string]interface{}{"PORT": 8080}},
+		{Name: "frontend", Init: "yarn", Command: "yarn start"},
+		{Command: "echo hello"},
+	}
+	started := []gitpodTask{
+		{Name: "backend", Init: "go build ./...", Command: "go run .", Env: map[string]interface{}{"PORT": float64(8080)}},
+		{Name: "frontend", Init: "npm install", Command: "yarn start"},
+	}
+
+	tests := []struct {
+		Desc        string
+		Selection   []string
+		Expectation []int
+		Error       bool
+	}{
+		{Desc: "changed tasks", Expectation: []int{1, 2}},
+		{Desc: "by name", Selection: []string{"backend"}, Expectation: []int{0}},
+		{Desc: "by index", Selection: []string{"2", "frontend"}, Expectation: []int{2, 1}},
+		{Desc: "unknown task", Selection: []string{"database"}, Error: true},
+		{Desc: "index out of range", Selection: []string{"3"}, Error: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			act, err := selectTasks(tasks, started, test.Selection)
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.Expectation, act); diff != "" {
+				t.Errorf("unexpected tasks (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGitpodTaskCommand(t *testing.T) {
+	task := gitpodTask{Before: "export FOO=bar", Command: "echo $FOO"}
+	expectation := "{\nexport FOO=bar\n} && {\necho $FOO\n}"
+	if act := task.command(); act != expectation {
+		t.Errorf("unexpected command: %q, expected %q", act, expectation)
+	}
+}
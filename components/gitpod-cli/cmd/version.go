@@ -7,6 +7,7 @@ package cmd
 import (
 	_ "embed"
 	"fmt"
+	"io"
 
 	gitpod "github.com/gitpod-io/gitpod/gitpod-cli/pkg/gitpod"
 
@@ -20,10 +21,19 @@ var versionCmd = &cobra.Command{
 	Short:  "Prints the version of the CLI",
 	Args:   cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println(gitpod.Version)
+		res := versionOutput{Version: gitpod.Version}
+		printOutput(&res, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, res.Version)
+			return err
+		})
 	},
 }
 
+// versionOutput is how gp version prints the version in the JSON and YAML formats
+type versionOutput struct {
+	Version string `json:"version"`
+}
+
 func init() {
 	rootCmd.AddCommand(versionCmd)
 }
@@ -14,8 +14,9 @@ type gitpodPort struct {
 }
 
 type gitpodTask struct {
-	Command string `yaml:"command,omitempty"`
+	Name    string `yaml:"name,omitempty"`
 	Init    string `yaml:"init,omitempty"`
+	Command string `yaml:"command,omitempty"`
 }
 
 type GitpodFile struct {
@@ -56,3 +57,12 @@ func (cfg *GitpodFile) AddTask(task ...string) {
 		})
 	}
 }
+
+// AddStackTask adds a startup task suggested for a detected stack
+func (cfg *GitpodFile) AddStackTask(task StackTask) {
+	cfg.Tasks = append(cfg.Tasks, gitpodTask{
+		Name:    task.Name,
+		Init:    task.Init,
+		Command: task.Command,
+	})
+}
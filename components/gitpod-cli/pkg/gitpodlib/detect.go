@@ -0,0 +1,445 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package gitpodlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultImage is the workspace image used when .gitpod.yml does not configure one
+const DefaultImage = "gitpod/workspace-full"
+
+// StackTask is a startup task suggested for a stack
+type StackTask struct {
+	Name    string
+	Init    string
+	Command string
+}
+
+// Stack is a technology detected in a project together with the configuration we suggest for it
+type Stack struct {
+	Name  string
+	Image string
+	Tasks []StackTask
+	Ports []int32
+}
+
+// stackDetector inspects a project and returns nil if the project does not use its stack
+type stackDetector func(dir string) (*Stack, error)
+
+var stackDetectors = []stackDetector{
+	detectGo,
+	detectNode,
+	detectMaven,
+	detectGradle,
+	detectPython,
+	detectRust,
+	detectDockerCompose,
+}
+
+// DetectStacks inspects the project in dir and returns the stacks it uses
+func DetectStacks(dir string) ([]*Stack, error) {
+	var res []*Stack
+	for _, detect := range stackDetectors {
+		stack, err := detect(dir)
+		if err != nil {
+			return nil, err
+		}
+		if stack != nil {
+			res = append(res, stack)
+		}
+	}
+	return res, nil
+}
+
+// SuggestImage returns the workspace image which suits all stacks, or an empty string if that's the default image
+func SuggestImage(stacks []*Stack) string {
+	var img string
+	for _, s := range stacks {
+		if s.Image == "" || s.Image == img {
+			continue
+		}
+		if img != "" {
+			// only the default image comes with all the tools
+			return ""
+		}
+		img = s.Image
+	}
+	if img == DefaultImage {
+		return ""
+	}
+	return img
+}
+
+func detectGo(dir string) (*Stack, error) {
+	if !fileExists(dir, "go.mod") {
+		return nil, nil
+	}
+	task := StackTask{Name: "Go", Init: "go mod download && go build ./..."}
+	if fileExists(dir, "main.go") {
+		task.Command = "go run ."
+	}
+	return &Stack{Name: "Go", Image: "gitpod/workspace-go", Tasks: []StackTask{task}}, nil
+}
+
+type packageJSON struct {
+	PackageManager  string            `json:"packageManager"`
+	Scripts         map[string]string `json:"scripts"`
+	Dependencies    map[string]string `json:"dependencies"`
+	DevDependencies map[string]string `json:"devDependencies"`
+}
+
+// nodeFrameworks are the default ports of frameworks, looked up in order
+var nodeFrameworks = []struct {
+	Dependency string
+	Port       int32
+	Configs    []string
+}{
+	{Dependency: "next", Port: 3000},
+	{Dependency: "nuxt", Port: 3000, Configs: []string{"nuxt.config.js", "nuxt.config.ts"}},
+	{Dependency: "@angular/core", Port: 4200, Configs: []string{"angular.json"}},
+	{Dependency: "@sveltejs/kit", Port: 5173, Configs: []string{"vite.config.js", "vite.config.ts"}},
+	{Dependency: "vite", Port: 5173, Configs: []string{"vite.config.js", "vite.config.ts", "vite.config.mjs"}},
+	{Dependency: "react-scripts", Port: 3000},
+	{Dependency: "gatsby", Port: 8000},
+	{Dependency: "express", Port: 3000},
+}
+
+var (
+	scriptPortRegexp = regexp.MustCompile(`(?:--port|-p)[ =](\d+)`)
+	configPortRegexp = regexp.MustCompile(`"?port"?\s*:\s*(\d+)`)
+)
+
+func detectNode(dir string) (*Stack, error) {
+	content, err := readProjectFile(dir, "package.json")
+	if err != nil || content == nil {
+		return nil, err
+	}
+	var pkg packageJSON
+	err = json.Unmarshal(content, &pkg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse package.json: %w", err)
+	}
+
+	pm := "npm"
+	switch {
+	case strings.HasPrefix(pkg.PackageManager, "pnpm"), fileExists(dir, "pnpm-lock.yaml"):
+		pm = "pnpm"
+	case strings.HasPrefix(pkg.PackageManager, "yarn"), fileExists(dir, "yarn.lock"):
+		pm = "yarn"
+	}
+
+	task := StackTask{Name: "Node.js", Init: pm + " install"}
+	var script string
+	switch {
+	case pkg.Scripts["dev"] != "":
+		script = pkg.Scripts["dev"]
+		task.Command = pm + " run dev"
+	case pkg.Scripts["start"] != "":
+		script = pkg.Scripts["start"]
+		task.Command = pm + " start"
+	}
+	if pm == "npm" && fileExists(dir, "package-lock.json") {
+		task.Init = "npm ci"
+	}
+	if pkg.Scripts["build"] != "" {
+		task.Init += " && " + pm + " run build"
+	}
+
+	stack := &Stack{Name: fmt.Sprintf("Node.js (%s)", pm), Image: "gitpod/workspace-node", Tasks: []StackTask{task}}
+	if task.Command == "" {
+		return stack, nil
+	}
+	if m := scriptPortRegexp.FindStringSubmatch(script); m != nil {
+		if port := validPort(m[1]); port != 0 {
+			stack.Ports = []int32{port}
+		}
+		return stack, nil
+	}
+	for _, fw := range nodeFrameworks {
+		if _, ok := pkg.Dependencies[fw.Dependency]; !ok {
+			if _, ok := pkg.DevDependencies[fw.Dependency]; !ok {
+				continue
+			}
+		}
+		port, err := findConfiguredPort(dir, fw.Configs, configPortRegexp)
+		if err != nil {
+			return nil, err
+		}
+		if port == 0 {
+			port = fw.Port
+		}
+		stack.Ports = []int32{port}
+		break
+	}
+	return stack, nil
+}
+
+var springPortRegexp = regexp.MustCompile(`(?m)^\s*(?:server\.)?port\s*[=:]\s*(\d+)`)
+
+// springPort returns the port a Spring Boot application listens on
+func springPort(dir string) (int32, error) {
+	res, err := findConfiguredPort(filepath.Join(dir, "src", "main", "resources"), []string{"application.properties", "application.yml", "application.yaml"}, springPortRegexp)
+	if err != nil {
+		return 0, err
+	}
+	if res == 0 {
+		res = 8080
+	}
+	return res, nil
+}
+
+func detectMaven(dir string) (*Stack, error) {
+	content, err := readProjectFile(dir, "pom.xml")
+	if err != nil || content == nil {
+		return nil, err
+	}
+
+	mvn := "mvn"
+	if fileExists(dir, "mvnw") {
+		mvn = "./mvnw"
+	}
+	stack := &Stack{Name: "Java (Maven)", Image: "gitpod/workspace-java-17"}
+	task := StackTask{Name: "Maven", Init: mvn + " install -DskipTests"}
+	if strings.Contains(string(content), "spring-boot") {
+		task.Command = mvn + " spring-boot:run"
+		port, err := springPort(dir)
+		if err != nil {
+			return nil, err
+		}
+		stack.Ports = []int32{port}
+	}
+	stack.Tasks = []StackTask{task}
+	return stack, nil
+}
+
+func detectGradle(dir string) (*Stack, error) {
+	var content []byte
+	for _, fn := range []string{"build.gradle", "build.gradle.kts"} {
+		var err error
+		content, err = readProjectFile(dir, fn)
+		if err != nil {
+			return nil, err
+		}
+		if content != nil {
+			break
+		}
+	}
+	if content == nil {
+		return nil, nil
+	}
+
+	gradle := "gradle"
+	if fileExists(dir, "gradlew") {
+		gradle = "./gradlew"
+	}
+	stack := &Stack{Name: "Java (Gradle)", Image: "gitpod/workspace-java-17"}
+	task := StackTask{Name: "Gradle", Init: gradle + " build -x test"}
+	if strings.Contains(string(content), "org.springframework.boot") {
+		task.Command = gradle + " bootRun"
+		port, err := springPort(dir)
+		if err != nil {
+			return nil, err
+		}
+		stack.Ports = []int32{port}
+	}
+	stack.Tasks = []StackTask{task}
+	return stack, nil
+}
+
+func detectPython(dir string) (*Stack, error) {
+	requirements, err := readProjectFile(dir, "requirements.txt")
+	if err != nil {
+		return nil, err
+	}
+	pyproject, err := readProjectFile(dir, "pyproject.toml")
+	if err != nil {
+		return nil, err
+	}
+	if requirements == nil && pyproject == nil {
+		return nil, nil
+	}
+
+	stack := &Stack{Name: "Python", Image: "gitpod/workspace-python"}
+	task := StackTask{Name: "Python"}
+	var run string
+	switch {
+	case strings.Contains(string(pyproject), "[tool.poetry]"):
+		stack.Name = "Python (poetry)"
+		task.Init = "poetry install"
+		run = "poetry run "
+	case requirements != nil:
+		task.Init = "pip install -r requirements.txt"
+	default:
+		task.Init = "pip install -e ."
+	}
+
+	deps := strings.ToLower(string(requirements) + string(pyproject))
+	switch {
+	case fileExists(dir, "manage.py"):
+		task.Command = run + "python manage.py runserver 0.0.0.0:8000"
+		stack.Ports = []int32{8000}
+	case strings.Contains(deps, "flask"):
+		task.Command = run + "flask run --host=0.0.0.0"
+		stack.Ports = []int32{5000}
+	case strings.Contains(deps, "fastapi") && fileExists(dir, "main.py"):
+		task.Command = run + "uvicorn main:app --host 0.0.0.0 --port 8000 --reload"
+		stack.Ports = []int32{8000}
+	}
+	stack.Tasks = []StackTask{task}
+	return stack, nil
+}
+
+func detectRust(dir string) (*Stack, error) {
+	content, err := readProjectFile(dir, "Cargo.toml")
+	if err != nil || content == nil {
+		return nil, err
+	}
+
+	stack := &Stack{Name: "Rust", Image: "gitpod/workspace-rust"}
+	task := StackTask{Name: "Rust", Init: "cargo build"}
+	if fileExists(dir, filepath.Join("src", "main.rs")) {
+		task.Command = "cargo run"
+		if strings.Contains(string(content), "rocket") {
+			stack.Ports = []int32{8000}
+		}
+	}
+	stack.Tasks = []StackTask{task}
+	return stack, nil
+}
+
+type composeFile struct {
+	Services map[string]struct {
+		Ports []interface{} `yaml:"ports"`
+	} `yaml:"services"`
+}
+
+func detectDockerCompose(dir string) (*Stack, error) {
+	var (
+		fn      string
+		content []byte
+	)
+	for _, fn = range []string{"docker-compose.yml", "docker-compose.yaml", "compose.yml", "compose.yaml"} {
+		var err error
+		content, err = readProjectFile(dir, fn)
+		if err != nil {
+			return nil, err
+		}
+		if content != nil {
+			break
+		}
+	}
+	if content == nil {
+		return nil, nil
+	}
+	var compose composeFile
+	err := yaml.Unmarshal(content, &compose)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", fn, err)
+	}
+
+	stack := &Stack{
+		Name: "Docker Compose",
+		// docker is not available in the language specific images
+		Image: DefaultImage,
+		Tasks: []StackTask{{Name: "Services", Init: "docker-compose pull", Command: "docker-compose up"}},
+	}
+	if fn != "docker-compose.yml" {
+		stack.Tasks[0].Init = "docker-compose -f " + fn + " pull"
+		stack.Tasks[0].Command = "docker-compose -f " + fn + " up"
+	}
+	ports := make(map[int32]struct{})
+	for _, svc := range compose.Services {
+		for _, p := range svc.Ports {
+			if port := composeHostPort(p); port != 0 {
+				ports[port] = struct{}{}
+			}
+		}
+	}
+	for p := range ports {
+		stack.Ports = append(stack.Ports, p)
+	}
+	sort.Slice(stack.Ports, func(i, j int) bool { return stack.Ports[i] < stack.Ports[j] })
+	return stack, nil
+}
+
+// composeHostPort returns the port a docker-compose port mapping publishes on the host, or 0 for ranges and
+// mappings without a fixed host port.
+func composeHostPort(mapping interface{}) int32 {
+	switch m := mapping.(type) {
+	case string:
+		m = strings.TrimSuffix(strings.TrimSuffix(m, "/tcp"), "/udp")
+		segs := strings.Split(m, ":")
+		if len(segs) < 2 {
+			// only the container port was given, docker picks a random host port
+			return 0
+		}
+		return validPort(segs[len(segs)-2])
+	case map[interface{}]interface{}:
+		switch p := m["published"].(type) {
+		case int:
+			return validPort(strconv.Itoa(p))
+		case string:
+			return validPort(p)
+		}
+	}
+	return 0
+}
+
+// findConfiguredPort looks for a port in the first of the config files in dir which exists
+func findConfiguredPort(dir string, configs []string, expr *regexp.Regexp) (int32, error) {
+	for _, fn := range configs {
+		content, err := readProjectFile(dir, fn)
+		if err != nil {
+			return 0, err
+		}
+		if content == nil {
+			continue
+		}
+		m := expr.FindSubmatch(content)
+		if m == nil {
+			return 0, nil
+		}
+		return validPort(string(m[1])), nil
+	}
+	return 0, nil
+}
+
+func validPort(p string) int32 {
+	port, err := strconv.ParseUint(strings.TrimSpace(p), 10, 16)
+	if err != nil {
+		return 0
+	}
+	return int32(port)
+}
+
+// readProjectFile returns the content of a file in dir, or nil if the file does not exist
+func readProjectFile(dir, name string) ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		content = []byte{}
+	}
+	return content, nil
+}
+
+func fileExists(dir, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
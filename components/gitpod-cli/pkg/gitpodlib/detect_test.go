@@ -0,0 +1,171 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package gitpodlib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDetectStacks(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Files       map[string]string
+		Expectation []*Stack
+	}{
+		{
+			Desc: "empty project",
+		},
+		{
+			Desc:  "go",
+			Files: map[string]string{"go.mod": "module example.com/foo", "main.go": "package main"},
+			Expectation: []*Stack{
+				{Name: "Go", Image: "gitpod/workspace-go", Tasks: []StackTask{{Name: "Go", Init: "go mod download && go build ./...", Command: "go run ."}}},
+			},
+		},
+		{
+			Desc: "next with yarn",
+			Files: map[string]string{
+				"package.json": `{"scripts": {"dev": "next dev", "build": "next build"}, "dependencies": {"next": "12.1.0"}}`,
+				"yarn.lock":    "",
+			},
+			Expectation: []*Stack{
+				{Name: "Node.js (yarn)", Image: "gitpod/workspace-node", Tasks: []StackTask{{Name: "Node.js", Init: "yarn install && yarn run build", Command: "yarn run dev"}}, Ports: []int32{3000}},
+			},
+		},
+		{
+			Desc: "vite with configured port",
+			Files: map[string]string{
+				"package.json":      `{"scripts": {"dev": "vite"}, "devDependencies": {"vite": "2.8.0"}}`,
+				"package-lock.json": "{}",
+				"vite.config.ts":    "export default defineConfig({ server: { port: 8081 } })",
+			},
+			Expectation: []*Stack{
+				{Name: "Node.js (npm)", Image: "gitpod/workspace-node", Tasks: []StackTask{{Name: "Node.js", Init: "npm ci", Command: "npm run dev"}}, Ports: []int32{8081}},
+			},
+		},
+		{
+			Desc: "port in script",
+			Files: map[string]string{
+				"package.json":   `{"packageManager": "pnpm@6.32.2", "scripts": {"start": "node server.js --port 4000"}, "dependencies": {"express": "4.17.3"}}`,
+				"pnpm-lock.yaml": "",
+			},
+			Expectation: []*Stack{
+				{Name: "Node.js (pnpm)", Image: "gitpod/workspace-node", Tasks: []StackTask{{Name: "Node.js", Init: "pnpm install", Command: "pnpm start"}}, Ports: []int32{4000}},
+			},
+		},
+		{
+			Desc: "spring boot with maven wrapper",
+			Files: map[string]string{
+				"pom.xml": "<project><parent><artifactId>spring-boot-starter-parent</artifactId></parent></project>",
+				"mvnw":    "",
+				"src/main/resources/application.properties": "server.port=9090\n",
+			},
+			Expectation: []*Stack{
+				{Name: "Java (Maven)", Image: "gitpod/workspace-java-17", Tasks: []StackTask{{Name: "Maven", Init: "./mvnw install -DskipTests", Command: "./mvnw spring-boot:run"}}, Ports: []int32{9090}},
+			},
+		},
+		{
+			Desc:  "gradle",
+			Files: map[string]string{"build.gradle.kts": "plugins { java }"},
+			Expectation: []*Stack{
+				{Name: "Java (Gradle)", Image: "gitpod/workspace-java-17", Tasks: []StackTask{{Name: "Gradle", Init: "gradle build -x test"}}},
+			},
+		},
+		{
+			Desc:  "django",
+			Files: map[string]string{"requirements.txt": "Django==4.0.3\n", "manage.py": ""},
+			Expectation: []*Stack{
+				{Name: "Python", Image: "gitpod/workspace-python", Tasks: []StackTask{{Name: "Python", Init: "pip install -r requirements.txt", Command: "python manage.py runserver 0.0.0.0:8000"}}, Ports: []int32{8000}},
+			},
+		},
+		{
+			Desc:  "flask with poetry",
+			Files: map[string]string{"pyproject.toml": "[tool.poetry]\n[tool.poetry.dependencies]\nFlask = \"^2.0\"\n"},
+			Expectation: []*Stack{
+				{Name: "Python (poetry)", Image: "gitpod/workspace-python", Tasks: []StackTask{{Name: "Python", Init: "poetry install", Command: "poetry run flask run --host=0.0.0.0"}}, Ports: []int32{5000}},
+			},
+		},
+		{
+			Desc:  "rust",
+			Files: map[string]string{"Cargo.toml": "[package]\nname = \"foo\"\n", "src/main.rs": ""},
+			Expectation: []*Stack{
+				{Name: "Rust", Image: "gitpod/workspace-rust", Tasks: []StackTask{{Name: "Rust", Init: "cargo build", Command: "cargo run"}}},
+			},
+		},
+		{
+			Desc: "go and docker compose",
+			Files: map[string]string{
+				"go.mod": "module example.com/foo",
+				"compose.yaml": `services:
+  db:
+    image: postgres
+    ports:
+      - "5432:5432"
+  web:
+    build: .
+    ports:
+      - 127.0.0.1:8080:80/tcp
+      - 9000
+      - target: 80
+        published: 8081
+`,
+			},
+			Expectation: []*Stack{
+				{Name: "Go", Image: "gitpod/workspace-go", Tasks: []StackTask{{Name: "Go", Init: "go mod download && go build ./..."}}},
+				{Name: "Docker Compose", Image: DefaultImage, Tasks: []StackTask{{Name: "Services", Init: "docker-compose -f compose.yaml pull", Command: "docker-compose -f compose.yaml up"}}, Ports: []int32{5432, 8080, 8081}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			dir := t.TempDir()
+			for fn, content := range test.Files {
+				fn = filepath.Join(dir, fn)
+				err := os.MkdirAll(filepath.Dir(fn), 0755)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = os.WriteFile(fn, []byte(content), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			act, err := DetectStacks(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Expectation, act); diff != "" {
+				t.Errorf("unexpected stacks (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSuggestImage(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Stacks      []*Stack
+		Expectation string
+	}{
+		{"no stacks", nil, ""},
+		{"single stack", []*Stack{{Image: "gitpod/workspace-go"}}, "gitpod/workspace-go"},
+		{"same image", []*Stack{{Image: "gitpod/workspace-java-17"}, {Image: "gitpod/workspace-java-17"}}, "gitpod/workspace-java-17"},
+		{"different images", []*Stack{{Image: "gitpod/workspace-go"}, {Image: "gitpod/workspace-node"}}, ""},
+		{"default image", []*Stack{{Image: DefaultImage}}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			if act := SuggestImage(test.Stacks); act != test.Expectation {
+				t.Errorf("unexpected image: %q, expected %q", act, test.Expectation)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/sourcegraph/jsonrpc2"
+	"github.com/spf13/cobra"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the format in which gp commands print their results and errors
+type Format string
+
+const (
+	// Text is the human readable output of a command
+	Text Format = "text"
+	// JSON prints results and errors as JSON
+	JSON Format = "json"
+	// YAML prints results and errors as YAML
+	YAML Format = "yaml"
+)
+
+// String implements pflag.Value
+func (f *Format) String() string {
+	if *f == "" {
+		return string(Text)
+	}
+	return string(*f)
+}
+
+// Set implements pflag.Value
+func (f *Format) Set(v string) error {
+	switch Format(v) {
+	case Text, JSON, YAML:
+		*f = Format(v)
+		return nil
+	default:
+		return xerrors.Errorf("unknown output format %q (supported formats are text, json and yaml)", v)
+	}
+}
+
+// Type implements pflag.Value
+func (f *Format) Type() string {
+	return "format"
+}
+
+// FromCommand returns the format selected using the --output flag of cmd or its parents.
+func FromCommand(cmd *cobra.Command) Format {
+	flag := cmd.Flag("output")
+	if flag == nil {
+		return Text
+	}
+	return Format(flag.Value.String())
+}
+
+// Write writes v as JSON or YAML using the JSON field names of v. For the text format, text is called instead.
+func Write(w io.Writer, format Format, v interface{}, text func(w io.Writer) error) error {
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		out, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	default:
+		return text(w)
+	}
+}
+
+// toYAML marshals v to JSON and converts the result to YAML, so that JSON field names and their order are retained.
+func toYAML(v interface{}) ([]byte, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	err = yaml.Unmarshal(js, &doc)
+	if err != nil {
+		return nil, err
+	}
+	resetStyle(&doc)
+	return yaml.Marshal(&doc)
+}
+
+// resetStyle turns the flow style and quoting of JSON into the default block style of YAML.
+func resetStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetStyle(c)
+	}
+}
+
+// Exit codes of gp. Scripts depend on them, hence they must not change.
+const (
+	// ExitCodeError is returned for all failures that have no exit code of their own
+	ExitCodeError = 1
+	// ExitCodeUsage is returned if the command line is invalid
+	ExitCodeUsage = 2
+	// ExitCodeNotInWorkspace is returned if gp is used outside of a Gitpod workspace
+	ExitCodeNotInWorkspace = 3
+	// ExitCodeSupervisorUnreachable is returned if gp cannot talk to supervisor
+	ExitCodeSupervisorUnreachable = 4
+	// ExitCodePermissionDenied is returned if Gitpod or the file system denied an operation
+	ExitCodePermissionDenied = 5
+)
+
+// ErrNotInWorkspace is returned by commands which need information only available in a Gitpod workspace
+var ErrNotInWorkspace = xerrors.New("not running in a Gitpod workspace")
+
+// ErrSupervisorUnreachable is returned if gp cannot establish a connection to supervisor
+var ErrSupervisorUnreachable = xerrors.New("cannot connect to supervisor")
+
+// ExitCode returns the exit code gp returns for err.
+func ExitCode(err error) int {
+	return exitCode(err, os.Getenv("GITPOD_WORKSPACE_ID") != "")
+}
+
+func exitCode(err error, inWorkspace bool) int {
+	if errors.Is(err, ErrNotInWorkspace) {
+		return ExitCodeNotInWorkspace
+	}
+	if errors.Is(err, ErrSupervisorUnreachable) {
+		if !inWorkspace {
+			return ExitCodeNotInWorkspace
+		}
+		return ExitCodeSupervisorUnreachable
+	}
+	if errors.Is(err, fs.ErrPermission) {
+		return ExitCodePermissionDenied
+	}
+
+	var rpcErr *jsonrpc2.Error
+	if errors.As(err, &rpcErr) {
+		// see ErrorCodes in gitpod-protocol
+		if rpcErr.Code == 401 || rpcErr.Code == 403 {
+			return ExitCodePermissionDenied
+		}
+		return ExitCodeError
+	}
+
+	// gp uses gRPC to talk to supervisor only
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		switch grpcErr.GRPCStatus().Code() {
+		case codes.PermissionDenied, codes.Unauthenticated:
+			return ExitCodePermissionDenied
+		case codes.Unavailable:
+			if !inWorkspace {
+				return ExitCodeNotInWorkspace
+			}
+			return ExitCodeSupervisorUnreachable
+		}
+	}
+	return ExitCodeError
+}
+
+// commandError is how errors are printed in the JSON and YAML formats
+type commandError struct {
+	Error    string `json:"error"`
+	ExitCode int    `json:"exitCode"`
+}
+
+// WriteError writes err in the given format.
+func WriteError(w io.Writer, format Format, err error, code int) error {
+	return Write(w, format, &commandError{Error: err.Error(), ExitCode: code}, func(w io.Writer) error {
+		_, werr := fmt.Fprintln(w, err.Error())
+		return werr
+	})
+}
+
+// Fail writes err in the given format to stderr and exits with the exit code for err.
+func Fail(format Format, err error) {
+	code := ExitCode(err)
+	_ = WriteError(os.Stderr, format, err, code)
+	os.Exit(code)
+}
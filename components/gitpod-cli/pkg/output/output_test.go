@@ -0,0 +1,115 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package output
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/jsonrpc2"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWrite(t *testing.T) {
+	type item struct {
+		Name  string   `json:"name"`
+		Value string   `json:"value,omitempty"`
+		Tags  []string `json:"tags"`
+	}
+	v := []item{
+		{Name: "b", Value: "123", Tags: []string{"x"}},
+		{Name: "a", Tags: []string{}},
+	}
+	text := func(w io.Writer) error {
+		_, err := io.WriteString(w, "some text\n")
+		return err
+	}
+	tests := []struct {
+		Format      Format
+		Expectation string
+	}{
+		{Text, "some text\n"},
+		{JSON, `[
+  {
+    "name": "b",
+    "value": "123",
+    "tags": [
+      "x"
+    ]
+  },
+  {
+    "name": "a",
+    "tags": []
+  }
+]
+`},
+		{YAML, `- name: b
+  value: "123"
+  tags:
+    - x
+- name: a
+  tags: []
+`},
+	}
+	for _, test := range tests {
+		t.Run(string(test.Format), func(t *testing.T) {
+			var out bytes.Buffer
+			err := Write(&out, test.Format, v, text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Expectation, out.String()); diff != "" {
+				t.Errorf("unexpected output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatSet(t *testing.T) {
+	var f Format
+	if f.String() != "text" {
+		t.Errorf("unexpected default format: %s", f.String())
+	}
+	if err := f.Set("yaml"); err != nil || f != YAML {
+		t.Errorf("cannot set format: %v", err)
+	}
+	if err := f.Set("xml"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Err         error
+		InWorkspace bool
+		Expectation int
+	}{
+		{"other error", xerrors.New("boom"), true, ExitCodeError},
+		{"not in workspace", xerrors.Errorf("cannot get URL: %w", ErrNotInWorkspace), true, ExitCodeNotInWorkspace},
+		{"permission denied by file system", xerrors.Errorf("cannot write: %w", os.ErrPermission), true, ExitCodePermissionDenied},
+		{"permission denied by server", &jsonrpc2.Error{Code: 403, Message: "operation not permitted"}, true, ExitCodePermissionDenied},
+		{"not authenticated by server", xerrors.Errorf("cannot get env vars: %w", &jsonrpc2.Error{Code: 401}), true, ExitCodePermissionDenied},
+		{"other server error", &jsonrpc2.Error{Code: 404}, true, ExitCodeError},
+		{"permission denied by supervisor", status.Error(codes.PermissionDenied, "denied"), true, ExitCodePermissionDenied},
+		{"supervisor unreachable", xerrors.Errorf("failed getting workspace info from supervisor: %w", status.Error(codes.Unavailable, "connection refused")), true, ExitCodeSupervisorUnreachable},
+		{"supervisor unreachable outside of workspace", status.Error(codes.Unavailable, "connection refused"), false, ExitCodeNotInWorkspace},
+		{"cannot dial supervisor", xerrors.Errorf("%w: invalid address", ErrSupervisorUnreachable), true, ExitCodeSupervisorUnreachable},
+		{"other supervisor error", status.Error(codes.NotFound, "not found"), true, ExitCodeError},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			act := exitCode(test.Err, test.InWorkspace)
+			if act != test.Expectation {
+				t.Errorf("unexpected exit code: %d, expected %d", act, test.Expectation)
+			}
+		})
+	}
+}
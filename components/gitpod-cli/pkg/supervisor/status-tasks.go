@@ -9,40 +9,30 @@ import (
 	"io"
 
 	"github.com/gitpod-io/gitpod/supervisor/api"
-	log "github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
 )
 
-func GetTasksList(ctx context.Context, client api.StatusServiceClient) []*api.TaskStatus {
+// GetTasksList returns the current status of all tasks in the workspace.
+func GetTasksList(ctx context.Context, client api.StatusServiceClient) ([]*api.TaskStatus, error) {
 	listen, err := client.TasksStatus(ctx, &api.TasksStatusRequest{Observe: false})
 	if err != nil {
-		log.WithError(err).Error("Cannot list tasks")
+		return nil, xerrors.Errorf("cannot list tasks: %w", err)
 	}
-
-	taskschan := make(chan []*api.TaskStatus)
-
-	go func() {
-		for {
-			resp, err := listen.Recv()
-			if err != nil && err != io.EOF {
-				panic(err)
-			}
-
-			chunk := resp.GetTasks()
-
-			if chunk == nil {
-				close(taskschan)
-				break
-			} else {
-				taskschan <- chunk
-			}
-		}
-	}()
-
-	return <-taskschan
+	resp, err := listen.Recv()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("cannot list tasks: %w", err)
+	}
+	return resp.GetTasks(), nil
 }
 
-func GetTasksListByState(ctx context.Context, client api.StatusServiceClient, filterState api.TaskState) []*api.TaskStatus {
-	tasks := GetTasksList(ctx, client)
+func GetTasksListByState(ctx context.Context, client api.StatusServiceClient, filterState api.TaskState) ([]*api.TaskStatus, error) {
+	tasks, err := GetTasksList(ctx, client)
+	if err != nil {
+		return nil, err
+	}
 
 	var filteredTasks []*api.TaskStatus
 
@@ -52,5 +42,5 @@ func GetTasksListByState(ctx context.Context, client api.StatusServiceClient, fi
 		}
 	}
 
-	return filteredTasks
+	return filteredTasks, nil
 }
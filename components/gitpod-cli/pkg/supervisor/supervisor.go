@@ -7,19 +7,22 @@ package supervisor
 import (
 	"os"
 
-	log "github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
 	"google.golang.org/grpc"
+
+	"github.com/gitpod-io/gitpod/gitpod-cli/pkg/output"
 )
 
-func Dial() *grpc.ClientConn {
+// Dial connects to supervisor at SUPERVISOR_ADDR, or at its default address if that is not set.
+func Dial() (*grpc.ClientConn, error) {
 	supervisorAddr := os.Getenv("SUPERVISOR_ADDR")
 	if supervisorAddr == "" {
 		supervisorAddr = "localhost:22999"
 	}
 	supervisorConn, err := grpc.Dial(supervisorAddr, grpc.WithInsecure())
 	if err != nil {
-		log.WithError(err).Fatal("cannot connect to supervisor")
+		return nil, xerrors.Errorf("%w: %v", output.ErrSupervisorUnreachable, err)
 	}
 
-	return supervisorConn
+	return supervisorConn, nil
 }
@@ -14,6 +14,16 @@ import (
 	"golang.org/x/xerrors"
 )
 
+// AdditionalRepositoriesItems
+type AdditionalRepositoriesItems struct {
+
+	// Path to where the repository should be checked out relative to `/workspace`. Defaults to the simple repository name.
+	CheckoutLocation string `yaml:"checkoutLocation,omitempty"`
+
+	// The url of the git repository to clone. Supports any context URLs.
+	Url string `yaml:"url"`
+}
+
 // Env Environment variables to set.
 type Env struct {
 }
@@ -28,12 +38,18 @@ type Github struct {
 // GitpodConfig
 type GitpodConfig struct {
 
+	// List of additional repositories that are part of this project.
+	AdditionalRepositories []*AdditionalRepositoriesItems `yaml:"additionalRepositories,omitempty"`
+
 	// Path to where the repository should be checked out.
 	CheckoutLocation string `yaml:"checkoutLocation,omitempty"`
 
 	// Git config values should be provided in pairs. E.g. `core.autocrlf: input`. See https://git-scm.com/docs/git-config#_values.
 	GitConfig map[string]string `yaml:"gitConfig,omitempty"`
 
+	// Experimental network configuration in workspaces (deprecated). Enabled by default
+	ExperimentalNetwork bool `yaml:"experimentalNetwork,omitempty"`
+
 	// Configures Gitpod's GitHub app
 	Github *Github `yaml:"github,omitempty"`
 
@@ -43,6 +59,9 @@ type GitpodConfig struct {
 	// The Docker image to run your workspace in.
 	Image interface{} `yaml:"image,omitempty"`
 
+	// The main repository, containing the dev environment configuration.
+	MainConfiguration string `yaml:"mainConfiguration,omitempty"`
+
 	// List of exposed ports.
 	Ports []*PortsItems `yaml:"ports,omitempty"`
 
@@ -72,6 +91,12 @@ type Image_object struct {
 // PortsItems
 type PortsItems struct {
 
+	// A domain (e.g. app.example.com) under which this port is served when it is public. The domain must point to the Gitpod installation and have a TXT record _gitpod.<domain> containing gitpod-user=<your user ID>.
+	CustomDomain string `yaml:"customDomain,omitempty"`
+
+	// A description to identify what is this port used for.
+	Description string `yaml:"description,omitempty"`
+
 	// Port name (deprecated).
 	Name string `yaml:"name,omitempty"`
 
@@ -81,6 +106,9 @@ type PortsItems struct {
 	// The port number (e.g. 1337) or range (e.g. 3000-3999) to expose.
 	Port interface{} `yaml:"port"`
 
+	// Restricts who can access a public port. Requests which satisfy none of the configured methods are rejected. The workspace owner always has access.
+	Protection *PortProtection `yaml:"protection,omitempty"`
+
 	// The protocol to be used. (deprecated)
 	Protocol string `yaml:"protocol,omitempty"`
 
@@ -88,6 +116,29 @@ type PortsItems struct {
 	Visibility string `yaml:"visibility,omitempty"`
 }
 
+// PortProtection Restricts who can access a public port.
+type PortProtection struct {
+
+	// IP addresses or CIDR ranges (e.g. 10.0.0.0/8) from which the port can be accessed.
+	AllowedCIDRs []string `yaml:"allowedCIDRs,omitempty"`
+
+	// Require visitors to sign in with the OpenID Connect provider configured for this Gitpod installation.
+	OIDC *PortProtectionOIDC `yaml:"oidc,omitempty"`
+
+	// A shared password visitors have to enter before they can access the port.
+	Password string `yaml:"password,omitempty"`
+}
+
+// PortProtectionOIDC Require visitors to sign in with the OpenID Connect provider configured for this Gitpod installation.
+type PortProtectionOIDC struct {
+
+	// Email addresses which are allowed to access the port.
+	AllowedEmails []string `yaml:"allowedEmails,omitempty"`
+
+	// Email domains (e.g. example.com) whose users are allowed to access the port.
+	AllowedDomains []string `yaml:"allowedDomains,omitempty"`
+}
+
 // Prebuilds_object Set to true to enable workspace prebuilds, false to disable them. Defaults to true.
 type Prebuilds_object struct {
 
@@ -181,6 +232,69 @@ type JetBrainsPrebuilds struct {
 	Version string `yaml:"version,omitempty"`
 }
 
+func (strct *AdditionalRepositoriesItems) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0))
+	buf.WriteString("{")
+	comma := false
+	// Marshal the "checkoutLocation" field
+	if comma {
+		buf.WriteString(",")
+	}
+	buf.WriteString("\"checkoutLocation\": ")
+	if tmp, err := json.Marshal(strct.CheckoutLocation); err != nil {
+		return nil, err
+	} else {
+		buf.Write(tmp)
+	}
+	comma = true
+	// "Url" field is required
+	// only required object types supported for marshal checking (for now)
+	// Marshal the "url" field
+	if comma {
+		buf.WriteString(",")
+	}
+	buf.WriteString("\"url\": ")
+	if tmp, err := json.Marshal(strct.Url); err != nil {
+		return nil, err
+	} else {
+		buf.Write(tmp)
+	}
+	comma = true
+
+	buf.WriteString("}")
+	rv := buf.Bytes()
+	return rv, nil
+}
+
+func (strct *AdditionalRepositoriesItems) UnmarshalJSON(b []byte) error {
+	urlReceived := false
+	var jsonMap map[string]json.RawMessage
+	if err := json.Unmarshal(b, &jsonMap); err != nil {
+		return err
+	}
+	// parse all the defined properties
+	for k, v := range jsonMap {
+		switch k {
+		case "checkoutLocation":
+			if err := json.Unmarshal([]byte(v), &strct.CheckoutLocation); err != nil {
+				return err
+			}
+		case "url":
+			if err := json.Unmarshal([]byte(v), &strct.Url); err != nil {
+				return err
+			}
+			urlReceived = true
+		default:
+			return xerrors.Errorf("additional property not allowed: \"" + k + "\"")
+		}
+	}
+	// check if url (a required property) was received
+	if !urlReceived {
+		return errors.New("\"url\" is required but was not present")
+	}
+	return nil
+}
+
 func (strct *Github) MarshalJSON() ([]byte, error) {
 	buf := bytes.NewBuffer(make([]byte, 0))
 	buf.WriteString("{")
@@ -225,6 +339,17 @@ func (strct *GitpodConfig) MarshalJSON() ([]byte, error) {
 	buf := bytes.NewBuffer(make([]byte, 0))
 	buf.WriteString("{")
 	comma := false
+	// Marshal the "additionalRepositories" field
+	if comma {
+		buf.WriteString(",")
+	}
+	buf.WriteString("\"additionalRepositories\": ")
+	if tmp, err := json.Marshal(strct.AdditionalRepositories); err != nil {
+		return nil, err
+	} else {
+		buf.Write(tmp)
+	}
+	comma = true
 	// Marshal the "checkoutLocation" field
 	if comma {
 		buf.WriteString(",")
@@ -247,6 +372,17 @@ func (strct *GitpodConfig) MarshalJSON() ([]byte, error) {
 		buf.Write(tmp)
 	}
 	comma = true
+	// Marshal the "experimentalNetwork" field
+	if comma {
+		buf.WriteString(",")
+	}
+	buf.WriteString("\"experimentalNetwork\": ")
+	if tmp, err := json.Marshal(strct.ExperimentalNetwork); err != nil {
+		return nil, err
+	} else {
+		buf.Write(tmp)
+	}
+	comma = true
 	// Marshal the "github" field
 	if comma {
 		buf.WriteString(",")
@@ -280,6 +416,17 @@ func (strct *GitpodConfig) MarshalJSON() ([]byte, error) {
 		buf.Write(tmp)
 	}
 	comma = true
+	// Marshal the "mainConfiguration" field
+	if comma {
+		buf.WriteString(",")
+	}
+	buf.WriteString("\"mainConfiguration\": ")
+	if tmp, err := json.Marshal(strct.MainConfiguration); err != nil {
+		return nil, err
+	} else {
+		buf.Write(tmp)
+	}
+	comma = true
 	// Marshal the "ports" field
 	if comma {
 		buf.WriteString(",")
@@ -338,6 +485,10 @@ func (strct *GitpodConfig) UnmarshalJSON(b []byte) error {
 	// parse all the defined properties
 	for k, v := range jsonMap {
 		switch k {
+		case "additionalRepositories":
+			if err := json.Unmarshal([]byte(v), &strct.AdditionalRepositories); err != nil {
+				return err
+			}
 		case "checkoutLocation":
 			if err := json.Unmarshal([]byte(v), &strct.CheckoutLocation); err != nil {
 				return err
@@ -346,6 +497,10 @@ func (strct *GitpodConfig) UnmarshalJSON(b []byte) error {
 			if err := json.Unmarshal([]byte(v), &strct.GitConfig); err != nil {
 				return err
 			}
+		case "experimentalNetwork":
+			if err := json.Unmarshal([]byte(v), &strct.ExperimentalNetwork); err != nil {
+				return err
+			}
 		case "github":
 			if err := json.Unmarshal([]byte(v), &strct.Github); err != nil {
 				return err
@@ -358,6 +513,10 @@ func (strct *GitpodConfig) UnmarshalJSON(b []byte) error {
 			if err := json.Unmarshal([]byte(v), &strct.Image); err != nil {
 				return err
 			}
+		case "mainConfiguration":
+			if err := json.Unmarshal([]byte(v), &strct.MainConfiguration); err != nil {
+				return err
+			}
 		case "ports":
 			if err := json.Unmarshal([]byte(v), &strct.Ports); err != nil {
 				return err
@@ -448,6 +607,28 @@ func (strct *PortsItems) MarshalJSON() ([]byte, error) {
 	buf := bytes.NewBuffer(make([]byte, 0))
 	buf.WriteString("{")
 	comma := false
+	// Marshal the "customDomain" field
+	if comma {
+		buf.WriteString(",")
+	}
+	buf.WriteString("\"customDomain\": ")
+	if tmp, err := json.Marshal(strct.CustomDomain); err != nil {
+		return nil, err
+	} else {
+		buf.Write(tmp)
+	}
+	comma = true
+	// Marshal the "description" field
+	if comma {
+		buf.WriteString(",")
+	}
+	buf.WriteString("\"description\": ")
+	if tmp, err := json.Marshal(strct.Description); err != nil {
+		return nil, err
+	} else {
+		buf.Write(tmp)
+	}
+	comma = true
 	// Marshal the "name" field
 	if comma {
 		buf.WriteString(",")
@@ -483,6 +664,17 @@ func (strct *PortsItems) MarshalJSON() ([]byte, error) {
 		buf.Write(tmp)
 	}
 	comma = true
+	// Marshal the "protection" field
+	if comma {
+		buf.WriteString(",")
+	}
+	buf.WriteString("\"protection\": ")
+	if tmp, err := json.Marshal(strct.Protection); err != nil {
+		return nil, err
+	} else {
+		buf.Write(tmp)
+	}
+	comma = true
 	// Marshal the "protocol" field
 	if comma {
 		buf.WriteString(",")
@@ -520,6 +712,14 @@ func (strct *PortsItems) UnmarshalJSON(b []byte) error {
 	// parse all the defined properties
 	for k, v := range jsonMap {
 		switch k {
+		case "customDomain":
+			if err := json.Unmarshal([]byte(v), &strct.CustomDomain); err != nil {
+				return err
+			}
+		case "description":
+			if err := json.Unmarshal([]byte(v), &strct.Description); err != nil {
+				return err
+			}
 		case "name":
 			if err := json.Unmarshal([]byte(v), &strct.Name); err != nil {
 				return err
@@ -533,6 +733,10 @@ func (strct *PortsItems) UnmarshalJSON(b []byte) error {
 				return err
 			}
 			portReceived = true
+		case "protection":
+			if err := json.Unmarshal([]byte(v), &strct.Protection); err != nil {
+				return err
+			}
 		case "protocol":
 			if err := json.Unmarshal([]byte(v), &strct.Protocol); err != nil {
 				return err
@@ -0,0 +1,15 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package protocol
+
+import (
+	_ "embed"
+)
+
+// GitpodSchema is the JSON schema of the .gitpod.yml the types in gitpod-config-types.go are generated from.
+// The build copies it from data/gitpod-schema.json.
+//
+//go:embed gitpod-schema.json
+var GitpodSchema []byte
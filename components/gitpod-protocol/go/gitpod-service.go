@@ -42,6 +42,7 @@ type APIInterface interface {
 	GetWorkspaceUsers(ctx context.Context, workspaceID string) (res []*WorkspaceInstanceUser, err error)
 	GetFeaturedRepositories(ctx context.Context) (res []*WhitelistedRepository, err error)
 	GetWorkspace(ctx context.Context, id string) (res *WorkspaceInfo, err error)
+	GetOwnerToken(ctx context.Context, workspaceID string) (res string, err error)
 	IsWorkspaceOwner(ctx context.Context, workspaceID string) (res bool, err error)
 	CreateWorkspace(ctx context.Context, options *CreateWorkspaceOptions) (res *WorkspaceCreationResult, err error)
 	StartWorkspace(ctx context.Context, id string, options *StartWorkspaceOptions) (res *StartWorkspaceResult, err error)
@@ -61,6 +62,7 @@ type APIInterface interface {
 	GetUserStorageResource(ctx context.Context, options *GetUserStorageResourceOptions) (res string, err error)
 	UpdateUserStorageResource(ctx context.Context, options *UpdateUserStorageResourceOptions) (err error)
 	GetEnvVars(ctx context.Context) (res []*UserEnvVarValue, err error)
+	GetAllEnvVars(ctx context.Context) (res []*UserEnvVarValue, err error)
 	SetEnvVar(ctx context.Context, variable *UserEnvVarValue) (err error)
 	DeleteEnvVar(ctx context.Context, variable *UserEnvVarValue) (err error)
 	GetContentBlobUploadURL(ctx context.Context, name string) (url string, err error)
@@ -123,6 +125,8 @@ const (
 	FunctionGetFeaturedRepositories FunctionName = "getFeaturedRepositories"
 	// FunctionGetWorkspace is the name of the getWorkspace function
 	FunctionGetWorkspace FunctionName = "getWorkspace"
+	// FunctionGetOwnerToken is the name of the getOwnerToken function
+	FunctionGetOwnerToken FunctionName = "getOwnerToken"
 	// FunctionIsWorkspaceOwner is the name of the isWorkspaceOwner function
 	FunctionIsWorkspaceOwner FunctionName = "isWorkspaceOwner"
 	// FunctionCreateWorkspace is the name of the createWorkspace function
@@ -161,6 +165,8 @@ const (
 	FunctionUpdateUserStorageResource FunctionName = "updateUserStorageResource"
 	// FunctionGetEnvVars is the name of the getEnvVars function
 	FunctionGetEnvVars FunctionName = "getEnvVars"
+	// FunctionGetAllEnvVars is the name of the getAllEnvVars function
+	FunctionGetAllEnvVars FunctionName = "getAllEnvVars"
 	// FunctionSetEnvVar is the name of the setEnvVar function
 	FunctionSetEnvVar FunctionName = "setEnvVar"
 	// FunctionDeleteEnvVar is the name of the deleteEnvVar function
@@ -694,6 +700,26 @@ func (gp *APIoverJSONRPC) GetWorkspace(ctx context.Context, id string) (res *Wor
 	return
 }
 
+// GetOwnerToken calls getOwnerToken on the server
+func (gp *APIoverJSONRPC) GetOwnerToken(ctx context.Context, workspaceID string) (res string, err error) {
+	if gp == nil {
+		err = errNotConnected
+		return
+	}
+	var _params []interface{}
+
+	_params = append(_params, workspaceID)
+
+	var result string
+	err = gp.C.Call(ctx, "getOwnerToken", _params, &result)
+	if err != nil {
+		return
+	}
+	res = result
+
+	return
+}
+
 // IsWorkspaceOwner calls isWorkspaceOwner on the server
 func (gp *APIoverJSONRPC) IsWorkspaceOwner(ctx context.Context, workspaceID string) (res bool, err error) {
 	if gp == nil {
@@ -1061,6 +1087,24 @@ func (gp *APIoverJSONRPC) GetEnvVars(ctx context.Context) (res []*UserEnvVarValu
 	return
 }
 
+// GetAllEnvVars calls getAllEnvVars on the server
+func (gp *APIoverJSONRPC) GetAllEnvVars(ctx context.Context) (res []*UserEnvVarValue, err error) {
+	if gp == nil {
+		err = errNotConnected
+		return
+	}
+	var _params []interface{}
+
+	var result []*UserEnvVarValue
+	err = gp.C.Call(ctx, "getAllEnvVars", _params, &result)
+	if err != nil {
+		return
+	}
+	res = result
+
+	return
+}
+
 // SetEnvVar calls setEnvVar on the server
 func (gp *APIoverJSONRPC) SetEnvVar(ctx context.Context, variable *UserEnvVarValue) (err error) {
 	if gp == nil {
@@ -1410,6 +1454,12 @@ const (
 	WorkspaceTimeoutDuration60m = "60m"
 	// WorkspaceTimeoutDuration180m sets "180m" as timeout duration
 	WorkspaceTimeoutDuration180m = "180m"
+	// WorkspaceTimeoutDurationShort sets the short (30m) timeout duration
+	WorkspaceTimeoutDurationShort = "short"
+	// WorkspaceTimeoutDurationLong sets the long (60m) timeout duration
+	WorkspaceTimeoutDurationLong = "long"
+	// WorkspaceTimeoutDurationExtended sets the extended (180m) timeout duration
+	WorkspaceTimeoutDurationExtended = "extended"
 )
 
 // UserInfo is the UserInfo message type
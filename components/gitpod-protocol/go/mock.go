@@ -180,6 +180,21 @@ func (mr *MockAPIInterfaceMockRecorder) GenerateNewGitpodToken(ctx, options inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateNewGitpodToken", reflect.TypeOf((*MockAPIInterface)(nil).GenerateNewGitpodToken), ctx, options)
 }
 
+// GetAllEnvVars mocks base method.
+func (m *MockAPIInterface) GetAllEnvVars(ctx context.Context) ([]*UserEnvVarValue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllEnvVars", ctx)
+	ret0, _ := ret[0].([]*UserEnvVarValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllEnvVars indicates an expected call of GetAllEnvVars.
+func (mr *MockAPIInterfaceMockRecorder) GetAllEnvVars(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllEnvVars", reflect.TypeOf((*MockAPIInterface)(nil).GetAllEnvVars), ctx)
+}
+
 // GetAuthProviders mocks base method.
 func (m *MockAPIInterface) GetAuthProviders(ctx context.Context) ([]*AuthProviderInfo, error) {
 	m.ctrl.T.Helper()
@@ -360,6 +375,21 @@ func (mr *MockAPIInterfaceMockRecorder) GetOwnAuthProviders(ctx interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwnAuthProviders", reflect.TypeOf((*MockAPIInterface)(nil).GetOwnAuthProviders), ctx)
 }
 
+// GetOwnerToken mocks base method.
+func (m *MockAPIInterface) GetOwnerToken(ctx context.Context, workspaceID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOwnerToken", ctx, workspaceID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOwnerToken indicates an expected call of GetOwnerToken.
+func (mr *MockAPIInterfaceMockRecorder) GetOwnerToken(ctx, workspaceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwnerToken", reflect.TypeOf((*MockAPIInterface)(nil).GetOwnerToken), ctx, workspaceID)
+}
+
 // GetPortAuthenticationToken mocks base method.
 func (m *MockAPIInterface) GetPortAuthenticationToken(ctx context.Context, workspaceID string) (*Token, error) {
 	m.ctrl.T.Helper()
@@ -3,6 +3,7 @@
 go install -u github.com/a-h/generate/...
 
 schema-generate -p protocol ../data/gitpod-schema.json > ../go/gitpod-config-types.go
+cp ../data/gitpod-schema.json ../go/gitpod-schema.json
 
 sed -i 's/json:/yaml:/g' ../go/gitpod-config-types.go
 gofmt -w ../go/gitpod-config-types.go
@@ -784,6 +784,16 @@ export interface PortConfig {
     visibility?: PortVisibility;
     description?: string;
     name?: string;
+    protection?: PortProtection;
+    customDomain?: string;
+}
+export interface PortProtection {
+    password?: string;
+    allowedCIDRs?: string[];
+    oidc?: {
+        allowedEmails?: string[];
+        allowedDomains?: string[];
+    };
 }
 export namespace PortConfig {
     export function is(config: any): config is PortConfig {
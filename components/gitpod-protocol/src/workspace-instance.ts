@@ -89,6 +89,9 @@ export interface WorkspaceInstanceStatus {
 
     // ownerToken is the token one needs to access the workspace. Its presence is checked by ws-proxy.
     ownerToken?: string;
+
+    // traffic is the traffic ws-proxy served for this instance so far, per port
+    traffic?: WorkspaceInstancePortTraffic[];
 }
 
 // WorkspaceInstancePhase describes a high-level state of a workspace instance
@@ -181,6 +184,27 @@ export interface WorkspaceInstancePort {
     url?: string;
 }
 
+// WorkspaceInstancePortTraffic counts the traffic of the IDE or a port of a workspace instance
+export interface WorkspaceInstancePortTraffic {
+    // The workspace port, or 0 for the traffic of the IDE
+    port: number;
+
+    // The number of bytes sent to the workspace
+    bytesIn: number;
+
+    // The number of bytes sent by the workspace
+    bytesOut: number;
+
+    // The number of HTTP requests and websocket connections
+    requests: number;
+
+    // The counters ws-manager reported last. ws-manager counts in memory and starts over after a restart,
+    // hence the totals above accumulate the increase of the reported counters.
+    reported?: PortTrafficCounters;
+}
+
+export type PortTrafficCounters = Pick<WorkspaceInstancePortTraffic, "bytesIn" | "bytesOut" | "requests">;
+
 // WorkspaceInstanceRepoStatus describes the status of th Git working copy of a workspace
 export interface WorkspaceInstanceRepoStatus {
     // branch is branch we're currently on
@@ -82,6 +82,10 @@ type Configuration struct {
 
 	// BuilderImage is an image ref to the workspace builder image
 	BuilderImage string `json:"builderImage"`
+
+	// SigningKeySecret names a Kubernetes secret which contains a `cosign.key` entry
+	// carrying a PEM encoded private key. If set, bob signs the workspace images it builds.
+	SigningKeySecret string `json:"signingKeySecret,omitempty"`
 }
 
 type TLS struct {
@@ -20,6 +20,7 @@ import (
 	"github.com/docker/cli/cli/config/configfile"
 	"github.com/docker/cli/cli/config/types"
 	"github.com/moby/buildkit/client"
+	"github.com/opencontainers/go-digest"
 	"golang.org/x/xerrors"
 )
 
@@ -78,7 +79,8 @@ func (b *Builder) buildBaseLayer(ctx context.Context, cl *client.Client) error {
 	}
 
 	log.Info("building base image")
-	return buildImage(ctx, b.Config.ContextDir, b.Config.Dockerfile, b.Config.WorkspaceLayerAuth, b.Config.BaseRef)
+	_, err := buildImage(ctx, b.Config.ContextDir, b.Config.Dockerfile, b.Config.WorkspaceLayerAuth, b.Config.BaseRef)
+	return err
 }
 
 func (b *Builder) buildWorkspaceImage(ctx context.Context, cl *client.Client) (err error) {
@@ -94,17 +96,26 @@ func (b *Builder) buildWorkspaceImage(ctx context.Context, cl *client.Client) (e
 		return xerrors.Errorf("unexpected error creating temporal directory: %w", err)
 	}
 
-	return buildImage(ctx, contextDir, filepath.Join(contextDir, "Dockerfile"), b.Config.WorkspaceLayerAuth, b.Config.TargetRef)
+	dgst, err := buildImage(ctx, contextDir, filepath.Join(contextDir, "Dockerfile"), b.Config.WorkspaceLayerAuth, b.Config.TargetRef)
+	if err != nil {
+		return err
+	}
+
+	if b.Config.signer == nil {
+		return nil
+	}
+	log.Info("signing workspace image")
+	return signImage(ctx, b.Config.TargetRef, dgst, b.Config.WorkspaceLayerAuth, b.Config.signer)
 }
 
-func buildImage(ctx context.Context, contextDir, dockerfile, authLayer, target string) (err error) {
+func buildImage(ctx context.Context, contextDir, dockerfile, authLayer, target string) (dgst digest.Digest, err error) {
 	log.Info("waiting for build context")
 	waitctx, cancel := context.WithTimeout(ctx, 30*time.Minute)
 	defer cancel()
 
 	err = waitForBuildContext(waitctx)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	dockerConfig := "/tmp/config.json"
@@ -117,7 +128,7 @@ func buildImage(ctx context.Context, contextDir, dockerfile, authLayer, target s
 
 		err := configFile.LoadFromReader(bytes.NewReader([]byte(fmt.Sprintf(`{"auths": %v }`, authLayer))))
 		if err != nil {
-			return xerrors.Errorf("unexpected error reading registry authentication: %w", err)
+			return "", xerrors.Errorf("unexpected error reading registry authentication: %w", err)
 		}
 
 		f, _ := os.OpenFile(dockerConfig, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
@@ -125,7 +136,7 @@ func buildImage(ctx context.Context, contextDir, dockerfile, authLayer, target s
 
 		err = configFile.SaveToWriter(f)
 		if err != nil {
-			return xerrors.Errorf("unexpected error writing registry authentication: %w", err)
+			return "", xerrors.Errorf("unexpected error writing registry authentication: %w", err)
 		}
 	}
 
@@ -134,10 +145,14 @@ func buildImage(ctx context.Context, contextDir, dockerfile, authLayer, target s
 		contextdir = "."
 	}
 
+	metadataFile := "/tmp/build-metadata.json"
+	defer os.Remove(metadataFile)
+
 	buildctlArgs := []string{
 		// "--debug",
 		"build",
 		"--progress=plain",
+		"--metadata-file=" + metadataFile,
 		"--output=type=image,name=" + target + ",push=true,oci-mediatypes=true,compression=estargz,force-compression=true",
 		//"--export-cache=type=inline",
 		"--local=context=" + contextdir,
@@ -158,15 +173,20 @@ func buildImage(ctx context.Context, contextDir, dockerfile, authLayer, target s
 	buildctlCmd.Env = env
 
 	if err := buildctlCmd.Start(); err != nil {
-		return err
+		return "", err
 	}
 
 	err = buildctlCmd.Wait()
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	dgst, err = readBuildMetadata(metadataFile)
+	if err != nil {
+		return "", xerrors.Errorf("cannot read image digest from build metadata: %w", err)
+	}
+
+	return dgst, nil
 }
 
 func waitForBuildContext(ctx context.Context) error {
@@ -5,6 +5,7 @@
 package builder
 
 import (
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
 	"encoding/base64"
@@ -27,6 +28,11 @@ type Config struct {
 	ContextDir         string
 	ExternalBuildkitd  string
 	localCacheImport   string
+
+	// SigningKey is a PEM encoded private key. If set, the workspace image is signed
+	// with a cosign-compatible signature after it was pushed.
+	SigningKey string
+	signer     crypto.Signer
 }
 
 // GetConfigFromEnv extracts configuration from environment variables
@@ -42,6 +48,7 @@ func GetConfigFromEnv() (*Config, error) {
 		ContextDir:         os.Getenv("BOB_CONTEXT_DIR"),
 		ExternalBuildkitd:  os.Getenv("BOB_EXTERNAL_BUILDKITD"),
 		localCacheImport:   os.Getenv("BOB_LOCAL_CACHE_IMPORT"),
+		SigningKey:         os.Getenv("BOB_SIGNING_KEY"),
 	}
 
 	if cfg.BaseRef == "" {
@@ -67,6 +74,14 @@ func GetConfigFromEnv() (*Config, error) {
 		}
 	}
 
+	if cfg.SigningKey != "" {
+		var err error
+		cfg.signer, err = parseSigningKey([]byte(cfg.SigningKey))
+		if err != nil {
+			return nil, xerrors.Errorf("cannot parse BOB_SIGNING_KEY: %w", err)
+		}
+	}
+
 	var authKey = os.Getenv("BOB_AUTH_KEY")
 	if authKey != "" {
 		if len(authKey) != 32 {
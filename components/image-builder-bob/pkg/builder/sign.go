@@ -0,0 +1,254 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package builder
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/cli/cli/config/types"
+	"github.com/docker/distribution/reference"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+)
+
+const (
+	// mediaTypeCosignSimpleSigning is the media type of cosign signature payload layers
+	mediaTypeCosignSimpleSigning = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// annotationCosignSignature is the layer annotation which holds the base64 encoded signature
+	annotationCosignSignature = "dev.cosignproject.cosign/signature"
+)
+
+// simpleSigningPayload is the payload cosign signs for container images
+type simpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+// parseSigningKey parses a PEM encoded ECDSA, RSA or ed25519 private key
+func parseSigningKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, xerrors.Errorf("no PEM data found")
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case *rsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	default:
+		return nil, xerrors.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// signPayload signs the payload the same way cosign does for the respective key type
+func signPayload(key crypto.Signer, payload []byte) ([]byte, error) {
+	if _, ok := key.(ed25519.PrivateKey); ok {
+		return key.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+
+	hash := sha256.Sum256(payload)
+	return key.Sign(rand.Reader, hash[:], crypto.SHA256)
+}
+
+// signImage produces a cosign-compatible signature for the manifest dgst of ref and pushes it
+// to the same repository under the sha256-<digest>.sig tag.
+func signImage(ctx context.Context, ref string, dgst digest.Digest, authLayer string, key crypto.Signer) error {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return xerrors.Errorf("cannot parse ref: %w", err)
+	}
+	sigRef, err := reference.WithTag(reference.TrimNamed(named), fmt.Sprintf("%s-%s.sig", dgst.Algorithm(), dgst.Encoded()))
+	if err != nil {
+		return xerrors.Errorf("cannot produce signature ref: %w", err)
+	}
+
+	var payload simpleSigningPayload
+	payload.Critical.Identity.DockerReference = reference.TrimNamed(named).String()
+	payload.Critical.Image.DockerManifestDigest = dgst.String()
+	payload.Critical.Type = "cosign container image signature"
+	rawPayload, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	sig, err := signPayload(key, rawPayload)
+	if err != nil {
+		return xerrors.Errorf("cannot sign image: %w", err)
+	}
+
+	payloadDesc := ociv1.Descriptor{
+		MediaType: mediaTypeCosignSimpleSigning,
+		Digest:    digest.FromBytes(rawPayload),
+		Size:      int64(len(rawPayload)),
+		Annotations: map[string]string{
+			annotationCosignSignature: base64.StdEncoding.EncodeToString(sig),
+		},
+	}
+	rawCfg, err := json.Marshal(ociv1.Image{
+		RootFS: ociv1.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{payloadDesc.Digest},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	cfgDesc := ociv1.Descriptor{
+		MediaType: ociv1.MediaTypeImageConfig,
+		Digest:    digest.FromBytes(rawCfg),
+		Size:      int64(len(rawCfg)),
+	}
+	rawManifest, err := json.Marshal(ociv1.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ociv1.MediaTypeImageManifest,
+		Config:    cfgDesc,
+		Layers:    []ociv1.Descriptor{payloadDesc},
+	})
+	if err != nil {
+		return err
+	}
+	manifestDesc := ociv1.Descriptor{
+		MediaType: ociv1.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(rawManifest),
+		Size:      int64(len(rawManifest)),
+	}
+
+	authorizer, err := newAuthorizer(authLayer)
+	if err != nil {
+		return err
+	}
+	resolver := docker.NewResolver(docker.ResolverOptions{
+		Hosts: docker.ConfigureDefaultRegistries(
+			docker.WithAuthorizer(authorizer),
+			docker.WithClient(http.DefaultClient),
+			// bob pushes through the proxy running on localhost which does not serve TLS
+			docker.WithPlainHTTP(docker.MatchLocalhost),
+		),
+	})
+	pusher, err := resolver.Pusher(ctx, sigRef.String())
+	if err != nil {
+		return xerrors.Errorf("cannot push signature: %w", err)
+	}
+
+	// the manifest must go last, as registries refuse manifests whose blobs they don't know
+	for _, p := range []struct {
+		Desc    ociv1.Descriptor
+		Content []byte
+	}{
+		{payloadDesc, rawPayload},
+		{cfgDesc, rawCfg},
+		{manifestDesc, rawManifest},
+	} {
+		err = pushContent(ctx, pusher, p.Desc, p.Content)
+		if err != nil {
+			return xerrors.Errorf("cannot push signature %s: %w", p.Desc.MediaType, err)
+		}
+	}
+
+	log.WithField("ref", sigRef.String()).WithField("digest", dgst).Info("signed image")
+	return nil
+}
+
+func pushContent(ctx context.Context, pusher remotes.Pusher, desc ociv1.Descriptor, c []byte) error {
+	w, err := pusher.Push(ctx, desc)
+	if errdefs.IsAlreadyExists(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	return content.Copy(ctx, w, bytes.NewReader(c), desc.Size, desc.Digest)
+}
+
+func newAuthorizer(authLayer string) (docker.Authorizer, error) {
+	configFile := configfile.ConfigFile{
+		AuthConfigs: make(map[string]types.AuthConfig),
+	}
+	if authLayer != "" {
+		err := configFile.LoadFromReader(bytes.NewReader([]byte(fmt.Sprintf(`{"auths": %v }`, authLayer))))
+		if err != nil {
+			return nil, xerrors.Errorf("unexpected error reading registry authentication: %w", err)
+		}
+	}
+
+	return docker.NewDockerAuthorizer(docker.WithAuthCreds(func(host string) (user, pass string, err error) {
+		auth, err := configFile.GetAuthConfig(host)
+		if err != nil {
+			return
+		}
+		return auth.Username, auth.Password, nil
+	})), nil
+}
+
+// readBuildMetadata reads the digest of the image produced by buildctl from its metadata file
+func readBuildMetadata(fn string) (digest.Digest, error) {
+	fc, err := os.ReadFile(fn)
+	if err != nil {
+		return "", err
+	}
+
+	var md struct {
+		Digest digest.Digest `json:"containerimage.digest"`
+	}
+	err = json.Unmarshal(fc, &md)
+	if err != nil {
+		return "", err
+	}
+	if md.Digest == "" {
+		return "", xerrors.Errorf("build metadata contains no image digest")
+	}
+	return md.Digest, md.Digest.Validate()
+}
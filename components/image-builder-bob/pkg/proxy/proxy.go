@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"regexp"
 	"strings"
 	"sync"
 
@@ -20,6 +21,9 @@ import (
 
 const authKey = "authKey"
 
+// signatureTagPattern matches the tags under which cosign-compatible image signatures are stored
+var signatureTagPattern = regexp.MustCompile(`^sha256-[a-f0-9]{64}\.sig$`)
+
 func NewProxy(host *url.URL, aliases map[string]Repo) (*Proxy, error) {
 	if host.Host == "" || host.Scheme == "" {
 		return nil, fmt.Errorf("host Host or Scheme are missing")
@@ -65,8 +69,10 @@ func rewriteURL(u *url.URL, fromRepo, toRepo, host, tag string) {
 	if tag != "" {
 		// We're forcing the image tag which only affects manifests. No matter what the user
 		// requested we look at, we'll force the tag to the one we're given.
+		//
+		// The only exception are image signatures which bob pushes alongside the image.
 		segs := strings.Split(u.Path, "/")
-		if len(segs) >= 2 && segs[len(segs)-2] == "manifests" {
+		if len(segs) >= 2 && segs[len(segs)-2] == "manifests" && !signatureTagPattern.MatchString(segs[len(segs)-1]) {
 			// We're on the manifest found, hence the last segment must be the reference.
 			// Even if the reference is a digest, we'll just force it to the tag.
 			// This might break some consumers, but we want to use the tag forcing as a means
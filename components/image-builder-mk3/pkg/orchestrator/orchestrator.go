@@ -337,6 +337,39 @@ func (o *Orchestrator) Build(req *protocol.BuildRequest, resp protocol.ImageBuil
 		}
 	}
 
+	envvars := []*wsmanapi.EnvironmentVariable{
+		{Name: "BOB_TARGET_REF", Value: "localhost:8080/target:latest"},
+		{Name: "BOB_BASE_REF", Value: bobBaseref},
+		{Name: "BOB_BUILD_BASE", Value: buildBase},
+		{Name: "BOB_DOCKERFILE_PATH", Value: dockerfilePath},
+		{Name: "BOB_CONTEXT_DIR", Value: contextPath},
+		{Name: "GITPOD_TASKS", Value: `[{"name": "build", "init": "sudo -E /app/bob build"}]`},
+		{Name: "WORKSPACEKIT_RING2_ENCLAVE", Value: "/app/bob proxy"},
+		{Name: "WORKSPACEKIT_BOBPROXY_BASEREF", Value: baseref},
+		{Name: "WORKSPACEKIT_BOBPROXY_TARGETREF", Value: wsrefstr},
+		{
+			Name: "WORKSPACEKIT_BOBPROXY_AUTH",
+			Secret: &wsmanapi.EnvironmentVariable_SecretKeyRef{
+				SecretName: o.Config.PullSecret,
+				Key:        ".dockerconfigjson",
+			},
+		},
+		{
+			Name:  "WORKSPACEKIT_BOBPROXY_ADDITIONALAUTH",
+			Value: string(additionalAuth),
+		},
+		{Name: "SUPERVISOR_DEBUG_ENABLE", Value: fmt.Sprintf("%v", log.Log.Logger.IsLevelEnabled(logrus.DebugLevel))},
+	}
+	if o.Config.SigningKeySecret != "" {
+		envvars = append(envvars, &wsmanapi.EnvironmentVariable{
+			Name: "BOB_SIGNING_KEY",
+			Secret: &wsmanapi.EnvironmentVariable_SecretKeyRef{
+				SecretName: o.Config.SigningKeySecret,
+				Key:        "cosign.key",
+			},
+		})
+	}
+
 	var swr *wsmanapi.StartWorkspaceResponse
 	err = retry(ctx, func(ctx context.Context) (err error) {
 		swr, err = o.wsman.StartWorkspace(ctx, &wsmanapi.StartWorkspaceRequest{
@@ -360,29 +393,7 @@ func (o *Orchestrator) Build(req *protocol.BuildRequest, resp protocol.ImageBuil
 					WebRef: o.Config.BuilderImage,
 				},
 				WorkspaceLocation: contextPath,
-				Envvars: []*wsmanapi.EnvironmentVariable{
-					{Name: "BOB_TARGET_REF", Value: "localhost:8080/target:latest"},
-					{Name: "BOB_BASE_REF", Value: bobBaseref},
-					{Name: "BOB_BUILD_BASE", Value: buildBase},
-					{Name: "BOB_DOCKERFILE_PATH", Value: dockerfilePath},
-					{Name: "BOB_CONTEXT_DIR", Value: contextPath},
-					{Name: "GITPOD_TASKS", Value: `[{"name": "build", "init": "sudo -E /app/bob build"}]`},
-					{Name: "WORKSPACEKIT_RING2_ENCLAVE", Value: "/app/bob proxy"},
-					{Name: "WORKSPACEKIT_BOBPROXY_BASEREF", Value: baseref},
-					{Name: "WORKSPACEKIT_BOBPROXY_TARGETREF", Value: wsrefstr},
-					{
-						Name: "WORKSPACEKIT_BOBPROXY_AUTH",
-						Secret: &wsmanapi.EnvironmentVariable_SecretKeyRef{
-							SecretName: o.Config.PullSecret,
-							Key:        ".dockerconfigjson",
-						},
-					},
-					{
-						Name:  "WORKSPACEKIT_BOBPROXY_ADDITIONALAUTH",
-						Value: string(additionalAuth),
-					},
-					{Name: "SUPERVISOR_DEBUG_ENABLE", Value: fmt.Sprintf("%v", log.Log.Logger.IsLevelEnabled(logrus.DebugLevel))},
-				},
+				Envvars:           envvars,
 			},
 			Type: wsmanapi.WorkspaceType_IMAGEBUILD,
 		})
@@ -29,6 +29,10 @@ type LocalAppClient interface {
 	TunnelStatus(ctx context.Context, in *TunnelStatusRequest, opts ...grpc.CallOption) (LocalApp_TunnelStatusClient, error)
 	AutoTunnel(ctx context.Context, in *AutoTunnelRequest, opts ...grpc.CallOption) (*AutoTunnelResponse, error)
 	ResolveSSHConnection(ctx context.Context, in *ResolveSSHConnectionRequest, opts ...grpc.CallOption) (*ResolveSSHConnectionResponse, error)
+	// StartSync starts to synchronise a local directory with a directory in a workspace in both directions.
+	StartSync(ctx context.Context, in *StartSyncRequest, opts ...grpc.CallOption) (*StartSyncResponse, error)
+	StopSync(ctx context.Context, in *StopSyncRequest, opts ...grpc.CallOption) (*StopSyncResponse, error)
+	SyncStatus(ctx context.Context, in *SyncStatusRequest, opts ...grpc.CallOption) (LocalApp_SyncStatusClient, error)
 }
 
 type localAppClient struct {
@@ -89,6 +93,56 @@ func (c *localAppClient) ResolveSSHConnection(ctx context.Context, in *ResolveSS
 	return out, nil
 }
 
+func (c *localAppClient) StartSync(ctx context.Context, in *StartSyncRequest, opts ...grpc.CallOption) (*StartSyncResponse, error) {
+	out := new(StartSyncResponse)
+	err := c.cc.Invoke(ctx, "/localapp.LocalApp/StartSync", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *localAppClient) StopSync(ctx context.Context, in *StopSyncRequest, opts ...grpc.CallOption) (*StopSyncResponse, error) {
+	out := new(StopSyncResponse)
+	err := c.cc.Invoke(ctx, "/localapp.LocalApp/StopSync", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *localAppClient) SyncStatus(ctx context.Context, in *SyncStatusRequest, opts ...grpc.CallOption) (LocalApp_SyncStatusClient, error) {
+	stream, err := c.cc.NewStream(ctx, &LocalApp_ServiceDesc.Streams[1], "/localapp.LocalApp/SyncStatus", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &localAppSyncStatusClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type LocalApp_SyncStatusClient interface {
+	Recv() (*SyncStatusResponse, error)
+	grpc.ClientStream
+}
+
+type localAppSyncStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *localAppSyncStatusClient) Recv() (*SyncStatusResponse, error) {
+	m := new(SyncStatusResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // LocalAppServer is the server API for LocalApp service.
 // All implementations must embed UnimplementedLocalAppServer
 // for forward compatibility
@@ -96,6 +150,10 @@ type LocalAppServer interface {
 	TunnelStatus(*TunnelStatusRequest, LocalApp_TunnelStatusServer) error
 	AutoTunnel(context.Context, *AutoTunnelRequest) (*AutoTunnelResponse, error)
 	ResolveSSHConnection(context.Context, *ResolveSSHConnectionRequest) (*ResolveSSHConnectionResponse, error)
+	// StartSync starts to synchronise a local directory with a directory in a workspace in both directions.
+	StartSync(context.Context, *StartSyncRequest) (*StartSyncResponse, error)
+	StopSync(context.Context, *StopSyncRequest) (*StopSyncResponse, error)
+	SyncStatus(*SyncStatusRequest, LocalApp_SyncStatusServer) error
 	mustEmbedUnimplementedLocalAppServer()
 }
 
@@ -112,6 +170,15 @@ func (UnimplementedLocalAppServer) AutoTunnel(context.Context, *AutoTunnelReques
 func (UnimplementedLocalAppServer) ResolveSSHConnection(context.Context, *ResolveSSHConnectionRequest) (*ResolveSSHConnectionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ResolveSSHConnection not implemented")
 }
+func (UnimplementedLocalAppServer) StartSync(context.Context, *StartSyncRequest) (*StartSyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartSync not implemented")
+}
+func (UnimplementedLocalAppServer) StopSync(context.Context, *StopSyncRequest) (*StopSyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopSync not implemented")
+}
+func (UnimplementedLocalAppServer) SyncStatus(*SyncStatusRequest, LocalApp_SyncStatusServer) error {
+	return status.Errorf(codes.Unimplemented, "method SyncStatus not implemented")
+}
 func (UnimplementedLocalAppServer) mustEmbedUnimplementedLocalAppServer() {}
 
 // UnsafeLocalAppServer may be embedded to opt out of forward compatibility for this service.
@@ -182,6 +249,63 @@ func _LocalApp_ResolveSSHConnection_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _LocalApp_StartSync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartSyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LocalAppServer).StartSync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/localapp.LocalApp/StartSync",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LocalAppServer).StartSync(ctx, req.(*StartSyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LocalApp_StopSync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopSyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LocalAppServer).StopSync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/localapp.LocalApp/StopSync",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LocalAppServer).StopSync(ctx, req.(*StopSyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LocalApp_SyncStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SyncStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LocalAppServer).SyncStatus(m, &localAppSyncStatusServer{stream})
+}
+
+type LocalApp_SyncStatusServer interface {
+	Send(*SyncStatusResponse) error
+	grpc.ServerStream
+}
+
+type localAppSyncStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *localAppSyncStatusServer) Send(m *SyncStatusResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // LocalApp_ServiceDesc is the grpc.ServiceDesc for LocalApp service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -197,6 +321,14 @@ var LocalApp_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ResolveSSHConnection",
 			Handler:    _LocalApp_ResolveSSHConnection_Handler,
 		},
+		{
+			MethodName: "StartSync",
+			Handler:    _LocalApp_StartSync_Handler,
+		},
+		{
+			MethodName: "StopSync",
+			Handler:    _LocalApp_StopSync_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -204,6 +336,11 @@ var LocalApp_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _LocalApp_TunnelStatus_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "SyncStatus",
+			Handler:       _LocalApp_SyncStatus_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "localapp.proto",
 }
@@ -6,6 +6,8 @@ package main
 
 import (
 	_ "embed"
+	"fmt"
+	"os/exec"
 	"path/filepath"
 	"strconv"
 	"time"
@@ -21,8 +23,10 @@ import (
 	appapi "github.com/gitpod-io/gitpod/local-app/api"
 	"github.com/gitpod-io/local-app/pkg/auth"
 	"github.com/gitpod-io/local-app/pkg/bastion"
+	"github.com/gitpod-io/local-app/pkg/lifecycle"
 	"github.com/improbable-eng/grpc-web/go/grpcweb"
 	"github.com/sirupsen/logrus"
+	"github.com/skratchdot/open-golang/open"
 	"github.com/urfave/cli/v2"
 	"github.com/zalando/go-keyring"
 	"google.golang.org/grpc"
@@ -80,6 +84,14 @@ func main() {
 				},
 				Value: true,
 			},
+			&cli.BoolFlag{
+				Name:  "proxy",
+				Usage: "Serve a SOCKS5 and HTTP CONNECT proxy into the network of each running workspace",
+				EnvVars: []string{
+					"GITPOD_LCA_PROXY",
+				},
+				Value: false,
+			},
 			&cli.StringFlag{
 				Name: "auth-redirect-url",
 				EnvVars: []string{
@@ -124,6 +136,7 @@ func main() {
 						apiPort:           c.Int("api-port"),
 						allowCORSFromPort: c.Bool("allow-cors-from-port"),
 						autoTunnel:        c.Bool("auto-tunnel"),
+						proxy:             c.Bool("proxy"),
 						authRedirectURL:   c.String("auth-redirect-url"),
 						verbose:           c.Bool("verbose"),
 						authTimeout:       c.Duration("auth-timeout"),
@@ -138,6 +151,142 @@ func main() {
 					},
 				},
 			},
+			{
+				Name:  "workspaces",
+				Usage: "Manage your workspaces",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List your most recently used workspaces",
+						Action: func(c *cli.Context) error {
+							client, err := connectForCommand(c)
+							if err != nil {
+								return err
+							}
+							defer client.Close()
+							return lifecycle.ListWorkspaces(c.Context, client, os.Stdout, c.Bool("running"))
+						},
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "running",
+								Usage: "only list workspaces which are not stopped",
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:      "start",
+				Usage:     "Create a workspace for a context URL, or start a stopped workspace, and wait until it is running",
+				ArgsUsage: "<context-url|workspace-id>",
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 1 {
+						return cli.Exit("start expects a context URL or a workspace ID", 2)
+					}
+					client, err := connectForCommand(c)
+					if err != nil {
+						return err
+					}
+					defer client.Close()
+					instance, err := lifecycle.StartWorkspace(c.Context, client, c.Args().First(), lifecycle.StartOptions{ForceNew: c.Bool("new")}, os.Stdout)
+					if err != nil {
+						return err
+					}
+					fmt.Println(instance.IdeURL)
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "new",
+						Usage: "create a new workspace even if one is running for the context URL already",
+					},
+				},
+			},
+			{
+				Name:      "stop",
+				Usage:     "Stop a workspace",
+				ArgsUsage: "[workspace-id]",
+				Action: func(c *cli.Context) error {
+					client, err := connectForCommand(c)
+					if err != nil {
+						return err
+					}
+					defer client.Close()
+					ws, err := lifecycle.ResolveWorkspace(c.Context, client, c.Args().First())
+					if err != nil {
+						return err
+					}
+					return lifecycle.StopWorkspace(c.Context, client, ws.Workspace.ID, c.Bool("wait"), os.Stdout)
+				},
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "wait",
+						Usage: "wait until the workspace is stopped",
+					},
+				},
+			},
+			{
+				Name:      "ssh",
+				Usage:     "Connect to a running workspace using SSH",
+				ArgsUsage: "[workspace-id] [-- command]",
+				Action: func(c *cli.Context) error {
+					client, err := connectForCommand(c)
+					if err != nil {
+						return err
+					}
+					defer client.Close()
+					ws, err := lifecycle.ResolveWorkspace(c.Context, client, c.Args().First())
+					if err != nil {
+						return err
+					}
+					dest, err := lifecycle.SSHDestination(c.Context, client, ws)
+					if err != nil {
+						return err
+					}
+
+					args := []string{dest}
+					if c.NArg() > 1 {
+						args = append(args, c.Args().Slice()[1:]...)
+					}
+					cmd := exec.CommandContext(c.Context, "ssh", args...)
+					cmd.Stdin = os.Stdin
+					cmd.Stdout = os.Stdout
+					cmd.Stderr = os.Stderr
+					err = cmd.Run()
+					if exitErr, ok := err.(*exec.ExitError); ok {
+						return cli.Exit("", exitErr.ExitCode())
+					}
+					return err
+				},
+			},
+			{
+				Name:      "open",
+				Usage:     "Open a running workspace in an IDE",
+				ArgsUsage: "[workspace-id]",
+				Action: func(c *cli.Context) error {
+					client, err := connectForCommand(c)
+					if err != nil {
+						return err
+					}
+					defer client.Close()
+					ws, err := lifecycle.ResolveWorkspace(c.Context, client, c.Args().First())
+					if err != nil {
+						return err
+					}
+					u, err := lifecycle.OpenURL(ws, c.String("ide"), strings.TrimRight(c.String("gitpod-host"), "/"))
+					if err != nil {
+						return err
+					}
+					return open.Run(u)
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "ide",
+						Usage: "the IDE to open the workspace in (" + strings.Join(lifecycle.IDEs(), ", ") + ")",
+						Value: "browser",
+					},
+				},
+			},
 		},
 	}
 	err := app.Run(os.Args)
@@ -158,6 +307,7 @@ type runOptions struct {
 	apiPort           int
 	allowCORSFromPort bool
 	autoTunnel        bool
+	proxy             bool
 	authRedirectURL   string
 	verbose           bool
 	authTimeout       time.Duration
@@ -211,6 +361,7 @@ func run(opts runOptions) error {
 
 	b = bastion.New(client, opts.localAppTimeout, cb)
 	b.EnableAutoTunnel = opts.autoTunnel
+	b.EnableProxy = opts.proxy
 	grpcServer := grpc.NewServer()
 	appapi.RegisterLocalAppServer(grpcServer, bastion.NewLocalAppService(b, s))
 	allowOrigin := func(origin string) bool {
@@ -240,6 +391,17 @@ func run(opts runOptions) error {
 	return b.Run()
 }
 
+// connectForCommand connects to the Gitpod server using the token in the keyring, logging in if there is none
+func connectForCommand(c *cli.Context) (*gitpod.APIoverJSONRPC, error) {
+	if c.Bool("mock-keyring") {
+		keyring.MockInit()
+	}
+	origin := strings.TrimRight(c.String("gitpod-host"), "/")
+	return connectToServer(auth.LoginOpts{GitpodURL: origin, RedirectURL: c.String("auth-redirect-url"), AuthTimeout: c.Duration("auth-timeout")}, func() {}, func(closeErr error) {
+		logrus.WithError(closeErr).Debug("server connection closed")
+	})
+}
+
 func connectToServer(loginOpts auth.LoginOpts, reconnectionHandler func(), closeHandler func(error)) (*gitpod.APIoverJSONRPC, error) {
 	var client *gitpod.APIoverJSONRPC
 	onClose := func(closeErr error) {
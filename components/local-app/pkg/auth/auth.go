@@ -32,9 +32,13 @@ const (
 
 var authScopes = []string{
 	"function:getGitpodTokenScopes",
+	"function:getOwnerToken",
 	"function:getWorkspace",
 	"function:getWorkspaces",
 	"function:listenForWorkspaceInstanceUpdates",
+	"function:createWorkspace",
+	"function:startWorkspace",
+	"function:stopWorkspace",
 	"resource:default",
 }
 
@@ -87,6 +87,12 @@ type Workspace struct {
 	SSHPrivateFN     string
 	SSHPublicKey     string
 
+	// proxyListener is guarded by tunnelMu
+	proxyListener *TunnelListener
+
+	syncMu sync.RWMutex
+	syncs  map[string]*FileSync
+
 	ctx    context.Context
 	cancel context.CancelFunc
 
@@ -105,6 +111,13 @@ func (ws *Workspace) Status() []*app.TunnelStatus {
 			Visibility: listener.Visibility,
 		})
 	}
+	if ws.proxyListener != nil {
+		res = append(res, &app.TunnelStatus{
+			LocalPort:  ws.proxyListener.LocalPort,
+			Visibility: ws.proxyListener.Visibility,
+			Proxy:      true,
+		})
+	}
 	return res
 }
 
@@ -176,6 +189,7 @@ func New(client gitpod.APIInterface, localAppTimeout time.Duration, cb Callbacks
 		stop:                   cancel,
 		updates:                make(chan *WorkspaceUpdateRequest, 10),
 		subscriptions:          make(map[*StatusSubscription]struct{}, 10),
+		syncSubscriptions:      make(map[*SyncStatusSubscription]struct{}, 10),
 	}
 }
 
@@ -202,8 +216,12 @@ type Bastion struct {
 
 	subscriptionsMu sync.RWMutex
 	subscriptions   map[*StatusSubscription]struct{}
+	// syncSubscriptions are guarded by subscriptionsMu as well
+	syncSubscriptions map[*SyncStatusSubscription]struct{}
 
 	EnableAutoTunnel bool
+	// EnableProxy serves a SOCKS5 and HTTP CONNECT proxy into the network of each running workspace
+	EnableProxy bool
 }
 
 func (b *Bastion) Run() error {
@@ -220,11 +238,18 @@ func (b *Bastion) Run() error {
 		for s := range b.subscriptions {
 			subs = append(subs, s)
 		}
+		syncSubs := make([]*SyncStatusSubscription, 0, len(b.syncSubscriptions))
+		for s := range b.syncSubscriptions {
+			syncSubs = append(syncSubs, s)
+		}
 		b.subscriptionsMu.Unlock()
 
 		for _, s := range subs {
 			s.Close()
 		}
+		for _, s := range syncSubs {
+			s.Close()
+		}
 	}()
 
 	go b.handleTimeout()
@@ -328,6 +353,7 @@ func (b *Bastion) handleUpdate(ur *WorkspaceUpdateRequest) {
 			tunnelClient:    make(chan chan *TunnelClient, 1),
 			tunnelListeners: make(map[uint32]*TunnelListener),
 			tunnelEnabled:   true,
+			syncs:           make(map[string]*FileSync),
 		}
 	}
 	ws.Phase = u.Status.Phase
@@ -387,6 +413,18 @@ func (b *Bastion) handleUpdate(ur *WorkspaceUpdateRequest) {
 			}()
 		}
 
+		if ws.proxyListener == nil && ws.localSSHListener != nil && b.EnableProxy {
+			listener, err := b.establishProxy(ws)
+			if err != nil {
+				logrus.WithError(err).WithField("workspace", ws.WorkspaceID).Error("cannot establish proxy")
+			} else {
+				ws.tunnelMu.Lock()
+				ws.proxyListener = listener
+				ws.tunnelMu.Unlock()
+				go b.notify(ws)
+			}
+		}
+
 	case "stopping", "stopped":
 		ws.cancel()
 		delete(b.workspaces, u.ID)
@@ -663,6 +701,35 @@ func (b *Bastion) establishSSHTunnel(ws *Workspace) (listener *TunnelListener, e
 	return listener, err
 }
 
+// dialWorkspaceSSH connects to the workspace SSH server through the local SSH tunnel
+func (b *Bastion) dialWorkspaceSSH(ws *Workspace) (*ssh.Client, error) {
+	b.workspacesMu.RLock()
+	listener, keyFN := ws.localSSHListener, ws.SSHPrivateFN
+	b.workspacesMu.RUnlock()
+	if listener == nil {
+		return nil, xerrors.Errorf("workspace ssh tunnel not configured")
+	}
+
+	key, err := ioutil.ReadFile(keyFN)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot read SSH key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot parse SSH key: %w", err)
+	}
+	conn, err := ssh.Dial("tcp", listener.LocalAddr, &ssh.ClientConfig{
+		User:            "gitpod",
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	})
+	if err != nil {
+		return nil, xerrors.Errorf("cannot connect to workspace SSH: %w", err)
+	}
+	return conn, nil
+}
+
 func installSSHAuthorizedKey(ws *Workspace, key string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
@@ -0,0 +1,276 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package bastion
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/xerrors"
+
+	supervisor "github.com/gitpod-io/gitpod/supervisor/api"
+)
+
+// SOCKS5 protocol constants, see RFC 1928
+const (
+	socks5Version = 0x05
+
+	socks5AuthNone         = 0x00
+	socks5AuthNoAcceptable = 0xff
+
+	socks5CmdConnect = 0x01
+
+	socks5AddrIPv4   = 0x01
+	socks5AddrDomain = 0x03
+	socks5AddrIPv6   = 0x04
+
+	socks5ReplySucceeded           = 0x00
+	socks5ReplyGeneralFailure      = 0x01
+	socks5ReplyCmdNotSupported     = 0x07
+	socks5ReplyAddrTypeUnsupported = 0x08
+)
+
+// proxyDialer connects to an address in the workspace network
+type proxyDialer func(addr string) (net.Conn, error)
+
+// serveProxy serves a single SOCKS5 or HTTP CONNECT proxy connection. Both protocols are served on the same
+// port, they are told apart by the first byte a client sends.
+func serveProxy(conn net.Conn, dial proxyDialer) error {
+	br := bufio.NewReader(conn)
+	first, err := br.Peek(1)
+	if err != nil {
+		return err
+	}
+
+	var target net.Conn
+	if first[0] == socks5Version {
+		target, err = serveSOCKS5Handshake(conn, br, dial)
+	} else {
+		target, err = serveHTTPConnectHandshake(conn, br, dial)
+	}
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_, _ = io.Copy(target, br)
+		cancel()
+	}()
+	go func() {
+		_, _ = io.Copy(conn, target)
+		cancel()
+	}()
+	<-ctx.Done()
+	return nil
+}
+
+func serveSOCKS5Handshake(conn net.Conn, br *bufio.Reader, dial proxyDialer) (net.Conn, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, err
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(br, methods); err != nil {
+		return nil, err
+	}
+	var noAuth bool
+	for _, m := range methods {
+		if m == socks5AuthNone {
+			noAuth = true
+		}
+	}
+	if !noAuth {
+		_, _ = conn.Write([]byte{socks5Version, socks5AuthNoAcceptable})
+		return nil, xerrors.Errorf("socks5: client does not support connecting without authentication")
+	}
+	if _, err := conn.Write([]byte{socks5Version, socks5AuthNone}); err != nil {
+		return nil, err
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(br, req[:]); err != nil {
+		return nil, err
+	}
+	if req[0] != socks5Version {
+		return nil, xerrors.Errorf("socks5: unsupported version %d", req[0])
+	}
+
+	var host string
+	switch req[3] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		ip := make(net.IP, net.IPv4len)
+		if req[3] == socks5AddrIPv6 {
+			ip = make(net.IP, net.IPv6len)
+		}
+		if _, err := io.ReadFull(br, ip); err != nil {
+			return nil, err
+		}
+		host = ip.String()
+	case socks5AddrDomain:
+		l, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		domain := make([]byte, l)
+		if _, err := io.ReadFull(br, domain); err != nil {
+			return nil, err
+		}
+		host = string(domain)
+	default:
+		writeSOCKS5Reply(conn, socks5ReplyAddrTypeUnsupported)
+		return nil, xerrors.Errorf("socks5: unsupported address type %d", req[3])
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(br, port[:]); err != nil {
+		return nil, err
+	}
+	if req[1] != socks5CmdConnect {
+		writeSOCKS5Reply(conn, socks5ReplyCmdNotSupported)
+		return nil, xerrors.Errorf("socks5: unsupported command %d", req[1])
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:]))))
+	target, err := dial(addr)
+	if err != nil {
+		writeSOCKS5Reply(conn, socks5ReplyGeneralFailure)
+		return nil, xerrors.Errorf("socks5: cannot connect to %s: %w", addr, err)
+	}
+	err = writeSOCKS5Reply(conn, socks5ReplySucceeded)
+	if err != nil {
+		target.Close()
+		return nil, err
+	}
+	return target, nil
+}
+
+// writeSOCKS5Reply writes a reply without a bound address, which clients don't need for CONNECT
+func writeSOCKS5Reply(conn net.Conn, reply byte) error {
+	_, err := conn.Write([]byte{socks5Version, reply, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+func serveHTTPConnectHandshake(conn net.Conn, br *bufio.Reader, dial proxyDialer) (net.Conn, error) {
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return nil, err
+	}
+	if req.Method != http.MethodConnect {
+		_, _ = fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\nConnection: close\r\n\r\n", http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return nil, xerrors.Errorf("http: unsupported method %s", req.Method)
+	}
+
+	target, err := dial(req.Host)
+	if err != nil {
+		_, _ = fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\nConnection: close\r\n\r\n", http.StatusBadGateway, http.StatusText(http.StatusBadGateway))
+		return nil, xerrors.Errorf("http: cannot connect to %s: %w", req.Host, err)
+	}
+	_, err = fmt.Fprint(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	if err != nil {
+		target.Close()
+		return nil, err
+	}
+	return target, nil
+}
+
+// sshProxyDialer dials addresses from within a workspace using SSH port forwarding. The SSH connection
+// is established on first use and re-established once it breaks.
+type sshProxyDialer struct {
+	connect func() (*ssh.Client, error)
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+func (d *sshProxyDialer) Dial(addr string) (net.Conn, error) {
+	d.mu.Lock()
+	if d.client == nil {
+		client, err := d.connect()
+		if err != nil {
+			d.mu.Unlock()
+			return nil, err
+		}
+		d.client = client
+		go func() {
+			_ = client.Wait()
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			if d.client == client {
+				d.client = nil
+			}
+		}()
+	}
+	client := d.client
+	d.mu.Unlock()
+
+	return client.Dial("tcp", addr)
+}
+
+func (d *sshProxyDialer) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.client == nil {
+		return nil
+	}
+	return d.client.Close()
+}
+
+// establishProxy serves a SOCKS5 and HTTP CONNECT proxy on localhost whose connections are dialled
+// from within the workspace, so that services can be reached by their hostnames in the workspace network.
+func (b *Bastion) establishProxy(ws *Workspace) (*TunnelListener, error) {
+	netListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	localPort := netListener.Addr().(*net.TCPAddr).Port
+	logrus.WithField("workspace", ws.WorkspaceID).Info("proxy: listening on " + netListener.Addr().String() + "...")
+
+	dialer := &sshProxyDialer{connect: func() (*ssh.Client, error) {
+		return b.dialWorkspaceSSH(ws)
+	}}
+	listenerCtx, cancel := context.WithCancel(ws.ctx)
+	go func() {
+		<-listenerCtx.Done()
+		netListener.Close()
+		dialer.Close()
+		logrus.WithField("workspace", ws.WorkspaceID).Info("proxy: closed")
+	}()
+	go func() {
+		for {
+			conn, err := netListener.Accept()
+			if listenerCtx.Err() != nil {
+				return
+			}
+			if err != nil {
+				logrus.WithError(err).WithField("workspace", ws.WorkspaceID).Warn("proxy: failed to accept connection")
+				continue
+			}
+			go func() {
+				defer conn.Close()
+				err := serveProxy(conn, dialer.Dial)
+				if err != nil {
+					logrus.WithError(err).WithField("workspace", ws.WorkspaceID).Debug("proxy: connection failed")
+				}
+			}()
+		}
+	}()
+	return &TunnelListener{
+		LocalAddr:  netListener.Addr().String(),
+		LocalPort:  uint32(localPort),
+		Visibility: supervisor.TunnelVisiblity_host,
+		Ctx:        listenerCtx,
+		Cancel:     cancel,
+	}, nil
+}
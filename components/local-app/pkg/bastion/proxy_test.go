@@ -0,0 +1,207 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package bastion
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// echoDialer records the dialled addresses and connects to an echo server
+type echoDialer struct {
+	addrs []string
+	err   error
+}
+
+func (d *echoDialer) Dial(addr string) (net.Conn, error) {
+	d.addrs = append(d.addrs, addr)
+	if d.err != nil {
+		return nil, d.err
+	}
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		_, _ = io.Copy(server, server)
+	}()
+	return client, nil
+}
+
+func startProxy(t *testing.T, dialer *echoDialer) (conn net.Conn, done chan error) {
+	client, server := net.Pipe()
+	done = make(chan error, 1)
+	go func() {
+		defer server.Close()
+		done <- serveProxy(server, dialer.Dial)
+	}()
+	t.Cleanup(func() { client.Close() })
+	return client, done
+}
+
+func readN(t *testing.T, r io.Reader, n int) []byte {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func assertEcho(t *testing.T, conn net.Conn, r io.Reader) {
+	go func() {
+		_, _ = conn.Write([]byte("ping"))
+	}()
+	if act := string(readN(t, r, 4)); act != "ping" {
+		t.Errorf("unexpected echo: %q", act)
+	}
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	tests := []struct {
+		Desc    string
+		Request []byte
+		Addr    string
+	}{
+		{
+			Desc:    "domain",
+			Request: []byte{0x05, 0x01, 0x00, 0x03, 9, 'l', 'o', 'c', 'a', 'l', 'h', 'o', 's', 't', 0x1f, 0x90},
+			Addr:    "localhost:8080",
+		},
+		{
+			Desc:    "ipv4",
+			Request: []byte{0x05, 0x01, 0x00, 0x01, 10, 0, 0, 1, 0x15, 0x38},
+			Addr:    "10.0.0.1:5432",
+		},
+		{
+			Desc:    "ipv6",
+			Request: []byte{0x05, 0x01, 0x00, 0x04, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0x00, 0x50},
+			Addr:    "[::1]:80",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			dialer := &echoDialer{}
+			conn, _ := startProxy(t, dialer)
+
+			go func() {
+				_, _ = conn.Write(append([]byte{0x05, 0x02, 0x02, 0x00}, test.Request...))
+			}()
+			if diff := cmp.Diff([]byte{0x05, 0x00}, readN(t, conn, 2)); diff != "" {
+				t.Fatalf("unexpected method selection (-want +got):\n%s", diff)
+			}
+			if reply := readN(t, conn, 10); reply[1] != socks5ReplySucceeded {
+				t.Fatalf("unexpected reply: %v", reply)
+			}
+			if diff := cmp.Diff([]string{test.Addr}, dialer.addrs); diff != "" {
+				t.Errorf("unexpected dialled addresses (-want +got):\n%s", diff)
+			}
+			assertEcho(t, conn, conn)
+		})
+	}
+}
+
+func TestSOCKS5ProxyFailures(t *testing.T) {
+	tests := []struct {
+		Desc     string
+		Request  []byte
+		DialErr  error
+		Response []byte
+	}{
+		{
+			Desc:     "authentication required",
+			Request:  []byte{0x05, 0x01, 0x02},
+			Response: []byte{0x05, 0xff},
+		},
+		{
+			Desc:     "bind is not supported",
+			Request:  []byte{0x05, 0x01, 0x00, 0x05, 0x02, 0x00, 0x01, 10, 0, 0, 1, 0x00, 0x50},
+			Response: []byte{0x05, 0x00, 0x05, socks5ReplyCmdNotSupported, 0, 1, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			Desc:     "dial fails",
+			Request:  []byte{0x05, 0x01, 0x00, 0x05, 0x01, 0x00, 0x01, 10, 0, 0, 1, 0x00, 0x50},
+			DialErr:  errors.New("connection refused"),
+			Response: []byte{0x05, 0x00, 0x05, socks5ReplyGeneralFailure, 0, 1, 0, 0, 0, 0, 0, 0},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			conn, done := startProxy(t, &echoDialer{err: test.DialErr})
+			go func() {
+				_, _ = conn.Write(test.Request)
+			}()
+			if diff := cmp.Diff(test.Response, readN(t, conn, len(test.Response))); diff != "" {
+				t.Errorf("unexpected response (-want +got):\n%s", diff)
+			}
+			if err := <-done; err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestHTTPConnectProxy(t *testing.T) {
+	dialer := &echoDialer{}
+	conn, _ := startProxy(t, dialer)
+
+	go func() {
+		_, _ = conn.Write([]byte("CONNECT db:5432 HTTP/1.1\r\nHost: db:5432\r\n\r\n"))
+	}()
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %s", resp.Status)
+	}
+	if diff := cmp.Diff([]string{"db:5432"}, dialer.addrs); diff != "" {
+		t.Errorf("unexpected dialled addresses (-want +got):\n%s", diff)
+	}
+	assertEcho(t, conn, br)
+}
+
+func TestHTTPConnectProxyFailures(t *testing.T) {
+	tests := []struct {
+		Desc    string
+		Request string
+		DialErr error
+		Status  int
+	}{
+		{
+			Desc:    "plain HTTP is not supported",
+			Request: "GET http://web:3000/ HTTP/1.1\r\nHost: web:3000\r\n\r\n",
+			Status:  http.StatusMethodNotAllowed,
+		},
+		{
+			Desc:    "dial fails",
+			Request: "CONNECT web:3000 HTTP/1.1\r\nHost: web:3000\r\n\r\n",
+			DialErr: errors.New("connection refused"),
+			Status:  http.StatusBadGateway,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			conn, done := startProxy(t, &echoDialer{err: test.DialErr})
+			go func() {
+				_, _ = conn.Write([]byte(test.Request))
+			}()
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.Status {
+				t.Errorf("unexpected status: want %d, got %d", test.Status, resp.StatusCode)
+			}
+			if err := <-done; err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -6,6 +6,10 @@ package bastion
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -78,3 +82,69 @@ func (s *LocalAppService) ResolveSSHConnection(ctx context.Context, req *api.Res
 		ConfigFile: s.s.Path,
 	}, nil
 }
+
+func (s *LocalAppService) StartSync(ctx context.Context, req *api.StartSyncRequest) (*api.StartSyncResponse, error) {
+	if !filepath.IsAbs(req.LocalPath) {
+		return nil, status.Error(codes.InvalidArgument, "local path must be absolute")
+	}
+	if !path.IsAbs(req.RemotePath) {
+		return nil, status.Error(codes.InvalidArgument, "remote path must be absolute")
+	}
+	if stat, err := os.Stat(req.LocalPath); err != nil || !stat.IsDir() {
+		return nil, status.Error(codes.InvalidArgument, "local path must be an existing directory")
+	}
+
+	sync, err := s.b.StartSync(req.InstanceId, req.LocalPath, req.RemotePath, req.Ignore)
+	if errors.Is(err, ErrWorkspaceNotFound) {
+		return nil, status.Error(codes.NotFound, "workspace not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &api.StartSyncResponse{SyncId: sync.ID}, nil
+}
+
+func (s *LocalAppService) StopSync(ctx context.Context, req *api.StopSyncRequest) (*api.StopSyncResponse, error) {
+	err := s.b.StopSync(req.SyncId)
+	if errors.Is(err, ErrSyncNotFound) {
+		return nil, status.Error(codes.NotFound, "sync not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &api.StopSyncResponse{}, nil
+}
+
+func (s *LocalAppService) SyncStatus(req *api.SyncStatusRequest, srv api.LocalApp_SyncStatusServer) error {
+	if !req.Observe {
+		return srv.Send(&api.SyncStatusResponse{
+			Syncs: s.b.SyncStatus(req.InstanceId),
+		})
+	}
+
+	sub, err := s.b.SubscribeSync(req.InstanceId)
+	if err == ErrTooManySubscriptions {
+		return status.Error(codes.ResourceExhausted, "too many subscriptions")
+	}
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-srv.Context().Done():
+			return nil
+		case update := <-sub.Updates():
+			if update == nil {
+				return nil
+			}
+			err := srv.Send(&api.SyncStatusResponse{
+				Syncs: update,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
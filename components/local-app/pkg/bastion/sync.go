@@ -0,0 +1,798 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package bastion
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/sftp"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/xerrors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	app "github.com/gitpod-io/gitpod/local-app/api"
+)
+
+var (
+	// ErrWorkspaceNotFound when the workspace instance is not known to the local app
+	ErrWorkspaceNotFound = errors.New("workspace not found")
+	// ErrSyncNotFound when there is no sync with the given ID
+	ErrSyncNotFound = errors.New("sync not found")
+)
+
+const (
+	// syncInterval is how often both directories are compared
+	syncInterval = 2 * time.Second
+	// syncRetryInterval is how long a failed sync waits before it reconnects
+	syncRetryInterval = 5 * time.Second
+	// syncTempPrefix marks files which are being written by a sync, they are never synchronised
+	syncTempPrefix = ".gitpod-sync-"
+	// maxSyncConflicts is the number of recent conflicts reported in the sync status
+	maxSyncConflicts = 10
+)
+
+// syncFile is the state of a file as far as the sync is concerned. Modification times are
+// truncated to seconds, since that's what SFTP transfers.
+type syncFile struct {
+	Size    int64
+	ModTime time.Time
+	Mode    os.FileMode
+}
+
+func newSyncFile(info os.FileInfo) syncFile {
+	return syncFile{
+		Size:    info.Size(),
+		ModTime: info.ModTime().Truncate(time.Second),
+		Mode:    info.Mode().Perm(),
+	}
+}
+
+func (f syncFile) equal(o syncFile) bool {
+	return f.Size == o.Size && f.ModTime.Equal(o.ModTime)
+}
+
+// syncBase is the state of a file on both sides after it was last synchronised
+type syncBase struct {
+	Local  syncFile
+	Remote syncFile
+}
+
+// syncFS is one side of a sync. All paths are slash separated and relative to the synchronised directory.
+type syncFS interface {
+	// List returns all regular files which are not ignored
+	List(ignore syncIgnore) (map[string]syncFile, error)
+	Stat(p string) (syncFile, error)
+	Open(p string) (io.ReadCloser, error)
+	// WriteFile atomically replaces the file at p with the content of r
+	WriteFile(p string, r io.Reader, f syncFile) error
+	Rename(oldpath, newpath string) error
+	Remove(p string) error
+}
+
+// syncIgnore matches paths against gitignore-like patterns: a pattern without a slash matches
+// a file or directory name at any depth, a pattern with a slash matches the path relative to the
+// synchronised directory, and a pattern with a trailing slash matches directories only.
+type syncIgnore []ignorePattern
+
+type ignorePattern struct {
+	pattern  string
+	anchored bool
+	dirOnly  bool
+}
+
+func newSyncIgnore(patterns []string) (syncIgnore, error) {
+	var res syncIgnore
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		var ip ignorePattern
+		if strings.HasSuffix(p, "/") {
+			ip.dirOnly = true
+			p = strings.TrimSuffix(p, "/")
+		}
+		if strings.Contains(p, "/") {
+			ip.anchored = true
+			p = strings.TrimPrefix(p, "/")
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, xerrors.Errorf("invalid ignore pattern %q: %w", p, err)
+		}
+		ip.pattern = p
+		res = append(res, ip)
+	}
+	return res, nil
+}
+
+// Match returns true if the file or directory at rel is ignored. Directories are walked top-down,
+// so a file in an ignored directory never needs to be matched.
+func (ig syncIgnore) Match(rel string, isDir bool) bool {
+	if strings.HasPrefix(path.Base(rel), syncTempPrefix) {
+		return true
+	}
+	for _, p := range ig {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		name := path.Base(rel)
+		if p.anchored {
+			name = rel
+		}
+		if ok, _ := path.Match(p.pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type syncActionKind int
+
+const (
+	syncUpload syncActionKind = iota
+	syncDownload
+	syncDeleteLocal
+	syncDeleteRemote
+	// syncRecord records the base of a file which is equal on both sides
+	syncRecord
+	// syncForget forgets the base of a file which was deleted on both sides
+	syncForget
+)
+
+type syncAction struct {
+	Kind syncActionKind
+	Path string
+	// Conflict is true if the file was changed on both sides and the other side's file
+	// has to be backed up before it is overwritten.
+	Conflict bool
+}
+
+// planSync compares the files of both sides with their base and decides how to bring both sides in sync.
+// A file which changed on one side only is copied to the other side. A file which was deleted on one side
+// is deleted on the other side, unless it was changed there. If a file changed on both sides the last writer wins.
+func planSync(local, remote map[string]syncFile, base map[string]syncBase) []syncAction {
+	paths := make(map[string]struct{}, len(local))
+	for p := range local {
+		paths[p] = struct{}{}
+	}
+	for p := range remote {
+		paths[p] = struct{}{}
+	}
+	for p := range base {
+		paths[p] = struct{}{}
+	}
+
+	var res []syncAction
+	for p := range paths {
+		l, lok := local[p]
+		r, rok := remote[p]
+		b, bok := base[p]
+		localChanged := lok && (!bok || !b.Local.equal(l))
+		remoteChanged := rok && (!bok || !b.Remote.equal(r))
+
+		switch {
+		case lok && rok:
+			switch {
+			case !localChanged && !remoteChanged:
+				continue
+			case localChanged && !remoteChanged:
+				res = append(res, syncAction{Kind: syncUpload, Path: p})
+			case !localChanged && remoteChanged:
+				res = append(res, syncAction{Kind: syncDownload, Path: p})
+			case l.equal(r):
+				res = append(res, syncAction{Kind: syncRecord, Path: p})
+			case r.ModTime.After(l.ModTime):
+				res = append(res, syncAction{Kind: syncDownload, Path: p, Conflict: true})
+			default:
+				res = append(res, syncAction{Kind: syncUpload, Path: p, Conflict: true})
+			}
+		case lok:
+			if bok && !localChanged {
+				res = append(res, syncAction{Kind: syncDeleteLocal, Path: p})
+			} else {
+				res = append(res, syncAction{Kind: syncUpload, Path: p})
+			}
+		case rok:
+			if bok && !remoteChanged {
+				res = append(res, syncAction{Kind: syncDeleteRemote, Path: p})
+			} else {
+				res = append(res, syncAction{Kind: syncDownload, Path: p})
+			}
+		default:
+			res = append(res, syncAction{Kind: syncForget, Path: p})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res
+}
+
+// conflictBackupPath returns where the losing version of a conflicting file is kept,
+// e.g. src/main.conflict-20220102-150405.go for src/main.go.
+func conflictBackupPath(p string, t time.Time) string {
+	ext := path.Ext(p)
+	if ext == path.Base(p) {
+		ext = ""
+	}
+	return strings.TrimSuffix(p, ext) + ".conflict-" + t.Format("20060102-150405") + ext
+}
+
+// FileSync synchronises a local directory with a workspace directory in both directions
+type FileSync struct {
+	ID         string
+	LocalPath  string
+	RemotePath string
+
+	ignore syncIgnore
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// base is only accessed by the sync loop
+	base map[string]syncBase
+
+	mu     sync.Mutex
+	status *app.SyncStatus
+}
+
+func (s *FileSync) Status() *app.SyncStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return proto.Clone(s.status).(*app.SyncStatus)
+}
+
+func (s *FileSync) updateStatus(f func(status *app.SyncStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(s.status)
+}
+
+// syncOnce brings both sides in sync once
+func (s *FileSync) syncOnce(local, remote syncFS, notify func()) error {
+	localFiles, err := local.List(s.ignore)
+	if err != nil {
+		return xerrors.Errorf("cannot list %s: %w", s.LocalPath, err)
+	}
+	remoteFiles, err := remote.List(s.ignore)
+	if err != nil {
+		return xerrors.Errorf("cannot list %s in the workspace: %w", s.RemotePath, err)
+	}
+
+	actions := planSync(localFiles, remoteFiles, s.base)
+	for _, a := range actions {
+		if a.Kind != syncRecord && a.Kind != syncForget {
+			s.updateStatus(func(status *app.SyncStatus) {
+				status.State = app.SyncState_syncing
+			})
+			notify()
+			break
+		}
+	}
+	for _, a := range actions {
+		err := s.apply(a, local, remote, localFiles, remoteFiles)
+		if err != nil {
+			return xerrors.Errorf("cannot sync %s: %w", a.Path, err)
+		}
+	}
+
+	s.updateStatus(func(status *app.SyncStatus) {
+		status.State = app.SyncState_in_sync
+		status.Error = ""
+		status.LastSync = timestamppb.Now()
+		status.Files = uint32(len(s.base))
+	})
+	if len(actions) > 0 {
+		notify()
+	}
+	return nil
+}
+
+func (s *FileSync) apply(a syncAction, local, remote syncFS, localFiles, remoteFiles map[string]syncFile) error {
+	switch a.Kind {
+	case syncUpload, syncDownload:
+		src, dst, f := local, remote, localFiles[a.Path]
+		if a.Kind == syncDownload {
+			src, dst, f = remote, local, remoteFiles[a.Path]
+		}
+		if a.Conflict {
+			backup := conflictBackupPath(a.Path, time.Now())
+			err := dst.Rename(a.Path, backup)
+			if err != nil {
+				return xerrors.Errorf("cannot back up conflicting file: %w", err)
+			}
+			s.updateStatus(func(status *app.SyncStatus) {
+				status.Conflicts = append(status.Conflicts, &app.SyncConflict{
+					Path:       a.Path,
+					BackupPath: backup,
+					LocalWon:   a.Kind == syncUpload,
+					Time:       timestamppb.Now(),
+				})
+				if len(status.Conflicts) > maxSyncConflicts {
+					status.Conflicts = status.Conflicts[len(status.Conflicts)-maxSyncConflicts:]
+				}
+			})
+		}
+		err := copySyncFile(src, dst, a.Path, f)
+		if err != nil {
+			return err
+		}
+		err = s.record(a.Path, local, remote)
+		if err != nil {
+			return err
+		}
+		s.updateStatus(func(status *app.SyncStatus) {
+			if a.Kind == syncUpload {
+				status.Uploaded++
+			} else {
+				status.Downloaded++
+			}
+		})
+	case syncDeleteLocal, syncDeleteRemote:
+		side := local
+		if a.Kind == syncDeleteRemote {
+			side = remote
+		}
+		err := side.Remove(a.Path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		delete(s.base, a.Path)
+		s.updateStatus(func(status *app.SyncStatus) {
+			status.Deleted++
+		})
+	case syncRecord:
+		s.base[a.Path] = syncBase{Local: localFiles[a.Path], Remote: remoteFiles[a.Path]}
+	case syncForget:
+		delete(s.base, a.Path)
+	}
+	return nil
+}
+
+// record stores the state of a file on both sides as its new base
+func (s *FileSync) record(p string, local, remote syncFS) error {
+	l, err := local.Stat(p)
+	if err != nil {
+		return err
+	}
+	r, err := remote.Stat(p)
+	if err != nil {
+		return err
+	}
+	s.base[p] = syncBase{Local: l, Remote: r}
+	return nil
+}
+
+func copySyncFile(src, dst syncFS, p string, f syncFile) error {
+	r, err := src.Open(p)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return dst.WriteFile(p, r, f)
+}
+
+// run keeps both sides in sync until the sync is stopped
+func (s *FileSync) run(connect func() (syncFS, io.Closer, error), notify func()) {
+	local := &localSyncFS{Root: s.LocalPath}
+	for {
+		err := s.runConnected(local, connect, notify)
+		if s.ctx.Err() != nil {
+			return
+		}
+		logrus.WithError(err).WithField("sync", s.ID).Warn("file sync failed, retrying...")
+		s.updateStatus(func(status *app.SyncStatus) {
+			status.State = app.SyncState_failed
+			status.Error = err.Error()
+		})
+		notify()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(syncRetryInterval):
+		}
+	}
+}
+
+func (s *FileSync) runConnected(local syncFS, connect func() (syncFS, io.Closer, error), notify func()) error {
+	remote, closer, err := connect()
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	for {
+		err := s.syncOnce(local, remote, notify)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-s.ctx.Done():
+			return nil
+		case <-time.After(syncInterval):
+		}
+	}
+}
+
+// localSyncFS is the local side of a sync
+type localSyncFS struct {
+	Root string
+}
+
+func (l *localSyncFS) path(p string) string {
+	return filepath.Join(l.Root, filepath.FromSlash(p))
+}
+
+func (l *localSyncFS) List(ignore syncIgnore) (map[string]syncFile, error) {
+	res := make(map[string]syncFile)
+	err := filepath.WalkDir(l.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(l.Root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if ignore.Match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		res[rel] = newSyncFile(info)
+		return nil
+	})
+	return res, err
+}
+
+func (l *localSyncFS) Stat(p string) (syncFile, error) {
+	info, err := os.Stat(l.path(p))
+	if err != nil {
+		return syncFile{}, err
+	}
+	return newSyncFile(info), nil
+}
+
+func (l *localSyncFS) Open(p string) (io.ReadCloser, error) {
+	return os.Open(l.path(p))
+}
+
+func (l *localSyncFS) WriteFile(p string, r io.Reader, f syncFile) error {
+	fn := l.path(p)
+	err := os.MkdirAll(filepath.Dir(fn), 0755)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(fn), syncTempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmp.Name(), f.Mode)
+	if err != nil {
+		return err
+	}
+	err = os.Chtimes(tmp.Name(), f.ModTime, f.ModTime)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fn)
+}
+
+func (l *localSyncFS) Rename(oldpath, newpath string) error {
+	return os.Rename(l.path(oldpath), l.path(newpath))
+}
+
+func (l *localSyncFS) Remove(p string) error {
+	return os.Remove(l.path(p))
+}
+
+// sftpSyncFS is the workspace side of a sync
+type sftpSyncFS struct {
+	Root   string
+	Client *sftp.Client
+}
+
+func (r *sftpSyncFS) path(p string) string {
+	return path.Join(r.Root, p)
+}
+
+func (r *sftpSyncFS) List(ignore syncIgnore) (map[string]syncFile, error) {
+	res := make(map[string]syncFile)
+	walker := r.Client.Walk(r.Root)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			if errors.Is(err, fs.ErrNotExist) && walker.Path() != r.Root {
+				continue
+			}
+			return nil, err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), r.Root), "/")
+		if rel == "" {
+			continue
+		}
+		info := walker.Stat()
+		if ignore.Match(rel, info.IsDir()) {
+			if info.IsDir() {
+				walker.SkipDir()
+			}
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		res[rel] = newSyncFile(info)
+	}
+	return res, nil
+}
+
+func (r *sftpSyncFS) Stat(p string) (syncFile, error) {
+	info, err := r.Client.Stat(r.path(p))
+	if err != nil {
+		return syncFile{}, err
+	}
+	return newSyncFile(info), nil
+}
+
+func (r *sftpSyncFS) Open(p string) (io.ReadCloser, error) {
+	return r.Client.Open(r.path(p))
+}
+
+func (r *sftpSyncFS) WriteFile(p string, src io.Reader, f syncFile) error {
+	fn := r.path(p)
+	err := r.Client.MkdirAll(path.Dir(fn))
+	if err != nil {
+		return err
+	}
+	tmpfn := path.Join(path.Dir(fn), syncTempPrefix+uuid.New().String())
+	tmp, err := r.Client.Create(tmpfn)
+	if err != nil {
+		return err
+	}
+	//nolint:errcheck
+	defer r.Client.Remove(tmpfn)
+	_, err = tmp.ReadFrom(src)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	err = r.Client.Chmod(tmpfn, f.Mode)
+	if err != nil {
+		return err
+	}
+	err = r.Client.Chtimes(tmpfn, f.ModTime, f.ModTime)
+	if err != nil {
+		return err
+	}
+	return r.Client.PosixRename(tmpfn, fn)
+}
+
+func (r *sftpSyncFS) Rename(oldpath, newpath string) error {
+	return r.Client.PosixRename(r.path(oldpath), r.path(newpath))
+}
+
+func (r *sftpSyncFS) Remove(p string) error {
+	return r.Client.Remove(r.path(p))
+}
+
+// SyncStatusSubscription is a subscription to file sync status updates
+type SyncStatusSubscription struct {
+	instanceID string
+	updates    chan []*app.SyncStatus
+	Close      func() error
+}
+
+func (s *SyncStatusSubscription) Updates() <-chan []*app.SyncStatus {
+	return s.updates
+}
+
+func (ws *Workspace) SyncStatus() []*app.SyncStatus {
+	ws.syncMu.RLock()
+	defer ws.syncMu.RUnlock()
+	res := make([]*app.SyncStatus, 0, len(ws.syncs))
+	for _, s := range ws.syncs {
+		res = append(res, s.Status())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].LocalPath < res[j].LocalPath })
+	return res
+}
+
+// StartSync starts to synchronise localPath with remotePath in a workspace. The sync stops
+// when it is stopped explicitly or when the workspace stops.
+func (b *Bastion) StartSync(instanceID, localPath, remotePath string, ignore []string) (*FileSync, error) {
+	ws, ok := b.getWorkspace(instanceID)
+	if !ok {
+		return nil, ErrWorkspaceNotFound
+	}
+	ig, err := newSyncIgnore(ignore)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ws.ctx)
+	s := &FileSync{
+		ID:         uuid.New().String(),
+		LocalPath:  filepath.Clean(localPath),
+		RemotePath: path.Clean(remotePath),
+		ignore:     ig,
+		ctx:        ctx,
+		cancel:     cancel,
+		base:       make(map[string]syncBase),
+	}
+	s.status = &app.SyncStatus{
+		SyncId:     s.ID,
+		LocalPath:  s.LocalPath,
+		RemotePath: s.RemotePath,
+		State:      app.SyncState_connecting,
+	}
+
+	ws.syncMu.Lock()
+	ws.syncs[s.ID] = s
+	ws.syncMu.Unlock()
+	go func() {
+		<-ctx.Done()
+		ws.syncMu.Lock()
+		delete(ws.syncs, s.ID)
+		ws.syncMu.Unlock()
+		b.notifySync(ws)
+	}()
+	go s.run(func() (syncFS, io.Closer, error) {
+		return b.connectSFTP(ws, s.RemotePath)
+	}, func() {
+		b.notifySync(ws)
+	})
+	b.notifySync(ws)
+
+	logrus.WithField("workspace", ws.WorkspaceID).WithField("sync", s.ID).Infof("syncing %s with %s", s.LocalPath, s.RemotePath)
+	return s, nil
+}
+
+// StopSync stops a file sync. Files which were synchronised so far are kept on both sides.
+func (b *Bastion) StopSync(syncID string) error {
+	b.workspacesMu.RLock()
+	defer b.workspacesMu.RUnlock()
+	for _, ws := range b.workspaces {
+		ws.syncMu.RLock()
+		s, ok := ws.syncs[syncID]
+		ws.syncMu.RUnlock()
+		if ok {
+			s.cancel()
+			return nil
+		}
+	}
+	return ErrSyncNotFound
+}
+
+func (b *Bastion) SyncStatus(instanceID string) []*app.SyncStatus {
+	ws, ok := b.getWorkspace(instanceID)
+	if !ok {
+		return nil
+	}
+	return ws.SyncStatus()
+}
+
+// connectSFTP opens an SFTP session over the workspace SSH tunnel
+func (b *Bastion) connectSFTP(ws *Workspace, root string) (syncFS, io.Closer, error) {
+	conn, err := b.dialWorkspaceSSH(ws)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, xerrors.Errorf("cannot start SFTP session: %w", err)
+	}
+	err = client.MkdirAll(root)
+	if err != nil {
+		client.Close()
+		conn.Close()
+		return nil, nil, xerrors.Errorf("cannot create %s in the workspace: %w", root, err)
+	}
+	return &sftpSyncFS{Root: root, Client: client}, closerFunc(func() error {
+		client.Close()
+		return conn.Close()
+	}), nil
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func (b *Bastion) notifySync(ws *Workspace) {
+	var dropped []*SyncStatusSubscription
+	// closing a subscription requires the write lock, hence we close dropped subscriptions after sending
+	defer func() {
+		for _, sub := range dropped {
+			sub.Close()
+		}
+	}()
+
+	b.subscriptionsMu.RLock()
+	defer b.subscriptionsMu.RUnlock()
+	var subs []*SyncStatusSubscription
+	for sub := range b.syncSubscriptions {
+		if sub.instanceID == ws.InstanceID {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) <= 0 {
+		return
+	}
+	status := ws.SyncStatus()
+	for _, sub := range subs {
+		select {
+		case sub.updates <- status:
+		case <-time.After(5 * time.Second):
+			logrus.Error("sync subscription dropped out")
+			dropped = append(dropped, sub)
+		}
+	}
+}
+
+func (b *Bastion) SubscribeSync(instanceID string) (*SyncStatusSubscription, error) {
+	b.subscriptionsMu.Lock()
+	defer b.subscriptionsMu.Unlock()
+
+	if b.ctx.Err() != nil {
+		return nil, ErrClosed
+	}
+
+	if len(b.syncSubscriptions) > maxStatusSubscriptions {
+		return nil, ErrTooManySubscriptions
+	}
+
+	sub := &SyncStatusSubscription{updates: make(chan []*app.SyncStatus, 5), instanceID: instanceID}
+	var once sync.Once
+	sub.Close = func() error {
+		b.subscriptionsMu.Lock()
+		defer b.subscriptionsMu.Unlock()
+
+		once.Do(func() {
+			close(sub.updates)
+		})
+		delete(b.syncSubscriptions, sub)
+
+		return nil
+	}
+	b.syncSubscriptions[sub] = struct{}{}
+
+	// makes sure that no updates can happen between clients receiving an initial status and subscribing
+	sub.updates <- b.SyncStatus(instanceID)
+	return sub, nil
+}
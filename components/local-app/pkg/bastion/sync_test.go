@@ -0,0 +1,273 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package bastion
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	app "github.com/gitpod-io/gitpod/local-app/api"
+)
+
+func TestPlanSync(t *testing.T) {
+	var (
+		t0 = time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC)
+		t1 = t0.Add(time.Minute)
+		t2 = t0.Add(2 * time.Minute)
+
+		v0 = syncFile{Size: 1, ModTime: t0}
+		v1 = syncFile{Size: 2, ModTime: t1}
+		v2 = syncFile{Size: 3, ModTime: t2}
+	)
+	synced := map[string]syncBase{"a": {Local: v0, Remote: v0}}
+
+	tests := []struct {
+		Desc        string
+		Local       map[string]syncFile
+		Remote      map[string]syncFile
+		Base        map[string]syncBase
+		Expectation []syncAction
+	}{
+		{
+			Desc:   "in sync",
+			Local:  map[string]syncFile{"a": v0},
+			Remote: map[string]syncFile{"a": v0},
+			Base:   synced,
+		},
+		{
+			Desc:        "new local file",
+			Local:       map[string]syncFile{"a": v0},
+			Expectation: []syncAction{{Kind: syncUpload, Path: "a"}},
+		},
+		{
+			Desc:        "new remote file",
+			Remote:      map[string]syncFile{"a": v0},
+			Expectation: []syncAction{{Kind: syncDownload, Path: "a"}},
+		},
+		{
+			Desc:        "changed local file",
+			Local:       map[string]syncFile{"a": v1},
+			Remote:      map[string]syncFile{"a": v0},
+			Base:        synced,
+			Expectation: []syncAction{{Kind: syncUpload, Path: "a"}},
+		},
+		{
+			Desc:        "changed remote file",
+			Local:       map[string]syncFile{"a": v0},
+			Remote:      map[string]syncFile{"a": v1},
+			Base:        synced,
+			Expectation: []syncAction{{Kind: syncDownload, Path: "a"}},
+		},
+		{
+			Desc:        "deleted local file",
+			Remote:      map[string]syncFile{"a": v0},
+			Base:        synced,
+			Expectation: []syncAction{{Kind: syncDeleteRemote, Path: "a"}},
+		},
+		{
+			Desc:        "deleted remote file",
+			Local:       map[string]syncFile{"a": v0},
+			Base:        synced,
+			Expectation: []syncAction{{Kind: syncDeleteLocal, Path: "a"}},
+		},
+		{
+			Desc:        "deleted local file changed remotely",
+			Remote:      map[string]syncFile{"a": v1},
+			Base:        synced,
+			Expectation: []syncAction{{Kind: syncDownload, Path: "a"}},
+		},
+		{
+			Desc:        "deleted on both sides",
+			Base:        synced,
+			Expectation: []syncAction{{Kind: syncForget, Path: "a"}},
+		},
+		{
+			Desc:        "changed on both sides, local is newer",
+			Local:       map[string]syncFile{"a": v2},
+			Remote:      map[string]syncFile{"a": v1},
+			Base:        synced,
+			Expectation: []syncAction{{Kind: syncUpload, Path: "a", Conflict: true}},
+		},
+		{
+			Desc:        "changed on both sides, remote is newer",
+			Local:       map[string]syncFile{"a": v1},
+			Remote:      map[string]syncFile{"a": v2},
+			Base:        synced,
+			Expectation: []syncAction{{Kind: syncDownload, Path: "a", Conflict: true}},
+		},
+		{
+			Desc:        "new on both sides and equal",
+			Local:       map[string]syncFile{"a": v1},
+			Remote:      map[string]syncFile{"a": v1},
+			Expectation: []syncAction{{Kind: syncRecord, Path: "a"}},
+		},
+		{
+			Desc:        "new on both sides and different",
+			Local:       map[string]syncFile{"a": v1},
+			Remote:      map[string]syncFile{"a": v0},
+			Expectation: []syncAction{{Kind: syncUpload, Path: "a", Conflict: true}},
+		},
+		{
+			Desc:   "multiple files are sorted",
+			Local:  map[string]syncFile{"c": v0, "b/a": v0},
+			Remote: map[string]syncFile{"a": v0},
+			Expectation: []syncAction{
+				{Kind: syncDownload, Path: "a"},
+				{Kind: syncUpload, Path: "b/a"},
+				{Kind: syncUpload, Path: "c"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			act := planSync(test.Local, test.Remote, test.Base)
+			if diff := cmp.Diff(test.Expectation, act); diff != "" {
+				t.Errorf("unexpected planSync (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSyncIgnore(t *testing.T) {
+	ignore, err := newSyncIgnore([]string{"# comment", "", "node_modules/", "*.log", "/build", "docs/*.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Path        string
+		IsDir       bool
+		Expectation bool
+	}{
+		{Path: "node_modules", IsDir: true, Expectation: true},
+		{Path: "src/node_modules", IsDir: true, Expectation: true},
+		{Path: "node_modules", Expectation: false},
+		{Path: "debug.log", Expectation: true},
+		{Path: "src/debug.log", Expectation: true},
+		{Path: "build", IsDir: true, Expectation: true},
+		{Path: "src/build", IsDir: true, Expectation: false},
+		{Path: "docs/README.md", Expectation: true},
+		{Path: "README.md", Expectation: false},
+		{Path: "src/.gitpod-sync-1234", Expectation: true},
+		{Path: "src/main.go", Expectation: false},
+	}
+	for _, test := range tests {
+		t.Run(test.Path, func(t *testing.T) {
+			act := ignore.Match(test.Path, test.IsDir)
+			if act != test.Expectation {
+				t.Errorf("unexpected Match: want %v, got %v", test.Expectation, act)
+			}
+		})
+	}
+
+	_, err = newSyncIgnore([]string{"[a-"})
+	if err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestConflictBackupPath(t *testing.T) {
+	now := time.Date(2022, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := map[string]string{
+		"src/main.go": "src/main.conflict-20220102-150405.go",
+		"Makefile":    "Makefile.conflict-20220102-150405",
+		".gitignore":  ".gitignore.conflict-20220102-150405",
+	}
+	for p, expectation := range tests {
+		if act := conflictBackupPath(p, now); act != expectation {
+			t.Errorf("unexpected conflictBackupPath(%q): want %q, got %q", p, expectation, act)
+		}
+	}
+}
+
+func TestSyncOnce(t *testing.T) {
+	localDir, remoteDir := t.TempDir(), t.TempDir()
+	local, remote := &localSyncFS{Root: localDir}, &localSyncFS{Root: remoteDir}
+
+	write := func(dir, name, content string, mtime time.Time) {
+		fn := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fn, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(fn, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(dir, name string) string {
+		content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return ""
+		}
+		return string(content)
+	}
+
+	ignore, _ := newSyncIgnore([]string{"*.log"})
+	s := &FileSync{
+		ignore: ignore,
+		base:   make(map[string]syncBase),
+		status: &app.SyncStatus{},
+	}
+	sync := func() {
+		if err := s.syncOnce(local, remote, func() {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+	write(localDir, "src/main.go", "local", t0)
+	write(localDir, "debug.log", "ignored", t0)
+	write(remoteDir, "README.md", "remote", t0)
+	sync()
+	if act := read(remoteDir, "src/main.go"); act != "local" {
+		t.Errorf("expected src/main.go to be uploaded, got %q", act)
+	}
+	if act := read(localDir, "README.md"); act != "remote" {
+		t.Errorf("expected README.md to be downloaded, got %q", act)
+	}
+	if act := read(remoteDir, "debug.log"); act != "" {
+		t.Errorf("expected debug.log to be ignored, got %q", act)
+	}
+
+	// a conflict: the remote change is newer and wins
+	write(localDir, "src/main.go", "local change", t0.Add(time.Minute))
+	write(remoteDir, "src/main.go", "remote change", t0.Add(2*time.Minute))
+	os.Remove(filepath.Join(remoteDir, "README.md"))
+	sync()
+	if act := read(localDir, "src/main.go"); act != "remote change" {
+		t.Errorf("expected the newer remote change to win, got %q", act)
+	}
+	if act := read(localDir, "README.md"); act != "" {
+		t.Errorf("expected README.md to be deleted locally, got %q", act)
+	}
+	status := s.Status()
+	if len(status.Conflicts) != 1 {
+		t.Fatalf("expected one conflict, got %v", status.Conflicts)
+	}
+	if act := read(localDir, status.Conflicts[0].BackupPath); act != "local change" {
+		t.Errorf("expected the local change to be backed up, got %q", act)
+	}
+
+	// the backup is synchronised like any other file
+	sync()
+	if act := read(remoteDir, status.Conflicts[0].BackupPath); act != "local change" {
+		t.Errorf("expected the backup to be uploaded, got %q", act)
+	}
+	status = s.Status()
+	if status.State != app.SyncState_in_sync || status.Files != 2 {
+		t.Errorf("unexpected status: %v", status)
+	}
+	if status.Uploaded != 2 || status.Downloaded != 2 || status.Deleted != 1 {
+		t.Errorf("unexpected counters: %v", status)
+	}
+}
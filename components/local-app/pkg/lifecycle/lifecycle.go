@@ -0,0 +1,322 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	gitpod "github.com/gitpod-io/gitpod/gitpod-protocol"
+	"golang.org/x/xerrors"
+)
+
+// pollInterval is how often the workspace is fetched while waiting for it, since instance updates are dropped
+// by the client while nobody is receiving them
+const pollInterval = 10 * time.Second
+
+var workspaceIDRegex = regexp.MustCompile("^([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|[0-9a-z]{2,16}-[0-9a-z]{2,16}-[0-9a-z]{8,11})$")
+
+// IsWorkspaceID returns true if s is a workspace ID rather than a context URL
+func IsWorkspaceID(s string) bool {
+	return workspaceIDRegex.MatchString(s)
+}
+
+// Phase returns the phase of the latest instance of a workspace, or stopped if it was never started
+func Phase(ws *gitpod.WorkspaceInfo) string {
+	if ws.LatestInstance == nil || ws.LatestInstance.Status == nil {
+		return "stopped"
+	}
+	return ws.LatestInstance.Status.Phase
+}
+
+func isRunning(ws *gitpod.WorkspaceInfo) bool {
+	phase := Phase(ws)
+	return phase != "stopping" && phase != "stopped"
+}
+
+// ListWorkspaces prints the most recently used workspaces
+func ListWorkspaces(ctx context.Context, client gitpod.APIInterface, out io.Writer, runningOnly bool) error {
+	wss, err := client.GetWorkspaces(ctx, &gitpod.GetWorkspacesOptions{Limit: float64(100)})
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKSPACE\tPHASE\tCONTEXT\tURL")
+	for _, ws := range wss {
+		if ws.Workspace == nil || (runningOnly && !isRunning(ws)) {
+			continue
+		}
+		line := ws.Workspace.ID + "\t" + Phase(ws) + "\t" + ws.Workspace.ContextURL
+		if isRunning(ws) {
+			// the last cell is not terminated by a tab, so that it is not padded
+			line += "\t" + ws.LatestInstance.IdeURL
+		}
+		fmt.Fprintln(w, line)
+	}
+	return w.Flush()
+}
+
+// ResolveWorkspace returns the workspace with the given ID. If no ID is given, it returns
+// the only running workspace and fails if there is none or more than one.
+func ResolveWorkspace(ctx context.Context, client gitpod.APIInterface, workspaceID string) (*gitpod.WorkspaceInfo, error) {
+	if workspaceID != "" {
+		return client.GetWorkspace(ctx, workspaceID)
+	}
+
+	wss, err := client.GetWorkspaces(ctx, &gitpod.GetWorkspacesOptions{Limit: float64(100)})
+	if err != nil {
+		return nil, err
+	}
+	var running []*gitpod.WorkspaceInfo
+	for _, ws := range wss {
+		if ws.Workspace != nil && isRunning(ws) {
+			running = append(running, ws)
+		}
+	}
+	switch len(running) {
+	case 0:
+		return nil, xerrors.Errorf("no workspace is running")
+	case 1:
+		return running[0], nil
+	default:
+		ids := make([]string, 0, len(running))
+		for _, ws := range running {
+			ids = append(ids, ws.Workspace.ID)
+		}
+		return nil, xerrors.Errorf("more than one workspace is running, please choose one of %s", strings.Join(ids, ", "))
+	}
+}
+
+// StartOptions configure StartWorkspace
+type StartOptions struct {
+	// ForceNew creates a new workspace even if one is running for the context already
+	ForceNew bool
+}
+
+// StartWorkspace creates a workspace for a context URL, or starts a stopped workspace if target is a workspace ID,
+// and waits until it is running. The progress is printed to out.
+func StartWorkspace(ctx context.Context, client gitpod.APIInterface, target string, opts StartOptions, out io.Writer) (*gitpod.WorkspaceInstance, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// we subscribe before starting, so that we don't miss the first updates
+	updates, err := client.InstanceUpdates(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var workspaceID string
+	if IsWorkspaceID(target) {
+		workspaceID = target
+		_, err = client.StartWorkspace(ctx, workspaceID, &gitpod.StartWorkspaceOptions{})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		mode := "select-if-running"
+		if opts.ForceNew {
+			mode = "force-new"
+		}
+		res, err := client.CreateWorkspace(ctx, &gitpod.CreateWorkspaceOptions{ContextURL: target, Mode: mode})
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case res.CreatedWorkspaceID != "":
+			workspaceID = res.CreatedWorkspaceID
+		case len(res.ExistingWorkspaces) > 0:
+			ws := res.ExistingWorkspaces[0]
+			if ws.Workspace == nil || ws.LatestInstance == nil {
+				return nil, xerrors.Errorf("server returned an invalid workspace")
+			}
+			fmt.Fprintf(out, "Workspace %s is already running for this context, use --new to create another one.\n", ws.Workspace.ID)
+			workspaceID = ws.Workspace.ID
+		case res.RunningWorkspacePrebuild != nil:
+			return nil, xerrors.Errorf("a prebuild is running for this context, please try again once it is done or use --new")
+		default:
+			return nil, xerrors.Errorf("server did not create a workspace")
+		}
+	}
+
+	fmt.Fprintf(out, "Starting workspace %s...\n", workspaceID)
+	return WaitForPhase(ctx, client, workspaceID, "running", updates, out)
+}
+
+// StopWorkspace stops a workspace. If wait is true, it waits until the workspace is stopped.
+func StopWorkspace(ctx context.Context, client gitpod.APIInterface, workspaceID string, wait bool, out io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var updates <-chan *gitpod.WorkspaceInstance
+	if wait {
+		var err error
+		updates, err = client.InstanceUpdates(ctx, "")
+		if err != nil {
+			return err
+		}
+	}
+	err := client.StopWorkspace(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Stopping workspace %s...\n", workspaceID)
+	if !wait {
+		return nil
+	}
+	_, err = WaitForPhase(ctx, client, workspaceID, "stopped", updates, out)
+	return err
+}
+
+// WaitForPhase prints the phases of a workspace until its latest instance reaches the given phase.
+// It fails if the workspace stops while waiting for another phase.
+func WaitForPhase(ctx context.Context, client gitpod.APIInterface, workspaceID string, phase string, updates <-chan *gitpod.WorkspaceInstance, out io.Writer) (*gitpod.WorkspaceInstance, error) {
+	var (
+		instanceID string
+		lastPhase  string
+	)
+	handle := func(instance *gitpod.WorkspaceInstance) (done bool, err error) {
+		if instance == nil || instance.Status == nil || instance.WorkspaceID != workspaceID {
+			return false, nil
+		}
+		if instanceID != "" && instance.ID != instanceID {
+			// an update of an older instance
+			return false, nil
+		}
+		instanceID = instance.ID
+		if instance.Status.Phase != lastPhase {
+			lastPhase = instance.Status.Phase
+			fmt.Fprintf(out, "%s: %s\n", workspaceID, lastPhase)
+		}
+		if lastPhase == phase {
+			return true, nil
+		}
+		if lastPhase == "stopping" || lastPhase == "stopped" {
+			if instance.Status.Conditions != nil && instance.Status.Conditions.Failed != "" {
+				return true, xerrors.Errorf("workspace failed: %s", instance.Status.Conditions.Failed)
+			}
+			if phase != "stopped" {
+				return true, xerrors.Errorf("workspace stopped unexpectedly")
+			}
+		}
+		return false, nil
+	}
+	fetch := func() (*gitpod.WorkspaceInstance, bool, error) {
+		ws, err := client.GetWorkspace(ctx, workspaceID)
+		if err != nil {
+			return nil, false, err
+		}
+		done, err := handle(ws.LatestInstance)
+		return ws.LatestInstance, done, err
+	}
+
+	instance, done, err := fetch()
+	if done || err != nil {
+		return instance, err
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case u, ok := <-updates:
+			if !ok {
+				return nil, xerrors.Errorf("connection to the server closed")
+			}
+			done, err := handle(u)
+			if done || err != nil {
+				return u, err
+			}
+		case <-ticker.C:
+			instance, done, err := fetch()
+			if done || err != nil {
+				return instance, err
+			}
+		}
+	}
+}
+
+// SSHDestination returns the destination of a running workspace on the Gitpod SSH gateway.
+// The owner token is passed as part of the user name, e.g. workspaceid#token@workspaceid.ssh.ws-eu.gitpod.io.
+// The server censors the owner token in the workspace info, hence it is fetched using getOwnerToken.
+func SSHDestination(ctx context.Context, client gitpod.APIInterface, ws *gitpod.WorkspaceInfo) (string, error) {
+	if ws.Workspace == nil || ws.LatestInstance == nil || ws.LatestInstance.Status == nil || ws.LatestInstance.Status.Phase != "running" {
+		return "", xerrors.Errorf("workspace is not running")
+	}
+	ownerToken, err := client.GetOwnerToken(ctx, ws.Workspace.ID)
+	if err != nil {
+		return "", xerrors.Errorf("cannot get workspace owner token: %w", err)
+	}
+	if ownerToken == "" {
+		return "", xerrors.Errorf("workspace owner token is not available")
+	}
+	ideURL, err := url.Parse(ws.LatestInstance.IdeURL)
+	if err != nil {
+		return "", xerrors.Errorf("invalid workspace URL %q: %w", ws.LatestInstance.IdeURL, err)
+	}
+	workspaceID := ws.Workspace.ID
+	clusterHost := strings.TrimPrefix(ideURL.Hostname(), workspaceID+".")
+	if clusterHost == ideURL.Hostname() {
+		return "", xerrors.Errorf("unexpected workspace URL %q", ws.LatestInstance.IdeURL)
+	}
+	return fmt.Sprintf("%s#%s@%s.ssh.%s", workspaceID, ownerToken, workspaceID, clusterHost), nil
+}
+
+// desktopIDESchemes maps the desktop IDEs which can be opened to their URL scheme
+var desktopIDESchemes = map[string]string{
+	"code":          "vscode",
+	"code-insiders": "vscode-insiders",
+}
+
+// IDEs lists the IDEs a workspace can be opened in
+func IDEs() []string {
+	return []string{"browser", "code", "code-insiders"}
+}
+
+// OpenURL returns the URL which opens a running workspace in the given IDE.
+// Desktop IDEs are opened using the Gitpod extension of the IDE.
+func OpenURL(ws *gitpod.WorkspaceInfo, ide string, gitpodHost string) (string, error) {
+	if ws.Workspace == nil || ws.LatestInstance == nil || Phase(ws) != "running" {
+		return "", xerrors.Errorf("workspace is not running")
+	}
+	if ide == "browser" {
+		return ws.LatestInstance.IdeURL, nil
+	}
+	scheme, ok := desktopIDESchemes[ide]
+	if !ok {
+		return "", xerrors.Errorf("unknown IDE %q, must be one of %s", ide, strings.Join(IDEs(), ", "))
+	}
+
+	query, err := json.Marshal(struct {
+		InstanceID  string `json:"instanceId"`
+		WorkspaceID string `json:"workspaceId"`
+		GitpodHost  string `json:"gitpodHost"`
+	}{
+		InstanceID:  ws.LatestInstance.ID,
+		WorkspaceID: ws.Workspace.ID,
+		GitpodHost:  gitpodHost,
+	})
+	if err != nil {
+		return "", err
+	}
+	location := "/workspace"
+	if ws.Workspace.Config != nil && ws.Workspace.Config.CheckoutLocation != "" {
+		location += "/" + strings.TrimPrefix(ws.Workspace.Config.CheckoutLocation, "/")
+	}
+	link := url.URL{
+		Scheme:   scheme,
+		Host:     "gitpod.gitpod-desktop",
+		Path:     location,
+		RawQuery: url.QueryEscape(string(query)),
+	}
+	return link.String(), nil
+}
@@ -0,0 +1,359 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	gitpod "github.com/gitpod-io/gitpod/gitpod-protocol"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+)
+
+func workspace(id, phase string) *gitpod.WorkspaceInfo {
+	ws := &gitpod.WorkspaceInfo{
+		Workspace: &gitpod.Workspace{ID: id, ContextURL: "https://github.com/gitpod-io/gitpod"},
+	}
+	if phase != "" {
+		ws.LatestInstance = &gitpod.WorkspaceInstance{
+			ID:          id + "-instance",
+			WorkspaceID: id,
+			IdeURL:      "https://" + id + ".ws-eu.gitpod.io",
+			Status:      &gitpod.WorkspaceInstanceStatus{Phase: phase, OwnerToken: "token"},
+		}
+	}
+	return ws
+}
+
+func TestIsWorkspaceID(t *testing.T) {
+	tests := map[string]bool{
+		"gitpodio-gitpod-abc123def45":          true,
+		"a7dcf2a9-1e6d-4b2d-9b5b-2b7e3f8a1c6d": true,
+		"https://github.com/gitpod-io/gitpod":  false,
+		"github.com/gitpod-io/gitpod":          false,
+		"":                                     false,
+	}
+	for s, expectation := range tests {
+		if act := IsWorkspaceID(s); act != expectation {
+			t.Errorf("unexpected IsWorkspaceID(%q): want %v, got %v", s, expectation, act)
+		}
+	}
+}
+
+func TestListWorkspaces(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := gitpod.NewMockAPIInterface(ctrl)
+	client.EXPECT().GetWorkspaces(gomock.Any(), gomock.Any()).Return([]*gitpod.WorkspaceInfo{
+		workspace("gitpodio-gitpod-abc123def45", "running"),
+		workspace("gitpodio-gitpod-xyz123def45", "stopped"),
+		workspace("gitpodio-gitpod-new123def45", ""),
+	}, nil).Times(2)
+
+	var out bytes.Buffer
+	err := ListWorkspaces(context.Background(), client, &out, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectation := `WORKSPACE                    PHASE    CONTEXT                              URL
+gitpodio-gitpod-abc123def45  running  https://github.com/gitpod-io/gitpod  https://gitpodio-gitpod-abc123def45.ws-eu.gitpod.io
+gitpodio-gitpod-xyz123def45  stopped  https://github.com/gitpod-io/gitpod
+gitpodio-gitpod-new123def45  stopped  https://github.com/gitpod-io/gitpod
+`
+	if diff := cmp.Diff(expectation, out.String()); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+
+	out.Reset()
+	err = ListWorkspaces(context.Background(), client, &out, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectation = `WORKSPACE                    PHASE    CONTEXT                              URL
+gitpodio-gitpod-abc123def45  running  https://github.com/gitpod-io/gitpod  https://gitpodio-gitpod-abc123def45.ws-eu.gitpod.io
+`
+	if diff := cmp.Diff(expectation, out.String()); diff != "" {
+		t.Errorf("unexpected output of running workspaces (-want +got):\n%s", diff)
+	}
+}
+
+func TestResolveWorkspace(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Workspaces  []*gitpod.WorkspaceInfo
+		Expectation string
+		Error       bool
+	}{
+		{
+			Desc:       "none running",
+			Workspaces: []*gitpod.WorkspaceInfo{workspace("a", "stopped")},
+			Error:      true,
+		},
+		{
+			Desc:        "one running",
+			Workspaces:  []*gitpod.WorkspaceInfo{workspace("a", "stopped"), workspace("b", "running")},
+			Expectation: "b",
+		},
+		{
+			Desc:       "more than one running",
+			Workspaces: []*gitpod.WorkspaceInfo{workspace("a", "running"), workspace("b", "creating")},
+			Error:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := gitpod.NewMockAPIInterface(ctrl)
+			client.EXPECT().GetWorkspaces(gomock.Any(), gomock.Any()).Return(test.Workspaces, nil)
+
+			ws, err := ResolveWorkspace(context.Background(), client, "")
+			if test.Error {
+				if err == nil {
+					t.Errorf("expected an error, got %v", ws.Workspace.ID)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ws.Workspace.ID != test.Expectation {
+				t.Errorf("unexpected workspace: want %s, got %s", test.Expectation, ws.Workspace.ID)
+			}
+		})
+	}
+}
+
+func TestStartWorkspace(t *testing.T) {
+	const workspaceID = "gitpodio-gitpod-abc123def45"
+	instance := func(phase string) *gitpod.WorkspaceInstance {
+		return workspace(workspaceID, phase).LatestInstance
+	}
+
+	tests := []struct {
+		Desc        string
+		Target      string
+		Updates     []*gitpod.WorkspaceInstance
+		Expectation string
+		Error       bool
+	}{
+		{
+			Desc:    "create",
+			Target:  "https://github.com/gitpod-io/gitpod",
+			Updates: []*gitpod.WorkspaceInstance{workspace("other-workspace-abc123def45", "running").LatestInstance, instance("pending"), instance("creating"), instance("running")},
+			Expectation: `Starting workspace gitpodio-gitpod-abc123def45...
+gitpodio-gitpod-abc123def45: preparing
+gitpodio-gitpod-abc123def45: pending
+gitpodio-gitpod-abc123def45: creating
+gitpodio-gitpod-abc123def45: running
+`,
+		},
+		{
+			Desc:    "start",
+			Target:  workspaceID,
+			Updates: []*gitpod.WorkspaceInstance{instance("running")},
+			Expectation: `Starting workspace gitpodio-gitpod-abc123def45...
+gitpodio-gitpod-abc123def45: preparing
+gitpodio-gitpod-abc123def45: running
+`,
+		},
+		{
+			Desc:    "failed",
+			Target:  workspaceID,
+			Updates: []*gitpod.WorkspaceInstance{{ID: workspaceID + "-instance", WorkspaceID: workspaceID, Status: &gitpod.WorkspaceInstanceStatus{Phase: "stopping", Conditions: &gitpod.WorkspaceInstanceConditions{Failed: "image build failed"}}}},
+			Expectation: `Starting workspace gitpodio-gitpod-abc123def45...
+gitpodio-gitpod-abc123def45: preparing
+gitpodio-gitpod-abc123def45: stopping
+`,
+			Error: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := gitpod.NewMockAPIInterface(ctrl)
+
+			updates := make(chan *gitpod.WorkspaceInstance)
+			client.EXPECT().InstanceUpdates(gomock.Any(), "").Return(updates, nil)
+			if IsWorkspaceID(test.Target) {
+				client.EXPECT().StartWorkspace(gomock.Any(), workspaceID, gomock.Any()).Return(&gitpod.StartWorkspaceResult{InstanceID: workspaceID + "-instance"}, nil)
+			} else {
+				client.EXPECT().CreateWorkspace(gomock.Any(), &gitpod.CreateWorkspaceOptions{ContextURL: test.Target, Mode: "select-if-running"}).Return(&gitpod.WorkspaceCreationResult{CreatedWorkspaceID: workspaceID}, nil)
+			}
+			client.EXPECT().GetWorkspace(gomock.Any(), workspaceID).Return(workspace(workspaceID, "preparing"), nil)
+			go func() {
+				for _, u := range test.Updates {
+					updates <- u
+				}
+			}()
+
+			var out bytes.Buffer
+			_, err := StartWorkspace(context.Background(), client, test.Target, StartOptions{}, &out)
+			if test.Error && err == nil {
+				t.Error("expected an error")
+			}
+			if !test.Error && err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.Expectation, out.String()); diff != "" {
+				t.Errorf("unexpected output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStartWorkspaceExisting(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := gitpod.NewMockAPIInterface(ctrl)
+
+	const workspaceID = "gitpodio-gitpod-abc123def45"
+	client.EXPECT().InstanceUpdates(gomock.Any(), "").Return(make(chan *gitpod.WorkspaceInstance), nil)
+	client.EXPECT().CreateWorkspace(gomock.Any(), gomock.Any()).Return(&gitpod.WorkspaceCreationResult{
+		ExistingWorkspaces: []*gitpod.WorkspaceInfo{workspace(workspaceID, "running")},
+	}, nil)
+	client.EXPECT().GetWorkspace(gomock.Any(), workspaceID).Return(workspace(workspaceID, "running"), nil)
+
+	var out bytes.Buffer
+	instance, err := StartWorkspace(context.Background(), client, "https://github.com/gitpod-io/gitpod", StartOptions{}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.IdeURL != "https://"+workspaceID+".ws-eu.gitpod.io" {
+		t.Errorf("unexpected IDE URL: %s", instance.IdeURL)
+	}
+}
+
+func TestStartWorkspaceError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := gitpod.NewMockAPIInterface(ctrl)
+
+	client.EXPECT().InstanceUpdates(gomock.Any(), "").Return(make(chan *gitpod.WorkspaceInstance), nil)
+	client.EXPECT().CreateWorkspace(gomock.Any(), gomock.Any()).Return(nil, errors.New("context not found"))
+
+	var out bytes.Buffer
+	_, err := StartWorkspace(context.Background(), client, "https://github.com/gitpod-io/missing", StartOptions{}, &out)
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSSHDestination(t *testing.T) {
+	tests := []struct {
+		Desc        string
+		Workspace   *gitpod.WorkspaceInfo
+		OwnerToken  string
+		Expectation string
+		Error       bool
+	}{
+		{
+			Desc:        "running",
+			Workspace:   workspace("gitpodio-gitpod-abc123def45", "running"),
+			OwnerToken:  "token",
+			Expectation: "gitpodio-gitpod-abc123def45#token@gitpodio-gitpod-abc123def45.ssh.ws-eu.gitpod.io",
+		},
+		{
+			Desc:      "stopped",
+			Workspace: workspace("gitpodio-gitpod-abc123def45", "stopped"),
+			Error:     true,
+		},
+		{
+			Desc:      "no owner token",
+			Workspace: workspace("gitpodio-gitpod-abc123def45", "running"),
+			Error:     true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			client := gitpod.NewMockAPIInterface(ctrl)
+			client.EXPECT().GetOwnerToken(gomock.Any(), test.Workspace.Workspace.ID).Return(test.OwnerToken, nil).AnyTimes()
+
+			act, err := SSHDestination(context.Background(), client, test.Workspace)
+			if test.Error {
+				if err == nil {
+					t.Errorf("expected an error, got %s", act)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if act != test.Expectation {
+				t.Errorf("unexpected destination: want %s, got %s", test.Expectation, act)
+			}
+		})
+	}
+}
+
+func TestSSHDestinationOfWorkspaceID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	client := gitpod.NewMockAPIInterface(ctrl)
+	// the server censors the owner token of the workspace info
+	censored := workspace("gitpodio-gitpod-abc123def45", "running")
+	censored.LatestInstance.Status.OwnerToken = ""
+	client.EXPECT().GetWorkspace(gomock.Any(), "gitpodio-gitpod-abc123def45").Return(censored, nil)
+	client.EXPECT().GetOwnerToken(gomock.Any(), "gitpodio-gitpod-abc123def45").Return("token", nil)
+
+	ws, err := ResolveWorkspace(context.Background(), client, "gitpodio-gitpod-abc123def45")
+	if err != nil {
+		t.Fatal(err)
+	}
+	act, err := SSHDestination(context.Background(), client, ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expectation := "gitpodio-gitpod-abc123def45#token@gitpodio-gitpod-abc123def45.ssh.ws-eu.gitpod.io"; act != expectation {
+		t.Errorf("unexpected destination: want %s, got %s", expectation, act)
+	}
+}
+
+func TestOpenURL(t *testing.T) {
+	ws := workspace("gitpodio-gitpod-abc123def45", "running")
+	ws.Workspace.Config = &gitpod.WorkspaceConfig{CheckoutLocation: "gitpod"}
+
+	tests := []struct {
+		IDE         string
+		Expectation string
+		Error       bool
+	}{
+		{
+			IDE:         "browser",
+			Expectation: "https://gitpodio-gitpod-abc123def45.ws-eu.gitpod.io",
+		},
+		{
+			IDE:         "code",
+			Expectation: "vscode://gitpod.gitpod-desktop/workspace/gitpod?%7B%22instanceId%22%3A%22gitpodio-gitpod-abc123def45-instance%22%2C%22workspaceId%22%3A%22gitpodio-gitpod-abc123def45%22%2C%22gitpodHost%22%3A%22https%3A%2F%2Fgitpod.io%22%7D",
+		},
+		{
+			IDE:   "notepad",
+			Error: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.IDE, func(t *testing.T) {
+			act, err := OpenURL(ws, test.IDE, "https://gitpod.io")
+			if test.Error {
+				if err == nil {
+					t.Errorf("expected an error, got %s", act)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if act != test.Expectation {
+				t.Errorf("unexpected URL: want %s, got %s", test.Expectation, act)
+			}
+		})
+	}
+}
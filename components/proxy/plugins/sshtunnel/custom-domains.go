@@ -0,0 +1,205 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package sshtunnel
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"go.uber.org/zap"
+)
+
+const (
+	// customDomainsPortEnv names the port of ws-proxy which serves custom domains. Unless it is set,
+	// custom domains are not passed through.
+	customDomainsPortEnv = "WS_PROXY_CUSTOM_DOMAINS_PORT"
+
+	clientHelloTimeout = 10 * time.Second
+)
+
+var errClientHelloRead = errors.New("client hello read")
+
+func init() {
+	caddy.RegisterModule(CustomDomainTunnel{})
+}
+
+// CustomDomainTunnel is a listener wrapper which passes TLS connections for hosts outside of the
+// Gitpod domain through to ws-proxy. ws-proxy routes verified custom domains to workspace ports and
+// obtains their certificates itself, hence the connections must not be terminated by Caddy.
+// The wrapper must come before the tls listener wrapper.
+type CustomDomainTunnel struct {
+	domain string
+	addr   string
+	logger *zap.Logger
+}
+
+func (CustomDomainTunnel) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "caddy.listeners.gitpod_custom_domains",
+		New: func() caddy.Module { return new(CustomDomainTunnel) },
+	}
+}
+
+func (t *CustomDomainTunnel) Provision(ctx caddy.Context) error {
+	t.logger = ctx.Logger(t)
+	t.domain = strings.ToLower(os.Getenv("GITPOD_DOMAIN"))
+	if port := os.Getenv(customDomainsPortEnv); port != "" {
+		t.addr = fmt.Sprintf("ws-proxy.%s.%s:%s", os.Getenv("KUBE_NAMESPACE"), os.Getenv("KUBE_DOMAIN"), port)
+	}
+	return nil
+}
+
+// UnmarshalCaddyfile sets up the listener wrapper from Caddyfile tokens. Syntax:
+//
+//	gitpod_custom_domains
+func (t *CustomDomainTunnel) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	for d.Next() {
+		if d.NextArg() {
+			return d.ArgErr()
+		}
+	}
+	return nil
+}
+
+func (t *CustomDomainTunnel) WrapListener(ln net.Listener) net.Listener {
+	if t.addr == "" || t.domain == "" {
+		return ln
+	}
+	t.logger.Info("passing custom domains through to ws-proxy", zap.String("addr", t.addr))
+
+	l := &customDomainListener{
+		Listener: ln,
+		tunnel:   t,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	go l.serve()
+	return l
+}
+
+// isCustomDomain returns true if connections for the TLS server name are passed through to ws-proxy
+func (t *CustomDomainTunnel) isCustomDomain(serverName string) bool {
+	name := strings.TrimSuffix(strings.ToLower(serverName), ".")
+	if name == "" {
+		// clients without SNI get the default certificate
+		return false
+	}
+	return name != t.domain && !strings.HasSuffix(name, "."+t.domain)
+}
+
+// customDomainListener reads the client hello of every connection before it is accepted,
+// so that connections for custom domains never reach Caddy.
+type customDomainListener struct {
+	net.Listener
+	tunnel *CustomDomainTunnel
+
+	conns chan net.Conn
+	// err is set before done is closed
+	err  error
+	done chan struct{}
+}
+
+func (l *customDomainListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, l.err
+	}
+}
+
+func (l *customDomainListener) serve() {
+	for {
+		conn, err := l.Listener.Accept()
+		if nerr, ok := err.(net.Error); ok && nerr.Temporary() {
+			// ignore temporary network error
+			continue
+		}
+		if err != nil {
+			l.err = err
+			close(l.done)
+			return
+		}
+		// reading the client hello must not block other connections
+		go l.handle(conn)
+	}
+}
+
+func (l *customDomainListener) handle(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(clientHelloTimeout))
+	serverName, peeked, err := peekServerName(conn)
+	_ = conn.SetReadDeadline(time.Time{})
+	if err == nil && l.tunnel.isCustomDomain(serverName) {
+		forward(peeked, l.tunnel.addr, true)
+		return
+	}
+
+	// everything else, including connections which do not start with a client hello, is up to Caddy
+	select {
+	case l.conns <- peeked:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+// peekServerName reads the TLS client hello from conn and returns the server name it asks for.
+// The returned connection replays the client hello.
+func peekServerName(conn net.Conn) (string, net.Conn, error) {
+	var (
+		hello      bytes.Buffer
+		serverName string
+	)
+	err := tls.Server(&readOnlyConn{Conn: conn, r: io.TeeReader(conn, &hello)}, &tls.Config{
+		GetConfigForClient: func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = info.ServerName
+			return nil, errClientHelloRead
+		},
+	}).Handshake()
+
+	peeked := &peekedConn{Conn: conn, r: io.MultiReader(&hello, conn)}
+	if !errors.Is(err, errClientHelloRead) {
+		return "", peeked, err
+	}
+	return serverName, peeked, nil
+}
+
+// readOnlyConn lets the TLS handshake read the client hello, but never answer it
+type readOnlyConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *readOnlyConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *readOnlyConn) Write(b []byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+// peekedConn is a connection whose data already read is replayed
+type peekedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *peekedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+var (
+	_ caddy.Provisioner     = (*CustomDomainTunnel)(nil)
+	_ caddy.ListenerWrapper = (*CustomDomainTunnel)(nil)
+	_ caddyfile.Unmarshaler = (*CustomDomainTunnel)(nil)
+)
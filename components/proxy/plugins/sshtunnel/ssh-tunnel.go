@@ -45,9 +45,23 @@ func (a InjectSSHTunnelAdaper) Adapt(body []byte, options map[string]interface{}
 type InjectSSHTunnelAdaper struct {
 }
 
+// tunnel forwards the connections to a port of the proxy to the same port of ws-proxy
+type tunnel struct {
+	port int
+	// proxyProtocol sends a PROXY protocol v1 header so that ws-proxy learns the client address
+	proxyProtocol bool
+}
+
+var tunnels = []tunnel{
+	// SSH gateway
+	{port: 22},
+	// TCP passthrough to workspace ports
+	{port: 9443, proxyProtocol: true},
+}
+
 type SSHTunnel struct {
-	listener net.Listener
-	logger   *zap.Logger
+	listeners []net.Listener
+	logger    *zap.Logger
 }
 
 func (SSHTunnel) CaddyModule() caddy.ModuleInfo {
@@ -63,27 +77,31 @@ func (s *SSHTunnel) Provision(ctx caddy.Context) error {
 }
 
 func (s *SSHTunnel) Start() error {
-	ln, err := caddy.Listen("tcp", "0.0.0.0:22")
-	if err != nil {
-		return err
+	for _, t := range tunnels {
+		ln, err := caddy.Listen("tcp", fmt.Sprintf("0.0.0.0:%d", t.port))
+		if err != nil {
+			return err
+		}
+		s.listeners = append(s.listeners, ln)
+		go s.serve(ln, t)
 	}
-	s.listener = ln
-	go s.serve()
 	s.logger.Info("SSH Tunnel is running")
 	return nil
 }
 
 func (s SSHTunnel) Stop() error {
-	err := s.listener.Close()
-	if err != nil {
-		return err
+	for _, ln := range s.listeners {
+		err := ln.Close()
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (s *SSHTunnel) serve() {
+func (s *SSHTunnel) serve(ln net.Listener, t tunnel) {
 	for {
-		conn, err := s.listener.Accept()
+		conn, err := ln.Accept()
 		if nerr, ok := err.(net.Error); ok && nerr.Temporary() {
 			// ignore temporary network error
 			continue
@@ -91,19 +109,30 @@ func (s *SSHTunnel) serve() {
 		if err != nil {
 			return
 		}
-		go s.handle(conn)
+		go s.handle(conn, t)
 	}
 }
 
-func (s *SSHTunnel) handle(conn net.Conn) {
+func (s *SSHTunnel) handle(conn net.Conn, t tunnel) {
+	forward(conn, fmt.Sprintf("ws-proxy.%s.%s:%d", os.Getenv("KUBE_NAMESPACE"), os.Getenv("KUBE_DOMAIN"), t.port), t.proxyProtocol)
+}
+
+// forward copies data between conn and a connection to addr until either side is closed
+func forward(conn net.Conn, addr string, proxyProtocol bool) {
 	defer conn.Close()
-	addr := fmt.Sprintf("ws-proxy.%s.%s:22", os.Getenv("KUBE_NAMESPACE"), os.Getenv("KUBE_DOMAIN"))
 	tconn, err := net.Dial("tcp", addr)
 	if err != nil {
 		fmt.Printf("dial %s failed with:%v\n", addr, err)
 		return
 	}
 	defer tconn.Close()
+	if proxyProtocol {
+		_, err = io.WriteString(tconn, proxyProtocolHeader(conn.RemoteAddr(), conn.LocalAddr()))
+		if err != nil {
+			fmt.Printf("writing PROXY protocol header to %s failed with:%v\n", addr, err)
+			return
+		}
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
 		io.Copy(conn, tconn)
@@ -117,4 +146,18 @@ func (s *SSHTunnel) handle(conn net.Conn) {
 	<-ctx.Done()
 }
 
+// proxyProtocolHeader returns the PROXY protocol v1 header for a connection from src to dst
+func proxyProtocolHeader(src, dst net.Addr) string {
+	srcAddr, srcOK := src.(*net.TCPAddr)
+	dstAddr, dstOK := dst.(*net.TCPAddr)
+	if !srcOK || !dstOK {
+		return "PROXY UNKNOWN\r\n"
+	}
+	family, srcIP, dstIP := "TCP4", srcAddr.IP.To4(), dstAddr.IP.To4()
+	if srcIP == nil || dstIP == nil {
+		family, srcIP, dstIP = "TCP6", srcAddr.IP.To16(), dstAddr.IP.To16()
+	}
+	return fmt.Sprintf("PROXY %s %s %s %d %d\r\n", family, srcIP, dstIP, srcAddr.Port, dstAddr.Port)
+}
+
 var _ caddy.App = (*SSHTunnel)(nil)
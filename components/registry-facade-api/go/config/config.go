@@ -39,6 +39,43 @@ func GetConfig(fn string) (*ServiceConfig, error) {
 		}
 	}
 
+	if sv := cfg.Registry.SignatureVerification; sv != nil && sv.Enabled && len(sv.PublicKeys) == 0 {
+		return nil, xerrors.Errorf("signature verification requires at least one public key")
+	}
+
+	if bc := cfg.Registry.BlobCache; bc != nil && bc.Enabled {
+		if bc.Path == "" {
+			return nil, xerrors.Errorf("blob cache requires a path")
+		}
+		if bc.MaxSize <= 0 {
+			return nil, xerrors.Errorf("blob cache requires a positive maxSizeBytes")
+		}
+		if bc.Peers != nil && bc.Peers.Port == 0 {
+			return nil, xerrors.Errorf("blob cache peers require a port")
+		}
+		if bc.Peers != nil && (bc.Peers.TLS == nil || bc.Peers.ServerName == "") {
+			return nil, xerrors.Errorf("blob cache peers require TLS and a server name")
+		}
+	}
+
+	if pf := cfg.Registry.Prefetch; pf != nil && pf.Enabled {
+		if pf.Addr == "" {
+			return nil, xerrors.Errorf("prefetch requires an address")
+		}
+		if cfg.Registry.BlobCache == nil || !cfg.Registry.BlobCache.Enabled {
+			return nil, xerrors.Errorf("prefetch requires the blob cache")
+		}
+		if pf.TLS == nil {
+			return nil, xerrors.Errorf("prefetch requires TLS")
+		}
+	}
+
+	if lp := cfg.Registry.LazyPull; lp != nil && lp.Enabled {
+		if cfg.Registry.BlobCache == nil || !cfg.Registry.BlobCache.Enabled {
+			return nil, xerrors.Errorf("lazy pulling requires the blob cache")
+		}
+	}
+
 	if cfg.Registry.RedisCache != nil {
 		rd := cfg.Registry.RedisCache
 		rd.Password = os.Getenv("REDIS_PASSWORD")
@@ -73,6 +110,14 @@ type Config struct {
 	IPFSCache *IPFSCacheConfig `json:"ipfs,omitempty"`
 
 	RedisCache *RedisCacheConfig `json:"redis,omitempty"`
+
+	SignatureVerification *SignatureVerificationConfig `json:"signatureVerification,omitempty"`
+
+	BlobCache *BlobCacheConfig `json:"blobCache,omitempty"`
+
+	Prefetch *PrefetchConfig `json:"prefetch,omitempty"`
+
+	LazyPull *LazyPullConfig `json:"lazyPull,omitempty"`
 }
 
 type RedisCacheConfig struct {
@@ -91,6 +136,82 @@ type IPFSCacheConfig struct {
 	IPFSAddr string `json:"ipfsAddr"`
 }
 
+// BlobCacheConfig configures the node-local blob cache
+type BlobCacheConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Path is the directory in which cached blobs are stored
+	Path string `json:"path"`
+
+	// MaxSize is the maximum size of the cache in bytes. When the cache grows beyond
+	// this size the least recently used blobs are evicted.
+	MaxSize int64 `json:"maxSizeBytes"`
+
+	Peers *BlobCachePeersConfig `json:"peers,omitempty"`
+}
+
+// BlobCachePeersConfig configures the lookup of blobs in the caches of registry-facade instances on other nodes
+type BlobCachePeersConfig struct {
+	// Port is the port on which this instance serves its cache to peers and on which peers are contacted
+	Port int `json:"port"`
+
+	// Static is a fixed list of peer hosts
+	Static []string `json:"static,omitempty"`
+
+	// KubernetesService is the DNS name of a headless service whose endpoints are the peers,
+	// e.g. registry-facade-peers.default.svc.cluster.local
+	KubernetesService string `json:"kubernetesService,omitempty"`
+
+	// TLS configures mutual TLS between peers. Cached blobs may belong to private images, hence
+	// peers only serve them to clients presenting a certificate signed by the same authority.
+	TLS *TLS `json:"tls"`
+
+	// ServerName is the name peer certificates are verified against, as peers are contacted by IP address
+	ServerName string `json:"serverName"`
+}
+
+// PrefetchConfig configures the Prefetcher gRPC service which ws-manager uses to warm up the caches
+type PrefetchConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Addr is the address on which the Prefetcher service is served, e.g. :9501
+	Addr string `json:"addr"`
+
+	// TLS configures mutual TLS for the Prefetcher service. It is required, as prefetching makes registry-facade
+	// pull images using its registry credentials.
+	TLS *TLS `json:"tls,omitempty"`
+}
+
+// LazyPullConfig configures serving layers in the seekable eStargz format. Snapshotters which support
+// lazy pulling can start workspaces from such layers before all of their content was downloaded.
+type LazyPullConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// ConvertBaseLayers enables the conversion of base image layers. Layers are converted in the
+	// background and served in their original format until the conversion is done.
+	ConvertBaseLayers bool `json:"convertBaseLayers"`
+
+	// Workers is the number of layers converted concurrently. Defaults to 1.
+	Workers int `json:"workers,omitempty"`
+
+	// ChunkSize is the size in bytes of the chunks large files are split into. Defaults to the eStargz default.
+	ChunkSize int `json:"chunkSize,omitempty"`
+}
+
+// SignatureVerificationConfig configures the verification of cosign-compatible image signatures
+type SignatureVerificationConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// PublicKeys are paths to PEM encoded public keys. An image is accepted if its
+	// signature can be verified by any one of them.
+	PublicKeys []string `json:"publicKeys"`
+
+	// Refs lists the image spec refs which must be signed. Valid values are
+	// "base", "ide", "desktopIde" and "supervisor". If empty, only the workspace image (base) is verified,
+	// as it is the only image signed by image-builder.
+	Refs []string `json:"refs,omitempty"`
+}
+
 // StaticLayerCfg configure statically added layer
 type StaticLayerCfg struct {
 	Ref  string `json:"ref"`
@@ -0,0 +1,238 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.0
+// 	protoc        v3.20.0
+// source: prefetch.proto
+
+package api
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PrefetchImageRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Spec *ImageSpec `protobuf:"bytes,1,opt,name=spec,proto3" json:"spec,omitempty"`
+}
+
+func (x *PrefetchImageRequest) Reset() {
+	*x = PrefetchImageRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_prefetch_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PrefetchImageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrefetchImageRequest) ProtoMessage() {}
+
+func (x *PrefetchImageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_prefetch_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrefetchImageRequest.ProtoReflect.Descriptor instead.
+func (*PrefetchImageRequest) Descriptor() ([]byte, []int) {
+	return file_prefetch_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PrefetchImageRequest) GetSpec() *ImageSpec {
+	if x != nil {
+		return x.Spec
+	}
+	return nil
+}
+
+type PrefetchImageResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// blobs is the number of blobs downloaded by this call. Blobs which were already cached are not counted.
+	Blobs int32 `protobuf:"varint,1,opt,name=blobs,proto3" json:"blobs,omitempty"`
+	// bytes is the total size of all blobs downloaded by this call
+	Bytes int64 `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"`
+}
+
+func (x *PrefetchImageResponse) Reset() {
+	*x = PrefetchImageResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_prefetch_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PrefetchImageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrefetchImageResponse) ProtoMessage() {}
+
+func (x *PrefetchImageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_prefetch_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrefetchImageResponse.ProtoReflect.Descriptor instead.
+func (*PrefetchImageResponse) Descriptor() ([]byte, []int) {
+	return file_prefetch_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PrefetchImageResponse) GetBlobs() int32 {
+	if x != nil {
+		return x.Blobs
+	}
+	return 0
+}
+
+func (x *PrefetchImageResponse) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+var File_prefetch_proto protoreflect.FileDescriptor
+
+var file_prefetch_proto_rawDesc = []byte{
+	0x0a, 0x0e, 0x70, 0x72, 0x65, 0x66, 0x65, 0x74, 0x63, 0x68, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x12, 0x0e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x66, 0x61, 0x63, 0x61, 0x64, 0x65,
+	0x1a, 0x0f, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x73, 0x70, 0x65, 0x63, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x22, 0x45, 0x0a, 0x14, 0x50, 0x72, 0x65, 0x66, 0x65, 0x74, 0x63, 0x68, 0x49, 0x6d, 0x61,
+	0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2d, 0x0a, 0x04, 0x73, 0x70, 0x65,
+	0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74,
+	0x72, 0x79, 0x66, 0x61, 0x63, 0x61, 0x64, 0x65, 0x2e, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x53, 0x70,
+	0x65, 0x63, 0x52, 0x04, 0x73, 0x70, 0x65, 0x63, 0x22, 0x43, 0x0a, 0x15, 0x50, 0x72, 0x65, 0x66,
+	0x65, 0x74, 0x63, 0x68, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x62, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x05, 0x62, 0x6c, 0x6f, 0x62, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x32, 0x6c, 0x0a,
+	0x0a, 0x50, 0x72, 0x65, 0x66, 0x65, 0x74, 0x63, 0x68, 0x65, 0x72, 0x12, 0x5e, 0x0a, 0x0d, 0x50,
+	0x72, 0x65, 0x66, 0x65, 0x74, 0x63, 0x68, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x12, 0x24, 0x2e, 0x72,
+	0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x66, 0x61, 0x63, 0x61, 0x64, 0x65, 0x2e, 0x50, 0x72,
+	0x65, 0x66, 0x65, 0x74, 0x63, 0x68, 0x49, 0x6d, 0x61, 0x67, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x25, 0x2e, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x66, 0x61, 0x63,
+	0x61, 0x64, 0x65, 0x2e, 0x50, 0x72, 0x65, 0x66, 0x65, 0x74, 0x63, 0x68, 0x49, 0x6d, 0x61, 0x67,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x31, 0x5a, 0x2f, 0x67,
+	0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x67, 0x69, 0x74, 0x70, 0x6f, 0x64,
+	0x2d, 0x69, 0x6f, 0x2f, 0x67, 0x69, 0x74, 0x70, 0x6f, 0x64, 0x2f, 0x72, 0x65, 0x67, 0x69, 0x73,
+	0x74, 0x72, 0x79, 0x2d, 0x66, 0x61, 0x63, 0x61, 0x64, 0x65, 0x2f, 0x61, 0x70, 0x69, 0x62, 0x06,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_prefetch_proto_rawDescOnce sync.Once
+	file_prefetch_proto_rawDescData = file_prefetch_proto_rawDesc
+)
+
+func file_prefetch_proto_rawDescGZIP() []byte {
+	file_prefetch_proto_rawDescOnce.Do(func() {
+		file_prefetch_proto_rawDescData = protoimpl.X.CompressGZIP(file_prefetch_proto_rawDescData)
+	})
+	return file_prefetch_proto_rawDescData
+}
+
+var file_prefetch_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_prefetch_proto_goTypes = []interface{}{
+	(*PrefetchImageRequest)(nil),  // 0: registryfacade.PrefetchImageRequest
+	(*PrefetchImageResponse)(nil), // 1: registryfacade.PrefetchImageResponse
+	(*ImageSpec)(nil),             // 2: registryfacade.ImageSpec
+}
+var file_prefetch_proto_depIdxs = []int32{
+	2, // 0: registryfacade.PrefetchImageRequest.spec:type_name -> registryfacade.ImageSpec
+	0, // 1: registryfacade.Prefetcher.PrefetchImage:input_type -> registryfacade.PrefetchImageRequest
+	1, // 2: registryfacade.Prefetcher.PrefetchImage:output_type -> registryfacade.PrefetchImageResponse
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_prefetch_proto_init() }
+func file_prefetch_proto_init() {
+	if File_prefetch_proto != nil {
+		return
+	}
+	file_imagespec_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_prefetch_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PrefetchImageRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_prefetch_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PrefetchImageResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_prefetch_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_prefetch_proto_goTypes,
+		DependencyIndexes: file_prefetch_proto_depIdxs,
+		MessageInfos:      file_prefetch_proto_msgTypes,
+	}.Build()
+	File_prefetch_proto = out.File
+	file_prefetch_proto_rawDesc = nil
+	file_prefetch_proto_goTypes = nil
+	file_prefetch_proto_depIdxs = nil
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.20.0
+// source: prefetch.proto
+
+package api
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// PrefetcherClient is the client API for Prefetcher service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PrefetcherClient interface {
+	// PrefetchImage downloads the manifests, configs and layers of all images referenced by the image spec
+	// into the local caches of this registry-facade instance. Subsequent pulls of workspace images with the
+	// same spec are then served without contacting the upstream registries.
+	PrefetchImage(ctx context.Context, in *PrefetchImageRequest, opts ...grpc.CallOption) (*PrefetchImageResponse, error)
+}
+
+type prefetcherClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPrefetcherClient(cc grpc.ClientConnInterface) PrefetcherClient {
+	return &prefetcherClient{cc}
+}
+
+func (c *prefetcherClient) PrefetchImage(ctx context.Context, in *PrefetchImageRequest, opts ...grpc.CallOption) (*PrefetchImageResponse, error) {
+	out := new(PrefetchImageResponse)
+	err := c.cc.Invoke(ctx, "/registryfacade.Prefetcher/PrefetchImage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PrefetcherServer is the server API for Prefetcher service.
+// All implementations must embed UnimplementedPrefetcherServer
+// for forward compatibility
+type PrefetcherServer interface {
+	// PrefetchImage downloads the manifests, configs and layers of all images referenced by the image spec
+	// into the local caches of this registry-facade instance. Subsequent pulls of workspace images with the
+	// same spec are then served without contacting the upstream registries.
+	PrefetchImage(context.Context, *PrefetchImageRequest) (*PrefetchImageResponse, error)
+	mustEmbedUnimplementedPrefetcherServer()
+}
+
+// UnimplementedPrefetcherServer must be embedded to have forward compatible implementations.
+type UnimplementedPrefetcherServer struct {
+}
+
+func (UnimplementedPrefetcherServer) PrefetchImage(context.Context, *PrefetchImageRequest) (*PrefetchImageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PrefetchImage not implemented")
+}
+func (UnimplementedPrefetcherServer) mustEmbedUnimplementedPrefetcherServer() {}
+
+// UnsafePrefetcherServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PrefetcherServer will
+// result in compilation errors.
+type UnsafePrefetcherServer interface {
+	mustEmbedUnimplementedPrefetcherServer()
+}
+
+func RegisterPrefetcherServer(s grpc.ServiceRegistrar, srv PrefetcherServer) {
+	s.RegisterService(&Prefetcher_ServiceDesc, srv)
+}
+
+func _Prefetcher_PrefetchImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrefetchImageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PrefetcherServer).PrefetchImage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/registryfacade.Prefetcher/PrefetchImage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PrefetcherServer).PrefetchImage(ctx, req.(*PrefetchImageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Prefetcher_ServiceDesc is the grpc.ServiceDesc for Prefetcher service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Prefetcher_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "registryfacade.Prefetcher",
+	HandlerType: (*PrefetcherServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "PrefetchImage",
+			Handler:    _Prefetcher_PrefetchImage_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "prefetch.proto",
+}
@@ -5,9 +5,7 @@
 package registry
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"io"
 	"net/http"
 	"sync"
@@ -61,7 +59,9 @@ func (reg *Registry) handleBlob(ctx context.Context, r *http.Request) http.Handl
 		AdditionalSources: []BlobSource{
 			reg.LayerSource,
 		},
-		ConfigModifier: reg.ConfigModifier,
+		BlobCache:      reg.BlobCache,
+		BlobCachePeers: reg.BlobCachePeers,
+		EStargz:        reg.EStargz,
 
 		Metrics: reg.metrics,
 	}
@@ -88,7 +88,9 @@ type blobHandler struct {
 	Store             BlobStore
 	IPFS              *IPFSBlobCache
 	AdditionalSources []BlobSource
-	ConfigModifier    ConfigModifier
+	BlobCache         *DiskBlobCache
+	BlobCachePeers    *BlobCachePeers
+	EStargz           *EStargzConverter
 
 	Metrics *metrics
 }
@@ -122,7 +124,9 @@ func (bh *blobHandler) getBlob(w http.ResponseWriter, r *http.Request) {
 		var srcs []BlobSource
 		srcs = append(srcs, storeBlobSource{Store: bh.Store})
 		srcs = append(srcs, proxyingBlobSource{Fetcher: fetcher, Blobs: manifest.Layers})
-		srcs = append(srcs, &configBlobSource{Fetcher: fetcher, Spec: bh.Spec, Manifest: manifest, ConfigModifier: bh.ConfigModifier})
+		if bh.EStargz != nil {
+			srcs = append(srcs, &estargzBaseLayerSource{Converter: bh.EStargz, Layers: manifest.Layers})
+		}
 		srcs = append(srcs, bh.AdditionalSources...)
 
 		var src BlobSource
@@ -135,6 +139,14 @@ func (bh *blobHandler) getBlob(w http.ResponseWriter, r *http.Request) {
 		if src == nil {
 			return distv2.ErrorCodeBlobUnknown
 		}
+		if bh.BlobCache != nil {
+			switch src.(type) {
+			case storeBlobSource, *estargzBaseLayerSource:
+				// manifests and configs are cached in the store already, converted layers live in the blob cache
+			default:
+				src = &cachingBlobSource{Cache: bh.BlobCache, Peers: bh.BlobCachePeers, Delegate: src, Metrics: bh.Metrics}
+			}
+		}
 
 		mediaType, url, rc, err := src.GetBlob(ctx, bh.Spec, bh.Digest)
 		if err != nil {
@@ -285,52 +297,3 @@ func (pbs proxyingBlobSource) GetBlob(ctx context.Context, spec *api.ImageSpec,
 	}
 	return src.MediaType, "", r, nil
 }
-
-type configBlobSource struct {
-	Fetcher        remotes.Fetcher
-	Spec           *api.ImageSpec
-	Manifest       *ociv1.Manifest
-	ConfigModifier ConfigModifier
-}
-
-func (pbs *configBlobSource) HasBlob(ctx context.Context, spec *api.ImageSpec, dgst digest.Digest) bool {
-	cfg, err := pbs.getConfig(ctx)
-	if err != nil {
-		log.WithError(err).Error("cannot (re-)produce image config")
-		return false
-	}
-
-	cfgDgst := digest.FromBytes(cfg)
-	return cfgDgst == dgst
-}
-
-func (pbs *configBlobSource) GetBlob(ctx context.Context, spec *api.ImageSpec, dgst digest.Digest) (mediaType string, url string, data io.ReadCloser, err error) {
-	if !pbs.HasBlob(ctx, spec, dgst) {
-		err = distv2.ErrorCodeBlobUnknown
-		return
-	}
-
-	cfg, err := pbs.getConfig(ctx)
-	if err != nil {
-		return
-	}
-	mediaType = pbs.Manifest.Config.MediaType
-	data = io.NopCloser(bytes.NewReader(cfg))
-	return
-}
-
-func (pbs *configBlobSource) getConfig(ctx context.Context) (rawCfg []byte, err error) {
-	manifest := *pbs.Manifest
-	cfg, err := DownloadConfig(ctx, AsFetcherFunc(pbs.Fetcher), "", manifest.Config)
-	if err != nil {
-		return
-	}
-
-	_, err = pbs.ConfigModifier(ctx, pbs.Spec, cfg)
-	if err != nil {
-		return
-	}
-
-	rawCfg, err = json.Marshal(cfg)
-	return
-}
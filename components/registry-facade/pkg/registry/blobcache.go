@@ -0,0 +1,625 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package registry
+
+import (
+	"container/list"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	"golang.org/x/xerrors"
+
+	common_grpc "github.com/gitpod-io/gitpod/common-go/grpc"
+	"github.com/gitpod-io/gitpod/common-go/log"
+	"github.com/gitpod-io/gitpod/registry-facade/api"
+	"github.com/gitpod-io/gitpod/registry-facade/api/config"
+)
+
+const (
+	// blobCachePeerPathPrefix is the HTTP path prefix under which blob caches are served to peers
+	blobCachePeerPathPrefix = "/blobcache/"
+
+	// blobCachePeerTimeout limits how long connecting to a peer and waiting for its answer may take,
+	// so that an unreachable peer does not stall cache misses
+	blobCachePeerTimeout = 2 * time.Second
+
+	blobCacheMetaSuffix = ".meta"
+	blobCacheTempPrefix = "tmp-"
+)
+
+type blobCacheEntry struct {
+	Digest    digest.Digest `json:"digest"`
+	MediaType string        `json:"mediaType"`
+	Size      int64         `json:"size"`
+}
+
+// DiskBlobCache is a disk-backed, size-bounded LRU cache for blobs
+type DiskBlobCache struct {
+	Path    string
+	MaxSize int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List
+	entries map[digest.Digest]*list.Element
+}
+
+// NewDiskBlobCache creates a new disk blob cache and restores the entries found in path
+func NewDiskBlobCache(path string, maxSize int64) (*DiskBlobCache, error) {
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot create blob cache directory: %w", err)
+	}
+
+	cache := &DiskBlobCache{
+		Path:    path,
+		MaxSize: maxSize,
+		lru:     list.New(),
+		entries: make(map[digest.Digest]*list.Element),
+	}
+	err = cache.restore()
+	if err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// restore rebuilds the LRU index from the blobs found on disk, using their modification time as last use
+func (c *DiskBlobCache) restore() error {
+	files, err := os.ReadDir(c.Path)
+	if err != nil {
+		return xerrors.Errorf("cannot read blob cache directory: %w", err)
+	}
+
+	type restored struct {
+		Entry   blobCacheEntry
+		ModTime time.Time
+	}
+	var found []restored
+	for _, f := range files {
+		fn := filepath.Join(c.Path, f.Name())
+		if strings.HasPrefix(f.Name(), blobCacheTempPrefix) {
+			// left over from an interrupted download
+			_ = os.Remove(fn)
+			continue
+		}
+		if !strings.HasSuffix(f.Name(), blobCacheMetaSuffix) {
+			continue
+		}
+
+		var entry blobCacheEntry
+		fc, err := os.ReadFile(fn)
+		if err == nil {
+			err = json.Unmarshal(fc, &entry)
+		}
+		if err != nil {
+			log.WithError(err).WithField("fn", fn).Warn("removing unreadable blob cache entry")
+			_ = os.Remove(fn)
+			continue
+		}
+
+		stat, err := os.Stat(c.blobPath(entry.Digest))
+		if err != nil || stat.Size() != entry.Size {
+			log.WithField("digest", entry.Digest).Warn("removing incomplete blob cache entry")
+			c.remove(entry.Digest)
+			continue
+		}
+		found = append(found, restored{Entry: entry, ModTime: stat.ModTime()})
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].ModTime.Before(found[j].ModTime) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range found {
+		c.entries[r.Entry.Digest] = c.lru.PushFront(r.Entry)
+		c.size += r.Entry.Size
+	}
+	c.evict()
+
+	log.WithField("entries", len(c.entries)).WithField("size", c.size).Info("restored blob cache")
+	return nil
+}
+
+func (c *DiskBlobCache) blobPath(dgst digest.Digest) string {
+	return filepath.Join(c.Path, fmt.Sprintf("%s-%s", dgst.Algorithm(), dgst.Encoded()))
+}
+
+func (c *DiskBlobCache) remove(dgst digest.Digest) {
+	fn := c.blobPath(dgst)
+	_ = os.Remove(fn)
+	_ = os.Remove(fn + blobCacheMetaSuffix)
+}
+
+// evict removes least recently used entries until the cache fits its size. Callers must hold mu.
+func (c *DiskBlobCache) evict() {
+	for c.size > c.MaxSize {
+		el := c.lru.Back()
+		if el == nil {
+			return
+		}
+		entry := c.lru.Remove(el).(blobCacheEntry)
+		delete(c.entries, entry.Digest)
+		c.size -= entry.Size
+		c.remove(entry.Digest)
+	}
+}
+
+// Has returns true if the blob is in the cache
+func (c *DiskBlobCache) Has(dgst digest.Digest) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[dgst]
+	return ok
+}
+
+// Get returns the content of a cached blob. Returns errdefs.ErrNotFound if the blob is not cached.
+func (c *DiskBlobCache) Get(dgst digest.Digest) (mediaType string, size int64, data io.ReadCloser, err error) {
+	c.mu.Lock()
+	el, ok := c.entries[dgst]
+	if !ok {
+		c.mu.Unlock()
+		return "", 0, nil, errdefs.ErrNotFound
+	}
+	c.lru.MoveToFront(el)
+	entry := el.Value.(blobCacheEntry)
+	c.mu.Unlock()
+
+	f, err := os.Open(c.blobPath(dgst))
+	if os.IsNotExist(err) {
+		return "", 0, nil, errdefs.ErrNotFound
+	}
+	if err != nil {
+		return "", 0, nil, err
+	}
+	now := time.Now()
+	_ = os.Chtimes(f.Name(), now, now)
+
+	return entry.MediaType, entry.Size, f, nil
+}
+
+// Store adds a blob to the cache. The content is only added if its digest matches dgst.
+func (c *DiskBlobCache) Store(dgst digest.Digest, mediaType string, r io.Reader) error {
+	w, err := c.newWriter(dgst, mediaType)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	if err != nil {
+		w.Abort()
+		return err
+	}
+	return w.Commit()
+}
+
+// WriteThrough returns a reader which adds the blob to the cache while rc is read.
+// The blob is only added once the reader was read to the end.
+func (c *DiskBlobCache) WriteThrough(dgst digest.Digest, mediaType string, rc io.ReadCloser) io.ReadCloser {
+	if c == nil || c.Has(dgst) {
+		return rc
+	}
+
+	w, err := c.newWriter(dgst, mediaType)
+	if err != nil {
+		log.WithError(err).WithField("digest", dgst).Warn("cannot add blob to cache")
+		return rc
+	}
+	return &writeThroughReader{ReadCloser: rc, w: w}
+}
+
+type writeThroughReader struct {
+	io.ReadCloser
+	w    *blobCacheWriter
+	done bool
+}
+
+func (r *writeThroughReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 && !r.done {
+		if _, werr := r.w.Write(p[:n]); werr != nil {
+			log.WithError(werr).WithField("digest", r.w.dgst).Warn("cannot add blob to cache")
+			r.w.Abort()
+			r.done = true
+		}
+	}
+	if err == io.EOF && !r.done {
+		if cerr := r.w.Commit(); cerr != nil {
+			log.WithError(cerr).WithField("digest", r.w.dgst).Warn("cannot add blob to cache")
+		}
+		r.done = true
+	}
+	return
+}
+
+func (r *writeThroughReader) Close() error {
+	if !r.done {
+		r.w.Abort()
+		r.done = true
+	}
+	return r.ReadCloser.Close()
+}
+
+type blobCacheWriter struct {
+	c         *DiskBlobCache
+	f         *os.File
+	dgst      digest.Digest
+	mediaType string
+	digester  digest.Digester
+	size      int64
+}
+
+func (c *DiskBlobCache) newWriter(dgst digest.Digest, mediaType string) (*blobCacheWriter, error) {
+	f, err := os.CreateTemp(c.Path, blobCacheTempPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	return &blobCacheWriter{
+		c:         c,
+		f:         f,
+		dgst:      dgst,
+		mediaType: mediaType,
+		digester:  dgst.Algorithm().Digester(),
+	}, nil
+}
+
+func (w *blobCacheWriter) Write(p []byte) (n int, err error) {
+	n, err = w.f.Write(p)
+	w.size += int64(n)
+	_, _ = w.digester.Hash().Write(p[:n])
+	return
+}
+
+func (w *blobCacheWriter) Abort() {
+	w.f.Close()
+	os.Remove(w.f.Name())
+}
+
+func (w *blobCacheWriter) Commit() error {
+	c := w.c
+	err := w.f.Close()
+	if err != nil {
+		os.Remove(w.f.Name())
+		return err
+	}
+	if act := w.digester.Digest(); act != w.dgst {
+		os.Remove(w.f.Name())
+		return xerrors.Errorf("digest mismatch: expected %s, got %s", w.dgst, act)
+	}
+	if w.size > c.MaxSize {
+		os.Remove(w.f.Name())
+		return xerrors.Errorf("blob is larger than the cache")
+	}
+
+	entry := blobCacheEntry{Digest: w.dgst, MediaType: w.mediaType, Size: w.size}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		os.Remove(w.f.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[w.dgst]; exists {
+		os.Remove(w.f.Name())
+		return nil
+	}
+	err = os.Rename(w.f.Name(), c.blobPath(w.dgst))
+	if err != nil {
+		os.Remove(w.f.Name())
+		return err
+	}
+	err = os.WriteFile(c.blobPath(w.dgst)+blobCacheMetaSuffix, meta, 0644)
+	if err != nil {
+		c.remove(w.dgst)
+		return err
+	}
+
+	c.entries[w.dgst] = c.lru.PushFront(entry)
+	c.size += entry.Size
+	c.evict()
+	return nil
+}
+
+// ServeHTTP serves cached blobs to peers. Only blobs present in this cache are served.
+func (c *DiskBlobCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	dgst, err := digest.Parse(strings.TrimPrefix(r.URL.Path, blobCachePeerPathPrefix))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mediaType, size, rc, err := c.Get(dgst)
+	if errdefs.IsNotFound(err) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.WithError(err).WithField("digest", dgst).Warn("cannot serve blob to peer")
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Content-Length", fmt.Sprint(size))
+	if r.Method == http.MethodHead {
+		return
+	}
+	bp := bufPool.Get().(*[]byte)
+	defer bufPool.Put(bp)
+	_, _ = io.CopyBuffer(w, rc, *bp)
+}
+
+// BlobCachePeers finds blobs in the caches of registry-facade instances on other nodes
+type BlobCachePeers struct {
+	Port              int
+	Static            []string
+	KubernetesService string
+	Client            *http.Client
+
+	mu    sync.RWMutex
+	peers []string
+}
+
+// NewBlobCachePeers creates peer lookup from configuration
+func NewBlobCachePeers(cfg *config.BlobCachePeersConfig) (*BlobCachePeers, error) {
+	tlsConfig, err := common_grpc.ClientAuthTLSConfig(
+		cfg.TLS.Authority, cfg.TLS.Certificate, cfg.TLS.PrivateKey,
+		common_grpc.WithSetRootCAs(true),
+		common_grpc.WithServerName(cfg.ServerName),
+	)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot load blob cache peer certs: %w", err)
+	}
+
+	return &BlobCachePeers{
+		Port:              cfg.Port,
+		Static:            cfg.Static,
+		KubernetesService: cfg.KubernetesService,
+		Client: &http.Client{
+			Transport: &http.Transport{
+				DialContext:           (&net.Dialer{Timeout: blobCachePeerTimeout}).DialContext,
+				TLSClientConfig:       tlsConfig,
+				TLSHandshakeTimeout:   blobCachePeerTimeout,
+				ResponseHeaderTimeout: blobCachePeerTimeout,
+				ForceAttemptHTTP2:     true,
+			},
+		},
+	}, nil
+}
+
+// ServeBlobCacheToPeers serves the blob cache to peers which authenticate using a client certificate
+func ServeBlobCacheToPeers(cache *DiskBlobCache, cfg *config.BlobCachePeersConfig) error {
+	tlsConfig, err := common_grpc.ClientAuthTLSConfig(
+		cfg.TLS.Authority, cfg.TLS.Certificate, cfg.TLS.PrivateKey,
+		common_grpc.WithClientAuth(tls.RequireAndVerifyClientCert),
+		common_grpc.WithSetClientCAs(true),
+	)
+	if err != nil {
+		return xerrors.Errorf("cannot load blob cache peer certs: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(blobCachePeerPathPrefix, cache)
+	srv := &http.Server{
+		Addr:      fmt.Sprintf(":%d", cfg.Port),
+		Handler:   mux,
+		TLSConfig: tlsConfig,
+	}
+	log.WithField("addr", srv.Addr).Info("serving blob cache to peers")
+	return srv.ListenAndServeTLS("", "")
+}
+
+// Run periodically refreshes the list of peers until the context is canceled
+func (p *BlobCachePeers) Run(ctx context.Context) {
+	t := time.NewTicker(1 * time.Minute)
+	defer t.Stop()
+	for {
+		p.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (p *BlobCachePeers) refresh(ctx context.Context) {
+	hosts := append([]string{}, p.Static...)
+	if p.KubernetesService != "" {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, p.KubernetesService)
+		if err != nil {
+			log.WithError(err).WithField("service", p.KubernetesService).Warn("cannot discover blob cache peers")
+		}
+		hosts = append(hosts, addrs...)
+	}
+
+	own := make(map[string]struct{})
+	if ifaddrs, err := net.InterfaceAddrs(); err == nil {
+		for _, a := range ifaddrs {
+			if ipnet, ok := a.(*net.IPNet); ok {
+				own[ipnet.IP.String()] = struct{}{}
+			}
+		}
+	}
+
+	peers := make([]string, 0, len(hosts))
+	for _, h := range hosts {
+		if _, self := own[h]; self {
+			continue
+		}
+		peers = append(peers, net.JoinHostPort(h, fmt.Sprint(p.Port)))
+	}
+
+	p.mu.Lock()
+	p.peers = peers
+	p.mu.Unlock()
+}
+
+// Peers returns the addresses of all currently known peers
+func (p *BlobCachePeers) Peers() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.peers
+}
+
+// Fetch downloads a blob from a peer which has it cached. All peers are asked at once,
+// the first one to answer with the blob wins. Returns errdefs.ErrNotFound if no peer has the blob.
+func (p *BlobCachePeers) Fetch(ctx context.Context, dgst digest.Digest) (mediaType string, data io.ReadCloser, err error) {
+	peers := p.Peers()
+	if len(peers) == 0 {
+		return "", nil, errdefs.ErrNotFound
+	}
+
+	type peerResponse struct {
+		Idx  int
+		Resp *http.Response
+	}
+	var (
+		responses = make(chan peerResponse, len(peers))
+		cancels   = make([]context.CancelFunc, len(peers))
+	)
+	for i, peer := range peers {
+		reqCtx, cancel := context.WithCancel(ctx)
+		cancels[i] = cancel
+		go func(i int, peer string) {
+			resp, err := p.fetchFromPeer(reqCtx, peer, dgst)
+			if err != nil {
+				log.WithError(err).WithField("peer", peer).Debug("cannot fetch blob from peer")
+			}
+			responses <- peerResponse{Idx: i, Resp: resp}
+		}(i, peer)
+	}
+
+	for received := 1; received <= len(peers); received++ {
+		r := <-responses
+		if r.Resp == nil {
+			cancels[r.Idx]()
+			continue
+		}
+
+		// cancel all other requests and drop their responses
+		for i, cancel := range cancels {
+			if i != r.Idx {
+				cancel()
+			}
+		}
+		go func(remaining int) {
+			for ; remaining > 0; remaining-- {
+				if o := <-responses; o.Resp != nil {
+					o.Resp.Body.Close()
+				}
+			}
+		}(len(peers) - received)
+
+		body := &cancelingReadCloser{ReadCloser: r.Resp.Body, cancel: cancels[r.Idx]}
+		return r.Resp.Header.Get("Content-Type"), &verifyingReader{ReadCloser: body, dgst: dgst, digester: dgst.Algorithm().Digester()}, nil
+	}
+	return "", nil, errdefs.ErrNotFound
+}
+
+func (p *BlobCachePeers) fetchFromPeer(ctx context.Context, peer string, dgst digest.Digest) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://%s%s%s", peer, blobCachePeerPathPrefix, dgst), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, xerrors.Errorf("peer responded with %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// cancelingReadCloser cancels the context of a request once its body is closed
+type cancelingReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelingReadCloser) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
+
+// verifyingReader fails at EOF if the content read does not match the expected digest
+type verifyingReader struct {
+	io.ReadCloser
+	dgst     digest.Digest
+	digester digest.Digester
+}
+
+func (r *verifyingReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	_, _ = r.digester.Hash().Write(p[:n])
+	if err == io.EOF && r.digester.Digest() != r.dgst {
+		err = xerrors.Errorf("digest mismatch: expected %s, got %s", r.dgst, r.digester.Digest())
+	}
+	return
+}
+
+// cachingBlobSource serves blobs from the node-local cache, then peers and only then from its delegate.
+// Blobs downloaded from peers or the delegate are added to the local cache.
+type cachingBlobSource struct {
+	Cache    *DiskBlobCache
+	Peers    *BlobCachePeers
+	Delegate BlobSource
+	Metrics  *metrics
+}
+
+func (cbs *cachingBlobSource) HasBlob(ctx context.Context, spec *api.ImageSpec, dgst digest.Digest) bool {
+	return cbs.Cache.Has(dgst) || cbs.Delegate.HasBlob(ctx, spec, dgst)
+}
+
+func (cbs *cachingBlobSource) GetBlob(ctx context.Context, spec *api.ImageSpec, dgst digest.Digest) (mediaType string, url string, data io.ReadCloser, err error) {
+	mediaType, _, data, err = cbs.Cache.Get(dgst)
+	if err == nil {
+		cbs.Metrics.BlobCacheCounter.WithLabelValues("local").Inc()
+		return mediaType, "", data, nil
+	}
+	if !errdefs.IsNotFound(err) {
+		log.WithError(err).WithField("digest", dgst).Warn("cannot read blob from cache")
+	}
+
+	if cbs.Peers != nil {
+		mediaType, data, err = cbs.Peers.Fetch(ctx, dgst)
+		if err == nil {
+			cbs.Metrics.BlobCacheCounter.WithLabelValues("peer").Inc()
+			return mediaType, "", cbs.Cache.WriteThrough(dgst, mediaType, data), nil
+		}
+	}
+
+	mediaType, url, data, err = cbs.Delegate.GetBlob(ctx, spec, dgst)
+	if err != nil || data == nil {
+		return
+	}
+	cbs.Metrics.BlobCacheCounter.WithLabelValues("upstream").Inc()
+	return mediaType, url, cbs.Cache.WriteThrough(dgst, mediaType, data), nil
+}
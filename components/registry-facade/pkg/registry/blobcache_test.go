@@ -0,0 +1,154 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+)
+
+func TestDiskBlobCache(t *testing.T) {
+	var (
+		blobA = []byte(strings.Repeat("a", 40))
+		blobB = []byte(strings.Repeat("b", 40))
+		blobC = []byte(strings.Repeat("c", 40))
+	)
+
+	dir := t.TempDir()
+	cache, err := NewDiskBlobCache(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, b := range [][]byte{blobA, blobB} {
+		err = cache.Store(digest.FromBytes(b), "application/octet-stream", bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// touch A so that B becomes the least recently used blob
+	_, _, rc, err := cache.Get(digest.FromBytes(blobA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+
+	err = cache.Store(digest.FromBytes(blobC), "application/octet-stream", bytes.NewReader(blobC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Has(digest.FromBytes(blobB)) {
+		t.Error("expected least recently used blob to be evicted")
+	}
+	if !cache.Has(digest.FromBytes(blobA)) || !cache.Has(digest.FromBytes(blobC)) {
+		t.Error("expected recently used blobs to remain in cache")
+	}
+
+	err = cache.Store(digest.FromBytes(blobB), "application/octet-stream", bytes.NewReader(blobA))
+	if err == nil {
+		t.Error("expected blob with mismatching digest to be rejected")
+	}
+
+	restored, err := NewDiskBlobCache(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, _, rc, err := restored.Get(digest.FromBytes(blobC))
+	if err != nil {
+		t.Fatalf("expected blob to survive restart: %v", err)
+	}
+	defer rc.Close()
+	if mediaType != "application/octet-stream" {
+		t.Errorf("unexpected media type after restart: %s", mediaType)
+	}
+	content, _ := io.ReadAll(rc)
+	if !bytes.Equal(content, blobC) {
+		t.Error("unexpected content after restart")
+	}
+}
+
+func TestDiskBlobCacheWriteThrough(t *testing.T) {
+	blob := []byte("hello world")
+	dgst := digest.FromBytes(blob)
+
+	cache, err := NewDiskBlobCache(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rc := cache.WriteThrough(dgst, "text/plain", io.NopCloser(bytes.NewReader(blob[:5])))
+	_, _ = io.ReadAll(rc)
+	rc.Close()
+	if cache.Has(dgst) {
+		t.Fatal("expected truncated blob not to be cached")
+	}
+
+	rc = cache.WriteThrough(dgst, "text/plain", io.NopCloser(bytes.NewReader(blob)))
+	_, _ = io.ReadAll(rc)
+	rc.Close()
+	if !cache.Has(dgst) {
+		t.Fatal("expected blob to be cached once read completely")
+	}
+}
+
+func TestBlobCachePeers(t *testing.T) {
+	blob := []byte("shared between nodes")
+	dgst := digest.FromBytes(blob)
+
+	peerCache, err := NewDiskBlobCache(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = peerCache.Store(dgst, "text/plain", bytes.NewReader(blob))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewTLSServer(peerCache)
+	defer srv.Close()
+
+	// a peer which accepts connections but never answers must not stall the lookup
+	stalled, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stalled.Close()
+
+	peers := &BlobCachePeers{Client: srv.Client(), peers: []string{stalled.Addr().String(), strings.TrimPrefix(srv.URL, "https://")}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	mediaType, rc, err := peers.Fetch(ctx, dgst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mediaType != "text/plain" || !bytes.Equal(content, blob) {
+		t.Errorf("unexpected blob from peer: %s %q", mediaType, content)
+	}
+
+	if ctx.Err() != nil {
+		t.Fatal("blob lookup waited for the stalled peer")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, _, err = peers.Fetch(ctx, digest.FromString("unknown"))
+	if err == nil {
+		t.Error("expected unknown blob not to be found")
+	}
+}
@@ -0,0 +1,631 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package registry
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/stargz-snapshotter/estargz"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/opencontainers/go-digest"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+	"github.com/gitpod-io/gitpod/registry-facade/api"
+	"github.com/gitpod-io/gitpod/registry-facade/api/config"
+)
+
+const (
+	// estargzIndexDir is the directory within the blob cache in which converted layers are recorded
+	estargzIndexDir = "estargz"
+
+	// estargzSyncConvertSize is the size up to which addon layers are converted while the manifest is produced.
+	// Larger layers are converted in the background.
+	estargzSyncConvertSize = 4 * 1024 * 1024
+
+	// estargzConvertTimeout is the time we allow for downloading and converting a single layer
+	estargzConvertTimeout = 30 * time.Minute
+
+	// estargzMaxOpeners is the number of converted layers which are converted again if they were evicted from the cache
+	estargzMaxOpeners = 1024
+)
+
+// EStargzLayer describes a layer which was converted to the eStargz format
+type EStargzLayer struct {
+	// Source is the digest of the layer this one was converted from
+	Source           digest.Digest `json:"source"`
+	Digest           digest.Digest `json:"digest"`
+	Size             int64         `json:"size"`
+	DiffID           digest.Digest `json:"diffID"`
+	TOCDigest        digest.Digest `json:"tocDigest"`
+	UncompressedSize int64         `json:"uncompressedSize"`
+}
+
+// Descriptor returns the descriptor of the converted layer, including the TOC annotations
+// lazy-pulling snapshotters require. srcMediaType is the media type of the original layer.
+func (l *EStargzLayer) Descriptor(srcMediaType string) ociv1.Descriptor {
+	mediaType := ociv1.MediaTypeImageLayerGzip
+	if strings.HasPrefix(srcMediaType, "application/vnd.docker.") {
+		mediaType = images.MediaTypeDockerSchema2LayerGzip
+	}
+
+	return ociv1.Descriptor{
+		MediaType: mediaType,
+		Digest:    l.Digest,
+		Size:      l.Size,
+		Annotations: map[string]string{
+			estargz.TOCJSONDigestAnnotation:         l.TOCDigest.String(),
+			estargz.StoreUncompressedSizeAnnotation: strconv.FormatInt(l.UncompressedSize, 10),
+		},
+	}
+}
+
+// isEStargzConvertible returns true if the layer described by desc can be converted to eStargz
+func isEStargzConvertible(desc ociv1.Descriptor) bool {
+	if len(desc.URLs) > 0 {
+		// layers served from elsewhere are not ours to convert
+		return false
+	}
+	if _, ok := desc.Annotations[estargz.TOCJSONDigestAnnotation]; ok {
+		return false
+	}
+
+	switch desc.MediaType {
+	case ociv1.MediaTypeImageLayer, ociv1.MediaTypeImageLayerGzip,
+		images.MediaTypeDockerSchema2Layer, images.MediaTypeDockerSchema2LayerGzip:
+		return true
+	default:
+		return false
+	}
+}
+
+// layerOpener downloads the original version of a layer
+type layerOpener func(ctx context.Context) (io.ReadCloser, error)
+
+// EStargzConverter converts layers to the seekable eStargz format and serves the converted blobs
+// from the blob cache. Converted blobs which were evicted from the cache are converted again
+// when they are requested.
+type EStargzConverter struct {
+	Cache       *DiskBlobCache
+	ChunkSize   int
+	ConvertBase bool
+
+	mu        sync.Mutex
+	bySource  map[digest.Digest]*EStargzLayer
+	byDigest  map[digest.Digest]*EStargzLayer
+	openers   *lru.Cache
+	inflight  map[digest.Digest]struct{}
+	workers   chan struct{}
+	indexPath string
+}
+
+// NewEStargzConverter creates a new converter which stores converted layers in the blob cache
+func NewEStargzConverter(cfg *config.LazyPullConfig, cache *DiskBlobCache) (*EStargzConverter, error) {
+	if cache == nil {
+		return nil, xerrors.Errorf("lazy pulling requires the blob cache")
+	}
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	// openers reference their image spec, hence we keep only those of recently served layers
+	openers, err := lru.New(estargzMaxOpeners)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &EStargzConverter{
+		Cache:       cache,
+		ChunkSize:   cfg.ChunkSize,
+		ConvertBase: cfg.ConvertBaseLayers,
+		bySource:    make(map[digest.Digest]*EStargzLayer),
+		byDigest:    make(map[digest.Digest]*EStargzLayer),
+		inflight:    make(map[digest.Digest]struct{}),
+		openers:     openers,
+		workers:     make(chan struct{}, workers),
+		indexPath:   filepath.Join(cache.Path, estargzIndexDir),
+	}
+	err = os.MkdirAll(c.indexPath, 0755)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot create eStargz index directory: %w", err)
+	}
+	err = c.restore()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// restore reads the record of previously converted layers
+func (c *EStargzConverter) restore() error {
+	files, err := os.ReadDir(c.indexPath)
+	if err != nil {
+		return xerrors.Errorf("cannot read eStargz index: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range files {
+		fn := filepath.Join(c.indexPath, f.Name())
+
+		var layer EStargzLayer
+		fc, err := os.ReadFile(fn)
+		if err == nil {
+			err = json.Unmarshal(fc, &layer)
+		}
+		if err != nil {
+			log.WithError(err).WithField("fn", fn).Warn("removing unreadable eStargz index entry")
+			_ = os.Remove(fn)
+			continue
+		}
+		c.bySource[layer.Source] = &layer
+		c.byDigest[layer.Digest] = &layer
+	}
+	return nil
+}
+
+func (c *EStargzConverter) indexEntryPath(src digest.Digest) string {
+	return filepath.Join(c.indexPath, fmt.Sprintf("%s-%s.json", src.Algorithm(), src.Encoded()))
+}
+
+// Lookup returns the eStargz version of a layer if it was converted and is still cached
+func (c *EStargzConverter) Lookup(src digest.Digest) (*EStargzLayer, bool) {
+	return c.lookup(src, nil)
+}
+
+// lookup returns the eStargz version of a layer if it was converted and is still cached.
+// If open is not nil, it is remembered to convert the layer again in case its converted
+// version is evicted from the cache before clients downloaded it.
+func (c *EStargzConverter) lookup(src digest.Digest, open layerOpener) (*EStargzLayer, bool) {
+	c.mu.Lock()
+	layer, ok := c.bySource[src]
+	c.mu.Unlock()
+	if ok && open != nil {
+		c.openers.Add(layer.Digest, open)
+	}
+
+	if !ok || !c.Cache.Has(layer.Digest) {
+		return nil, false
+	}
+	return layer, true
+}
+
+// Convert converts the layer with digest src, read from r, and adds the result to the blob cache
+func (c *EStargzConverter) Convert(src digest.Digest, r io.Reader) (res *EStargzLayer, err error) {
+	if layer, ok := c.Lookup(src); ok {
+		return layer, nil
+	}
+
+	in, err := os.CreateTemp(c.Cache.Path, blobCacheTempPrefix+"estargz-src-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(in.Name())
+	defer in.Close()
+
+	verifier := src.Verifier()
+	n, err := io.Copy(io.MultiWriter(in, verifier), r)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot read layer: %w", err)
+	}
+	if !verifier.Verified() {
+		return nil, xerrors.Errorf("layer does not match digest %s", src)
+	}
+
+	opts := []estargz.Option{estargz.WithCompression(&estargzGzipCompression{GzipDecompressor: &estargz.GzipDecompressor{}})}
+	if c.ChunkSize > 0 {
+		opts = append(opts, estargz.WithChunkSize(c.ChunkSize))
+	}
+	blob, err := estargz.Build(io.NewSectionReader(in, 0, n), opts...)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot convert layer: %w", err)
+	}
+	defer blob.Close()
+
+	out, err := os.CreateTemp(c.Cache.Path, blobCacheTempPrefix+"estargz-dst-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	digester := digest.Canonical.Digester()
+	size, err := io.Copy(io.MultiWriter(out, digester.Hash()), blob)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot write converted layer: %w", err)
+	}
+	err = blob.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// snapshotters use the uncompressed size to size their local copy of the layer
+	_, err = out.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(out)
+	if err != nil {
+		return nil, err
+	}
+	uncompressedSize, err := io.Copy(io.Discard, zr)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot read converted layer: %w", err)
+	}
+	_, err = out.Seek(0, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	res = &EStargzLayer{
+		Source:           src,
+		Digest:           digester.Digest(),
+		Size:             size,
+		DiffID:           blob.DiffID(),
+		TOCDigest:        blob.TOCDigest(),
+		UncompressedSize: uncompressedSize,
+	}
+	err = c.Cache.Store(res.Digest, ociv1.MediaTypeImageLayerGzip, out)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot cache converted layer: %w", err)
+	}
+
+	rawEntry, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	err = os.WriteFile(c.indexEntryPath(src), rawEntry, 0644)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot record converted layer: %w", err)
+	}
+
+	c.mu.Lock()
+	c.bySource[src] = res
+	c.byDigest[res.Digest] = res
+	c.mu.Unlock()
+
+	log.WithField("source", src).WithField("digest", res.Digest).WithField("tocDigest", res.TOCDigest).Debug("converted layer to eStargz")
+	return res, nil
+}
+
+// ConvertAsync converts a layer in the background unless its conversion is already under way.
+// open is called once a conversion worker is available.
+func (c *EStargzConverter) ConvertAsync(src digest.Digest, open func(ctx context.Context) (io.ReadCloser, error)) {
+	c.mu.Lock()
+	if _, ok := c.inflight[src]; ok {
+		c.mu.Unlock()
+		return
+	}
+	c.inflight[src] = struct{}{}
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.inflight, src)
+			c.mu.Unlock()
+		}()
+
+		c.workers <- struct{}{}
+		defer func() { <-c.workers }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), estargzConvertTimeout)
+		defer cancel()
+
+		rc, err := open(ctx)
+		if err != nil {
+			log.WithError(err).WithField("source", src).Warn("cannot download layer for eStargz conversion")
+			return
+		}
+		defer rc.Close()
+
+		_, err = c.Convert(src, rc)
+		if err != nil {
+			log.WithError(err).WithField("source", src).Warn("cannot convert layer to eStargz")
+		}
+	}()
+}
+
+// ConvertBaseLayers replaces the layers in manifest and the corresponding diffIDs in cfg with their eStargz version
+// where one exists. If fetch is not nil, the conversion of all other layers is scheduled.
+func (c *EStargzConverter) ConvertBaseLayers(manifest *ociv1.Manifest, cfg *ociv1.Image, fetch func(ctx context.Context, desc ociv1.Descriptor) (io.ReadCloser, error)) {
+	if c == nil || !c.ConvertBase {
+		return
+	}
+	if len(manifest.Layers) != len(cfg.RootFS.DiffIDs) {
+		// we cannot tell which diffID belongs to which layer
+		return
+	}
+
+	for i, l := range manifest.Layers {
+		if !isEStargzConvertible(l) {
+			continue
+		}
+
+		var open layerOpener
+		if fetch != nil {
+			desc := l
+			open = func(ctx context.Context) (io.ReadCloser, error) {
+				return fetch(ctx, desc)
+			}
+		}
+		if layer, ok := c.lookup(l.Digest, open); ok {
+			manifest.Layers[i] = layer.Descriptor(l.MediaType)
+			cfg.RootFS.DiffIDs[i] = layer.DiffID
+			continue
+		}
+		if open == nil {
+			continue
+		}
+
+		c.ConvertAsync(l.Digest, open)
+	}
+}
+
+// convertedLayer returns the converted layer with digest dgst if it can be served, i.e. if it is cached
+// or can be converted again. Callers must check that the source of the layer belongs to the image spec
+// the layer is requested for, as converted layers of all images share the cache.
+func (c *EStargzConverter) convertedLayer(dgst digest.Digest) (*EStargzLayer, bool) {
+	c.mu.Lock()
+	layer, ok := c.byDigest[dgst]
+	c.mu.Unlock()
+
+	if !ok || !(c.openers.Contains(dgst) || c.Cache.Has(dgst)) {
+		return nil, false
+	}
+	return layer, true
+}
+
+// getBlob provides access to a converted layer, converting it again if it was evicted from the cache.
+// The receiver is expected to close the returned ReadCloser eventually.
+func (c *EStargzConverter) getBlob(ctx context.Context, dgst digest.Digest) (mediaType string, url string, data io.ReadCloser, err error) {
+	c.mu.Lock()
+	layer, ok := c.byDigest[dgst]
+	c.mu.Unlock()
+	if !ok {
+		err = errdefs.ErrNotFound
+		return
+	}
+
+	mediaType, _, data, err = c.Cache.Get(dgst)
+	if !errdefs.IsNotFound(err) {
+		return
+	}
+	open, ok := c.openers.Get(dgst)
+	if !ok {
+		return
+	}
+
+	// the converted layer was evicted from the cache - conversion is deterministic, hence converting
+	// the original layer again produces the very blob clients expect
+	log.WithField("source", layer.Source).WithField("digest", dgst).Debug("converting evicted eStargz layer again")
+	err = c.reconvert(ctx, layer, open.(layerOpener))
+	if err != nil {
+		return "", "", nil, err
+	}
+	mediaType, _, data, err = c.Cache.Get(dgst)
+	return
+}
+
+func (c *EStargzConverter) reconvert(ctx context.Context, layer *EStargzLayer, open layerOpener) error {
+	rc, err := open(ctx)
+	if err != nil {
+		return xerrors.Errorf("cannot download layer %s for eStargz conversion: %w", layer.Source, err)
+	}
+	defer rc.Close()
+
+	res, err := c.Convert(layer.Source, rc)
+	if err != nil {
+		return err
+	}
+	if res.Digest != layer.Digest {
+		return xerrors.Errorf("converting layer %s again produced %s instead of %s", layer.Source, res.Digest, layer.Digest)
+	}
+	return nil
+}
+
+// estargzGzipCompression produces regular gzip eStargz blobs. Other than the eStargz default it writes the
+// footer by hand: the footer must be exactly estargz.FooterSize bytes long, which compress/gzip does not
+// guarantee for empty streams across Go versions.
+type estargzGzipCompression struct {
+	*estargz.GzipDecompressor
+}
+
+func (gc *estargzGzipCompression) Writer(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, gzip.BestCompression)
+}
+
+func (gc *estargzGzipCompression) WriteTOCAndFooter(w io.Writer, off int64, toc *estargz.JTOC, diffHash hash.Hash) (digest.Digest, error) {
+	tocJSON, err := json.MarshalIndent(toc, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	gz, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+	gw := io.Writer(gz)
+	if diffHash != nil {
+		gw = io.MultiWriter(gz, diffHash)
+	}
+	tw := tar.NewWriter(gw)
+	err = tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     estargz.TOCTarName,
+		Size:     int64(len(tocJSON)),
+	})
+	if err != nil {
+		return "", err
+	}
+	_, err = tw.Write(tocJSON)
+	if err != nil {
+		return "", err
+	}
+	err = tw.Close()
+	if err != nil {
+		return "", err
+	}
+	err = gz.Close()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = w.Write(estargzFooter(off))
+	if err != nil {
+		return "", err
+	}
+	return digest.FromBytes(tocJSON), nil
+}
+
+// estargzFooter produces an empty gzip member whose extra field points to the TOC at tocOffset
+func estargzFooter(tocOffset int64) []byte {
+	subfield := fmt.Sprintf("%016xSTARGZ", tocOffset)
+
+	extra := make([]byte, 6, 6+len(subfield))
+	binary.LittleEndian.PutUint16(extra[0:2], uint16(4+len(subfield)))
+	extra[2], extra[3] = 'S', 'G'
+	binary.LittleEndian.PutUint16(extra[4:6], uint16(len(subfield)))
+	extra = append(extra, subfield...)
+
+	footer := make([]byte, 0, estargz.FooterSize)
+	// gzip header with FEXTRA set, no mtime, unknown OS
+	footer = append(footer, 0x1f, 0x8b, 0x08, 0x04, 0, 0, 0, 0, 0, 0xff)
+	footer = append(footer, extra...)
+	// a final, empty stored block followed by CRC32 and size of the (empty) content
+	footer = append(footer, 0x01, 0x00, 0x00, 0xff, 0xff)
+	footer = append(footer, 0, 0, 0, 0, 0, 0, 0, 0)
+	return footer
+}
+
+// estargzBaseLayerSource serves the converted versions of the layers of a base image
+type estargzBaseLayerSource struct {
+	Converter *EStargzConverter
+	// Layers are the original layers of the base image
+	Layers []ociv1.Descriptor
+}
+
+// HasBlob checks if a digest can be served by this blob source
+func (s *estargzBaseLayerSource) HasBlob(ctx context.Context, spec *api.ImageSpec, dgst digest.Digest) bool {
+	layer, ok := s.Converter.convertedLayer(dgst)
+	if !ok {
+		return false
+	}
+	for _, l := range s.Layers {
+		if l.Digest == layer.Source {
+			return true
+		}
+	}
+	return false
+}
+
+// GetBlob provides access to a blob. If a ReadCloser is returned the receiver is expected to
+// call close on it eventually.
+func (s *estargzBaseLayerSource) GetBlob(ctx context.Context, spec *api.ImageSpec, dgst digest.Digest) (mediaType string, url string, data io.ReadCloser, err error) {
+	if !s.HasBlob(ctx, spec, dgst) {
+		err = errdefs.ErrNotFound
+		return
+	}
+	return s.Converter.getBlob(ctx, dgst)
+}
+
+// EStargzLayerSource provides the layers of its delegate in the eStargz format. Small layers are
+// converted right away, larger ones are provided as is until their conversion is done.
+type EStargzLayerSource struct {
+	Delegate  LayerSource
+	Converter *EStargzConverter
+}
+
+// Envs returns the list of env modifiers
+func (s *EStargzLayerSource) Envs(ctx context.Context, spec *api.ImageSpec) ([]EnvModifier, error) {
+	return s.Delegate.Envs(ctx, spec)
+}
+
+// GetLayer returns the layers of the delegate, replacing those which were converted
+func (s *EStargzLayerSource) GetLayer(ctx context.Context, spec *api.ImageSpec) ([]AddonLayer, error) {
+	layers, err := s.Delegate.GetLayer(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]AddonLayer, len(layers))
+	for i, l := range layers {
+		res[i] = l
+		if !isEStargzConvertible(l.Descriptor) {
+			continue
+		}
+
+		src := l.Descriptor
+		open := func(ctx context.Context) (io.ReadCloser, error) {
+			_, _, rc, err := s.Delegate.GetBlob(ctx, spec, src.Digest)
+			if err != nil {
+				return nil, err
+			}
+			if rc == nil {
+				return nil, xerrors.Errorf("layer %s has no content", src.Digest)
+			}
+			return rc, nil
+		}
+		if layer, ok := s.Converter.lookup(src.Digest, open); ok {
+			res[i] = AddonLayer{Descriptor: layer.Descriptor(src.MediaType), DiffID: layer.DiffID}
+			continue
+		}
+
+		if src.Size > estargzSyncConvertSize {
+			s.Converter.ConvertAsync(src.Digest, open)
+			continue
+		}
+
+		layer, err := func() (*EStargzLayer, error) {
+			rc, err := open(ctx)
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return s.Converter.Convert(src.Digest, rc)
+		}()
+		if err != nil {
+			log.WithError(err).WithField("digest", src.Digest).Warn("cannot convert layer to eStargz - serving it as is")
+			continue
+		}
+		s.Converter.lookup(src.Digest, open)
+		res[i] = AddonLayer{Descriptor: layer.Descriptor(src.MediaType), DiffID: layer.DiffID}
+	}
+	return res, nil
+}
+
+// hasConverted returns true if dgst is a converted layer whose original is a layer of the spec
+func (s *EStargzLayerSource) hasConverted(ctx context.Context, spec *api.ImageSpec, dgst digest.Digest) bool {
+	layer, ok := s.Converter.convertedLayer(dgst)
+	return ok && s.Delegate.HasBlob(ctx, spec, layer.Source)
+}
+
+// HasBlob checks if a digest can be served by this blob source
+func (s *EStargzLayerSource) HasBlob(ctx context.Context, spec *api.ImageSpec, dgst digest.Digest) bool {
+	return s.hasConverted(ctx, spec, dgst) || s.Delegate.HasBlob(ctx, spec, dgst)
+}
+
+// GetBlob provides access to a blob. If a ReadCloser is returned the receiver is expected to
+// call close on it eventually.
+func (s *EStargzLayerSource) GetBlob(ctx context.Context, spec *api.ImageSpec, dgst digest.Digest) (mediaType string, url string, data io.ReadCloser, err error) {
+	if s.hasConverted(ctx, spec, dgst) {
+		return s.Converter.getBlob(ctx, dgst)
+	}
+	return s.Delegate.GetBlob(ctx, spec, dgst)
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/containerd/stargz-snapshotter/estargz"
+	"github.com/opencontainers/go-digest"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/gitpod-io/gitpod/registry-facade/api"
+	"github.com/gitpod-io/gitpod/registry-facade/api/config"
+)
+
+func TestEStargzConverter(t *testing.T) {
+	layer := testTarLayer(t, map[string]string{
+		"workspace/README.md": "hello world",
+		"workspace/main.go":   "package main",
+	})
+	src := digest.FromBytes(layer)
+
+	cache, err := NewDiskBlobCache(t.TempDir(), 10*1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv, err := NewEStargzConverter(&config.LazyPullConfig{Enabled: true, ConvertBaseLayers: true}, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = conv.Convert(digest.FromString("something else"), bytes.NewReader(layer))
+	if err == nil {
+		t.Fatal("expected layer with mismatching digest to be rejected")
+	}
+
+	converted, err := conv.Convert(src, bytes.NewReader(layer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted.Digest == src {
+		t.Fatal("expected converted layer to have a different digest")
+	}
+
+	_, _, rc, err := cache.Get(converted.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := estargz.Open(io.NewSectionReader(bytes.NewReader(blob), 0, int64(len(blob))))
+	if err != nil {
+		t.Fatalf("converted layer is no eStargz blob: %v", err)
+	}
+	if r.TOCDigest() != converted.TOCDigest {
+		t.Errorf("TOC digest mismatch: %s != %s", r.TOCDigest(), converted.TOCDigest)
+	}
+	if _, ok := r.Lookup("workspace/main.go"); !ok {
+		t.Error("expected converted layer to contain workspace/main.go")
+	}
+
+	desc := converted.Descriptor(ociv1.MediaTypeImageLayer)
+	if desc.Annotations[estargz.TOCJSONDigestAnnotation] != converted.TOCDigest.String() {
+		t.Errorf("descriptor lacks TOC digest annotation: %v", desc.Annotations)
+	}
+	base := &estargzBaseLayerSource{Converter: conv, Layers: []ociv1.Descriptor{{MediaType: ociv1.MediaTypeImageLayer, Digest: src}}}
+	if !base.HasBlob(context.Background(), nil, converted.Digest) {
+		t.Error("expected converted layer to be served for the image it was converted from")
+	}
+	other := &estargzBaseLayerSource{Converter: conv, Layers: []ociv1.Descriptor{{MediaType: ociv1.MediaTypeImageLayer, Digest: digest.FromString("other layer")}}}
+	if other.HasBlob(context.Background(), nil, converted.Digest) {
+		t.Error("expected converted layer not to be served for another image")
+	}
+
+	restored, err := NewEStargzConverter(&config.LazyPullConfig{Enabled: true, ConvertBaseLayers: true}, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := restored.Lookup(src); !ok {
+		t.Error("expected converted layer to survive restart")
+	}
+
+	manifest := &ociv1.Manifest{Layers: []ociv1.Descriptor{{MediaType: ociv1.MediaTypeImageLayer, Digest: src, Size: int64(len(layer))}}}
+	cfg := &ociv1.Image{RootFS: ociv1.RootFS{Type: "layers", DiffIDs: []digest.Digest{src}}}
+	restored.ConvertBaseLayers(manifest, cfg, nil)
+	if manifest.Layers[0].Digest != converted.Digest || cfg.RootFS.DiffIDs[0] != converted.DiffID {
+		t.Errorf("expected base layer to be replaced by its eStargz version: %v %v", manifest.Layers[0], cfg.RootFS.DiffIDs)
+	}
+}
+
+func TestEStargzLayerSource(t *testing.T) {
+	content := testTarLayer(t, map[string]string{"workspace/.gitpod.yml": "tasks: []"})
+	spec := &api.ImageSpec{
+		ContentLayer: []*api.ContentLayer{{Spec: &api.ContentLayer_Direct{Direct: &api.DirectContentLayer{Content: content}}}},
+	}
+
+	cache, err := NewDiskBlobCache(t.TempDir(), 10*1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conv, err := NewEStargzConverter(&config.LazyPullConfig{Enabled: true}, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delegate, err := NewContentLayerSource()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &EStargzLayerSource{Delegate: delegate, Converter: conv}
+
+	layers, err := src.GetLayer(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 1 {
+		t.Fatalf("expected one layer, got %d", len(layers))
+	}
+	l := layers[0]
+	if _, ok := l.Descriptor.Annotations[estargz.TOCJSONDigestAnnotation]; !ok {
+		t.Fatalf("expected content layer to carry TOC metadata: %v", l.Descriptor)
+	}
+	if l.DiffID == digest.FromBytes(content) {
+		t.Error("expected diffID of the converted layer")
+	}
+	if !src.HasBlob(context.Background(), spec, l.Descriptor.Digest) {
+		t.Error("expected converted content layer to be served")
+	}
+	if !src.HasBlob(context.Background(), spec, digest.FromBytes(content)) {
+		t.Error("expected original content layer to still be served")
+	}
+	otherSpec := &api.ImageSpec{
+		ContentLayer: []*api.ContentLayer{{Spec: &api.ContentLayer_Direct{Direct: &api.DirectContentLayer{Content: testTarLayer(t, map[string]string{"other": "content"})}}}},
+	}
+	if src.HasBlob(context.Background(), otherSpec, l.Descriptor.Digest) {
+		t.Error("expected converted content layer not to be served for another spec")
+	}
+
+	// a converted layer evicted from the cache is converted again
+	cache.mu.Lock()
+	maxSize := cache.MaxSize
+	cache.MaxSize = 0
+	cache.evict()
+	cache.MaxSize = maxSize
+	cache.mu.Unlock()
+	if cache.Has(l.Descriptor.Digest) {
+		t.Fatal("expected converted layer to be evicted")
+	}
+	if !src.HasBlob(context.Background(), spec, l.Descriptor.Digest) {
+		t.Fatal("expected evicted converted layer to still be served")
+	}
+	_, _, rc, err := src.GetBlob(context.Background(), spec, l.Descriptor.Digest)
+	if err != nil {
+		t.Fatalf("cannot get evicted converted layer: %v", err)
+	}
+	blob, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest.FromBytes(blob) != l.Descriptor.Digest {
+		t.Errorf("converted layer does not match its digest %s", l.Descriptor.Digest)
+	}
+}
+
+func testTarLayer(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for name, content := range files {
+		err := w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = w.Write([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -59,6 +59,8 @@ func (reg *Registry) handleManifest(ctx context.Context, r *http.Request) http.H
 		Store:            reg.Store,
 		ConfigModifier:   reg.ConfigModifier,
 		ManifestModifier: reg.ipfsManifestModifier,
+		Verifier:         reg.Verifier,
+		EStargz:          reg.EStargz,
 	}
 	reference := getReference(ctx)
 	dgst, err := digest.Parse(reference)
@@ -92,6 +94,8 @@ type manifestHandler struct {
 	Store            BlobStore
 	ConfigModifier   ConfigModifier
 	ManifestModifier func(*ociv1.Manifest) error
+	Verifier         *SignatureVerifier
+	EStargz          *EStargzConverter
 
 	Name   string
 	Tag    string
@@ -134,6 +138,14 @@ func (mh *manifestHandler) getManifest(w http.ResponseWriter, r *http.Request) {
 			return distv2.ErrorCodeManifestUnknown.WithMessage("Accept header does not include OCIv1 or v2 manifests")
 		}
 
+		// Blobs are not verified again: clients learn their digests from this manifest only.
+		// The verified refs are pinned to the digests which were verified, so that we serve exactly those.
+		spec, err := mh.Verifier.VerifySpec(ctx, mh.Spec)
+		if err != nil {
+			return err
+		}
+		mh.Spec = spec
+
 		// Note: we ignore the mh.Digest for now because we always return a manifest, never a manifest index.
 		ref := mh.Spec.BaseRef
 
@@ -175,6 +187,15 @@ func (mh *manifestHandler) getManifest(w http.ResponseWriter, r *http.Request) {
 				return err
 			}
 
+			// serve base layers which were converted for lazy pulling and convert the others in the background
+			mh.EStargz.ConvertBaseLayers(manifest, cfg, func(ctx context.Context, desc ociv1.Descriptor) (io.ReadCloser, error) {
+				fetcher, err := mh.Resolver.Fetcher(ctx, ref)
+				if err != nil {
+					return nil, err
+				}
+				return fetcher.Fetch(ctx, desc)
+			})
+
 			// modify config
 			addonLayer, err := mh.ConfigModifier(ctx, mh.Spec, cfg)
 			if err != nil {
@@ -196,20 +217,13 @@ func (mh *manifestHandler) getManifest(w http.ResponseWriter, r *http.Request) {
 			manifest.Config.URLs = nil
 			manifest.Config.Size = int64(len(rawCfg))
 
-			// optimization: we store the config in the store just in case the client attempts to download the config blob
-			// 				 from us. If they download it from a registry facade from which the manifest hasn't been downloaded
-			//               we'll re-create the config on the fly.
-			if w, err := mh.Store.Writer(ctx, content.WithRef(ref), content.WithDescriptor(manifest.Config)); err == nil {
-				defer w.Close()
-
-				_, err = w.Write(rawCfg)
-				if err != nil {
-					log.WithError(err).WithFields(logFields).Warn("cannot write config to store - we'll regenerate it on demand")
-				}
-				err = w.Commit(ctx, 0, cfgDgst, content.WithLabels(contentTypeLabel(manifest.Config.MediaType)))
-				if err != nil {
-					log.WithError(err).WithFields(logFields).Warn("cannot commit config to store - we'll regenerate it on demand")
-				}
+			// The config is served from the store only. It depends on the layers converted for lazy pulling and on
+			// the addon layers at the time of this request, hence producing it again later could yield a config
+			// which no longer matches this manifest.
+			err = storeBlob(ctx, mh.Store, manifest.Config, rawCfg)
+			if err != nil {
+				log.WithError(err).WithFields(logFields).Error("cannot place config in store")
+				return err
 			}
 
 			// We might have additional modifications, e.g. adding IPFS URLs to the layers
@@ -353,6 +367,42 @@ func WithStore(store BlobStore) ManifestDownloadOption {
 	}
 }
 
+// storeBlobRetries is how often storeBlob tries to write a blob which is being written by someone else already
+const storeBlobRetries = 10
+
+// storeBlob places a blob in the store unless it is present already
+func storeBlob(ctx context.Context, store BlobStore, desc ociv1.Descriptor, data []byte) error {
+	if _, err := store.Info(ctx, desc.Digest); err == nil {
+		return nil
+	}
+
+	for i := 0; ; i++ {
+		w, err := store.Writer(ctx, content.WithRef(desc.Digest.String()), content.WithDescriptor(desc))
+		if errdefs.IsAlreadyExists(err) {
+			return nil
+		}
+		if errdefs.IsUnavailable(err) && i < storeBlobRetries {
+			// someone else writes the same blob right now
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+
+		_, err = w.Write(data)
+		if err != nil {
+			return err
+		}
+		err = w.Commit(ctx, int64(len(data)), desc.Digest, content.WithLabels(contentTypeLabel(desc.MediaType)))
+		if errdefs.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+}
+
 type BlobStore interface {
 	ReaderAt(ctx context.Context, desc ociv1.Descriptor) (content.ReaderAt, error)
 
@@ -55,6 +55,7 @@ type metrics struct {
 	ReqFailedCounter      *prometheus.CounterVec
 	BlobCounter           prometheus.Counter
 	BlobDownloadSpeedHist prometheus.Histogram
+	BlobCacheCounter      *prometheus.CounterVec
 }
 
 func newMetrics(reg prometheus.Registerer, upstream bool) (*metrics, error) {
@@ -93,11 +94,19 @@ func newMetrics(reg prometheus.Registerer, upstream bool) (*metrics, error) {
 		Help:    "blob download speed in bytes per second",
 		Buckets: prometheus.ExponentialBuckets(1024*1024, 2, 10),
 	})
+	blobCacheCounter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blob_cache_total",
+		Help: "number of blobs served by origin (local cache, peer cache or upstream)",
+	}, []string{"origin"})
 	if upstream {
 		err = reg.Register(blobDownloadSpeedHist)
 		if err != nil {
 			return nil, err
 		}
+		err = reg.Register(blobCacheCounter)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &metrics{
@@ -105,5 +114,6 @@ func newMetrics(reg prometheus.Registerer, upstream bool) (*metrics, error) {
 		ReqFailedCounter:      reqFailedCounter,
 		BlobCounter:           blobCounter,
 		BlobDownloadSpeedHist: blobDownloadSpeedHist,
+		BlobCacheCounter:      blobCacheCounter,
 	}, nil
 }
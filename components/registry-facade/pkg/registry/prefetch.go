@@ -0,0 +1,142 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+
+	"github.com/opencontainers/go-digest"
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	common_grpc "github.com/gitpod-io/gitpod/common-go/grpc"
+	"github.com/gitpod-io/gitpod/common-go/log"
+	"github.com/gitpod-io/gitpod/registry-facade/api"
+)
+
+// PrefetchImage downloads all manifests, configs and layers of the images referenced by a spec
+// into the manifest store and the node-local blob cache.
+func (reg *Registry) PrefetchImage(ctx context.Context, req *api.PrefetchImageRequest) (*api.PrefetchImageResponse, error) {
+	spec := req.Spec
+	if spec == nil || spec.BaseRef == "" {
+		return nil, status.Error(codes.InvalidArgument, "spec.baseRef is required")
+	}
+	if reg.BlobCache == nil {
+		return nil, status.Error(codes.FailedPrecondition, "prefetching requires the blob cache")
+	}
+
+	spec, err := reg.Verifier.VerifySpec(ctx, spec)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	var resp api.PrefetchImageResponse
+	for _, ref := range []string{spec.BaseRef, spec.IdeRef, spec.DesktopIdeRef, spec.SupervisorRef} {
+		if ref == "" {
+			continue
+		}
+
+		blobs, size, err := reg.prefetchRef(ctx, ref)
+		if err != nil {
+			log.WithError(err).WithField("ref", ref).Warn("cannot prefetch image")
+			return nil, status.Errorf(codes.Unavailable, "cannot prefetch %s: %v", ref, err)
+		}
+		resp.Blobs += int32(blobs)
+		resp.Bytes += size
+	}
+
+	log.WithField("spec", spec).WithField("blobs", resp.Blobs).WithField("bytes", resp.Bytes).Debug("prefetched image")
+	return &resp, nil
+}
+
+func (reg *Registry) prefetchRef(ctx context.Context, ref string) (blobs int, size int64, err error) {
+	resolver := reg.Resolver()
+	_, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return 0, 0, xerrors.Errorf("cannot resolve ref: %w", err)
+	}
+	fetcher, err := resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return 0, 0, xerrors.Errorf("cannot get fetcher: %w", err)
+	}
+
+	manifest, _, err := DownloadManifest(ctx, AsFetcherFunc(fetcher), desc, WithStore(reg.Store))
+	if err != nil {
+		return 0, 0, xerrors.Errorf("cannot download manifest: %w", err)
+	}
+	_, err = DownloadConfig(ctx, AsFetcherFunc(fetcher), ref, manifest.Config, WithStore(reg.Store))
+	if err != nil {
+		return 0, 0, xerrors.Errorf("cannot download config: %w", err)
+	}
+
+	for _, layer := range manifest.Layers {
+		if reg.BlobCache.Has(layer.Digest) {
+			continue
+		}
+
+		var rc io.ReadCloser
+		if reg.BlobCachePeers != nil {
+			_, rc, err = reg.BlobCachePeers.Fetch(ctx, layer.Digest)
+			if err != nil {
+				rc = nil
+			}
+		}
+		if rc == nil {
+			rc, err = fetcher.Fetch(ctx, layer)
+			if err != nil {
+				return blobs, size, xerrors.Errorf("cannot fetch layer %s: %w", layer.Digest, err)
+			}
+		}
+
+		err = reg.storePrefetchedBlob(layer.Digest, layer.MediaType, rc)
+		if err != nil {
+			return blobs, size, xerrors.Errorf("cannot cache layer %s: %w", layer.Digest, err)
+		}
+		blobs++
+		size += layer.Size
+	}
+
+	return blobs, size, nil
+}
+
+func (reg *Registry) storePrefetchedBlob(dgst digest.Digest, mediaType string, rc io.ReadCloser) error {
+	defer rc.Close()
+	return reg.BlobCache.Store(dgst, mediaType, rc)
+}
+
+// servePrefetcher serves the Prefetcher gRPC service
+func (reg *Registry) servePrefetcher() error {
+	cfg := reg.Config.Prefetch
+
+	if cfg.TLS == nil {
+		return xerrors.Errorf("prefetch requires TLS")
+	}
+	tlsConfig, err := common_grpc.ClientAuthTLSConfig(
+		cfg.TLS.Authority, cfg.TLS.Certificate, cfg.TLS.PrivateKey,
+		common_grpc.WithClientAuth(tls.RequireAndVerifyClientCert),
+		common_grpc.WithSetClientCAs(true),
+	)
+	if err != nil {
+		return xerrors.Errorf("cannot load prefetch certs: %w", err)
+	}
+	grpcOpts := append(common_grpc.DefaultServerOptions(), grpc.Creds(credentials.NewTLS(tlsConfig)))
+
+	l, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+
+	srv := grpc.NewServer(grpcOpts...)
+	api.RegisterPrefetcherServer(srv, reg)
+
+	log.WithField("addr", cfg.Addr).Info("prefetch gRPC server listening")
+	return srv.Serve(l)
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/containerd/containerd/content/local"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/gitpod-io/gitpod/registry-facade/api"
+)
+
+func TestPrefetchImage(t *testing.T) {
+	const ref = "docker.io/gitpod/workspace-full:latest"
+
+	var (
+		layer    = []byte("layer content")
+		rawCfg   = mustMarshal(t, ociv1.Image{RootFS: ociv1.RootFS{Type: "layers", DiffIDs: []digest.Digest{digest.FromBytes(layer)}}})
+		layerDsc = ociv1.Descriptor{MediaType: ociv1.MediaTypeImageLayerGzip, Digest: digest.FromBytes(layer), Size: int64(len(layer))}
+		cfgDesc  = ociv1.Descriptor{MediaType: ociv1.MediaTypeImageConfig, Digest: digest.FromBytes(rawCfg), Size: int64(len(rawCfg))}
+		rawMF    = mustMarshal(t, ociv1.Manifest{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			MediaType: ociv1.MediaTypeImageManifest,
+			Config:    cfgDesc,
+			Layers:    []ociv1.Descriptor{layerDsc},
+		})
+		mfDesc = ociv1.Descriptor{MediaType: ociv1.MediaTypeImageManifest, Digest: digest.FromBytes(rawMF), Size: int64(len(rawMF))}
+	)
+	content := map[string][]byte{
+		ref:                       mustMarshal(t, mfDesc),
+		mfDesc.Digest.Encoded():   rawMF,
+		cfgDesc.Digest.Encoded():  rawCfg,
+		layerDsc.Digest.Encoded(): layer,
+	}
+
+	store, err := local.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewDiskBlobCache(t.TempDir(), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := &Registry{
+		Resolver:  func() remotes.Resolver { return &fakeFetcher{Content: content} },
+		Store:     store,
+		BlobCache: cache,
+	}
+
+	req := &api.PrefetchImageRequest{Spec: &api.ImageSpec{BaseRef: ref}}
+	resp, err := reg.PrefetchImage(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Blobs != 1 || resp.Bytes != layerDsc.Size {
+		t.Errorf("unexpected prefetch result: %d blobs, %d bytes", resp.Blobs, resp.Bytes)
+	}
+	if !cache.Has(layerDsc.Digest) {
+		t.Error("expected layer to be cached")
+	}
+	if _, err := store.Info(context.Background(), mfDesc.Digest); err != nil {
+		t.Errorf("expected manifest to be stored: %v", err)
+	}
+
+	resp, err = reg.PrefetchImage(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Blobs != 0 {
+		t.Errorf("expected cached blobs not to be downloaded again, got %d", resp.Blobs)
+	}
+
+	_, err = reg.PrefetchImage(context.Background(), &api.PrefetchImageRequest{Spec: &api.ImageSpec{}})
+	if err == nil {
+		t.Error("expected spec without base ref to be rejected")
+	}
+}
@@ -74,10 +74,16 @@ type Registry struct {
 	LayerSource    LayerSource
 	ConfigModifier ConfigModifier
 	SpecProvider   map[string]ImageSpecProvider
+	Verifier       *SignatureVerifier
+	BlobCache      *DiskBlobCache
+	BlobCachePeers *BlobCachePeers
+	EStargz        *EStargzConverter
 
 	staticLayerSource *RevisioningLayerSource
 	metrics           *metrics
 	srv               *http.Server
+
+	api.UnimplementedPrefetcherServer
 }
 
 // NewRegistry creates a new registry
@@ -254,13 +260,56 @@ func NewRegistry(cfg config.Config, newResolver ResolverProvider, reg prometheus
 		log.WithField("config", cfg.IPFSCache).Info("enabling IPFS caching")
 	}
 
-	layerSource := CompositeLayerSource(layerSources)
+	var verifier *SignatureVerifier
+	if cfg.SignatureVerification != nil && cfg.SignatureVerification.Enabled {
+		verifier, err = NewSignatureVerifier(cfg.SignatureVerification, newResolver)
+		if err != nil {
+			return nil, xerrors.Errorf("cannot create signature verifier: %w", err)
+		}
+		log.WithField("config", cfg.SignatureVerification).Info("enabling image signature verification")
+	}
+
+	var (
+		blobCache *DiskBlobCache
+		peers     *BlobCachePeers
+	)
+	if cfg.BlobCache != nil && cfg.BlobCache.Enabled {
+		blobCache, err = NewDiskBlobCache(cfg.BlobCache.Path, cfg.BlobCache.MaxSize)
+		if err != nil {
+			return nil, xerrors.Errorf("cannot create blob cache: %w", err)
+		}
+		if cfg.BlobCache.Peers != nil {
+			peers, err = NewBlobCachePeers(cfg.BlobCache.Peers)
+			if err != nil {
+				return nil, err
+			}
+		}
+		log.WithField("config", cfg.BlobCache).Info("enabling node-local blob cache")
+	}
+
+	var (
+		estargzConverter *EStargzConverter
+		layerSource      LayerSource = CompositeLayerSource(layerSources)
+	)
+	if cfg.LazyPull != nil && cfg.LazyPull.Enabled {
+		estargzConverter, err = NewEStargzConverter(cfg.LazyPull, blobCache)
+		if err != nil {
+			return nil, xerrors.Errorf("cannot create eStargz converter: %w", err)
+		}
+		layerSource = &EStargzLayerSource{Delegate: layerSource, Converter: estargzConverter}
+		log.WithField("config", cfg.LazyPull).Info("serving layers in eStargz format for lazy pulling")
+	}
+
 	return &Registry{
 		Config:            cfg,
 		Resolver:          newResolver,
 		Store:             mfStore,
 		IPFS:              ipfs,
 		SpecProvider:      specProvider,
+		Verifier:          verifier,
+		BlobCache:         blobCache,
+		BlobCachePeers:    peers,
+		EStargz:           estargzConverter,
 		LayerSource:       layerSource,
 		staticLayerSource: staticLayer,
 		ConfigModifier:    NewConfigModifierFromLayerSource(layerSource),
@@ -353,6 +402,25 @@ func (reg *Registry) Serve() error {
 		}()
 	}
 
+	if reg.BlobCachePeers != nil {
+		go reg.BlobCachePeers.Run(context.Background())
+		go func() {
+			err := ServeBlobCacheToPeers(reg.BlobCache, reg.Config.BlobCache.Peers)
+			if err != nil {
+				log.WithError(err).Error("start of blob cache peer server failed")
+			}
+		}()
+	}
+
+	if reg.Config.Prefetch != nil && reg.Config.Prefetch.Enabled {
+		go func() {
+			err := reg.servePrefetcher()
+			if err != nil {
+				log.WithError(err).Error("start of prefetch server failed")
+			}
+		}()
+	}
+
 	addr := fmt.Sprintf(":%d", reg.Config.Port)
 	l, err := net.Listen("tcp", addr)
 	if err != nil {
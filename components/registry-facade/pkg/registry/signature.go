@@ -0,0 +1,306 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package registry
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/docker/distribution/reference"
+	"github.com/golang/protobuf/proto"
+	distv2 "github.com/docker/distribution/registry/api/v2"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/opencontainers/go-digest"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+	"github.com/gitpod-io/gitpod/registry-facade/api"
+	"github.com/gitpod-io/gitpod/registry-facade/api/config"
+)
+
+const (
+	// MediaTypeCosignSimpleSigning is the media type of cosign signature payload layers
+	MediaTypeCosignSimpleSigning = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// AnnotationCosignSignature is the layer annotation which holds the base64 encoded signature
+	AnnotationCosignSignature = "dev.cosignproject.cosign/signature"
+
+	// cosignSignatureType is the critical type cosign places in its simple signing payloads
+	cosignSignatureType = "cosign container image signature"
+
+	// errMsgSignatureVerification is part of every verification error served to clients.
+	// ws-manager looks for this phrase to produce a meaningful workspace failure.
+	errMsgSignatureVerification = "failed signature verification"
+)
+
+// defaultSignatureRefs are the refs verified if none are configured. Only the workspace image
+// is signed by image-builder, IDE and supervisor images are not.
+var defaultSignatureRefs = map[string]bool{"base": true}
+
+// SimpleSigningPayload is the payload cosign signs for container images
+type SimpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+// SignatureTag returns the tag under which cosign stores the signature of a manifest with the given digest
+func SignatureTag(dgst digest.Digest) string {
+	return fmt.Sprintf("%s-%s.sig", dgst.Algorithm(), dgst.Encoded())
+}
+
+// SignatureVerifier verifies cosign-compatible signatures of the images referenced in an image spec
+type SignatureVerifier struct {
+	Resolver ResolverProvider
+	Keys     []crypto.PublicKey
+	Refs     map[string]bool
+
+	verified *lru.Cache
+}
+
+// NewSignatureVerifier produces a new signature verifier from configuration
+func NewSignatureVerifier(cfg *config.SignatureVerificationConfig, resolver ResolverProvider) (*SignatureVerifier, error) {
+	if len(cfg.PublicKeys) == 0 {
+		return nil, xerrors.Errorf("signature verification requires at least one public key")
+	}
+
+	var keys []crypto.PublicKey
+	for _, fn := range cfg.PublicKeys {
+		fc, err := os.ReadFile(fn)
+		if err != nil {
+			return nil, xerrors.Errorf("cannot read public key %s: %w", fn, err)
+		}
+		key, err := ParsePublicKey(fc)
+		if err != nil {
+			return nil, xerrors.Errorf("cannot parse public key %s: %w", fn, err)
+		}
+		keys = append(keys, key)
+	}
+
+	refs := make(map[string]bool)
+	for _, r := range cfg.Refs {
+		switch r {
+		case "base", "ide", "desktopIde", "supervisor":
+			refs[r] = true
+		default:
+			return nil, xerrors.Errorf("unknown ref for signature verification: %s", r)
+		}
+	}
+
+	if len(refs) == 0 {
+		refs = defaultSignatureRefs
+	}
+
+	verified, err := lru.New(1024)
+	if err != nil {
+		return nil, err
+	}
+	return &SignatureVerifier{
+		Resolver: resolver,
+		Keys:     keys,
+		Refs:     refs,
+		verified: verified,
+	}, nil
+}
+
+// ParsePublicKey parses a PEM encoded ECDSA, RSA or ed25519 public key
+func ParsePublicKey(pemBytes []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, xerrors.Errorf("no PEM data found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, xerrors.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// VerifySpec verifies the signatures of all configured refs of an image spec. It returns a copy of the spec
+// in which the verified refs are pinned to the digest that was verified, so that a tag which is moved
+// after verification cannot be served. The returned error can be served to registry clients as is.
+func (sv *SignatureVerifier) VerifySpec(ctx context.Context, spec *api.ImageSpec) (*api.ImageSpec, error) {
+	if sv == nil {
+		return spec, nil
+	}
+	spec = proto.Clone(spec).(*api.ImageSpec)
+
+	refs := []struct {
+		Name string
+		Ref  *string
+	}{
+		{"base", &spec.BaseRef},
+		{"ide", &spec.IdeRef},
+		{"desktopIde", &spec.DesktopIdeRef},
+		{"supervisor", &spec.SupervisorRef},
+	}
+	verifiedRefs := sv.Refs
+	if len(verifiedRefs) == 0 {
+		verifiedRefs = defaultSignatureRefs
+	}
+	for _, r := range refs {
+		if *r.Ref == "" || !verifiedRefs[r.Name] {
+			continue
+		}
+
+		pinned, err := sv.VerifyRef(ctx, *r.Ref)
+		if err != nil {
+			log.WithError(err).WithField("ref", *r.Ref).WithField("refName", r.Name).Warn("refusing to serve unsigned image")
+			return nil, distv2.ErrorCodeManifestUnverified.WithMessage(fmt.Sprintf("%s image %s %s: %v", r.Name, *r.Ref, errMsgSignatureVerification, err))
+		}
+		*r.Ref = pinned
+	}
+	return spec, nil
+}
+
+// VerifyRef resolves a ref to a digest once and verifies that the manifest with that digest was signed by one
+// of the configured keys. It returns the ref pinned to the verified digest, which must be used from then on.
+// Successful verifications are cached by digest.
+func (sv *SignatureVerifier) VerifyRef(ctx context.Context, ref string) (pinned string, err error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", xerrors.Errorf("cannot parse ref: %w", err)
+	}
+
+	resolver := sv.Resolver()
+	var dgst digest.Digest
+	if digested, ok := named.(reference.Digested); ok {
+		dgst = digested.Digest()
+	} else {
+		_, desc, err := resolver.Resolve(ctx, ref)
+		if err != nil {
+			return "", xerrors.Errorf("cannot resolve image: %w", err)
+		}
+		dgst = desc.Digest
+	}
+	pinnedRef, err := reference.WithDigest(reference.TrimNamed(named), dgst)
+	if err != nil {
+		return "", xerrors.Errorf("cannot pin ref to digest: %w", err)
+	}
+	if _, ok := sv.verified.Get(dgst); ok {
+		return pinnedRef.String(), nil
+	}
+
+	sigRef, err := reference.WithTag(reference.TrimNamed(named), SignatureTag(dgst))
+	if err != nil {
+		return "", xerrors.Errorf("cannot produce signature ref: %w", err)
+	}
+
+	_, sigDesc, err := resolver.Resolve(ctx, sigRef.String())
+	if err != nil {
+		return "", xerrors.Errorf("no signature found: %w", err)
+	}
+	fetcher, err := resolver.Fetcher(ctx, sigRef.String())
+	if err != nil {
+		return "", xerrors.Errorf("cannot fetch signature: %w", err)
+	}
+	sigManifest, _, err := DownloadManifest(ctx, AsFetcherFunc(fetcher), sigDesc)
+	if err != nil {
+		return "", xerrors.Errorf("cannot download signature manifest: %w", err)
+	}
+
+	for _, layer := range sigManifest.Layers {
+		if layer.MediaType != MediaTypeCosignSimpleSigning {
+			continue
+		}
+		sig, ok := layer.Annotations[AnnotationCosignSignature]
+		if !ok {
+			continue
+		}
+
+		payload, err := fetchPayload(ctx, fetcher, layer)
+		if err != nil {
+			log.WithError(err).WithField("ref", sigRef.String()).Debug("cannot fetch signature payload")
+			continue
+		}
+		err = verifyPayload(sv.Keys, payload, sig, dgst)
+		if err != nil {
+			log.WithError(err).WithField("ref", sigRef.String()).Debug("signature does not verify")
+			continue
+		}
+
+		sv.verified.Add(dgst, struct{}{})
+		return pinnedRef.String(), nil
+	}
+
+	return "", xerrors.Errorf("no valid signature for %s", dgst)
+}
+
+func fetchPayload(ctx context.Context, fetcher remotes.Fetcher, desc ociv1.Descriptor) ([]byte, error) {
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(rc, 1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	if digest.FromBytes(payload) != desc.Digest {
+		return nil, xerrors.Errorf("payload digest mismatch")
+	}
+	return payload, nil
+}
+
+func verifyPayload(keys []crypto.PublicKey, payload []byte, b64sig string, dgst digest.Digest) error {
+	sig, err := base64.StdEncoding.DecodeString(b64sig)
+	if err != nil {
+		return xerrors.Errorf("cannot decode signature: %w", err)
+	}
+
+	var p SimpleSigningPayload
+	err = json.Unmarshal(payload, &p)
+	if err != nil {
+		return xerrors.Errorf("cannot unmarshal payload: %w", err)
+	}
+	if p.Critical.Type != cosignSignatureType {
+		return xerrors.Errorf("unexpected payload type: %s", p.Critical.Type)
+	}
+	if p.Critical.Image.DockerManifestDigest != dgst.String() {
+		return xerrors.Errorf("payload is for %s, not %s", p.Critical.Image.DockerManifestDigest, dgst)
+	}
+
+	hash := sha256.Sum256(payload)
+	for _, key := range keys {
+		var ok bool
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			ok = ecdsa.VerifyASN1(k, hash[:], sig)
+		case *rsa.PublicKey:
+			ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], sig) == nil
+		case ed25519.PublicKey:
+			ok = ed25519.Verify(k, payload, sig)
+		}
+		if ok {
+			return nil
+		}
+	}
+	return xerrors.Errorf("signature does not match any key")
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package registry
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/containerd/containerd/remotes"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ociv1 "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/gitpod-io/gitpod/registry-facade/api"
+)
+
+func TestSignatureVerifier(t *testing.T) {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const ref = "docker.io/gitpod/workspace-full:latest"
+	mfDesc := ociv1.Descriptor{
+		MediaType: ociv1.MediaTypeImageManifest,
+		Digest:    digest.FromString("workspace-full"),
+	}
+
+	tests := []struct {
+		Name          string
+		Sign          bool
+		SignedDigest  digest.Digest
+		VerifyKey     crypto.PublicKey
+		ExpectFailure bool
+	}{
+		{Name: "valid signature", Sign: true, SignedDigest: mfDesc.Digest, VerifyKey: &signingKey.PublicKey},
+		{Name: "no signature", VerifyKey: &signingKey.PublicKey, ExpectFailure: true},
+		{Name: "wrong key", Sign: true, SignedDigest: mfDesc.Digest, VerifyKey: &otherKey.PublicKey, ExpectFailure: true},
+		{Name: "wrong digest", Sign: true, SignedDigest: digest.FromString("something else"), VerifyKey: &signingKey.PublicKey, ExpectFailure: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			content := map[string][]byte{
+				ref: mustMarshal(t, mfDesc),
+			}
+			if test.Sign {
+				var payload SimpleSigningPayload
+				payload.Critical.Identity.DockerReference = "docker.io/gitpod/workspace-full"
+				payload.Critical.Image.DockerManifestDigest = test.SignedDigest.String()
+				payload.Critical.Type = cosignSignatureType
+				rawPayload := mustMarshal(t, payload)
+
+				hash := sha256.Sum256(rawPayload)
+				sig, err := ecdsa.SignASN1(rand.Reader, signingKey, hash[:])
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				payloadDesc := ociv1.Descriptor{
+					MediaType: MediaTypeCosignSimpleSigning,
+					Digest:    digest.FromBytes(rawPayload),
+					Size:      int64(len(rawPayload)),
+					Annotations: map[string]string{
+						AnnotationCosignSignature: base64.StdEncoding.EncodeToString(sig),
+					},
+				}
+				sigMF := mustMarshal(t, ociv1.Manifest{
+					Versioned: specs.Versioned{SchemaVersion: 2},
+					MediaType: ociv1.MediaTypeImageManifest,
+					Layers:    []ociv1.Descriptor{payloadDesc},
+				})
+				sigMFDesc := ociv1.Descriptor{
+					MediaType: ociv1.MediaTypeImageManifest,
+					Digest:    digest.FromBytes(sigMF),
+					Size:      int64(len(sigMF)),
+				}
+
+				content["docker.io/gitpod/workspace-full:"+SignatureTag(mfDesc.Digest)] = mustMarshal(t, sigMFDesc)
+				content[sigMFDesc.Digest.Encoded()] = sigMF
+				content[payloadDesc.Digest.Encoded()] = rawPayload
+			}
+
+			verified, _ := lru.New(10)
+			verifier := &SignatureVerifier{
+				Resolver: func() remotes.Resolver { return &fakeFetcher{Content: content} },
+				Keys:     []crypto.PublicKey{test.VerifyKey},
+				verified: verified,
+			}
+
+			// the IDE image is not signed and must not be verified by default
+			spec := &api.ImageSpec{BaseRef: ref, IdeRef: "docker.io/gitpod/openvscode-server:latest"}
+			pinned, err := verifier.VerifySpec(context.Background(), spec)
+			if test.ExpectFailure && err == nil {
+				t.Fatal("expected verification to fail")
+			}
+			if !test.ExpectFailure && err != nil {
+				t.Fatalf("unexpected verification failure: %v", err)
+			}
+			if test.ExpectFailure {
+				return
+			}
+
+			// the verified digest is served, not whatever the tag points to later
+			expectedRef := "docker.io/gitpod/workspace-full@" + mfDesc.Digest.String()
+			if pinned.BaseRef != expectedRef {
+				t.Errorf("unexpected base ref: want %s, got %s", expectedRef, pinned.BaseRef)
+			}
+			if pinned.IdeRef != spec.IdeRef {
+				t.Errorf("unexpected IDE ref: want %s, got %s", spec.IdeRef, pinned.IdeRef)
+			}
+			if spec.BaseRef != ref {
+				t.Errorf("spec was modified: %s", spec.BaseRef)
+			}
+
+			// a verified digest is not verified again
+			delete(content, "docker.io/gitpod/workspace-full:"+SignatureTag(mfDesc.Digest))
+			_, err = verifier.VerifySpec(context.Background(), spec)
+			if err != nil {
+				t.Fatalf("unexpected verification failure of a previously verified digest: %v", err)
+			}
+
+			// moving the tag to an unsigned manifest fails verification
+			content[ref] = mustMarshal(t, ociv1.Descriptor{MediaType: ociv1.MediaTypeImageManifest, Digest: digest.FromString("unsigned")})
+			_, err = verifier.VerifySpec(context.Background(), spec)
+			if err == nil {
+				t.Error("expected verification of a moved tag to fail")
+			}
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, obj interface{}) []byte {
+	res, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
@@ -28,9 +28,13 @@ const localClient: OAuthClient = {
     allowedGrants: ["authorization_code"],
     scopes: [
         { name: "function:getGitpodTokenScopes" },
+        { name: "function:getOwnerToken" },
         { name: "function:getWorkspace" },
         { name: "function:getWorkspaces" },
         { name: "function:listenForWorkspaceInstanceUpdates" },
+        { name: "function:createWorkspace" },
+        { name: "function:startWorkspace" },
+        { name: "function:stopWorkspace" },
         { name: "resource:default" },
     ],
 };
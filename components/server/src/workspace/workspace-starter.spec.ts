@@ -4,10 +4,10 @@
  * See License-AGPL.txt in the project root for license information.
  */
 
-import { User } from "@gitpod/gitpod-protocol";
+import { PortConfig, User } from "@gitpod/gitpod-protocol";
 import { IDEOption, IDEOptions } from "@gitpod/gitpod-protocol/lib/ide-protocol";
 import * as chai from "chai";
-import { migrationIDESettings, chooseIDE } from "./workspace-starter";
+import { migrationIDESettings, chooseIDE, createPortProtection, createCustomDomains } from "./workspace-starter";
 const expect = chai.expect;
 
 describe("workspace-starter", function () {
@@ -264,4 +264,58 @@ describe("workspace-starter", function () {
             expect(result.ideImage).to.equal(ideOptions.options["code"].latestImage);
         });
     });
+    describe("createPortProtection", function () {
+        it("should not protect private ports", function () {
+            const ports: PortConfig[] = [{ port: 3000, visibility: "private", protection: { password: "secret" } }];
+            expect(createPortProtection(ports)).to.be.undefined;
+        });
+
+        it("should hash passwords", function () {
+            const ports: PortConfig[] = [{ port: 3000, visibility: "public", protection: { password: "secret" } }];
+            const result = JSON.parse(createPortProtection(ports)!);
+            expect(result).to.have.length(1);
+            expect(result[0].port).to.equal(3000);
+            expect(result[0].password).to.be.undefined;
+            expect(result[0].passwordHash).to.match(/^scrypt:[0-9a-f]{32}:[0-9a-f]{64}$/);
+            expect(JSON.stringify(result)).not.to.contain("secret");
+        });
+
+        it("should expand port ranges to numeric ports", function () {
+            const ports = [
+                { port: "3000-3002", visibility: "public", protection: { allowedCIDRs: ["10.0.0.0/8"] } },
+                { port: 3001, visibility: "public", protection: { allowedCIDRs: ["192.168.0.0/16"] } },
+            ] as any as PortConfig[];
+            const result = JSON.parse(createPortProtection(ports)!);
+            expect(result.map((p: any) => p.port)).to.have.members([3000, 3001, 3002]);
+            for (const p of result) {
+                expect(p.port).to.be.a("number");
+                // the protection of a single port takes precedence over that of its range
+                expect(p.allowedCIDRs).to.deep.equal(p.port === 3001 ? ["192.168.0.0/16"] : ["10.0.0.0/8"]);
+            }
+        });
+    });
+    describe("createCustomDomains", function () {
+        it("should only map public ports", function () {
+            const ports: PortConfig[] = [
+                { port: 3000, visibility: "private", customDomain: "private.example.com" },
+                { port: 3001, visibility: "public", customDomain: "app.example.com" },
+            ];
+            expect(JSON.parse(createCustomDomains(ports)!)).to.deep.equal([{ domain: "app.example.com", port: 3001 }]);
+        });
+
+        it("should ignore port ranges", function () {
+            const ports = [
+                { port: "3000-3002", visibility: "public", customDomain: "range.example.com" },
+                { port: 8080, visibility: "public", customDomain: "app.example.com" },
+            ] as any as PortConfig[];
+            expect(JSON.parse(createCustomDomains(ports)!)).to.deep.equal([{ domain: "app.example.com", port: 8080 }]);
+        });
+
+        it("should return undefined without custom domains", function () {
+            const ports = [
+                { port: "3000-3002", visibility: "public", customDomain: "range.example.com" },
+            ] as any as PortConfig[];
+            expect(createCustomDomains(ports)).to.be.undefined;
+        });
+    });
 });
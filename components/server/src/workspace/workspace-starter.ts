@@ -57,6 +57,7 @@ import {
     ProjectEnvVar,
     ImageBuildLogInfo,
     IDESettings,
+    PortConfig,
 } from "@gitpod/gitpod-protocol";
 import { IAnalyticsWriter } from "@gitpod/gitpod-protocol/lib/analytics";
 import { log } from "@gitpod/gitpod-protocol/lib/util/logging";
@@ -179,6 +180,75 @@ export const chooseIDE = (
     return data;
 };
 
+/**
+ * Returns the ports a port config covers. Port ranges such as "3000-3999" are expanded to all of their ports.
+ */
+export const expandPortConfig = (port: number | string): number[] => {
+    if (typeof port === "number") {
+        return [port];
+    }
+    const match = /^(\d+)-(\d+)$/.exec(port.trim());
+    if (!match) {
+        return [];
+    }
+    const ports: number[] = [];
+    for (let p = parseInt(match[1], 10); p <= parseInt(match[2], 10) && p <= 65535; p++) {
+        ports.push(p);
+    }
+    return ports;
+};
+
+/**
+ * Hashes the password of a protected port, so that it is not stored in the workspace pod in plaintext.
+ * ws-proxy verifies passwords against the hash.
+ */
+export const hashPortPassword = (password: string): string => {
+    const salt = crypto.randomBytes(16);
+    const hash = crypto.scryptSync(password, salt, 32, { N: 16384, r: 8, p: 1 });
+    return `scrypt:${salt.toString("hex")}:${hash.toString("hex")}`;
+};
+
+/**
+ * Serializes the protection of all public ports, or returns undefined if no port is protected.
+ * Port ranges are expanded, the protection of a single port takes precedence over that of a range it is part of.
+ */
+export const createPortProtection = (ports: PortConfig[]): string | undefined => {
+    const protectedPorts = ports.filter((p) => p.visibility === "public" && !!p.protection);
+    const isRange = (p: PortConfig) => typeof p.port !== "number";
+    const protections = new Map<number, object>();
+    for (const p of [...protectedPorts.filter(isRange), ...protectedPorts.filter((p) => !isRange(p))]) {
+        const passwordHash = p.protection!.password ? hashPortPassword(p.protection!.password) : undefined;
+        for (const port of expandPortConfig(p.port)) {
+            protections.set(port, {
+                port,
+                passwordHash,
+                allowedCIDRs: p.protection!.allowedCIDRs,
+                oidc: p.protection!.oidc,
+            });
+        }
+    }
+    if (protections.size === 0) {
+        return undefined;
+    }
+    return JSON.stringify(Array.from(protections.values()));
+};
+
+/**
+ * Serializes the custom domains of public ports. A domain maps to a single port, hence port ranges are ignored.
+ */
+export const createCustomDomains = (ports: PortConfig[]): string | undefined => {
+    const domains = ports
+        .filter((p) => p.visibility === "public" && !!p.customDomain && typeof p.port === "number")
+        .map((p) => ({
+            domain: p.customDomain!,
+            port: p.port,
+        }));
+    if (domains.length === 0) {
+        return undefined;
+    }
+    return JSON.stringify(domains);
+};
+
 @injectable()
 export class WorkspaceStarter {
     @inject(WorkspaceManagerClientProvider) protected readonly clientProvider: WorkspaceManagerClientProvider;
@@ -383,6 +453,16 @@ export class WorkspaceStarter {
             const metadata = new WorkspaceMetadata();
             metadata.setOwner(workspace.ownerId);
             metadata.setMetaId(workspace.id);
+            const portProtection = this.createPortProtection(workspace);
+            if (portProtection) {
+                // ws-proxy reads the protection of public ports from this annotation
+                metadata.getAnnotationsMap().set("portProtection", portProtection);
+            }
+            const customDomains = this.createCustomDomains(workspace);
+            if (customDomains) {
+                // ws-proxy routes these domains to the workspace once their ownership is verified
+                metadata.getAnnotationsMap().set("customDomains", customDomains);
+            }
             const startRequest = new StartWorkspaceRequest();
             startRequest.setId(instance.id);
             startRequest.setMetadata(metadata);
@@ -1273,6 +1353,20 @@ export class WorkspaceStarter {
         return spec;
     }
 
+    /**
+     * Serializes the protection of all public ports of a workspace, or returns undefined if no port is protected.
+     */
+    protected createPortProtection(workspace: Workspace): string | undefined {
+        return createPortProtection(workspace.config.ports || []);
+    }
+
+    /**
+     * Serializes the custom domains of all public ports of a workspace, or returns undefined if there are none.
+     */
+    protected createCustomDomains(workspace: Workspace): string | undefined {
+        return createCustomDomains(workspace.config.ports || []);
+    }
+
     protected createDefaultGitpodAPITokenScopes(workspace: Workspace, instance: WorkspaceInstance): string[] {
         const scopes = [
             "function:getWorkspace",
@@ -1301,6 +1395,7 @@ export class WorkspaceStarter {
             "function:accessCodeSyncStorage",
             "function:guessGitTokenScopes",
             "function:getEnvVars",
+            "function:getAllEnvVars",
             "function:setEnvVar",
             "function:deleteEnvVar",
             "function:trackEvent",
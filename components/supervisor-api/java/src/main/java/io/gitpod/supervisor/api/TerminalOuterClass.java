@@ -7949,6 +7949,44 @@ java.lang.String defaultValue);
      */
     com.google.protobuf.ByteString
         getAliasBytes();
+
+    /**
+     * <pre>
+     * history_only ends the stream once the recorded output was sent
+     * instead of following the terminal.
+     * </pre>
+     *
+     * <code>bool history_only = 2;</code>
+     * @return The historyOnly.
+     */
+    boolean getHistoryOnly();
+
+    /**
+     * <pre>
+     * since limits the recorded output to what was written at or after this time.
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp since = 3;</code>
+     * @return Whether the since field is set.
+     */
+    boolean hasSince();
+    /**
+     * <pre>
+     * since limits the recorded output to what was written at or after this time.
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp since = 3;</code>
+     * @return The since.
+     */
+    com.google.protobuf.Timestamp getSince();
+    /**
+     * <pre>
+     * since limits the recorded output to what was written at or after this time.
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp since = 3;</code>
+     */
+    com.google.protobuf.TimestampOrBuilder getSinceOrBuilder();
   }
   /**
    * Protobuf type {@code supervisor.ListenTerminalRequest}
@@ -8002,6 +8040,24 @@ java.lang.String defaultValue);
               alias_ = s;
               break;
             }
+            case 16: {
+
+              historyOnly_ = input.readBool();
+              break;
+            }
+            case 26: {
+              com.google.protobuf.Timestamp.Builder subBuilder = null;
+              if (since_ != null) {
+                subBuilder = since_.toBuilder();
+              }
+              since_ = input.readMessage(com.google.protobuf.Timestamp.parser(), extensionRegistry);
+              if (subBuilder != null) {
+                subBuilder.mergeFrom(since_);
+                since_ = subBuilder.buildPartial();
+              }
+
+              break;
+            }
             default: {
               if (!parseUnknownField(
                   input, unknownFields, extensionRegistry, tag)) {
@@ -8074,6 +8130,60 @@ java.lang.String defaultValue);
       }
     }
 
+    public static final int HISTORY_ONLY_FIELD_NUMBER = 2;
+    private boolean historyOnly_;
+    /**
+     * <pre>
+     * history_only ends the stream once the recorded output was sent
+     * instead of following the terminal.
+     * </pre>
+     *
+     * <code>bool history_only = 2;</code>
+     * @return The historyOnly.
+     */
+    @java.lang.Override
+    public boolean getHistoryOnly() {
+      return historyOnly_;
+    }
+
+    public static final int SINCE_FIELD_NUMBER = 3;
+    private com.google.protobuf.Timestamp since_;
+    /**
+     * <pre>
+     * since limits the recorded output to what was written at or after this time.
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp since = 3;</code>
+     * @return Whether the since field is set.
+     */
+    @java.lang.Override
+    public boolean hasSince() {
+      return since_ != null;
+    }
+    /**
+     * <pre>
+     * since limits the recorded output to what was written at or after this time.
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp since = 3;</code>
+     * @return The since.
+     */
+    @java.lang.Override
+    public com.google.protobuf.Timestamp getSince() {
+      return since_ == null ? com.google.protobuf.Timestamp.getDefaultInstance() : since_;
+    }
+    /**
+     * <pre>
+     * since limits the recorded output to what was written at or after this time.
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp since = 3;</code>
+     */
+    @java.lang.Override
+    public com.google.protobuf.TimestampOrBuilder getSinceOrBuilder() {
+      return getSince();
+    }
+
     private byte memoizedIsInitialized = -1;
     @java.lang.Override
     public final boolean isInitialized() {
@@ -8091,6 +8201,12 @@ java.lang.String defaultValue);
       if (!com.google.protobuf.GeneratedMessageV3.isStringEmpty(alias_)) {
         com.google.protobuf.GeneratedMessageV3.writeString(output, 1, alias_);
       }
+      if (historyOnly_ != false) {
+        output.writeBool(2, historyOnly_);
+      }
+      if (since_ != null) {
+        output.writeMessage(3, getSince());
+      }
       unknownFields.writeTo(output);
     }
 
@@ -8103,6 +8219,14 @@ java.lang.String defaultValue);
       if (!com.google.protobuf.GeneratedMessageV3.isStringEmpty(alias_)) {
         size += com.google.protobuf.GeneratedMessageV3.computeStringSize(1, alias_);
       }
+      if (historyOnly_ != false) {
+        size += com.google.protobuf.CodedOutputStream
+          .computeBoolSize(2, historyOnly_);
+      }
+      if (since_ != null) {
+        size += com.google.protobuf.CodedOutputStream
+          .computeMessageSize(3, getSince());
+      }
       size += unknownFields.getSerializedSize();
       memoizedSize = size;
       return size;
@@ -8120,6 +8244,13 @@ java.lang.String defaultValue);
 
       if (!getAlias()
           .equals(other.getAlias())) return false;
+      if (getHistoryOnly()
+          != other.getHistoryOnly()) return false;
+      if (hasSince() != other.hasSince()) return false;
+      if (hasSince()) {
+        if (!getSince()
+            .equals(other.getSince())) return false;
+      }
       if (!unknownFields.equals(other.unknownFields)) return false;
       return true;
     }
@@ -8133,6 +8264,13 @@ java.lang.String defaultValue);
       hash = (19 * hash) + getDescriptor().hashCode();
       hash = (37 * hash) + ALIAS_FIELD_NUMBER;
       hash = (53 * hash) + getAlias().hashCode();
+      hash = (37 * hash) + HISTORY_ONLY_FIELD_NUMBER;
+      hash = (53 * hash) + com.google.protobuf.Internal.hashBoolean(
+          getHistoryOnly());
+      if (hasSince()) {
+        hash = (37 * hash) + SINCE_FIELD_NUMBER;
+        hash = (53 * hash) + getSince().hashCode();
+      }
       hash = (29 * hash) + unknownFields.hashCode();
       memoizedHashCode = hash;
       return hash;
@@ -8268,6 +8406,14 @@ java.lang.String defaultValue);
         super.clear();
         alias_ = "";
 
+        historyOnly_ = false;
+
+        if (sinceBuilder_ == null) {
+          since_ = null;
+        } else {
+          since_ = null;
+          sinceBuilder_ = null;
+        }
         return this;
       }
 
@@ -8295,6 +8441,12 @@ java.lang.String defaultValue);
       public io.gitpod.supervisor.api.TerminalOuterClass.ListenTerminalRequest buildPartial() {
         io.gitpod.supervisor.api.TerminalOuterClass.ListenTerminalRequest result = new io.gitpod.supervisor.api.TerminalOuterClass.ListenTerminalRequest(this);
         result.alias_ = alias_;
+        result.historyOnly_ = historyOnly_;
+        if (sinceBuilder_ == null) {
+          result.since_ = since_;
+        } else {
+          result.since_ = sinceBuilder_.build();
+        }
         onBuilt();
         return result;
       }
@@ -8347,6 +8499,12 @@ java.lang.String defaultValue);
           alias_ = other.alias_;
           onChanged();
         }
+        if (other.getHistoryOnly() != false) {
+          setHistoryOnly(other.getHistoryOnly());
+        }
+        if (other.hasSince()) {
+          mergeSince(other.getSince());
+        }
         this.mergeUnknownFields(other.unknownFields);
         onChanged();
         return this;
@@ -8451,6 +8609,207 @@ java.lang.String defaultValue);
         onChanged();
         return this;
       }
+
+      private boolean historyOnly_ ;
+      /**
+       * <pre>
+       * history_only ends the stream once the recorded output was sent
+       * instead of following the terminal.
+       * </pre>
+       *
+       * <code>bool history_only = 2;</code>
+       * @return The historyOnly.
+       */
+      @java.lang.Override
+      public boolean getHistoryOnly() {
+        return historyOnly_;
+      }
+      /**
+       * <pre>
+       * history_only ends the stream once the recorded output was sent
+       * instead of following the terminal.
+       * </pre>
+       *
+       * <code>bool history_only = 2;</code>
+       * @param value The historyOnly to set.
+       * @return This builder for chaining.
+       */
+      public Builder setHistoryOnly(boolean value) {
+        
+        historyOnly_ = value;
+        onChanged();
+        return this;
+      }
+      /**
+       * <pre>
+       * history_only ends the stream once the recorded output was sent
+       * instead of following the terminal.
+       * </pre>
+       *
+       * <code>bool history_only = 2;</code>
+       * @return This builder for chaining.
+       */
+      public Builder clearHistoryOnly() {
+        
+        historyOnly_ = false;
+        onChanged();
+        return this;
+      }
+
+      private com.google.protobuf.Timestamp since_;
+      private com.google.protobuf.SingleFieldBuilderV3<
+          com.google.protobuf.Timestamp, com.google.protobuf.Timestamp.Builder, com.google.protobuf.TimestampOrBuilder> sinceBuilder_;
+      /**
+       * <pre>
+       * since limits the recorded output to what was written at or after this time.
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp since = 3;</code>
+       * @return Whether the since field is set.
+       */
+      public boolean hasSince() {
+        return sinceBuilder_ != null || since_ != null;
+      }
+      /**
+       * <pre>
+       * since limits the recorded output to what was written at or after this time.
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp since = 3;</code>
+       * @return The since.
+       */
+      public com.google.protobuf.Timestamp getSince() {
+        if (sinceBuilder_ == null) {
+          return since_ == null ? com.google.protobuf.Timestamp.getDefaultInstance() : since_;
+        } else {
+          return sinceBuilder_.getMessage();
+        }
+      }
+      /**
+       * <pre>
+       * since limits the recorded output to what was written at or after this time.
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp since = 3;</code>
+       */
+      public Builder setSince(com.google.protobuf.Timestamp value) {
+        if (sinceBuilder_ == null) {
+          if (value == null) {
+            throw new NullPointerException();
+          }
+          since_ = value;
+          onChanged();
+        } else {
+          sinceBuilder_.setMessage(value);
+        }
+
+        return this;
+      }
+      /**
+       * <pre>
+       * since limits the recorded output to what was written at or after this time.
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp since = 3;</code>
+       */
+      public Builder setSince(
+          com.google.protobuf.Timestamp.Builder builderForValue) {
+        if (sinceBuilder_ == null) {
+          since_ = builderForValue.build();
+          onChanged();
+        } else {
+          sinceBuilder_.setMessage(builderForValue.build());
+        }
+
+        return this;
+      }
+      /**
+       * <pre>
+       * since limits the recorded output to what was written at or after this time.
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp since = 3;</code>
+       */
+      public Builder mergeSince(com.google.protobuf.Timestamp value) {
+        if (sinceBuilder_ == null) {
+          if (since_ != null) {
+            since_ =
+              com.google.protobuf.Timestamp.newBuilder(since_).mergeFrom(value).buildPartial();
+          } else {
+            since_ = value;
+          }
+          onChanged();
+        } else {
+          sinceBuilder_.mergeFrom(value);
+        }
+
+        return this;
+      }
+      /**
+       * <pre>
+       * since limits the recorded output to what was written at or after this time.
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp since = 3;</code>
+       */
+      public Builder clearSince() {
+        if (sinceBuilder_ == null) {
+          since_ = null;
+          onChanged();
+        } else {
+          since_ = null;
+          sinceBuilder_ = null;
+        }
+
+        return this;
+      }
+      /**
+       * <pre>
+       * since limits the recorded output to what was written at or after this time.
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp since = 3;</code>
+       */
+      public com.google.protobuf.Timestamp.Builder getSinceBuilder() {
+        
+        onChanged();
+        return getSinceFieldBuilder().getBuilder();
+      }
+      /**
+       * <pre>
+       * since limits the recorded output to what was written at or after this time.
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp since = 3;</code>
+       */
+      public com.google.protobuf.TimestampOrBuilder getSinceOrBuilder() {
+        if (sinceBuilder_ != null) {
+          return sinceBuilder_.getMessageOrBuilder();
+        } else {
+          return since_ == null ?
+              com.google.protobuf.Timestamp.getDefaultInstance() : since_;
+        }
+      }
+      /**
+       * <pre>
+       * since limits the recorded output to what was written at or after this time.
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp since = 3;</code>
+       */
+      private com.google.protobuf.SingleFieldBuilderV3<
+          com.google.protobuf.Timestamp, com.google.protobuf.Timestamp.Builder, com.google.protobuf.TimestampOrBuilder> 
+          getSinceFieldBuilder() {
+        if (sinceBuilder_ == null) {
+          sinceBuilder_ = new com.google.protobuf.SingleFieldBuilderV3<
+              com.google.protobuf.Timestamp, com.google.protobuf.Timestamp.Builder, com.google.protobuf.TimestampOrBuilder>(
+                  getSince(),
+                  getParentForChildren(),
+                  isClean());
+          since_ = null;
+        }
+        return sinceBuilder_;
+      }
       @java.lang.Override
       public final Builder setUnknownFields(
           final com.google.protobuf.UnknownFieldSet unknownFields) {
@@ -8566,6 +8925,33 @@ java.lang.String defaultValue);
      */
     io.gitpod.supervisor.api.TerminalOuterClass.TerminalTitleSource getTitleSource();
 
+    /**
+     * <pre>
+     * time the data was written to the terminal, only present if output is data
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp time = 5;</code>
+     * @return Whether the time field is set.
+     */
+    boolean hasTime();
+    /**
+     * <pre>
+     * time the data was written to the terminal, only present if output is data
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp time = 5;</code>
+     * @return The time.
+     */
+    com.google.protobuf.Timestamp getTime();
+    /**
+     * <pre>
+     * time the data was written to the terminal, only present if output is data
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp time = 5;</code>
+     */
+    com.google.protobuf.TimestampOrBuilder getTimeOrBuilder();
+
     public io.gitpod.supervisor.api.TerminalOuterClass.ListenTerminalResponse.OutputCase getOutputCase();
   }
   /**
@@ -8636,6 +9022,19 @@ java.lang.String defaultValue);
               titleSource_ = rawValue;
               break;
             }
+            case 42: {
+              com.google.protobuf.Timestamp.Builder subBuilder = null;
+              if (time_ != null) {
+                subBuilder = time_.toBuilder();
+              }
+              time_ = input.readMessage(com.google.protobuf.Timestamp.parser(), extensionRegistry);
+              if (subBuilder != null) {
+                subBuilder.mergeFrom(time_);
+                time_ = subBuilder.buildPartial();
+              }
+
+              break;
+            }
             default: {
               if (!parseUnknownField(
                   input, unknownFields, extensionRegistry, tag)) {
@@ -8834,6 +9233,44 @@ java.lang.String defaultValue);
       return result == null ? io.gitpod.supervisor.api.TerminalOuterClass.TerminalTitleSource.UNRECOGNIZED : result;
     }
 
+    public static final int TIME_FIELD_NUMBER = 5;
+    private com.google.protobuf.Timestamp time_;
+    /**
+     * <pre>
+     * time the data was written to the terminal, only present if output is data
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp time = 5;</code>
+     * @return Whether the time field is set.
+     */
+    @java.lang.Override
+    public boolean hasTime() {
+      return time_ != null;
+    }
+    /**
+     * <pre>
+     * time the data was written to the terminal, only present if output is data
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp time = 5;</code>
+     * @return The time.
+     */
+    @java.lang.Override
+    public com.google.protobuf.Timestamp getTime() {
+      return time_ == null ? com.google.protobuf.Timestamp.getDefaultInstance() : time_;
+    }
+    /**
+     * <pre>
+     * time the data was written to the terminal, only present if output is data
+     * </pre>
+     *
+     * <code>.google.protobuf.Timestamp time = 5;</code>
+     */
+    @java.lang.Override
+    public com.google.protobuf.TimestampOrBuilder getTimeOrBuilder() {
+      return getTime();
+    }
+
     private byte memoizedIsInitialized = -1;
     @java.lang.Override
     public final boolean isInitialized() {
@@ -8862,6 +9299,9 @@ java.lang.String defaultValue);
       if (titleSource_ != io.gitpod.supervisor.api.TerminalOuterClass.TerminalTitleSource.process.getNumber()) {
         output.writeEnum(4, titleSource_);
       }
+      if (time_ != null) {
+        output.writeMessage(5, getTime());
+      }
       unknownFields.writeTo(output);
     }
 
@@ -8888,6 +9328,10 @@ java.lang.String defaultValue);
         size += com.google.protobuf.CodedOutputStream
           .computeEnumSize(4, titleSource_);
       }
+      if (time_ != null) {
+        size += com.google.protobuf.CodedOutputStream
+          .computeMessageSize(5, getTime());
+      }
       size += unknownFields.getSerializedSize();
       memoizedSize = size;
       return size;
@@ -8904,6 +9348,11 @@ java.lang.String defaultValue);
       io.gitpod.supervisor.api.TerminalOuterClass.ListenTerminalResponse other = (io.gitpod.supervisor.api.TerminalOuterClass.ListenTerminalResponse) obj;
 
       if (titleSource_ != other.titleSource_) return false;
+      if (hasTime() != other.hasTime()) return false;
+      if (hasTime()) {
+        if (!getTime()
+            .equals(other.getTime())) return false;
+      }
       if (!getOutputCase().equals(other.getOutputCase())) return false;
       switch (outputCase_) {
         case 1:
@@ -8934,6 +9383,10 @@ java.lang.String defaultValue);
       hash = (19 * hash) + getDescriptor().hashCode();
       hash = (37 * hash) + TITLE_SOURCE_FIELD_NUMBER;
       hash = (53 * hash) + titleSource_;
+      if (hasTime()) {
+        hash = (37 * hash) + TIME_FIELD_NUMBER;
+        hash = (53 * hash) + getTime().hashCode();
+      }
       switch (outputCase_) {
         case 1:
           hash = (37 * hash) + DATA_FIELD_NUMBER;
@@ -9085,6 +9538,12 @@ java.lang.String defaultValue);
         super.clear();
         titleSource_ = 0;
 
+        if (timeBuilder_ == null) {
+          time_ = null;
+        } else {
+          time_ = null;
+          timeBuilder_ = null;
+        }
         outputCase_ = 0;
         output_ = null;
         return this;
@@ -9123,6 +9582,11 @@ java.lang.String defaultValue);
           result.output_ = output_;
         }
         result.titleSource_ = titleSource_;
+        if (timeBuilder_ == null) {
+          result.time_ = time_;
+        } else {
+          result.time_ = timeBuilder_.build();
+        }
         result.outputCase_ = outputCase_;
         onBuilt();
         return result;
@@ -9175,6 +9639,9 @@ java.lang.String defaultValue);
         if (other.titleSource_ != 0) {
           setTitleSourceValue(other.getTitleSourceValue());
         }
+        if (other.hasTime()) {
+          mergeTime(other.getTime());
+        }
         switch (other.getOutputCase()) {
           case DATA: {
             setData(other.getData());
@@ -9493,6 +9960,161 @@ java.lang.String defaultValue);
         onChanged();
         return this;
       }
+
+      private com.google.protobuf.Timestamp time_;
+      private com.google.protobuf.SingleFieldBuilderV3<
+          com.google.protobuf.Timestamp, com.google.protobuf.Timestamp.Builder, com.google.protobuf.TimestampOrBuilder> timeBuilder_;
+      /**
+       * <pre>
+       * time the data was written to the terminal, only present if output is data
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp time = 5;</code>
+       * @return Whether the time field is set.
+       */
+      public boolean hasTime() {
+        return timeBuilder_ != null || time_ != null;
+      }
+      /**
+       * <pre>
+       * time the data was written to the terminal, only present if output is data
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp time = 5;</code>
+       * @return The time.
+       */
+      public com.google.protobuf.Timestamp getTime() {
+        if (timeBuilder_ == null) {
+          return time_ == null ? com.google.protobuf.Timestamp.getDefaultInstance() : time_;
+        } else {
+          return timeBuilder_.getMessage();
+        }
+      }
+      /**
+       * <pre>
+       * time the data was written to the terminal, only present if output is data
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp time = 5;</code>
+       */
+      public Builder setTime(com.google.protobuf.Timestamp value) {
+        if (timeBuilder_ == null) {
+          if (value == null) {
+            throw new NullPointerException();
+          }
+          time_ = value;
+          onChanged();
+        } else {
+          timeBuilder_.setMessage(value);
+        }
+
+        return this;
+      }
+      /**
+       * <pre>
+       * time the data was written to the terminal, only present if output is data
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp time = 5;</code>
+       */
+      public Builder setTime(
+          com.google.protobuf.Timestamp.Builder builderForValue) {
+        if (timeBuilder_ == null) {
+          time_ = builderForValue.build();
+          onChanged();
+        } else {
+          timeBuilder_.setMessage(builderForValue.build());
+        }
+
+        return this;
+      }
+      /**
+       * <pre>
+       * time the data was written to the terminal, only present if output is data
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp time = 5;</code>
+       */
+      public Builder mergeTime(com.google.protobuf.Timestamp value) {
+        if (timeBuilder_ == null) {
+          if (time_ != null) {
+            time_ =
+              com.google.protobuf.Timestamp.newBuilder(time_).mergeFrom(value).buildPartial();
+          } else {
+            time_ = value;
+          }
+          onChanged();
+        } else {
+          timeBuilder_.mergeFrom(value);
+        }
+
+        return this;
+      }
+      /**
+       * <pre>
+       * time the data was written to the terminal, only present if output is data
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp time = 5;</code>
+       */
+      public Builder clearTime() {
+        if (timeBuilder_ == null) {
+          time_ = null;
+          onChanged();
+        } else {
+          time_ = null;
+          timeBuilder_ = null;
+        }
+
+        return this;
+      }
+      /**
+       * <pre>
+       * time the data was written to the terminal, only present if output is data
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp time = 5;</code>
+       */
+      public com.google.protobuf.Timestamp.Builder getTimeBuilder() {
+        
+        onChanged();
+        return getTimeFieldBuilder().getBuilder();
+      }
+      /**
+       * <pre>
+       * time the data was written to the terminal, only present if output is data
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp time = 5;</code>
+       */
+      public com.google.protobuf.TimestampOrBuilder getTimeOrBuilder() {
+        if (timeBuilder_ != null) {
+          return timeBuilder_.getMessageOrBuilder();
+        } else {
+          return time_ == null ?
+              com.google.protobuf.Timestamp.getDefaultInstance() : time_;
+        }
+      }
+      /**
+       * <pre>
+       * time the data was written to the terminal, only present if output is data
+       * </pre>
+       *
+       * <code>.google.protobuf.Timestamp time = 5;</code>
+       */
+      private com.google.protobuf.SingleFieldBuilderV3<
+          com.google.protobuf.Timestamp, com.google.protobuf.Timestamp.Builder, com.google.protobuf.TimestampOrBuilder> 
+          getTimeFieldBuilder() {
+        if (timeBuilder_ == null) {
+          timeBuilder_ = new com.google.protobuf.SingleFieldBuilderV3<
+              com.google.protobuf.Timestamp, com.google.protobuf.Timestamp.Builder, com.google.protobuf.TimestampOrBuilder>(
+                  getTime(),
+                  getParentForChildren(),
+                  isClean());
+          time_ = null;
+        }
+        return timeBuilder_;
+      }
       @java.lang.Override
       public final Builder setUnknownFields(
           final com.google.protobuf.UnknownFieldSet unknownFields) {
@@ -15211,82 +15833,87 @@ java.lang.String defaultValue);
   static {
     java.lang.String[] descriptorData = {
       "\n\016terminal.proto\022\nsupervisor\032\034google/api" +
-      "/annotations.proto\"M\n\014TerminalSize\022\014\n\004ro" +
-      "ws\030\001 \001(\r\022\014\n\004cols\030\002 \001(\r\022\017\n\007widthPx\030\003 \001(\r\022" +
-      "\020\n\010heightPx\030\004 \001(\r\"\317\002\n\023OpenTerminalReques" +
-      "t\022\017\n\007workdir\030\001 \001(\t\0225\n\003env\030\002 \003(\0132(.superv" +
-      "isor.OpenTerminalRequest.EnvEntry\022E\n\013ann" +
-      "otations\030\003 \003(\01320.supervisor.OpenTerminal" +
-      "Request.AnnotationsEntry\022\r\n\005shell\030\004 \001(\t\022" +
-      "\022\n\nshell_args\030\005 \003(\t\022&\n\004size\030\006 \001(\0132\030.supe" +
-      "rvisor.TerminalSize\032*\n\010EnvEntry\022\013\n\003key\030\001" +
-      " \001(\t\022\r\n\005value\030\002 \001(\t:\0028\001\0322\n\020AnnotationsEn" +
-      "try\022\013\n\003key\030\001 \001(\t\022\r\n\005value\030\002 \001(\t:\0028\001\"U\n\024O" +
-      "penTerminalResponse\022&\n\010terminal\030\001 \001(\0132\024." +
-      "supervisor.Terminal\022\025\n\rstarter_token\030\002 \001" +
-      "(\t\"(\n\027ShutdownTerminalRequest\022\r\n\005alias\030\001" +
-      " \001(\t\"\032\n\030ShutdownTerminalResponse\"\237\002\n\010Ter" +
-      "minal\022\r\n\005alias\030\001 \001(\t\022\017\n\007command\030\002 \003(\t\022\r\n" +
-      "\005title\030\003 \001(\t\022\013\n\003pid\030\004 \001(\003\022\027\n\017initial_wor" +
-      "kdir\030\005 \001(\t\022\027\n\017current_workdir\030\006 \001(\t\022:\n\013a" +
-      "nnotations\030\007 \003(\0132%.supervisor.Terminal.A" +
-      "nnotationsEntry\0225\n\014title_source\030\010 \001(\0162\037." +
-      "supervisor.TerminalTitleSource\0322\n\020Annota" +
-      "tionsEntry\022\013\n\003key\030\001 \001(\t\022\r\n\005value\030\002 \001(\t:\002" +
-      "8\001\"#\n\022GetTerminalRequest\022\r\n\005alias\030\001 \001(\t\"" +
-      "\026\n\024ListTerminalsRequest\"@\n\025ListTerminals" +
-      "Response\022\'\n\tterminals\030\001 \003(\0132\024.supervisor" +
-      ".Terminal\"&\n\025ListenTerminalRequest\022\r\n\005al" +
-      "ias\030\001 \001(\t\"\217\001\n\026ListenTerminalResponse\022\016\n\004" +
-      "data\030\001 \001(\014H\000\022\023\n\texit_code\030\002 \001(\005H\000\022\017\n\005tit" +
-      "le\030\003 \001(\tH\000\0225\n\014title_source\030\004 \001(\0162\037.super" +
-      "visor.TerminalTitleSourceB\010\n\006output\"4\n\024W" +
-      "riteTerminalRequest\022\r\n\005alias\030\001 \001(\t\022\r\n\005st" +
-      "din\030\002 \001(\014\".\n\025WriteTerminalResponse\022\025\n\rby" +
-      "tes_written\030\001 \001(\r\"}\n\026SetTerminalSizeRequ" +
-      "est\022\r\n\005alias\030\001 \001(\t\022\017\n\005token\030\002 \001(\tH\000\022\017\n\005f" +
-      "orce\030\003 \001(\010H\000\022&\n\004size\030\004 \001(\0132\030.supervisor." +
-      "TerminalSizeB\n\n\010priority\"\031\n\027SetTerminalS" +
-      "izeResponse\"7\n\027SetTerminalTitleRequest\022\r" +
-      "\n\005alias\030\001 \001(\t\022\r\n\005title\030\002 \001(\t\"\032\n\030SetTermi" +
-      "nalTitleResponse\"\276\001\n UpdateTerminalAnnot" +
-      "ationsRequest\022\r\n\005alias\030\001 \001(\t\022J\n\007changed\030" +
-      "\002 \003(\01329.supervisor.UpdateTerminalAnnotat" +
-      "ionsRequest.ChangedEntry\022\017\n\007deleted\030\003 \003(" +
-      "\t\032.\n\014ChangedEntry\022\013\n\003key\030\001 \001(\t\022\r\n\005value\030" +
-      "\002 \001(\t:\0028\001\"#\n!UpdateTerminalAnnotationsRe" +
-      "sponse*+\n\023TerminalTitleSource\022\013\n\007process" +
-      "\020\000\022\007\n\003api\020\0012\260\007\n\017TerminalService\022K\n\004Open\022" +
-      "\037.supervisor.OpenTerminalRequest\032 .super" +
-      "visor.OpenTerminalResponse\"\000\022|\n\010Shutdown" +
-      "\022#.supervisor.ShutdownTerminalRequest\032$." +
-      "supervisor.ShutdownTerminalResponse\"%\202\323\344" +
-      "\223\002\037\022\035/v1/terminal/shutdown/{alias}\022]\n\003Ge" +
-      "t\022\036.supervisor.GetTerminalRequest\032\024.supe" +
-      "rvisor.Terminal\" \202\323\344\223\002\032\022\030/v1/terminal/ge" +
-      "t/{alias}\022f\n\004List\022 .supervisor.ListTermi" +
-      "nalsRequest\032!.supervisor.ListTerminalsRe" +
-      "sponse\"\031\202\323\344\223\002\023\022\021/v1/terminal/list\022v\n\006Lis" +
-      "ten\022!.supervisor.ListenTerminalRequest\032\"" +
-      ".supervisor.ListenTerminalResponse\"#\202\323\344\223" +
-      "\002\035\022\033/v1/terminal/listen/{alias}0\001\022p\n\005Wri" +
-      "te\022 .supervisor.WriteTerminalRequest\032!.s" +
-      "upervisor.WriteTerminalResponse\"\"\202\323\344\223\002\034\"" +
-      "\032/v1/terminal/write/{alias}\022T\n\007SetSize\022\"" +
-      ".supervisor.SetTerminalSizeRequest\032#.sup" +
-      "ervisor.SetTerminalSizeResponse\"\000\022W\n\010Set" +
-      "Title\022#.supervisor.SetTerminalTitleReque" +
-      "st\032$.supervisor.SetTerminalTitleResponse" +
-      "\"\000\022r\n\021UpdateAnnotations\022,.supervisor.Upd" +
-      "ateTerminalAnnotationsRequest\032-.supervis" +
-      "or.UpdateTerminalAnnotationsResponse\"\000BF" +
-      "\n\030io.gitpod.supervisor.apiZ*github.com/g" +
-      "itpod-io/gitpod/supervisor/apib\006proto3"
+      "/annotations.proto\032\037google/protobuf/time" +
+      "stamp.proto\"M\n\014TerminalSize\022\014\n\004rows\030\001 \001(" +
+      "\r\022\014\n\004cols\030\002 \001(\r\022\017\n\007widthPx\030\003 \001(\r\022\020\n\010heig" +
+      "htPx\030\004 \001(\r\"\317\002\n\023OpenTerminalRequest\022\017\n\007wo" +
+      "rkdir\030\001 \001(\t\0225\n\003env\030\002 \003(\0132(.supervisor.Op" +
+      "enTerminalRequest.EnvEntry\022E\n\013annotation" +
+      "s\030\003 \003(\01320.supervisor.OpenTerminalRequest" +
+      ".AnnotationsEntry\022\r\n\005shell\030\004 \001(\t\022\022\n\nshel" +
+      "l_args\030\005 \003(\t\022&\n\004size\030\006 \001(\0132\030.supervisor." +
+      "TerminalSize\032*\n\010EnvEntry\022\013\n\003key\030\001 \001(\t\022\r\n" +
+      "\005value\030\002 \001(\t:\0028\001\0322\n\020AnnotationsEntry\022\013\n\003" +
+      "key\030\001 \001(\t\022\r\n\005value\030\002 \001(\t:\0028\001\"U\n\024OpenTerm" +
+      "inalResponse\022&\n\010terminal\030\001 \001(\0132\024.supervi" +
+      "sor.Terminal\022\025\n\rstarter_token\030\002 \001(\t\"(\n\027S" +
+      "hutdownTerminalRequest\022\r\n\005alias\030\001 \001(\t\"\032\n" +
+      "\030ShutdownTerminalResponse\"\237\002\n\010Terminal\022\r" +
+      "\n\005alias\030\001 \001(\t\022\017\n\007command\030\002 \003(\t\022\r\n\005title\030" +
+      "\003 \001(\t\022\013\n\003pid\030\004 \001(\003\022\027\n\017initial_workdir\030\005 " +
+      "\001(\t\022\027\n\017current_workdir\030\006 \001(\t\022:\n\013annotati" +
+      "ons\030\007 \003(\0132%.supervisor.Terminal.Annotati" +
+      "onsEntry\0225\n\014title_source\030\010 \001(\0162\037.supervi" +
+      "sor.TerminalTitleSource\0322\n\020AnnotationsEn" +
+      "try\022\013\n\003key\030\001 \001(\t\022\r\n\005value\030\002 \001(\t:\0028\001\"#\n\022G" +
+      "etTerminalRequest\022\r\n\005alias\030\001 \001(\t\"\026\n\024List" +
+      "TerminalsRequest\"@\n\025ListTerminalsRespons" +
+      "e\022\'\n\tterminals\030\001 \003(\0132\024.supervisor.Termin" +
+      "al\"g\n\025ListenTerminalRequest\022\r\n\005alias\030\001 \001" +
+      "(\t\022\024\n\014history_only\030\002 \001(\010\022)\n\005since\030\003 \001(\0132" +
+      "\032.google.protobuf.Timestamp\"\271\001\n\026ListenTe" +
+      "rminalResponse\022\016\n\004data\030\001 \001(\014H\000\022\023\n\texit_c" +
+      "ode\030\002 \001(\005H\000\022\017\n\005title\030\003 \001(\tH\000\0225\n\014title_so" +
+      "urce\030\004 \001(\0162\037.supervisor.TerminalTitleSou" +
+      "rce\022(\n\004time\030\005 \001(\0132\032.google.protobuf.Time" +
+      "stampB\010\n\006output\"4\n\024WriteTerminalRequest\022" +
+      "\r\n\005alias\030\001 \001(\t\022\r\n\005stdin\030\002 \001(\014\".\n\025WriteTe" +
+      "rminalResponse\022\025\n\rbytes_written\030\001 \001(\r\"}\n" +
+      "\026SetTerminalSizeRequest\022\r\n\005alias\030\001 \001(\t\022\017" +
+      "\n\005token\030\002 \001(\tH\000\022\017\n\005force\030\003 \001(\010H\000\022&\n\004size" +
+      "\030\004 \001(\0132\030.supervisor.TerminalSizeB\n\n\010prio" +
+      "rity\"\031\n\027SetTerminalSizeResponse\"7\n\027SetTe" +
+      "rminalTitleRequest\022\r\n\005alias\030\001 \001(\t\022\r\n\005tit" +
+      "le\030\002 \001(\t\"\032\n\030SetTerminalTitleResponse\"\276\001\n" +
+      " UpdateTerminalAnnotationsRequest\022\r\n\005ali" +
+      "as\030\001 \001(\t\022J\n\007changed\030\002 \003(\01329.supervisor.U" +
+      "pdateTerminalAnnotationsRequest.ChangedE" +
+      "ntry\022\017\n\007deleted\030\003 \003(\t\032.\n\014ChangedEntry\022\013\n" +
+      "\003key\030\001 \001(\t\022\r\n\005value\030\002 \001(\t:\0028\001\"#\n!UpdateT" +
+      "erminalAnnotationsResponse*+\n\023TerminalTi" +
+      "tleSource\022\013\n\007process\020\000\022\007\n\003api\020\0012\260\007\n\017Term" +
+      "inalService\022K\n\004Open\022\037.supervisor.OpenTer" +
+      "minalRequest\032 .supervisor.OpenTerminalRe" +
+      "sponse\"\000\022|\n\010Shutdown\022#.supervisor.Shutdo" +
+      "wnTerminalRequest\032$.supervisor.ShutdownT" +
+      "erminalResponse\"%\202\323\344\223\002\037\022\035/v1/terminal/sh" +
+      "utdown/{alias}\022]\n\003Get\022\036.supervisor.GetTe" +
+      "rminalRequest\032\024.supervisor.Terminal\" \202\323\344" +
+      "\223\002\032\022\030/v1/terminal/get/{alias}\022f\n\004List\022 ." +
+      "supervisor.ListTerminalsRequest\032!.superv" +
+      "isor.ListTerminalsResponse\"\031\202\323\344\223\002\023\022\021/v1/" +
+      "terminal/list\022v\n\006Listen\022!.supervisor.Lis" +
+      "tenTerminalRequest\032\".supervisor.ListenTe" +
+      "rminalResponse\"#\202\323\344\223\002\035\022\033/v1/terminal/lis" +
+      "ten/{alias}0\001\022p\n\005Write\022 .supervisor.Writ" +
+      "eTerminalRequest\032!.supervisor.WriteTermi" +
+      "nalResponse\"\"\202\323\344\223\002\034\"\032/v1/terminal/write/" +
+      "{alias}\022T\n\007SetSize\022\".supervisor.SetTermi" +
+      "nalSizeRequest\032#.supervisor.SetTerminalS" +
+      "izeResponse\"\000\022W\n\010SetTitle\022#.supervisor.S" +
+      "etTerminalTitleRequest\032$.supervisor.SetT" +
+      "erminalTitleResponse\"\000\022r\n\021UpdateAnnotati" +
+      "ons\022,.supervisor.UpdateTerminalAnnotatio" +
+      "nsRequest\032-.supervisor.UpdateTerminalAnn" +
+      "otationsResponse\"\000BF\n\030io.gitpod.supervis" +
+      "or.apiZ*github.com/gitpod-io/gitpod/supe" +
+      "rvisor/apib\006proto3"
     };
     descriptor = com.google.protobuf.Descriptors.FileDescriptor
       .internalBuildGeneratedFileFrom(descriptorData,
         new com.google.protobuf.Descriptors.FileDescriptor[] {
           com.google.api.AnnotationsProto.getDescriptor(),
+          com.google.protobuf.TimestampProto.getDescriptor(),
         });
     internal_static_supervisor_TerminalSize_descriptor =
       getDescriptor().getMessageTypes().get(0);
@@ -15365,13 +15992,13 @@ java.lang.String defaultValue);
     internal_static_supervisor_ListenTerminalRequest_fieldAccessorTable = new
       com.google.protobuf.GeneratedMessageV3.FieldAccessorTable(
         internal_static_supervisor_ListenTerminalRequest_descriptor,
-        new java.lang.String[] { "Alias", });
+        new java.lang.String[] { "Alias", "HistoryOnly", "Since", });
     internal_static_supervisor_ListenTerminalResponse_descriptor =
       getDescriptor().getMessageTypes().get(10);
     internal_static_supervisor_ListenTerminalResponse_fieldAccessorTable = new
       com.google.protobuf.GeneratedMessageV3.FieldAccessorTable(
         internal_static_supervisor_ListenTerminalResponse_descriptor,
-        new java.lang.String[] { "Data", "ExitCode", "Title", "TitleSource", "Output", });
+        new java.lang.String[] { "Data", "ExitCode", "Title", "TitleSource", "Time", "Output", });
     internal_static_supervisor_WriteTerminalRequest_descriptor =
       getDescriptor().getMessageTypes().get(11);
     internal_static_supervisor_WriteTerminalRequest_fieldAccessorTable = new
@@ -15432,6 +16059,7 @@ java.lang.String defaultValue);
     com.google.protobuf.Descriptors.FileDescriptor
         .internalUpdateFileDescriptor(descriptor, registry);
     com.google.api.AnnotationsProto.getDescriptor();
+    com.google.protobuf.TimestampProto.getDescriptor();
   }
 
   // @@protoc_insertion_point(outer_class_scope)
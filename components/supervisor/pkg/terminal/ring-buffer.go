@@ -4,7 +4,11 @@
 
 package terminal
 
-import "golang.org/x/xerrors"
+import (
+	"time"
+
+	"golang.org/x/xerrors"
+)
 
 // RingBuffer implements a ring buffer. It is a fixed size,
 // and new writes overwrite older data, such that for a buffer
@@ -15,6 +19,29 @@ type RingBuffer struct {
 	size        int64
 	writeCursor int64
 	written     int64
+
+	// marks records when the retained data was written
+	marks []writeMark
+}
+
+// writeMark marks the offset (in total bytes written) at which a write happened.
+type writeMark struct {
+	offset int64
+	time   time.Time
+}
+
+const (
+	// writeMarkResolution is the duration within which consecutive writes share a write mark
+	writeMarkResolution = 10 * time.Millisecond
+	// maxWriteMarks limits the memory write marks consume for buffers that receive lots of small writes.
+	// Once reached, the oldest marks are dropped and their data is attributed to the next oldest mark.
+	maxWriteMarks = 4096
+)
+
+// RecordedChunk is a chunk of data retained by a ring buffer together with the time it was written.
+type RecordedChunk struct {
+	Data []byte
+	Time time.Time
 }
 
 // NewRingBuffer creates a new buffer of a given size. The size
@@ -34,6 +61,12 @@ func NewRingBuffer(size int64) (*RingBuffer, error) {
 // Write writes up to len(buf) bytes to the internal ring,
 // overriding older data if necessary.
 func (b *RingBuffer) Write(buf []byte) (int, error) {
+	return b.writeAt(buf, time.Now())
+}
+
+func (b *RingBuffer) writeAt(buf []byte, t time.Time) (int, error) {
+	b.mark(t, len(buf))
+
 	// Account for total bytes written
 	n := len(buf)
 	b.written += int64(n)
@@ -56,6 +89,21 @@ func (b *RingBuffer) Write(buf []byte) (int, error) {
 	return n, nil
 }
 
+// mark records a write of n bytes at time t and drops marks of data that is no longer retained.
+func (b *RingBuffer) mark(t time.Time, n int) {
+	if n == 0 {
+		return
+	}
+	if l := len(b.marks); l == 0 || t.Sub(b.marks[l-1].time) >= writeMarkResolution {
+		b.marks = append(b.marks, writeMark{offset: b.written, time: t})
+	}
+
+	retained := b.written + int64(n) - b.size
+	for len(b.marks) > 1 && (b.marks[1].offset <= retained || len(b.marks) > maxWriteMarks) {
+		b.marks = b.marks[1:]
+	}
+}
+
 // Size returns the size of the buffer.
 func (b *RingBuffer) Size() int64 {
 	return b.size
@@ -82,10 +130,41 @@ func (b *RingBuffer) Bytes() []byte {
 	}
 }
 
+// Chunks provides the retained data split into chunks by the time it was written.
+// If since is not zero, only data written at or after since is returned.
+// Other than Bytes, the returned chunks are copies and can be used after further writes.
+func (b *RingBuffer) Chunks(since time.Time) []RecordedChunk {
+	data := b.Bytes()
+	start := b.written - int64(len(data))
+
+	var res []RecordedChunk
+	for i, m := range b.marks {
+		from, to := m.offset-start, int64(len(data))
+		if i == 0 || from < 0 {
+			from = 0
+		}
+		if i+1 < len(b.marks) {
+			to = b.marks[i+1].offset - start
+		}
+		if to <= from {
+			continue
+		}
+		if !since.IsZero() && m.time.Before(since) {
+			continue
+		}
+		res = append(res, RecordedChunk{
+			Data: append([]byte(nil), data[from:to]...),
+			Time: m.time,
+		})
+	}
+	return res
+}
+
 // Reset resets the buffer so it has no content.
 func (b *RingBuffer) Reset() {
 	b.writeCursor = 0
 	b.written = 0
+	b.marks = nil
 }
 
 // String returns the contents of the buffer as a string.
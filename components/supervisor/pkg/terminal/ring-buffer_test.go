@@ -0,0 +1,106 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package terminal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRingBufferChunks(t *testing.T) {
+	t0 := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	type write struct {
+		Data   string
+		Offset time.Duration
+	}
+	type chunk struct {
+		Data   string
+		Offset time.Duration
+	}
+	tests := []struct {
+		Desc        string
+		Size        int64
+		Writes      []write
+		Since       time.Duration
+		Expectation []chunk
+	}{
+		{
+			Desc: "empty",
+			Size: 10,
+		},
+		{
+			Desc:        "writes within resolution share a chunk",
+			Size:        10,
+			Writes:      []write{{"ab", 0}, {"cd", time.Millisecond}, {"ef", time.Second}},
+			Expectation: []chunk{{"abcd", 0}, {"ef", time.Second}},
+		},
+		{
+			Desc:        "overwritten data is dropped",
+			Size:        4,
+			Writes:      []write{{"abc", 0}, {"def", time.Second}, {"g", 2 * time.Second}},
+			Expectation: []chunk{{"def", time.Second}, {"g", 2 * time.Second}},
+		},
+		{
+			Desc:        "partially overwritten chunk",
+			Size:        4,
+			Writes:      []write{{"abc", 0}, {"de", time.Second}},
+			Expectation: []chunk{{"bc", 0}, {"de", time.Second}},
+		},
+		{
+			Desc:        "since",
+			Size:        10,
+			Writes:      []write{{"ab", 0}, {"cd", time.Second}, {"ef", 2 * time.Second}},
+			Since:       time.Second,
+			Expectation: []chunk{{"cd", time.Second}, {"ef", 2 * time.Second}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Desc, func(t *testing.T) {
+			b, err := NewRingBuffer(test.Size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range test.Writes {
+				_, _ = b.writeAt([]byte(w.Data), t0.Add(w.Offset))
+			}
+
+			var since time.Time
+			if test.Since != 0 {
+				since = t0.Add(test.Since)
+			}
+			var act []chunk
+			for _, c := range b.Chunks(since) {
+				act = append(act, chunk{Data: string(c.Data), Offset: c.Time.Sub(t0)})
+			}
+			if diff := cmp.Diff(test.Expectation, act); diff != "" {
+				t.Errorf("unexpected chunks (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRingBufferMaxWriteMarks(t *testing.T) {
+	t0 := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	b, err := NewRingBuffer(2 * maxWriteMarks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxWriteMarks+10; i++ {
+		_, _ = b.writeAt([]byte("a"), t0.Add(time.Duration(i)*time.Second))
+	}
+	if len(b.marks) != maxWriteMarks {
+		t.Errorf("unexpected number of write marks: %d, expected %d", len(b.marks), maxWriteMarks)
+	}
+
+	var total int
+	for _, c := range b.Chunks(time.Time{}) {
+		total += len(c.Data)
+	}
+	if total != maxWriteMarks+10 {
+		t.Errorf("unexpected number of bytes in chunks: %d, expected %d", total, maxWriteMarks+10)
+	}
+}
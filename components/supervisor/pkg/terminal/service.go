@@ -19,6 +19,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/gitpod-io/gitpod/common-go/log"
 	"github.com/gitpod-io/gitpod/supervisor/api"
@@ -223,7 +224,23 @@ func (srv *MuxTerminalService) Listen(req *api.ListenTerminalRequest, resp api.T
 	if !ok {
 		return status.Error(codes.NotFound, "terminal not found")
 	}
-	stdout := term.Stdout.Listen()
+	var since time.Time
+	if req.Since != nil {
+		since = req.Since.AsTime()
+	}
+	if req.HistoryOnly {
+		for _, chunk := range term.Stdout.History(since) {
+			for _, message := range recordedOutputMessages(chunk) {
+				err := resp.Send(message)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	history, stdout := term.Stdout.ListenWithHistory(TermListenOptions{}, since)
 	defer stdout.Close()
 
 	log.WithField("alias", req.Alias).Info("new terminal client")
@@ -232,6 +249,11 @@ func (srv *MuxTerminalService) Listen(req *api.ListenTerminalRequest, resp api.T
 	errchan := make(chan error, 1)
 	messages := make(chan *api.ListenTerminalResponse, 1)
 	go func() {
+		for _, chunk := range history {
+			for _, message := range recordedOutputMessages(chunk) {
+				messages <- message
+			}
+		}
 		for {
 			buf := make([]byte, 4096)
 			n, err := stdout.Read(buf)
@@ -242,7 +264,11 @@ func (srv *MuxTerminalService) Listen(req *api.ListenTerminalRequest, resp api.T
 				errchan <- err
 				return
 			}
-			messages <- &api.ListenTerminalResponse{Output: &api.ListenTerminalResponse_Data{Data: buf[:n]}}
+			now := time.Now()
+			if now.Before(since) {
+				continue
+			}
+			messages <- &api.ListenTerminalResponse{Output: &api.ListenTerminalResponse_Data{Data: buf[:n]}, Time: timestamppb.New(now)}
 		}
 
 		state, err := term.Wait()
@@ -294,6 +320,24 @@ func (srv *MuxTerminalService) Listen(req *api.ListenTerminalRequest, resp api.T
 	}
 }
 
+// recordedOutputMessages splits recorded terminal output into messages of at most 4096 bytes.
+func recordedOutputMessages(chunk RecordedChunk) []*api.ListenTerminalResponse {
+	var (
+		res  []*api.ListenTerminalResponse
+		ts   = timestamppb.New(chunk.Time)
+		data = chunk.Data
+	)
+	for len(data) > 0 {
+		n := len(data)
+		if n > 4096 {
+			n = 4096
+		}
+		res = append(res, &api.ListenTerminalResponse{Output: &api.ListenTerminalResponse_Data{Data: data[:n]}, Time: ts})
+		data = data[n:]
+	}
+	return res
+}
+
 // Write writes to a terminal.
 func (srv *MuxTerminalService) Write(ctx context.Context, req *api.WriteTerminalRequest) (*api.WriteTerminalResponse, error) {
 	srv.Mux.mu.RLock()
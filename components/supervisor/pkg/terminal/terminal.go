@@ -435,6 +435,32 @@ func (mw *multiWriter) ListenWithOptions(options TermListenOptions) io.ReadClose
 	mw.mu.Lock()
 	defer mw.mu.Unlock()
 
+	return mw.listen(options, mw.recorder.Bytes())
+}
+
+// ListenWithHistory listens in on the multi-writer stream with given options. Other than ListenWithOptions
+// the recorded output is not written to the listener, but returned together with the time it was written.
+// If since is not zero, only output written at or after since is returned.
+func (mw *multiWriter) ListenWithHistory(options TermListenOptions, since time.Time) ([]RecordedChunk, io.ReadCloser) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	return mw.recorder.Chunks(since), mw.listen(options, nil)
+}
+
+// History returns the recorded output together with the time it was written.
+// If since is not zero, only output written at or after since is returned.
+func (mw *multiWriter) History(since time.Time) []RecordedChunk {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	return mw.recorder.Chunks(since)
+}
+
+// listen adds a new listener which receives the recording first.
+//
+// Callers are expected to hold mu.
+func (mw *multiWriter) listen(options TermListenOptions, recording []byte) io.ReadCloser {
 	if mw.closed {
 		return closedListener
 	}
@@ -453,9 +479,10 @@ func (mw *multiWriter) ListenWithOptions(options TermListenOptions) io.ReadClose
 		timeout:   timeout,
 	}
 
-	recording := mw.recorder.Bytes()
 	go func() {
-		_, _ = w.Write(recording)
+		if len(recording) > 0 {
+			_, _ = w.Write(recording)
+		}
 
 		// copy bytes from channel to writer.
 		// Note: we close the writer independently of the write operation s.t. we don't
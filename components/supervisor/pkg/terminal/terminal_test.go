@@ -17,6 +17,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/gitpod-io/gitpod/supervisor/api"
 )
@@ -379,3 +380,69 @@ func TestWorkDirProvider(t *testing.T) {
 		expectedWorkDir: providedWorkDir,
 	})
 }
+
+func TestListenHistoryOnly(t *testing.T) {
+	mux := NewMux()
+	defer mux.Close()
+
+	alias, err := mux.Start(exec.Command("/bin/sh", "-c", "echo hello; sleep 10"), TermOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	term, ok := mux.Get(alias)
+	if !ok {
+		t.Fatal("terminal is not found")
+	}
+	start := time.Now()
+	for len(term.Stdout.History(time.Time{})) == 0 {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("terminal did not produce output")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	terminalService := NewMuxTerminalService(mux)
+	listener := &testHistoryListener{}
+	err = terminalService.Listen(&api.ListenTerminalRequest{Alias: alias, HistoryOnly: true}, listener)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var output string
+	for _, resp := range listener.resps {
+		data, ok := resp.Output.(*api.ListenTerminalResponse_Data)
+		if !ok {
+			t.Fatalf("unexpected output: %v", resp.Output)
+		}
+		if resp.Time == nil || resp.Time.AsTime().Before(start.Add(-5*time.Second)) {
+			t.Errorf("unexpected time: %v", resp.Time)
+		}
+		output += string(data.Data)
+	}
+	if diff := cmp.Diff("hello\r\n", output); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+
+	listener = &testHistoryListener{}
+	err = terminalService.Listen(&api.ListenTerminalRequest{Alias: alias, HistoryOnly: true, Since: timestamppb.New(time.Now().Add(time.Minute))}, listener)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listener.resps) != 0 {
+		t.Errorf("expected no output since a future time, got %d messages", len(listener.resps))
+	}
+}
+
+type testHistoryListener struct {
+	resps []*api.ListenTerminalResponse
+	grpc.ServerStream
+}
+
+func (listener *testHistoryListener) Send(resp *api.ListenTerminalResponse) error {
+	listener.resps = append(listener.resps, resp)
+	return nil
+}
+
+func (listener *testHistoryListener) Context() context.Context {
+	return context.Background()
+}
@@ -111,6 +111,8 @@ type Configuration struct {
 	WorkspaceClusterHost string `json:"workspaceClusterHost"`
 	// WorkspaceClasses provide different resource classes for workspaces
 	WorkspaceClasses map[string]*WorkspaceClass `json:"workspaceClass"`
+	// ImagePrefetch configures the warm-up of registry-facade caches with the most frequently started workspace images
+	ImagePrefetch *ImagePrefetchConfiguration `json:"imagePrefetch,omitempty"`
 }
 
 type WorkspaceClass struct {
@@ -181,6 +183,26 @@ type WorkspaceDaemonConfiguration struct {
 	} `json:"tls"`
 }
 
+// ImagePrefetchConfiguration configures the warm-up of registry-facade caches on all nodes
+type ImagePrefetchConfiguration struct {
+	Enabled bool `json:"enabled"`
+	// TopN is the number of most frequently started image specs which are prefetched
+	TopN int `json:"topN"`
+	// Interval is the time between two prefetch rounds. Start counts decay by half every round.
+	Interval util.Duration `json:"interval"`
+	// Port is the port on which registry-facade serves its Prefetcher service
+	Port int `json:"port"`
+	// TLS is the certificate/key config to connect to registry-facade
+	TLS struct {
+		// Authority is the root certificate that was used to sign the certificate itself
+		Authority string `json:"ca"`
+		// Certificate is the crt file, the actual certificate
+		Certificate string `json:"crt"`
+		// PrivateKey is the private key in order to use the certificate
+		PrivateKey string `json:"key"`
+	} `json:"tls"`
+}
+
 // Validate validates the configuration to catch issues during startup and not at runtime
 func (c *Configuration) Validate() error {
 	err := validation.ValidateStruct(&c.Timeouts,
@@ -211,6 +233,17 @@ func (c *Configuration) Validate() error {
 		return err
 	}
 
+	if c.ImagePrefetch != nil && c.ImagePrefetch.Enabled {
+		err = validation.ValidateStruct(c.ImagePrefetch,
+			validation.Field(&c.ImagePrefetch.TopN, validation.Required, validation.Min(1)),
+			validation.Field(&c.ImagePrefetch.Interval, validation.Required),
+			validation.Field(&c.ImagePrefetch.Port, validation.Required),
+		)
+		if err != nil {
+			return xerrors.Errorf("imagePrefetch: %w", err)
+		}
+	}
+
 	for name, class := range c.WorkspaceClasses {
 		if err := class.Container.Validate(); err != nil {
 			return xerrors.Errorf("workspace class %s: %w", name, err)
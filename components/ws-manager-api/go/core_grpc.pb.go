@@ -48,6 +48,8 @@ type WorkspaceManagerClient interface {
 	TakeSnapshot(ctx context.Context, in *TakeSnapshotRequest, opts ...grpc.CallOption) (*TakeSnapshotResponse, error)
 	// controlAdmission makes a workspace accessible for everyone or for the owner only
 	ControlAdmission(ctx context.Context, in *ControlAdmissionRequest, opts ...grpc.CallOption) (*ControlAdmissionResponse, error)
+	// reportTraffic adds the traffic ws-proxy served since its last report to the traffic counters of workspaces
+	ReportTraffic(ctx context.Context, in *ReportTrafficRequest, opts ...grpc.CallOption) (*ReportTrafficResponse, error)
 }
 
 type workspaceManagerClient struct {
@@ -180,6 +182,15 @@ func (c *workspaceManagerClient) ControlAdmission(ctx context.Context, in *Contr
 	return out, nil
 }
 
+func (c *workspaceManagerClient) ReportTraffic(ctx context.Context, in *ReportTrafficRequest, opts ...grpc.CallOption) (*ReportTrafficResponse, error) {
+	out := new(ReportTrafficResponse)
+	err := c.cc.Invoke(ctx, "/wsman.WorkspaceManager/ReportTraffic", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // WorkspaceManagerServer is the server API for WorkspaceManager service.
 // All implementations must embed UnimplementedWorkspaceManagerServer
 // for forward compatibility
@@ -206,6 +217,8 @@ type WorkspaceManagerServer interface {
 	TakeSnapshot(context.Context, *TakeSnapshotRequest) (*TakeSnapshotResponse, error)
 	// controlAdmission makes a workspace accessible for everyone or for the owner only
 	ControlAdmission(context.Context, *ControlAdmissionRequest) (*ControlAdmissionResponse, error)
+	// reportTraffic adds the traffic ws-proxy served since its last report to the traffic counters of workspaces
+	ReportTraffic(context.Context, *ReportTrafficRequest) (*ReportTrafficResponse, error)
 	mustEmbedUnimplementedWorkspaceManagerServer()
 }
 
@@ -246,6 +259,9 @@ func (UnimplementedWorkspaceManagerServer) TakeSnapshot(context.Context, *TakeSn
 func (UnimplementedWorkspaceManagerServer) ControlAdmission(context.Context, *ControlAdmissionRequest) (*ControlAdmissionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ControlAdmission not implemented")
 }
+func (UnimplementedWorkspaceManagerServer) ReportTraffic(context.Context, *ReportTrafficRequest) (*ReportTrafficResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportTraffic not implemented")
+}
 func (UnimplementedWorkspaceManagerServer) mustEmbedUnimplementedWorkspaceManagerServer() {}
 
 // UnsafeWorkspaceManagerServer may be embedded to opt out of forward compatibility for this service.
@@ -460,6 +476,24 @@ func _WorkspaceManager_ControlAdmission_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _WorkspaceManager_ReportTraffic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportTrafficRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WorkspaceManagerServer).ReportTraffic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wsman.WorkspaceManager/ReportTraffic",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WorkspaceManagerServer).ReportTraffic(ctx, req.(*ReportTrafficRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // WorkspaceManager_ServiceDesc is the grpc.ServiceDesc for WorkspaceManager service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -507,6 +541,10 @@ var WorkspaceManager_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ControlAdmission",
 			Handler:    _WorkspaceManager_ControlAdmission_Handler,
 		},
+		{
+			MethodName: "ReportTraffic",
+			Handler:    _WorkspaceManager_ReportTraffic_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
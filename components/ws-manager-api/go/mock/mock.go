@@ -250,6 +250,21 @@ func (mr *MockWorkspaceManagerServerMockRecorder) MarkActive(arg0, arg1 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkActive", reflect.TypeOf((*MockWorkspaceManagerServer)(nil).MarkActive), arg0, arg1)
 }
 
+// ReportTraffic mocks base method.
+func (m *MockWorkspaceManagerServer) ReportTraffic(arg0 context.Context, arg1 *api.ReportTrafficRequest) (*api.ReportTrafficResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReportTraffic", arg0, arg1)
+	ret0, _ := ret[0].(*api.ReportTrafficResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReportTraffic indicates an expected call of ReportTraffic.
+func (mr *MockWorkspaceManagerServerMockRecorder) ReportTraffic(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportTraffic", reflect.TypeOf((*MockWorkspaceManagerServer)(nil).ReportTraffic), arg0, arg1)
+}
+
 // SetTimeout mocks base method.
 func (m *MockWorkspaceManagerServer) SetTimeout(arg0 context.Context, arg1 *api.SetTimeoutRequest) (*api.SetTimeoutResponse, error) {
 	m.ctrl.T.Helper()
@@ -602,6 +617,26 @@ func (mr *MockWorkspaceManagerClientMockRecorder) MarkActive(arg0, arg1 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkActive", reflect.TypeOf((*MockWorkspaceManagerClient)(nil).MarkActive), varargs...)
 }
 
+// ReportTraffic mocks base method.
+func (m *MockWorkspaceManagerClient) ReportTraffic(arg0 context.Context, arg1 *api.ReportTrafficRequest, arg2 ...grpc.CallOption) (*api.ReportTrafficResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ReportTraffic", varargs...)
+	ret0, _ := ret[0].(*api.ReportTrafficResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReportTraffic indicates an expected call of ReportTraffic.
+func (mr *MockWorkspaceManagerClientMockRecorder) ReportTraffic(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReportTraffic", reflect.TypeOf((*MockWorkspaceManagerClient)(nil).ReportTraffic), varargs...)
+}
+
 // SetTimeout mocks base method.
 func (m *MockWorkspaceManagerClient) SetTimeout(arg0 context.Context, arg1 *api.SetTimeoutRequest, arg2 ...grpc.CallOption) (*api.SetTimeoutResponse, error) {
 	m.ctrl.T.Helper()
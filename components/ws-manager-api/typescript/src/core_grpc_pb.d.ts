@@ -27,6 +27,7 @@ interface IWorkspaceManagerService extends grpc.ServiceDefinition<grpc.UntypedSe
     controlPort: IWorkspaceManagerService_IControlPort;
     takeSnapshot: IWorkspaceManagerService_ITakeSnapshot;
     controlAdmission: IWorkspaceManagerService_IControlAdmission;
+    reportTraffic: IWorkspaceManagerService_IReportTraffic;
 }
 
 interface IWorkspaceManagerService_IGetWorkspaces extends grpc.MethodDefinition<core_pb.GetWorkspacesRequest, core_pb.GetWorkspacesResponse> {
@@ -128,6 +129,15 @@ interface IWorkspaceManagerService_IControlAdmission extends grpc.MethodDefiniti
     responseSerialize: grpc.serialize<core_pb.ControlAdmissionResponse>;
     responseDeserialize: grpc.deserialize<core_pb.ControlAdmissionResponse>;
 }
+interface IWorkspaceManagerService_IReportTraffic extends grpc.MethodDefinition<core_pb.ReportTrafficRequest, core_pb.ReportTrafficResponse> {
+    path: "/wsman.WorkspaceManager/ReportTraffic";
+    requestStream: false;
+    responseStream: false;
+    requestSerialize: grpc.serialize<core_pb.ReportTrafficRequest>;
+    requestDeserialize: grpc.deserialize<core_pb.ReportTrafficRequest>;
+    responseSerialize: grpc.serialize<core_pb.ReportTrafficResponse>;
+    responseDeserialize: grpc.deserialize<core_pb.ReportTrafficResponse>;
+}
 
 export const WorkspaceManagerService: IWorkspaceManagerService;
 
@@ -143,6 +153,7 @@ export interface IWorkspaceManagerServer extends grpc.UntypedServiceImplementati
     controlPort: grpc.handleUnaryCall<core_pb.ControlPortRequest, core_pb.ControlPortResponse>;
     takeSnapshot: grpc.handleUnaryCall<core_pb.TakeSnapshotRequest, core_pb.TakeSnapshotResponse>;
     controlAdmission: grpc.handleUnaryCall<core_pb.ControlAdmissionRequest, core_pb.ControlAdmissionResponse>;
+    reportTraffic: grpc.handleUnaryCall<core_pb.ReportTrafficRequest, core_pb.ReportTrafficResponse>;
 }
 
 export interface IWorkspaceManagerClient {
@@ -178,6 +189,9 @@ export interface IWorkspaceManagerClient {
     controlAdmission(request: core_pb.ControlAdmissionRequest, callback: (error: grpc.ServiceError | null, response: core_pb.ControlAdmissionResponse) => void): grpc.ClientUnaryCall;
     controlAdmission(request: core_pb.ControlAdmissionRequest, metadata: grpc.Metadata, callback: (error: grpc.ServiceError | null, response: core_pb.ControlAdmissionResponse) => void): grpc.ClientUnaryCall;
     controlAdmission(request: core_pb.ControlAdmissionRequest, metadata: grpc.Metadata, options: Partial<grpc.CallOptions>, callback: (error: grpc.ServiceError | null, response: core_pb.ControlAdmissionResponse) => void): grpc.ClientUnaryCall;
+    reportTraffic(request: core_pb.ReportTrafficRequest, callback: (error: grpc.ServiceError | null, response: core_pb.ReportTrafficResponse) => void): grpc.ClientUnaryCall;
+    reportTraffic(request: core_pb.ReportTrafficRequest, metadata: grpc.Metadata, callback: (error: grpc.ServiceError | null, response: core_pb.ReportTrafficResponse) => void): grpc.ClientUnaryCall;
+    reportTraffic(request: core_pb.ReportTrafficRequest, metadata: grpc.Metadata, options: Partial<grpc.CallOptions>, callback: (error: grpc.ServiceError | null, response: core_pb.ReportTrafficResponse) => void): grpc.ClientUnaryCall;
 }
 
 export class WorkspaceManagerClient extends grpc.Client implements IWorkspaceManagerClient {
@@ -214,4 +228,7 @@ export class WorkspaceManagerClient extends grpc.Client implements IWorkspaceMan
     public controlAdmission(request: core_pb.ControlAdmissionRequest, callback: (error: grpc.ServiceError | null, response: core_pb.ControlAdmissionResponse) => void): grpc.ClientUnaryCall;
     public controlAdmission(request: core_pb.ControlAdmissionRequest, metadata: grpc.Metadata, callback: (error: grpc.ServiceError | null, response: core_pb.ControlAdmissionResponse) => void): grpc.ClientUnaryCall;
     public controlAdmission(request: core_pb.ControlAdmissionRequest, metadata: grpc.Metadata, options: Partial<grpc.CallOptions>, callback: (error: grpc.ServiceError | null, response: core_pb.ControlAdmissionResponse) => void): grpc.ClientUnaryCall;
+    public reportTraffic(request: core_pb.ReportTrafficRequest, callback: (error: grpc.ServiceError | null, response: core_pb.ReportTrafficResponse) => void): grpc.ClientUnaryCall;
+    public reportTraffic(request: core_pb.ReportTrafficRequest, metadata: grpc.Metadata, callback: (error: grpc.ServiceError | null, response: core_pb.ReportTrafficResponse) => void): grpc.ClientUnaryCall;
+    public reportTraffic(request: core_pb.ReportTrafficRequest, metadata: grpc.Metadata, options: Partial<grpc.CallOptions>, callback: (error: grpc.ServiceError | null, response: core_pb.ReportTrafficResponse) => void): grpc.ClientUnaryCall;
 }
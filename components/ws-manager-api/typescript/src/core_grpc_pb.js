@@ -144,6 +144,28 @@ function deserialize_wsman_MarkActiveResponse(buffer_arg) {
   return core_pb.MarkActiveResponse.deserializeBinary(new Uint8Array(buffer_arg));
 }
 
+function serialize_wsman_ReportTrafficRequest(arg) {
+  if (!(arg instanceof core_pb.ReportTrafficRequest)) {
+    throw new Error('Expected argument of type wsman.ReportTrafficRequest');
+  }
+  return Buffer.from(arg.serializeBinary());
+}
+
+function deserialize_wsman_ReportTrafficRequest(buffer_arg) {
+  return core_pb.ReportTrafficRequest.deserializeBinary(new Uint8Array(buffer_arg));
+}
+
+function serialize_wsman_ReportTrafficResponse(arg) {
+  if (!(arg instanceof core_pb.ReportTrafficResponse)) {
+    throw new Error('Expected argument of type wsman.ReportTrafficResponse');
+  }
+  return Buffer.from(arg.serializeBinary());
+}
+
+function deserialize_wsman_ReportTrafficResponse(buffer_arg) {
+  return core_pb.ReportTrafficResponse.deserializeBinary(new Uint8Array(buffer_arg));
+}
+
 function serialize_wsman_SetTimeoutRequest(arg) {
   if (!(arg instanceof core_pb.SetTimeoutRequest)) {
     throw new Error('Expected argument of type wsman.SetTimeoutRequest');
@@ -388,6 +410,18 @@ controlAdmission: {
     responseSerialize: serialize_wsman_ControlAdmissionResponse,
     responseDeserialize: deserialize_wsman_ControlAdmissionResponse,
   },
+  // reportTraffic adds the traffic ws-proxy served since its last report to the traffic counters of workspaces
+reportTraffic: {
+    path: '/wsman.WorkspaceManager/ReportTraffic',
+    requestStream: false,
+    responseStream: false,
+    requestType: core_pb.ReportTrafficRequest,
+    responseType: core_pb.ReportTrafficResponse,
+    requestSerialize: serialize_wsman_ReportTrafficRequest,
+    requestDeserialize: deserialize_wsman_ReportTrafficRequest,
+    responseSerialize: serialize_wsman_ReportTrafficResponse,
+    responseDeserialize: deserialize_wsman_ReportTrafficResponse,
+  },
 };
 
 exports.WorkspaceManagerClient = grpc.makeGenericClientConstructor(WorkspaceManagerService);
@@ -216,6 +216,10 @@ export class DescribeWorkspaceResponse extends jspb.Message {
     setStatus(value?: WorkspaceStatus): DescribeWorkspaceResponse;
     getLastactivity(): string;
     setLastactivity(value: string): DescribeWorkspaceResponse;
+    clearTrafficList(): void;
+    getTrafficList(): Array<PortTraffic>;
+    setTrafficList(value: Array<PortTraffic>): DescribeWorkspaceResponse;
+    addTraffic(value?: PortTraffic, index?: number): PortTraffic;
 
     serializeBinary(): Uint8Array;
     toObject(includeInstance?: boolean): DescribeWorkspaceResponse.AsObject;
@@ -231,6 +235,7 @@ export namespace DescribeWorkspaceResponse {
     export type AsObject = {
         status?: WorkspaceStatus.AsObject,
         lastactivity: string,
+        trafficList: Array<PortTraffic.AsObject>,
     }
 }
 
@@ -573,6 +578,10 @@ export class WorkspaceStatus extends jspb.Message {
     clearAuth(): void;
     getAuth(): WorkspaceAuthentication | undefined;
     setAuth(value?: WorkspaceAuthentication): WorkspaceStatus;
+    clearTrafficList(): void;
+    getTrafficList(): Array<PortTraffic>;
+    setTrafficList(value: Array<PortTraffic>): WorkspaceStatus;
+    addTraffic(value?: PortTraffic, index?: number): PortTraffic;
 
     serializeBinary(): Uint8Array;
     toObject(includeInstance?: boolean): WorkspaceStatus.AsObject;
@@ -596,6 +605,7 @@ export namespace WorkspaceStatus {
         repo?: content_service_api_initializer_pb.GitStatus.AsObject,
         runtime?: WorkspaceRuntimeInfo.AsObject,
         auth?: WorkspaceAuthentication.AsObject,
+        trafficList: Array<PortTraffic.AsObject>,
     }
 }
 
@@ -1000,6 +1010,99 @@ export namespace ExposedPorts {
     }
 }
 
+export class ReportTrafficRequest extends jspb.Message {
+    clearWorkspacesList(): void;
+    getWorkspacesList(): Array<WorkspaceTraffic>;
+    setWorkspacesList(value: Array<WorkspaceTraffic>): ReportTrafficRequest;
+    addWorkspaces(value?: WorkspaceTraffic, index?: number): WorkspaceTraffic;
+
+    serializeBinary(): Uint8Array;
+    toObject(includeInstance?: boolean): ReportTrafficRequest.AsObject;
+    static toObject(includeInstance: boolean, msg: ReportTrafficRequest): ReportTrafficRequest.AsObject;
+    static extensions: {[key: number]: jspb.ExtensionFieldInfo<jspb.Message>};
+    static extensionsBinary: {[key: number]: jspb.ExtensionFieldBinaryInfo<jspb.Message>};
+    static serializeBinaryToWriter(message: ReportTrafficRequest, writer: jspb.BinaryWriter): void;
+    static deserializeBinary(bytes: Uint8Array): ReportTrafficRequest;
+    static deserializeBinaryFromReader(message: ReportTrafficRequest, reader: jspb.BinaryReader): ReportTrafficRequest;
+}
+
+export namespace ReportTrafficRequest {
+    export type AsObject = {
+        workspacesList: Array<WorkspaceTraffic.AsObject>,
+    }
+}
+
+export class ReportTrafficResponse extends jspb.Message {
+
+    serializeBinary(): Uint8Array;
+    toObject(includeInstance?: boolean): ReportTrafficResponse.AsObject;
+    static toObject(includeInstance: boolean, msg: ReportTrafficResponse): ReportTrafficResponse.AsObject;
+    static extensions: {[key: number]: jspb.ExtensionFieldInfo<jspb.Message>};
+    static extensionsBinary: {[key: number]: jspb.ExtensionFieldBinaryInfo<jspb.Message>};
+    static serializeBinaryToWriter(message: ReportTrafficResponse, writer: jspb.BinaryWriter): void;
+    static deserializeBinary(bytes: Uint8Array): ReportTrafficResponse;
+    static deserializeBinaryFromReader(message: ReportTrafficResponse, reader: jspb.BinaryReader): ReportTrafficResponse;
+}
+
+export namespace ReportTrafficResponse {
+    export type AsObject = {
+    }
+}
+
+export class WorkspaceTraffic extends jspb.Message {
+    getId(): string;
+    setId(value: string): WorkspaceTraffic;
+    clearPortsList(): void;
+    getPortsList(): Array<PortTraffic>;
+    setPortsList(value: Array<PortTraffic>): WorkspaceTraffic;
+    addPorts(value?: PortTraffic, index?: number): PortTraffic;
+
+    serializeBinary(): Uint8Array;
+    toObject(includeInstance?: boolean): WorkspaceTraffic.AsObject;
+    static toObject(includeInstance: boolean, msg: WorkspaceTraffic): WorkspaceTraffic.AsObject;
+    static extensions: {[key: number]: jspb.ExtensionFieldInfo<jspb.Message>};
+    static extensionsBinary: {[key: number]: jspb.ExtensionFieldBinaryInfo<jspb.Message>};
+    static serializeBinaryToWriter(message: WorkspaceTraffic, writer: jspb.BinaryWriter): void;
+    static deserializeBinary(bytes: Uint8Array): WorkspaceTraffic;
+    static deserializeBinaryFromReader(message: WorkspaceTraffic, reader: jspb.BinaryReader): WorkspaceTraffic;
+}
+
+export namespace WorkspaceTraffic {
+    export type AsObject = {
+        id: string,
+        portsList: Array<PortTraffic.AsObject>,
+    }
+}
+
+export class PortTraffic extends jspb.Message {
+    getPort(): number;
+    setPort(value: number): PortTraffic;
+    getBytesIn(): number;
+    setBytesIn(value: number): PortTraffic;
+    getBytesOut(): number;
+    setBytesOut(value: number): PortTraffic;
+    getRequests(): number;
+    setRequests(value: number): PortTraffic;
+
+    serializeBinary(): Uint8Array;
+    toObject(includeInstance?: boolean): PortTraffic.AsObject;
+    static toObject(includeInstance: boolean, msg: PortTraffic): PortTraffic.AsObject;
+    static extensions: {[key: number]: jspb.ExtensionFieldInfo<jspb.Message>};
+    static extensionsBinary: {[key: number]: jspb.ExtensionFieldBinaryInfo<jspb.Message>};
+    static serializeBinaryToWriter(message: PortTraffic, writer: jspb.BinaryWriter): void;
+    static deserializeBinary(bytes: Uint8Array): PortTraffic;
+    static deserializeBinaryFromReader(message: PortTraffic, reader: jspb.BinaryReader): PortTraffic;
+}
+
+export namespace PortTraffic {
+    export type AsObject = {
+        port: number,
+        bytesIn: number,
+        bytesOut: number,
+        requests: number,
+    }
+}
+
 export enum StopWorkspacePolicy {
     NORMALLY = 0,
     IMMEDIATELY = 1,
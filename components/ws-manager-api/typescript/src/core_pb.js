@@ -45,7 +45,10 @@ goog.exportSymbol('proto.wsman.MarkActiveRequest', null, global);
 goog.exportSymbol('proto.wsman.MarkActiveResponse', null, global);
 goog.exportSymbol('proto.wsman.MetadataFilter', null, global);
 goog.exportSymbol('proto.wsman.PortSpec', null, global);
+goog.exportSymbol('proto.wsman.PortTraffic', null, global);
 goog.exportSymbol('proto.wsman.PortVisibility', null, global);
+goog.exportSymbol('proto.wsman.ReportTrafficRequest', null, global);
+goog.exportSymbol('proto.wsman.ReportTrafficResponse', null, global);
 goog.exportSymbol('proto.wsman.SetTimeoutRequest', null, global);
 goog.exportSymbol('proto.wsman.SetTimeoutResponse', null, global);
 goog.exportSymbol('proto.wsman.StartWorkspaceRequest', null, global);
@@ -67,6 +70,7 @@ goog.exportSymbol('proto.wsman.WorkspacePhase', null, global);
 goog.exportSymbol('proto.wsman.WorkspaceRuntimeInfo', null, global);
 goog.exportSymbol('proto.wsman.WorkspaceSpec', null, global);
 goog.exportSymbol('proto.wsman.WorkspaceStatus', null, global);
+goog.exportSymbol('proto.wsman.WorkspaceTraffic', null, global);
 goog.exportSymbol('proto.wsman.WorkspaceType', null, global);
 /**
  * Generated by JsPbCodeGenerator.
@@ -247,7 +251,7 @@ if (goog.DEBUG && !COMPILED) {
  * @constructor
  */
 proto.wsman.DescribeWorkspaceResponse = function(opt_data) {
-  jspb.Message.initialize(this, opt_data, 0, -1, null, null);
+  jspb.Message.initialize(this, opt_data, 0, -1, proto.wsman.DescribeWorkspaceResponse.repeatedFields_, null);
 };
 goog.inherits(proto.wsman.DescribeWorkspaceResponse, jspb.Message);
 if (goog.DEBUG && !COMPILED) {
@@ -562,7 +566,7 @@ if (goog.DEBUG && !COMPILED) {
  * @constructor
  */
 proto.wsman.WorkspaceStatus = function(opt_data) {
-  jspb.Message.initialize(this, opt_data, 0, -1, null, null);
+  jspb.Message.initialize(this, opt_data, 0, -1, proto.wsman.WorkspaceStatus.repeatedFields_, null);
 };
 goog.inherits(proto.wsman.WorkspaceStatus, jspb.Message);
 if (goog.DEBUG && !COMPILED) {
@@ -824,6 +828,90 @@ if (goog.DEBUG && !COMPILED) {
    */
   proto.wsman.ExposedPorts.displayName = 'proto.wsman.ExposedPorts';
 }
+/**
+ * Generated by JsPbCodeGenerator.
+ * @param {Array=} opt_data Optional initial data array, typically from a
+ * server response, or constructed directly in Javascript. The array is used
+ * in place and becomes part of the constructed object. It is not cloned.
+ * If no data is provided, the constructed object will be empty, but still
+ * valid.
+ * @extends {jspb.Message}
+ * @constructor
+ */
+proto.wsman.ReportTrafficRequest = function(opt_data) {
+  jspb.Message.initialize(this, opt_data, 0, -1, proto.wsman.ReportTrafficRequest.repeatedFields_, null);
+};
+goog.inherits(proto.wsman.ReportTrafficRequest, jspb.Message);
+if (goog.DEBUG && !COMPILED) {
+  /**
+   * @public
+   * @override
+   */
+  proto.wsman.ReportTrafficRequest.displayName = 'proto.wsman.ReportTrafficRequest';
+}
+/**
+ * Generated by JsPbCodeGenerator.
+ * @param {Array=} opt_data Optional initial data array, typically from a
+ * server response, or constructed directly in Javascript. The array is used
+ * in place and becomes part of the constructed object. It is not cloned.
+ * If no data is provided, the constructed object will be empty, but still
+ * valid.
+ * @extends {jspb.Message}
+ * @constructor
+ */
+proto.wsman.ReportTrafficResponse = function(opt_data) {
+  jspb.Message.initialize(this, opt_data, 0, -1, null, null);
+};
+goog.inherits(proto.wsman.ReportTrafficResponse, jspb.Message);
+if (goog.DEBUG && !COMPILED) {
+  /**
+   * @public
+   * @override
+   */
+  proto.wsman.ReportTrafficResponse.displayName = 'proto.wsman.ReportTrafficResponse';
+}
+/**
+ * Generated by JsPbCodeGenerator.
+ * @param {Array=} opt_data Optional initial data array, typically from a
+ * server response, or constructed directly in Javascript. The array is used
+ * in place and becomes part of the constructed object. It is not cloned.
+ * If no data is provided, the constructed object will be empty, but still
+ * valid.
+ * @extends {jspb.Message}
+ * @constructor
+ */
+proto.wsman.WorkspaceTraffic = function(opt_data) {
+  jspb.Message.initialize(this, opt_data, 0, -1, proto.wsman.WorkspaceTraffic.repeatedFields_, null);
+};
+goog.inherits(proto.wsman.WorkspaceTraffic, jspb.Message);
+if (goog.DEBUG && !COMPILED) {
+  /**
+   * @public
+   * @override
+   */
+  proto.wsman.WorkspaceTraffic.displayName = 'proto.wsman.WorkspaceTraffic';
+}
+/**
+ * Generated by JsPbCodeGenerator.
+ * @param {Array=} opt_data Optional initial data array, typically from a
+ * server response, or constructed directly in Javascript. The array is used
+ * in place and becomes part of the constructed object. It is not cloned.
+ * If no data is provided, the constructed object will be empty, but still
+ * valid.
+ * @extends {jspb.Message}
+ * @constructor
+ */
+proto.wsman.PortTraffic = function(opt_data) {
+  jspb.Message.initialize(this, opt_data, 0, -1, null, null);
+};
+goog.inherits(proto.wsman.PortTraffic, jspb.Message);
+if (goog.DEBUG && !COMPILED) {
+  /**
+   * @public
+   * @override
+   */
+  proto.wsman.PortTraffic.displayName = 'proto.wsman.PortTraffic';
+}
 
 
 
@@ -2172,6 +2260,13 @@ proto.wsman.DescribeWorkspaceRequest.prototype.setId = function(value) {
 
 
 
+/**
+ * List of repeated fields within this message type.
+ * @private {!Array<number>}
+ * @const
+ */
+proto.wsman.DescribeWorkspaceResponse.repeatedFields_ = [3];
+
 
 
 if (jspb.Message.GENERATE_TO_OBJECT) {
@@ -2204,7 +2299,9 @@ proto.wsman.DescribeWorkspaceResponse.prototype.toObject = function(opt_includeI
 proto.wsman.DescribeWorkspaceResponse.toObject = function(includeInstance, msg) {
   var f, obj = {
     status: (f = msg.getStatus()) && proto.wsman.WorkspaceStatus.toObject(includeInstance, f),
-    lastactivity: jspb.Message.getFieldWithDefault(msg, 2, "")
+    lastactivity: jspb.Message.getFieldWithDefault(msg, 2, ""),
+    trafficList: jspb.Message.toObjectList(msg.getTrafficList(),
+    proto.wsman.PortTraffic.toObject, includeInstance)
   };
 
   if (includeInstance) {
@@ -2250,6 +2347,11 @@ proto.wsman.DescribeWorkspaceResponse.deserializeBinaryFromReader = function(msg
       var value = /** @type {string} */ (reader.readString());
       msg.setLastactivity(value);
       break;
+    case 3:
+      var value = new proto.wsman.PortTraffic;
+      reader.readMessage(value,proto.wsman.PortTraffic.deserializeBinaryFromReader);
+      msg.addTraffic(value);
+      break;
     default:
       reader.skipField();
       break;
@@ -2294,6 +2396,14 @@ proto.wsman.DescribeWorkspaceResponse.serializeBinaryToWriter = function(message
       f
     );
   }
+  f = message.getTrafficList();
+  if (f.length > 0) {
+    writer.writeRepeatedMessage(
+      3,
+      f,
+      proto.wsman.PortTraffic.serializeBinaryToWriter
+    );
+  }
 };
 
 
@@ -2352,6 +2462,44 @@ proto.wsman.DescribeWorkspaceResponse.prototype.setLastactivity = function(value
 };
 
 
+/**
+ * repeated PortTraffic traffic = 3;
+ * @return {!Array<!proto.wsman.PortTraffic>}
+ */
+proto.wsman.DescribeWorkspaceResponse.prototype.getTrafficList = function() {
+  return /** @type{!Array<!proto.wsman.PortTraffic>} */ (
+    jspb.Message.getRepeatedWrapperField(this, proto.wsman.PortTraffic, 3));
+};
+
+
+/**
+ * @param {!Array<!proto.wsman.PortTraffic>} value
+ * @return {!proto.wsman.DescribeWorkspaceResponse} returns this
+*/
+proto.wsman.DescribeWorkspaceResponse.prototype.setTrafficList = function(value) {
+  return jspb.Message.setRepeatedWrapperField(this, 3, value);
+};
+
+
+/**
+ * @param {!proto.wsman.PortTraffic=} opt_value
+ * @param {number=} opt_index
+ * @return {!proto.wsman.PortTraffic}
+ */
+proto.wsman.DescribeWorkspaceResponse.prototype.addTraffic = function(opt_value, opt_index) {
+  return jspb.Message.addToRepeatedWrapperField(this, 3, opt_value, proto.wsman.PortTraffic, opt_index);
+};
+
+
+/**
+ * Clears the list making it empty but non-null.
+ * @return {!proto.wsman.DescribeWorkspaceResponse} returns this
+ */
+proto.wsman.DescribeWorkspaceResponse.prototype.clearTrafficList = function() {
+  return this.setTrafficList([]);
+};
+
+
 
 
 
@@ -4333,6 +4481,12 @@ proto.wsman.BackupWorkspaceResponse.prototype.setUrl = function(value) {
 
 
 
+/**
+ * List of repeated fields within this message type.
+ * @private {!Array<number>}
+ * @const
+ */
+proto.wsman.WorkspaceStatus.repeatedFields_ = [11];
 
 
 if (jspb.Message.GENERATE_TO_OBJECT) {
@@ -4373,7 +4527,9 @@ proto.wsman.WorkspaceStatus.toObject = function(includeInstance, msg) {
     message: jspb.Message.getFieldWithDefault(msg, 6, ""),
     repo: (f = msg.getRepo()) && content$service$api_initializer_pb.GitStatus.toObject(includeInstance, f),
     runtime: (f = msg.getRuntime()) && proto.wsman.WorkspaceRuntimeInfo.toObject(includeInstance, f),
-    auth: (f = msg.getAuth()) && proto.wsman.WorkspaceAuthentication.toObject(includeInstance, f)
+    auth: (f = msg.getAuth()) && proto.wsman.WorkspaceAuthentication.toObject(includeInstance, f),
+    trafficList: jspb.Message.toObjectList(msg.getTrafficList(),
+    proto.wsman.PortTraffic.toObject, includeInstance)
   };
 
   if (includeInstance) {
@@ -4456,6 +4612,11 @@ proto.wsman.WorkspaceStatus.deserializeBinaryFromReader = function(msg, reader)
       reader.readMessage(value,proto.wsman.WorkspaceAuthentication.deserializeBinaryFromReader);
       msg.setAuth(value);
       break;
+    case 11:
+      var value = new proto.wsman.PortTraffic;
+      reader.readMessage(value,proto.wsman.PortTraffic.deserializeBinaryFromReader);
+      msg.addTraffic(value);
+      break;
     default:
       reader.skipField();
       break;
@@ -4561,6 +4722,14 @@ proto.wsman.WorkspaceStatus.serializeBinaryToWriter = function(message, writer)
       proto.wsman.WorkspaceAuthentication.serializeBinaryToWriter
     );
   }
+  f = message.getTrafficList();
+  if (f.length > 0) {
+    writer.writeRepeatedMessage(
+      11,
+      f,
+      proto.wsman.PortTraffic.serializeBinaryToWriter
+    );
+  }
 };
 
 
@@ -4858,6 +5027,44 @@ proto.wsman.WorkspaceStatus.prototype.hasAuth = function() {
 };
 
 
+/**
+ * repeated PortTraffic traffic = 11;
+ * @return {!Array<!proto.wsman.PortTraffic>}
+ */
+proto.wsman.WorkspaceStatus.prototype.getTrafficList = function() {
+  return /** @type{!Array<!proto.wsman.PortTraffic>} */ (
+    jspb.Message.getRepeatedWrapperField(this, proto.wsman.PortTraffic, 11));
+};
+
+
+/**
+ * @param {!Array<!proto.wsman.PortTraffic>} value
+ * @return {!proto.wsman.WorkspaceStatus} returns this
+*/
+proto.wsman.WorkspaceStatus.prototype.setTrafficList = function(value) {
+  return jspb.Message.setRepeatedWrapperField(this, 11, value);
+};
+
+
+/**
+ * @param {!proto.wsman.PortTraffic=} opt_value
+ * @param {number=} opt_index
+ * @return {!proto.wsman.PortTraffic}
+ */
+proto.wsman.WorkspaceStatus.prototype.addTraffic = function(opt_value, opt_index) {
+  return jspb.Message.addToRepeatedWrapperField(this, 11, opt_value, proto.wsman.PortTraffic, opt_index);
+};
+
+
+/**
+ * Clears the list making it empty but non-null.
+ * @return {!proto.wsman.WorkspaceStatus} returns this
+ */
+proto.wsman.WorkspaceStatus.prototype.clearTrafficList = function() {
+  return this.setTrafficList([]);
+};
+
+
 
 
 
@@ -7962,6 +8169,675 @@ proto.wsman.ExposedPorts.prototype.clearPortsList = function() {
 };
 
 
+
+/**
+ * List of repeated fields within this message type.
+ * @private {!Array<number>}
+ * @const
+ */
+proto.wsman.ReportTrafficRequest.repeatedFields_ = [1];
+
+
+
+if (jspb.Message.GENERATE_TO_OBJECT) {
+/**
+ * Creates an object representation of this proto.
+ * Field names that are reserved in JavaScript and will be renamed to pb_name.
+ * Optional fields that are not set will be set to undefined.
+ * To access a reserved field use, foo.pb_<name>, eg, foo.pb_default.
+ * For the list of reserved names please see:
+ *     net/proto2/compiler/js/internal/generator.cc#kKeyword.
+ * @param {boolean=} opt_includeInstance Deprecated. whether to include the
+ *     JSPB instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @return {!Object}
+ */
+proto.wsman.ReportTrafficRequest.prototype.toObject = function(opt_includeInstance) {
+  return proto.wsman.ReportTrafficRequest.toObject(opt_includeInstance, this);
+};
+
+
+/**
+ * Static version of the {@see toObject} method.
+ * @param {boolean|undefined} includeInstance Deprecated. Whether to include
+ *     the JSPB instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @param {!proto.wsman.ReportTrafficRequest} msg The msg instance to transform.
+ * @return {!Object}
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.wsman.ReportTrafficRequest.toObject = function(includeInstance, msg) {
+  var f, obj = {
+    workspacesList: jspb.Message.toObjectList(msg.getWorkspacesList(),
+    proto.wsman.WorkspaceTraffic.toObject, includeInstance)
+  };
+
+  if (includeInstance) {
+    obj.$jspbMessageInstance = msg;
+  }
+  return obj;
+};
+}
+
+
+/**
+ * Deserializes binary data (in protobuf wire format).
+ * @param {jspb.ByteSource} bytes The bytes to deserialize.
+ * @return {!proto.wsman.ReportTrafficRequest}
+ */
+proto.wsman.ReportTrafficRequest.deserializeBinary = function(bytes) {
+  var reader = new jspb.BinaryReader(bytes);
+  var msg = new proto.wsman.ReportTrafficRequest;
+  return proto.wsman.ReportTrafficRequest.deserializeBinaryFromReader(msg, reader);
+};
+
+
+/**
+ * Deserializes binary data (in protobuf wire format) from the
+ * given reader into the given message object.
+ * @param {!proto.wsman.ReportTrafficRequest} msg The message object to deserialize into.
+ * @param {!jspb.BinaryReader} reader The BinaryReader to use.
+ * @return {!proto.wsman.ReportTrafficRequest}
+ */
+proto.wsman.ReportTrafficRequest.deserializeBinaryFromReader = function(msg, reader) {
+  while (reader.nextField()) {
+    if (reader.isEndGroup()) {
+      break;
+    }
+    var field = reader.getFieldNumber();
+    switch (field) {
+    case 1:
+      var value = new proto.wsman.WorkspaceTraffic;
+      reader.readMessage(value,proto.wsman.WorkspaceTraffic.deserializeBinaryFromReader);
+      msg.addWorkspaces(value);
+      break;
+    default:
+      reader.skipField();
+      break;
+    }
+  }
+  return msg;
+};
+
+
+/**
+ * Serializes the message to binary data (in protobuf wire format).
+ * @return {!Uint8Array}
+ */
+proto.wsman.ReportTrafficRequest.prototype.serializeBinary = function() {
+  var writer = new jspb.BinaryWriter();
+  proto.wsman.ReportTrafficRequest.serializeBinaryToWriter(this, writer);
+  return writer.getResultBuffer();
+};
+
+
+/**
+ * Serializes the given message to binary data (in protobuf wire
+ * format), writing to the given BinaryWriter.
+ * @param {!proto.wsman.ReportTrafficRequest} message
+ * @param {!jspb.BinaryWriter} writer
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.wsman.ReportTrafficRequest.serializeBinaryToWriter = function(message, writer) {
+  var f = undefined;
+  f = message.getWorkspacesList();
+  if (f.length > 0) {
+    writer.writeRepeatedMessage(
+      1,
+      f,
+      proto.wsman.WorkspaceTraffic.serializeBinaryToWriter
+    );
+  }
+};
+
+
+/**
+ * repeated WorkspaceTraffic workspaces = 1;
+ * @return {!Array<!proto.wsman.WorkspaceTraffic>}
+ */
+proto.wsman.ReportTrafficRequest.prototype.getWorkspacesList = function() {
+  return /** @type{!Array<!proto.wsman.WorkspaceTraffic>} */ (
+    jspb.Message.getRepeatedWrapperField(this, proto.wsman.WorkspaceTraffic, 1));
+};
+
+
+/**
+ * @param {!Array<!proto.wsman.WorkspaceTraffic>} value
+ * @return {!proto.wsman.ReportTrafficRequest} returns this
+*/
+proto.wsman.ReportTrafficRequest.prototype.setWorkspacesList = function(value) {
+  return jspb.Message.setRepeatedWrapperField(this, 1, value);
+};
+
+
+/**
+ * @param {!proto.wsman.WorkspaceTraffic=} opt_value
+ * @param {number=} opt_index
+ * @return {!proto.wsman.WorkspaceTraffic}
+ */
+proto.wsman.ReportTrafficRequest.prototype.addWorkspaces = function(opt_value, opt_index) {
+  return jspb.Message.addToRepeatedWrapperField(this, 1, opt_value, proto.wsman.WorkspaceTraffic, opt_index);
+};
+
+
+/**
+ * Clears the list making it empty but non-null.
+ * @return {!proto.wsman.ReportTrafficRequest} returns this
+ */
+proto.wsman.ReportTrafficRequest.prototype.clearWorkspacesList = function() {
+  return this.setWorkspacesList([]);
+};
+
+
+
+
+if (jspb.Message.GENERATE_TO_OBJECT) {
+/**
+ * Creates an object representation of this proto.
+ * Field names that are reserved in JavaScript and will be renamed to pb_name.
+ * Optional fields that are not set will be set to undefined.
+ * To access a reserved field use, foo.pb_<name>, eg, foo.pb_default.
+ * For the list of reserved names please see:
+ *     net/proto2/compiler/js/internal/generator.cc#kKeyword.
+ * @param {boolean=} opt_includeInstance Deprecated. whether to include the
+ *     JSPB instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @return {!Object}
+ */
+proto.wsman.ReportTrafficResponse.prototype.toObject = function(opt_includeInstance) {
+  return proto.wsman.ReportTrafficResponse.toObject(opt_includeInstance, this);
+};
+
+
+/**
+ * Static version of the {@see toObject} method.
+ * @param {boolean|undefined} includeInstance Deprecated. Whether to include
+ *     the JSPB instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @param {!proto.wsman.ReportTrafficResponse} msg The msg instance to transform.
+ * @return {!Object}
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.wsman.ReportTrafficResponse.toObject = function(includeInstance, msg) {
+  var f, obj = {
+
+  };
+
+  if (includeInstance) {
+    obj.$jspbMessageInstance = msg;
+  }
+  return obj;
+};
+}
+
+
+/**
+ * Deserializes binary data (in protobuf wire format).
+ * @param {jspb.ByteSource} bytes The bytes to deserialize.
+ * @return {!proto.wsman.ReportTrafficResponse}
+ */
+proto.wsman.ReportTrafficResponse.deserializeBinary = function(bytes) {
+  var reader = new jspb.BinaryReader(bytes);
+  var msg = new proto.wsman.ReportTrafficResponse;
+  return proto.wsman.ReportTrafficResponse.deserializeBinaryFromReader(msg, reader);
+};
+
+
+/**
+ * Deserializes binary data (in protobuf wire format) from the
+ * given reader into the given message object.
+ * @param {!proto.wsman.ReportTrafficResponse} msg The message object to deserialize into.
+ * @param {!jspb.BinaryReader} reader The BinaryReader to use.
+ * @return {!proto.wsman.ReportTrafficResponse}
+ */
+proto.wsman.ReportTrafficResponse.deserializeBinaryFromReader = function(msg, reader) {
+  while (reader.nextField()) {
+    if (reader.isEndGroup()) {
+      break;
+    }
+    var field = reader.getFieldNumber();
+    switch (field) {
+    default:
+      reader.skipField();
+      break;
+    }
+  }
+  return msg;
+};
+
+
+/**
+ * Serializes the message to binary data (in protobuf wire format).
+ * @return {!Uint8Array}
+ */
+proto.wsman.ReportTrafficResponse.prototype.serializeBinary = function() {
+  var writer = new jspb.BinaryWriter();
+  proto.wsman.ReportTrafficResponse.serializeBinaryToWriter(this, writer);
+  return writer.getResultBuffer();
+};
+
+
+/**
+ * Serializes the given message to binary data (in protobuf wire
+ * format), writing to the given BinaryWriter.
+ * @param {!proto.wsman.ReportTrafficResponse} message
+ * @param {!jspb.BinaryWriter} writer
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.wsman.ReportTrafficResponse.serializeBinaryToWriter = function(message, writer) {
+  var f = undefined;
+};
+
+
+
+/**
+ * List of repeated fields within this message type.
+ * @private {!Array<number>}
+ * @const
+ */
+proto.wsman.WorkspaceTraffic.repeatedFields_ = [2];
+
+
+
+if (jspb.Message.GENERATE_TO_OBJECT) {
+/**
+ * Creates an object representation of this proto.
+ * Field names that are reserved in JavaScript and will be renamed to pb_name.
+ * Optional fields that are not set will be set to undefined.
+ * To access a reserved field use, foo.pb_<name>, eg, foo.pb_default.
+ * For the list of reserved names please see:
+ *     net/proto2/compiler/js/internal/generator.cc#kKeyword.
+ * @param {boolean=} opt_includeInstance Deprecated. whether to include the
+ *     JSPB instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @return {!Object}
+ */
+proto.wsman.WorkspaceTraffic.prototype.toObject = function(opt_includeInstance) {
+  return proto.wsman.WorkspaceTraffic.toObject(opt_includeInstance, this);
+};
+
+
+/**
+ * Static version of the {@see toObject} method.
+ * @param {boolean|undefined} includeInstance Deprecated. Whether to include
+ *     the JSPB instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @param {!proto.wsman.WorkspaceTraffic} msg The msg instance to transform.
+ * @return {!Object}
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.wsman.WorkspaceTraffic.toObject = function(includeInstance, msg) {
+  var f, obj = {
+    id: jspb.Message.getFieldWithDefault(msg, 1, ""),
+    portsList: jspb.Message.toObjectList(msg.getPortsList(),
+    proto.wsman.PortTraffic.toObject, includeInstance)
+  };
+
+  if (includeInstance) {
+    obj.$jspbMessageInstance = msg;
+  }
+  return obj;
+};
+}
+
+
+/**
+ * Deserializes binary data (in protobuf wire format).
+ * @param {jspb.ByteSource} bytes The bytes to deserialize.
+ * @return {!proto.wsman.WorkspaceTraffic}
+ */
+proto.wsman.WorkspaceTraffic.deserializeBinary = function(bytes) {
+  var reader = new jspb.BinaryReader(bytes);
+  var msg = new proto.wsman.WorkspaceTraffic;
+  return proto.wsman.WorkspaceTraffic.deserializeBinaryFromReader(msg, reader);
+};
+
+
+/**
+ * Deserializes binary data (in protobuf wire format) from the
+ * given reader into the given message object.
+ * @param {!proto.wsman.WorkspaceTraffic} msg The message object to deserialize into.
+ * @param {!jspb.BinaryReader} reader The BinaryReader to use.
+ * @return {!proto.wsman.WorkspaceTraffic}
+ */
+proto.wsman.WorkspaceTraffic.deserializeBinaryFromReader = function(msg, reader) {
+  while (reader.nextField()) {
+    if (reader.isEndGroup()) {
+      break;
+    }
+    var field = reader.getFieldNumber();
+    switch (field) {
+    case 1:
+      var value = /** @type {string} */ (reader.readString());
+      msg.setId(value);
+      break;
+    case 2:
+      var value = new proto.wsman.PortTraffic;
+      reader.readMessage(value,proto.wsman.PortTraffic.deserializeBinaryFromReader);
+      msg.addPorts(value);
+      break;
+    default:
+      reader.skipField();
+      break;
+    }
+  }
+  return msg;
+};
+
+
+/**
+ * Serializes the message to binary data (in protobuf wire format).
+ * @return {!Uint8Array}
+ */
+proto.wsman.WorkspaceTraffic.prototype.serializeBinary = function() {
+  var writer = new jspb.BinaryWriter();
+  proto.wsman.WorkspaceTraffic.serializeBinaryToWriter(this, writer);
+  return writer.getResultBuffer();
+};
+
+
+/**
+ * Serializes the given message to binary data (in protobuf wire
+ * format), writing to the given BinaryWriter.
+ * @param {!proto.wsman.WorkspaceTraffic} message
+ * @param {!jspb.BinaryWriter} writer
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.wsman.WorkspaceTraffic.serializeBinaryToWriter = function(message, writer) {
+  var f = undefined;
+  f = message.getId();
+  if (f.length > 0) {
+    writer.writeString(
+      1,
+      f
+    );
+  }
+  f = message.getPortsList();
+  if (f.length > 0) {
+    writer.writeRepeatedMessage(
+      2,
+      f,
+      proto.wsman.PortTraffic.serializeBinaryToWriter
+    );
+  }
+};
+
+
+/**
+ * optional string id = 1;
+ * @return {string}
+ */
+proto.wsman.WorkspaceTraffic.prototype.getId = function() {
+  return /** @type {string} */ (jspb.Message.getFieldWithDefault(this, 1, ""));
+};
+
+
+/**
+ * @param {string} value
+ * @return {!proto.wsman.WorkspaceTraffic} returns this
+ */
+proto.wsman.WorkspaceTraffic.prototype.setId = function(value) {
+  return jspb.Message.setProto3StringField(this, 1, value);
+};
+
+
+/**
+ * repeated PortTraffic ports = 2;
+ * @return {!Array<!proto.wsman.PortTraffic>}
+ */
+proto.wsman.WorkspaceTraffic.prototype.getPortsList = function() {
+  return /** @type{!Array<!proto.wsman.PortTraffic>} */ (
+    jspb.Message.getRepeatedWrapperField(this, proto.wsman.PortTraffic, 2));
+};
+
+
+/**
+ * @param {!Array<!proto.wsman.PortTraffic>} value
+ * @return {!proto.wsman.WorkspaceTraffic} returns this
+*/
+proto.wsman.WorkspaceTraffic.prototype.setPortsList = function(value) {
+  return jspb.Message.setRepeatedWrapperField(this, 2, value);
+};
+
+
+/**
+ * @param {!proto.wsman.PortTraffic=} opt_value
+ * @param {number=} opt_index
+ * @return {!proto.wsman.PortTraffic}
+ */
+proto.wsman.WorkspaceTraffic.prototype.addPorts = function(opt_value, opt_index) {
+  return jspb.Message.addToRepeatedWrapperField(this, 2, opt_value, proto.wsman.PortTraffic, opt_index);
+};
+
+
+/**
+ * Clears the list making it empty but non-null.
+ * @return {!proto.wsman.WorkspaceTraffic} returns this
+ */
+proto.wsman.WorkspaceTraffic.prototype.clearPortsList = function() {
+  return this.setPortsList([]);
+};
+
+
+
+
+if (jspb.Message.GENERATE_TO_OBJECT) {
+/**
+ * Creates an object representation of this proto.
+ * Field names that are reserved in JavaScript and will be renamed to pb_name.
+ * Optional fields that are not set will be set to undefined.
+ * To access a reserved field use, foo.pb_<name>, eg, foo.pb_default.
+ * For the list of reserved names please see:
+ *     net/proto2/compiler/js/internal/generator.cc#kKeyword.
+ * @param {boolean=} opt_includeInstance Deprecated. whether to include the
+ *     JSPB instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @return {!Object}
+ */
+proto.wsman.PortTraffic.prototype.toObject = function(opt_includeInstance) {
+  return proto.wsman.PortTraffic.toObject(opt_includeInstance, this);
+};
+
+
+/**
+ * Static version of the {@see toObject} method.
+ * @param {boolean|undefined} includeInstance Deprecated. Whether to include
+ *     the JSPB instance for transitional soy proto support:
+ *     http://goto/soy-param-migration
+ * @param {!proto.wsman.PortTraffic} msg The msg instance to transform.
+ * @return {!Object}
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.wsman.PortTraffic.toObject = function(includeInstance, msg) {
+  var f, obj = {
+    port: jspb.Message.getFieldWithDefault(msg, 1, 0),
+    bytesIn: jspb.Message.getFieldWithDefault(msg, 2, 0),
+    bytesOut: jspb.Message.getFieldWithDefault(msg, 3, 0),
+    requests: jspb.Message.getFieldWithDefault(msg, 4, 0)
+  };
+
+  if (includeInstance) {
+    obj.$jspbMessageInstance = msg;
+  }
+  return obj;
+};
+}
+
+
+/**
+ * Deserializes binary data (in protobuf wire format).
+ * @param {jspb.ByteSource} bytes The bytes to deserialize.
+ * @return {!proto.wsman.PortTraffic}
+ */
+proto.wsman.PortTraffic.deserializeBinary = function(bytes) {
+  var reader = new jspb.BinaryReader(bytes);
+  var msg = new proto.wsman.PortTraffic;
+  return proto.wsman.PortTraffic.deserializeBinaryFromReader(msg, reader);
+};
+
+
+/**
+ * Deserializes binary data (in protobuf wire format) from the
+ * given reader into the given message object.
+ * @param {!proto.wsman.PortTraffic} msg The message object to deserialize into.
+ * @param {!jspb.BinaryReader} reader The BinaryReader to use.
+ * @return {!proto.wsman.PortTraffic}
+ */
+proto.wsman.PortTraffic.deserializeBinaryFromReader = function(msg, reader) {
+  while (reader.nextField()) {
+    if (reader.isEndGroup()) {
+      break;
+    }
+    var field = reader.getFieldNumber();
+    switch (field) {
+    case 1:
+      var value = /** @type {number} */ (reader.readUint32());
+      msg.setPort(value);
+      break;
+    case 2:
+      var value = /** @type {number} */ (reader.readUint64());
+      msg.setBytesIn(value);
+      break;
+    case 3:
+      var value = /** @type {number} */ (reader.readUint64());
+      msg.setBytesOut(value);
+      break;
+    case 4:
+      var value = /** @type {number} */ (reader.readUint64());
+      msg.setRequests(value);
+      break;
+    default:
+      reader.skipField();
+      break;
+    }
+  }
+  return msg;
+};
+
+
+/**
+ * Serializes the message to binary data (in protobuf wire format).
+ * @return {!Uint8Array}
+ */
+proto.wsman.PortTraffic.prototype.serializeBinary = function() {
+  var writer = new jspb.BinaryWriter();
+  proto.wsman.PortTraffic.serializeBinaryToWriter(this, writer);
+  return writer.getResultBuffer();
+};
+
+
+/**
+ * Serializes the given message to binary data (in protobuf wire
+ * format), writing to the given BinaryWriter.
+ * @param {!proto.wsman.PortTraffic} message
+ * @param {!jspb.BinaryWriter} writer
+ * @suppress {unusedLocalVariables} f is only used for nested messages
+ */
+proto.wsman.PortTraffic.serializeBinaryToWriter = function(message, writer) {
+  var f = undefined;
+  f = message.getPort();
+  if (f !== 0) {
+    writer.writeUint32(
+      1,
+      f
+    );
+  }
+  f = message.getBytesIn();
+  if (f !== 0) {
+    writer.writeUint64(
+      2,
+      f
+    );
+  }
+  f = message.getBytesOut();
+  if (f !== 0) {
+    writer.writeUint64(
+      3,
+      f
+    );
+  }
+  f = message.getRequests();
+  if (f !== 0) {
+    writer.writeUint64(
+      4,
+      f
+    );
+  }
+};
+
+
+/**
+ * optional uint32 port = 1;
+ * @return {number}
+ */
+proto.wsman.PortTraffic.prototype.getPort = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 1, 0));
+};
+
+
+/**
+ * @param {number} value
+ * @return {!proto.wsman.PortTraffic} returns this
+ */
+proto.wsman.PortTraffic.prototype.setPort = function(value) {
+  return jspb.Message.setProto3IntField(this, 1, value);
+};
+
+
+/**
+ * optional uint64 bytes_in = 2;
+ * @return {number}
+ */
+proto.wsman.PortTraffic.prototype.getBytesIn = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 2, 0));
+};
+
+
+/**
+ * @param {number} value
+ * @return {!proto.wsman.PortTraffic} returns this
+ */
+proto.wsman.PortTraffic.prototype.setBytesIn = function(value) {
+  return jspb.Message.setProto3IntField(this, 2, value);
+};
+
+
+/**
+ * optional uint64 bytes_out = 3;
+ * @return {number}
+ */
+proto.wsman.PortTraffic.prototype.getBytesOut = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 3, 0));
+};
+
+
+/**
+ * @param {number} value
+ * @return {!proto.wsman.PortTraffic} returns this
+ */
+proto.wsman.PortTraffic.prototype.setBytesOut = function(value) {
+  return jspb.Message.setProto3IntField(this, 3, value);
+};
+
+
+/**
+ * optional uint64 requests = 4;
+ * @return {number}
+ */
+proto.wsman.PortTraffic.prototype.getRequests = function() {
+  return /** @type {number} */ (jspb.Message.getFieldWithDefault(this, 4, 0));
+};
+
+
+/**
+ * @param {number} value
+ * @return {!proto.wsman.PortTraffic} returns this
+ */
+proto.wsman.PortTraffic.prototype.setRequests = function(value) {
+  return jspb.Message.setProto3IntField(this, 4, value);
+};
+
+
 /**
  * @enum {number}
  */
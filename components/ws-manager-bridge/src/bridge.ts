@@ -11,6 +11,7 @@ import {
     WorkspaceInstance,
     Queue,
     WorkspaceInstancePort,
+    WorkspaceInstancePortTraffic,
     PortVisibility,
     RunningWorkspaceInfo,
     DisposableCollection,
@@ -22,6 +23,7 @@ import {
     WorkspaceConditionBool,
     PortVisibility as WsManPortVisibility,
     PromisifiedWorkspaceManagerClient,
+    PortTraffic,
 } from "@gitpod/ws-manager/lib";
 import { WorkspaceDB } from "@gitpod/gitpod-db/lib/workspace-db";
 import { UserDB } from "@gitpod/gitpod-db/lib/user-db";
@@ -252,6 +254,11 @@ export class WorkspaceManagerBridge implements Disposable {
                 });
             }
 
+            // ws-manager counts traffic in memory only, hence we accumulate the increase of its counters
+            if (status.trafficList.length > 0) {
+                instance.status.traffic = accumulateTraffic(instance.status.traffic, status.trafficList);
+            }
+
             if (!instance.status.conditions.firstUserActivity && status.conditions.firstUserActivity) {
                 // Only report this when it's observed the first time
                 const firstUserActivity = mapFirstUserActivity(rawStatus.getConditions()!.getFirstUserActivity())!;
@@ -512,6 +519,35 @@ const mapPortVisibility = (visibility: WsManPortVisibility | undefined): PortVis
     }
 };
 
+/**
+ * Adds the increase of the traffic counters ws-manager reported to the stored totals. Counters lower than
+ * the ones reported last mean that ws-manager started over, in which case they are added as a whole.
+ * Hence the totals never go down.
+ */
+const accumulateTraffic = (
+    stored: WorkspaceInstancePortTraffic[] | undefined,
+    reported: PortTraffic.AsObject[],
+): WorkspaceInstancePortTraffic[] => {
+    const res = new Map<number, WorkspaceInstancePortTraffic>();
+    for (const t of stored || []) {
+        res.set(t.port, { ...t });
+    }
+    for (const r of reported) {
+        const total = res.get(r.port) || { port: r.port, bytesIn: 0, bytesOut: 0, requests: 0 };
+        // totals stored before we kept the reported counters are the counters reported last
+        const last = total.reported || { bytesIn: total.bytesIn, bytesOut: total.bytesOut, requests: total.requests };
+        const reset = r.bytesIn < last.bytesIn || r.bytesOut < last.bytesOut || r.requests < last.requests;
+        const since = reset ? { bytesIn: 0, bytesOut: 0, requests: 0 } : last;
+
+        total.bytesIn += r.bytesIn - since.bytesIn;
+        total.bytesOut += r.bytesOut - since.bytesOut;
+        total.requests += r.requests - since.requests;
+        total.reported = { bytesIn: r.bytesIn, bytesOut: r.bytesOut, requests: r.requests };
+        res.set(r.port, total);
+    }
+    return Array.from(res.values()).sort((a, b) => a.port - b.port);
+};
+
 const durationLongerThanSeconds = (time: number, durationSeconds: number, now: number = Date.now()) => {
     return (now - time) / 1000 > durationSeconds;
 };
@@ -217,6 +217,28 @@ func mergeProbe(dst, src reflect.Value) (err error) {
 	return nil
 }
 
+// newImageSpec produces the registry-facade image spec for a workspace
+func newImageSpec(spec *api.StartWorkspaceSpec) *regapi.ImageSpec {
+	ideRef := spec.DeprecatedIdeImage
+	var desktopIdeRef string
+	if spec.IdeImage != nil && len(spec.IdeImage.WebRef) > 0 {
+		ideRef = spec.IdeImage.WebRef
+		desktopIdeRef = spec.IdeImage.DesktopRef
+	}
+
+	var supervisorRef string
+	if spec.IdeImage != nil && len(spec.IdeImage.SupervisorRef) > 0 {
+		supervisorRef = spec.IdeImage.SupervisorRef
+	}
+
+	return &regapi.ImageSpec{
+		BaseRef:       spec.WorkspaceImage,
+		IdeRef:        ideRef,
+		DesktopIdeRef: desktopIdeRef,
+		SupervisorRef: supervisorRef,
+	}
+}
+
 // createDefiniteWorkspacePod creates a workspace pod without regard for any template.
 // The result of this function can be deployed and it would work.
 func (m *Manager) createDefiniteWorkspacePod(startContext *startWorkspaceContext) (*corev1.Pod, error) {
@@ -241,24 +263,7 @@ func (m *Manager) createDefiniteWorkspacePod(startContext *startWorkspaceContext
 		labels[k] = v
 	}
 
-	ideRef := startContext.Request.Spec.DeprecatedIdeImage
-	var desktopIdeRef string
-	if startContext.Request.Spec.IdeImage != nil && len(startContext.Request.Spec.IdeImage.WebRef) > 0 {
-		ideRef = startContext.Request.Spec.IdeImage.WebRef
-		desktopIdeRef = startContext.Request.Spec.IdeImage.DesktopRef
-	}
-
-	var supervisorRef string
-	if startContext.Request.Spec.IdeImage != nil && len(startContext.Request.Spec.IdeImage.SupervisorRef) > 0 {
-		supervisorRef = startContext.Request.Spec.IdeImage.SupervisorRef
-	}
-
-	spec := regapi.ImageSpec{
-		BaseRef:       startContext.Request.Spec.WorkspaceImage,
-		IdeRef:        ideRef,
-		DesktopIdeRef: desktopIdeRef,
-		SupervisorRef: supervisorRef,
-	}
+	spec := newImageSpec(startContext.Request.Spec)
 	imageSpec, err := spec.ToBase64()
 	if err != nil {
 		return nil, xerrors.Errorf("cannot create remarshal image spec: %w", err)
@@ -633,6 +638,10 @@ func (m *Manager) createWorkspaceEnvironment(startContext *startWorkspaceContext
 	result = append(result, corev1.EnvVar{Name: "GITPOD_HOST", Value: m.Config.GitpodHostURL})
 	result = append(result, corev1.EnvVar{Name: "GITPOD_WORKSPACE_URL", Value: startContext.WorkspaceURL})
 	result = append(result, corev1.EnvVar{Name: "GITPOD_WORKSPACE_CLUSTER_HOST", Value: m.Config.WorkspaceClusterHost})
+	if spec.Class != "" {
+		result = append(result, corev1.EnvVar{Name: "GITPOD_WORKSPACE_CLASS", Value: spec.Class})
+	}
+	result = append(result, corev1.EnvVar{Name: "GITPOD_WORKSPACE_MAX_LIFETIME", Value: time.Duration(m.Config.Timeouts.MaxLifetime).String()})
 	result = append(result, corev1.EnvVar{Name: "THEIA_SUPERVISOR_ENDPOINT", Value: fmt.Sprintf(":%d", startContext.SupervisorPort)})
 	// TODO(ak) remove THEIA_WEBVIEW_EXTERNAL_ENDPOINT and THEIA_MINI_BROWSER_HOST_PATTERN when Theia is removed
 	result = append(result, corev1.EnvVar{Name: "THEIA_WEBVIEW_EXTERNAL_ENDPOINT", Value: "webview-{{hostname}}"})
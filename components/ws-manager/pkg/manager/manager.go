@@ -54,9 +54,11 @@ type Manager struct {
 	OnChange  func(context.Context, *api.WorkspaceStatus)
 
 	activity sync.Map
+	traffic  sync.Map
 	clock    *clock.HLC
 
 	wsdaemonPool *grpcpool.Pool
+	prefetcher   *imagePrefetcher
 
 	subscribers    map[string]chan *api.SubscribeResponse
 	subscriberLock sync.RWMutex
@@ -130,6 +132,13 @@ func New(config config.Configuration, client client.Client, rawClient kubernetes
 		subscribers:  make(map[string]chan *api.SubscribeResponse),
 		wsdaemonPool: grpcpool.New(wsdaemonConnfactory, checkWSDaemonEndpoint(config.Namespace, client)),
 	}
+	if config.ImagePrefetch != nil && config.ImagePrefetch.Enabled {
+		m.prefetcher, err = newImagePrefetcher(*config.ImagePrefetch, config.Namespace, client)
+		if err != nil {
+			return nil, err
+		}
+		go m.prefetcher.Run()
+	}
 	m.metrics = newMetrics(m)
 	m.OnChange = m.onChange
 	return m, nil
@@ -145,6 +154,7 @@ func Register(grpcServer *grpc.Server, manager *Manager) {
 // to function properly anymore.
 func (m *Manager) Close() {
 	m.wsdaemonPool.Close()
+	m.prefetcher.Close()
 }
 
 // StartWorkspace creates a new running workspace within the manager's cluster
@@ -317,6 +327,7 @@ func (m *Manager) StartWorkspace(ctx context.Context, req *api.StartWorkspaceReq
 	}
 
 	m.metrics.OnWorkspaceStarted(req.Type)
+	m.prefetcher.Record(newImageSpec(req.Spec))
 
 	return okResponse, nil
 }
@@ -765,6 +776,7 @@ func (m *Manager) DescribeWorkspace(ctx context.Context, req *api.DescribeWorksp
 	if lastActivity != nil {
 		result.LastActivity = lastActivity.UTC().Format(time.RFC3339Nano)
 	}
+	result.Traffic = m.getWorkspaceTraffic(req.Id)
 	return result, nil
 }
 
@@ -187,6 +187,9 @@ func (m *Monitor) onPodEvent(evt watch.Event) error {
 		}
 		m.probeMapLock.Unlock()
 
+		// The workspace is gone, and so is the need to keep its traffic counters around.
+		m.manager.traffic.Delete(status.Id)
+
 		// We're handling a pod event, thus Kubernetes gives us the pod we're handling. However, this is also a deleted
 		// event which means the pod doesn't actually exist anymore. We need to reflect that in our status compution, hence
 		// we change the deployed condition.
@@ -0,0 +1,219 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	common_grpc "github.com/gitpod-io/gitpod/common-go/grpc"
+	"github.com/gitpod-io/gitpod/common-go/log"
+	regapi "github.com/gitpod-io/gitpod/registry-facade/api"
+	config "github.com/gitpod-io/gitpod/ws-manager/api/config"
+)
+
+const (
+	// prefetchDecay is the factor by which start counts are multiplied after each prefetch round
+	prefetchDecay = 0.5
+	// prefetchMinScore is the score below which an image spec is forgotten
+	prefetchMinScore = 0.1
+)
+
+type prefetchCandidate struct {
+	Spec  *regapi.ImageSpec
+	Score float64
+}
+
+// imagePrefetcher keeps track of the most frequently started image specs and
+// periodically has all registry-facade instances prefetch them.
+type imagePrefetcher struct {
+	Config    config.ImagePrefetchConfiguration
+	Namespace string
+	Clientset client.Client
+
+	grpcOpts []grpc.DialOption
+
+	mu         sync.Mutex
+	candidates map[string]*prefetchCandidate
+
+	stop chan struct{}
+}
+
+func newImagePrefetcher(cfg config.ImagePrefetchConfiguration, namespace string, clientset client.Client) (*imagePrefetcher, error) {
+	grpcOpts := common_grpc.DefaultClientOptions()
+	if cfg.TLS.Authority != "" || cfg.TLS.Certificate != "" && cfg.TLS.PrivateKey != "" {
+		tlsConfig, err := common_grpc.ClientAuthTLSConfig(
+			cfg.TLS.Authority, cfg.TLS.Certificate, cfg.TLS.PrivateKey,
+			common_grpc.WithSetRootCAs(true),
+			common_grpc.WithServerName("registry-facade"),
+		)
+		if err != nil {
+			return nil, xerrors.Errorf("cannot load registry-facade certs: %w", err)
+		}
+
+		grpcOpts = append(grpcOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		grpcOpts = append(grpcOpts, grpc.WithInsecure())
+	}
+
+	return &imagePrefetcher{
+		Config:     cfg,
+		Namespace:  namespace,
+		Clientset:  clientset,
+		grpcOpts:   grpcOpts,
+		candidates: make(map[string]*prefetchCandidate),
+		stop:       make(chan struct{}),
+	}, nil
+}
+
+// Record counts a workspace start with the given image spec
+func (p *imagePrefetcher) Record(spec *regapi.ImageSpec) {
+	if p == nil || spec == nil || spec.BaseRef == "" {
+		return
+	}
+	key, err := spec.ToBase64()
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.candidates[key]
+	if !ok {
+		c = &prefetchCandidate{Spec: spec}
+		p.candidates[key] = c
+	}
+	c.Score++
+}
+
+// Top returns the n image specs with the highest score and decays all scores
+func (p *imagePrefetcher) Top(n int) []*regapi.ImageSpec {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	candidates := make([]*prefetchCandidate, 0, len(p.candidates))
+	for _, c := range p.candidates {
+		candidates = append(candidates, c)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	res := make([]*regapi.ImageSpec, len(candidates))
+	for i, c := range candidates {
+		res[i] = c.Spec
+	}
+
+	for k, c := range p.candidates {
+		c.Score *= prefetchDecay
+		if c.Score < prefetchMinScore {
+			delete(p.candidates, k)
+		}
+	}
+
+	return res
+}
+
+// Run periodically prefetches the most frequently started images until Close is called
+func (p *imagePrefetcher) Run() {
+	interval := time.Duration(p.Config.Interval)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-t.C:
+		}
+
+		specs := p.Top(p.Config.TopN)
+		if len(specs) == 0 {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := p.Prefetch(ctx, specs)
+		cancel()
+		if err != nil {
+			log.WithError(err).Warn("cannot prefetch workspace images")
+		}
+	}
+}
+
+// Prefetch has all registry-facade instances prefetch the given image specs
+func (p *imagePrefetcher) Prefetch(ctx context.Context, specs []*regapi.ImageSpec) error {
+	var podList corev1.PodList
+	err := p.Clientset.List(ctx, &podList,
+		&client.ListOptions{
+			Namespace: p.Namespace,
+			LabelSelector: labels.SelectorFromSet(labels.Set{
+				"component": "registry-facade",
+				"app":       "gitpod",
+			}),
+		},
+	)
+	if err != nil {
+		return xerrors.Errorf("cannot list registry-facade pods: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, pod := range podList.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(pod corev1.Pod) {
+			defer wg.Done()
+
+			err := p.prefetchOn(ctx, pod.Status.PodIP, specs)
+			if err != nil {
+				log.WithError(err).WithField("pod", pod.Name).WithField("node", pod.Spec.NodeName).Warn("cannot prefetch images on registry-facade")
+			}
+		}(pod)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (p *imagePrefetcher) prefetchOn(ctx context.Context, host string, specs []*regapi.ImageSpec) error {
+	addr := fmt.Sprintf("%s:%d", host, p.Config.Port)
+	conn, err := grpc.DialContext(ctx, addr, p.grpcOpts...)
+	if err != nil {
+		return xerrors.Errorf("cannot connect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	client := regapi.NewPrefetcherClient(conn)
+	for _, spec := range specs {
+		resp, err := client.PrefetchImage(ctx, &regapi.PrefetchImageRequest{Spec: spec})
+		if err != nil {
+			return xerrors.Errorf("cannot prefetch %s: %w", spec.BaseRef, err)
+		}
+		log.WithField("addr", addr).WithField("baseRef", spec.BaseRef).WithField("blobs", resp.Blobs).WithField("bytes", resp.Bytes).Debug("prefetched workspace image")
+	}
+	return nil
+}
+
+// Close stops the periodic prefetching
+func (p *imagePrefetcher) Close() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+}
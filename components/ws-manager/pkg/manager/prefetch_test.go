@@ -0,0 +1,61 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package manager
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	regapi "github.com/gitpod-io/gitpod/registry-facade/api"
+)
+
+func TestImagePrefetcherTop(t *testing.T) {
+	var (
+		full   = &regapi.ImageSpec{BaseRef: "gitpod/workspace-full", IdeRef: "ide"}
+		python = &regapi.ImageSpec{BaseRef: "gitpod/workspace-python", IdeRef: "ide"}
+		node   = &regapi.ImageSpec{BaseRef: "gitpod/workspace-node", IdeRef: "ide"}
+	)
+
+	p := &imagePrefetcher{candidates: make(map[string]*prefetchCandidate)}
+	for i := 0; i < 3; i++ {
+		p.Record(full)
+	}
+	for i := 0; i < 2; i++ {
+		p.Record(python)
+	}
+	p.Record(node)
+	p.Record(&regapi.ImageSpec{})
+
+	top := p.Top(2)
+	if diff := cmp.Diff([]string{full.BaseRef, python.BaseRef}, baseRefs(top)); diff != "" {
+		t.Errorf("unexpected top image specs (-want +got):\n%s", diff)
+	}
+
+	// after decaying the scores, recent starts outweigh old ones
+	for i := 0; i < 2; i++ {
+		p.Record(node)
+	}
+	top = p.Top(1)
+	if diff := cmp.Diff([]string{node.BaseRef}, baseRefs(top)); diff != "" {
+		t.Errorf("unexpected top image specs after decay (-want +got):\n%s", diff)
+	}
+
+	// unused specs are forgotten eventually
+	for i := 0; i < 10; i++ {
+		p.Top(1)
+	}
+	if len(p.candidates) != 0 {
+		t.Errorf("expected all candidates to be forgotten, %d remain", len(p.candidates))
+	}
+}
+
+func baseRefs(specs []*regapi.ImageSpec) []string {
+	res := make([]string, len(specs))
+	for i, s := range specs {
+		res[i] = s.BaseRef
+	}
+	return res
+}
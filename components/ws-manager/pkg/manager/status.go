@@ -266,6 +266,7 @@ func (m *Manager) getWorkspaceStatus(wso workspaceObjects) (*api.WorkspaceStatus
 	if err != nil {
 		return nil, xerrors.Errorf("cannot get workspace status: %w", err)
 	}
+	status.Traffic = m.getWorkspaceTraffic(id)
 
 	return status, nil
 }
@@ -510,6 +511,10 @@ func (m *Manager) extractStatusFromPod(result *api.WorkspaceStatus, wso workspac
 	return nil
 }
 
+// imageSignatureVerificationFailure is part of the error message registry-facade produces
+// when it refuses to serve an image whose signature cannot be verified.
+const imageSignatureVerificationFailure = "failed signature verification"
+
 // extractFailure returns a pod failure reason and possibly a phase. If phase is nil then
 // one should extract the phase themselves. If the pod has not failed, this function returns "", nil.
 func extractFailure(wso workspaceObjects) (string, *api.WorkspacePhase) {
@@ -543,6 +548,10 @@ func extractFailure(wso workspaceObjects) (string, *api.WorkspacePhase) {
 					c := api.WorkspacePhase_CREATING
 					res = &c
 				}
+				if strings.Contains(cs.State.Waiting.Message, imageSignatureVerificationFailure) {
+					// registry-facade refused to serve the image because it is not signed by a trusted key
+					return fmt.Sprintf("image signature verification failed: %s", cs.State.Waiting.Message), res
+				}
 				return fmt.Sprintf("cannot pull image: %s", cs.State.Waiting.Message), res
 			}
 		}
@@ -0,0 +1,110 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package manager
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+	"github.com/gitpod-io/gitpod/common-go/tracing"
+	"github.com/gitpod-io/gitpod/ws-manager/api"
+)
+
+// workspaceTraffic accumulates the traffic ws-proxy reported for a workspace instance
+type workspaceTraffic struct {
+	mu    sync.Mutex
+	ports map[uint32]*api.PortTraffic
+}
+
+// ReportTraffic adds the traffic ws-proxy served since its last report to the traffic counters of workspaces
+func (m *Manager) ReportTraffic(ctx context.Context, req *api.ReportTrafficRequest) (res *api.ReportTrafficResponse, err error) {
+	//nolint:ineffassign
+	span, ctx := tracing.FromContext(ctx, "ReportTraffic")
+	defer tracing.FinishSpan(span, &err)
+
+	// Only keep counters for workspaces which exist. The monitor drops the counters once a workspace pod is gone,
+	// hence reports for stopped workspaces must not bring them back. Terminating pods don't get new counters either.
+	var pods corev1.PodList
+	err = m.Clientset.List(ctx, &pods, workspaceObjectListOptions(m.Config.Namespace))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "cannot list workspaces: %q", err)
+	}
+	terminating := make(map[string]bool, len(pods.Items))
+	for _, pod := range pods.Items {
+		if wsid, ok := pod.Annotations[workspaceIDAnnotation]; ok {
+			terminating[wsid] = pod.DeletionTimestamp != nil
+		}
+	}
+
+	// Like the last activity, we do not keep the traffic as annotation on the workspace. ws-proxy reports
+	// regularly for all workspaces it serves and we don't want to place this load on the K8S master.
+	// Instead, the counters are part of the workspace status. They start over when ws-manager restarts, hence
+	// consumers of the status accumulate the increase of the counters rather than taking them as totals.
+	var rejected int
+	for _, ws := range req.Workspaces {
+		isTerminating, exists := terminating[ws.Id]
+		if !exists {
+			rejected++
+			continue
+		}
+
+		var entry interface{}
+		if isTerminating {
+			entry, exists = m.traffic.Load(ws.Id)
+		} else {
+			entry, _ = m.traffic.LoadOrStore(ws.Id, &workspaceTraffic{ports: make(map[uint32]*api.PortTraffic)})
+		}
+		if !exists {
+			rejected++
+			continue
+		}
+		wt := entry.(*workspaceTraffic)
+		wt.mu.Lock()
+		for _, p := range ws.Ports {
+			cnt, ok := wt.ports[p.Port]
+			if !ok {
+				cnt = &api.PortTraffic{Port: p.Port}
+				wt.ports[p.Port] = cnt
+			}
+			cnt.BytesIn += p.BytesIn
+			cnt.BytesOut += p.BytesOut
+			cnt.Requests += p.Requests
+		}
+		wt.mu.Unlock()
+	}
+	log.WithField("workspaces", len(req.Workspaces)).WithField("rejected", rejected).Debug("traffic reported")
+
+	return &api.ReportTrafficResponse{}, nil
+}
+
+// getWorkspaceTraffic returns the traffic counters of a workspace instance sorted by port
+func (m *Manager) getWorkspaceTraffic(instanceID string) []*api.PortTraffic {
+	entry, ok := m.traffic.Load(instanceID)
+	if !ok {
+		return nil
+	}
+
+	wt := entry.(*workspaceTraffic)
+	wt.mu.Lock()
+	res := make([]*api.PortTraffic, 0, len(wt.ports))
+	for _, p := range wt.ports {
+		res = append(res, &api.PortTraffic{
+			Port:     p.Port,
+			BytesIn:  p.BytesIn,
+			BytesOut: p.BytesOut,
+			Requests: p.Requests,
+		})
+	}
+	wt.mu.Unlock()
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Port < res[j].Port })
+	return res
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/gitpod-io/gitpod/ws-manager/api"
+)
+
+func TestReportTraffic(t *testing.T) {
+	workspacePod := func(id string, terminating bool) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:        "ws-" + id,
+			Namespace:   "default",
+			Labels:      map[string]string{markerLabel: "true"},
+			Annotations: map[string]string{workspaceIDAnnotation: id},
+		}}
+		if terminating {
+			now := metav1.NewTime(time.Now())
+			pod.DeletionTimestamp = &now
+			pod.Finalizers = []string{"gitpod.io/finalizer"}
+		}
+		return pod
+	}
+
+	m := &Manager{}
+	m.Config.Namespace = "default"
+	m.Clientset = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		workspacePod("foobar", false),
+		workspacePod("other", false),
+		workspacePod("stopping", true),
+	).Build()
+	// the traffic of a stopping workspace was reported before it started to stop
+	m.traffic.Store("stopping", &workspaceTraffic{ports: map[uint32]*api.PortTraffic{
+		3000: {Port: 3000, BytesIn: 1, BytesOut: 1, Requests: 1},
+	}})
+
+	reports := []*api.ReportTrafficRequest{
+		{Workspaces: []*api.WorkspaceTraffic{
+			{Id: "foobar", Ports: []*api.PortTraffic{
+				{Port: 3000, BytesIn: 100, BytesOut: 2000, Requests: 2},
+				{Port: 0, BytesIn: 10, BytesOut: 20, Requests: 1},
+			}},
+			{Id: "other", Ports: []*api.PortTraffic{
+				{Port: 8080, BytesIn: 1, BytesOut: 1, Requests: 1},
+			}},
+			{Id: "stopping", Ports: []*api.PortTraffic{
+				{Port: 3000, BytesIn: 1, BytesOut: 1, Requests: 1},
+			}},
+		}},
+		{Workspaces: []*api.WorkspaceTraffic{
+			{Id: "foobar", Ports: []*api.PortTraffic{
+				{Port: 3000, BytesIn: 50, BytesOut: 500, Requests: 1},
+			}},
+			{Ports: []*api.PortTraffic{
+				{Port: 3000, BytesIn: 50, BytesOut: 500, Requests: 1},
+			}},
+			{Id: "stopped", Ports: []*api.PortTraffic{
+				{Port: 3000, BytesIn: 50, BytesOut: 500, Requests: 1},
+			}},
+		}},
+	}
+	for _, r := range reports {
+		_, err := m.ReportTraffic(context.Background(), r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectation := []*api.PortTraffic{
+		{Port: 0, BytesIn: 10, BytesOut: 20, Requests: 1},
+		{Port: 3000, BytesIn: 150, BytesOut: 2500, Requests: 3},
+	}
+	if diff := cmp.Diff(expectation, m.getWorkspaceTraffic("foobar"), protocmp.Transform()); diff != "" {
+		t.Errorf("unexpected traffic (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]*api.PortTraffic{{Port: 3000, BytesIn: 2, BytesOut: 2, Requests: 2}}, m.getWorkspaceTraffic("stopping"), protocmp.Transform()); diff != "" {
+		t.Errorf("unexpected traffic of stopping workspace (-want +got):\n%s", diff)
+	}
+	for _, id := range []string{"", "stopped", "unknown"} {
+		if _, ok := m.traffic.Load(id); ok {
+			t.Errorf("expected no traffic counters for %q", id)
+		}
+	}
+
+	// reports for stopping workspaces must not create counters which are never removed
+	m.traffic.Delete("stopping")
+	_, err := m.ReportTraffic(context.Background(), reports[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.traffic.Load("stopping"); ok {
+		t.Error("expected no new traffic counters for stopping workspace")
+	}
+}
@@ -26,6 +26,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	runtime_client "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	common_grpc "github.com/gitpod-io/gitpod/common-go/grpc"
 	"github.com/gitpod-io/gitpod/common-go/log"
@@ -71,6 +72,9 @@ var runCmd = &cobra.Command{
 		}
 
 		workspaceInfoProvider := proxy.NewRemoteWorkspaceInfoProvider(mgr.GetClient(), mgr.GetScheme())
+		if cfg.Proxy.CustomDomains != nil {
+			workspaceInfoProvider.DomainVerifier = proxy.NewDNSDomainVerifier()
+		}
 		err = workspaceInfoProvider.SetupWithManager(mgr)
 		if err != nil {
 			log.WithError(err).Fatal(err, "unable to create controller", "controller", "Pod")
@@ -92,7 +96,10 @@ var runCmd = &cobra.Command{
 
 		log.Infof("workspace info provider started")
 
-		var heartbeat sshproxy.Heartbeat
+		var (
+			heartbeat   sshproxy.Heartbeat
+			wsmanClient wsmanapi.WorkspaceManagerClient
+		)
 		if wsm := cfg.WorkspaceManager; wsm != nil {
 			var dialOption grpc.DialOption = grpc.WithInsecure()
 			if wsm.TLS.CA != "" && wsm.TLS.Cert != "" && wsm.TLS.Key != "" {
@@ -116,8 +123,9 @@ var runCmd = &cobra.Command{
 				log.WithError(err).Fatal("cannot connect to ws-manager")
 			}
 
+			wsmanClient = wsmanapi.NewWorkspaceManagerClient(conn)
 			heartbeat = &sshproxy.WorkspaceManagerHeartbeat{
-				Client: wsmanapi.NewWorkspaceManagerClient(conn),
+				Client: wsmanClient,
 			}
 		}
 
@@ -141,6 +149,21 @@ var runCmd = &cobra.Command{
 			}
 			if len(signers) > 0 {
 				server := sshproxy.New(signers, workspaceInfoProvider, heartbeat)
+				if cfg.SSHGateway != nil {
+					server.RemoteForwardLimit = cfg.SSHGateway.RemoteForwardLimit
+					if cfg.SSHGateway.AuthorizedKeysDir != "" {
+						server.AuthorizedKeys = &sshproxy.FileAuthorizedKeysProvider{Dir: cfg.SSHGateway.AuthorizedKeysDir}
+					}
+					if audit := cfg.SSHGateway.Audit; audit != nil && audit.File != "" {
+						sink, err := sshproxy.NewFileAuditSink(audit.File)
+						if err != nil {
+							log.WithError(err).Fatal("cannot create SSH audit log")
+						}
+						server.Auditor = sink
+					} else if audit != nil && audit.WebhookURL != "" {
+						server.Auditor = sshproxy.NewWebhookAuditSink(audit.WebhookURL)
+					}
+				}
 				l, err := net.Listen("tcp", ":2200")
 				if err != nil {
 					panic(err)
@@ -150,7 +173,33 @@ var runCmd = &cobra.Command{
 			}
 		}
 
-		go proxy.NewWorkspaceProxy(cfg.Ingress, cfg.Proxy, proxy.HostBasedRouter(cfg.Ingress.Header, cfg.Proxy.GitpodInstallation.WorkspaceHostSuffix, cfg.Proxy.GitpodInstallation.WorkspaceHostSuffixRegex), workspaceInfoProvider, signers).MustServe()
+		trafficCtx, stopTraffic := context.WithCancel(context.Background())
+		defer stopTraffic()
+
+		wsproxy := proxy.NewWorkspaceProxy(cfg.Ingress, cfg.Proxy, proxy.HostBasedRouter(cfg.Ingress.Header, cfg.Proxy.GitpodInstallation.WorkspaceHostSuffix, cfg.Proxy.GitpodInstallation.WorkspaceHostSuffixRegex), workspaceInfoProvider, signers)
+		wsproxy.MetricsRegistry = metrics.Registry
+		if cfg.PrometheusAddr != "" {
+			err = mgr.AddMetricsExtraHandler("/debug/connections", wsproxy.Connections)
+			if err != nil {
+				log.WithError(err).Fatal("cannot register connections debug endpoint")
+			}
+		}
+		if traffic := cfg.Proxy.TrafficAccounting; traffic != nil {
+			var sink proxy.TrafficSink
+			if traffic.File != "" {
+				sink, err = proxy.NewFileTrafficSink(traffic.File)
+				if err != nil {
+					log.WithError(err).Fatal("cannot create traffic log")
+				}
+			} else if wsmanClient != nil {
+				sink = &proxy.WorkspaceManagerTrafficSink{Client: wsmanClient}
+			} else {
+				log.Fatal("traffic accounting needs either a file or ws-manager to report to")
+			}
+			wsproxy.Traffic = proxy.NewTrafficMeter(traffic, sink, workspaceInfoProvider)
+			go wsproxy.Traffic.Run(trafficCtx)
+		}
+		go wsproxy.MustServe()
 		log.Infof("started proxying on %s", cfg.Ingress.HTTPAddress)
 
 		log.Info("🚪 ws-proxy is up and running")
@@ -158,7 +207,15 @@ var runCmd = &cobra.Command{
 			log.WithError(err).Fatal(err, "problem starting ws-proxy")
 		}
 
-		log.Info("Received SIGINT - shutting down")
+		var drainPeriod time.Duration
+		if cfg.Proxy.ConnectionDrain != nil {
+			drainPeriod = time.Duration(cfg.Proxy.ConnectionDrain.Period)
+		}
+		log.WithField("drainPeriod", drainPeriod.String()).Info("Received SIGINT - shutting down")
+		stopTraffic()
+		ctx, cancel := context.WithTimeout(context.Background(), drainPeriod)
+		defer cancel()
+		wsproxy.Shutdown(ctx)
 	},
 }
 
@@ -0,0 +1,528 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+// Package acmetest provides a minimal ACME (RFC 8555) server for tests.
+// It validates http-01 challenges and issues certificates signed by its own CA.
+// JWS signatures and nonces are not verified, hence the server must never be used outside of tests.
+package acmetest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	statusPending = "pending"
+	statusReady   = "ready"
+	statusValid   = "valid"
+	statusInvalid = "invalid"
+)
+
+// Server is a minimal ACME server
+type Server struct {
+	// URL is the base URL of the server
+	URL string
+	// ChallengeAddr is the address (host:port) the server connects to when validating
+	// http-01 challenges. The challenged domain is sent as Host header.
+	ChallengeAddr string
+	// CACert is the certificate which signs all issued certificates
+	CACert *x509.Certificate
+	// Roots contains CACert
+	Roots *x509.CertPool
+
+	srv   *httptest.Server
+	caKey crypto.Signer
+
+	mu       sync.Mutex
+	nonce    int
+	accounts []*account
+	orders   []*order
+	authzs   []*authz
+}
+
+type account struct {
+	URL        string
+	Thumbprint string
+}
+
+type order struct {
+	Identifiers []identifier
+	Authzs      []int
+	Cert        []byte
+}
+
+type authz struct {
+	Identifier identifier
+	Account    *account
+	Token      string
+	Status     string
+}
+
+type identifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// NewServer starts a new ACME server. Callers must call Close when done.
+func NewServer() (*Server, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "acmetest CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, caKey.Public(), caKey)
+	if err != nil {
+		return nil, err
+	}
+	caCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	s := &Server{
+		CACert: caCert,
+		Roots:  roots,
+		caKey:  caKey,
+	}
+	s.srv = httptest.NewServer(s.handler())
+	s.URL = s.srv.URL
+	return s, nil
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// DirectoryURL is the URL ACME clients start from
+func (s *Server) DirectoryURL() string {
+	return s.URL + "/directory"
+}
+
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/directory", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   s.URL + "/new-nonce",
+			"newAccount": s.URL + "/new-account",
+			"newOrder":   s.URL + "/new-order",
+			"revokeCert": s.URL + "/revoke-cert",
+			"keyChange":  s.URL + "/key-change",
+		})
+	})
+	mux.HandleFunc("/new-nonce", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/new-account", s.post(s.handleNewAccount))
+	mux.HandleFunc("/new-order", s.post(s.handleNewOrder))
+	mux.HandleFunc("/order/", s.post(s.handleOrder))
+	mux.HandleFunc("/finalize/", s.post(s.handleFinalize))
+	mux.HandleFunc("/authz/", s.post(s.handleAuthz))
+	mux.HandleFunc("/challenge/", s.post(s.handleChallenge))
+	mux.HandleFunc("/cert/", s.post(s.handleCert))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.nonce++
+		nonce := strconv.Itoa(s.nonce)
+		s.mu.Unlock()
+		w.Header().Set("Replay-Nonce", nonce)
+		w.Header().Set("Cache-Control", "no-store")
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// jwsRequest is a decoded, but not verified, JWS request
+type jwsRequest struct {
+	JWK     json.RawMessage
+	KID     string
+	URL     string
+	Payload []byte
+	ID      int
+}
+
+type postHandler func(w http.ResponseWriter, r *http.Request, req *jwsRequest)
+
+func (s *Server) post(h postHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeProblem(w, http.StatusMethodNotAllowed, "malformed", "expected POST")
+			return
+		}
+
+		var body struct {
+			Protected string `json:"protected"`
+			Payload   string `json:"payload"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&body)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, "malformed", err.Error())
+			return
+		}
+		protected, err := base64.RawURLEncoding.DecodeString(body.Protected)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, "malformed", err.Error())
+			return
+		}
+		var req jwsRequest
+		var hdr struct {
+			JWK json.RawMessage `json:"jwk"`
+			KID string          `json:"kid"`
+			URL string          `json:"url"`
+		}
+		err = json.Unmarshal(protected, &hdr)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, "malformed", err.Error())
+			return
+		}
+		req.JWK, req.KID, req.URL = hdr.JWK, hdr.KID, hdr.URL
+		req.Payload, err = base64.RawURLEncoding.DecodeString(body.Payload)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, "malformed", err.Error())
+			return
+		}
+
+		if idx := strings.LastIndex(r.URL.Path, "/"); idx >= 0 {
+			if id, err := strconv.Atoi(r.URL.Path[idx+1:]); err == nil {
+				req.ID = id
+			}
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		h(w, r, &req)
+	}
+}
+
+func (s *Server) account(req *jwsRequest) *account {
+	for _, a := range s.accounts {
+		if a.URL == req.KID {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleNewAccount(w http.ResponseWriter, r *http.Request, req *jwsRequest) {
+	thumbprint, err := jwkThumbprint(req.JWK)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "badPublicKey", err.Error())
+		return
+	}
+	for _, a := range s.accounts {
+		if a.Thumbprint == thumbprint {
+			w.Header().Set("Location", a.URL)
+			writeJSON(w, http.StatusOK, map[string]string{"status": statusValid})
+			return
+		}
+	}
+
+	var payload struct {
+		OnlyReturnExisting bool `json:"onlyReturnExisting"`
+	}
+	_ = json.Unmarshal(req.Payload, &payload)
+	if payload.OnlyReturnExisting {
+		writeProblem(w, http.StatusBadRequest, "accountDoesNotExist", "no account for this key")
+		return
+	}
+
+	a := &account{
+		URL:        fmt.Sprintf("%s/account/%d", s.URL, len(s.accounts)),
+		Thumbprint: thumbprint,
+	}
+	s.accounts = append(s.accounts, a)
+	w.Header().Set("Location", a.URL)
+	writeJSON(w, http.StatusCreated, map[string]string{"status": statusValid})
+}
+
+func (s *Server) handleNewOrder(w http.ResponseWriter, r *http.Request, req *jwsRequest) {
+	acc := s.account(req)
+	if acc == nil {
+		writeProblem(w, http.StatusUnauthorized, "accountDoesNotExist", "unknown account")
+		return
+	}
+	var payload struct {
+		Identifiers []identifier `json:"identifiers"`
+	}
+	err := json.Unmarshal(req.Payload, &payload)
+	if err != nil || len(payload.Identifiers) == 0 {
+		writeProblem(w, http.StatusBadRequest, "malformed", "order without identifiers")
+		return
+	}
+
+	o := &order{Identifiers: payload.Identifiers}
+	for _, id := range payload.Identifiers {
+		token := make([]byte, 16)
+		_, _ = rand.Read(token)
+		s.authzs = append(s.authzs, &authz{
+			Identifier: id,
+			Account:    acc,
+			Token:      base64.RawURLEncoding.EncodeToString(token),
+			Status:     statusPending,
+		})
+		o.Authzs = append(o.Authzs, len(s.authzs)-1)
+	}
+	s.orders = append(s.orders, o)
+	s.writeOrder(w, http.StatusCreated, len(s.orders)-1)
+}
+
+func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request, req *jwsRequest) {
+	if req.ID >= len(s.orders) {
+		writeProblem(w, http.StatusNotFound, "malformed", "order not found")
+		return
+	}
+	s.writeOrder(w, http.StatusOK, req.ID)
+}
+
+func (s *Server) orderStatus(o *order) string {
+	if o.Cert != nil {
+		return statusValid
+	}
+	status := statusReady
+	for _, id := range o.Authzs {
+		switch s.authzs[id].Status {
+		case statusInvalid:
+			return statusInvalid
+		case statusPending:
+			status = statusPending
+		}
+	}
+	return status
+}
+
+func (s *Server) writeOrder(w http.ResponseWriter, code int, id int) {
+	o := s.orders[id]
+	res := map[string]interface{}{
+		"status":      s.orderStatus(o),
+		"identifiers": o.Identifiers,
+		"finalize":    fmt.Sprintf("%s/finalize/%d", s.URL, id),
+	}
+	var authzs []string
+	for _, z := range o.Authzs {
+		authzs = append(authzs, fmt.Sprintf("%s/authz/%d", s.URL, z))
+	}
+	res["authorizations"] = authzs
+	if o.Cert != nil {
+		res["certificate"] = fmt.Sprintf("%s/cert/%d", s.URL, id)
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/order/%d", s.URL, id))
+	writeJSON(w, code, res)
+}
+
+func (s *Server) handleAuthz(w http.ResponseWriter, r *http.Request, req *jwsRequest) {
+	if req.ID >= len(s.authzs) {
+		writeProblem(w, http.StatusNotFound, "malformed", "authorization not found")
+		return
+	}
+	z := s.authzs[req.ID]
+	if bytes.Contains(req.Payload, []byte(`"deactivated"`)) {
+		z.Status = "deactivated"
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":     z.Status,
+		"identifier": z.Identifier,
+		"challenges": []map[string]string{s.challenge(req.ID)},
+	})
+}
+
+func (s *Server) challenge(id int) map[string]string {
+	z := s.authzs[id]
+	status := z.Status
+	if status == "deactivated" {
+		status = statusInvalid
+	}
+	return map[string]string{
+		"type":   "http-01",
+		"url":    fmt.Sprintf("%s/challenge/%d", s.URL, id),
+		"token":  z.Token,
+		"status": status,
+	}
+}
+
+func (s *Server) handleChallenge(w http.ResponseWriter, r *http.Request, req *jwsRequest) {
+	if req.ID >= len(s.authzs) {
+		writeProblem(w, http.StatusNotFound, "malformed", "challenge not found")
+		return
+	}
+	z := s.authzs[req.ID]
+	if z.Status == statusPending {
+		if s.validate(z) {
+			z.Status = statusValid
+		} else {
+			z.Status = statusInvalid
+		}
+	}
+	writeJSON(w, http.StatusOK, s.challenge(req.ID))
+}
+
+// validate performs the http-01 validation of an authorization
+func (s *Server) validate(z *authz) bool {
+	hreq, err := http.NewRequest(http.MethodGet, "http://"+s.ChallengeAddr+"/.well-known/acme-challenge/"+z.Token, nil)
+	if err != nil {
+		return false
+	}
+	hreq.Host = z.Identifier.Value
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(hreq)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(body)) == z.Token+"."+z.Account.Thumbprint
+}
+
+func (s *Server) handleFinalize(w http.ResponseWriter, r *http.Request, req *jwsRequest) {
+	if req.ID >= len(s.orders) {
+		writeProblem(w, http.StatusNotFound, "malformed", "order not found")
+		return
+	}
+	o := s.orders[req.ID]
+	if s.orderStatus(o) != statusReady {
+		writeProblem(w, http.StatusForbidden, "orderNotReady", "order is not ready")
+		return
+	}
+
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	err := json.Unmarshal(req.Payload, &payload)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "malformed", err.Error())
+		return
+	}
+	der, err := base64.RawURLEncoding.DecodeString(payload.CSR)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "badCSR", err.Error())
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		writeProblem(w, http.StatusBadRequest, "badCSR", err.Error())
+		return
+	}
+	authorized := make(map[string]bool, len(o.Identifiers))
+	for _, id := range o.Identifiers {
+		authorized[id.Value] = true
+	}
+	for _, name := range csr.DNSNames {
+		if !authorized[name] {
+			writeProblem(w, http.StatusForbidden, "badCSR", "CSR contains unauthorized name "+name)
+			return
+		}
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, "serverInternal", err.Error())
+		return
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: csr.DNSNames[0]},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	crt, err := x509.CreateCertificate(rand.Reader, tmpl, s.CACert, csr.PublicKey, s.caKey)
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, "serverInternal", err.Error())
+		return
+	}
+	var chain bytes.Buffer
+	_ = pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: crt})
+	_ = pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: s.CACert.Raw})
+	o.Cert = chain.Bytes()
+
+	s.writeOrder(w, http.StatusOK, req.ID)
+}
+
+func (s *Server) handleCert(w http.ResponseWriter, r *http.Request, req *jwsRequest) {
+	if req.ID >= len(s.orders) || s.orders[req.ID].Cert == nil {
+		writeProblem(w, http.StatusNotFound, "malformed", "certificate not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(s.orders[req.ID].Cert)
+}
+
+// jwkThumbprint computes the RFC 7638 thumbprint of a JWK
+func jwkThumbprint(jwk json.RawMessage) (string, error) {
+	var k map[string]string
+	err := json.Unmarshal(jwk, &k)
+	if err != nil {
+		return "", err
+	}
+
+	var canonical string
+	switch k["kty"] {
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":"%s","kty":"EC","x":"%s","y":"%s"}`, k["crv"], k["x"], k["y"])
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":"%s","kty":"RSA","n":"%s"}`, k["e"], k["n"])
+	default:
+		return "", fmt.Errorf("unsupported key type %q", k["kty"])
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeProblem(w http.ResponseWriter, code int, typ, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"type":   "urn:ietf:params:acme:error:" + typ,
+		"detail": detail,
+	})
+}
@@ -22,6 +22,27 @@ type Config struct {
 	ReadinessProbeAddr string                       `json:"readinessProbeAddr"`
 	Namespace          string                       `json:"namespace"`
 	WorkspaceManager   *WorkspaceManagerConn        `json:"wsManager"`
+	SSHGateway         *SSHGatewayConfig            `json:"sshGateway,omitempty"`
+}
+
+// SSHGatewayConfig configures the SSH gateway.
+type SSHGatewayConfig struct {
+	// RemoteForwardLimit is the maximum number of ports a workspace can remote forward through the gateway.
+	// Zero uses the default limit, a negative value disables remote port forwarding.
+	RemoteForwardLimit int `json:"remoteForwardLimit,omitempty"`
+	// AuthorizedKeysDir contains a file per user with their registered SSH public keys in authorized_keys format.
+	// If set, users can authenticate with these keys instead of the owner token.
+	AuthorizedKeysDir string `json:"authorizedKeysDir,omitempty"`
+	// Audit configures where the audit events of SSH sessions are sent to
+	Audit *SSHAuditConfig `json:"audit,omitempty"`
+}
+
+// SSHAuditConfig configures the sink of SSH audit events. Exactly one of File and WebhookURL must be set.
+type SSHAuditConfig struct {
+	// File is the path of a file the events are appended to as JSON lines
+	File string `json:"file,omitempty"`
+	// WebhookURL is the URL events are posted to as JSON
+	WebhookURL string `json:"webhookURL,omitempty"`
 }
 
 type WorkspaceManagerConn struct {
@@ -43,6 +64,12 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if c.SSHGateway != nil && c.SSHGateway.Audit != nil {
+		if (c.SSHGateway.Audit.File == "") == (c.SSHGateway.Audit.WebhookURL == "") {
+			return xerrors.Errorf("sshGateway.audit: exactly one of file and webhookURL must be set")
+		}
+	}
+
 	return nil
 }
 
@@ -5,6 +5,7 @@
 package proxy
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -17,7 +18,8 @@ import (
 )
 
 // WorkspaceAuthHandler rejects requests which are not authenticated or authorized to access a workspace.
-func WorkspaceAuthHandler(domain string, info WorkspaceInfoProvider) mux.MiddlewareFunc {
+// Requests to protected public ports are subject to the port's protection unless they come from the owner.
+func WorkspaceAuthHandler(domain string, info WorkspaceInfoProvider, protector *PortProtector) mux.MiddlewareFunc {
 	return func(h http.Handler) http.Handler {
 		cookiePrefix := domain
 		for _, c := range []string{" ", "-", "."} {
@@ -74,6 +76,13 @@ func WorkspaceAuthHandler(domain string, info WorkspaceInfoProvider) mux.Middlew
 				}
 
 				if isPublic {
+					// protected ports are open to the owner and visitors who pass the protection
+					if prot := ws.PortProtection[uint32(prt)]; prot != nil && !isOwnerRequest(req, cookiePrefix, ws) {
+						if !protector.Handle(resp, req, ws, prot, cookiePrefix) {
+							return
+						}
+					}
+
 					// workspace port is free for all - no tokens or cookies matter
 					h.ServeHTTP(resp, req)
 
@@ -115,3 +124,24 @@ func WorkspaceAuthHandler(domain string, info WorkspaceInfoProvider) mux.Middlew
 		})
 	}
 }
+
+// isOwnerRequest returns true if the request carries the owner token of the workspace
+func isOwnerRequest(req *http.Request, cookiePrefix string, ws *WorkspaceInfo) bool {
+	if ws.Auth == nil || ws.Auth.OwnerToken == "" {
+		return false
+	}
+
+	tkn := req.Header.Get("x-gitpod-owner-token")
+	if tkn == "" {
+		c, err := req.Cookie(fmt.Sprintf("%s%s_owner_", cookiePrefix, ws.InstanceID))
+		if err != nil {
+			return false
+		}
+		tkn = c.Value
+	}
+	tkn, err := url.QueryUnescape(tkn)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(tkn), []byte(ws.Auth.OwnerToken)) == 1
+}
@@ -56,6 +56,18 @@ func TestWorkspaceAuthHandler(t *testing.T) {
 				Ports: []*api.PortSpec{{Port: testPort, Visibility: api.PortVisibility_PORT_VISIBILITY_PUBLIC}},
 			},
 		}
+		protectedPortInfos = map[string]*WorkspaceInfo{
+			workspaceID: {
+				WorkspaceID: workspaceID,
+				InstanceID:  instanceID,
+				Auth: &api.WorkspaceAuthentication{
+					Admission:  api.AdmissionLevel_ADMIT_OWNER_ONLY,
+					OwnerToken: ownerToken,
+				},
+				Ports:          []*api.PortSpec{{Port: testPort, Visibility: api.PortVisibility_PORT_VISIBILITY_PUBLIC}},
+				PortProtection: map[uint32]*PortProtection{testPort: {Port: testPort, PasswordHash: testPasswordHash}},
+			},
+		}
 		admitEveryoneInfos = map[string]*WorkspaceInfo{
 			workspaceID: {
 				WorkspaceID: workspaceID,
@@ -197,6 +209,27 @@ func TestWorkspaceAuthHandler(t *testing.T) {
 				StatusCode:    http.StatusOK,
 			},
 		},
+		{
+			Name:        "protected public port",
+			Infos:       protectedPortInfos,
+			WorkspaceID: workspaceID,
+			Port:        strconv.Itoa(testPort),
+			Expected: testResult{
+				HandlerCalled: false,
+				StatusCode:    http.StatusUnauthorized,
+			},
+		},
+		{
+			Name:        "protected public port with owner cookie",
+			Infos:       protectedPortInfos,
+			WorkspaceID: workspaceID,
+			Port:        strconv.Itoa(testPort),
+			OwnerCookie: ownerToken,
+			Expected: testResult{
+				HandlerCalled: true,
+				StatusCode:    http.StatusOK,
+			},
+		},
 		{
 			Name:        "broken port",
 			Infos:       publicPortInfos,
@@ -223,7 +256,7 @@ func TestWorkspaceAuthHandler(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
 			var res testResult
-			handler := WorkspaceAuthHandler(domain, &fixedInfoProvider{Infos: test.Infos})(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			handler := WorkspaceAuthHandler(domain, &fixedInfoProvider{Infos: test.Infos}, nil)(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 				res.HandlerCalled = true
 				resp.WriteHeader(http.StatusOK)
 			}))
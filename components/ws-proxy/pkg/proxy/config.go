@@ -28,6 +28,14 @@ type Config struct {
 	WorkspacePodConfig *WorkspacePodConfig `json:"workspacePodConfig"`
 
 	BuiltinPages BuiltinPagesConfig `json:"builtinPages"`
+
+	PortProtection    *PortProtectionConfig    `json:"portProtection,omitempty"`
+	Inspector         *InspectorConfig         `json:"inspector,omitempty"`
+	RateLimit         *RateLimitConfig         `json:"rateLimit,omitempty"`
+	CustomDomains     *CustomDomainsConfig     `json:"customDomains,omitempty"`
+	TCPPassthrough    *TCPPassthroughConfig    `json:"tcpPassthrough,omitempty"`
+	ConnectionDrain   *ConnectionDrainConfig   `json:"connectionDrain,omitempty"`
+	TrafficAccounting *TrafficAccountingConfig `json:"trafficAccounting,omitempty"`
 }
 
 // Validate validates the configuration to catch issues during startup and not at runtime.
@@ -40,6 +48,13 @@ func (c *Config) Validate() error {
 		c.BlobServer,
 		c.GitpodInstallation,
 		c.WorkspacePodConfig,
+		c.PortProtection,
+		c.Inspector,
+		c.RateLimit,
+		c.CustomDomains,
+		c.TCPPassthrough,
+		c.ConnectionDrain,
+		c.TrafficAccounting,
 	} {
 		err := v.Validate()
 		if err != nil {
@@ -0,0 +1,419 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+	"github.com/gitpod-io/gitpod/common-go/util"
+)
+
+const (
+	// longPollThreshold is the time after which a pending request is considered a long-poll
+	longPollThreshold = 10 * time.Second
+	// drainPollInterval is how often we check if all connections are gone while draining
+	drainPollInterval = 100 * time.Millisecond
+	// closeFrameWriteTimeout bounds the time we wait for a client to accept a close frame
+	closeFrameWriteTimeout = 5 * time.Second
+
+	// wsCloseServiceRestart is the websocket close code which tells clients to reconnect (RFC 6455, IANA registry)
+	wsCloseServiceRestart = 1012
+	wsCloseReason         = "ws-proxy is restarting, please reconnect"
+)
+
+// ConnectionDrainConfig configures how ws-proxy shuts down
+type ConnectionDrainConfig struct {
+	// Period is how long ws-proxy waits for connections to close after asking clients to reconnect
+	Period util.Duration `json:"period"`
+}
+
+// Validate validates the configuration to catch issues during startup and not at runtime.
+func (c *ConnectionDrainConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Period < 0 {
+		return xerrors.Errorf("connectionDrain.period must not be negative")
+	}
+	return nil
+}
+
+// ConnectionKind describes a long-lived connection
+type ConnectionKind string
+
+const (
+	// ConnectionWebsocket is an upgraded websocket connection
+	ConnectionWebsocket ConnectionKind = "websocket"
+	// ConnectionLongPoll is a request which has been pending for longPollThreshold or longer
+	ConnectionLongPoll ConnectionKind = "long-poll"
+)
+
+// ConnectionInfo describes a tracked connection
+type ConnectionInfo struct {
+	ID          uint64         `json:"id"`
+	Kind        ConnectionKind `json:"kind"`
+	WorkspaceID string         `json:"workspaceId"`
+	Port        string         `json:"port,omitempty"`
+	Path        string         `json:"path"`
+	RemoteAddr  string         `json:"remoteAddr"`
+	Since       time.Time      `json:"since"`
+}
+
+type trackedConnection struct {
+	ConnectionInfo
+
+	// conn is set once a websocket connection was hijacked
+	conn *wsConn
+}
+
+// ConnectionTracker keeps track of websockets and pending requests per workspace and
+// drains them when ws-proxy shuts down.
+type ConnectionTracker struct {
+	mu       sync.Mutex
+	conns    map[uint64]*trackedConnection
+	nextID   uint64
+	draining bool
+}
+
+// NewConnectionTracker creates a new connection tracker
+func NewConnectionTracker() *ConnectionTracker {
+	return &ConnectionTracker{
+		conns: make(map[uint64]*trackedConnection),
+	}
+}
+
+// Handler tracks requests while they are served. Once the tracker drains, new websocket
+// upgrades are rejected and clients are asked not to reuse their connection.
+func (t *ConnectionTracker) Handler(h http.Handler) http.Handler {
+	if t == nil {
+		return h
+	}
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		websocket := isWebsocketUpgrade(req)
+
+		t.mu.Lock()
+		if t.draining {
+			t.mu.Unlock()
+			resp.Header().Set("Connection", "close")
+			if websocket {
+				resp.Header().Set("Retry-After", "1")
+				http.Error(resp, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			h.ServeHTTP(resp, req)
+			return
+		}
+
+		coords := getWorkspaceCoords(req)
+		t.nextID++
+		tc := &trackedConnection{
+			ConnectionInfo: ConnectionInfo{
+				ID:          t.nextID,
+				Kind:        ConnectionLongPoll,
+				WorkspaceID: coords.ID,
+				Port:        coords.Port,
+				Path:        req.URL.Path,
+				RemoteAddr:  req.RemoteAddr,
+				Since:       time.Now(),
+			},
+		}
+		if websocket {
+			tc.Kind = ConnectionWebsocket
+		}
+		t.conns[tc.ID] = tc
+		t.mu.Unlock()
+
+		defer func() {
+			t.mu.Lock()
+			delete(t.conns, tc.ID)
+			t.mu.Unlock()
+		}()
+
+		if websocket {
+			resp = &trackingResponseWriter{ResponseWriter: resp, tracker: t, conn: tc}
+		}
+		h.ServeHTTP(resp, req)
+	})
+}
+
+// List returns the websockets and long-polls of a workspace, or of all workspaces if workspaceID is empty.
+func (t *ConnectionTracker) List(workspaceID string) []ConnectionInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := make([]ConnectionInfo, 0, len(t.conns))
+	for _, c := range t.conns {
+		if workspaceID != "" && c.WorkspaceID != workspaceID {
+			continue
+		}
+		if c.Kind == ConnectionLongPoll && time.Since(c.Since) < longPollThreshold {
+			continue
+		}
+		res = append(res, c.ConnectionInfo)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+// ServeHTTP serves the tracked connections as JSON. The list can be narrowed down to a single
+// workspace using the workspace query parameter.
+func (t *ConnectionTracker) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	t.mu.Lock()
+	draining := t.draining
+	t.mu.Unlock()
+
+	resp.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(resp).Encode(struct {
+		Draining    bool             `json:"draining"`
+		Connections []ConnectionInfo `json:"connections"`
+	}{
+		Draining:    draining,
+		Connections: t.List(req.URL.Query().Get("workspace")),
+	})
+	if err != nil {
+		log.WithError(err).Debug("cannot serve tracked connections")
+	}
+}
+
+// Drain asks all websocket clients to reconnect and waits until all tracked connections are
+// closed or ctx is done. Connections which are still open by then are closed.
+func (t *ConnectionTracker) Drain(ctx context.Context) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.draining = true
+	var websockets []*wsConn
+	for _, c := range t.conns {
+		if c.conn != nil {
+			websockets = append(websockets, c.conn)
+		}
+	}
+	t.mu.Unlock()
+
+	log.WithField("websockets", len(websockets)).Info("draining connections")
+	for _, c := range websockets {
+		// A close frame can only be sent once the frame currently written to the client is complete,
+		// which takes as long as the client needs to receive it. Don't let slow clients hold up the drain.
+		go c.requestClose()
+	}
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		t.mu.Lock()
+		remaining := len(t.conns)
+		t.mu.Unlock()
+		if remaining == 0 {
+			log.Info("all connections drained")
+			return
+		}
+
+		select {
+		case <-ticker.C:
+			continue
+		case <-ctx.Done():
+		}
+
+		t.mu.Lock()
+		for _, c := range t.conns {
+			if c.conn != nil {
+				c.conn.Close()
+			}
+		}
+		t.mu.Unlock()
+		log.WithField("remaining", remaining).Info("drain period is over - closing remaining connections")
+		return
+	}
+}
+
+func isWebsocketUpgrade(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Upgrade"), "websocket")
+}
+
+// trackingResponseWriter registers the connection of a websocket with the tracker once it is hijacked
+type trackingResponseWriter struct {
+	http.ResponseWriter
+	tracker *ConnectionTracker
+	conn    *trackedConnection
+}
+
+func (w *trackingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, xerrors.Errorf("response writer does not support hijacking")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wc := &wsConn{Conn: conn}
+	w.tracker.mu.Lock()
+	w.conn.conn = wc
+	draining := w.tracker.draining
+	w.tracker.mu.Unlock()
+	if draining {
+		wc.requestClose()
+	}
+	return wc, brw, nil
+}
+
+func (w *trackingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// wsConn is the client side of a websocket connection. It follows the frames written to the
+// client so that a close frame can be sent in between two frames.
+type wsConn struct {
+	net.Conn
+
+	mu sync.Mutex
+	// header holds the part of the current frame header written so far,
+	// remaining is the number of payload bytes of the current frame not written yet.
+	header    []byte
+	remaining uint64
+	// closing is set when a close frame should be sent at the next frame boundary
+	closing bool
+	// closed is set once the close frame was sent. Everything written afterwards is dropped.
+	closed bool
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int
+	for len(p) > 0 {
+		if c.closed {
+			// the client must not receive frames after the close frame
+			return n + len(p), nil
+		}
+		if c.closing && c.atFrameBoundary() {
+			err := c.writeClose()
+			if err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		k := c.advance(p)
+		w, err := c.Conn.Write(p[:k])
+		n += w
+		if err != nil {
+			return n, err
+		}
+		p = p[k:]
+	}
+	return n, nil
+}
+
+// requestClose sends a close frame which asks the client to reconnect as soon as no frame is being written
+func (c *wsConn) requestClose() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closing || c.closed {
+		return
+	}
+	c.closing = true
+	if c.atFrameBoundary() {
+		err := c.writeClose()
+		if err != nil {
+			log.WithError(err).Debug("cannot send websocket close frame")
+		}
+	}
+}
+
+func (c *wsConn) atFrameBoundary() bool {
+	return len(c.header) == 0 && c.remaining == 0
+}
+
+// advance consumes the part of p which belongs to the current frame and returns its length. Callers must hold c.mu.
+func (c *wsConn) advance(p []byte) int {
+	if c.remaining > 0 {
+		k := uint64(len(p))
+		if k > c.remaining {
+			k = c.remaining
+		}
+		c.remaining -= k
+		return int(k)
+	}
+
+	for k := 0; k < len(p); k++ {
+		c.header = append(c.header, p[k])
+		if headerLen, payloadLen, ok := parseFrameHeader(c.header); ok && len(c.header) == headerLen {
+			c.header = c.header[:0]
+			c.remaining = payloadLen
+			return k + 1
+		}
+	}
+	return len(p)
+}
+
+// writeClose sends the close frame. Callers must hold c.mu.
+func (c *wsConn) writeClose() error {
+	c.closed = true
+
+	payload := make([]byte, 2, 2+len(wsCloseReason))
+	binary.BigEndian.PutUint16(payload, wsCloseServiceRestart)
+	payload = append(payload, wsCloseReason...)
+	// FIN + close opcode, unmasked as it's sent by the server
+	frame := append([]byte{0x88, byte(len(payload))}, payload...)
+
+	_ = c.Conn.SetWriteDeadline(time.Now().Add(closeFrameWriteTimeout))
+	_, err := c.Conn.Write(frame)
+	_ = c.Conn.SetWriteDeadline(time.Time{})
+	return err
+}
+
+// parseFrameHeader returns the length of a websocket frame header and its payload once the header is complete
+func parseFrameHeader(h []byte) (headerLen int, payloadLen uint64, ok bool) {
+	if len(h) < 2 {
+		return 0, 0, false
+	}
+
+	headerLen = 2
+	length := h[1] & 0x7f
+	switch length {
+	case 126:
+		headerLen += 2
+	case 127:
+		headerLen += 8
+	}
+	if h[1]&0x80 != 0 {
+		// masking key
+		headerLen += 4
+	}
+	if len(h) < headerLen {
+		return headerLen, 0, false
+	}
+
+	switch length {
+	case 126:
+		payloadLen = uint64(binary.BigEndian.Uint16(h[2:4]))
+	case 127:
+		payloadLen = binary.BigEndian.Uint64(h[2:10])
+	default:
+		payloadLen = uint64(length)
+	}
+	return headerLen, payloadLen, true
+}
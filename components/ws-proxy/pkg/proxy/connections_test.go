@@ -0,0 +1,261 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/gorilla/mux"
+)
+
+type recordingConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *recordingConn) Write(p []byte) (int, error)        { return c.buf.Write(p) }
+func (c *recordingConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func closeFrame() []byte {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, wsCloseServiceRestart)
+	payload = append(payload, wsCloseReason...)
+	return append([]byte{0x88, byte(len(payload))}, payload...)
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func TestWSConnClose(t *testing.T) {
+	var (
+		// text frame "hello"
+		small = []byte{0x81, 0x05, 'h', 'e', 'l', 'l', 'o'}
+		// binary frame with 16 bit length
+		medium = concat([]byte{0x82, 126, 0x01, 0x00}, bytes.Repeat([]byte{'x'}, 256))
+		// masked frame with 64 bit length
+		large = concat([]byte{0x82, 0xff, 0, 0, 0, 0, 0, 0, 0x01, 0x00, 1, 2, 3, 4}, bytes.Repeat([]byte{'y'}, 256))
+	)
+
+	tests := []struct {
+		Name string
+		// Writes are passed to the connection in order, the close is requested before the write with index CloseBefore
+		Writes      [][]byte
+		CloseBefore int
+		Expectation []byte
+	}{
+		{
+			Name:        "idle connection",
+			Writes:      [][]byte{small},
+			CloseBefore: 1,
+			Expectation: concat(small, closeFrame()),
+		},
+		{
+			Name:        "frames after close are dropped",
+			Writes:      [][]byte{small, medium},
+			CloseBefore: 1,
+			Expectation: concat(small, closeFrame()),
+		},
+		{
+			Name:        "within payload",
+			Writes:      [][]byte{medium[:100], medium[100:], small},
+			CloseBefore: 1,
+			Expectation: concat(medium, closeFrame()),
+		},
+		{
+			Name:        "within header",
+			Writes:      [][]byte{large[:3], large[3:12], large[12:], small},
+			CloseBefore: 1,
+			Expectation: concat(large, closeFrame()),
+		},
+		{
+			Name:        "frame boundary within write",
+			Writes:      [][]byte{small[:3], concat(small[3:], medium, small)},
+			CloseBefore: 1,
+			Expectation: concat(small, closeFrame()),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			rec := &recordingConn{}
+			conn := &wsConn{Conn: rec}
+			for i, w := range test.Writes {
+				if i == test.CloseBefore {
+					conn.requestClose()
+				}
+				n, err := conn.Write(w)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n != len(w) {
+					t.Fatalf("short write: %d of %d bytes", n, len(w))
+				}
+			}
+			if len(test.Writes) == test.CloseBefore {
+				conn.requestClose()
+			}
+
+			if diff := cmp.Diff(test.Expectation, rec.buf.Bytes()); diff != "" {
+				t.Errorf("unexpected output (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConnectionTrackerDrain(t *testing.T) {
+	tracker := NewConnectionTracker()
+	upgraded := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		req = mux.SetURLVars(req, map[string]string{workspaceIDIdentifier: "amaranth-smelt-9ba20cc1", workspacePortIdentifier: "3000"})
+		tracker.Handler(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			// act like the reverse proxy does for websockets
+			conn, brw, err := resp.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+			_ = brw.Flush()
+			upgraded <- struct{}{}
+			_, _ = io.Copy(io.Discard, conn)
+		})).ServeHTTP(resp, req)
+	}))
+	defer srv.Close()
+
+	upgrade := func() (net.Conn, *http.Response) {
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = conn.Write([]byte("GET /socket HTTP/1.1\r\nHost: 3000-amaranth-smelt-9ba20cc1.ws.gitpod.dev\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return conn, resp
+	}
+
+	client, resp := upgrade()
+	defer client.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	<-upgraded
+
+	act := tracker.List("amaranth-smelt-9ba20cc1")
+	expected := []ConnectionInfo{{ID: 1, Kind: ConnectionWebsocket, WorkspaceID: "amaranth-smelt-9ba20cc1", Port: "3000", Path: "/socket"}}
+	if diff := cmp.Diff(expected, act, cmpopts.IgnoreFields(ConnectionInfo{}, "RemoteAddr", "Since")); diff != "" {
+		t.Errorf("unexpected connections (-want +got):\n%s", diff)
+	}
+	if other := tracker.List("blue-whale-12345678"); len(other) != 0 {
+		t.Errorf("expected no connections of other workspaces, got %v", other)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	drained := make(chan struct{})
+	go func() {
+		tracker.Drain(ctx)
+		close(drained)
+	}()
+
+	frame := make([]byte, len(closeFrame()))
+	_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err := io.ReadFull(client, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(closeFrame(), frame); diff != "" {
+		t.Errorf("unexpected close frame (-want +got):\n%s", diff)
+	}
+
+	// new websockets are rejected while draining
+	rejected, resp := upgrade()
+	rejected.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected websocket to be rejected while draining, got status %d", resp.StatusCode)
+	}
+
+	// the client reconnects, hence closes its connection
+	client.Close()
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		t.Fatal("drain did not finish after all connections were closed")
+	}
+	if remaining := tracker.List(""); len(remaining) != 0 {
+		t.Errorf("expected no connections after draining, got %v", remaining)
+	}
+}
+
+// blockingConn blocks all writes until it is closed, like a connection to a client which stopped reading
+type blockingConn struct {
+	net.Conn
+	writing chan struct{}
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func (c *blockingConn) Write(p []byte) (int, error) {
+	select {
+	case c.writing <- struct{}{}:
+	default:
+	}
+	<-c.closed
+	return 0, net.ErrClosed
+}
+
+func (c *blockingConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *blockingConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func TestConnectionTrackerDrainSlowClient(t *testing.T) {
+	conn := &blockingConn{writing: make(chan struct{}, 1), closed: make(chan struct{})}
+	wc := &wsConn{Conn: conn}
+	tracker := NewConnectionTracker()
+	tracker.conns[1] = &trackedConnection{ConnectionInfo: ConnectionInfo{ID: 1, Kind: ConnectionWebsocket}, conn: wc}
+
+	// the proxy is in the middle of writing a frame to a client which does not read
+	go func() { _, _ = wc.Write([]byte{0x81, 0x05, 'h', 'e', 'l', 'l', 'o'}) }()
+	<-conn.writing
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	drained := make(chan struct{})
+	go func() {
+		tracker.Drain(ctx)
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(5 * time.Second):
+		t.Fatal("drain did not finish when its context was done")
+	}
+	select {
+	case <-conn.closed:
+	default:
+		t.Error("expected remaining connection to be closed")
+	}
+}
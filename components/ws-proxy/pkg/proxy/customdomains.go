@@ -0,0 +1,269 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+)
+
+const (
+	// customDomainVerificationPrefix is prepended to a custom domain to find its verification record
+	customDomainVerificationPrefix = "_gitpod."
+	// customDomainVerificationValue is the prefix of TXT records which name the user allowed to use a domain
+	customDomainVerificationValue = "gitpod-user="
+
+	customDomainVerifiedTTL   = 5 * time.Minute
+	customDomainUnverifiedTTL = 30 * time.Second
+)
+
+// CustomDomainsConfig enables routing of verified custom domains to workspace ports
+type CustomDomainsConfig struct {
+	ACME ACMEConfig `json:"acme"`
+
+	// Address is where ws-proxy accepts TLS connections for custom domains which a proxy in front of
+	// ws-proxy passes through, e.g. :9091. Custom domains are always served on the HTTPS address, too.
+	Address string `json:"address,omitempty"`
+	// ProxyProtocol expects every connection to Address to start with a PROXY protocol v1 header
+	// which carries the address of the client.
+	ProxyProtocol bool `json:"proxyProtocol,omitempty"`
+}
+
+// ACMEConfig configures how certificates for custom domains are obtained
+type ACMEConfig struct {
+	// DirectoryURL is the ACME directory of the CA. Defaults to Let's Encrypt.
+	DirectoryURL string `json:"directoryURL,omitempty"`
+	// Email is the contact of the ACME account
+	Email string `json:"email,omitempty"`
+	// CacheDir is where accounts keys and certificates are stored
+	CacheDir string `json:"cacheDir"`
+	// CACertificate is a PEM file of the CA to trust when talking to the ACME server, e.g. for self-hosted CAs
+	CACertificate string `json:"caCertificate,omitempty"`
+}
+
+// Validate validates the configuration to catch issues during startup and not at runtime.
+func (c *CustomDomainsConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.ACME.CacheDir == "" {
+		return xerrors.Errorf("customDomains.acme.cacheDir is required")
+	}
+	return nil
+}
+
+// CustomDomain maps a domain to a workspace port
+type CustomDomain struct {
+	Domain string `json:"domain"`
+	Port   uint32 `json:"port"`
+}
+
+// CustomDomainsFromJSON parses the custom domains of a workspace
+func CustomDomainsFromJSON(data string) (map[string]uint32, error) {
+	var domains []CustomDomain
+	err := json.Unmarshal([]byte(data), &domains)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]uint32, len(domains))
+	for _, d := range domains {
+		res[normalizeDomain(d.Domain)] = d.Port
+	}
+	return res, nil
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(domain), ".")
+}
+
+// CustomDomainResolver is implemented by workspace info providers which can route custom domains.
+type CustomDomainResolver interface {
+	// ResolveCustomDomain returns the workspace port a verified custom domain maps to, or nil
+	// if the domain is unknown or not verified.
+	ResolveCustomDomain(ctx context.Context, domain string) *WorkspaceCoords
+}
+
+// DomainVerifier checks that a user is allowed to use a custom domain
+type DomainVerifier interface {
+	Verify(ctx context.Context, domain, userID string) bool
+}
+
+// DNSDomainVerifier verifies custom domains using a TXT record at _gitpod.<domain>
+// which contains gitpod-user=<user ID>. Results are cached.
+type DNSDomainVerifier struct {
+	LookupTXT func(ctx context.Context, name string) ([]string, error)
+
+	mu    sync.Mutex
+	cache map[string]domainVerification
+}
+
+type domainVerification struct {
+	Records []string
+	Expires time.Time
+}
+
+// NewDNSDomainVerifier creates a verifier which uses the system resolver
+func NewDNSDomainVerifier() *DNSDomainVerifier {
+	return &DNSDomainVerifier{
+		LookupTXT: net.DefaultResolver.LookupTXT,
+		cache:     make(map[string]domainVerification),
+	}
+}
+
+// Verify returns true if the domain's verification record names the user
+func (v *DNSDomainVerifier) Verify(ctx context.Context, domain, userID string) bool {
+	if userID == "" {
+		return false
+	}
+
+	domain = normalizeDomain(domain)
+	v.mu.Lock()
+	res, ok := v.cache[domain]
+	v.mu.Unlock()
+
+	if !ok || time.Now().After(res.Expires) {
+		records, err := v.LookupTXT(ctx, customDomainVerificationPrefix+domain)
+		ttl := customDomainVerifiedTTL
+		if err != nil {
+			log.WithError(err).WithField("domain", domain).Debug("cannot look up custom domain verification record")
+			records, ttl = nil, customDomainUnverifiedTTL
+		}
+		res = domainVerification{Records: records, Expires: time.Now().Add(ttl)}
+
+		v.mu.Lock()
+		v.cache[domain] = res
+		v.mu.Unlock()
+	}
+
+	for _, r := range res.Records {
+		if strings.TrimSpace(r) == customDomainVerificationValue+userID {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCustomDomainHostHeader matches requests to verified custom domains and sets the coordinates of the workspace port
+func matchCustomDomainHostHeader(resolver CustomDomainResolver, headerProvider hostHeaderProvider) func(req *http.Request, vars map[string]string) bool {
+	return func(req *http.Request, vars map[string]string) bool {
+		hostname := headerProvider(req)
+		if hostname == "" {
+			return false
+		}
+
+		coords := resolver.ResolveCustomDomain(req.Context(), hostname)
+		if coords == nil {
+			return false
+		}
+		vars[workspaceIDIdentifier] = coords.ID
+		vars[workspacePortIdentifier] = coords.Port
+		return true
+	}
+}
+
+// newCustomDomainCertManager creates an ACME certificate manager which obtains certificates for verified custom domains only.
+func newCustomDomainCertManager(cfg *CustomDomainsConfig, resolver CustomDomainResolver) (*autocert.Manager, error) {
+	client := &acme.Client{DirectoryURL: cfg.ACME.DirectoryURL}
+	if cfg.ACME.CACertificate != "" {
+		ca, err := os.ReadFile(cfg.ACME.CACertificate)
+		if err != nil {
+			return nil, xerrors.Errorf("cannot read ACME CA certificate: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(ca) {
+			return nil, xerrors.Errorf("ACME CA certificate %s contains no certificate", cfg.ACME.CACertificate)
+		}
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: roots},
+			},
+		}
+	}
+
+	return &autocert.Manager{
+		Prompt: autocert.AcceptTOS,
+		Cache:  autocert.DirCache(cfg.ACME.CacheDir),
+		Client: client,
+		Email:  cfg.ACME.Email,
+		HostPolicy: func(ctx context.Context, host string) error {
+			if resolver.ResolveCustomDomain(ctx, host) == nil {
+				return xerrors.Errorf("%s is not a verified custom domain of a running workspace", host)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// customDomainGetCertificate serves ACME certificates for custom domains and leaves all other
+// server names to the statically configured certificate.
+func customDomainGetCertificate(mgr *autocert.Manager, wsHostSuffix string) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	wsHostSuffix = strings.TrimPrefix(normalizeDomain(wsHostSuffix), ".")
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		name := normalizeDomain(hello.ServerName)
+		if name == "" || name == wsHostSuffix || strings.HasSuffix(name, "."+wsHostSuffix) {
+			// a nil certificate makes crypto/tls fall back to the configured certificates
+			return nil, nil
+		}
+		return mgr.GetCertificate(hello)
+	}
+}
+
+// customDomainHandler serves the connections passed through to the custom domain address. Those come
+// from clients, not from the proxy in front of ws-proxy, hence the headers set by that proxy are removed.
+func customDomainHandler(handler http.Handler, hostHeader string) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		req.Header.Del("X-Real-IP")
+		if !strings.EqualFold(hostHeader, "Host") {
+			req.Header.Del(hostHeader)
+		}
+		handler.ServeHTTP(resp, req)
+	})
+}
+
+// ResolveCustomDomain returns the workspace port a verified custom domain maps to.
+// If several workspaces claim the domain, the most recently started one wins.
+func (r *RemoteWorkspaceInfoProvider) ResolveCustomDomain(ctx context.Context, domain string) *WorkspaceCoords {
+	domain = normalizeDomain(domain)
+	workspaces, err := r.store.ByIndex(customDomainIndex, domain)
+	if err != nil || len(workspaces) == 0 {
+		return nil
+	}
+
+	var candidate *WorkspaceInfo
+	for _, obj := range workspaces {
+		ws := obj.(*WorkspaceInfo)
+		if candidate != nil && !ws.StartedAt.After(candidate.StartedAt) {
+			continue
+		}
+		if r.DomainVerifier == nil || !r.DomainVerifier.Verify(ctx, domain, ws.OwnerUserID) {
+			continue
+		}
+		candidate = ws
+	}
+	if candidate == nil {
+		return nil
+	}
+	return &WorkspaceCoords{
+		ID:   candidate.WorkspaceID,
+		Port: strconv.FormatUint(uint64(candidate.CustomDomains[domain]), 10),
+	}
+}
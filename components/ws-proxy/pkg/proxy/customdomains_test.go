@@ -0,0 +1,258 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/mux"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/ws-proxy/pkg/acmetest"
+)
+
+type fakeDomainVerifier map[string]string
+
+func (v fakeDomainVerifier) Verify(ctx context.Context, domain, userID string) bool {
+	return v[domain] == userID
+}
+
+func TestDNSDomainVerifier(t *testing.T) {
+	var lookups int
+	records := map[string][]string{
+		"_gitpod.app.example.com": {"v=spf1 -all", " gitpod-user=owner "},
+	}
+	v := NewDNSDomainVerifier()
+	v.LookupTXT = func(ctx context.Context, name string) ([]string, error) {
+		lookups++
+		r, ok := records[name]
+		if !ok {
+			return nil, xerrors.Errorf("no such host")
+		}
+		return r, nil
+	}
+
+	tests := []struct {
+		Domain   string
+		UserID   string
+		Expected bool
+	}{
+		{Domain: "app.example.com", UserID: "owner", Expected: true},
+		{Domain: "App.Example.com.", UserID: "owner", Expected: true},
+		{Domain: "app.example.com", UserID: "someone-else"},
+		{Domain: "app.example.com", UserID: ""},
+		{Domain: "unknown.example.com", UserID: "owner"},
+	}
+	for _, test := range tests {
+		if act := v.Verify(context.Background(), test.Domain, test.UserID); act != test.Expected {
+			t.Errorf("Verify(%q, %q) = %v, expected %v", test.Domain, test.UserID, act, test.Expected)
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("expected verification records to be cached, got %d lookups", lookups)
+	}
+
+	v.mu.Lock()
+	for domain, res := range v.cache {
+		res.Expires = time.Now().Add(-time.Second)
+		v.cache[domain] = res
+	}
+	v.mu.Unlock()
+	v.Verify(context.Background(), "app.example.com", "owner")
+	if lookups != 3 {
+		t.Errorf("expected expired verification to be looked up again, got %d lookups", lookups)
+	}
+}
+
+func newCustomDomainInfoProvider(verifier DomainVerifier, infos ...*WorkspaceInfo) *RemoteWorkspaceInfoProvider {
+	p := NewRemoteWorkspaceInfoProvider(nil, nil)
+	p.DomainVerifier = verifier
+	for _, info := range infos {
+		p.store.Update(info.WorkspaceID, info)
+	}
+	return p
+}
+
+func TestResolveCustomDomain(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		Name     string
+		Domain   string
+		Verifier DomainVerifier
+		Infos    []*WorkspaceInfo
+		Expected *WorkspaceCoords
+	}{
+		{
+			Name:     "verified domain",
+			Domain:   "App.example.com",
+			Verifier: fakeDomainVerifier{"app.example.com": "owner"},
+			Infos: []*WorkspaceInfo{
+				{WorkspaceID: "ws1", OwnerUserID: "owner", CustomDomains: map[string]uint32{"app.example.com": 3000}},
+			},
+			Expected: &WorkspaceCoords{ID: "ws1", Port: "3000"},
+		},
+		{
+			Name:     "unverified domain",
+			Domain:   "app.example.com",
+			Verifier: fakeDomainVerifier{"app.example.com": "someone-else"},
+			Infos: []*WorkspaceInfo{
+				{WorkspaceID: "ws1", OwnerUserID: "owner", CustomDomains: map[string]uint32{"app.example.com": 3000}},
+			},
+		},
+		{
+			Name:   "no verifier",
+			Domain: "app.example.com",
+			Infos: []*WorkspaceInfo{
+				{WorkspaceID: "ws1", OwnerUserID: "owner", CustomDomains: map[string]uint32{"app.example.com": 3000}},
+			},
+		},
+		{
+			Name:     "unknown domain",
+			Domain:   "other.example.com",
+			Verifier: fakeDomainVerifier{"other.example.com": "owner"},
+			Infos: []*WorkspaceInfo{
+				{WorkspaceID: "ws1", OwnerUserID: "owner", CustomDomains: map[string]uint32{"app.example.com": 3000}},
+			},
+		},
+		{
+			Name:     "most recent verified workspace wins",
+			Domain:   "app.example.com",
+			Verifier: fakeDomainVerifier{"app.example.com": "owner"},
+			Infos: []*WorkspaceInfo{
+				{WorkspaceID: "ws1", OwnerUserID: "owner", StartedAt: now.Add(-time.Hour), CustomDomains: map[string]uint32{"app.example.com": 3000}},
+				{WorkspaceID: "ws2", OwnerUserID: "owner", StartedAt: now, CustomDomains: map[string]uint32{"app.example.com": 8080}},
+				{WorkspaceID: "ws3", OwnerUserID: "squatter", StartedAt: now.Add(time.Minute), CustomDomains: map[string]uint32{"app.example.com": 80}},
+			},
+			Expected: &WorkspaceCoords{ID: "ws2", Port: "8080"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			p := newCustomDomainInfoProvider(test.Verifier, test.Infos...)
+			act := p.ResolveCustomDomain(context.Background(), test.Domain)
+			if diff := cmp.Diff(test.Expected, act); diff != "" {
+				t.Errorf("unexpected coords (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCustomDomainRouting(t *testing.T) {
+	const wsHostSuffix = ".ws.gitpod.dev"
+	p := newCustomDomainInfoProvider(fakeDomainVerifier{"app.example.com": "owner"},
+		&WorkspaceInfo{WorkspaceID: "amaranth-smelt-9ba20cc1", OwnerUserID: "owner", CustomDomains: map[string]uint32{"app.example.com": 3000}},
+	)
+
+	r := mux.NewRouter()
+	_, portRouter, _ := HostBasedRouter(forwardedHostnameHeader, wsHostSuffix, "")(r, p)
+	var act map[string]string
+	portRouter.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		act = mux.Vars(req)
+	})
+
+	tests := []struct {
+		Host     string
+		Expected map[string]string
+	}{
+		{
+			Host:     "app.example.com",
+			Expected: map[string]string{workspaceIDIdentifier: "amaranth-smelt-9ba20cc1", workspacePortIdentifier: "3000"},
+		},
+		{
+			Host:     "1234-amaranth-smelt-9ba20cc1.ws.gitpod.dev",
+			Expected: map[string]string{workspaceIDIdentifier: "amaranth-smelt-9ba20cc1", workspacePortIdentifier: "1234"},
+		},
+		{
+			Host: "unknown.example.com",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Host, func(t *testing.T) {
+			act = nil
+			req := httptest.NewRequest(http.MethodGet, "http://"+test.Host+"/", nil)
+			req.Header.Set(forwardedHostnameHeader, test.Host)
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			if diff := cmp.Diff(test.Expected, act); diff != "" {
+				t.Errorf("unexpected vars (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCustomDomainCertificate(t *testing.T) {
+	ca, err := acmetest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ca.Close()
+
+	p := newCustomDomainInfoProvider(fakeDomainVerifier{"app.example.com": "owner"},
+		&WorkspaceInfo{WorkspaceID: "ws1", OwnerUserID: "owner", CustomDomains: map[string]uint32{"app.example.com": 3000, "unverified.example.com": 3000}},
+	)
+	mgr, err := newCustomDomainCertManager(&CustomDomainsConfig{ACME: ACMEConfig{
+		DirectoryURL: ca.DirectoryURL(),
+		CacheDir:     t.TempDir(),
+	}}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the ACME server validates http-01 challenges against our plain HTTP listener
+	challengeSrv := httptest.NewServer(mgr.HTTPHandler(nil))
+	defer challengeSrv.Close()
+	ca.ChallengeAddr = strings.TrimPrefix(challengeSrv.URL, "http://")
+
+	getCertificate := customDomainGetCertificate(mgr, ".ws.gitpod.dev")
+
+	cert, err := getCertificate(&tls.ClientHelloInfo{ServerName: "app.example.com"})
+	if err != nil {
+		t.Fatalf("cannot obtain certificate: %v", err)
+	}
+	if cert == nil || cert.Leaf == nil {
+		t.Fatal("expected a certificate for the verified custom domain")
+	}
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: "app.example.com", Roots: ca.Roots})
+	if err != nil {
+		t.Errorf("certificate is not signed by the ACME CA: %v", err)
+	}
+
+	_, err = getCertificate(&tls.ClientHelloInfo{ServerName: "unverified.example.com"})
+	if err == nil {
+		t.Error("expected no certificate for an unverified custom domain")
+	}
+
+	for _, name := range []string{"", "1234-ws1.ws.gitpod.dev", "ws.gitpod.dev"} {
+		cert, err := getCertificate(&tls.ClientHelloInfo{ServerName: name})
+		if cert != nil || err != nil {
+			t.Errorf("expected %q to be left to the static certificate, got %v, %v", name, cert, err)
+		}
+	}
+}
+
+func TestCustomDomainHandler(t *testing.T) {
+	var act http.Header
+	handler := customDomainHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		act = req.Header
+	}), forwardedHostnameHeader)
+
+	req := httptest.NewRequest(http.MethodGet, "https://app.example.com/", nil)
+	req.Header.Set("X-Real-IP", "10.1.2.3")
+	req.Header.Set(forwardedHostnameHeader, "3000-amaranth-smelt-9ba20cc1.ws.gitpod.dev")
+	req.Header.Set("Accept", "text/html")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	expected := http.Header{"Accept": {"text/html"}}
+	if diff := cmp.Diff(expected, act); diff != "" {
+		t.Errorf("expected headers of the proxy to be removed (-want +got):\n%s", diff)
+	}
+}
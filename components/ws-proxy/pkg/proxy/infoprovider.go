@@ -47,6 +47,7 @@ type WorkspaceInfoProvider interface {
 type WorkspaceInfo struct {
 	WorkspaceID string
 	InstanceID  string
+	OwnerUserID string
 	URL         string
 
 	IDEImage        string
@@ -58,6 +59,10 @@ type WorkspaceInfo struct {
 	IPAddress string
 
 	Ports []*api.PortSpec
+	// PortProtection contains the protection of public ports by port number
+	PortProtection map[uint32]*PortProtection
+	// CustomDomains maps custom domains to the port they route to
+	CustomDomains map[string]uint32
 
 	Auth      *wsapi.WorkspaceAuthentication
 	StartedAt time.Time
@@ -67,12 +72,15 @@ type WorkspaceInfo struct {
 type RemoteWorkspaceInfoProvider struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// DomainVerifier verifies custom domains. If nil, no custom domain is routed.
+	DomainVerifier DomainVerifier
 
 	store cache.ThreadSafeStore
 }
 
 const (
-	workspaceIndex = "workspaceIndex"
+	workspaceIndex    = "workspaceIndex"
+	customDomainIndex = "customDomainIndex"
 )
 
 // NewRemoteWorkspaceInfoProvider creates a fresh WorkspaceInfoProvider.
@@ -86,6 +94,18 @@ func NewRemoteWorkspaceInfoProvider(client client.Client, scheme *runtime.Scheme
 
 			return nil, xerrors.Errorf("object is not a WorkspaceInfo")
 		},
+		customDomainIndex: func(obj interface{}) ([]string, error) {
+			workspaceInfo, ok := obj.(*WorkspaceInfo)
+			if !ok {
+				return nil, xerrors.Errorf("object is not a WorkspaceInfo")
+			}
+
+			res := make([]string, 0, len(workspaceInfo.CustomDomains))
+			for domain := range workspaceInfo.CustomDomains {
+				res = append(res, domain)
+			}
+			return res, nil
+		},
 	}
 
 	return &RemoteWorkspaceInfoProvider{
@@ -148,15 +168,37 @@ func mapPodToWorkspaceInfo(pod *corev1.Pod) *WorkspaceInfo {
 
 	workspaceURL := pod.Annotations[kubernetes.WorkspaceURLAnnotation]
 
+	var portProtection map[uint32]*PortProtection
+	if data, ok := pod.Annotations[kubernetes.WorkspacePortProtectionAnnotation]; ok {
+		var err error
+		portProtection, err = PortProtectionFromJSON(data)
+		if err != nil {
+			log.WithError(err).WithFields(kubernetes.GetOWIFromObject(&pod.ObjectMeta)).Error("cannot parse port protection - denying access to all public ports")
+			portProtection = denyAllPorts(extractExposedPorts(pod).Ports)
+		}
+	}
+
+	var customDomains map[string]uint32
+	if data, ok := pod.Annotations[kubernetes.WorkspaceCustomDomainsAnnotation]; ok {
+		var err error
+		customDomains, err = CustomDomainsFromJSON(data)
+		if err != nil {
+			log.WithError(err).WithFields(kubernetes.GetOWIFromObject(&pod.ObjectMeta)).Warn("cannot parse custom domains")
+		}
+	}
+
 	return &WorkspaceInfo{
 		WorkspaceID:     pod.Labels[kubernetes.MetaIDLabel],
 		InstanceID:      pod.Labels[kubernetes.WorkspaceIDLabel],
+		OwnerUserID:     pod.Labels[kubernetes.OwnerLabel],
 		URL:             workspaceURL,
 		IDEImage:        imageSpec.IdeRef,
 		IDEPublicPort:   getPortStr(workspaceURL),
 		SupervisorImage: imageSpec.SupervisorRef,
 		IPAddress:       pod.Status.PodIP,
 		Ports:           extractExposedPorts(pod).Ports,
+		PortProtection:  portProtection,
+		CustomDomains:   customDomains,
 		Auth:            &wsapi.WorkspaceAuthentication{Admission: admission, OwnerToken: ownerToken},
 		StartedAt:       pod.CreationTimestamp.Time,
 	}
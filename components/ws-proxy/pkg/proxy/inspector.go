@@ -0,0 +1,552 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+)
+
+const (
+	// DefaultInspectorCapacity is the number of exchanges kept per workspace if no other capacity is configured
+	DefaultInspectorCapacity = 100
+	// DefaultInspectorMaxBodySize is the number of body bytes captured per request and response if no other limit is configured
+	DefaultInspectorMaxBodySize = 64 * 1024
+	// DefaultInspectorMaxTotalSize is the number of bytes all captured exchanges may take up if no other limit is configured
+	DefaultInspectorMaxTotalSize = 64 * 1024 * 1024
+
+	// capturedExchangeOverhead approximates the memory an exchange takes up besides its headers and bodies
+	capturedExchangeOverhead = 512
+
+	// inspectorPathPrefix is where the inspector API is served on the workspace host
+	inspectorPathPrefix = "/_inspector/v1"
+
+	inspectorExchangeIdentifier = "exchangeID"
+	inspectorPortIdentifier     = "port"
+)
+
+// InspectorConfig enables the request inspector. Requests are only captured for the ports
+// on which the workspace owner enabled capturing using the inspector API.
+type InspectorConfig struct {
+	// Capacity is the number of exchanges kept per workspace. Older exchanges are dropped.
+	Capacity int `json:"capacity"`
+	// MaxBodySize is the number of bytes of request and response bodies which are captured
+	MaxBodySize int64 `json:"maxBodySize"`
+	// MaxTotalSize is the number of bytes the exchanges of all workspaces may take up.
+	// The oldest exchanges are dropped once it is exceeded.
+	MaxTotalSize int64 `json:"maxTotalSize"`
+}
+
+// Validate validates the configuration to catch issues during startup and not at runtime.
+func (c *InspectorConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Capacity < 0 {
+		return xerrors.Errorf("inspector capacity must not be negative")
+	}
+	if c.MaxBodySize < 0 {
+		return xerrors.Errorf("inspector maxBodySize must not be negative")
+	}
+	if c.MaxTotalSize < 0 {
+		return xerrors.Errorf("inspector maxTotalSize must not be negative")
+	}
+	return nil
+}
+
+// CapturedRequest is a request to a workspace port as seen by the inspector
+type CapturedRequest struct {
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	Host          string      `json:"host"`
+	Header        http.Header `json:"header"`
+	Body          []byte      `json:"body,omitempty"`
+	BodyTruncated bool        `json:"bodyTruncated,omitempty"`
+}
+
+// CapturedResponse is the response a workspace port sent to a captured request
+type CapturedResponse struct {
+	StatusCode    int         `json:"statusCode"`
+	Header        http.Header `json:"header"`
+	Body          []byte      `json:"body,omitempty"`
+	BodyTruncated bool        `json:"bodyTruncated,omitempty"`
+}
+
+// CapturedExchange is a request/response pair captured by the inspector
+type CapturedExchange struct {
+	ID          string            `json:"id"`
+	WorkspaceID string            `json:"workspaceId"`
+	Port        uint32            `json:"port"`
+	Time        time.Time         `json:"time"`
+	Duration    float64           `json:"durationSeconds"`
+	ReplayOf    string            `json:"replayOf,omitempty"`
+	Request     CapturedRequest   `json:"request"`
+	Response    *CapturedResponse `json:"response,omitempty"`
+}
+
+// size approximates the memory an exchange takes up
+func (e *CapturedExchange) size() int64 {
+	size := int64(capturedExchangeOverhead + len(e.Request.URL) + len(e.Request.Body))
+	size += headerSize(e.Request.Header)
+	if e.Response != nil {
+		size += int64(len(e.Response.Body)) + headerSize(e.Response.Header)
+	}
+	return size
+}
+
+func headerSize(h http.Header) int64 {
+	var size int64
+	for k, vs := range h {
+		for _, v := range vs {
+			size += int64(len(k) + len(v))
+		}
+	}
+	return size
+}
+
+// inspectedWorkspace holds the capture state of a single workspace
+type inspectedWorkspace struct {
+	// ports are the ports for which the owner enabled capturing
+	ports map[uint32]struct{}
+	// exchanges are the elements of the workspace's exchanges in RequestInspector.exchanges, oldest first
+	exchanges []*list.Element
+}
+
+// RequestInspector captures requests to workspace ports and their responses, similar to ngrok's inspector.
+// Capturing is opt-in per workspace port.
+type RequestInspector struct {
+	Capacity     int
+	MaxBodySize  int64
+	MaxTotalSize int64
+
+	mu         sync.RWMutex
+	workspaces map[string]*inspectedWorkspace
+	// exchanges contains the exchanges of all workspaces, oldest first
+	exchanges *list.List
+	size      int64
+	counter   uint64
+}
+
+// NewRequestInspector creates a new inspector. If cfg is nil, nil is returned and no requests are captured.
+func NewRequestInspector(cfg *InspectorConfig) *RequestInspector {
+	if cfg == nil {
+		return nil
+	}
+
+	res := &RequestInspector{
+		Capacity:     cfg.Capacity,
+		MaxBodySize:  cfg.MaxBodySize,
+		MaxTotalSize: cfg.MaxTotalSize,
+		workspaces:   make(map[string]*inspectedWorkspace),
+		exchanges:    list.New(),
+	}
+	if res.Capacity == 0 {
+		res.Capacity = DefaultInspectorCapacity
+	}
+	if res.MaxBodySize == 0 {
+		res.MaxBodySize = DefaultInspectorMaxBodySize
+	}
+	if res.MaxTotalSize == 0 {
+		res.MaxTotalSize = DefaultInspectorMaxTotalSize
+	}
+	return res
+}
+
+// EnableCapture starts capturing the requests to a port of a workspace
+func (ri *RequestInspector) EnableCapture(workspaceID string, port uint32, infoProvider WorkspaceInfoProvider) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	ws, ok := ri.workspaces[workspaceID]
+	if !ok {
+		// we're about to use more memory - free the state of workspaces which are gone
+		for wsid := range ri.workspaces {
+			if infoProvider.WorkspaceInfo(wsid) == nil {
+				ri.removeWorkspace(wsid)
+			}
+		}
+
+		ws = &inspectedWorkspace{ports: make(map[uint32]struct{})}
+		ri.workspaces[workspaceID] = ws
+	}
+	ws.ports[port] = struct{}{}
+}
+
+// DisableCapture stops capturing the requests to a port of a workspace. Exchanges captured so far are kept.
+func (ri *RequestInspector) DisableCapture(workspaceID string, port uint32) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	if ws, ok := ri.workspaces[workspaceID]; ok {
+		delete(ws.ports, port)
+	}
+}
+
+// CapturedPorts returns the ports of a workspace whose requests are captured
+func (ri *RequestInspector) CapturedPorts(workspaceID string) []uint32 {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	res := []uint32{}
+	if ws, ok := ri.workspaces[workspaceID]; ok {
+		for p := range ws.ports {
+			res = append(res, p)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+func (ri *RequestInspector) isCapturing(workspaceID string, port uint32) bool {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	ws, ok := ri.workspaces[workspaceID]
+	if !ok {
+		return false
+	}
+	_, ok = ws.ports[port]
+	return ok
+}
+
+// List returns the captured exchanges of a workspace, newest first. If port is not zero,
+// only the exchanges of that port are returned.
+func (ri *RequestInspector) List(workspaceID string, port uint32) []*CapturedExchange {
+	ri.mu.RLock()
+	defer ri.mu.RUnlock()
+
+	ws, ok := ri.workspaces[workspaceID]
+	if !ok {
+		return []*CapturedExchange{}
+	}
+	res := make([]*CapturedExchange, 0, len(ws.exchanges))
+	for i := len(ws.exchanges) - 1; i >= 0; i-- {
+		e := ws.exchanges[i].Value.(*CapturedExchange)
+		if port != 0 && e.Port != port {
+			continue
+		}
+		res = append(res, e)
+	}
+	return res
+}
+
+// Get returns a single captured exchange of a workspace or nil if it does not exist (anymore).
+func (ri *RequestInspector) Get(workspaceID, id string) *CapturedExchange {
+	for _, e := range ri.List(workspaceID, 0) {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// add records an exchange of a workspace for which capturing was enabled. It drops the oldest exchanges
+// of the workspace beyond its capacity and the oldest exchanges of all workspaces beyond the total size.
+func (ri *RequestInspector) add(e *CapturedExchange) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	ws, ok := ri.workspaces[e.WorkspaceID]
+	if !ok {
+		return
+	}
+	ws.exchanges = append(ws.exchanges, ri.exchanges.PushBack(e))
+	ri.size += e.size()
+	if len(ws.exchanges) > ri.Capacity {
+		ri.removeOldest(ws)
+	}
+	for ri.size > ri.MaxTotalSize && ri.exchanges.Len() > 0 {
+		oldest := ri.exchanges.Front().Value.(*CapturedExchange)
+		// the oldest exchange of all workspaces is the oldest one of its workspace
+		ri.removeOldest(ri.workspaces[oldest.WorkspaceID])
+	}
+}
+
+// removeOldest drops the oldest exchange of a workspace. Callers must hold mu.
+func (ri *RequestInspector) removeOldest(ws *inspectedWorkspace) {
+	el := ws.exchanges[0]
+	ws.exchanges[0] = nil
+	ws.exchanges = ws.exchanges[1:]
+	ri.size -= ri.exchanges.Remove(el).(*CapturedExchange).size()
+}
+
+// removeWorkspace drops all state of a workspace. Callers must hold mu.
+func (ri *RequestInspector) removeWorkspace(workspaceID string) {
+	ws := ri.workspaces[workspaceID]
+	for len(ws.exchanges) > 0 {
+		ri.removeOldest(ws)
+	}
+	delete(ri.workspaces, workspaceID)
+}
+
+func (ri *RequestInspector) nextID() string {
+	return strconv.FormatUint(atomic.AddUint64(&ri.counter, 1), 10)
+}
+
+// Capture records the requests h serves to ports for which capturing is enabled.
+// Websocket upgrades are passed through without capture.
+func (ri *RequestInspector) Capture(h http.Handler) http.Handler {
+	if ri == nil {
+		return h
+	}
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		coords := getWorkspaceCoords(req)
+		port, err := strconv.ParseUint(coords.Port, 10, 16)
+		if err != nil || req.Header.Get("Upgrade") != "" || !ri.isCapturing(coords.ID, uint32(port)) {
+			h.ServeHTTP(resp, req)
+			return
+		}
+
+		ex := &CapturedExchange{
+			ID:          ri.nextID(),
+			WorkspaceID: coords.ID,
+			Port:        uint32(port),
+			Time:        time.Now(),
+			Request: CapturedRequest{
+				Method: req.Method,
+				URL:    req.URL.RequestURI(),
+				Host:   req.Host,
+				Header: sanitizeCapturedHeader(req.Header),
+			},
+		}
+
+		reqBody := &limitedBuffer{Limit: ri.MaxBodySize}
+		if req.Body != nil && req.Body != http.NoBody {
+			req.Body = &teeReadCloser{Reader: io.TeeReader(req.Body, reqBody), Closer: req.Body}
+		}
+		rw := &capturingResponseWriter{ResponseWriter: resp, body: limitedBuffer{Limit: ri.MaxBodySize}}
+
+		h.ServeHTTP(rw, req)
+
+		ex.Duration = time.Since(ex.Time).Seconds()
+		ex.Request.Body, ex.Request.BodyTruncated = reqBody.Bytes(), reqBody.Truncated
+		header := rw.header
+		if header == nil {
+			header = resp.Header().Clone()
+		}
+		ex.Response = &CapturedResponse{
+			StatusCode:    rw.StatusCode(),
+			Header:        header,
+			Body:          rw.body.Bytes(),
+			BodyTruncated: rw.body.Truncated,
+		}
+		ri.add(ex)
+	})
+}
+
+// Replay re-sends a captured request to the workspace port it was originally sent to
+// and captures the exchange as if the request came in through the proxy.
+func (ri *RequestInspector) Replay(config *RouteHandlerConfig, infoProvider WorkspaceInfoProvider, original *CapturedExchange) (*CapturedExchange, error) {
+	if original.Request.BodyTruncated {
+		return nil, xerrors.Errorf("cannot replay request with truncated body")
+	}
+	info := infoProvider.WorkspaceInfo(original.WorkspaceID)
+	if info == nil {
+		return nil, xerrors.Errorf("workspace %s not found", original.WorkspaceID)
+	}
+	target, err := buildWorkspacePodURL(info.IPAddress, strconv.FormatUint(uint64(original.Port), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(original.Request.Method, target.String()+original.Request.URL, bytes.NewReader(original.Request.Body))
+	if err != nil {
+		return nil, xerrors.Errorf("cannot create replay request: %w", err)
+	}
+	req.Header = original.Request.Header.Clone()
+	req.Host = original.Request.Host
+
+	ex := &CapturedExchange{
+		ID:          ri.nextID(),
+		WorkspaceID: original.WorkspaceID,
+		Port:        original.Port,
+		Time:        time.Now(),
+		ReplayOf:    original.ID,
+		Request:     original.Request,
+	}
+	resp, err := config.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot replay request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body := &limitedBuffer{Limit: ri.MaxBodySize}
+	_, err = io.Copy(body, resp.Body)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot read replay response: %w", err)
+	}
+	ex.Duration = time.Since(ex.Time).Seconds()
+	ex.Response = &CapturedResponse{
+		StatusCode:    resp.StatusCode,
+		Header:        resp.Header,
+		Body:          body.Bytes(),
+		BodyTruncated: body.Truncated,
+	}
+	ri.add(ex)
+
+	return ex, nil
+}
+
+// HandleAPI serves the inspector API on the given router. It enables and disables capturing per port,
+// lists exchanges, returns a single exchange and replays exchanges:
+//
+//	GET    /capture
+//	PUT    /capture/<port>
+//	DELETE /capture/<port>
+//	GET    /requests[?port=<port>]
+//	GET    /requests/<id>
+//	POST   /requests/<id>/replay
+func (ri *RequestInspector) HandleAPI(r *mux.Router, config *RouteHandlerConfig, infoProvider WorkspaceInfoProvider) {
+	r.Path("/capture").Methods(http.MethodGet).HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		writeInspectorJSON(resp, struct {
+			Ports []uint32 `json:"ports"`
+		}{ri.CapturedPorts(getWorkspaceCoords(req).ID)})
+	})
+	r.Path("/capture/{"+inspectorPortIdentifier+"}").Methods(http.MethodPut, http.MethodDelete).HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		port, err := strconv.ParseUint(mux.Vars(req)[inspectorPortIdentifier], 10, 16)
+		if err != nil || port == 0 {
+			http.Error(resp, "invalid port", http.StatusBadRequest)
+			return
+		}
+		if req.Method == http.MethodPut {
+			ri.EnableCapture(getWorkspaceCoords(req).ID, uint32(port), infoProvider)
+		} else {
+			ri.DisableCapture(getWorkspaceCoords(req).ID, uint32(port))
+		}
+		resp.WriteHeader(http.StatusNoContent)
+	})
+	r.Path("/requests").Methods(http.MethodGet).HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		var port uint64
+		if p := req.URL.Query().Get("port"); p != "" {
+			var err error
+			port, err = strconv.ParseUint(p, 10, 16)
+			if err != nil {
+				http.Error(resp, "invalid port", http.StatusBadRequest)
+				return
+			}
+		}
+		writeInspectorJSON(resp, ri.List(getWorkspaceCoords(req).ID, uint32(port)))
+	})
+	r.Path("/requests/{" + inspectorExchangeIdentifier + "}").Methods(http.MethodGet).HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		ex := ri.Get(getWorkspaceCoords(req).ID, mux.Vars(req)[inspectorExchangeIdentifier])
+		if ex == nil {
+			http.Error(resp, "request not found", http.StatusNotFound)
+			return
+		}
+		writeInspectorJSON(resp, ex)
+	})
+	r.Path("/requests/{" + inspectorExchangeIdentifier + "}/replay").Methods(http.MethodPost).HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		ex := ri.Get(getWorkspaceCoords(req).ID, mux.Vars(req)[inspectorExchangeIdentifier])
+		if ex == nil {
+			http.Error(resp, "request not found", http.StatusNotFound)
+			return
+		}
+		replayed, err := ri.Replay(config, infoProvider, ex)
+		if err != nil {
+			getLog(req.Context()).WithError(err).WithField("exchangeId", ex.ID).Debug("cannot replay request")
+			http.Error(resp, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeInspectorJSON(resp, replayed)
+	})
+}
+
+func writeInspectorJSON(resp http.ResponseWriter, v interface{}) {
+	resp.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(resp).Encode(v)
+	if err != nil {
+		log.WithError(err).Debug("cannot write inspector response")
+	}
+}
+
+// sanitizeCapturedHeader removes credentials of the workspace owner from captured headers
+func sanitizeCapturedHeader(h http.Header) http.Header {
+	res := h.Clone()
+	res.Del("x-gitpod-owner-token")
+	return res
+}
+
+// limitedBuffer keeps the first Limit bytes written to it and discards the rest
+type limitedBuffer struct {
+	Limit     int64
+	Truncated bool
+
+	buf bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if rem := b.Limit - int64(b.buf.Len()); int64(len(p)) > rem {
+		p = p[:rem]
+		b.Truncated = true
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	return b.buf.Bytes()
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// capturingResponseWriter records the status, header and body of a response
+type capturingResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+	header http.Header
+	body   limitedBuffer
+}
+
+func (w *capturingResponseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+		w.header = w.ResponseWriter.Header().Clone()
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *capturingResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush keeps streaming responses (e.g. server-sent events) working
+func (w *capturingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *capturingResponseWriter) StatusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
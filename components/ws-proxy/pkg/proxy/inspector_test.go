@@ -0,0 +1,214 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/mux"
+)
+
+func TestRequestInspectorEviction(t *testing.T) {
+	exchangeSize := (&CapturedExchange{}).size()
+	tests := []struct {
+		Name         string
+		Capacity     int
+		MaxTotalSize int64
+		Adds         []string
+		Expected     map[string][]string
+	}{
+		{Name: "empty", Capacity: 3, Expected: map[string][]string{"ws1": {}, "ws2": {}}},
+		{Name: "not full", Capacity: 3, Adds: []string{"ws1", "ws1"}, Expected: map[string][]string{"ws1": {"2", "1"}, "ws2": {}}},
+		{Name: "full", Capacity: 3, Adds: []string{"ws1", "ws1", "ws1"}, Expected: map[string][]string{"ws1": {"3", "2", "1"}, "ws2": {}}},
+		{Name: "capacity exceeded", Capacity: 3, Adds: []string{"ws1", "ws1", "ws1", "ws1", "ws1"}, Expected: map[string][]string{"ws1": {"5", "4", "3"}, "ws2": {}}},
+		{Name: "capacity is per workspace", Capacity: 2, Adds: []string{"ws1", "ws2", "ws1", "ws1", "ws2"}, Expected: map[string][]string{"ws1": {"4", "3"}, "ws2": {"5", "2"}}},
+		{Name: "total size exceeded", Capacity: 3, MaxTotalSize: 3 * exchangeSize, Adds: []string{"ws1", "ws2", "ws1", "ws2"}, Expected: map[string][]string{"ws1": {"3"}, "ws2": {"4", "2"}}},
+		{Name: "unknown workspace", Capacity: 3, Adds: []string{"ws3"}, Expected: map[string][]string{"ws1": {}, "ws2": {}, "ws3": {}}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ri := NewRequestInspector(&InspectorConfig{Capacity: test.Capacity, MaxTotalSize: test.MaxTotalSize})
+			info := &fakeWsInfoProvider{infos: []WorkspaceInfo{{WorkspaceID: "ws1"}, {WorkspaceID: "ws2"}}}
+			ri.EnableCapture("ws1", 3000, info)
+			ri.EnableCapture("ws2", 3000, info)
+			for _, wsid := range test.Adds {
+				ri.add(&CapturedExchange{ID: ri.nextID(), WorkspaceID: wsid, Port: 3000})
+			}
+
+			act := make(map[string][]string)
+			for wsid := range test.Expected {
+				act[wsid] = []string{}
+				for _, e := range ri.List(wsid, 0) {
+					act[wsid] = append(act[wsid], e.ID)
+				}
+			}
+			if diff := cmp.Diff(test.Expected, act); diff != "" {
+				t.Errorf("unexpected exchanges (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRequestInspectorFreesStoppedWorkspaces(t *testing.T) {
+	ri := NewRequestInspector(&InspectorConfig{})
+	info := &fakeWsInfoProvider{infos: []WorkspaceInfo{{WorkspaceID: "ws1"}, {WorkspaceID: "ws2"}}}
+	ri.EnableCapture("ws1", 3000, info)
+	ri.add(&CapturedExchange{ID: ri.nextID(), WorkspaceID: "ws1", Port: 3000})
+
+	info.infos = info.infos[1:]
+	ri.EnableCapture("ws2", 3000, info)
+	if len(ri.CapturedPorts("ws1")) != 0 || len(ri.List("ws1", 0)) != 0 || ri.size != 0 {
+		t.Error("expected state of stopped workspace to be freed")
+	}
+}
+
+func TestRequestInspector(t *testing.T) {
+	var received []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+		w.Header().Set("X-Backend", "true")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello " + string(body)))
+	}))
+	defer backend.Close()
+	host, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+
+	var (
+		info = &fakeWsInfoProvider{infos: []WorkspaceInfo{{WorkspaceID: "ws", IPAddress: host}}}
+		cfg  = &RouteHandlerConfig{DefaultTransport: http.DefaultTransport}
+		ri   = NewRequestInspector(&InspectorConfig{MaxBodySize: 8})
+	)
+	handler := ri.Capture(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target, _ := buildWorkspacePodURL(host, port)
+		proxyPass(cfg, info, func(*Config, WorkspaceInfoProvider, *http.Request) (*url.URL, error) {
+			return target, nil
+		})(w, r)
+	}))
+
+	serve := func(body string) {
+		req := httptest.NewRequest(http.MethodPost, "https://"+port+"-ws.test-domain.com/hook?x=1", strings.NewReader(body))
+		req.Header.Set("x-gitpod-owner-token", "secret")
+		req = mux.SetURLVars(req, map[string]string{workspaceIDIdentifier: "ws", workspacePortIdentifier: port})
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Body.String() != "hello "+body {
+			t.Fatalf("capture altered the response: %q", rr.Body.String())
+		}
+	}
+
+	serve("not captured")
+	if len(ri.List("ws", 0)) != 0 {
+		t.Fatal("expected requests not to be captured before capturing is enabled")
+	}
+
+	r := mux.NewRouter()
+	api := r.PathPrefix(inspectorPathPrefix).Subrouter()
+	api.Use(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			vars := mux.Vars(req)
+			vars[workspaceIDIdentifier] = "ws"
+			h.ServeHTTP(w, mux.SetURLVars(req, vars))
+		})
+	})
+	ri.HandleAPI(api, cfg, info)
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, inspectorPathPrefix+"/capture/"+port, nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("enabling capture failed: %d %s", rr.Code, rr.Body.String())
+	}
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, inspectorPathPrefix+"/capture", nil))
+	if strings.TrimSpace(rr.Body.String()) != `{"ports":[`+port+`]}` {
+		t.Errorf("unexpected captured ports: %s", rr.Body.String())
+	}
+
+	for _, body := range []string{"webhook", "a very long payload"} {
+		serve(body)
+	}
+
+	exchanges := ri.List("ws", 0)
+	if len(exchanges) != 2 {
+		t.Fatalf("expected two captured exchanges, got %d", len(exchanges))
+	}
+	truncated, captured := exchanges[0], exchanges[1]
+	if !truncated.Request.BodyTruncated || string(truncated.Request.Body) != "a very l" || !truncated.Response.BodyTruncated {
+		t.Errorf("expected bodies to be truncated: %+v", truncated)
+	}
+	if diff := cmp.Diff(&CapturedResponse{StatusCode: http.StatusCreated, Header: captured.Response.Header, Body: []byte("hello we"), BodyTruncated: true}, captured.Response, cmp.FilterPath(func(p cmp.Path) bool { return p.String() == "Header" }, cmp.Ignore())); diff != "" {
+		t.Errorf("unexpected captured response (-want +got):\n%s", diff)
+	}
+	if captured.Response.Header.Get("X-Backend") != "true" {
+		t.Errorf("expected response header to be captured: %v", captured.Response.Header)
+	}
+	if captured.Request.Header.Get("x-gitpod-owner-token") != "" {
+		t.Error("owner token must not be captured")
+	}
+	if captured.Request.URL != "/hook?x=1" || string(captured.Request.Body) != "webhook" {
+		t.Errorf("unexpected captured request: %+v", captured.Request)
+	}
+	if len(ri.List("ws", 1234)) != 0 {
+		t.Error("expected port filter to exclude exchanges of other ports")
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, inspectorPathPrefix+"/requests/"+truncated.ID+"/replay", nil))
+	if rr.Code != http.StatusBadGateway {
+		t.Errorf("expected replay of truncated request to fail, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, inspectorPathPrefix+"/requests/"+captured.ID+"/replay", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("replay failed: %d %s", rr.Code, rr.Body.String())
+	}
+	var replayed CapturedExchange
+	err := json.Unmarshal(rr.Body.Bytes(), &replayed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.ReplayOf != captured.ID || replayed.Response.StatusCode != http.StatusCreated {
+		t.Errorf("unexpected replay: %+v", replayed)
+	}
+	if diff := cmp.Diff([]string{"not captured", "webhook", "a very long payload", "webhook"}, received); diff != "" {
+		t.Errorf("unexpected requests at backend (-want +got):\n%s", diff)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, inspectorPathPrefix+"/requests", nil))
+	var listed []*CapturedExchange
+	err = json.Unmarshal(rr.Body.Bytes(), &listed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 3 || listed[0].ID != replayed.ID {
+		t.Errorf("expected replay to be listed first: %+v", listed)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, inspectorPathPrefix+"/requests/does-not-exist", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected unknown exchange to be not found, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, inspectorPathPrefix+"/capture/"+port, nil))
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("disabling capture failed: %d %s", rr.Code, rr.Body.String())
+	}
+	serve("no longer captured")
+	if len(ri.List("ws", 0)) != 3 {
+		t.Error("expected requests not to be captured after capturing was disabled")
+	}
+}
@@ -0,0 +1,548 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/oauth2"
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/ws-manager/api"
+)
+
+const (
+	// portAuthPathPrefix is the path prefix on protected port hosts which ws-proxy handles itself
+	portAuthPathPrefix       = "/_gitpod/port-auth/"
+	portAuthPasswordPath     = portAuthPathPrefix + "password"
+	portAuthOIDCLoginPath    = portAuthPathPrefix + "oidc/login"
+	portAuthOIDCCallbackPath = portAuthPathPrefix + "oidc/callback"
+
+	// portAuthSessionDuration is how long a visitor stays logged in to a protected port
+	portAuthSessionDuration = 12 * time.Hour
+	// portAuthStateDuration is how long an OIDC login may take
+	portAuthStateDuration = 10 * time.Minute
+)
+
+// PortProtection restricts access to a public workspace port. Visitors must connect from one of
+// the AllowedCIDRs (if any) and authenticate using the password or OIDC (if configured).
+// Only a hash of the password is known, see verifyPortPassword.
+type PortProtection struct {
+	Port         uint32              `json:"port"`
+	PasswordHash string              `json:"passwordHash,omitempty"`
+	AllowedCIDRs []string            `json:"allowedCIDRs,omitempty"`
+	OIDC         *OIDCPortProtection `json:"oidc,omitempty"`
+
+	// deny rejects all requests, e.g. because the protection could not be parsed
+	deny bool
+}
+
+// OIDCPortProtection requires visitors to log in with the OIDC issuer configured for ws-proxy.
+// If neither AllowedEmails nor AllowedDomains are set, every user of the issuer is admitted.
+type OIDCPortProtection struct {
+	AllowedEmails  []string `json:"allowedEmails,omitempty"`
+	AllowedDomains []string `json:"allowedDomains,omitempty"`
+}
+
+// PortProtectionFromJSON parses the port protection annotation of a workspace pod
+func PortProtectionFromJSON(data string) (map[uint32]*PortProtection, error) {
+	var prots []*PortProtection
+	err := json.Unmarshal([]byte(data), &prots)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[uint32]*PortProtection, len(prots))
+	for _, p := range prots {
+		res[p.Port] = p
+	}
+	return res, nil
+}
+
+// denyAllPorts protects all ports such that no request gets through
+func denyAllPorts(ports []*api.PortSpec) map[uint32]*PortProtection {
+	res := make(map[uint32]*PortProtection, len(ports))
+	for _, p := range ports {
+		res[p.Port] = &PortProtection{Port: p.Port, deny: true}
+	}
+	return res
+}
+
+// PortProtectionConfig configures how protected ports are enforced.
+type PortProtectionConfig struct {
+	OIDC *OIDCConfig `json:"oidc,omitempty"`
+
+	// TrustedProxies are the CIDRs or IPs of proxies in front of ws-proxy. The client IP reported
+	// in the X-Real-IP header is only used for requests coming from one of those.
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+}
+
+// OIDCConfig configures the OIDC issuer visitors of OIDC protected ports log in with.
+// The issuer must accept https://<port-host>/_gitpod/port-auth/oidc/callback as redirect URL
+// for all workspace port hosts.
+type OIDCConfig struct {
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"clientID"`
+	ClientSecret string `json:"clientSecret"`
+}
+
+// Validate validates the configuration to catch issues during startup and not at runtime.
+func (c *PortProtectionConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, p := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			return xerrors.Errorf("invalid port protection config: trusted proxy %q is neither a CIDR nor an IP", p)
+		}
+	}
+	if c.OIDC == nil {
+		return nil
+	}
+
+	err := validation.ValidateStruct(c.OIDC,
+		validation.Field(&c.OIDC.Issuer, validation.Required),
+		validation.Field(&c.OIDC.ClientID, validation.Required),
+		validation.Field(&c.OIDC.ClientSecret, validation.Required),
+	)
+	if err != nil {
+		return xerrors.Errorf("invalid port protection config: %w", err)
+	}
+	return nil
+}
+
+// PortProtector enforces the protection of public workspace ports
+type PortProtector struct {
+	Config *PortProtectionConfig
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// NewPortProtector creates a new port protector. cfg may be nil in which case OIDC protection is unavailable.
+func NewPortProtector(cfg *PortProtectionConfig) *PortProtector {
+	return &PortProtector{Config: cfg}
+}
+
+// portAuthSession is the content of the cookie which proves a visitor authenticated for a port
+type portAuthSession struct {
+	Method  string `json:"m"`
+	Subject string `json:"s,omitempty"`
+	Expires int64  `json:"e"`
+	// Nonce binds an OIDC login state to the browser which started the login
+	Nonce    string `json:"n,omitempty"`
+	Redirect string `json:"r,omitempty"`
+}
+
+// Handle enforces the port protection for a request. It returns true if the request may be passed on to
+// the workspace. Otherwise Handle has answered the request already.
+func (p *PortProtector) Handle(resp http.ResponseWriter, req *http.Request, ws *WorkspaceInfo, prot *PortProtection, cookiePrefix string) bool {
+	log := getLog(req.Context())
+
+	if prot.deny {
+		resp.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	if ip := p.clientIP(req); len(prot.AllowedCIDRs) > 0 && !isAllowedIP(ip, prot.AllowedCIDRs) {
+		log.WithField("clientIP", ip).Debug("rejecting request to protected port - IP not allowed")
+		resp.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	if prot.PasswordHash == "" && prot.OIDC == nil {
+		return true
+	}
+
+	var (
+		cookieName = fmt.Sprintf("%s%s_port_%d_auth_", cookiePrefix, ws.InstanceID, prot.Port)
+		stateName  = fmt.Sprintf("%s%s_port_%d_state_", cookiePrefix, ws.InstanceID, prot.Port)
+		key        = portAuthKey(ws, prot.Port)
+	)
+	switch req.URL.Path {
+	case portAuthPasswordPath:
+		if prot.PasswordHash != "" && req.Method == http.MethodPost {
+			p.handlePasswordLogin(resp, req, prot, key, cookieName)
+			return false
+		}
+	case portAuthOIDCLoginPath:
+		if prot.OIDC != nil {
+			p.handleOIDCLogin(resp, req, key, stateName)
+			return false
+		}
+	case portAuthOIDCCallbackPath:
+		if prot.OIDC != nil {
+			p.handleOIDCCallback(resp, req, prot, key, cookieName, stateName)
+			return false
+		}
+	}
+
+	if c, err := req.Cookie(cookieName); err == nil {
+		session, ok := verifyPortAuthSession(key, c.Value)
+		// the method must still be one the port accepts - OIDC login states in particular are no sessions
+		if ok && (session.Method == "password" && prot.PasswordHash != "" || session.Method == "oidc" && prot.OIDC != nil) {
+			removeCookie(req, cookieName)
+			removeCookie(req, stateName)
+			return true
+		}
+	}
+
+	if req.Method != http.MethodGet || !strings.Contains(req.Header.Get("Accept"), "text/html") {
+		resp.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	if prot.PasswordHash == "" {
+		http.Redirect(resp, req, portAuthOIDCLoginPath+"?redirect="+url.QueryEscape(req.URL.RequestURI()), http.StatusSeeOther)
+		return false
+	}
+	servePasswordForm(resp, req.URL.RequestURI(), prot.OIDC != nil, false)
+	return false
+}
+
+func (p *PortProtector) handlePasswordLogin(resp http.ResponseWriter, req *http.Request, prot *PortProtection, key []byte, cookieName string) {
+	redirect := safeRedirect(req.FormValue("redirect"))
+	password := req.FormValue("password")
+	if !verifyPortPassword(prot.PasswordHash, password) {
+		getLog(req.Context()).Debug("wrong password for protected port")
+		servePasswordForm(resp, redirect, prot.OIDC != nil, true)
+		return
+	}
+
+	setPortAuthCookie(resp, cookieName, key, portAuthSession{Method: "password"}, portAuthSessionDuration)
+	http.Redirect(resp, req, redirect, http.StatusSeeOther)
+}
+
+// verifyPortPassword checks a password against a hash of the form scrypt:<hex salt>:<hex key>
+// as produced by server when it starts a workspace
+func verifyPortPassword(hash, password string) bool {
+	segs := strings.Split(hash, ":")
+	if len(segs) != 3 || segs[0] != "scrypt" {
+		return false
+	}
+	salt, err := hex.DecodeString(segs[1])
+	if err != nil {
+		return false
+	}
+	expected, err := hex.DecodeString(segs[2])
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+	key, err := scrypt.Key([]byte(password), salt, 16384, 8, 1, len(expected))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(key, expected) == 1
+}
+
+func (p *PortProtector) handleOIDCLogin(resp http.ResponseWriter, req *http.Request, key []byte, stateName string) {
+	oauthCfg, _, err := p.oauth2Config(req.Context(), req)
+	if err != nil {
+		getLog(req.Context()).WithError(err).Error("cannot log in to protected port using OIDC")
+		http.Error(resp, "OIDC login is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	nonce := make([]byte, 16)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		http.Error(resp, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	state := portAuthSession{
+		Method:   "oidc-state",
+		Nonce:    base64.RawURLEncoding.EncodeToString(nonce),
+		Redirect: safeRedirect(req.URL.Query().Get("redirect")),
+	}
+	stateValue := setPortAuthCookie(resp, stateName, key, state, portAuthStateDuration)
+
+	http.Redirect(resp, req, oauthCfg.AuthCodeURL(stateValue, oidc.Nonce(state.Nonce)), http.StatusFound)
+}
+
+func (p *PortProtector) handleOIDCCallback(resp http.ResponseWriter, req *http.Request, prot *PortProtection, key []byte, cookieName, stateName string) {
+	log := getLog(req.Context())
+
+	c, err := req.Cookie(stateName)
+	if err != nil || c.Value != req.URL.Query().Get("state") {
+		http.Error(resp, "invalid login state", http.StatusBadRequest)
+		return
+	}
+	state, ok := verifyPortAuthSession(key, c.Value)
+	if !ok || state.Method != "oidc-state" {
+		http.Error(resp, "invalid login state", http.StatusBadRequest)
+		return
+	}
+
+	oauthCfg, provider, err := p.oauth2Config(req.Context(), req)
+	if err != nil {
+		log.WithError(err).Error("cannot log in to protected port using OIDC")
+		http.Error(resp, "OIDC login is not available", http.StatusServiceUnavailable)
+		return
+	}
+	token, err := oauthCfg.Exchange(req.Context(), req.URL.Query().Get("code"))
+	if err != nil {
+		log.WithError(err).Warn("cannot exchange OIDC code")
+		http.Error(resp, "login failed", http.StatusUnauthorized)
+		return
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		http.Error(resp, "login failed", http.StatusUnauthorized)
+		return
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: oauthCfg.ClientID}).Verify(req.Context(), rawIDToken)
+	if err != nil || idToken.Nonce != state.Nonce {
+		log.WithError(err).Warn("cannot verify OIDC ID token")
+		http.Error(resp, "login failed", http.StatusUnauthorized)
+		return
+	}
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+	}
+	err = idToken.Claims(&claims)
+	if err != nil {
+		http.Error(resp, "login failed", http.StatusUnauthorized)
+		return
+	}
+	if claims.EmailVerified != nil && !*claims.EmailVerified {
+		claims.Email = ""
+	}
+	if !isAllowedEmail(claims.Email, prot.OIDC) {
+		log.WithField("email", claims.Email).Debug("rejecting OIDC login to protected port")
+		http.Error(resp, "you are not allowed to access this port", http.StatusForbidden)
+		return
+	}
+
+	http.SetCookie(resp, &http.Cookie{Name: stateName, Path: "/", MaxAge: -1, Secure: true, HttpOnly: true})
+	setPortAuthCookie(resp, cookieName, key, portAuthSession{Method: "oidc", Subject: claims.Email}, portAuthSessionDuration)
+	http.Redirect(resp, req, safeRedirect(state.Redirect), http.StatusSeeOther)
+}
+
+// oauth2Config returns the OAuth2 config for the port host of req. The OIDC provider is discovered on first use.
+func (p *PortProtector) oauth2Config(ctx context.Context, req *http.Request) (*oauth2.Config, *oidc.Provider, error) {
+	if p == nil || p.Config == nil || p.Config.OIDC == nil {
+		return nil, nil, xerrors.Errorf("no OIDC issuer configured")
+	}
+	cfg := p.Config.OIDC
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.provider == nil {
+		provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+		if err != nil {
+			return nil, nil, xerrors.Errorf("cannot discover OIDC issuer %s: %w", cfg.Issuer, err)
+		}
+		p.provider = provider
+	}
+
+	return &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Endpoint:     p.provider.Endpoint(),
+		RedirectURL:  (&url.URL{Scheme: "https", Host: req.Host, Path: portAuthOIDCCallbackPath}).String(),
+		Scopes:       []string{oidc.ScopeOpenID, "email"},
+	}, p.provider, nil
+}
+
+// portAuthKey derives the key port auth cookies are signed with. Using the owner token ties
+// sessions to the workspace instance and invalidates them when the workspace restarts.
+func portAuthKey(ws *WorkspaceInfo, port uint32) []byte {
+	var ownerToken string
+	if ws.Auth != nil {
+		ownerToken = ws.Auth.OwnerToken
+	}
+	mac := hmac.New(sha256.New, []byte(ownerToken))
+	fmt.Fprintf(mac, "port-auth:%s:%d", ws.InstanceID, port)
+	return mac.Sum(nil)
+}
+
+func setPortAuthCookie(resp http.ResponseWriter, name string, key []byte, session portAuthSession, validity time.Duration) string {
+	session.Expires = time.Now().Add(validity).Unix()
+	value := signPortAuthSession(key, session)
+	http.SetCookie(resp, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(validity.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return value
+}
+
+func signPortAuthSession(key []byte, session portAuthSession) string {
+	payload, _ := json.Marshal(session)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func verifyPortAuthSession(key []byte, value string) (*portAuthSession, bool) {
+	segs := strings.Split(value, ".")
+	if len(segs) != 2 {
+		return nil, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(segs[0])
+	if err != nil {
+		return nil, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(segs[1])
+	if err != nil {
+		return nil, false
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, false
+	}
+
+	var session portAuthSession
+	err = json.Unmarshal(payload, &session)
+	if err != nil {
+		return nil, false
+	}
+	if time.Now().Unix() > session.Expires {
+		return nil, false
+	}
+	return &session, true
+}
+
+// removeCookie removes a cookie from the request so that it does not reach the workspace
+func removeCookie(req *http.Request, name string) {
+	cookies := req.Cookies()
+	req.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name == name {
+			continue
+		}
+		req.AddCookie(c)
+	}
+}
+
+// clientIP returns the IP of the client. The X-Real-IP header is only honoured if the request
+// comes from a trusted proxy, as anyone else could use it to spoof their IP.
+func (p *PortProtector) clientIP(req *http.Request) net.IP {
+	var remote net.IP
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		remote = net.ParseIP(host)
+	}
+	if p == nil || p.Config == nil || !isAllowedIP(remote, p.Config.TrustedProxies) {
+		return remote
+	}
+	if ip := net.ParseIP(req.Header.Get("X-Real-IP")); ip != nil {
+		return ip
+	}
+	return remote
+}
+
+func isAllowedIP(ip net.IP, cidrs []string) bool {
+	if ip == nil {
+		return false
+	}
+	for _, c := range cidrs {
+		_, network, err := net.ParseCIDR(c)
+		if err != nil {
+			if single := net.ParseIP(c); single != nil && single.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAllowedEmail(email string, prot *OIDCPortProtection) bool {
+	if len(prot.AllowedEmails) == 0 && len(prot.AllowedDomains) == 0 {
+		return true
+	}
+	if email == "" {
+		return false
+	}
+	email = strings.ToLower(email)
+	for _, e := range prot.AllowedEmails {
+		if strings.ToLower(e) == email {
+			return true
+		}
+	}
+	for _, d := range prot.AllowedDomains {
+		if strings.HasSuffix(email, "@"+strings.ToLower(d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// safeRedirect makes sure we only ever redirect to paths on the same host
+func safeRedirect(redirect string) string {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") || strings.HasPrefix(redirect, "/\\") || strings.HasPrefix(redirect, portAuthPathPrefix) {
+		return "/"
+	}
+	return redirect
+}
+
+var passwordFormTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Protected port</title>
+<style>
+body { font-family: sans-serif; display: flex; justify-content: center; margin-top: 15vh; }
+form { display: flex; flex-direction: column; gap: 0.5em; min-width: 18em; }
+.error { color: #c00; }
+</style>
+</head>
+<body>
+<form method="POST" action="{{ .Action }}">
+<h2>This port is password protected</h2>
+{{ if .Failed }}<p class="error">Wrong password, please try again.</p>{{ end }}
+<input type="hidden" name="redirect" value="{{ .Redirect }}">
+<input type="password" name="password" placeholder="Password" autofocus required>
+<button type="submit">Continue</button>
+{{ if .OIDC }}<a href="{{ .OIDCLogin }}">Log in with single sign-on instead</a>{{ end }}
+</form>
+</body>
+</html>
+`))
+
+func servePasswordForm(resp http.ResponseWriter, redirect string, withOIDC, failed bool) {
+	redirect = safeRedirect(redirect)
+	resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	resp.Header().Set("Cache-Control", "no-store")
+	resp.WriteHeader(http.StatusUnauthorized)
+	passwordFormTemplate.Execute(resp, struct {
+		Action    string
+		Redirect  string
+		OIDC      bool
+		OIDCLogin string
+		Failed    bool
+	}{
+		Action:    portAuthPasswordPath,
+		Redirect:  redirect,
+		OIDC:      withOIDC,
+		OIDCLogin: portAuthOIDCLoginPath + "?redirect=" + url.QueryEscape(redirect),
+		Failed:    failed,
+	})
+}
@@ -0,0 +1,313 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/square/go-jose.v2"
+
+	"github.com/gitpod-io/gitpod/ws-manager/api"
+)
+
+const (
+	testPortHost     = "3000-workspac-65f4-43c9-bf46-3541b89dca85.test-domain.com"
+	testCookiePrefix = "_test_domain_com_ws_"
+)
+
+var testPortWorkspace = &WorkspaceInfo{
+	WorkspaceID: "workspac-65f4-43c9-bf46-3541b89dca85",
+	InstanceID:  "instance-fce1-4ff6-9364-cf6dff0c4ecf",
+	Auth:        &api.WorkspaceAuthentication{OwnerToken: "owner-token"},
+}
+
+type portProtectionResult struct {
+	Passed     bool
+	StatusCode int
+	Location   string
+}
+
+func handleProtected(p *PortProtector, prot *PortProtection, req *http.Request) (portProtectionResult, *httptest.ResponseRecorder) {
+	rr := httptest.NewRecorder()
+	passed := p.Handle(rr, req, testPortWorkspace, prot, testCookiePrefix)
+	return portProtectionResult{
+		Passed:     passed,
+		StatusCode: rr.Code,
+		Location:   rr.Header().Get("Location"),
+	}, rr
+}
+
+func TestPortProtectionCIDR(t *testing.T) {
+	var (
+		prot    = &PortProtection{Port: 3000, AllowedCIDRs: []string{"10.0.0.0/8", "192.168.1.1"}}
+		proxyIP = "172.16.0.5:4242"
+	)
+	tests := []struct {
+		Name       string
+		RemoteAddr string
+		RealIP     string
+		Config     *PortProtectionConfig
+		Expected   portProtectionResult
+	}{
+		{Name: "in range", RemoteAddr: "10.1.2.3:4242", Expected: portProtectionResult{Passed: true, StatusCode: http.StatusOK}},
+		{Name: "single IP", RemoteAddr: "192.168.1.1:4242", Expected: portProtectionResult{Passed: true, StatusCode: http.StatusOK}},
+		{Name: "out of range", RemoteAddr: "192.168.1.2:4242", Expected: portProtectionResult{StatusCode: http.StatusForbidden}},
+		{Name: "no IP", RemoteAddr: "invalid", Expected: portProtectionResult{StatusCode: http.StatusForbidden}},
+		{Name: "trusted proxy in range", RemoteAddr: proxyIP, RealIP: "10.1.2.3", Config: &PortProtectionConfig{TrustedProxies: []string{"172.16.0.0/12"}}, Expected: portProtectionResult{Passed: true, StatusCode: http.StatusOK}},
+		{Name: "trusted proxy out of range", RemoteAddr: proxyIP, RealIP: "192.168.1.2", Config: &PortProtectionConfig{TrustedProxies: []string{"172.16.0.5"}}, Expected: portProtectionResult{StatusCode: http.StatusForbidden}},
+		{Name: "spoofed header without trusted proxies", RemoteAddr: "192.168.1.2:4242", RealIP: "10.1.2.3", Expected: portProtectionResult{StatusCode: http.StatusForbidden}},
+		{Name: "spoofed header from untrusted client", RemoteAddr: "192.168.1.2:4242", RealIP: "10.1.2.3", Config: &PortProtectionConfig{TrustedProxies: []string{"172.16.0.0/12"}}, Expected: portProtectionResult{StatusCode: http.StatusForbidden}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "https://"+testPortHost+"/", nil)
+			req.RemoteAddr = test.RemoteAddr
+			if test.RealIP != "" {
+				req.Header.Set("X-Real-IP", test.RealIP)
+			}
+			res, _ := handleProtected(NewPortProtector(test.Config), prot, req)
+			if diff := cmp.Diff(test.Expected, res); diff != "" {
+				t.Errorf("unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// testPasswordHash is the hash server produces for the password "secret" with salt 00112233445566778899aabbccddeeff
+const testPasswordHash = "scrypt:00112233445566778899aabbccddeeff:3e83302f4925189a3822090d5b99c3609fec7126bef8f441f058461279235e79"
+
+func TestVerifyPortPassword(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Hash     string
+		Password string
+		Expected bool
+	}{
+		{Name: "correct password", Hash: testPasswordHash, Password: "secret", Expected: true},
+		{Name: "wrong password", Hash: testPasswordHash, Password: "wrong"},
+		{Name: "plaintext is no hash", Hash: "secret", Password: "secret"},
+		{Name: "unknown algorithm", Hash: "md5:00:5ebe2294ecd0e0f08eab7690d2a6ee69", Password: "secret"},
+		{Name: "empty key", Hash: "scrypt:00112233:", Password: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if act := verifyPortPassword(test.Hash, test.Password); act != test.Expected {
+				t.Errorf("verifyPortPassword() = %v, expected %v", act, test.Expected)
+			}
+		})
+	}
+}
+
+func TestPortProtectionFromJSON(t *testing.T) {
+	// the annotation as server produces it, with port ranges expanded to numeric ports
+	prots, err := PortProtectionFromJSON(`[{"port":3000,"passwordHash":"` + testPasswordHash + `"},{"port":3001,"allowedCIDRs":["10.0.0.0/8"]}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prots) != 2 || prots[3000].PasswordHash != testPasswordHash || len(prots[3001].AllowedCIDRs) != 1 {
+		t.Errorf("unexpected port protection: %v", prots)
+	}
+}
+
+func TestPortProtectionPassword(t *testing.T) {
+	var (
+		p    = NewPortProtector(nil)
+		prot = &PortProtection{Port: 3000, PasswordHash: testPasswordHash}
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "https://"+testPortHost+"/app?x=1", nil)
+	req.Header.Set("Accept", "text/html")
+	res, rr := handleProtected(p, prot, req)
+	if diff := cmp.Diff(portProtectionResult{StatusCode: http.StatusUnauthorized}, res); diff != "" {
+		t.Errorf("unexpected result for browser request (-want +got):\n%s", diff)
+	}
+	if !strings.Contains(rr.Body.String(), `value="/app?x=1"`) {
+		t.Errorf("expected password form redirecting to the original URL, got:\n%s", rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "https://"+testPortHost+portAuthPasswordPath, strings.NewReader(url.Values{"password": {"wrong"}, "redirect": {"/app"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, rr = handleProtected(p, prot, req)
+	if res.StatusCode != http.StatusUnauthorized || len(rr.Result().Cookies()) != 0 {
+		t.Errorf("expected wrong password to be rejected: %v", res)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "https://"+testPortHost+portAuthPasswordPath, strings.NewReader(url.Values{"password": {"secret"}, "redirect": {"//evil.com/"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	res, rr = handleProtected(p, prot, req)
+	if diff := cmp.Diff(portProtectionResult{StatusCode: http.StatusSeeOther, Location: "/"}, res); diff != "" {
+		t.Errorf("unexpected result for password login (-want +got):\n%s", diff)
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected a session cookie, got %v", cookies)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "https://"+testPortHost+"/app", nil)
+	req.AddCookie(cookies[0])
+	req.AddCookie(&http.Cookie{Name: "app-cookie", Value: "foo"})
+	res, _ = handleProtected(p, prot, req)
+	if !res.Passed {
+		t.Errorf("expected session cookie to grant access: %v", res)
+	}
+	if c := req.Cookies(); len(c) != 1 || c[0].Name != "app-cookie" {
+		t.Errorf("expected session cookie to be removed before proxying, got %v", c)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "https://"+testPortHost+"/app", nil)
+	req.AddCookie(cookies[0])
+	res, _ = handleProtected(p, &PortProtection{Port: 3001, PasswordHash: testPasswordHash}, req)
+	if res.Passed {
+		t.Error("expected session cookie of another port to be rejected")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "https://"+testPortHost+"/api", nil)
+	res, _ = handleProtected(p, prot, req)
+	if diff := cmp.Diff(portProtectionResult{StatusCode: http.StatusUnauthorized}, res); diff != "" {
+		t.Errorf("unexpected result for non-browser request (-want +got):\n%s", diff)
+	}
+}
+
+func TestPortProtectionOIDC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: &jose.JSONWebKey{Key: key, KeyID: "test"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		nonce string
+		email = "jane@example.com"
+	)
+	mux := http.NewServeMux()
+	issuer := httptest.NewServer(mux)
+	defer issuer.Close()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                issuer.URL,
+			"authorization_endpoint":                issuer.URL + "/auth",
+			"token_endpoint":                        issuer.URL + "/token",
+			"jwks_uri":                              issuer.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "test", Algorithm: "RS256", Use: "sig"}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := json.Marshal(map[string]interface{}{
+			"iss":   issuer.URL,
+			"sub":   "jane",
+			"aud":   "client",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": nonce,
+			"email": email,
+		})
+		sig, err := signer.Sign(claims)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		idToken, _ := sig.CompactSerialize()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     idToken,
+		})
+	})
+
+	var (
+		p    = NewPortProtector(&PortProtectionConfig{OIDC: &OIDCConfig{Issuer: issuer.URL, ClientID: "client", ClientSecret: "secret"}})
+		prot = &PortProtection{Port: 3000, OIDC: &OIDCPortProtection{AllowedDomains: []string{"example.com"}}}
+	)
+
+	login := func(t *testing.T) (*http.Cookie, string) {
+		req := httptest.NewRequest(http.MethodGet, "https://"+testPortHost+"/app", nil)
+		req.Header.Set("Accept", "text/html")
+		res, _ := handleProtected(p, prot, req)
+		if res.StatusCode != http.StatusSeeOther || !strings.HasPrefix(res.Location, portAuthOIDCLoginPath) {
+			t.Fatalf("expected redirect to OIDC login, got %v", res)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "https://"+testPortHost+res.Location, nil)
+		res, rr := handleProtected(p, prot, req)
+		if res.StatusCode != http.StatusFound || !strings.HasPrefix(res.Location, issuer.URL+"/auth") {
+			t.Fatalf("expected redirect to issuer, got %v", res)
+		}
+		authURL, _ := url.Parse(res.Location)
+		nonce = authURL.Query().Get("nonce")
+		if redirect := authURL.Query().Get("redirect_uri"); redirect != "https://"+testPortHost+portAuthOIDCCallbackPath {
+			t.Errorf("unexpected redirect URL: %s", redirect)
+		}
+		return rr.Result().Cookies()[0], authURL.Query().Get("state")
+	}
+
+	t.Run("allowed user", func(t *testing.T) {
+		stateCookie, state := login(t)
+
+		// a login state must not work as session
+		req := httptest.NewRequest(http.MethodGet, "https://"+testPortHost+"/app", nil)
+		req.AddCookie(&http.Cookie{Name: strings.Replace(stateCookie.Name, "_state_", "_auth_", 1), Value: stateCookie.Value})
+		res, _ := handleProtected(p, prot, req)
+		if res.Passed {
+			t.Fatal("expected login state to be rejected as session")
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "https://"+testPortHost+portAuthOIDCCallbackPath+"?code=code&state="+url.QueryEscape(state), nil)
+		req.AddCookie(stateCookie)
+		res, rr := handleProtected(p, prot, req)
+		if diff := cmp.Diff(portProtectionResult{StatusCode: http.StatusSeeOther, Location: "/app"}, res); diff != "" {
+			t.Fatalf("unexpected callback result (-want +got):\n%s", diff)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "https://"+testPortHost+"/app", nil)
+		for _, c := range rr.Result().Cookies() {
+			if c.MaxAge > 0 {
+				req.AddCookie(c)
+			}
+		}
+		res, _ = handleProtected(p, prot, req)
+		if !res.Passed {
+			t.Errorf("expected OIDC session to grant access: %v", res)
+		}
+	})
+
+	t.Run("user of other domain", func(t *testing.T) {
+		email = "john@other.com"
+		defer func() { email = "jane@example.com" }()
+
+		stateCookie, state := login(t)
+		req := httptest.NewRequest(http.MethodGet, "https://"+testPortHost+portAuthOIDCCallbackPath+"?code=code&state="+url.QueryEscape(state), nil)
+		req.AddCookie(stateCookie)
+		res, _ := handleProtected(p, prot, req)
+		if res.StatusCode != http.StatusForbidden {
+			t.Errorf("expected user of other domain to be rejected, got %v", res)
+		}
+	})
+
+	t.Run("state mismatch", func(t *testing.T) {
+		stateCookie, _ := login(t)
+		req := httptest.NewRequest(http.MethodGet, "https://"+testPortHost+portAuthOIDCCallbackPath+"?code=code&state=forged", nil)
+		req.AddCookie(stateCookie)
+		res, _ := handleProtected(p, prot, req)
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected forged state to be rejected, got %v", res)
+		}
+	})
+}
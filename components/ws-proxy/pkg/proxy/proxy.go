@@ -5,13 +5,19 @@
 package proxy
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/gorilla/mux"
 	"github.com/klauspost/cpuid/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/acme"
 	"golang.org/x/crypto/ssh"
 
 	"github.com/gitpod-io/gitpod/common-go/log"
@@ -24,6 +30,18 @@ type WorkspaceProxy struct {
 	WorkspaceRouter       WorkspaceRouter
 	WorkspaceInfoProvider WorkspaceInfoProvider
 	SSHHostSigners        []ssh.Signer
+	// MetricsRegistry is where the proxy registers its metrics. If nil, no metrics are registered.
+	MetricsRegistry prometheus.Registerer
+	// Connections tracks the websockets and long-polls served by the proxy
+	Connections *ConnectionTracker
+	// Traffic counts the traffic served per workspace. If nil, traffic is not accounted for.
+	Traffic *TrafficMeter
+
+	mu                 sync.Mutex
+	httpServer         *http.Server
+	httpsServer        *http.Server
+	customDomainServer *http.Server
+	tcpPassthrough     net.Listener
 }
 
 // NewWorkspaceProxy creates a new workspace proxy.
@@ -34,6 +52,7 @@ func NewWorkspaceProxy(ingress HostBasedIngressConfig, config Config, workspaceR
 		WorkspaceRouter:       workspaceRouter,
 		WorkspaceInfoProvider: workspaceInfoProvider,
 		SSHHostSigners:        signers,
+		Connections:           NewConnectionTracker(),
 	}
 }
 
@@ -66,6 +85,10 @@ func (p *WorkspaceProxy) MustServe() {
 		},
 	}
 
+	httpSrv := &http.Server{
+		Addr: p.Ingress.HTTPAddress,
+	}
+
 	var (
 		crt = p.Config.HTTPS.Certificate
 		key = p.Config.HTTPS.Key
@@ -74,20 +97,143 @@ func (p *WorkspaceProxy) MustServe() {
 		crt = filepath.Join(tproot, crt)
 		key = filepath.Join(tproot, key)
 	}
-	go func() {
-		err := http.ListenAndServe(p.Ingress.HTTPAddress, http.HandlerFunc(redirectToHTTPS))
+	httpSrv.Handler = http.HandlerFunc(redirectToHTTPS)
+	var customDomainSrv *http.Server
+	if p.Config.CustomDomains != nil {
+		resolver, ok := p.WorkspaceInfoProvider.(CustomDomainResolver)
+		if !ok {
+			log.Fatal("custom domains are configured, but the workspace info provider cannot resolve them")
+			return
+		}
+		mgr, err := newCustomDomainCertManager(p.Config.CustomDomains, resolver)
+		if err != nil {
+			log.WithError(err).Fatal("cannot set up certificate management for custom domains")
+			return
+		}
+		srv.TLSConfig.GetCertificate = customDomainGetCertificate(mgr, p.Config.GitpodInstallation.WorkspaceHostSuffix)
+		srv.TLSConfig.NextProtos = append(srv.TLSConfig.NextProtos, acme.ALPNProto)
+		// http-01 challenges are answered on the plain HTTP port
+		httpSrv.Handler = mgr.HTTPHandler(httpSrv.Handler)
+
+		if addr := p.Config.CustomDomains.Address; addr != "" {
+			customDomainSrv = &http.Server{
+				Addr:      addr,
+				Handler:   customDomainHandler(handler, p.Ingress.Header),
+				TLSConfig: srv.TLSConfig.Clone(),
+			}
+		}
+	}
+
+	if cfg := p.Config.TCPPassthrough; cfg != nil {
+		cert, err := tls.LoadX509KeyPair(crt, key)
+		if err != nil {
+			log.WithError(err).Fatal("cannot load certificate for TCP passthrough")
+			return
+		}
+		tlsConfig := srv.TLSConfig.Clone()
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		tlsConfig.NextProtos = nil
+
+		l, err := net.Listen("tcp", cfg.Address)
+		if err != nil {
+			log.WithError(err).Fatal("cannot start TCP passthrough")
+			return
+		}
+		p.mu.Lock()
+		p.tcpPassthrough = l
+		p.mu.Unlock()
+		passthrough := NewTCPPassthrough(p.Config.GitpodInstallation.WorkspaceHostSuffix, p.WorkspaceInfoProvider, tlsConfig)
+		passthrough.ProxyProtocol = cfg.ProxyProtocol
+		go func() {
+			err := passthrough.Serve(l)
+			if err != nil && !errors.Is(err, net.ErrClosed) {
+				log.WithError(err).Fatal("cannot serve TCP passthrough")
+			}
+		}()
+		log.WithField("address", cfg.Address).Info("TCP passthrough is up and running")
+	}
+
+	if customDomainSrv != nil {
+		l, err := net.Listen("tcp", customDomainSrv.Addr)
 		if err != nil {
+			log.WithError(err).Fatal("cannot start custom domain proxy")
+			return
+		}
+		if p.Config.CustomDomains.ProxyProtocol {
+			l = &proxyProtocolListener{Listener: l}
+		}
+		go func() {
+			err := customDomainSrv.ServeTLS(l, crt, key)
+			if err != nil && err != http.ErrServerClosed {
+				log.WithError(err).Fatal("cannot serve custom domains")
+			}
+		}()
+		log.WithField("address", customDomainSrv.Addr).Info("custom domain proxy is up and running")
+	}
+
+	p.mu.Lock()
+	p.httpServer, p.httpsServer, p.customDomainServer = httpSrv, srv, customDomainSrv
+	p.mu.Unlock()
+
+	go func() {
+		err := httpSrv.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
 			log.WithError(err).Fatal("cannot start http proxy")
 		}
 	}()
 
 	err = srv.ListenAndServeTLS(crt, key)
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		log.WithError(err).Fatal("cannot start proxy")
 		return
 	}
 }
 
+// Shutdown stops accepting new connections, asks websocket clients to reconnect and waits
+// for open connections to finish until ctx is done. Remaining connections are closed then,
+// and the traffic served since the last flush is reported.
+func (p *WorkspaceProxy) Shutdown(ctx context.Context) {
+	p.mu.Lock()
+	servers := []*http.Server{p.httpsServer, p.httpServer, p.customDomainServer}
+	tcpPassthrough := p.tcpPassthrough
+	p.mu.Unlock()
+
+	if tcpPassthrough != nil {
+		_ = tcpPassthrough.Close()
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.Connections.Drain(ctx)
+	}()
+	for _, srv := range servers {
+		if srv == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			// hijacked connections (i.e. websockets) are not part of the shutdown, but drained by the connection tracker
+			err := srv.Shutdown(ctx)
+			if err != nil {
+				log.WithError(err).WithField("address", srv.Addr).Info("closing remaining connections")
+				_ = srv.Close()
+			}
+		}(srv)
+	}
+	wg.Wait()
+
+	// report the traffic served since the last flush, even if the drain period is over already
+	flushCtx, cancel := context.WithTimeout(context.Background(), trafficFlushTimeout)
+	defer cancel()
+	err := p.Traffic.Flush(flushCtx)
+	if err != nil {
+		log.WithError(err).Warn("cannot flush traffic counters")
+	}
+}
+
 // Handler returns the HTTP handler that serves the proxy routes.
 func (p *WorkspaceProxy) Handler() (http.Handler, error) {
 	r := mux.NewRouter()
@@ -97,6 +243,14 @@ func (p *WorkspaceProxy) Handler() (http.Handler, error) {
 	if err != nil {
 		return nil, err
 	}
+	handlerConfig.Connections = p.Connections
+	handlerConfig.Traffic = p.Traffic
+	if p.MetricsRegistry != nil {
+		err = handlerConfig.RateLimiter.RegisterMetrics(p.MetricsRegistry)
+		if err != nil {
+			return nil, err
+		}
+	}
 	ideRouter, portRouter, blobserveRouter := p.WorkspaceRouter(r, p.WorkspaceInfoProvider)
 	installWorkspaceRoutes(ideRouter, handlerConfig, p.WorkspaceInfoProvider, p.SSHHostSigners)
 	err = installWorkspacePortRoutes(portRouter, handlerConfig, p.WorkspaceInfoProvider)
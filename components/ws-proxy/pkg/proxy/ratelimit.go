@@ -0,0 +1,364 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"golang.org/x/xerrors"
+)
+
+const (
+	metricsNamespace = "gitpod"
+	metricsSubsystem = "ws_proxy"
+
+	// rateLimitIdleTimeout is the time after which the state of an unused limit is forgotten
+	rateLimitIdleTimeout = 10 * time.Minute
+	// minBandwidthBurst is the smallest chunk in which bandwidth limited traffic is passed on
+	minBandwidthBurst = 32 * 1024
+)
+
+// RateLimitConfig configures the limits of workspace port traffic
+type RateLimitConfig struct {
+	// Workspace limits the traffic of all ports of a workspace combined
+	Workspace *RateLimit `json:"workspace,omitempty"`
+	// Port limits the traffic of each port of a workspace
+	Port *RateLimit `json:"port,omitempty"`
+}
+
+// Validate validates the configuration to catch issues during startup and not at runtime.
+func (c *RateLimitConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, l := range []*RateLimit{c.Workspace, c.Port} {
+		if l == nil {
+			continue
+		}
+		if l.RequestsPerSecond < 0 || l.Burst < 0 || l.MaxConcurrent < 0 || l.BytesPerSecond < 0 {
+			return xerrors.Errorf("rate limits must not be negative")
+		}
+		if l.RequestsPerSecond > 0 && l.Burst == 0 {
+			return xerrors.Errorf("rate limit burst must be set if requestsPerSecond is")
+		}
+	}
+	return nil
+}
+
+// RateLimit is a set of token-bucket limits. Zero values disable the respective limit.
+type RateLimit struct {
+	// RequestsPerSecond is the rate at which requests are admitted, Burst the number of requests
+	// which can be admitted at once.
+	RequestsPerSecond float64 `json:"requestsPerSecond,omitempty"`
+	Burst             int     `json:"burst,omitempty"`
+	// MaxConcurrent is the number of requests and connections (e.g. websockets) served at the same time
+	MaxConcurrent int `json:"maxConcurrent,omitempty"`
+	// BytesPerSecond limits the bandwidth of request and response bodies. Exceeding traffic is slowed down, not rejected.
+	BytesPerSecond int64 `json:"bytesPerSecond,omitempty"`
+}
+
+// limitState is the state of a RateLimit for one workspace or port
+type limitState struct {
+	requests  *rate.Limiter
+	bandwidth *rate.Limiter
+	inflight  int
+	lastUsed  time.Time
+}
+
+func newLimitState(l *RateLimit) *limitState {
+	res := &limitState{}
+	if l.RequestsPerSecond > 0 {
+		res.requests = rate.NewLimiter(rate.Limit(l.RequestsPerSecond), l.Burst)
+	}
+	if l.BytesPerSecond > 0 {
+		burst := l.BytesPerSecond
+		if burst < minBandwidthBurst {
+			burst = minBandwidthBurst
+		}
+		res.bandwidth = rate.NewLimiter(rate.Limit(l.BytesPerSecond), int(burst))
+	}
+	return res
+}
+
+type limitKey struct {
+	WorkspaceID string
+	Port        string
+}
+
+// RateLimiter enforces rate limits on workspace port traffic
+type RateLimiter struct {
+	Config RateLimitConfig
+
+	mu     sync.Mutex
+	states map[limitKey]*limitState
+
+	rejectedTotal    *prometheus.CounterVec
+	throttledSeconds *prometheus.CounterVec
+	inflight         *prometheus.GaugeVec
+}
+
+// NewRateLimiter creates a new rate limiter. If cfg is nil, nil is returned and no traffic is limited.
+func NewRateLimiter(cfg *RateLimitConfig) *RateLimiter {
+	if cfg == nil || (cfg.Workspace == nil && cfg.Port == nil) {
+		return nil
+	}
+
+	return &RateLimiter{
+		Config: *cfg,
+		states: make(map[limitKey]*limitState),
+		rejectedTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "port_requests_rate_limited_total",
+			Help:      "Number of requests to workspace ports rejected due to rate limits",
+		}, []string{"workspace", "reason"}),
+		throttledSeconds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "port_bandwidth_throttled_seconds_total",
+			Help:      "Time workspace port traffic was delayed due to bandwidth limits",
+		}, []string{"workspace"}),
+		inflight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "port_requests_inflight",
+			Help:      "Number of requests and connections currently served by workspace ports",
+		}, []string{"workspace"}),
+	}
+}
+
+// RegisterMetrics registers the rate limit metrics with the registry
+func (rl *RateLimiter) RegisterMetrics(reg prometheus.Registerer) error {
+	if rl == nil {
+		return nil
+	}
+	for _, c := range []prometheus.Collector{rl.rejectedTotal, rl.throttledSeconds, rl.inflight} {
+		err := reg.Register(c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// admission is the outcome of admitting a request
+type admission struct {
+	states     []*limitState
+	retryAfter time.Duration
+	reason     string
+}
+
+// admit checks if a request to the port of a workspace is within all limits and marks it as in flight if so.
+func (rl *RateLimiter) admit(workspaceID, port string, now time.Time) admission {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	var (
+		keys   []limitKey
+		limits []*RateLimit
+	)
+	if rl.Config.Workspace != nil {
+		keys, limits = append(keys, limitKey{WorkspaceID: workspaceID}), append(limits, rl.Config.Workspace)
+	}
+	if rl.Config.Port != nil {
+		keys, limits = append(keys, limitKey{WorkspaceID: workspaceID, Port: port}), append(limits, rl.Config.Port)
+	}
+
+	states := make([]*limitState, len(keys))
+	for i, k := range keys {
+		state, ok := rl.states[k]
+		if !ok {
+			rl.gc(now)
+			state = newLimitState(limits[i])
+			rl.states[k] = state
+		}
+		state.lastUsed = now
+		states[i] = state
+
+		if limits[i].MaxConcurrent > 0 && state.inflight >= limits[i].MaxConcurrent {
+			return admission{retryAfter: time.Second, reason: "concurrency"}
+		}
+	}
+
+	var reservations []*rate.Reservation
+	for _, state := range states {
+		if state.requests == nil {
+			continue
+		}
+		r := state.requests.ReserveN(now, 1)
+		if delay := r.DelayFrom(now); !r.OK() || delay > 0 {
+			r.CancelAt(now)
+			for _, prev := range reservations {
+				prev.CancelAt(now)
+			}
+			if !r.OK() {
+				delay = time.Second
+			}
+			return admission{retryAfter: delay, reason: "rate"}
+		}
+		reservations = append(reservations, r)
+	}
+
+	for _, state := range states {
+		state.inflight++
+	}
+	return admission{states: states}
+}
+
+func (rl *RateLimiter) release(workspaceID string, adm admission) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	for _, state := range adm.states {
+		state.inflight--
+		state.lastUsed = now
+	}
+	rl.inflight.WithLabelValues(workspaceID).Dec()
+}
+
+// gc forgets the limit state which was not used for some time. Callers must hold rl.mu.
+func (rl *RateLimiter) gc(now time.Time) {
+	forgotten := make(map[string]struct{})
+	for k, state := range rl.states {
+		if state.inflight > 0 || now.Sub(state.lastUsed) < rateLimitIdleTimeout {
+			continue
+		}
+		delete(rl.states, k)
+		forgotten[k.WorkspaceID] = struct{}{}
+	}
+	for k := range rl.states {
+		delete(forgotten, k.WorkspaceID)
+	}
+
+	// drop the metrics of workspaces we no longer track to keep their cardinality in check
+	for wsid := range forgotten {
+		for _, reason := range []string{"concurrency", "rate"} {
+			rl.rejectedTotal.DeleteLabelValues(wsid, reason)
+		}
+		rl.throttledSeconds.DeleteLabelValues(wsid)
+		rl.inflight.DeleteLabelValues(wsid)
+	}
+}
+
+// Handler rejects requests which exceed the configured limits with 429 Too Many Requests
+// and slows down traffic which exceeds the bandwidth limits.
+func (rl *RateLimiter) Handler(h http.Handler) http.Handler {
+	if rl == nil {
+		return h
+	}
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		coords := getWorkspaceCoords(req)
+		adm := rl.admit(coords.ID, coords.Port, time.Now())
+		if adm.states == nil {
+			rl.rejectedTotal.WithLabelValues(coords.ID, adm.reason).Inc()
+			getLog(req.Context()).WithField("reason", adm.reason).Debug("rate limited request to workspace port")
+
+			resp.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(adm.retryAfter.Seconds()))))
+			http.Error(resp, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		rl.inflight.WithLabelValues(coords.ID).Inc()
+		defer rl.release(coords.ID, adm)
+
+		var bandwidth []*rate.Limiter
+		for _, state := range adm.states {
+			if state.bandwidth != nil {
+				bandwidth = append(bandwidth, state.bandwidth)
+			}
+		}
+		// websocket connections are hijacked and not subject to the bandwidth limits
+		if len(bandwidth) > 0 && req.Header.Get("Upgrade") == "" {
+			throttle := &bandwidthThrottle{
+				ctx:      req.Context(),
+				limiters: bandwidth,
+				waited:   rl.throttledSeconds.WithLabelValues(coords.ID),
+			}
+			if req.Body != nil && req.Body != http.NoBody {
+				req.Body = &throttledReadCloser{ReadCloser: req.Body, throttle: throttle}
+			}
+			resp = &throttledResponseWriter{ResponseWriter: resp, throttle: throttle}
+		}
+
+		h.ServeHTTP(resp, req)
+	})
+}
+
+// bandwidthThrottle delays traffic until all of its token buckets admit it
+type bandwidthThrottle struct {
+	ctx      context.Context
+	limiters []*rate.Limiter
+	waited   prometheus.Counter
+}
+
+func (t *bandwidthThrottle) wait(n int) error {
+	start := time.Now()
+	defer func() {
+		if d := time.Since(start); d > time.Millisecond {
+			t.waited.Add(d.Seconds())
+		}
+	}()
+
+	for n > 0 {
+		chunk := n
+		for _, l := range t.limiters {
+			if chunk > l.Burst() {
+				chunk = l.Burst()
+			}
+		}
+		for _, l := range t.limiters {
+			err := l.WaitN(t.ctx, chunk)
+			if err != nil {
+				return err
+			}
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+type throttledReadCloser struct {
+	io.ReadCloser
+	throttle *bandwidthThrottle
+}
+
+func (r *throttledReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		if werr := r.throttle.wait(n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+type throttledResponseWriter struct {
+	http.ResponseWriter
+	throttle *bandwidthThrottle
+}
+
+func (w *throttledResponseWriter) Write(p []byte) (int, error) {
+	err := w.throttle.wait(len(p))
+	if err != nil {
+		return 0, err
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush keeps streaming responses (e.g. server-sent events) working
+func (w *throttledResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
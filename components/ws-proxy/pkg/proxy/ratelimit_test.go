@@ -0,0 +1,192 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRateLimiterAdmit(t *testing.T) {
+	type request struct {
+		WorkspaceID string
+		Port        string
+		Offset      time.Duration
+	}
+	type result struct {
+		Admitted   bool
+		Reason     string
+		RetryAfter time.Duration
+	}
+	tests := []struct {
+		Name     string
+		Config   RateLimitConfig
+		Requests []request
+		Expected []result
+	}{
+		{
+			Name:   "burst exceeded",
+			Config: RateLimitConfig{Port: &RateLimit{RequestsPerSecond: 2, Burst: 2}},
+			Requests: []request{
+				{WorkspaceID: "ws1", Port: "3000"},
+				{WorkspaceID: "ws1", Port: "3000"},
+				{WorkspaceID: "ws1", Port: "3000"},
+				{WorkspaceID: "ws1", Port: "3000", Offset: 500 * time.Millisecond},
+			},
+			Expected: []result{
+				{Admitted: true},
+				{Admitted: true},
+				{Reason: "rate", RetryAfter: 500 * time.Millisecond},
+				{Admitted: true},
+			},
+		},
+		{
+			Name:   "ports and workspaces are limited independently",
+			Config: RateLimitConfig{Port: &RateLimit{RequestsPerSecond: 1, Burst: 1}},
+			Requests: []request{
+				{WorkspaceID: "ws1", Port: "3000"},
+				{WorkspaceID: "ws1", Port: "3001"},
+				{WorkspaceID: "ws2", Port: "3000"},
+				{WorkspaceID: "ws1", Port: "3000"},
+			},
+			Expected: []result{
+				{Admitted: true},
+				{Admitted: true},
+				{Admitted: true},
+				{Reason: "rate", RetryAfter: time.Second},
+			},
+		},
+		{
+			Name: "workspace limit spans ports",
+			Config: RateLimitConfig{
+				Workspace: &RateLimit{RequestsPerSecond: 1, Burst: 2},
+				Port:      &RateLimit{RequestsPerSecond: 10, Burst: 10},
+			},
+			Requests: []request{
+				{WorkspaceID: "ws1", Port: "3000"},
+				{WorkspaceID: "ws1", Port: "3001"},
+				{WorkspaceID: "ws1", Port: "3002"},
+			},
+			Expected: []result{
+				{Admitted: true},
+				{Admitted: true},
+				{Reason: "rate", RetryAfter: time.Second},
+			},
+		},
+		{
+			Name:   "concurrency",
+			Config: RateLimitConfig{Workspace: &RateLimit{MaxConcurrent: 2}},
+			Requests: []request{
+				{WorkspaceID: "ws1", Port: "3000"},
+				{WorkspaceID: "ws1", Port: "3001"},
+				{WorkspaceID: "ws1", Port: "3000"},
+				{WorkspaceID: "ws2", Port: "3000"},
+			},
+			Expected: []result{
+				{Admitted: true},
+				{Admitted: true},
+				{Reason: "concurrency", RetryAfter: time.Second},
+				{Admitted: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var (
+				rl  = NewRateLimiter(&test.Config)
+				now = time.Now()
+				act []result
+			)
+			for _, req := range test.Requests {
+				adm := rl.admit(req.WorkspaceID, req.Port, now.Add(req.Offset))
+				act = append(act, result{Admitted: adm.states != nil, Reason: adm.reason, RetryAfter: adm.retryAfter})
+			}
+			if diff := cmp.Diff(test.Expected, act); diff != "" {
+				t.Errorf("unexpected admissions (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRateLimiterHandler(t *testing.T) {
+	var (
+		rl      = NewRateLimiter(&RateLimitConfig{Port: &RateLimit{MaxConcurrent: 1}})
+		block   = make(chan struct{})
+		started = make(chan struct{})
+	)
+	handler := rl.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-block
+	}))
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "https://3000-ws1.test-domain.com/", nil)
+		return mux.SetURLVars(req, map[string]string{workspaceIDIdentifier: "ws1", workspacePortIdentifier: "3000"})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), newRequest())
+		close(done)
+	}()
+	<-started
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, newRequest())
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("expected concurrent request to be rejected, got %d", rr.Code)
+	}
+	if ra := rr.Header().Get("Retry-After"); ra != "1" {
+		t.Errorf("unexpected Retry-After header: %q", ra)
+	}
+	if v := testutil.ToFloat64(rl.rejectedTotal.WithLabelValues("ws1", "concurrency")); v != 1 {
+		t.Errorf("expected one rejected request in metrics, got %v", v)
+	}
+	if v := testutil.ToFloat64(rl.inflight.WithLabelValues("ws1")); v != 1 {
+		t.Errorf("expected one inflight request in metrics, got %v", v)
+	}
+
+	close(block)
+	<-done
+	if v := testutil.ToFloat64(rl.inflight.WithLabelValues("ws1")); v != 0 {
+		t.Errorf("expected no inflight request in metrics, got %v", v)
+	}
+
+	rl.mu.Lock()
+	rl.gc(time.Now().Add(2 * rateLimitIdleTimeout))
+	remaining := len(rl.states)
+	rl.mu.Unlock()
+	if remaining != 0 {
+		t.Errorf("expected idle limit state to be forgotten, %d remaining", remaining)
+	}
+	if n := testutil.CollectAndCount(rl.rejectedTotal); n != 0 {
+		t.Errorf("expected metrics of forgotten workspace to be dropped, %d remaining", n)
+	}
+}
+
+func TestRateLimiterBandwidth(t *testing.T) {
+	rl := NewRateLimiter(&RateLimitConfig{Workspace: &RateLimit{BytesPerSecond: 64 * 1024}})
+	payload := make([]byte, 96*1024)
+	handler := rl.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(payload)
+	}))
+
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "https://3000-ws1.test-domain.com/", nil), map[string]string{workspaceIDIdentifier: "ws1", workspacePortIdentifier: "3000"})
+	rr := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(rr, req)
+	// the initial burst covers 64KiB, the remaining 32KiB take half a second
+	if d := time.Since(start); d < 400*time.Millisecond {
+		t.Errorf("expected response to be throttled, took %v", d)
+	}
+	if rr.Body.Len() != len(payload) {
+		t.Errorf("expected complete response, got %d bytes", rr.Body.Len())
+	}
+}
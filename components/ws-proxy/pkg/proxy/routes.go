@@ -35,6 +35,14 @@ type RouteHandlerConfig struct {
 	DefaultTransport     http.RoundTripper
 	CorsHandler          mux.MiddlewareFunc
 	WorkspaceAuthHandler mux.MiddlewareFunc
+	// Inspector captures requests to workspace ports. It is nil if the inspector is disabled.
+	Inspector *RequestInspector
+	// RateLimiter limits the traffic of workspace ports. It is nil if no limits are configured.
+	RateLimiter *RateLimiter
+	// Connections tracks websockets and long-polls. It is nil if connections are not tracked.
+	Connections *ConnectionTracker
+	// Traffic counts the traffic per workspace instance and port. It is nil if traffic accounting is disabled.
+	Traffic *TrafficMeter
 }
 
 // RouteHandlerConfigOpt modifies the router handler config.
@@ -43,7 +51,7 @@ type RouteHandlerConfigOpt func(*Config, *RouteHandlerConfig)
 // WithDefaultAuth enables workspace access authentication.
 func WithDefaultAuth(infoprov WorkspaceInfoProvider) RouteHandlerConfigOpt {
 	return func(config *Config, c *RouteHandlerConfig) {
-		c.WorkspaceAuthHandler = WorkspaceAuthHandler(config.GitpodInstallation.HostName, infoprov)
+		c.WorkspaceAuthHandler = WorkspaceAuthHandler(config.GitpodInstallation.HostName, infoprov, NewPortProtector(config.PortProtection))
 	}
 }
 
@@ -59,6 +67,8 @@ func NewRouteHandlerConfig(config *Config, opts ...RouteHandlerConfigOpt) (*Rout
 		DefaultTransport:     createDefaultTransport(config.TransportConfig),
 		CorsHandler:          corsHandler,
 		WorkspaceAuthHandler: func(h http.Handler) http.Handler { return h },
+		Inspector:            NewRequestInspector(config.Inspector),
+		RateLimiter:          NewRateLimiter(config.RateLimit),
 	}
 	for _, o := range opts {
 		o(config, cfg)
@@ -72,6 +82,8 @@ type RouteHandler = func(r *mux.Router, config *RouteHandlerConfig)
 // installWorkspaceRoutes configures routing of workspace and IDE requests.
 func installWorkspaceRoutes(r *mux.Router, config *RouteHandlerConfig, ip WorkspaceInfoProvider, hostKeyList []ssh.Signer) {
 	r.Use(logHandler)
+	r.Use(config.Traffic.Handler)
+	r.Use(config.Connections.Handler)
 
 	// Note: the order of routes defines their priority.
 	//       Routes registered first have priority over those that come afterwards.
@@ -103,6 +115,10 @@ func installWorkspaceRoutes(r *mux.Router, config *RouteHandlerConfig, ip Worksp
 		routes.HandleDirectIDERoute(r.PathPrefix(pp))
 	}
 
+	if config.Inspector != nil {
+		routes.HandleInspectorRoute(r.PathPrefix(inspectorPathPrefix))
+	}
+
 	routes.HandleSupervisorFrontendRoute(enableCompression(r).PathPrefix("/_supervisor/frontend"))
 
 	routes.HandleDirectSupervisorRoute(r.PathPrefix("/_supervisor/v1/status/supervisor"), false)
@@ -184,6 +200,17 @@ func (ir *ideRoutes) HandleDirectSupervisorRoute(route *mux.Route, authenticated
 	r.NewRoute().HandlerFunc(proxyPass(ir.Config, ir.InfoProvider, workspacePodSupervisorResolver))
 }
 
+// HandleInspectorRoute serves the API of the request inspector to the workspace owner
+func (ir *ideRoutes) HandleInspectorRoute(route *mux.Route) {
+	r := route.Subrouter()
+	r.Use(logRouteHandlerHandler("HandleInspectorRoute"))
+	r.Use(ir.Config.CorsHandler)
+	r.Use(ir.workspaceMustExistHandler)
+	r.Use(ir.Config.WorkspaceAuthHandler)
+
+	ir.Config.Inspector.HandleAPI(r, ir.Config, ir.InfoProvider)
+}
+
 func (ir *ideRoutes) HandleSupervisorFrontendRoute(route *mux.Route) {
 	if ir.Config.Config.BlobServer == nil {
 		// if we don't have blobserve, we serve the supervisor frontend from supervisor directly
@@ -371,12 +398,15 @@ func installWorkspacePortRoutes(r *mux.Router, config *RouteHandlerConfig, infoP
 	}
 
 	r.Use(logHandler)
+	r.Use(config.Traffic.Handler)
+	r.Use(config.Connections.Handler)
+	r.Use(config.RateLimiter.Handler)
 	r.Use(config.WorkspaceAuthHandler)
 	// filter all session cookies
 	r.Use(sensitiveCookieHandler(config.Config.GitpodInstallation.HostName))
 
 	// forward request to workspace port
-	r.NewRoute().HandlerFunc(
+	r.NewRoute().Handler(config.Inspector.Capture(http.HandlerFunc(
 		func(rw http.ResponseWriter, r *http.Request) {
 			// a work-around for servers which does not respect case-insensitive headers, see https://github.com/gitpod-io/gitpod/issues/4047#issuecomment-856566526
 			for _, name := range []string{"Key", "Extensions", "Accept", "Protocol", "Version"} {
@@ -397,7 +427,7 @@ func installWorkspacePortRoutes(r *mux.Router, config *RouteHandlerConfig, infoP
 				withWorkspaceTransport(),
 			)(rw, r)
 		},
-	)
+	)))
 
 	return nil
 }
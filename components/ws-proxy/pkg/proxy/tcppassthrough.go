@@ -0,0 +1,295 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+	"github.com/gitpod-io/gitpod/ws-manager/api"
+)
+
+const (
+	tcpPassthroughHandshakeTimeout = 10 * time.Second
+	tcpPassthroughDialTimeout      = 5 * time.Second
+
+	// proxyProtocolMaxHeaderLen is the maximum length of a PROXY protocol v1 header including CRLF
+	proxyProtocolMaxHeaderLen = 107
+)
+
+// TCPPassthroughConfig enables raw TCP access to public workspace ports. Clients connect using TLS
+// with the port host (e.g. 5432-<workspace ID><workspace host suffix>) as server name. ws-proxy
+// terminates TLS and forwards the plain TCP stream to the port.
+//
+// The allowed CIDRs of a port protection are checked against the remote address of the connection.
+// If ws-proxy is exposed using a load balancer which does not preserve the client address, that is
+// the address of the load balancer. Put a proxy which speaks the PROXY protocol in front of ws-proxy
+// and enable ProxyProtocol to check the address of the client instead.
+type TCPPassthroughConfig struct {
+	// Address is where ws-proxy accepts TLS connections, e.g. :9443
+	Address string `json:"address"`
+	// ProxyProtocol expects every connection to start with a PROXY protocol v1 header
+	// which carries the address of the client. Connections without header are rejected.
+	ProxyProtocol bool `json:"proxyProtocol"`
+}
+
+// Validate validates the configuration to catch issues during startup and not at runtime.
+func (c *TCPPassthroughConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Address == "" {
+		return xerrors.Errorf("tcpPassthrough.address is required")
+	}
+	return nil
+}
+
+// TCPPassthrough forwards TLS connections to workspace ports as plain TCP
+type TCPPassthrough struct {
+	InfoProvider WorkspaceInfoProvider
+	TLSConfig    *tls.Config
+	// ProxyProtocol expects connections to start with a PROXY protocol v1 header
+	ProxyProtocol bool
+
+	hostRegex *regexp.Regexp
+}
+
+// NewTCPPassthrough creates a new TCP passthrough for port hosts under wsHostSuffix
+func NewTCPPassthrough(wsHostSuffix string, infoProvider WorkspaceInfoProvider, tlsConfig *tls.Config) *TCPPassthrough {
+	return &TCPPassthrough{
+		InfoProvider: infoProvider,
+		TLSConfig:    tlsConfig,
+		hostRegex:    regexp.MustCompile("^" + workspacePortRegex + workspaceIDRegex + regexp.QuoteMeta(wsHostSuffix) + "$"),
+	}
+}
+
+// Serve accepts connections on the listener until it is closed
+func (p *TCPPassthrough) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go p.handle(conn)
+	}
+}
+
+func (p *TCPPassthrough) handle(conn net.Conn) {
+	defer conn.Close()
+
+	if p.ProxyProtocol {
+		_ = conn.SetDeadline(time.Now().Add(tcpPassthroughHandshakeTimeout))
+		pconn, err := readProxyProtocolHeader(conn)
+		if err != nil {
+			log.WithError(err).WithField("remoteAddr", conn.RemoteAddr()).Debug("invalid PROXY protocol header for TCP passthrough")
+			return
+		}
+		conn = pconn
+	}
+
+	tlsConn := tls.Server(conn, p.TLSConfig)
+	_ = tlsConn.SetDeadline(time.Now().Add(tcpPassthroughHandshakeTimeout))
+	err := tlsConn.Handshake()
+	if err != nil {
+		log.WithError(err).WithField("remoteAddr", conn.RemoteAddr()).Debug("TLS handshake for TCP passthrough failed")
+		return
+	}
+	_ = tlsConn.SetDeadline(time.Time{})
+
+	serverName := tlsConn.ConnectionState().ServerName
+	var clientIP net.IP
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		clientIP = addr.IP
+	}
+	coords, target, err := p.resolveTarget(serverName, clientIP)
+	if err != nil {
+		log.WithError(err).WithField("serverName", serverName).WithField("remoteAddr", conn.RemoteAddr()).Debug("rejecting TCP passthrough connection")
+		return
+	}
+	logger := log.WithFields(log.OWI("", coords.ID, "")).WithField("port", coords.Port)
+
+	upstream, err := net.DialTimeout("tcp", target, tcpPassthroughDialTimeout)
+	if err != nil {
+		logger.WithError(err).Debug("cannot connect to workspace port")
+		return
+	}
+	defer upstream.Close()
+
+	logger.Debug("forwarding TCP connection to workspace port")
+	pipe(tlsConn, upstream)
+}
+
+// resolveTarget returns the address of the workspace port a server name refers to if the client may access it
+func (p *TCPPassthrough) resolveTarget(serverName string, clientIP net.IP) (*WorkspaceCoords, string, error) {
+	matches := p.hostRegex.FindStringSubmatch(normalizeDomain(serverName))
+	if matches == nil {
+		return nil, "", xerrors.Errorf("%q is not a workspace port host", serverName)
+	}
+	coords := &WorkspaceCoords{
+		Port: matches[p.hostRegex.SubexpIndex(workspacePortIdentifier)],
+		ID:   matches[p.hostRegex.SubexpIndex(workspaceIDIdentifier)],
+	}
+	port, err := strconv.ParseUint(coords.Port, 10, 16)
+	if err != nil {
+		return nil, "", xerrors.Errorf("invalid port %s: %w", coords.Port, err)
+	}
+
+	info := p.InfoProvider.WorkspaceInfo(coords.ID)
+	if info == nil {
+		return nil, "", xerrors.Errorf("workspace %s not found", coords.ID)
+	}
+
+	// without HTTP there is no way to authenticate, hence only public ports are reachable
+	var public bool
+	for _, p := range info.Ports {
+		if p.Port == uint32(port) && p.Visibility == api.PortVisibility_PORT_VISIBILITY_PUBLIC {
+			public = true
+			break
+		}
+	}
+	if !public {
+		return nil, "", xerrors.Errorf("port %d is not public", port)
+	}
+	if prot, ok := info.PortProtection[uint32(port)]; ok {
+		if prot.deny || prot.PasswordHash != "" || prot.OIDC != nil {
+			return nil, "", xerrors.Errorf("port %d requires authentication which is not available for TCP", port)
+		}
+		if len(prot.AllowedCIDRs) > 0 && !isAllowedIP(clientIP, prot.AllowedCIDRs) {
+			return nil, "", xerrors.Errorf("client IP %s is not allowed to access port %d", clientIP, port)
+		}
+	}
+
+	return coords, net.JoinHostPort(info.IPAddress, coords.Port), nil
+}
+
+// pipe copies data in both directions until both sides are done. When one side stops
+// sending, the write side of the other one is closed so that half-closed connections work.
+func pipe(a, b net.Conn) {
+	var wg sync.WaitGroup
+	copyAndCloseWrite := func(dst, src net.Conn) {
+		defer wg.Done()
+		_, _ = io.Copy(dst, src)
+		if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+			_ = cw.CloseWrite()
+		} else {
+			_ = dst.Close()
+		}
+	}
+
+	wg.Add(2)
+	go copyAndCloseWrite(a, b)
+	go copyAndCloseWrite(b, a)
+	wg.Wait()
+}
+
+// proxyProtocolConn is a connection whose remote address was read from a PROXY protocol header
+type proxyProtocolConn struct {
+	net.Conn
+	r          *bufio.Reader
+	remoteAddr net.Addr
+}
+
+func (c *proxyProtocolConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+// proxyProtocolListener expects accepted connections to start with a PROXY protocol v1 header.
+// The header is read on first use of the connection, so that slow clients do not block Accept.
+type proxyProtocolListener struct {
+	net.Listener
+}
+
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &lazyProxyProtocolConn{Conn: conn}, nil
+}
+
+// lazyProxyProtocolConn reads the PROXY protocol header once it is read from or its remote address is requested.
+// Connections with an invalid header fail on read.
+type lazyProxyProtocolConn struct {
+	net.Conn
+
+	once sync.Once
+	conn net.Conn
+	err  error
+}
+
+func (c *lazyProxyProtocolConn) init() {
+	c.once.Do(func() {
+		_ = c.Conn.SetReadDeadline(time.Now().Add(tcpPassthroughHandshakeTimeout))
+		c.conn, c.err = readProxyProtocolHeader(c.Conn)
+		_ = c.Conn.SetReadDeadline(time.Time{})
+		if c.err != nil {
+			log.WithError(c.err).WithField("remoteAddr", c.Conn.RemoteAddr()).Debug("invalid PROXY protocol header")
+		}
+	})
+}
+
+func (c *lazyProxyProtocolConn) Read(b []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.conn.Read(b)
+}
+
+func (c *lazyProxyProtocolConn) RemoteAddr() net.Addr {
+	c.init()
+	if c.err != nil {
+		return c.Conn.RemoteAddr()
+	}
+	return c.conn.RemoteAddr()
+}
+
+// readProxyProtocolHeader reads a PROXY protocol v1 header, e.g. "PROXY TCP4 <src> <dst> <src port> <dst port>\r\n",
+// and returns a connection whose remote address is the source address of the header.
+// For "PROXY UNKNOWN" the remote address of conn is kept.
+func readProxyProtocolHeader(conn net.Conn) (net.Conn, error) {
+	r := bufio.NewReaderSize(conn, proxyProtocolMaxHeaderLen)
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		return nil, xerrors.Errorf("cannot read PROXY protocol header: %w", err)
+	}
+	header := strings.TrimSuffix(string(line), "\r\n")
+	if len(header) == len(line) {
+		return nil, xerrors.Errorf("PROXY protocol header does not end with CRLF")
+	}
+
+	res := &proxyProtocolConn{Conn: conn, r: r, remoteAddr: conn.RemoteAddr()}
+	fields := strings.Split(header, " ")
+	if len(fields) >= 2 && fields[0] == "PROXY" && fields[1] == "UNKNOWN" {
+		return res, nil
+	}
+	if len(fields) != 6 || fields[0] != "PROXY" || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, xerrors.Errorf("invalid PROXY protocol header %q", header)
+	}
+	ip := net.ParseIP(fields[2])
+	if ip == nil {
+		return nil, xerrors.Errorf("invalid source address in PROXY protocol header %q", header)
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid source port in PROXY protocol header %q", header)
+	}
+	res.remoteAddr = &net.TCPAddr{IP: ip, Port: int(port)}
+	return res, nil
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/gitpod-io/gitpod/ws-manager/api"
+)
+
+const tcpTestWorkspaceID = "amaranth-smelt-9ba20cc1"
+
+func TestTCPPassthroughResolveTarget(t *testing.T) {
+	infos := &fixedInfoProvider{Infos: map[string]*WorkspaceInfo{
+		tcpTestWorkspaceID: {
+			WorkspaceID: tcpTestWorkspaceID,
+			IPAddress:   "10.0.0.1",
+			Ports: []*api.PortSpec{
+				{Port: 5432, Visibility: api.PortVisibility_PORT_VISIBILITY_PUBLIC},
+				{Port: 6379, Visibility: api.PortVisibility_PORT_VISIBILITY_PRIVATE},
+				{Port: 8080, Visibility: api.PortVisibility_PORT_VISIBILITY_PUBLIC},
+				{Port: 9000, Visibility: api.PortVisibility_PORT_VISIBILITY_PUBLIC},
+			},
+			PortProtection: map[uint32]*PortProtection{
+				8080: {Port: 8080, PasswordHash: testPasswordHash},
+				9000: {Port: 9000, AllowedCIDRs: []string{"192.168.0.0/16"}},
+			},
+		},
+	}}
+	p := NewTCPPassthrough(".ws.gitpod.dev", infos, nil)
+
+	tests := []struct {
+		Name       string
+		ServerName string
+		ClientIP   string
+		Expected   string
+		Error      bool
+	}{
+		{Name: "public port", ServerName: "5432-" + tcpTestWorkspaceID + ".ws.gitpod.dev", Expected: "10.0.0.1:5432"},
+		{Name: "case insensitive", ServerName: "5432-" + tcpTestWorkspaceID + ".WS.gitpod.dev.", Expected: "10.0.0.1:5432"},
+		{Name: "private port", ServerName: "6379-" + tcpTestWorkspaceID + ".ws.gitpod.dev", Error: true},
+		{Name: "unexposed port", ServerName: "3000-" + tcpTestWorkspaceID + ".ws.gitpod.dev", Error: true},
+		{Name: "password protected port", ServerName: "8080-" + tcpTestWorkspaceID + ".ws.gitpod.dev", Error: true},
+		{Name: "allowed client IP", ServerName: "9000-" + tcpTestWorkspaceID + ".ws.gitpod.dev", ClientIP: "192.168.1.1", Expected: "10.0.0.1:9000"},
+		{Name: "disallowed client IP", ServerName: "9000-" + tcpTestWorkspaceID + ".ws.gitpod.dev", ClientIP: "10.1.1.1", Error: true},
+		{Name: "unknown workspace", ServerName: "5432-blue-whale-12345678.ws.gitpod.dev", Error: true},
+		{Name: "workspace host", ServerName: tcpTestWorkspaceID + ".ws.gitpod.dev", Error: true},
+		{Name: "foreign host", ServerName: "5432-" + tcpTestWorkspaceID + ".ws.gitpod.dev.example.com", Error: true},
+		{Name: "no server name", Error: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, act, err := p.resolveTarget(test.ServerName, net.ParseIP(test.ClientIP))
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.Expected, act); diff != "" {
+				t.Errorf("unexpected target (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTCPPassthrough(t *testing.T) {
+	// the workspace port echoes everything it receives until the client stops sending
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	port := upstream.Addr().(*net.TCPAddr).Port
+
+	infos := &fixedInfoProvider{Infos: map[string]*WorkspaceInfo{
+		tcpTestWorkspaceID: {
+			WorkspaceID: tcpTestWorkspaceID,
+			IPAddress:   "127.0.0.1",
+			Ports:       []*api.PortSpec{{Port: uint32(port), Visibility: api.PortVisibility_PORT_VISIBILITY_PUBLIC}},
+		},
+	}}
+	// borrow the self-signed certificate of an httptest server
+	certSrv := httptest.NewTLSServer(nil)
+	defer certSrv.Close()
+	p := NewTCPPassthrough(".ws.gitpod.dev", infos, &tls.Config{Certificates: certSrv.TLS.Certificates})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() { _ = p.Serve(l) }()
+
+	dial := func(serverName string) (*tls.Conn, error) {
+		return tls.Dial("tcp", l.Addr().String(), &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	}
+
+	conn, err := dial(fmt.Sprintf("%d-%s.ws.gitpod.dev", port, tcpTestWorkspaceID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte("hello workspace"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = conn.CloseWrite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp) != "hello workspace" {
+		t.Errorf("unexpected response: %q", resp)
+	}
+
+	rejected, err := dial(fmt.Sprintf("%d-%s.ws.gitpod.dev", port+1, tcpTestWorkspaceID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rejected.Close()
+	resp, _ = io.ReadAll(rejected)
+	if len(resp) != 0 {
+		t.Errorf("expected connection to unexposed port to be closed, got %q", resp)
+	}
+}
+
+func TestReadProxyProtocolHeader(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Input      string
+		RemoteAddr string
+		Error      bool
+	}{
+		{Name: "tcp4", Input: "PROXY TCP4 192.168.1.1 10.0.0.1 56324 9443\r\nhello", RemoteAddr: "192.168.1.1:56324"},
+		{Name: "tcp6", Input: "PROXY TCP6 2001:db8::1 2001:db8::2 56324 9443\r\nhello", RemoteAddr: "[2001:db8::1]:56324"},
+		{Name: "unknown", Input: "PROXY UNKNOWN\r\nhello", RemoteAddr: "pipe"},
+		{Name: "no header", Input: "hello\r\n", Error: true},
+		{Name: "missing CRLF", Input: "PROXY TCP4 192.168.1.1 10.0.0.1 56324 9443\nhello", Error: true},
+		{Name: "invalid address", Input: "PROXY TCP4 192.168.1 10.0.0.1 56324 9443\r\nhello", Error: true},
+		{Name: "invalid port", Input: "PROXY TCP4 192.168.1.1 10.0.0.1 foo 9443\r\nhello", Error: true},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer server.Close()
+			go func() {
+				_, _ = client.Write([]byte(test.Input))
+				client.Close()
+			}()
+
+			conn, err := readProxyProtocolHeader(server)
+			if (err != nil) != test.Error {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.Error {
+				return
+			}
+			if act := conn.RemoteAddr().String(); act != test.RemoteAddr {
+				t.Errorf("unexpected remote address: %s", act)
+			}
+			rest, _ := io.ReadAll(conn)
+			if string(rest) != "hello" {
+				t.Errorf("expected data after the header to be kept, got %q", rest)
+			}
+		})
+	}
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := &proxyProtocolListener{Listener: l}
+	defer pl.Close()
+
+	// a client which does not send anything must not block other connections
+	idle, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idle.Close()
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Write([]byte("PROXY TCP4 192.168.1.1 10.0.0.1 56324 9091\r\nhello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+
+	if _, err := pl.Accept(); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := pl.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if act := conn.RemoteAddr().String(); act != "192.168.1.1:56324" {
+		t.Errorf("unexpected remote address: %s", act)
+	}
+	rest, _ := io.ReadAll(conn)
+	if string(rest) != "hello" {
+		t.Errorf("expected data after the header to be kept, got %q", rest)
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/xerrors"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+	"github.com/gitpod-io/gitpod/common-go/util"
+	wsmanapi "github.com/gitpod-io/gitpod/ws-manager/api"
+)
+
+// trafficFlushTimeout bounds the time a single flush of the traffic counters may take
+const trafficFlushTimeout = 10 * time.Second
+
+// TrafficAccountingConfig configures the per-workspace traffic accounting
+type TrafficAccountingConfig struct {
+	// FlushInterval is how often the traffic counters are reported
+	FlushInterval util.Duration `json:"flushInterval"`
+	// File is a file the traffic is appended to as JSON lines. If empty, traffic is reported to ws-manager.
+	File string `json:"file,omitempty"`
+}
+
+// Validate validates the configuration to catch issues during startup and not at runtime.
+func (c *TrafficAccountingConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.FlushInterval <= 0 {
+		return xerrors.Errorf("trafficAccounting.flushInterval must be positive")
+	}
+	return nil
+}
+
+// TrafficRecord is the traffic of a workspace port served since the last flush. Port 0 is the IDE.
+type TrafficRecord struct {
+	Time        time.Time `json:"time"`
+	InstanceID  string    `json:"instanceId"`
+	WorkspaceID string    `json:"workspaceId"`
+	Port        uint32    `json:"port"`
+	// BytesIn is the number of body bytes sent from clients to the workspace
+	BytesIn uint64 `json:"bytesIn"`
+	// BytesOut is the number of body bytes sent from the workspace to clients
+	BytesOut uint64 `json:"bytesOut"`
+	Requests uint64 `json:"requests"`
+}
+
+// TrafficSink receives the traffic counted by a TrafficMeter
+type TrafficSink interface {
+	// Report records the traffic. If it fails, the traffic is reported again with the next flush.
+	Report(ctx context.Context, records []*TrafficRecord) error
+}
+
+// FileTrafficSink appends traffic records to a file, one JSON object per line
+type FileTrafficSink struct {
+	mu  sync.Mutex
+	out *os.File
+}
+
+// NewFileTrafficSink opens fn for appending traffic records
+func NewFileTrafficSink(fn string) (*FileTrafficSink, error) {
+	f, err := os.OpenFile(fn, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot open traffic log: %w", err)
+	}
+	return &FileTrafficSink{out: f}, nil
+}
+
+// Report appends the records to the file
+func (s *FileTrafficSink) Report(ctx context.Context, records []*TrafficRecord) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range records {
+		err := enc.Encode(r)
+		if err != nil {
+			return xerrors.Errorf("cannot marshal traffic record: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.out.Write(buf.Bytes())
+	if err != nil {
+		return xerrors.Errorf("cannot write traffic records: %w", err)
+	}
+	return nil
+}
+
+// Close closes the traffic log file
+func (s *FileTrafficSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.out.Close()
+}
+
+// WorkspaceManagerTrafficSink reports traffic to ws-manager, which exposes it in DescribeWorkspace
+type WorkspaceManagerTrafficSink struct {
+	Client wsmanapi.WorkspaceManagerClient
+}
+
+// Report sends the records to ws-manager
+func (s *WorkspaceManagerTrafficSink) Report(ctx context.Context, records []*TrafficRecord) error {
+	var (
+		req       wsmanapi.ReportTrafficRequest
+		instances = make(map[string]*wsmanapi.WorkspaceTraffic)
+	)
+	for _, r := range records {
+		ws, ok := instances[r.InstanceID]
+		if !ok {
+			ws = &wsmanapi.WorkspaceTraffic{Id: r.InstanceID}
+			instances[r.InstanceID] = ws
+			req.Workspaces = append(req.Workspaces, ws)
+		}
+		ws.Ports = append(ws.Ports, &wsmanapi.PortTraffic{
+			Port:     r.Port,
+			BytesIn:  r.BytesIn,
+			BytesOut: r.BytesOut,
+			Requests: r.Requests,
+		})
+	}
+
+	_, err := s.Client.ReportTraffic(ctx, &req)
+	if err != nil {
+		return xerrors.Errorf("cannot report traffic to ws-manager: %w", err)
+	}
+	return nil
+}
+
+type trafficKey struct {
+	InstanceID  string
+	WorkspaceID string
+	Port        uint32
+}
+
+// trafficCounter counts the traffic of a workspace port. Its fields are accessed atomically.
+type trafficCounter struct {
+	bytesIn  uint64
+	bytesOut uint64
+	requests uint64
+	// inflight is the number of requests and connections currently counted
+	inflight int64
+}
+
+// TrafficMeter counts the bytes and requests served per workspace instance and port,
+// and periodically reports them to a sink.
+type TrafficMeter struct {
+	Config       TrafficAccountingConfig
+	Sink         TrafficSink
+	InfoProvider WorkspaceInfoProvider
+
+	mu       sync.Mutex
+	counters map[trafficKey]*trafficCounter
+	// flushMu makes sure flushes happen one at a time so that traffic is reported in order
+	flushMu sync.Mutex
+}
+
+// NewTrafficMeter creates a new traffic meter. If cfg is nil, nil is returned and no traffic is counted.
+func NewTrafficMeter(cfg *TrafficAccountingConfig, sink TrafficSink, infoProvider WorkspaceInfoProvider) *TrafficMeter {
+	if cfg == nil {
+		return nil
+	}
+
+	return &TrafficMeter{
+		Config:       *cfg,
+		Sink:         sink,
+		InfoProvider: infoProvider,
+		counters:     make(map[trafficKey]*trafficCounter),
+	}
+}
+
+// Handler counts the body bytes and requests served. The traffic of websockets is counted
+// for as long as the connection is open.
+func (m *TrafficMeter) Handler(h http.Handler) http.Handler {
+	if m == nil {
+		return h
+	}
+
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		coords := getWorkspaceCoords(req)
+		info := m.InfoProvider.WorkspaceInfo(coords.ID)
+		if info == nil {
+			// requests to unknown workspaces are not served and hence not accounted for
+			h.ServeHTTP(resp, req)
+			return
+		}
+		var port uint32
+		if coords.Port != "" {
+			p, err := strconv.ParseUint(coords.Port, 10, 16)
+			if err != nil {
+				h.ServeHTTP(resp, req)
+				return
+			}
+			port = uint32(p)
+		}
+
+		cnt := m.acquire(trafficKey{InstanceID: info.InstanceID, WorkspaceID: info.WorkspaceID, Port: port})
+		defer atomic.AddInt64(&cnt.inflight, -1)
+		atomic.AddUint64(&cnt.requests, 1)
+
+		if req.Body != nil && req.Body != http.NoBody {
+			req.Body = &countingReadCloser{ReadCloser: req.Body, n: &cnt.bytesIn}
+		}
+		h.ServeHTTP(&countingResponseWriter{ResponseWriter: resp, cnt: cnt}, req)
+	})
+}
+
+// acquire returns the counter of a workspace port and marks it as in use
+func (m *TrafficMeter) acquire(key trafficKey) *trafficCounter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cnt, ok := m.counters[key]
+	if !ok {
+		cnt = &trafficCounter{}
+		m.counters[key] = cnt
+	}
+	atomic.AddInt64(&cnt.inflight, 1)
+	return cnt
+}
+
+// Run flushes the traffic counters every flush interval until ctx is done
+func (m *TrafficMeter) Run(ctx context.Context) {
+	if m == nil {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(m.Config.FlushInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		flushCtx, cancel := context.WithTimeout(ctx, trafficFlushTimeout)
+		err := m.Flush(flushCtx)
+		cancel()
+		if err != nil {
+			log.WithError(err).Warn("cannot flush traffic counters - will try again with the next flush")
+		}
+	}
+}
+
+// Flush reports the traffic served since the last flush to the sink. If that fails,
+// the traffic is kept and reported with the next flush.
+func (m *TrafficMeter) Flush(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
+
+	now := time.Now()
+	var records []*TrafficRecord
+	m.mu.Lock()
+	for key, cnt := range m.counters {
+		r := &TrafficRecord{
+			Time:        now,
+			InstanceID:  key.InstanceID,
+			WorkspaceID: key.WorkspaceID,
+			Port:        key.Port,
+			BytesIn:     atomic.SwapUint64(&cnt.bytesIn, 0),
+			BytesOut:    atomic.SwapUint64(&cnt.bytesOut, 0),
+			Requests:    atomic.SwapUint64(&cnt.requests, 0),
+		}
+		if r.BytesIn == 0 && r.BytesOut == 0 && r.Requests == 0 {
+			if atomic.LoadInt64(&cnt.inflight) == 0 {
+				// nothing happened since the last flush and nothing is going on - forget about this port
+				delete(m.counters, key)
+			}
+			continue
+		}
+		records = append(records, r)
+	}
+	m.mu.Unlock()
+
+	if len(records) == 0 {
+		return nil
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].InstanceID != records[j].InstanceID {
+			return records[i].InstanceID < records[j].InstanceID
+		}
+		return records[i].Port < records[j].Port
+	})
+
+	err := m.Sink.Report(ctx, records)
+	if err != nil {
+		m.restore(records)
+		return err
+	}
+	return nil
+}
+
+// restore adds the traffic of records which could not be reported back to the counters
+func (m *TrafficMeter) restore(records []*TrafficRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range records {
+		key := trafficKey{InstanceID: r.InstanceID, WorkspaceID: r.WorkspaceID, Port: r.Port}
+		cnt, ok := m.counters[key]
+		if !ok {
+			cnt = &trafficCounter{}
+			m.counters[key] = cnt
+		}
+		atomic.AddUint64(&cnt.bytesIn, r.BytesIn)
+		atomic.AddUint64(&cnt.bytesOut, r.BytesOut)
+		atomic.AddUint64(&cnt.requests, r.Requests)
+	}
+}
+
+// countingReadCloser counts the bytes read from a request body
+type countingReadCloser struct {
+	io.ReadCloser
+	n *uint64
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddUint64(r.n, uint64(n))
+	return n, err
+}
+
+// countingResponseWriter counts the bytes of a response body, and of both directions once a connection is hijacked
+type countingResponseWriter struct {
+	http.ResponseWriter
+	cnt *trafficCounter
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	atomic.AddUint64(&w.cnt.bytesOut, uint64(n))
+	return n, err
+}
+
+func (w *countingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *countingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, xerrors.Errorf("response writer does not support hijacking")
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	// the connection outlives the request, hence the counter must not be forgotten until it's closed
+	atomic.AddInt64(&w.cnt.inflight, 1)
+	return &countingConn{Conn: conn, cnt: w.cnt}, brw, nil
+}
+
+// countingConn counts the bytes of a hijacked connection
+type countingConn struct {
+	net.Conn
+	cnt *trafficCounter
+
+	closeOnce sync.Once
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddUint64(&c.cnt.bytesIn, uint64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	atomic.AddUint64(&c.cnt.bytesOut, uint64(n))
+	return n, err
+}
+
+func (c *countingConn) Close() error {
+	c.closeOnce.Do(func() {
+		atomic.AddInt64(&c.cnt.inflight, -1)
+	})
+	return c.Conn.Close()
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/gorilla/mux"
+	"golang.org/x/xerrors"
+)
+
+type recordingTrafficSink struct {
+	Records []*TrafficRecord
+	Err     error
+}
+
+func (s *recordingTrafficSink) Report(ctx context.Context, records []*TrafficRecord) error {
+	if s.Err != nil {
+		return s.Err
+	}
+	s.Records = append(s.Records, records...)
+	return nil
+}
+
+func newTestTrafficMeter(sink TrafficSink) *TrafficMeter {
+	return NewTrafficMeter(&TrafficAccountingConfig{}, sink, &fixedInfoProvider{Infos: map[string]*WorkspaceInfo{
+		"amaranth-smelt-9ba20cc1": {WorkspaceID: "amaranth-smelt-9ba20cc1", InstanceID: "e63e2f2b-3e38-4b8b-9b6f-b3a4a0c8e0d1"},
+	}})
+}
+
+func serveTraffic(meter *TrafficMeter, workspaceID, port, body string) {
+	handler := meter.Handler(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		_, _ = resp.Write([]byte("hello world"))
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req = mux.SetURLVars(req, map[string]string{workspaceIDIdentifier: workspaceID, workspacePortIdentifier: port})
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestTrafficMeter(t *testing.T) {
+	sink := &recordingTrafficSink{}
+	meter := newTestTrafficMeter(sink)
+
+	serveTraffic(meter, "amaranth-smelt-9ba20cc1", "3000", "12345")
+	serveTraffic(meter, "amaranth-smelt-9ba20cc1", "3000", "1234567890")
+	serveTraffic(meter, "amaranth-smelt-9ba20cc1", "", "")
+	serveTraffic(meter, "blue-whale-12345678", "3000", "12345")
+
+	err := meter.Flush(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectation := []*TrafficRecord{
+		{InstanceID: "e63e2f2b-3e38-4b8b-9b6f-b3a4a0c8e0d1", WorkspaceID: "amaranth-smelt-9ba20cc1", Port: 0, BytesIn: 0, BytesOut: 11, Requests: 1},
+		{InstanceID: "e63e2f2b-3e38-4b8b-9b6f-b3a4a0c8e0d1", WorkspaceID: "amaranth-smelt-9ba20cc1", Port: 3000, BytesIn: 15, BytesOut: 22, Requests: 2},
+	}
+	if diff := cmp.Diff(expectation, sink.Records, cmpopts.IgnoreFields(TrafficRecord{}, "Time")); diff != "" {
+		t.Errorf("unexpected traffic (-want +got):\n%s", diff)
+	}
+
+	// the counters start over after a flush
+	sink.Records = nil
+	err = meter.Flush(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.Records) != 0 {
+		t.Errorf("expected no traffic after flush, got %v", sink.Records)
+	}
+	if len(meter.counters) != 0 {
+		t.Errorf("expected idle counters to be forgotten, got %d", len(meter.counters))
+	}
+}
+
+func TestTrafficMeterFailedFlush(t *testing.T) {
+	sink := &recordingTrafficSink{Err: xerrors.Errorf("ws-manager is unavailable")}
+	meter := newTestTrafficMeter(sink)
+
+	serveTraffic(meter, "amaranth-smelt-9ba20cc1", "3000", "12345")
+	err := meter.Flush(context.Background())
+	if err == nil {
+		t.Fatal("expected flush to fail")
+	}
+
+	serveTraffic(meter, "amaranth-smelt-9ba20cc1", "3000", "12345")
+	sink.Err = nil
+	err = meter.Flush(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectation := []*TrafficRecord{
+		{InstanceID: "e63e2f2b-3e38-4b8b-9b6f-b3a4a0c8e0d1", WorkspaceID: "amaranth-smelt-9ba20cc1", Port: 3000, BytesIn: 10, BytesOut: 22, Requests: 2},
+	}
+	if diff := cmp.Diff(expectation, sink.Records, cmpopts.IgnoreFields(TrafficRecord{}, "Time")); diff != "" {
+		t.Errorf("unexpected traffic (-want +got):\n%s", diff)
+	}
+}
+
+func TestFileTrafficSink(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "traffic.jsonl")
+	sink, err := NewFileTrafficSink(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []*TrafficRecord{
+		{InstanceID: "e63e2f2b-3e38-4b8b-9b6f-b3a4a0c8e0d1", WorkspaceID: "amaranth-smelt-9ba20cc1", Port: 0, BytesOut: 11, Requests: 1},
+		{InstanceID: "e63e2f2b-3e38-4b8b-9b6f-b3a4a0c8e0d1", WorkspaceID: "amaranth-smelt-9ba20cc1", Port: 3000, BytesIn: 15, BytesOut: 22, Requests: 2},
+	}
+	err = sink.Report(context.Background(), records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = sink.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	var act []*TrafficRecord
+	for _, l := range lines {
+		var r TrafficRecord
+		err := json.Unmarshal([]byte(l), &r)
+		if err != nil {
+			t.Fatalf("cannot unmarshal line %q: %v", l, err)
+		}
+		act = append(act, &r)
+	}
+	if diff := cmp.Diff(records, act); diff != "" {
+		t.Errorf("unexpected records (-want +got):\n%s", diff)
+	}
+}
@@ -62,8 +62,27 @@ func HostBasedRouter(header, wsHostSuffix string, wsHostSuffixRegex string) Work
 				}
 				return host
 			}
+			matchPortHost = matchWorkspaceHostHeader(wsHostSuffix, getHostHeader, true)
+		)
+		if resolver, ok := wsInfoProvider.(CustomDomainResolver); ok {
+			// custom domains route to workspace ports just like the generated port hosts do
+			var (
+				matchGeneratedHost = matchPortHost
+				matchCustomDomain  = matchCustomDomainHostHeader(resolver, getHostHeader)
+			)
+			matchPortHost = func(req *http.Request, m *mux.RouteMatch) bool {
+				if matchGeneratedHost(req, m) {
+					return true
+				}
+				if m.Vars == nil {
+					m.Vars = make(map[string]string)
+				}
+				return matchCustomDomain(req, m.Vars)
+			}
+		}
+		var (
 			blobserveRouter = r.MatcherFunc(matchBlobserveHostHeader(wsHostSuffix, getHostHeader)).Subrouter()
-			portRouter      = r.MatcherFunc(matchWorkspaceHostHeader(wsHostSuffix, getHostHeader, true)).Subrouter()
+			portRouter      = r.MatcherFunc(matchPortHost).Subrouter()
 			ideRouter       = r.MatcherFunc(matchWorkspaceHostHeader(allClusterWsHostSuffixRegex, getHostHeader, false)).Subrouter()
 		)
 
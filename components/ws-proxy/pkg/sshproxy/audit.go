@@ -0,0 +1,233 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package sshproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/xerrors"
+)
+
+// AuditEventType describes what happened in an SSH session
+type AuditEventType string
+
+const (
+	// AuditSessionStart is emitted once a client is connected to the workspace
+	AuditSessionStart AuditEventType = "session-start"
+	// AuditSessionEnd is emitted when a client disconnects
+	AuditSessionEnd AuditEventType = "session-end"
+	// AuditChannelOpen is emitted for every channel a client or the workspace opens
+	AuditChannelOpen AuditEventType = "channel-open"
+	// AuditChannelClose is emitted when a channel is closed
+	AuditChannelClose AuditEventType = "channel-close"
+	// AuditExec is emitted for every shell, command or subsystem a client runs
+	AuditExec AuditEventType = "exec"
+	// AuditRemoteForward is emitted for every remote port forward a client requests
+	AuditRemoteForward AuditEventType = "remote-forward"
+)
+
+// AuditEvent is a structured record of something that happened in an SSH session
+type AuditEvent struct {
+	Time        time.Time      `json:"time"`
+	Type        AuditEventType `json:"type"`
+	UserID      string         `json:"userId,omitempty"`
+	WorkspaceID string         `json:"workspaceId"`
+	InstanceID  string         `json:"instanceId"`
+	SourceIP    string         `json:"sourceIP,omitempty"`
+	AuthMethod  string         `json:"authMethod,omitempty"`
+	PublicKey   string         `json:"publicKey,omitempty"`
+
+	ChannelType string `json:"channelType,omitempty"`
+	Request     string `json:"request,omitempty"`
+	Command     string `json:"command,omitempty"`
+	Target      string `json:"target,omitempty"`
+	Success     *bool  `json:"success,omitempty"`
+
+	// BytesIn is the number of bytes sent from the client to the workspace
+	BytesIn int64 `json:"bytesIn,omitempty"`
+	// BytesOut is the number of bytes sent from the workspace to the client
+	BytesOut int64   `json:"bytesOut,omitempty"`
+	Duration float64 `json:"durationSeconds,omitempty"`
+}
+
+// AuditSink receives the audit events of all SSH sessions
+type AuditSink interface {
+	// Emit records an audit event. Implementations must not block.
+	Emit(ev *AuditEvent)
+}
+
+type noAuditSink struct{}
+
+func (noAuditSink) Emit(ev *AuditEvent) {}
+
+// FileAuditSink appends audit events to a file, one JSON object per line
+type FileAuditSink struct {
+	mu  sync.Mutex
+	out *os.File
+}
+
+// NewFileAuditSink opens fn for appending audit events
+func NewFileAuditSink(fn string) (*FileAuditSink, error) {
+	f, err := os.OpenFile(fn, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, xerrors.Errorf("cannot open audit log: %w", err)
+	}
+	return &FileAuditSink{out: f}, nil
+}
+
+// Emit appends the event to the file
+func (s *FileAuditSink) Emit(ev *AuditEvent) {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		log.WithError(err).Warn("cannot marshal SSH audit event")
+		return
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.out.Write(line)
+	if err != nil {
+		log.WithError(err).Warn("cannot write SSH audit event")
+	}
+}
+
+// Close closes the audit log file
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.out.Close()
+}
+
+// webhookAuditQueueSize is the number of events a webhook sink buffers before it drops events
+const webhookAuditQueueSize = 1000
+
+// WebhookAuditSink posts audit events as JSON to a URL
+type WebhookAuditSink struct {
+	URL    string
+	Client *http.Client
+
+	queue chan *AuditEvent
+}
+
+// NewWebhookAuditSink creates a sink which posts events to url in the background
+func NewWebhookAuditSink(url string) *WebhookAuditSink {
+	res := &WebhookAuditSink{
+		URL:    url,
+		Client: &http.Client{Timeout: 10 * time.Second},
+		queue:  make(chan *AuditEvent, webhookAuditQueueSize),
+	}
+	go res.run()
+	return res
+}
+
+// Emit queues the event for delivery and drops it if the queue is full
+func (s *WebhookAuditSink) Emit(ev *AuditEvent) {
+	select {
+	case s.queue <- ev:
+	default:
+		log.WithField("type", ev.Type).WithField("workspaceId", ev.WorkspaceID).Warn("SSH audit webhook queue is full - dropping event")
+	}
+}
+
+func (s *WebhookAuditSink) run() {
+	for ev := range s.queue {
+		err := s.post(ev)
+		if err != nil {
+			log.WithError(err).WithField("url", s.URL).Warn("cannot deliver SSH audit event")
+		}
+	}
+}
+
+func (s *WebhookAuditSink) post(ev *AuditEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return xerrors.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// audit emits an event of the session
+func (s *Server) audit(session *Session, ev AuditEvent) {
+	ev.Time = time.Now()
+	ev.UserID = session.UserID
+	ev.WorkspaceID = session.WorkspaceID
+	ev.InstanceID = session.InstanceID
+	ev.SourceIP = session.SourceIP
+	ev.AuthMethod = session.AuthMethod
+	ev.PublicKey = session.PublicKeyFingerprint
+	s.Auditor.Emit(&ev)
+}
+
+// channelOpenTCPIP is the extra data of direct-tcpip and forwarded-tcpip channels (RFC 4254, sections 7.1 and 7.2)
+type channelOpenTCPIP struct {
+	Host       string
+	Port       uint32
+	OriginAddr string
+	OriginPort uint32
+}
+
+// channelTarget returns the address a port forwarding channel connects to
+func channelTarget(newChannel ssh.NewChannel) string {
+	switch newChannel.ChannelType() {
+	case "direct-tcpip", "forwarded-tcpip":
+	default:
+		return ""
+	}
+
+	var data channelOpenTCPIP
+	err := ssh.Unmarshal(newChannel.ExtraData(), &data)
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(data.Host, strconv.FormatUint(uint64(data.Port), 10))
+}
+
+// execCommand returns the command of exec requests and the subsystem of subsystem requests.
+// The second return value is false if the request does not run anything.
+func execCommand(req *ssh.Request) (string, bool) {
+	switch req.Type {
+	case "shell":
+		return "", true
+	case "exec", "subsystem":
+	default:
+		return "", false
+	}
+
+	var payload struct{ Value string }
+	err := ssh.Unmarshal(req.Payload, &payload)
+	if err != nil {
+		return "", false
+	}
+	return payload.Value, true
+}
+
+func boolPtr(b bool) *bool { return &b }
@@ -0,0 +1,120 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package sshproxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestAuditSinks(t *testing.T) {
+	session := &Session{
+		WorkspaceID: "ws1",
+		InstanceID:  "inst1",
+		UserID:      "owner",
+		SourceIP:    "10.0.0.1",
+		AuthMethod:  "publicKey",
+	}
+	expectation := []AuditEvent{
+		{Type: AuditSessionStart, UserID: "owner", WorkspaceID: "ws1", InstanceID: "inst1", SourceIP: "10.0.0.1", AuthMethod: "publicKey"},
+		{Type: AuditExec, UserID: "owner", WorkspaceID: "ws1", InstanceID: "inst1", SourceIP: "10.0.0.1", AuthMethod: "publicKey", ChannelType: "session", Request: "exec", Command: "ls -la"},
+	}
+	emit := func(s *Server) {
+		s.audit(session, AuditEvent{Type: AuditSessionStart})
+		s.audit(session, AuditEvent{Type: AuditExec, ChannelType: "session", Request: "exec", Command: "ls -la"})
+	}
+	ignoreTime := cmpopts.IgnoreFields(AuditEvent{}, "Time")
+
+	t.Run("file", func(t *testing.T) {
+		fn := filepath.Join(t.TempDir(), "audit.jsonl")
+		sink, err := NewFileAuditSink(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		emit(&Server{Auditor: sink})
+		sink.Close()
+
+		f, err := os.Open(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		var act []AuditEvent
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var ev AuditEvent
+			err := json.Unmarshal(scanner.Bytes(), &ev)
+			if err != nil {
+				t.Fatalf("cannot parse audit line %q: %v", scanner.Text(), err)
+			}
+			act = append(act, ev)
+		}
+		if diff := cmp.Diff(expectation, act, ignoreTime); diff != "" {
+			t.Errorf("unexpected audit events (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		events := make(chan AuditEvent, len(expectation))
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var ev AuditEvent
+			err := json.NewDecoder(r.Body).Decode(&ev)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			events <- ev
+		}))
+		defer srv.Close()
+
+		emit(&Server{Auditor: NewWebhookAuditSink(srv.URL)})
+
+		var act []AuditEvent
+		for range expectation {
+			select {
+			case ev := <-events:
+				act = append(act, ev)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for audit events")
+			}
+		}
+		if diff := cmp.Diff(expectation, act, ignoreTime); diff != "" {
+			t.Errorf("unexpected audit events (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestExecCommand(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Req     *ssh.Request
+		Command string
+		Exec    bool
+	}{
+		{Name: "exec", Req: &ssh.Request{Type: "exec", Payload: ssh.Marshal(struct{ Value string }{"echo hello"})}, Command: "echo hello", Exec: true},
+		{Name: "subsystem", Req: &ssh.Request{Type: "subsystem", Payload: ssh.Marshal(struct{ Value string }{"sftp"})}, Command: "sftp", Exec: true},
+		{Name: "shell", Req: &ssh.Request{Type: "shell"}, Exec: true},
+		{Name: "pty", Req: &ssh.Request{Type: "pty-req"}},
+		{Name: "broken payload", Req: &ssh.Request{Type: "exec", Payload: []byte{0}}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			cmd, exec := execCommand(test.Req)
+			if cmd != test.Command || exec != test.Exec {
+				t.Errorf("execCommand() = %q, %v; expected %q, %v", cmd, exec, test.Command, test.Exec)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package sshproxy
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/xerrors"
+)
+
+// AuthorizedKeysProvider provides the SSH public keys users registered with their account
+type AuthorizedKeysProvider interface {
+	// AuthorizedKeys returns the public keys of the given user
+	AuthorizedKeys(ctx context.Context, userID string) ([]ssh.PublicKey, error)
+}
+
+// FileAuthorizedKeysProvider reads the public keys of a user from a file named after the user ID
+// in authorized_keys format. The files are read on every lookup so that changes apply immediately.
+type FileAuthorizedKeysProvider struct {
+	Dir string
+}
+
+// AuthorizedKeys returns the public keys of the given user
+func (p *FileAuthorizedKeysProvider) AuthorizedKeys(ctx context.Context, userID string) ([]ssh.PublicKey, error) {
+	if userID == "" || strings.ContainsAny(userID, `/\`) || strings.HasPrefix(userID, ".") {
+		return nil, xerrors.Errorf("invalid user ID: %q", userID)
+	}
+
+	fc, err := os.ReadFile(filepath.Join(p.Dir, userID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, xerrors.Errorf("cannot read authorized keys: %w", err)
+	}
+
+	var res []ssh.PublicKey
+	for len(fc) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(fc)
+		if err != nil {
+			// ParseAuthorizedKey skips invalid lines and only fails if there's no valid key left
+			break
+		}
+		res = append(res, key)
+		fc = rest
+	}
+	return res, nil
+}
+
+// isAuthorizedKey returns true if key is among the registered keys of the workspace owner
+func (s *Server) isAuthorizedKey(ctx context.Context, ownerUserID string, key ssh.PublicKey) (bool, error) {
+	if s.AuthorizedKeys == nil || ownerUserID == "" {
+		return false, nil
+	}
+
+	keys, err := s.AuthorizedKeys.AuthorizedKeys(ctx, ownerUserID)
+	if err != nil {
+		return false, err
+	}
+	for _, k := range keys {
+		if k.Type() == key.Type() && bytes.Equal(k.Marshal(), key.Marshal()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
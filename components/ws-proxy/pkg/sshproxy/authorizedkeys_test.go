@@ -0,0 +1,80 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package sshproxy
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	p "github.com/gitpod-io/gitpod/ws-proxy/pkg/proxy"
+)
+
+func TestPublicKeyAuthenticator(t *testing.T) {
+	var (
+		registered = mustGeneratePublicKey(t)
+		other      = mustGeneratePublicKey(t)
+	)
+
+	dir := t.TempDir()
+	authorizedKeys := "# registered keys\n" + string(ssh.MarshalAuthorizedKey(registered))
+	err := os.WriteFile(filepath.Join(dir, "owner"), []byte(authorizedKeys), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(nil, fakeWorkspaceInfoProvider{
+		"ws1": {WorkspaceID: "ws1", OwnerUserID: "owner"},
+		"ws2": {WorkspaceID: "ws2", OwnerUserID: "someone-else"},
+		"ws3": {WorkspaceID: "ws3", OwnerUserID: "../owner"},
+	}, nil)
+	s.AuthorizedKeys = &FileAuthorizedKeysProvider{Dir: dir}
+
+	tests := []struct {
+		Name        string
+		WorkspaceID string
+		Key         ssh.PublicKey
+		Success     bool
+	}{
+		{Name: "registered key", WorkspaceID: "ws1", Key: registered, Success: true},
+		{Name: "unregistered key", WorkspaceID: "ws1", Key: other},
+		{Name: "key of other user", WorkspaceID: "ws2", Key: registered},
+		{Name: "unknown workspace", WorkspaceID: "ws0", Key: registered},
+		{Name: "invalid owner", WorkspaceID: "ws3", Key: registered},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := s.PublicKeyAuthenticator(test.WorkspaceID, test.Key)
+			if test.Success && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !test.Success && err == nil {
+				t.Error("expected authentication to fail")
+			}
+		})
+	}
+}
+
+type fakeWorkspaceInfoProvider map[string]*p.WorkspaceInfo
+
+func (f fakeWorkspaceInfoProvider) WorkspaceInfo(workspaceID string) *p.WorkspaceInfo {
+	return f[workspaceID]
+}
+
+func mustGeneratePublicKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
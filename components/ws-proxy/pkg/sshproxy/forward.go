@@ -15,10 +15,12 @@ import (
 )
 
 func (s *Server) ChannelForward(ctx context.Context, session *Session, client *ssh.Client, newChannel ssh.NewChannel) {
+	target := channelTarget(newChannel)
 	workspaceChan, workspaceReqs, err := client.OpenChannel(newChannel.ChannelType(), newChannel.ExtraData())
 	if err != nil {
 		log.WithFields(log.OWI("", session.WorkspaceID, session.InstanceID)).Error("open workspace channel error")
 		newChannel.Reject(ssh.ConnectionFailed, "open workspace channel error")
+		s.audit(session, AuditEvent{Type: AuditChannelOpen, ChannelType: newChannel.ChannelType(), Target: target, Success: boolPtr(false)})
 		return
 	}
 	defer workspaceChan.Close()
@@ -32,11 +34,15 @@ func (s *Server) ChannelForward(ctx context.Context, session *Session, client *s
 		clientChan = startHeartbeatingChannel(clientChan, s.Heartbeater, session.InstanceID)
 	}
 	defer clientChan.Close()
+	s.audit(session, AuditEvent{Type: AuditChannelOpen, ChannelType: newChannel.ChannelType(), Target: target, Success: boolPtr(true)})
 
 	maskedReqs := make(chan *ssh.Request, 1)
 
 	go func() {
 		for req := range clientReqs {
+			if cmd, ok := execCommand(req); ok {
+				s.audit(session, AuditEvent{Type: AuditExec, ChannelType: newChannel.ChannelType(), Request: req.Type, Command: cmd})
+			}
 			switch req.Type {
 			case "pty-req", "shell":
 				log.WithFields(log.OWI("", session.WorkspaceID, session.InstanceID)).Debugf("forwarding %s request", req.Type)
@@ -50,15 +56,7 @@ func (s *Server) ChannelForward(ctx context.Context, session *Session, client *s
 		close(maskedReqs)
 	}()
 
-	go func() {
-		io.Copy(workspaceChan, clientChan)
-		workspaceChan.CloseWrite()
-	}()
-
-	go func() {
-		io.Copy(clientChan, workspaceChan)
-		clientChan.CloseWrite()
-	}()
+	s.copyChannel(session, newChannel.ChannelType(), target, clientChan, workspaceChan)
 
 	wg := sync.WaitGroup{}
 	forward := func(sourceReqs <-chan *ssh.Request, targetChan ssh.Channel) {
@@ -89,6 +87,42 @@ func (s *Server) ChannelForward(ctx context.Context, session *Session, client *s
 	log.WithFields(log.OWI("", session.WorkspaceID, session.InstanceID)).Debug("session forward stop")
 }
 
+// copyChannel copies data between the client and workspace channel in both directions and
+// audits the channel once both directions are done.
+func (s *Server) copyChannel(session *Session, channelType, target string, clientChan, workspaceChan ssh.Channel) (done <-chan struct{}) {
+	var (
+		start             = time.Now()
+		bytesIn, bytesOut int64
+		wg                sync.WaitGroup
+		res               = make(chan struct{})
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		bytesIn, _ = io.Copy(workspaceChan, clientChan)
+		workspaceChan.CloseWrite()
+	}()
+	go func() {
+		defer wg.Done()
+		bytesOut, _ = io.Copy(clientChan, workspaceChan)
+		clientChan.CloseWrite()
+	}()
+	go func() {
+		wg.Wait()
+		session.countBytes(bytesIn, bytesOut)
+		s.audit(session, AuditEvent{
+			Type:        AuditChannelClose,
+			ChannelType: channelType,
+			Target:      target,
+			BytesIn:     bytesIn,
+			BytesOut:    bytesOut,
+			Duration:    time.Since(start).Seconds(),
+		})
+		close(res)
+	}()
+	return res
+}
+
 func startHeartbeatingChannel(c ssh.Channel, heartbeat Heartbeat, instanceID string) ssh.Channel {
 	ctx, cancel := context.WithCancel(context.Background())
 	res := &heartbeatingChannel{
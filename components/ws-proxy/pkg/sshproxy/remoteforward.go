@@ -0,0 +1,198 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package sshproxy
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultRemoteForwardLimit is the number of ports a workspace can remote forward
+// through the gateway if no other limit is configured.
+const DefaultRemoteForwardLimit = 10
+
+// remoteForwardRequest is the payload of tcpip-forward and cancel-tcpip-forward global requests (RFC 4254, section 7.1)
+type remoteForwardRequest struct {
+	BindAddr string
+	BindPort uint32
+}
+
+// remoteForwardSuccess is the reply payload of a tcpip-forward request asking for port 0
+type remoteForwardSuccess struct {
+	BindPort uint32
+}
+
+// remoteForwardLimiter counts the remote port forwards of each workspace across all of its connections
+type remoteForwardLimiter struct {
+	mu       sync.Mutex
+	forwards map[string]int
+}
+
+func newRemoteForwardLimiter() *remoteForwardLimiter {
+	return &remoteForwardLimiter{
+		forwards: make(map[string]int),
+	}
+}
+
+// Acquire reserves one remote forward for the workspace if that keeps it within max
+func (l *remoteForwardLimiter) Acquire(workspaceID string, max int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.forwards[workspaceID] >= max {
+		return false
+	}
+	l.forwards[workspaceID]++
+	return true
+}
+
+// Release frees one remote forward of the workspace
+func (l *remoteForwardLimiter) Release(workspaceID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.forwards[workspaceID]--
+	if l.forwards[workspaceID] <= 0 {
+		delete(l.forwards, workspaceID)
+	}
+}
+
+func (s *Server) remoteForwardLimit() int {
+	if s.RemoteForwardLimit == 0 {
+		return DefaultRemoteForwardLimit
+	}
+	return s.RemoteForwardLimit
+}
+
+// HandleGlobalRequests relays the remote port forwarding requests of a client to the workspace
+// and rejects all other global requests.
+func (s *Server) HandleGlobalRequests(session *Session, client *ssh.Client, reqs <-chan *ssh.Request) {
+	// forwards contains the remote forwards of this connection which are active in the workspace
+	forwards := make(map[string]struct{})
+	defer func() {
+		for range forwards {
+			s.remoteForwards.Release(session.WorkspaceID)
+		}
+	}()
+
+	for req := range reqs {
+		switch req.Type {
+		case "tcpip-forward":
+			s.remoteForward(session, client, req, forwards)
+		case "cancel-tcpip-forward":
+			s.cancelRemoteForward(session, client, req, forwards)
+		default:
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+}
+
+func (s *Server) remoteForward(session *Session, client *ssh.Client, req *ssh.Request, forwards map[string]struct{}) {
+	var fwd remoteForwardRequest
+	err := ssh.Unmarshal(req.Payload, &fwd)
+	if err != nil {
+		req.Reply(false, nil)
+		return
+	}
+	logger := log.WithFields(log.OWI("", session.WorkspaceID, session.InstanceID)).WithField("bindAddr", fwd.BindAddr).WithField("bindPort", fwd.BindPort)
+
+	target := remoteForwardKey(fwd.BindAddr, fwd.BindPort)
+
+	limit := s.remoteForwardLimit()
+	if limit < 0 || !s.remoteForwards.Acquire(session.WorkspaceID, limit) {
+		logger.WithField("limit", limit).Info("rejecting remote port forward - limit reached or remote forwarding disabled")
+		s.audit(session, AuditEvent{Type: AuditRemoteForward, Request: req.Type, Target: target, Success: boolPtr(false)})
+		req.Reply(false, nil)
+		return
+	}
+
+	ok, payload, err := client.SendRequest(req.Type, true, req.Payload)
+	if err != nil || !ok {
+		s.remoteForwards.Release(session.WorkspaceID)
+		if err != nil {
+			logger.WithError(err).Error("cannot relay remote port forward to workspace")
+		}
+		s.audit(session, AuditEvent{Type: AuditRemoteForward, Request: req.Type, Target: target, Success: boolPtr(false)})
+		req.Reply(false, nil)
+		return
+	}
+
+	port := fwd.BindPort
+	if port == 0 {
+		var resp remoteForwardSuccess
+		if err := ssh.Unmarshal(payload, &resp); err == nil {
+			port = resp.BindPort
+		}
+	}
+	forwards[remoteForwardKey(fwd.BindAddr, port)] = struct{}{}
+	logger.WithField("port", port).Debug("remote port forward established")
+	s.audit(session, AuditEvent{Type: AuditRemoteForward, Request: req.Type, Target: remoteForwardKey(fwd.BindAddr, port), Success: boolPtr(true)})
+
+	req.Reply(true, payload)
+}
+
+func (s *Server) cancelRemoteForward(session *Session, client *ssh.Client, req *ssh.Request, forwards map[string]struct{}) {
+	var fwd remoteForwardRequest
+	err := ssh.Unmarshal(req.Payload, &fwd)
+	if err != nil {
+		req.Reply(false, nil)
+		return
+	}
+
+	key := remoteForwardKey(fwd.BindAddr, fwd.BindPort)
+	if _, exists := forwards[key]; !exists {
+		req.Reply(false, nil)
+		return
+	}
+
+	ok, payload, err := client.SendRequest(req.Type, true, req.Payload)
+	if err != nil {
+		log.WithFields(log.OWI("", session.WorkspaceID, session.InstanceID)).WithError(err).Error("cannot relay remote port forward cancellation to workspace")
+	}
+	if ok {
+		delete(forwards, key)
+		s.remoteForwards.Release(session.WorkspaceID)
+	}
+	s.audit(session, AuditEvent{Type: AuditRemoteForward, Request: req.Type, Target: key, Success: boolPtr(ok)})
+	req.Reply(ok, payload)
+}
+
+func remoteForwardKey(addr string, port uint32) string {
+	return net.JoinHostPort(addr, strconv.FormatUint(uint64(port), 10))
+}
+
+// RemoteForwardChannel forwards a connection which the workspace accepted on a remote forwarded
+// port to the client.
+func (s *Server) RemoteForwardChannel(session *Session, newChannel ssh.NewChannel) {
+	target := channelTarget(newChannel)
+	clientChan, clientReqs, err := session.Conn.OpenChannel(newChannel.ChannelType(), newChannel.ExtraData())
+	if err != nil {
+		log.WithFields(log.OWI("", session.WorkspaceID, session.InstanceID)).WithError(err).Debug("cannot open forwarded-tcpip channel to client")
+		newChannel.Reject(ssh.ConnectionFailed, fmt.Sprintf("cannot open channel to client: %v", err))
+		s.audit(session, AuditEvent{Type: AuditChannelOpen, ChannelType: newChannel.ChannelType(), Target: target, Success: boolPtr(false)})
+		return
+	}
+	defer clientChan.Close()
+
+	workspaceChan, workspaceReqs, err := newChannel.Accept()
+	if err != nil {
+		log.WithFields(log.OWI("", session.WorkspaceID, session.InstanceID)).WithError(err).Error("accept forwarded-tcpip channel failed")
+		return
+	}
+	defer workspaceChan.Close()
+	s.audit(session, AuditEvent{Type: AuditChannelOpen, ChannelType: newChannel.ChannelType(), Target: target, Success: boolPtr(true)})
+
+	go ssh.DiscardRequests(clientReqs)
+	go ssh.DiscardRequests(workspaceReqs)
+
+	<-s.copyChannel(session, newChannel.ChannelType(), target, clientChan, workspaceChan)
+}
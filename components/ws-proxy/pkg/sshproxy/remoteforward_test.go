@@ -0,0 +1,270 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package sshproxy
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestRemoteForwardLimiter(t *testing.T) {
+	l := newRemoteForwardLimiter()
+
+	for i := 0; i < 2; i++ {
+		if !l.Acquire("ws1", 2) {
+			t.Fatalf("expected remote forward %d to be within the limit", i)
+		}
+	}
+	if l.Acquire("ws1", 2) {
+		t.Error("expected third remote forward to exceed the limit")
+	}
+	if !l.Acquire("ws2", 2) {
+		t.Error("expected limit to apply per workspace")
+	}
+
+	l.Release("ws1")
+	if !l.Acquire("ws1", 2) {
+		t.Error("expected released remote forward to be available again")
+	}
+
+	l.Release("ws1")
+	l.Release("ws1")
+	l.Release("ws2")
+	if len(l.forwards) != 0 {
+		t.Errorf("expected all remote forwards to be released, got %v", l.forwards)
+	}
+}
+
+func TestRemoteForward(t *testing.T) {
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(signer)
+	clientConfig := &ssh.ClientConfig{User: GitpodUsername, HostKeyCallback: ssh.InsecureIgnoreHostKey()}
+
+	// the workspace sshd listens on the remote forwarded ports
+	wsListeners := make(chan net.Listener, 1)
+	wsGatewaySide, wsSide := tcpPipe(t)
+	go serveFakeWorkspaceSSH(t, wsSide, serverConfig, wsListeners)
+	wsConn, wsChans, wsReqs, err := ssh.NewClientConn(wsGatewaySide, "workspace", clientConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wsClient := ssh.NewClient(wsConn, wsChans, wsReqs)
+	defer wsClient.Close()
+
+	// the gateway relays between the user and the workspace as in HandleConn
+	srv := New(nil, nil, nil)
+	userGatewaySide, userSide := tcpPipe(t)
+	go func() {
+		sshConn, chans, reqs, err := ssh.NewServerConn(userGatewaySide, serverConfig)
+		if err != nil {
+			return
+		}
+		session := &Session{Conn: sshConn, WorkspaceID: "ws1"}
+		go func() {
+			for newChannel := range wsClient.HandleChannelOpen("forwarded-tcpip") {
+				go srv.RemoteForwardChannel(session, newChannel)
+			}
+		}()
+		go srv.HandleGlobalRequests(session, wsClient, reqs)
+		for newChannel := range chans {
+			newChannel.Reject(ssh.UnknownChannelType, "not supported")
+		}
+	}()
+	userConn, userChans, userReqs, err := ssh.NewClientConn(userSide, "gateway", clientConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := ssh.NewClient(userConn, userChans, userReqs)
+	defer user.Close()
+
+	l, err := user.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("remote forward failed: %v", err)
+	}
+	var wsListener net.Listener
+	select {
+	case wsListener = <-wsListeners:
+	case <-time.After(5 * time.Second):
+		t.Fatal("workspace did not receive the remote forward")
+	}
+	if l.Addr().String() != wsListener.Addr().String() {
+		t.Errorf("remote forward reports %s, workspace listens on %s", l.Addr(), wsListener.Addr())
+	}
+	if n := forwardCount(srv, "ws1"); n != 1 {
+		t.Errorf("expected one remote forward to count against the limit, got %d", n)
+	}
+
+	// a connection to the forwarded port in the workspace reaches the user
+	conn, err := net.Dial("tcp", wsListener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	forwarded, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer forwarded.Close()
+		_, _ = io.Copy(forwarded, forwarded)
+	}()
+	_, err = conn.Write([]byte("ping"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		t.Fatalf("cannot read from forwarded connection: %v", err)
+	}
+	if string(buf) != "ping" {
+		t.Errorf("unexpected data from forwarded connection: %q", buf)
+	}
+
+	// cancelling the forward closes the port in the workspace and releases it
+	err = l.Close()
+	if err != nil {
+		t.Fatalf("cancelling the remote forward failed: %v", err)
+	}
+	if n := forwardCount(srv, "ws1"); n != 0 {
+		t.Errorf("expected the cancelled remote forward to be released, got %d", n)
+	}
+	_, err = net.Dial("tcp", wsListener.Addr().String())
+	if err == nil {
+		t.Error("expected the forwarded port to be closed in the workspace")
+	}
+}
+
+// tcpPipe returns both ends of a loopback TCP connection. Unlike net.Pipe it buffers, which
+// the SSH handshake requires as both sides send their version first.
+func tcpPipe(t *testing.T) (net.Conn, net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		accepted <- c
+	}()
+	a, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ok := <-accepted
+	if !ok {
+		t.Fatal("cannot accept loopback connection")
+	}
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+	return a, b
+}
+
+func forwardCount(s *Server, workspaceID string) int {
+	s.remoteForwards.mu.Lock()
+	defer s.remoteForwards.mu.Unlock()
+	return s.remoteForwards.forwards[workspaceID]
+}
+
+// serveFakeWorkspaceSSH serves remote port forwards like the sshd in a workspace does
+func serveFakeWorkspaceSSH(t *testing.T, c net.Conn, cfg *ssh.ServerConfig, listeners chan<- net.Listener) {
+	conn, chans, reqs, err := ssh.NewServerConn(c, cfg)
+	if err != nil {
+		t.Errorf("workspace SSH handshake failed: %v", err)
+		return
+	}
+	go func() {
+		for newChannel := range chans {
+			newChannel.Reject(ssh.UnknownChannelType, "not supported")
+		}
+	}()
+
+	forwards := make(map[string]net.Listener)
+	for req := range reqs {
+		var fwd remoteForwardRequest
+		err := ssh.Unmarshal(req.Payload, &fwd)
+		if err != nil {
+			req.Reply(false, nil)
+			continue
+		}
+		switch req.Type {
+		case "tcpip-forward":
+			l, err := net.Listen("tcp", net.JoinHostPort(fwd.BindAddr, strconv.FormatUint(uint64(fwd.BindPort), 10)))
+			if err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			port := uint32(l.Addr().(*net.TCPAddr).Port)
+			forwards[remoteForwardKey(fwd.BindAddr, port)] = l
+			go acceptForwardedConns(conn, l, fwd.BindAddr, port)
+			req.Reply(true, ssh.Marshal(remoteForwardSuccess{BindPort: port}))
+			listeners <- l
+		case "cancel-tcpip-forward":
+			key := remoteForwardKey(fwd.BindAddr, fwd.BindPort)
+			l, ok := forwards[key]
+			if ok {
+				l.Close()
+				delete(forwards, key)
+			}
+			req.Reply(ok, nil)
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+func acceptForwardedConns(conn ssh.Conn, l net.Listener, addr string, port uint32) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		origin := c.RemoteAddr().(*net.TCPAddr)
+		payload := ssh.Marshal(struct {
+			Addr       string
+			Port       uint32
+			OriginAddr string
+			OriginPort uint32
+		}{addr, port, origin.IP.String(), uint32(origin.Port)})
+		go func() {
+			defer c.Close()
+			ch, reqs, err := conn.OpenChannel("forwarded-tcpip", payload)
+			if err != nil {
+				return
+			}
+			defer ch.Close()
+			go ssh.DiscardRequests(reqs)
+			go func() {
+				_, _ = io.Copy(ch, c)
+				_ = ch.CloseWrite()
+			}()
+			_, _ = io.Copy(c, ch)
+		}()
+	}
+}
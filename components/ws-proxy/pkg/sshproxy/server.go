@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gitpod-io/gitpod/common-go/log"
@@ -26,16 +27,41 @@ type Session struct {
 
 	WorkspaceID string
 	InstanceID  string
+	UserID      string
+	SourceIP    string
+
+	// AuthMethod is either "ownerToken" or "publicKey"
+	AuthMethod           string
+	PublicKeyFingerprint string
 
 	PublicKey           ssh.PublicKey
 	WorkspacePrivateKey ssh.Signer
+
+	bytesIn  int64
+	bytesOut int64
+}
+
+// countBytes adds the bytes transferred in a channel to the session total
+func (s *Session) countBytes(in, out int64) {
+	atomic.AddInt64(&s.bytesIn, in)
+	atomic.AddInt64(&s.bytesOut, out)
 }
 
 type Server struct {
 	Heartbeater Heartbeat
+	Auditor     AuditSink
+
+	// RemoteForwardLimit is the maximum number of ports a workspace can remote forward.
+	// Zero means DefaultRemoteForwardLimit, a negative value disables remote port forwarding.
+	RemoteForwardLimit int
+
+	// AuthorizedKeys provides the public keys users registered with their account. If set,
+	// users can authenticate as <workspaceID> using one of their keys.
+	AuthorizedKeys AuthorizedKeysProvider
 
 	sshConfig             *ssh.ServerConfig
 	workspaceInfoProvider p.WorkspaceInfoProvider
+	remoteForwards        *remoteForwardLimiter
 }
 
 // New creates a new SSH proxy server
@@ -44,6 +70,8 @@ func New(signers []ssh.Signer, workspaceInfoProvider p.WorkspaceInfoProvider, he
 	server := &Server{
 		workspaceInfoProvider: workspaceInfoProvider,
 		Heartbeater:           &noHeartbeat{},
+		Auditor:               &noAuditSink{},
+		remoteForwards:        newRemoteForwardLimiter(),
 	}
 	if heartbeat != nil {
 		server.Heartbeater = heartbeat
@@ -60,11 +88,27 @@ func New(signers []ssh.Signer, workspaceInfoProvider p.WorkspaceInfoProvider, he
 			return &ssh.Permissions{
 				Extensions: map[string]string{
 					"workspaceId": workspaceId,
+					"authMethod":  "ownerToken",
 				},
 			}, nil
 		},
 		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
 			args := strings.Split(conn.User(), "#")
+			if len(args) == 1 {
+				// workspaceId, authenticated by one of the keys the owner registered
+				workspaceId := args[0]
+				err := server.PublicKeyAuthenticator(workspaceId, key)
+				if err != nil {
+					return nil, err
+				}
+				return &ssh.Permissions{
+					Extensions: map[string]string{
+						"workspaceId": workspaceId,
+						"authMethod":  "publicKey",
+						"publicKey":   ssh.FingerprintSHA256(key),
+					},
+				}, nil
+			}
 			// workspaceId#ownerToken
 			if len(args) != 2 {
 				return nil, fmt.Errorf("username error")
@@ -77,6 +121,7 @@ func New(signers []ssh.Signer, workspaceInfoProvider p.WorkspaceInfoProvider, he
 			return &ssh.Permissions{
 				Extensions: map[string]string{
 					"workspaceId": workspaceId,
+					"authMethod":  "ownerToken",
 				},
 			}, nil
 		},
@@ -95,33 +140,44 @@ func (s *Server) HandleConn(c net.Conn) {
 	}
 	defer sshConn.Close()
 
-	go ssh.DiscardRequests(reqs)
 	if sshConn.Permissions == nil || sshConn.Permissions.Extensions == nil || sshConn.Permissions.Extensions["workspaceId"] == "" {
+		go ssh.DiscardRequests(reqs)
 		return
 	}
 	workspaceId := sshConn.Permissions.Extensions["workspaceId"]
 	wsInfo := s.workspaceInfoProvider.WorkspaceInfo(workspaceId)
 	if wsInfo == nil {
+		go ssh.DiscardRequests(reqs)
 		return
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	key, err := s.GetWorkspaceSSHKey(ctx, wsInfo.IPAddress)
 	if err != nil {
 		cancel()
+		go ssh.DiscardRequests(reqs)
 		return
 	}
 	cancel()
 
+	var sourceIP string
+	if addr, ok := sshConn.RemoteAddr().(*net.TCPAddr); ok {
+		sourceIP = addr.IP.String()
+	}
 	session := &Session{
-		Conn:                sshConn,
-		WorkspaceID:         workspaceId,
-		InstanceID:          wsInfo.InstanceID,
-		WorkspacePrivateKey: key,
+		Conn:                 sshConn,
+		WorkspaceID:          workspaceId,
+		InstanceID:           wsInfo.InstanceID,
+		UserID:               wsInfo.OwnerUserID,
+		SourceIP:             sourceIP,
+		AuthMethod:           sshConn.Permissions.Extensions["authMethod"],
+		PublicKeyFingerprint: sshConn.Permissions.Extensions["publicKey"],
+		WorkspacePrivateKey:  key,
 	}
 	remoteAddr := wsInfo.IPAddress + ":23001"
 	conn, err := net.Dial("tcp", remoteAddr)
 	if err != nil {
 		log.WithField("instanceId", wsInfo.InstanceID).WithField("workspaceIP", wsInfo.IPAddress).WithError(err).Error("dail failed")
+		go ssh.DiscardRequests(reqs)
 		return
 	}
 	defer conn.Close()
@@ -138,12 +194,33 @@ func (s *Server) HandleConn(c net.Conn) {
 	})
 	if err != nil {
 		log.WithField("instanceId", wsInfo.InstanceID).WithField("workspaceIP", wsInfo.IPAddress).WithError(err).Error("connect failed")
+		go ssh.DiscardRequests(reqs)
 		return
 	}
 	s.Heartbeater.SendHeartbeat(wsInfo.InstanceID, false)
 	client := ssh.NewClient(clientConn, clientChans, clientReqs)
+
+	start := time.Now()
+	s.audit(session, AuditEvent{Type: AuditSessionStart})
+	defer func() {
+		s.audit(session, AuditEvent{
+			Type:     AuditSessionEnd,
+			BytesIn:  atomic.LoadInt64(&session.bytesIn),
+			BytesOut: atomic.LoadInt64(&session.bytesOut),
+			Duration: time.Since(start).Seconds(),
+		})
+	}()
 	ctx, cancel = context.WithCancel(context.Background())
 
+	// the workspace opens a forwarded-tcpip channel for every connection to a remote forwarded port
+	forwardedChans := client.HandleChannelOpen("forwarded-tcpip")
+	go func() {
+		for newChannel := range forwardedChans {
+			go s.RemoteForwardChannel(session, newChannel)
+		}
+	}()
+	go s.HandleGlobalRequests(session, client, reqs)
+
 	go func() {
 		client.Wait()
 		cancel()
@@ -153,8 +230,6 @@ func (s *Server) HandleConn(c net.Conn) {
 		switch newChannel.ChannelType() {
 		case "session", "direct-tcpip":
 			go s.ChannelForward(ctx, session, client, newChannel)
-		case "tcpip-forward":
-			newChannel.Reject(ssh.UnknownChannelType, "Gitpod SSH Gateway cannot remote forward ports")
 		default:
 			newChannel.Reject(ssh.UnknownChannelType, fmt.Sprintf("Gitpod SSH Gateway cannot handle %s channel types", newChannel.ChannelType()))
 		}
@@ -172,6 +247,26 @@ func (s *Server) Authenticator(workspaceId, ownerToken string) (err error) {
 	return nil
 }
 
+// PublicKeyAuthenticator checks that key is one of the keys the workspace owner registered
+func (s *Server) PublicKeyAuthenticator(workspaceId string, key ssh.PublicKey) (err error) {
+	wsInfo := s.workspaceInfoProvider.WorkspaceInfo(workspaceId)
+	if wsInfo == nil {
+		return fmt.Errorf("not found workspace")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ok, err := s.isAuthorizedKey(ctx, wsInfo.OwnerUserID, key)
+	if err != nil {
+		log.WithFields(log.OWI(wsInfo.OwnerUserID, workspaceId, wsInfo.InstanceID)).WithError(err).Warn("cannot look up authorized keys")
+		return fmt.Errorf("auth failed")
+	}
+	if !ok {
+		return fmt.Errorf("auth failed")
+	}
+	return nil
+}
+
 func (s *Server) GetWorkspaceSSHKey(ctx context.Context, workspaceIP string) (ssh.Signer, error) {
 	supervisorConn, err := grpc.Dial(workspaceIP+":22999", grpc.WithInsecure())
 	if err != nil {
@@ -0,0 +1,107 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/gitpod-io/gitpod/common-go/log"
+	"github.com/gitpod-io/gitpod/gpctl/pkg/util"
+	regapi "github.com/gitpod-io/gitpod/registry-facade/api"
+)
+
+// registryFacadePrefetchCmd represents the registry-facade prefetch command
+var registryFacadePrefetchCmd = &cobra.Command{
+	Use:   "prefetch <base-ref>",
+	Short: "Warms up the caches of all registry-facade instances with a workspace image",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		spec := &regapi.ImageSpec{BaseRef: args[0]}
+		spec.IdeRef, _ = cmd.Flags().GetString("ide")
+		spec.DesktopIdeRef, _ = cmd.Flags().GetString("desktop-ide")
+		spec.SupervisorRef, _ = cmd.Flags().GetString("supervisor")
+		remotePort, _ := registryFacadeCmd.PersistentFlags().GetInt("port")
+
+		secopt, err := getRegistryFacadeSecurityOption()
+		if err != nil {
+			log.WithError(err).Fatal("cannot load TLS config")
+		}
+
+		cfg, namespace, err := getKubeconfig()
+		if err != nil {
+			log.WithError(err).Fatal("cannot get kubeconfig")
+		}
+		clientSet, err := kubernetes.NewForConfig(cfg)
+		if err != nil {
+			log.WithError(err).Fatal("cannot connect to Kubernetes")
+		}
+		pods, err := util.FindPodsForComponent(clientSet, namespace, "registry-facade")
+		if err != nil {
+			log.WithError(err).Fatal("cannot find registry-facade")
+		}
+
+		var failed bool
+		for _, pod := range pods {
+			err := prefetchOnPod(ctx, pod, remotePort, secopt, spec)
+			if err != nil {
+				log.WithError(err).WithField("pod", pod).Error("cannot prefetch image")
+				failed = true
+			}
+		}
+		if failed {
+			log.Fatal("prefetch failed on some registry-facade instances")
+		}
+	},
+}
+
+func prefetchOnPod(ctx context.Context, pod string, remotePort int, secopt grpc.DialOption, spec *regapi.ImageSpec) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cfg, namespace, err := getKubeconfig()
+	if err != nil {
+		return err
+	}
+	freePort, err := GetFreePort()
+	if err != nil {
+		return err
+	}
+	readychan, errchan := util.ForwardPort(ctx, cfg, namespace, pod, fmt.Sprintf("%d:%d", freePort, remotePort))
+	select {
+	case <-readychan:
+	case err := <-errchan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	conn, err := grpc.Dial(fmt.Sprintf("localhost:%d", freePort), secopt, util.WithClientUnaryInterceptor())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	resp, err := regapi.NewPrefetcherClient(conn).PrefetchImage(ctx, &regapi.PrefetchImageRequest{Spec: spec})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: prefetched %d blobs (%d bytes)\n", pod, resp.Blobs, resp.Bytes)
+	return nil
+}
+
+func init() {
+	registryFacadePrefetchCmd.Flags().String("ide", "", "IDE image ref")
+	registryFacadePrefetchCmd.Flags().String("desktop-ide", "", "desktop IDE image ref")
+	registryFacadePrefetchCmd.Flags().String("supervisor", "", "supervisor image ref")
+	registryFacadeCmd.AddCommand(registryFacadePrefetchCmd)
+}
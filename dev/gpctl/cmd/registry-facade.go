@@ -0,0 +1,54 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// registryFacadeCmd represents the registry-facade command
+var registryFacadeCmd = &cobra.Command{
+	Use:   "registry-facade",
+	Short: "Controls the registry-facade instances running on the nodes",
+	Args:  cobra.ExactArgs(1),
+}
+
+func init() {
+	registryFacadeCmd.PersistentFlags().Int("port", 9501, "port on which registry-facade serves its Prefetcher service")
+	registryFacadeCmd.PersistentFlags().String("tls-path", "", "directory containing the tls.crt, tls.key and ca.crt used to connect to registry-facade")
+
+	rootCmd.AddCommand(registryFacadeCmd)
+}
+
+func getRegistryFacadeSecurityOption() (grpc.DialOption, error) {
+	fn, _ := registryFacadeCmd.PersistentFlags().GetString("tls-path")
+	if fn == "" {
+		return grpc.WithInsecure(), nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(filepath.Join(fn, "tls.crt"), filepath.Join(fn, "tls.key"))
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(filepath.Join(fn, "ca.crt"))
+	if err != nil {
+		return nil, err
+	}
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(ca)
+
+	return grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      certPool,
+		ServerName:   "registry-facade",
+	})), nil
+}
@@ -43,6 +43,10 @@ const (
 	WSManagerComponent          = "ws-manager"
 	WSManagerBridgeComponent    = "ws-manager-bridge"
 	WSProxyComponent            = "ws-proxy"
+	WSProxyTCPPassthroughPort   = 9443
+	WSProxyTCPPassthroughName   = "tcp-proxy"
+	WSProxyCustomDomainsPort    = 9091
+	WSProxyCustomDomainsName    = "custom-domains"
 	ImageBuilderComponent       = "image-builder-mk3"
 	ImageBuilderRPCPort         = 8080
 
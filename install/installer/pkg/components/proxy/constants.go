@@ -14,6 +14,8 @@ const (
 	ContainerHTTPSName    = common.ProxyContainerHTTPSName
 	ContainerSSHPort      = 22
 	ContainerSSHName      = "ssh"
+	ContainerTCPProxyPort = common.WSProxyTCPPassthroughPort
+	ContainerTCPProxyName = common.WSProxyTCPPassthroughName
 	PrometheusPort        = 9500
 	InitContainerImage    = "library/alpine"
 	InitContainerTag      = "3.15"
@@ -119,6 +119,21 @@ func deployment(ctx *common.RenderContext) ([]runtime.Object, error) {
 	})
 
 	const kubeRbacProxyContainerName = "kube-rbac-proxy"
+	env := []corev1.EnvVar{{
+		Name:  "PROXY_DOMAIN",
+		Value: ctx.Config.Domain,
+	}}
+	_ = ctx.WithExperimental(func(cfg *experimental.Config) error {
+		if cfg.Workspace != nil && cfg.Workspace.WSProxy.CustomDomains != nil {
+			// the custom domain listener wrapper passes TLS connections for custom domains through to ws-proxy
+			env = append(env, corev1.EnvVar{
+				Name:  "WS_PROXY_CUSTOM_DOMAINS_PORT",
+				Value: fmt.Sprintf("%d", common.WSProxyCustomDomainsPort),
+			})
+		}
+		return nil
+	})
+
 	return []runtime.Object{
 		&appsv1.Deployment{
 			TypeMeta: common.TypeMetaDeployment,
@@ -223,6 +238,10 @@ func deployment(ctx *common.RenderContext) ([]runtime.Object, error) {
 								ContainerPort: ContainerSSHPort,
 								Name:          ContainerSSHName,
 								Protocol:      *common.TCPProtocol,
+							}, {
+								ContainerPort: ContainerTCPProxyPort,
+								Name:          ContainerTCPProxyName,
+								Protocol:      *common.TCPProtocol,
 							}, prometheusPort},
 							SecurityContext: &corev1.SecurityContext{
 								Privileged: pointer.Bool(false),
@@ -243,10 +262,7 @@ func deployment(ctx *common.RenderContext) ([]runtime.Object, error) {
 							VolumeMounts: volumeMounts,
 							Env: common.MergeEnv(
 								common.DefaultEnv(&ctx.Config),
-								[]corev1.EnvVar{{
-									Name:  "PROXY_DOMAIN",
-									Value: ctx.Config.Domain,
-								}},
+								env,
 							),
 						}},
 					},
@@ -0,0 +1,46 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package proxy
+
+import (
+	"testing"
+
+	"github.com/gitpod-io/gitpod/installer/pkg/common"
+	configv1 "github.com/gitpod-io/gitpod/installer/pkg/config/v1"
+	"github.com/gitpod-io/gitpod/installer/pkg/config/v1/experimental"
+	"github.com/gitpod-io/gitpod/installer/pkg/config/versions"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+func TestDeploymentCustomDomains(t *testing.T) {
+	customDomainsPort := func(cfg configv1.Config) string {
+		var manifest versions.Manifest
+		manifest.Components.Proxy.Version = "test"
+		cfg.Domain = "gitpod.example.com"
+		cfg.Repository = "eu.gcr.io/gitpod-core-dev/build"
+		ctx, err := common.NewRenderContext(cfg, manifest, "test_namespace")
+		require.NoError(t, err)
+
+		objs, err := deployment(ctx)
+		require.NoError(t, err)
+		dpl, ok := objs[0].(*appsv1.Deployment)
+		require.True(t, ok, "rendering deployment did not return a deployment")
+		for _, c := range dpl.Spec.Template.Spec.Containers {
+			for _, env := range c.Env {
+				if env.Name == "WS_PROXY_CUSTOM_DOMAINS_PORT" {
+					return env.Value
+				}
+			}
+		}
+		return ""
+	}
+
+	require.Empty(t, customDomainsPort(configv1.Config{}))
+
+	cfg := configv1.Config{Experimental: &experimental.Config{Workspace: &experimental.WorkspaceConfig{}}}
+	cfg.Experimental.Workspace.WSProxy.CustomDomains = &experimental.CustomDomainsConfig{}
+	require.Equal(t, "9091", customDomainsPort(cfg))
+}
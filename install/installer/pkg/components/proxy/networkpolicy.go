@@ -38,6 +38,9 @@ func networkpolicy(ctx *common.RenderContext) ([]runtime.Object, error) {
 				}, {
 					Protocol: common.TCPProtocol,
 					Port:     &intstr.IntOrString{IntVal: ContainerSSHPort},
+				}, {
+					Protocol: common.TCPProtocol,
+					Port:     &intstr.IntOrString{IntVal: ContainerTCPProxyPort},
 				}},
 			}, {
 				Ports: []networkingv1.NetworkPolicyPort{{
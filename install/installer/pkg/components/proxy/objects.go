@@ -31,6 +31,10 @@ var Objects = common.CompositeRenderFunc(
 				ContainerPort: PrometheusPort,
 				ServicePort:   PrometheusPort,
 			},
+			ContainerTCPProxyName: {
+				ContainerPort: ContainerTCPProxyPort,
+				ServicePort:   ContainerTCPProxyPort,
+			},
 		}
 		if cfg.Config.SSHGatewayHostKey != nil {
 			ports[ContainerSSHName] = common.ServicePort{
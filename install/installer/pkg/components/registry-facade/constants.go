@@ -18,4 +18,5 @@ const (
 	SupervisorImage   = workspace.SupervisorImage
 	WorkspacekitImage = workspace.WorkspacekitImage
 	ReadinessPort     = 8086
+	BlobCachePeerPort = 9502
 )
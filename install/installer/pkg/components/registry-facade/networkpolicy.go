@@ -10,6 +10,7 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func networkpolicy(ctx *common.RenderContext) ([]runtime.Object, error) {
@@ -25,7 +26,35 @@ func networkpolicy(ctx *common.RenderContext) ([]runtime.Object, error) {
 		Spec: networkingv1.NetworkPolicySpec{
 			PodSelector: metav1.LabelSelector{MatchLabels: labels},
 			PolicyTypes: []networkingv1.PolicyType{"Ingress"},
-			Ingress:     []networkingv1.NetworkPolicyIngressRule{{}},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					Ports: []networkingv1.NetworkPolicyPort{
+						{
+							Protocol: common.TCPProtocol,
+							Port:     &intstr.IntOrString{IntVal: ContainerPort},
+						},
+						{
+							Protocol: common.TCPProtocol,
+							Port:     &intstr.IntOrString{IntVal: ReadinessPort},
+						},
+					},
+				},
+				{
+					// cached blobs may belong to private images and are only served to other registry-facade instances
+					Ports: []networkingv1.NetworkPolicyPort{
+						{
+							Protocol: common.TCPProtocol,
+							Port:     &intstr.IntOrString{IntVal: BlobCachePeerPort},
+						},
+					},
+					From: []networkingv1.NetworkPolicyPeer{
+						{
+							PodSelector: &metav1.LabelSelector{MatchLabels: labels},
+						},
+					},
+				},
+				common.PrometheusIngressRule,
+			},
 		},
 	}}, nil
 }
@@ -12,6 +12,8 @@ import (
 
 	"github.com/gitpod-io/gitpod/common-go/util"
 	"github.com/gitpod-io/gitpod/installer/pkg/common"
+	configv1 "github.com/gitpod-io/gitpod/installer/pkg/config/v1"
+	"github.com/gitpod-io/gitpod/installer/pkg/config/v1/experimental"
 	"github.com/gitpod-io/gitpod/ws-proxy/pkg/config"
 	"github.com/gitpod-io/gitpod/ws-proxy/pkg/proxy"
 
@@ -20,7 +22,36 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// clusterNetworks are the private networks the proxy in front of ws-proxy connects from
+var clusterNetworks = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+
 func configmap(ctx *common.RenderContext) ([]runtime.Object, error) {
+	// unless ws-proxy is the entrypoint, requests come through proxy which sends the client IP as X-Real-IP
+	var portProtection *proxy.PortProtectionConfig
+	if ctx.Config.Kind != configv1.InstallationWorkspace {
+		portProtection = &proxy.PortProtectionConfig{TrustedProxies: clusterNetworks}
+	}
+
+	var customDomains *proxy.CustomDomainsConfig
+	_ = ctx.WithExperimental(func(cfg *experimental.Config) error {
+		if cfg.Workspace == nil || cfg.Workspace.WSProxy.CustomDomains == nil {
+			return nil
+		}
+		customDomains = &proxy.CustomDomainsConfig{
+			ACME: proxy.ACMEConfig{
+				DirectoryURL: cfg.Workspace.WSProxy.CustomDomains.ACMEDirectoryURL,
+				Email:        cfg.Workspace.WSProxy.CustomDomains.ACMEEmail,
+				CacheDir:     ACMECacheDir,
+			},
+		}
+		// unless ws-proxy is the entrypoint, proxy passes the TLS connections for custom domains through
+		if ctx.Config.Kind != configv1.InstallationWorkspace {
+			customDomains.Address = fmt.Sprintf(":%d", CustomDomainsPort)
+			customDomains.ProxyProtocol = true
+		}
+		return nil
+	})
+
 	// todo(sje): wsManagerProxy seems to be unused
 	wspcfg := config.Config{
 		Namespace: ctx.Namespace,
@@ -61,6 +92,16 @@ func configmap(ctx *common.RenderContext) ([]runtime.Object, error) {
 			BuiltinPages: proxy.BuiltinPagesConfig{
 				Location: "/app/public",
 			},
+			TCPPassthrough: &proxy.TCPPassthroughConfig{
+				Address: fmt.Sprintf(":%d", TCPProxyPort),
+				// unless ws-proxy is the entrypoint, connections are tunneled through proxy which sends the client address
+				ProxyProtocol: ctx.Config.Kind != configv1.InstallationWorkspace,
+			},
+			PortProtection: portProtection,
+			CustomDomains:  customDomains,
+			ConnectionDrain: &proxy.ConnectionDrainConfig{
+				Period: util.Duration(ConnectionDrainPeriod),
+			},
 		},
 		PProfAddr:          ":60060",
 		PrometheusAddr:     "127.0.0.1:9500",
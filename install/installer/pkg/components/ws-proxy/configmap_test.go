@@ -0,0 +1,97 @@
+// Copyright (c) 2022 Gitpod GmbH. All rights reserved.
+// Licensed under the GNU Affero General Public License (AGPL).
+// See License-AGPL.txt in the project root for license information.
+
+package wsproxy
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gitpod-io/gitpod/installer/pkg/common"
+	configv1 "github.com/gitpod-io/gitpod/installer/pkg/config/v1"
+	"github.com/gitpod-io/gitpod/installer/pkg/config/v1/experimental"
+	"github.com/gitpod-io/gitpod/installer/pkg/config/versions"
+	"github.com/gitpod-io/gitpod/ws-proxy/pkg/config"
+	"github.com/gitpod-io/gitpod/ws-proxy/pkg/proxy"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func renderContext(t *testing.T, cfg configv1.Config) *common.RenderContext {
+	var manifest versions.Manifest
+	manifest.Components.Workspace.Supervisor.Version = "test"
+	manifest.Components.WSProxy.Version = "test"
+	cfg.Domain = "gitpod.example.com"
+	cfg.Repository = "eu.gcr.io/gitpod-core-dev/build"
+	ctx, err := common.NewRenderContext(cfg, manifest, "test_namespace")
+	require.NoError(t, err)
+	return ctx
+}
+
+func renderConfig(t *testing.T, cfg configv1.Config) config.Config {
+	ctx := renderContext(t, cfg)
+
+	objs, err := configmap(ctx)
+	require.NoError(t, err)
+	cm, ok := objs[0].(*corev1.ConfigMap)
+	require.True(t, ok, "rendering configmap did not return a configMap")
+
+	var res config.Config
+	require.NoError(t, json.Unmarshal([]byte(cm.Data["config.json"]), &res))
+	return res
+}
+
+func TestConfigMapTrustedProxies(t *testing.T) {
+	cfg := renderConfig(t, configv1.Config{Kind: configv1.InstallationFull})
+	require.NotNil(t, cfg.Proxy.PortProtection)
+	require.Equal(t, clusterNetworks, cfg.Proxy.PortProtection.TrustedProxies)
+
+	// ws-proxy is the entrypoint of workspace clusters and must not trust X-Real-IP
+	cfg = renderConfig(t, configv1.Config{Kind: configv1.InstallationWorkspace})
+	require.Nil(t, cfg.Proxy.PortProtection)
+}
+
+func TestConfigMapCustomDomains(t *testing.T) {
+	cfg := renderConfig(t, configv1.Config{Kind: configv1.InstallationFull})
+	require.Nil(t, cfg.Proxy.CustomDomains)
+
+	withCustomDomains := func(kind configv1.InstallationKind) configv1.Config {
+		res := configv1.Config{Kind: kind, Experimental: &experimental.Config{Workspace: &experimental.WorkspaceConfig{}}}
+		res.Experimental.Workspace.WSProxy.CustomDomains = &experimental.CustomDomainsConfig{ACMEEmail: "admin@example.com"}
+		return res
+	}
+
+	// proxy passes the connections for custom domains through to a dedicated port
+	cfg = renderConfig(t, withCustomDomains(configv1.InstallationFull))
+	require.Equal(t, &proxy.CustomDomainsConfig{
+		ACME:          proxy.ACMEConfig{Email: "admin@example.com", CacheDir: ACMECacheDir},
+		Address:       ":9091",
+		ProxyProtocol: true,
+	}, cfg.Proxy.CustomDomains)
+	require.NoError(t, cfg.Validate())
+
+	// clients of workspace clusters connect to ws-proxy directly
+	cfg = renderConfig(t, withCustomDomains(configv1.InstallationWorkspace))
+	require.Equal(t, &proxy.CustomDomainsConfig{
+		ACME: proxy.ACMEConfig{Email: "admin@example.com", CacheDir: ACMECacheDir},
+	}, cfg.Proxy.CustomDomains)
+}
+
+func TestConfigMapConnectionDrain(t *testing.T) {
+	cfg := renderConfig(t, configv1.Config{Kind: configv1.InstallationFull})
+	require.NotNil(t, cfg.Proxy.ConnectionDrain)
+	period := time.Duration(cfg.Proxy.ConnectionDrain.Period)
+	require.Greater(t, period, time.Duration(0))
+
+	objs, err := deployment(renderContext(t, configv1.Config{Kind: configv1.InstallationFull}))
+	require.NoError(t, err)
+	dpl, ok := objs[0].(*appsv1.Deployment)
+	require.True(t, ok, "rendering deployment did not return a deployment")
+	gracePeriod := dpl.Spec.Template.Spec.TerminationGracePeriodSeconds
+	require.NotNil(t, gracePeriod)
+	// ws-proxy must be done draining and reporting the traffic before it is killed
+	require.Less(t, period, time.Duration(*gracePeriod)*time.Second)
+}
@@ -5,6 +5,8 @@
 package wsproxy
 
 import (
+	"time"
+
 	"github.com/gitpod-io/gitpod/installer/pkg/common"
 )
 
@@ -18,7 +20,18 @@ const (
 	SSHServicePort     = 22
 	SSHTargetPort      = 2200
 	SSHPortName        = "ssh"
-	MetricsPort        = 9500
-	MetricsPortName    = "metrics"
-	ReadinessPort      = 8086
+	TCPProxyPort       = common.WSProxyTCPPassthroughPort
+	TCPProxyPortName   = common.WSProxyTCPPassthroughName
+	CustomDomainsPort  = common.WSProxyCustomDomainsPort
+	CustomDomainsName  = common.WSProxyCustomDomainsName
+	ACMECacheDir       = "/mnt/acme"
+
+	// TerminationGracePeriod is how long Kubernetes waits for ws-proxy to shut down before killing it
+	TerminationGracePeriod = 60 * time.Second
+	// ConnectionDrainPeriod is how long ws-proxy waits for connections to close on shutdown. It leaves
+	// time to report the traffic served before the termination grace period is over.
+	ConnectionDrainPeriod = 45 * time.Second
+	MetricsPort           = 9500
+	MetricsPortName       = "metrics"
+	ReadinessPort         = 8086
 )
@@ -7,6 +7,7 @@ package wsproxy
 import (
 	"github.com/gitpod-io/gitpod/installer/pkg/cluster"
 	"github.com/gitpod-io/gitpod/installer/pkg/common"
+	"github.com/gitpod-io/gitpod/installer/pkg/config/v1/experimental"
 
 	wsmanager "github.com/gitpod-io/gitpod/installer/pkg/components/ws-manager"
 
@@ -60,6 +61,22 @@ func deployment(ctx *common.RenderContext) ([]runtime.Object, error) {
 		})
 	}
 
+	_ = ctx.WithExperimental(func(cfg *experimental.Config) error {
+		if cfg.Workspace == nil || cfg.Workspace.WSProxy.CustomDomains == nil {
+			return nil
+		}
+		// certificates of custom domains are obtained again when the pod is replaced
+		volumes = append(volumes, corev1.Volume{
+			Name:         "acme-cache",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      "acme-cache",
+			MountPath: ACMECacheDir,
+		})
+		return nil
+	})
+
 	return []runtime.Object{
 		&appsv1.Deployment{
 			TypeMeta: common.TypeMetaDeployment,
@@ -92,8 +109,9 @@ func deployment(ctx *common.RenderContext) ([]runtime.Object, error) {
 								WhenUnsatisfiable: corev1.DoNotSchedule,
 							},
 						},
-						EnableServiceLinks: pointer.Bool(false),
-						ServiceAccountName: Component,
+						EnableServiceLinks:            pointer.Bool(false),
+						ServiceAccountName:            Component,
+						TerminationGracePeriodSeconds: pointer.Int64(int64(TerminationGracePeriod.Seconds())),
 						SecurityContext: &corev1.PodSecurityContext{
 							RunAsUser: pointer.Int64(31002),
 						},
@@ -132,6 +150,12 @@ func deployment(ctx *common.RenderContext) ([]runtime.Object, error) {
 							}, {
 								Name:          MetricsPortName,
 								ContainerPort: MetricsPort,
+							}, {
+								Name:          TCPProxyPortName,
+								ContainerPort: TCPProxyPort,
+							}, {
+								Name:          CustomDomainsName,
+								ContainerPort: CustomDomainsPort,
 							}},
 							SecurityContext: &corev1.SecurityContext{
 								Privileged: pointer.Bool(false),
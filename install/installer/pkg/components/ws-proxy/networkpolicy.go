@@ -37,6 +37,12 @@ func networkpolicy(ctx *common.RenderContext) ([]runtime.Object, error) {
 					}, {
 						Protocol: common.TCPProtocol,
 						Port:     &intstr.IntOrString{IntVal: SSHTargetPort},
+					}, {
+						Protocol: common.TCPProtocol,
+						Port:     &intstr.IntOrString{IntVal: TCPProxyPort},
+					}, {
+						Protocol: common.TCPProtocol,
+						Port:     &intstr.IntOrString{IntVal: CustomDomainsPort},
 					},
 				},
 			}},
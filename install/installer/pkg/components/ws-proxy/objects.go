@@ -35,6 +35,14 @@ var Objects = common.CompositeRenderFunc(
 				ContainerPort: SSHTargetPort,
 				ServicePort:   SSHServicePort,
 			},
+			TCPProxyPortName: {
+				ContainerPort: TCPProxyPort,
+				ServicePort:   TCPProxyPort,
+			},
+			CustomDomainsName: {
+				ContainerPort: CustomDomainsPort,
+				ServicePort:   CustomDomainsPort,
+			},
 		}
 		return common.GenerateService(Component, ports, func(service *corev1.Service) {
 			// In the case of Workspace only setup, `ws-proxy` service is the entrypoint
@@ -63,9 +63,20 @@ type WorkspaceConfig struct {
 		} `json:"redisCache"`
 	} `json:"registryFacade"`
 
+	WSProxy struct {
+		CustomDomains *CustomDomainsConfig `json:"customDomains,omitempty"`
+	} `json:"wsProxy"`
+
 	WorkspaceClasses map[string]WorkspaceClass `json:"classes,omitempty"`
 }
 
+// CustomDomainsConfig enables routing verified custom domains to workspace ports.
+// ws-proxy obtains their certificates from an ACME CA, Let's Encrypt unless configured otherwise.
+type CustomDomainsConfig struct {
+	ACMEDirectoryURL string `json:"acmeDirectoryURL,omitempty"`
+	ACMEEmail        string `json:"acmeEmail,omitempty"`
+}
+
 type WorkspaceClass struct {
 	Resources struct {
 		Requests corev1.ResourceList `json:"requests" validate:"required"`